@@ -2,23 +2,34 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"strconv"
-	"strings"
+	"sync"
 	"syscall"
 	"time"
 
-	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 	handlers "github.com/pabbloacevedog/whatspp-service-glidpa/internal/handlers/http"
 	"github.com/pabbloacevedog/whatspp-service-glidpa/internal/usecases"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/auth"
 	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/config"
 	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/metrics"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/ratelimit"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/redis"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/requestid"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/store"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/templates"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/tracing"
 	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/utils"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/version"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/webhook"
 	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/whatsapp"
 	"go.uber.org/zap"
 )
@@ -29,7 +40,12 @@ func main() {
 	if err != nil {
 		panic(fmt.Sprintf("Failed to initialize logger: %v", err))
 	}
-	// No hay método Sync en la interfaz Logger, así que no podemos usar defer log.Sync()
+	// Vaciar los logs pendientes al terminar
+	defer func() {
+		if err := log.Sync(); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to sync logger: %v\n", err)
+		}
+	}()
 
 	// Cargar variables de entorno (ignorar error si no existe)
 	_ = godotenv.Load() // No falla si el archivo .env no existe
@@ -43,83 +59,484 @@ func main() {
 		log.Fatal("Port not configured")
 	}
 
+	// Validar la configuración: en producción cualquier problema impide
+	// iniciar, en desarrollo solo se advierte
+	if err := cfg.Validate(); err != nil {
+		if cfg.IsProduction() {
+			log.Fatal("Invalid configuration", zap.Error(err))
+		}
+		problems := []error{err}
+		if joined, ok := err.(interface{ Unwrap() []error }); ok {
+			problems = joined.Unwrap()
+		}
+		for _, problem := range problems {
+			log.Warn("Configuration problem", zap.Error(problem))
+		}
+	}
+
+	// Exportar trazas de OpenTelemetry cuando se instrumenta Redis
+	var shutdownTracing tracing.ShutdownFunc
+	if cfg.RedisTracing {
+		shutdownTracing, err = tracing.Setup(context.Background(), "whatsapp-service", version.Get().Version)
+		if err != nil {
+			log.Fatal("Failed to initialize tracing", zap.Error(err))
+		}
+	}
+
+	// Inicializar el cliente de Redis
+	var redisOptions []redis.ClientOption
+	if cfg.RedisMetrics {
+		redisOptions = append(redisOptions, redis.WithMetrics())
+	}
+	if cfg.RedisTracing {
+		redisOptions = append(redisOptions, redis.WithTracing())
+	}
+	redisClient := redis.NewClient(cfg.RedisAddr, redisOptions...)
+	if err := redisClient.Ping(context.Background()); err != nil {
+		log.Warn("Redis is not reachable", zap.String("addr", cfg.RedisAddr), zap.Error(err))
+	}
+
+	// Contexto para los trabajos en segundo plano
+	bgCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+
+	// Inicializar el almacén de estado
+	stateStore, durableStore := newStateStores(bgCtx, cfg, redisClient, log)
+	log.Info("State store initialized", zap.String("backend", cfg.StateStore))
+
+	// Persistir los envíos para correlacionar los acuses de recibo
+	sendRecordUseCase := usecases.NewSendRecordUseCase(stateStore, log, cfg.SendRecordRetention)
+
+	// Usar Postgres para las plantillas si está configurado
+	var templateRepository templates.Repository = templates.NewStoreRepository(stateStore)
+	var postgresTemplates *templates.PostgresRepository
+	if cfg.PostgresURL != "" {
+		migrateCtx, cancelMigrate := context.WithTimeout(bgCtx, 10*time.Second)
+		postgresTemplates, err = templates.NewPostgresRepository(migrateCtx, cfg.PostgresURL)
+		cancelMigrate()
+		if err != nil {
+			log.Fatal("Failed to initialize template repository", zap.Error(err))
+		}
+		templateRepository = postgresTemplates
+		log.Info("Template repository backed by Postgres")
+	}
+
+	// Guardar el historial reciente de cada conversación
+	historyUseCase := usecases.NewHistoryUseCase(stateStore, log, cfg.HistoryRetention, cfg.HistoryStoreBodies)
+
+	// Registrar el ciclo de vida de cada reserva con su trace ID
+	traceUseCase := usecases.NewTraceUseCase(stateStore, log, cfg.BookingTraceRetention)
+
+	// Medir la tasa de entrega por número y global, con alertas bajo el umbral
+	deliveryHealthUseCase := usecases.NewDeliveryHealthUseCase(
+		log,
+		cfg.DeliverySLAWindow,
+		cfg.DeliverySLATimeout,
+		cfg.DeliverySLAThreshold,
+		cfg.DeliverySLAMinSamples,
+		newDeliveryAlert(cfg, log),
+	)
+
+	// Agrupar los acuses de recibo para reducir las escrituras al almacén
+	var sendRecorder whatsapp.SendRecorder = whatsapp.MultiSendRecorder(sendRecordUseCase, historyUseCase, traceUseCase, deliveryHealthUseCase)
+	var receiptBatcher *whatsapp.ReceiptBatcher
+	if cfg.ReceiptFlushInterval > 0 {
+		receiptBatcher = whatsapp.NewReceiptBatcher(sendRecorder, log, cfg.ReceiptBatchSize)
+		receiptBatcher.Start(bgCtx, cfg.ReceiptFlushInterval)
+		sendRecorder = receiptBatcher
+	}
+
+	// Abrir el diario de eventos si está habilitado
+	clientOptions := []whatsapp.ClientOption{
+		whatsapp.WithLogger(log),
+		whatsapp.WithDefaultLinkPreview(cfg.MessageLinkPreview),
+		whatsapp.WithDefaultDisappearTimer(cfg.MessageDisappearAfter),
+		whatsapp.WithHumanizedTyping(cfg.HumanizedTyping),
+		whatsapp.WithReconnectBackoff(cfg.ReconnectRetry.Backoff()),
+		whatsapp.WithReconnectAlert(newReconnectAlert(cfg, log)),
+		whatsapp.WithSendRecorder(sendRecorder),
+		whatsapp.WithOfflineFlushInterval(cfg.OfflineFlushInterval),
+		whatsapp.WithAccountID(cfg.WhatsAppAccountID),
+		whatsapp.WithMaxInboundAge(cfg.MaxInboundAge),
+		whatsapp.WithWhatsmeowLog(cfg.WhatsmeowLogLevel),
+		// Reencolar los envíos a destinatarios momentáneamente inalcanzables
+		whatsapp.WithRecipientRetry(cfg.RecipientRetry.Backoff()),
+		// Registrar cada cambio de estado de la conexión
+		whatsapp.WithConnectionStateHook(whatsapp.LogConnectionState(log)),
+	}
+	var journal *whatsapp.Journal
+	if cfg.EventJournalPath != "" {
+		journal, err = whatsapp.OpenJournal(cfg.EventJournalPath)
+		if err != nil {
+			log.Fatal("Failed to open event journal", zap.Error(err))
+		}
+		clientOptions = append(clientOptions, whatsapp.WithJournal(journal))
+		log.Info("Event journal enabled", zap.String("path", cfg.EventJournalPath))
+	}
+	if cfg.ContactCacheTTL > 0 {
+		clientOptions = append(clientOptions, whatsapp.WithContactCache(stateStore, cfg.ContactCacheTTL))
+	}
+	if cfg.DryRun {
+		clientOptions = append(clientOptions, whatsapp.WithDryRun(cfg.DryRunLatency, cfg.DryRunFailureRate))
+	}
+	if cfg.InboundMediaDir != "" {
+		// Descargar solo los adjuntos permitidos y dentro del tamaño máximo
+		clientOptions = append(clientOptions, whatsapp.WithMediaDownload(cfg.InboundMediaDir, cfg.InboundAllowedMedia, cfg.InboundMediaMaxSize))
+	}
+	if cfg.StoreIntegrityCheck {
+		// Verificar la base de la sesión antes de abrirla
+		clientOptions = append(clientOptions, whatsapp.WithStoreIntegrityCheck(cfg.StoreRecoverCorrupt))
+	}
+	if cfg.SendInterval > 0 {
+		// Encolar los envíos por prioridad respetando el ritmo configurado
+		clientOptions = append(clientOptions, whatsapp.WithSendQueue(cfg.SendInterval, cfg.SendQueueMaxWait))
+	}
+
 	// Inicializar el cliente de WhatsApp
-	whatsappClient, err := whatsapp.NewClient("./whatsapp.db", whatsapp.WithLogger(log))
+	whatsappClient, err := whatsapp.NewClient("./whatsapp.db", clientOptions...)
 	if err != nil {
 		log.Fatal("Failed to initialize WhatsApp client", zap.Error(err))
 	}
 
-	// Conectar el cliente de WhatsApp
-	if err := whatsappClient.Connect(); err != nil {
+	// Registrar cada mensaje saliente antes de enviarlo
+	whatsappClient.OnBeforeSend(func(msg *whatsapp.OutboundMessage) error {
+		log.Debug("Outbound message composed",
+			zap.String("to", msg.To.String()),
+			zap.Any("metadata", msg.Metadata))
+		return nil
+	})
+
+	// Notificar los eventos de conexión a sistemas externos si está configurado
+	if hook := newConnectionEvents(bgCtx, cfg, log, whatsappClient); hook != nil {
+		whatsappClient.OnConnectionStateChange(hook)
+	}
+
+	// Conectar el cliente de WhatsApp y esperar a que esté listo
+	connectCtx, cancelConnect := context.WithTimeout(context.Background(), cfg.WhatsAppConnectTimeout)
+	err = whatsappClient.ConnectAndWait(connectCtx)
+	cancelConnect()
+	if errors.Is(err, whatsapp.ErrConnectTimeout) {
+		log.Warn("WhatsApp connection not ready yet, continuing startup", zap.Error(err))
+	} else if err != nil {
 		log.Fatal("Failed to connect WhatsApp client", zap.Error(err))
 	}
 
 	// Inicializar el caso de uso de autenticación
-	authUseCase := usecases.NewWhatsAppAuthUseCase(
+	authOptions := []usecases.WhatsAppAuthUseCaseOption{
+		usecases.WithQRTimeout(5 * time.Minute),
+		usecases.WithQRSize(256),
+		usecases.WithTokenStore(stateStore),
+		usecases.WithQRTokenTTL(cfg.QRTokenTTL),
+	}
+
+	// Auditar los intentos de QR y vinculación
+	var authAttemptUseCase *usecases.AuthAttemptUseCase
+	if cfg.AuthAttemptLog {
+		authAttemptUseCase = usecases.NewAuthAttemptUseCase(stateStore, log, cfg.AuthAttemptRetention)
+		authAttemptUseCase.Watch(whatsappClient)
+		authOptions = append(authOptions, usecases.WithAuthAttempts(authAttemptUseCase))
+	}
+	authUseCase := usecases.NewWhatsAppAuthUseCase(whatsappClient, log, authOptions...)
+
+	// Mantener un QR vigente mientras no haya sesión (modo kiosco)
+	if cfg.QRAutoRefresh && !whatsappClient.IsLoggedIn() {
+		authUseCase.StartQRRefresh(bgCtx)
+	}
+
+	// Si la sesión deja de ser válida, regenerar el QR cuando esté habilitado
+	whatsappClient.OnSessionInvalid(func() {
+		log.Error("WhatsApp session needs re-authentication", zap.String("replica_id", cfg.ReplicaID))
+		if cfg.QRAutoRefresh {
+			authUseCase.StartQRRefresh(bgCtx)
+		}
+	})
+
+	// Enrutar los envíos por cuenta para evitar mezclar números entre clientes
+	clientManager := whatsapp.NewClientManager(cfg.WhatsAppAccountID)
+	clientManager.Register(cfg.WhatsAppAccountID, whatsappClient)
+	// Cada cuenta completa los números locales con el país de su cliente
+	for accountID, region := range cfg.AccountPhoneRegions {
+		clientManager.SetPhoneRegion(accountID, region)
+	}
+
+	// Cargar las reglas de palabras clave para clasificar las respuestas
+	intentRules, err := usecases.LoadIntentRules(cfg.IntentRulesFile)
+	if err != nil {
+		log.Fatal("Failed to load intent rules", zap.Error(err))
+	}
+
+	// Registrar las bajas (STOP) y bloquear los envíos no transaccionales
+	// durante el periodo de espera posterior
+	// Usa el almacén durable: si Redis falla, el marketing se bloquea y una
+	// baja que no se pudo guardar se informa como error en vez de confirmarse
+	consentUseCase := usecases.NewConsentUseCase(
 		whatsappClient,
+		durableStore,
 		log,
-		usecases.WithQRTimeout(5*time.Minute),
-		usecases.WithQRSize(256),
+		cfg.OptOutCooldown,
+		cfg.OptOutAllowTransactional,
+		cfg.OptOutKeywords,
+		cfg.OptInKeywords,
+		cfg.DefaultPhoneRegion,
+		cfg.DefaultLocale,
 	)
+	consentUseCase.Guard(whatsappClient)
+
+	// Marcar los números que WhatsApp rechaza y no volver a enviarles
+	deliverabilityUseCase := usecases.NewDeliverabilityUseCase(stateStore, log, cfg.DefaultPhoneRegion)
+	deliverabilityUseCase.Guard(whatsappClient)
 
 	// Inicializar el caso de uso de reservas
-	bookingUseCase := usecases.NewBookingUseCase(whatsappClient, log)
+	bookingOptions := []usecases.BookingUseCaseOption{
+		usecases.WithEmoji(cfg.MessageEmoji),
+		usecases.WithPhoneRegion(cfg.DefaultPhoneRegion),
+		usecases.WithBookingStore(stateStore),
+		usecases.WithClientManager(clientManager),
+		usecases.WithConfirmationDeadline(cfg.BookingConfirmationDeadline),
+		usecases.WithExpiryMessage(cfg.BookingExpiryMessage),
+		usecases.WithTimezone(cfg.BookingTimezone),
+		// Las plantillas reply_<estado> reemplazan las respuestas automáticas
+		usecases.WithReplyTemplates(templateRepository),
+		usecases.WithMaxBodyLength(cfg.MaxInboundBodyLen, cfg.MaxInboundBodyHardLen),
+		usecases.WithDefaultLocale(cfg.DefaultLocale),
+		usecases.WithLanguageThreshold(cfg.LanguageThreshold),
+		usecases.WithTrace(traceUseCase),
+		// Reenviar la respuesta guardada cuando se repite un Idempotency-Key
+		usecases.WithIdempotencyWindow(cfg.BookingIdempotencyWindow),
+		usecases.WithIntentRules(intentRules),
+		usecases.WithConsent(consentUseCase),
+		usecases.WithFallbackReplies(
+			usecases.FallbackReply{
+				Disabled: !cfg.NoBookingReply,
+				Text:     cfg.NoBookingReplyText,
+				Locale:   cfg.FallbackReplyLocale,
+			},
+			usecases.FallbackReply{
+				Disabled: !cfg.AmbiguousReply,
+				Text:     cfg.AmbiguousReplyText,
+				Locale:   cfg.FallbackReplyLocale,
+			},
+		),
+		// Acusar recibo de los adjuntos en lugar de la respuesta de respaldo
+		usecases.WithMediaReply(usecases.FallbackReply{
+			Disabled: !cfg.MediaReply,
+			Text:     cfg.MediaReplyText,
+			Locale:   cfg.FallbackReplyLocale,
+		}),
+	}
+	// Reintentar los callbacks al integrador con el backoff configurado
+	callbackRetry := webhook.WithRetry(cfg.CallbackRetry.Backoff())
+	if cfg.BookingCallbackURL != "" {
+		bookingOptions = append(bookingOptions, usecases.WithResponseCallback(webhook.NewNotifier(cfg.BookingCallbackURL, callbackRetry)))
+	}
+	if cfg.ReminderPollInterval > 0 {
+		// Los recordatorios viven en un sorted set de Redis y sobreviven a reinicios
+		bookingOptions = append(bookingOptions, usecases.WithReminders(redisClient))
+	}
+	if cfg.ReplyMode == "external" {
+		// El integrador recibe la intención y envía la respuesta por su cuenta
+		bookingOptions = append(bookingOptions, usecases.WithExternalReply(webhook.NewNotifier(cfg.IntentWebhookURL, callbackRetry)))
+	}
+	bookingUseCase := usecases.NewBookingUseCase(whatsappClient, log, bookingOptions...)
 
-	// Registrar el manejador de mensajes de WhatsApp
-	whatsappClient.AddEventHandler(func(evt interface{}) {
-		// Verificar si es un mensaje de WhatsApp
-		if msg, ok := evt.(*whatsapp.WhatsAppMessage); ok {
-			log.Info("Procesando mensaje de WhatsApp en el manejador principal",
-				zap.String("from", msg.From),
-				zap.String("body", msg.Body))
+	// Expirar las reservas sin confirmar una vez vencido el plazo
+	bookingUseCase.StartExpiry(bgCtx, 30*time.Second)
 
-			// Procesar el mensaje con el caso de uso de reservas
-			_, err := bookingUseCase.ProcessIncomingMessage(msg.From, msg.Body)
-			if err != nil {
-				log.Error("Error al procesar mensaje en el manejador principal", zap.Error(err))
-			}
+	// Enviar los recordatorios de citas cuando llega su hora
+	bookingUseCase.StartReminders(bgCtx, cfg.ReminderPollInterval)
+
+	// Publicar la salud de la sesión en Redis
+	sessionHealthUseCase := usecases.NewSessionHealthUseCase(
+		whatsappClient,
+		redisClient,
+		log,
+		cfg.ReplicaID,
+		cfg.SessionHealthInterval,
+	)
+	sessionHealthUseCase.Start(bgCtx)
+
+	// Depurar periódicamente los registros de envío antiguos
+	sendRecordUseCase.Start(bgCtx, time.Hour)
+
+	// Contar los envíos rechazados y los que no reciben acuse a tiempo
+	deliveryHealthUseCase.Watch(whatsappClient)
+	deliveryHealthUseCase.Start(bgCtx, time.Minute)
+
+	// Procesar un mensaje entrante con el caso de uso de reservas, que lo cita al responder
+	processInbound := func(ctx context.Context, msg *whatsapp.WhatsAppMessage) {
+		if _, err := bookingUseCase.ProcessIncomingMessage(ctx, msg); err != nil {
+			log.Error("Error al procesar mensaje en el manejador principal", zap.Error(err))
 		}
-	})
+	}
+
+	// Durante el mantenimiento los mensajes entrantes se difieren hasta salir de él
+	maintenanceUseCase := usecases.NewMaintenanceUseCase(stateStore, log, processInbound)
+
+	// Registrar el manejador de mensajes de WhatsApp; los mensajes recibidos
+	// directamente por whatsmeow se procesan sin pasar por /webhook
+	var inboundDedup *usecases.InboundDedup
+	if cfg.InboundSource == "both" {
+		inboundDedup = usecases.NewInboundDedup(stateStore, log)
+	}
+	whatsappClient.AddNamedEventHandler("inbound", newInboundHandler(cfg, log, historyUseCase, maintenanceUseCase, inboundDedup, processInbound))
 
 	// Configurar el router Gin
 	router := gin.Default()
 
+	// Propagar el ID de solicitud a callbacks y registros de envío
+	requestid.SetHeader(cfg.RequestIDHeader)
+	router.Use(handlers.RequestIDMiddleware())
+
+	// Continuar la traza del llamador para enlazar los spans de Redis
+	if cfg.RedisTracing {
+		router.Use(handlers.TracingMiddleware())
+	}
+
 	// Configurar CORS
-	router.Use(cors.New(cors.Config{
-		AllowOrigins:     strings.Split(cfg.CorsAllowedOrigins, ","),
-		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
-		AllowCredentials: true,
-		MaxAge:           12 * time.Hour,
-	}))
-
-	// Agregar endpoint de health check
+	router.Use(handlers.NewCORSMiddleware(cfg))
+
+	// Rechazar los JWT revocados antes de su expiración; sin revocación se
+	// registra igual con una lista nil, ya que las rutas con JWT lo exigen
+	var tokenRevocations *auth.RevocationList
+	if cfg.JWTRevocation {
+		tokenRevocations = auth.NewRevocationList(redisClient)
+	}
+	router.Use(handlers.TokenRevocationMiddleware(tokenRevocations))
+
+	// Rechazar envíos y webhooks mientras el modo mantenimiento esté activo
+	maintenanceHandler := handlers.NewMaintenanceHandler(maintenanceUseCase, log)
+	router.Use(maintenanceHandler.Middleware())
+	maintenanceHandler.RegisterRoutes(router)
+
+	// Limitar los envíos por usuario para que un cliente en un ciclo no
+	// provoque un bloqueo de WhatsApp; el límite se comparte entre réplicas
+	if cfg.RateLimitPerMinute > 0 {
+		router.Use(handlers.RateLimitMiddleware(ratelimit.NewLimiter(redisClient, cfg.RateLimitPerMinute, cfg.RateLimitBurst), log))
+	}
+
+	// Agregar endpoint de liveness, que no revisa las dependencias (ver /health)
 	router.GET("/ping", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"status": "ok"})
 	})
 
+	// Agregar endpoints de salud y readiness
+	healthUseCase := usecases.NewHealthUseCase(whatsappClient, redisClient, authUseCase, log)
+	healthHandler := handlers.NewHealthHandler(healthUseCase, log)
+	healthHandler.RegisterRoutes(router)
+
+	// Exponer las métricas de Prometheus
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
+
 	// Registrar los manejadores HTTP
-	authHandler := handlers.NewAuthHandler(authUseCase, log)
+	var loginOptions []usecases.LoginUseCaseOption
+	if cfg.AuthAPIKey != "" {
+		loginOptions = append(loginOptions, usecases.WithLoginAPIKey(cfg.AuthAPIKey))
+	}
+	if cfg.AuthUsername != "" {
+		loginOptions = append(loginOptions, usecases.WithLoginPassword(cfg.AuthUsername, cfg.AuthPassword))
+	}
+	if tokenRevocations != nil {
+		loginOptions = append(loginOptions, usecases.WithRevocationList(tokenRevocations))
+	}
+	loginUseCase := usecases.NewLoginUseCase(log, loginOptions...)
+	authHandler := handlers.NewAuthHandler(authUseCase, loginUseCase, log)
 	authHandler.RegisterRoutes(router)
 
 	// Registrar el manejador de reservas
 	bookingHandler := handlers.NewBookingHandler(bookingUseCase, log)
 	bookingHandler.RegisterRoutes(router, authHandler)
 
+	// Registrar el manejador de mensajes; las difusiones de prueba solo
+	// llegan a los números de SANDBOX_NUMBERS
+	templateUseCase := usecases.NewTemplateUseCase(templateRepository, whatsappClient, log, cfg.DefaultPhoneRegion)
+	messageUseCase := usecases.NewMessageUseCase(whatsappClient, log, cfg.DefaultPhoneRegion,
+		usecases.WithBulkSend(cfg.BulkMaxRecipients, cfg.BulkSendDelay))
+	broadcastUseCase := usecases.NewBroadcastUseCase(templateUseCase, cfg.SandboxNumbers, log)
+	messageHandler := handlers.NewMessageHandler(messageUseCase, broadcastUseCase, log)
+	messageHandler.RegisterRoutes(router, authHandler)
+
+	// Registrar el manejador de grupos
+	groupUseCase := usecases.NewGroupUseCase(whatsappClient, log, cfg.DefaultPhoneRegion)
+	groupHandler := handlers.NewGroupHandler(groupUseCase, log)
+	groupHandler.RegisterRoutes(router, authHandler)
+
+	// Registrar el manejador de plantillas
+	templateHandler := handlers.NewTemplateHandler(templateUseCase, log)
+	templateHandler.RegisterRoutes(router, authHandler)
+
+	// Registrar el manejador de contactos
+	contactUseCase := usecases.NewContactUseCase(whatsappClient, log, cfg.DefaultPhoneRegion)
+	contactHandler := handlers.NewContactHandler(contactUseCase, consentUseCase, deliverabilityUseCase, log)
+	contactHandler.RegisterRoutes(router)
+
+	// Configurar el manejador de conversaciones
+	conversationHandler := handlers.NewConversationHandler(historyUseCase, log, cfg.DefaultPhoneRegion)
+	conversationHandler.RegisterRoutes(router)
+
 	// Registrar el manejador de webhook para mensajes entrantes
-	webhookHandler := handlers.NewWebhookHandler(bookingUseCase, log)
-	webhookHandler.RegisterRoutes(router)
+	var webhookDispatcher *usecases.WebhookDispatcher
+	if cfg.InboundSource != "direct" {
+		if cfg.WebhookMode == "async" {
+			var dispatcherOptions []usecases.WebhookDispatcherOption
+			if cfg.WebhookDurable {
+				// Guardar cada mensaje hasta procesarlo para no perderlo al reiniciar
+				dispatcherOptions = append(dispatcherOptions, usecases.WithDurableQueue(durableStore, cfg.ReplicaID))
+			}
+			webhookDispatcher = usecases.NewWebhookDispatcher(bookingUseCase, log, cfg.WebhookWorkers, cfg.WebhookQueueSize, dispatcherOptions...)
 
-	// Configurar el servidor HTTP con timeouts
-	server := &http.Server{
-		Addr:         fmt.Sprintf(":%s", cfg.Port),
-		Handler:      router,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-		IdleTimeout:  60 * time.Second,
+			// Reprocesar los mensajes que quedaron en cola en la ejecución anterior
+			replayed, err := webhookDispatcher.Replay(bgCtx)
+			if err != nil {
+				log.Error("Error al reprocesar los webhooks en cola", zap.Error(err))
+			}
+			if replayed > 0 {
+				log.Info("Webhooks en cola reprocesados", zap.Int("count", replayed))
+			}
+			// Reprocesar al conectarse los mensajes que fallaron sin conexión
+			whatsappClient.OnConnectionStateChange(webhookDispatcher.StateHook(bgCtx))
+		}
+		// Verificar la firma de los webhooks salvo que se omita en desarrollo
+		webhookSecret := cfg.WebhookSecret
+		if cfg.WebhookSkipSignature {
+			webhookSecret = ""
+			log.Warn("Webhook signature verification is disabled; anyone who finds /webhook can trigger replies")
+		}
+		webhookHandler := handlers.NewWebhookHandler(bookingUseCase, webhookDispatcher, inboundDedup, historyUseCase, cfg.WebhookMapping, webhookSecret, cfg.DefaultPhoneRegion, log)
+		webhookHandler.RegisterRoutes(router)
+	}
+
+	// Registrar el manejador de administración
+	adminHandler := handlers.NewAdminHandler(sessionHealthUseCase, log)
+	adminHandler.RegisterRoutes(router)
+
+	// Registrar el manejador de auditoría de intentos de QR
+	if authAttemptUseCase != nil {
+		authAttemptHandler := handlers.NewAuthAttemptHandler(authAttemptUseCase, log)
+		authAttemptHandler.RegisterRoutes(router)
 	}
 
+	// Registrar el manejador de salud de entregas
+	deliveryHealthHandler := handlers.NewDeliveryHealthHandler(deliveryHealthUseCase, log)
+	deliveryHealthHandler.RegisterRoutes(router)
+
+	// Registrar el manejador de trabajos programados
+	scheduleHandler := handlers.NewScheduleHandler(bookingUseCase, log, cfg.DefaultPhoneRegion)
+	scheduleHandler.RegisterRoutes(router)
+
+	// Registrar el manejador del estado de las conversaciones
+	conversationStateHandler := handlers.NewConversationStateHandler(bookingUseCase, log)
+	conversationStateHandler.RegisterRoutes(router)
+
+	// Registrar el manejador de reproducción de eventos
+	if journal != nil {
+		replayUseCase := usecases.NewReplayUseCase(whatsappClient, journal, log)
+		replayHandler := handlers.NewReplayHandler(replayUseCase, log)
+		replayHandler.RegisterRoutes(router)
+	}
+
+	// Configurar el servidor HTTP con timeouts
+	server := newServer(cfg, router)
+
 	// Canal para señales de apagado
 	shutdown := make(chan os.Signal, 1)
 	signal.Notify(shutdown, os.Interrupt, syscall.SIGTERM)
@@ -144,8 +561,12 @@ func main() {
 			log.Info("Using alternative port", zap.Int("new_port", portInt))
 		}
 
-		log.Info("Server starting", zap.Int("port", portInt))
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		listener, err := net.Listen("tcp", server.Addr)
+		if err == nil {
+			log.Info("Server starting", zap.Int("port", portInt), zap.Bool("tls", cfg.TLSCertFile != ""))
+			err = serve(server, listener, cfg)
+		}
+		if err != nil && err != http.ErrServerClosed {
 			log.Error("Server failed to start", zap.Error(err))
 			if err.Error() == "listen tcp :"+strconv.Itoa(portInt)+": bind: address already in use" {
 				log.Error("The port is already in use. Please try using a different port by setting the PORT environment variable")
@@ -154,6 +575,18 @@ func main() {
 		}
 	}()
 
+	// Redirigir HTTP a HTTPS en un listener aparte
+	var redirectServer *http.Server
+	if cfg.TLSCertFile != "" && cfg.HTTPRedirect {
+		redirectServer = newRedirectServer(cfg)
+		go func() {
+			log.Info("HTTP redirect listener starting", zap.String("port", cfg.HTTPRedirectPort))
+			if err := redirectServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Error("HTTP redirect listener failed", zap.Error(err))
+			}
+		}()
+	}
+
 	// Esperar señal de apagado
 	<-shutdown
 	log.Info("Server stopping")
@@ -167,10 +600,319 @@ func main() {
 		log.Error("Server forced to shutdown", zap.Error(err))
 	}
 
+	if redirectServer != nil {
+		if err := redirectServer.Shutdown(ctx); err != nil {
+			log.Error("HTTP redirect listener forced to shutdown", zap.Error(err))
+		}
+	}
+
+	// Procesar los mensajes de webhook pendientes
+	if webhookDispatcher != nil {
+		if err := webhookDispatcher.Stop(ctx); err != nil {
+			log.Error("Pending webhook messages were not processed", zap.Error(err))
+		}
+	}
+
+	// Ejecutar los trabajos programados inminentes y dejar el resto
+	// persistido para el próximo arranque
+	drain, err := bookingUseCase.DrainScheduled(ctx, cfg.ScheduleDrainWindow)
+	if err != nil {
+		log.Error("Failed to drain scheduled jobs", zap.Error(err))
+	}
+	log.Info("Scheduled jobs drained",
+		zap.Int("completed", drain.Completed),
+		zap.Int("deferred", len(drain.Deferred)))
+	if len(drain.Deferred) > 0 && cfg.StateStore == "memory" {
+		log.Warn("Deferred scheduled jobs live in the memory state store and will be lost on exit",
+			zap.Int("deferred", len(drain.Deferred)))
+	}
+
+	// Detener los trabajos en segundo plano
+	stopBackground()
+
 	// Desconectar el cliente de WhatsApp
 	if err := whatsappClient.Disconnect(); err != nil {
 		log.Error("Failed to disconnect WhatsApp client", zap.Error(err))
 	}
 
+	// Escribir los acuses de recibo pendientes
+	if receiptBatcher != nil {
+		receiptBatcher.Flush(context.Background())
+	}
+
+	// Cerrar el diario de eventos
+	if journal != nil {
+		if err := journal.Close(); err != nil {
+			log.Error("Failed to close event journal", zap.Error(err))
+		}
+	}
+
+	// Cerrar la conexión a Postgres
+	if postgresTemplates != nil {
+		if err := postgresTemplates.Close(); err != nil {
+			log.Error("Failed to close Postgres connection", zap.Error(err))
+		}
+	}
+
+	// Cerrar el cliente de Redis
+	if err := redisClient.Close(); err != nil {
+		log.Error("Failed to close Redis client", zap.Error(err))
+	}
+
+	// Enviar las trazas pendientes
+	if shutdownTracing != nil {
+		if err := shutdownTracing(ctx); err != nil {
+			log.Error("Failed to flush traces", zap.Error(err))
+		}
+	}
+
 	log.Info("Server stopped")
 }
+
+// newStateStores crea el almacén de estado y su versión durable. Con Redis,
+// el almacén de estado degrada sin fallar cuando Redis no responde; el
+// durable informa los errores, para las colas que necesitan que el remitente
+// reintente y para las funciones que deben fallar cerradas, como las bajas.
+func newStateStores(ctx context.Context, cfg *config.Config, redisClient *redis.Client, log logger.Logger) (store.Store, store.Store) {
+	if cfg.StateStore == "memory" {
+		// El almacén en memoria no expira claves por sí mismo
+		memoryStore := store.NewMemoryStore()
+		memoryStore.StartJanitor(ctx, cfg.StoreCleanupInterval)
+		return memoryStore, memoryStore
+	}
+	redisStore := store.NewRedisStore(redisClient)
+	return store.NewResilientStore(redisStore, log), redisStore
+}
+
+// newInboundHandler crea el manejador de los mensajes recibidos directamente
+// por whatsmeow: los guarda en el historial y los procesa con process, salvo
+// los duplicados, los vacíos, los de grupo sin respuesta automática y los
+// recibidos durante el mantenimiento, que se difieren
+func newInboundHandler(cfg *config.Config, log logger.Logger, historyUseCase *usecases.HistoryUseCase, maintenanceUseCase *usecases.MaintenanceUseCase, inboundDedup *usecases.InboundDedup, process func(context.Context, *whatsapp.WhatsAppMessage)) whatsapp.EventHandler {
+	return func(evt interface{}) {
+		if cfg.InboundSource == "webhook" {
+			return
+		}
+
+		// Verificar si es un mensaje de WhatsApp
+		if msg, ok := evt.(*whatsapp.WhatsAppMessage); ok {
+			body, _ := utils.Truncate(msg.Body, cfg.MaxInboundBodyLen)
+			log.Info("Procesando mensaje de WhatsApp en el manejador principal",
+				zap.String("from", msg.From),
+				zap.String("body", body))
+
+			ctx := requestid.NewContext(context.Background(), requestid.New())
+			if inboundDedup != nil && !msg.Replayed && !inboundDedup.Claim(ctx, msg.ID, "direct") {
+				return
+			}
+			if err := historyUseCase.RecordInbound(ctx, msg, time.Now()); err != nil {
+				log.Warn("Error al guardar el mensaje en el historial", zap.Error(err))
+			}
+
+			// Los mensajes antiguos, p. ej. de la sincronización del historial,
+			// quedan en el historial pero no se responden
+			if msg.Stale {
+				log.Info("Mensaje antiguo, sin respuesta automática", zap.String("message_id", msg.ID))
+				return
+			}
+
+			// Los mensajes sin texto ni adjunto quedan en el historial pero no se responden
+			if msg.Body == "" && msg.Media == nil {
+				log.Info("Mensaje sin texto, sin respuesta automática", zap.String("message_id", msg.ID))
+				return
+			}
+
+			// No responder automáticamente en grupos salvo que esté habilitado
+			if msg.IsGroup && !cfg.GroupAutoReply {
+				log.Info("Mensaje de grupo sin respuesta automática", zap.String("message_id", msg.ID))
+				return
+			}
+
+			if maintenanceUseCase.Enabled(ctx) {
+				if err := maintenanceUseCase.Defer(ctx, msg); err != nil {
+					log.Error("Error al diferir el mensaje durante el mantenimiento", zap.Error(err))
+				}
+				return
+			}
+
+			process(ctx, msg)
+		}
+	}
+}
+
+// newReconnectAlert crea el hook que se ejecuta cuando se agotan los
+// intentos de reconexión
+func newReconnectAlert(cfg *config.Config, log logger.Logger) whatsapp.ReconnectAlertFunc {
+	var notifier *webhook.Notifier
+	if cfg.AlertWebhookURL != "" {
+		notifier = webhook.NewNotifier(cfg.AlertWebhookURL, webhook.WithRetry(cfg.CallbackRetry.Backoff()))
+	}
+
+	return func(attempts int, err error) {
+		log.Error("WhatsApp reconnect failed, manual intervention required",
+			zap.String("replica_id", cfg.ReplicaID),
+			zap.Int("attempts", attempts),
+			zap.Error(err))
+
+		if notifier == nil {
+			return
+		}
+
+		// Dar margen a los reintentos de la alerta
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		if err := notifier.Notify(ctx, "reconnect_failed", gin.H{
+			"replica_id": cfg.ReplicaID,
+			"attempts":   attempts,
+			"error":      err.Error(),
+		}); err != nil {
+			log.Error("Failed to send reconnect alert", zap.Error(err))
+		}
+	}
+}
+
+// newDeliveryAlert crea la alerta que se dispara cuando la tasa de entrega de
+// un número, o la global, cae bajo el umbral
+func newDeliveryAlert(cfg *config.Config, log logger.Logger) usecases.DeliveryAlertFunc {
+	var notifier *webhook.Notifier
+	if cfg.AlertWebhookURL != "" {
+		notifier = webhook.NewNotifier(cfg.AlertWebhookURL, webhook.WithRetry(cfg.CallbackRetry.Backoff()))
+	}
+
+	return func(scope string, rate usecases.DeliveryRate) {
+		if notifier == nil {
+			return
+		}
+
+		// Dar margen a los reintentos de la alerta
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		if err := notifier.Notify(ctx, "delivery_rate_low", gin.H{
+			"replica_id": cfg.ReplicaID,
+			"scope":      scope,
+			"delivered":  rate.Delivered,
+			"failed":     rate.Failed,
+			"rate":       rate.Rate,
+			"threshold":  cfg.DeliverySLAThreshold,
+		}); err != nil {
+			log.Error("Failed to send delivery alert", zap.Error(err))
+		}
+	}
+}
+
+// connectionEventQueue es cuántos eventos de conexión esperan su envío
+const connectionEventQueue = 64
+
+// newConnectionEvents crea el hook que publica en EVENT_WEBHOOK_URL cuando la
+// sesión se conecta, se desconecta o se cierra, firmado como los webhooks
+// entrantes. Un único worker envía los eventos en orden, con reintentos
+// acotados, para que un "disconnected" no llegue después del "connected" que
+// lo siguió; cada evento lleva además un número de secuencia creciente. Sin
+// URL devuelve nil.
+func newConnectionEvents(ctx context.Context, cfg *config.Config, log logger.Logger, client *whatsapp.Client) whatsapp.ConnectionStateHook {
+	if cfg.EventWebhookURL == "" {
+		return nil
+	}
+	notifier := webhook.NewNotifier(cfg.EventWebhookURL,
+		webhook.WithRetry(cfg.CallbackRetry.Backoff()),
+		webhook.WithSecret(cfg.WebhookSecret))
+
+	queue := make(chan gin.H, connectionEventQueue)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case payload := <-queue:
+				// Dar margen a los reintentos del evento
+				postCtx, cancel := context.WithTimeout(ctx, time.Minute)
+				if err := notifier.Post(postCtx, payload); err != nil {
+					log.Error("Failed to send connection event",
+						zap.Any("event", payload["event"]),
+						zap.Error(err))
+				}
+				cancel()
+			}
+		}
+	}()
+
+	// Recordar el último número conocido, que ya no está disponible tras
+	// cerrar la sesión
+	var mu sync.Mutex
+	var phone string
+	var sequence int64
+
+	return func(_, state whatsapp.ConnectionState) {
+		switch state {
+		case whatsapp.StateConnected, whatsapp.StateDisconnected, whatsapp.StateLoggedOut:
+		default:
+			return
+		}
+
+		// Encolar con el lock tomado, para que el orden de la cola sea el de
+		// la secuencia
+		mu.Lock()
+		defer mu.Unlock()
+		if current := client.GetPhoneNumber(); current != "" {
+			phone = current
+		}
+		sequence++
+		payload := gin.H{
+			"event":     string(state),
+			"phone":     phone,
+			"timestamp": time.Now(),
+			"sequence":  sequence,
+		}
+
+		// El hook no debe bloquear el manejo de eventos
+		select {
+		case queue <- payload:
+		default:
+			log.Error("Connection event queue full, dropping event",
+				zap.String("event", string(state)),
+				zap.Int64("sequence", sequence))
+		}
+	}
+}
+
+// serve atiende las peticiones del listener, con HTTPS (y HTTP/2) si hay
+// certificado configurado
+func serve(server *http.Server, listener net.Listener, cfg *config.Config) error {
+	if cfg.TLSCertFile != "" {
+		return server.ServeTLS(listener, cfg.TLSCertFile, cfg.TLSKeyFile)
+	}
+	return server.Serve(listener)
+}
+
+// newServer crea el servidor HTTP de la API con sus timeouts. Las respuestas
+// que se transmiten por más tiempo, como el stream del QR, quitan su propio
+// límite de escritura
+func newServer(cfg *config.Config, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:         fmt.Sprintf(":%s", cfg.Port),
+		Handler:      handler,
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+		IdleTimeout:  60 * time.Second,
+	}
+}
+
+// newRedirectServer crea el servidor que redirige las peticiones HTTP al
+// puerto HTTPS del servicio
+func newRedirectServer(cfg *config.Config) *http.Server {
+	return &http.Server{
+		Addr: fmt.Sprintf(":%s", cfg.HTTPRedirectPort),
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			host := r.Host
+			if h, _, err := net.SplitHostPort(r.Host); err == nil {
+				host = h
+			}
+			if cfg.Port != "443" {
+				host = net.JoinHostPort(host, cfg.Port)
+			}
+			http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+		}),
+		ReadTimeout:  5 * time.Second,
+		WriteTimeout: 5 * time.Second,
+	}
+}
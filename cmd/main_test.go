@@ -0,0 +1,545 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	handlers "github.com/pabbloacevedog/whatspp-service-glidpa/internal/handlers/http"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/internal/usecases"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/config"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/redis"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/store"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/webhook"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/whatsapp"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	"google.golang.org/protobuf/proto"
+)
+
+// writeTestCertificate escribe un certificado autofirmado para 127.0.0.1 y
+// su clave, y devuelve sus rutas y el certificado
+func writeTestCertificate(t *testing.T) (string, string, *x509.Certificate) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate() error = %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalECPrivateKey() error = %v", err)
+	}
+
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600)
+	return certFile, keyFile, cert
+}
+
+// startServer atiende un manejador que responde con el protocolo de la
+// petición y devuelve la dirección en que escucha
+func startServer(t *testing.T, cfg *config.Config) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	})}
+	done := make(chan error, 1)
+	go func() { done <- serve(server, listener, cfg) }()
+	t.Cleanup(func() {
+		server.Close()
+		if err := <-done; !errors.Is(err, http.ErrServerClosed) {
+			t.Errorf("serve() error = %v", err)
+		}
+	})
+	return listener.Addr().String()
+}
+
+func TestServeTLSWithHTTP2(t *testing.T) {
+	certFile, keyFile, cert := writeTestCertificate(t)
+	addr := startServer(t, &config.Config{TLSCertFile: certFile, TLSKeyFile: keyFile})
+
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{RootCAs: roots},
+		ForceAttemptHTTP2: true,
+	}}
+	resp, err := client.Get("https://" + addr + "/health")
+	if err != nil {
+		t.Fatalf("GET over TLS error = %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.TLS == nil || resp.ProtoMajor != 2 {
+		t.Errorf("response over %s (TLS %v), want HTTP/2 over TLS", resp.Proto, resp.TLS != nil)
+	}
+
+	// El puerto TLS rechaza HTTP sin cifrar
+	if resp, err := http.Get("http://" + addr + "/health"); err == nil {
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("plain HTTP on the TLS port: status = %d, want 400", resp.StatusCode)
+		}
+	}
+}
+
+func TestServePlainHTTPWithoutCertificate(t *testing.T) {
+	addr := startServer(t, &config.Config{})
+
+	resp, err := http.Get("http://" + addr + "/health")
+	if err != nil {
+		t.Fatalf("GET error = %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.TLS != nil || resp.ProtoMajor != 1 {
+		t.Errorf("response over %s, want plain HTTP/1.1", resp.Proto)
+	}
+}
+
+func TestRedirectServer(t *testing.T) {
+	tests := []struct {
+		port string
+		want string
+	}{
+		{port: "443", want: "https://reservas.example.com/booking/1?lang=es"},
+		{port: "8443", want: "https://reservas.example.com:8443/booking/1?lang=es"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.port, func(t *testing.T) {
+			server := newRedirectServer(&config.Config{Port: tt.port, HTTPRedirectPort: "80"})
+			req := httptest.NewRequest(http.MethodGet, "http://reservas.example.com:80/booking/1?lang=es", nil)
+			rec := httptest.NewRecorder()
+			server.Handler.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != tt.want {
+				t.Errorf("redirect = %d %s, want 301 %s", rec.Code, rec.Header().Get("Location"), tt.want)
+			}
+			if server.Addr != ":80" {
+				t.Errorf("Addr = %q, want :80", server.Addr)
+			}
+		})
+	}
+}
+
+// newDryRunClient crea un cliente de WhatsApp en modo de prueba sobre una
+// base temporal, que envía sin contactar a WhatsApp
+func newDryRunClient(t *testing.T, options ...whatsapp.ClientOption) *whatsapp.Client {
+	t.Helper()
+	options = append([]whatsapp.ClientOption{whatsapp.WithLogger(logger.NewNop()), whatsapp.WithDryRun(0, 0)}, options...)
+	client, err := whatsapp.NewClient("file:"+t.TempDir()+"/whatsapp.db?_foreign_keys=on", options...)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	t.Cleanup(func() { client.Disconnect() })
+	return client
+}
+
+// inboundEvent crea un mensaje de texto recibido por whatsmeow
+func inboundEvent(id, text string) *events.Message {
+	jid := types.NewJID("56961234567", types.DefaultUserServer)
+	return &events.Message{
+		Info: types.MessageInfo{
+			MessageSource: types.MessageSource{Chat: jid, Sender: jid},
+			ID:            id,
+			Timestamp:     time.Now(),
+		},
+		Message: &waE2E.Message{Conversation: proto.String(text)},
+	}
+}
+
+func TestInboundEventIsProcessed(t *testing.T) {
+	tests := []struct {
+		name          string
+		inboundSource string
+		want          int
+	}{
+		{name: "direct", inboundSource: "direct", want: 1},
+		{name: "webhook only", inboundSource: "webhook", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newDryRunClient(t)
+			replies := make(chan *whatsapp.OutboundMessage, 4)
+			client.OnBeforeSend(func(msg *whatsapp.OutboundMessage) error {
+				replies <- msg
+				return nil
+			})
+
+			stateStore := store.NewMemoryStore()
+			bookingUseCase := usecases.NewBookingUseCase(client, logger.NewNop(), usecases.WithBookingStore(stateStore))
+			processed := make(chan string, 4)
+			process := func(ctx context.Context, msg *whatsapp.WhatsAppMessage) {
+				if _, err := bookingUseCase.ProcessIncomingMessage(ctx, msg); err != nil {
+					t.Errorf("ProcessIncomingMessage() error = %v", err)
+				}
+				processed <- msg.ID
+			}
+			cfg := &config.Config{InboundSource: tt.inboundSource, MaxInboundBodyLen: 1000}
+			handler := newInboundHandler(cfg, logger.NewNop(),
+				usecases.NewHistoryUseCase(stateStore, logger.NewNop(), time.Hour, false),
+				usecases.NewMaintenanceUseCase(stateStore, logger.NewNop(), process),
+				nil, process)
+
+			// Registrar el manejador dos veces no procesa cada mensaje dos veces
+			if !client.AddNamedEventHandler("inbound", handler) {
+				t.Fatal("AddNamedEventHandler() = false, want the handler added")
+			}
+			if client.AddNamedEventHandler("inbound", handler) {
+				t.Error("AddNamedEventHandler() with a duplicate name = true, want false")
+			}
+
+			client.Replay(inboundEvent("msg-1", "Sí"))
+			for i := 0; i < tt.want; i++ {
+				select {
+				case id := <-processed:
+					if id != "msg-1" {
+						t.Errorf("processed %s, want msg-1", id)
+					}
+				case <-time.After(time.Second):
+					t.Fatal("message not processed")
+				}
+				select {
+				case reply := <-replies:
+					if reply.To.User != "56961234567" {
+						t.Errorf("reply sent to %s, want 56961234567", reply.To)
+					}
+				case <-time.After(time.Second):
+					t.Fatal("message not answered")
+				}
+			}
+			select {
+			case id := <-processed:
+				t.Errorf("%s processed more than %d times", id, tt.want)
+			case <-time.After(100 * time.Millisecond):
+			}
+		})
+	}
+}
+
+func TestStaleMessageIsKeptInHistory(t *testing.T) {
+	client := newDryRunClient(t, whatsapp.WithMaxInboundAge(10*time.Minute))
+	stateStore := store.NewMemoryStore()
+	historyUseCase := usecases.NewHistoryUseCase(stateStore, logger.NewNop(), time.Hour, false)
+	processed := make(chan string, 4)
+	process := func(_ context.Context, msg *whatsapp.WhatsAppMessage) { processed <- msg.ID }
+	cfg := &config.Config{InboundSource: "direct", MaxInboundBodyLen: 1000}
+	client.AddNamedEventHandler("inbound", newInboundHandler(cfg, logger.NewNop(), historyUseCase,
+		usecases.NewMaintenanceUseCase(stateStore, logger.NewNop(), process), nil, process))
+
+	// Un "No" antiguo de la sincronización del historial queda registrado
+	// pero no se procesa
+	stale := inboundEvent("msg-1", "No")
+	stale.Info.Timestamp = time.Now().Add(-2 * 24 * time.Hour)
+	client.Replay(stale)
+	deadline := time.Now().Add(time.Second)
+	for {
+		messages, err := historyUseCase.List(context.Background(), "56961234567", 0, time.Time{})
+		if err != nil {
+			t.Fatalf("List() error = %v", err)
+		}
+		if len(messages) == 1 && messages[0].ID == "msg-1" && messages[0].Direction == usecases.DirectionInbound {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("history = %+v, want the stale message", messages)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case id := <-processed:
+		t.Errorf("stale message %s processed, want it only kept in the history", id)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestInboundBothSourcesDedup(t *testing.T) {
+	client := newDryRunClient(t)
+	stateStore := store.NewMemoryStore()
+	bookingUseCase := usecases.NewBookingUseCase(client, logger.NewNop(), usecases.WithBookingStore(stateStore))
+	var processed []string
+	process := func(ctx context.Context, msg *whatsapp.WhatsAppMessage) {
+		processed = append(processed, msg.ID)
+	}
+	cfg := &config.Config{InboundSource: "both", MaxInboundBodyLen: 1000}
+	dedup := usecases.NewInboundDedup(stateStore, logger.NewNop())
+	direct := newInboundHandler(cfg, logger.NewNop(),
+		usecases.NewHistoryUseCase(stateStore, logger.NewNop(), time.Hour, false),
+		usecases.NewMaintenanceUseCase(stateStore, logger.NewNop(), process),
+		dedup, process)
+	mapping, _ := webhook.Preset(webhook.DefaultMapping)
+	router := gin.New()
+	handlers.NewWebhookHandler(bookingUseCase, nil, dedup, nil, mapping, "", "CL", logger.NewNop()).RegisterRoutes(router)
+	postWebhook := func(id string) string {
+		t.Helper()
+		body := `{"message_id":"` + id + `","from":"56961234567","body":"Sí"}`
+		req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("webhook %s: status = %d, want 200: %s", id, rec.Code, rec.Body)
+		}
+		var response struct {
+			Status string `json:"status"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &response)
+		return response.Status
+	}
+	message := func(id string) *whatsapp.WhatsAppMessage {
+		return &whatsapp.WhatsAppMessage{ID: id, From: "56961234567", Body: "Sí"}
+	}
+
+	// Primero llega el evento directo y el webhook es el duplicado
+	direct(message("msg-1"))
+	if status := postWebhook("msg-1"); status != "duplicate" {
+		t.Errorf("webhook after the direct event: status %q, want duplicate", status)
+	}
+
+	// Primero llega el webhook y el evento directo es el duplicado
+	if status := postWebhook("msg-2"); status == "duplicate" {
+		t.Error("first delivery of msg-2 dropped as a duplicate")
+	}
+	direct(message("msg-2"))
+
+	// Los eventos reproducidos del diario se procesan aunque ya se recibieran
+	replayed := message("msg-1")
+	replayed.Replayed = true
+	direct(replayed)
+
+	if want := []string{"msg-1", "msg-1"}; !slices.Equal(processed, want) {
+		t.Errorf("direct path processed %q, want %q", processed, want)
+	}
+}
+
+func TestGroupMessagesAreNotAnswered(t *testing.T) {
+	tests := []struct {
+		name           string
+		groupAutoReply bool
+		wantReplies    int
+	}{
+		{name: "disabled", wantReplies: 0},
+		{name: "enabled", groupAutoReply: true, wantReplies: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newDryRunClient(t)
+			replies := make(chan *whatsapp.OutboundMessage, 4)
+			client.OnBeforeSend(func(msg *whatsapp.OutboundMessage) error {
+				replies <- msg
+				return nil
+			})
+			stateStore := store.NewMemoryStore()
+			bookingUseCase := usecases.NewBookingUseCase(client, logger.NewNop(), usecases.WithBookingStore(stateStore))
+			process := func(ctx context.Context, msg *whatsapp.WhatsAppMessage) {
+				bookingUseCase.ProcessIncomingMessage(ctx, msg)
+			}
+			history := usecases.NewHistoryUseCase(stateStore, logger.NewNop(), time.Hour, true)
+			cfg := &config.Config{InboundSource: "direct", MaxInboundBodyLen: 1000, GroupAutoReply: tt.groupAutoReply}
+			client.AddNamedEventHandler("inbound", newInboundHandler(cfg, logger.NewNop(), history,
+				usecases.NewMaintenanceUseCase(stateStore, logger.NewNop(), process), nil, process))
+
+			evt := inboundEvent("msg-1", "Sí")
+			evt.Info.Chat = types.NewJID("120363000000000000", types.GroupServer)
+			evt.Info.IsGroup = true
+			client.Replay(evt)
+
+			// El mensaje de grupo queda en el historial aunque no se responda
+			deadline := time.Now().Add(time.Second)
+			for {
+				messages, _ := history.List(context.Background(), "56961234567", 10, time.Time{})
+				if len(messages) == 1 && messages[0].Body == "Sí" {
+					break
+				}
+				if time.Now().After(deadline) {
+					t.Fatalf("history = %+v, want the group message", messages)
+				}
+				time.Sleep(5 * time.Millisecond)
+			}
+
+			got := 0
+			for done := false; !done; {
+				select {
+				case reply := <-replies:
+					got++
+					// La respuesta va al grupo, no al chat privado del remitente
+					if reply.To != evt.Info.Chat {
+						t.Errorf("reply sent to %s, want the group %s", reply.To, evt.Info.Chat)
+					}
+				case <-time.After(100 * time.Millisecond):
+					done = true
+				}
+			}
+			if got != tt.wantReplies {
+				t.Errorf("sent %d replies, want %d", got, tt.wantReplies)
+			}
+		})
+	}
+}
+
+func TestConsentFailsClosedWhileRedisIsDown(t *testing.T) {
+	server := miniredis.RunT(t)
+	redisClient := redis.NewClient(server.Addr())
+	t.Cleanup(func() { redisClient.Close() })
+	client := newDryRunClient(t)
+	stateStore, durableStore := newStateStores(context.Background(), &config.Config{StateStore: "redis"}, redisClient, logger.NewNop())
+	newConsent := func(consentStore store.Store) *usecases.ConsentUseCase {
+		return usecases.NewConsentUseCase(client, consentStore, logger.NewNop(), time.Hour, true,
+			[]string{"STOP"}, []string{"START"}, "CL", "es")
+	}
+	consent := newConsent(durableStore)
+	server.Close()
+	ctx := context.Background()
+
+	// El marketing se bloquea y la baja no se confirma sin guardarla
+	if err := consent.Allow(ctx, "56961234567", whatsapp.PriorityLow); !errors.Is(err, usecases.ErrOptedOut) {
+		t.Errorf("Allow(marketing) error = %v, want %v", err, usecases.ErrOptedOut)
+	}
+	if err := consent.Allow(ctx, "56961234567", whatsapp.PriorityNormal); err != nil {
+		t.Errorf("Allow(transactional) error = %v", err)
+	}
+	if response, err := consent.HandleInbound(ctx, "56961234567", "STOP"); err == nil {
+		t.Errorf("HandleInbound(STOP) = %+v, want the failed write reported", response)
+	}
+
+	// Sobre el almacén que degrada, el marketing saldría a todos
+	if err := newConsent(stateStore).Allow(ctx, "56961234567", whatsapp.PriorityLow); err != nil {
+		t.Errorf("Allow(marketing) on the degraded store error = %v, want it to fail open", err)
+	}
+}
+
+func TestQRStreamOutlivesWriteTimeout(t *testing.T) {
+	router := gin.New()
+	server := httptest.NewUnstartedServer(router)
+	server.Config = newServer(&config.Config{Port: "0"}, router)
+	// El stream sigue abierto hasta pasado el límite de escritura del servidor
+	authUseCase := usecases.NewWhatsAppAuthUseCase(newDryRunClient(t), logger.NewNop(),
+		usecases.WithQRTimeout(server.Config.WriteTimeout+time.Second))
+	router.GET("/auth/qr/stream", handlers.NewAuthHandler(authUseCase, nil, logger.NewNop()).StreamQR)
+	server.Start()
+	t.Cleanup(server.Close)
+
+	start := time.Now()
+	resp, err := http.Get(server.URL + "/auth/qr/stream")
+	if err != nil {
+		t.Fatalf("GET /auth/qr/stream error = %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("reading the stream error = %v after %v", err, time.Since(start))
+	}
+	if !strings.Contains(string(body), "event:"+usecases.QRStreamTimeout) {
+		t.Errorf("stream = %q after %v, want it to end with the QR timeout", body, time.Since(start))
+	}
+}
+
+func TestConnectionEvents(t *testing.T) {
+	const secret = "events-secret"
+	type received struct {
+		body      []byte
+		signature string
+	}
+	posts := make(chan received, 8)
+	var failed atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		// El primer evento falla una vez; el siguiente debe esperar su reintento
+		if failed.CompareAndSwap(false, true) {
+			time.Sleep(20 * time.Millisecond)
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		posts <- received{body: body, signature: r.Header.Get(webhook.SignatureHeader)}
+	}))
+	t.Cleanup(server.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	cfg := &config.Config{
+		EventWebhookURL: server.URL,
+		WebhookSecret:   secret,
+		CallbackRetry:   config.RetryConfig{BaseDelay: time.Millisecond, MaxAttempts: 3},
+	}
+	hook := newConnectionEvents(ctx, cfg, logger.NewNop(), newDryRunClient(t))
+
+	hook(whatsapp.StateConnected, whatsapp.StateDisconnected)
+	// Los estados intermedios no se publican
+	hook(whatsapp.StateDisconnected, whatsapp.StateReconnecting)
+	hook(whatsapp.StateReconnecting, whatsapp.StateConnected)
+
+	for i, want := range []string{"disconnected", "connected"} {
+		var post received
+		select {
+		case post = <-posts:
+		case <-time.After(time.Second):
+			t.Fatalf("evento %d no publicado", i+1)
+		}
+		if !webhook.VerifySignature(secret, post.body, post.signature) {
+			t.Errorf("evento %d con firma %q inválida", i+1, post.signature)
+		}
+		var payload map[string]any
+		if err := json.Unmarshal(post.body, &payload); err != nil {
+			t.Fatalf("Unmarshal() error = %v", err)
+		}
+		if payload["event"] != want || payload["sequence"] != float64(i+1) {
+			t.Errorf("evento %d = %v, want %s con secuencia %d", i+1, payload, want, i+1)
+		}
+		if _, ok := payload["phone"]; !ok {
+			t.Errorf("evento %d sin phone: %v", i+1, payload)
+		}
+		if _, err := time.Parse(time.RFC3339Nano, payload["timestamp"].(string)); err != nil {
+			t.Errorf("evento %d con timestamp inválido: %v", i+1, payload["timestamp"])
+		}
+	}
+	select {
+	case post := <-posts:
+		t.Errorf("evento inesperado: %s", post.body)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Sin URL no hay hook
+	if hook := newConnectionEvents(ctx, &config.Config{}, logger.NewNop(), newDryRunClient(t)); hook != nil {
+		t.Error("newConnectionEvents() sin URL devolvió un hook")
+	}
+}
@@ -0,0 +1,125 @@
+// Command pair vincula el servicio con un número de WhatsApp desde la
+// terminal: muestra el QR, espera a que se escanee y termina.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/internal/usecases"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/config"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/utils"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/whatsapp"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+func main() {
+	dbPath := flag.String("db", "./whatsapp.db", "ruta de la base de datos de la sesión")
+	timeout := flag.Duration("timeout", 5*time.Minute, "tiempo máximo para escanear el QR")
+	flag.Parse()
+
+	if err := run(*dbPath, *timeout); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(dbPath string, timeout time.Duration) error {
+	// Cargar variables de entorno (ignorar error si no existe)
+	_ = godotenv.Load()
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	// Los logs van a stderr; el QR y el resultado a stdout
+	log, err := logger.New(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to initialize logger: %w", err)
+	}
+	defer log.Sync()
+
+	options := []whatsapp.ClientOption{
+		whatsapp.WithLogger(log),
+		whatsapp.WithWhatsmeowLog(cfg.WhatsmeowLogLevel),
+	}
+	if cfg.StoreIntegrityCheck {
+		options = append(options, whatsapp.WithStoreIntegrityCheck(cfg.StoreRecoverCorrupt))
+	}
+	client, err := whatsapp.NewClient(dbPath, options...)
+	if err != nil {
+		return fmt.Errorf("failed to initialize WhatsApp client: %w", err)
+	}
+	defer client.Close()
+
+	// Si ya hay una sesión, no hay nada que vincular
+	if client.IsLoggedIn() {
+		fmt.Printf("Ya vinculado con el número +%s\n", client.GetPhoneNumber())
+		return nil
+	}
+
+	// Escuchar el resultado de la vinculación
+	paired := make(chan string, 1)
+	pairFailed := make(chan error, 1)
+	client.AddEventHandler(func(evt interface{}) {
+		switch v := evt.(type) {
+		case *events.PairSuccess:
+			select {
+			case paired <- v.ID.User:
+			default:
+			}
+		case *events.PairError:
+			select {
+			case pairFailed <- v.Error:
+			default:
+			}
+		}
+	})
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// Reutilizar el bucle que renueva el QR antes de que expire
+	authUseCase := usecases.NewWhatsAppAuthUseCase(client, log, usecases.WithQRTimeout(timeout))
+	authUseCase.StartQRRefresh(ctx)
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	var shown string
+	for {
+		// Mostrar el QR cada vez que se renueva
+		if code, err := authUseCase.GenerateQR(ctx); err == nil && code != shown {
+			shown = code
+			fmt.Print("\nEscanea este código desde WhatsApp > Dispositivos vinculados:\n\n")
+			fmt.Println(utils.RenderQR(code))
+		}
+
+		select {
+		case phone := <-paired:
+			fmt.Printf("Vinculado con el número +%s\n", phone)
+			return client.Disconnect()
+		case err := <-pairFailed:
+			_ = client.Disconnect()
+			return fmt.Errorf("pairing failed: %w", err)
+		case <-ctx.Done():
+			_ = client.Disconnect()
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return errors.New("timed out waiting for the QR code to be scanned")
+			}
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/internal/usecases"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// AdminHandler handles administrative endpoints
+type AdminHandler struct {
+	sessionHealthUseCase *usecases.SessionHealthUseCase
+	logger               logger.Logger
+}
+
+// NewAdminHandler creates a new AdminHandler
+func NewAdminHandler(sessionHealthUseCase *usecases.SessionHealthUseCase, logger logger.Logger) *AdminHandler {
+	return &AdminHandler{
+		sessionHealthUseCase: sessionHealthUseCase,
+		logger:               logger,
+	}
+}
+
+// RegisterRoutes registers the admin routes
+func (h *AdminHandler) RegisterRoutes(router *gin.Engine) {
+	admin := router.Group("/admin", JWTMiddleware())
+	{
+		admin.GET("/sessions", h.GetSessions)
+	}
+}
+
+// GetSessions returns the session health of all replicas
+// @Summary List WhatsApp sessions
+// @Description Returns the session health reported by every live replica
+// @Tags admin
+// @Produce json
+// @Success 200 {array} usecases.SessionHealth "Session health entries"
+// @Failure 401 {object} map[string]string "Error message"
+// @Failure 500 {object} map[string]string "Error message"
+// @Router /admin/sessions [get]
+func (h *AdminHandler) GetSessions(c *gin.Context) {
+	sessions, err := h.sessionHealthUseCase.ListSessions(c.Request.Context())
+	if err != nil {
+		h.logger.Error("Failed to list sessions", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sessions": sessions})
+}
@@ -0,0 +1,41 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/internal/usecases"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+)
+
+func TestGetSessionsRequiresToken(t *testing.T) {
+	token := newTestToken(t)
+	redisClient, _ := newTestRedis(t)
+	sessionHealth := usecases.NewSessionHealthUseCase(newTestClient(t), redisClient, logger.NewNop(), "replica-1", time.Minute)
+	if err := sessionHealth.Publish(context.Background()); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	router := newTestRouter()
+	NewAdminHandler(sessionHealth, logger.NewNop()).RegisterRoutes(router)
+
+	if rec := serve(router, http.MethodGet, "/admin/sessions", "", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("without token: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	if rec := serve(router, http.MethodGet, "/admin/sessions", "", "not-a-token"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("with invalid token: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+
+	rec := serve(router, http.MethodGet, "/admin/sessions", "", token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("with token: status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	var body struct {
+		Sessions []usecases.SessionHealth `json:"sessions"`
+	}
+	decode(t, rec, &body)
+	if len(body.Sessions) != 1 || body.Sessions[0].ReplicaID != "replica-1" {
+		t.Errorf("sessions = %+v, want replica-1", body.Sessions)
+	}
+}
@@ -0,0 +1,73 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/internal/usecases"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// AuthAttemptHandler handles the QR and pairing audit endpoint
+type AuthAttemptHandler struct {
+	attemptUseCase *usecases.AuthAttemptUseCase
+	logger         logger.Logger
+}
+
+// NewAuthAttemptHandler creates a new AuthAttemptHandler
+func NewAuthAttemptHandler(attemptUseCase *usecases.AuthAttemptUseCase, logger logger.Logger) *AuthAttemptHandler {
+	return &AuthAttemptHandler{
+		attemptUseCase: attemptUseCase,
+		logger:         logger,
+	}
+}
+
+// RegisterRoutes registers the auth attempt routes
+func (h *AuthAttemptHandler) RegisterRoutes(router *gin.Engine) {
+	admin := router.Group("/admin", JWTMiddleware())
+	{
+		admin.GET("/auth-attempts", h.ListAttempts)
+	}
+}
+
+// ListAttempts returns the recent QR and pairing attempts
+// @Summary List QR and pairing attempts
+// @Description Returns recent QR generations and pairing attempts, newest first, with a count per outcome
+// @Tags admin
+// @Produce json
+// @Param limit query int false "Maximum attempts to return (default 50)"
+// @Success 200 {object} map[string]interface{} "Attempts and outcome counts"
+// @Failure 400 {object} map[string]string "Error message"
+// @Failure 401 {object} map[string]string "Error message"
+// @Failure 500 {object} map[string]string "Error message"
+// @Router /admin/auth-attempts [get]
+func (h *AuthAttemptHandler) ListAttempts(c *gin.Context) {
+	limit := 50
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return
+		}
+		limit = parsed
+	}
+
+	attempts, err := h.attemptUseCase.List(c.Request.Context(), limit)
+	if err != nil {
+		h.logger.Error("Failed to list auth attempts", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list auth attempts"})
+		return
+	}
+
+	outcomes := make(map[string]int)
+	for _, attempt := range attempts {
+		outcomes[attempt.Outcome]++
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"attempts": attempts,
+		"outcomes": outcomes,
+	})
+}
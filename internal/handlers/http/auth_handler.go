@@ -2,25 +2,31 @@ package http
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/pabbloacevedog/whatspp-service-glidpa/internal/usecases"
 	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/whatsapp"
 	"go.uber.org/zap"
 )
 
 // AuthHandler handles authentication endpoints
 type AuthHandler struct {
-	authUseCase *usecases.WhatsAppAuthUseCase
-	logger      logger.Logger
+	authUseCase  *usecases.WhatsAppAuthUseCase
+	loginUseCase *usecases.LoginUseCase
+	logger       logger.Logger
 }
 
 // NewAuthHandler creates a new AuthHandler
-func NewAuthHandler(authUseCase *usecases.WhatsAppAuthUseCase, logger logger.Logger) *AuthHandler {
+func NewAuthHandler(authUseCase *usecases.WhatsAppAuthUseCase, loginUseCase *usecases.LoginUseCase, logger logger.Logger) *AuthHandler {
 	return &AuthHandler{
-		authUseCase: authUseCase,
-		logger:      logger,
+		authUseCase:  authUseCase,
+		loginUseCase: loginUseCase,
+		logger:       logger,
 	}
 }
 
@@ -28,29 +34,138 @@ func NewAuthHandler(authUseCase *usecases.WhatsAppAuthUseCase, logger logger.Log
 func (h *AuthHandler) RegisterRoutes(router *gin.Engine) {
 	auth := router.Group("/auth")
 	{
-		auth.GET("/qr", h.GetQR)
+		auth.POST("/login", h.Login)
+		auth.POST("/token/revoke", JWTMiddleware(), h.RevokeToken)
+		auth.GET("/qr", h.QRAccessMiddleware(), h.GetQR)
+		auth.GET("/qr.png", h.QRAccessMiddleware(), h.GetQRPNG)
+		auth.GET("/qr/stream", h.QRAccessMiddleware(), h.StreamQR)
+		auth.GET("/qr/status", h.GetQRState)
+		auth.GET("/pair", h.QRAccessMiddleware(), h.GetPairCode)
+		auth.POST("/qr/token", JWTMiddleware(), h.IssueQRToken)
 		auth.GET("/status", h.GetStatus)
-		auth.POST("/logout", h.Logout)
+		auth.POST("/connect", JWTMiddleware(), h.Connect)
+		auth.POST("/logout", JWTMiddleware(), h.Logout)
 	}
 }
 
+// LoginRequest represents the request body for logging in with an API key
+// or a username and password
+type LoginRequest struct {
+	APIKey   string `json:"api_key"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// Login exchanges the configured credentials for a JWT
+// @Summary Log in
+// @Description Returns a JWT for the API key or the username and password configured on the service
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body LoginRequest true "Credentials"
+// @Success 200 {object} usecases.LoginResult "Token"
+// @Failure 400 {object} map[string]string "Error message"
+// @Failure 401 {object} map[string]string "Error message"
+// @Failure 500 {object} map[string]string "Error message"
+// @Router /auth/login [post]
+func (h *AuthHandler) Login(c *gin.Context) {
+	var request LoginRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	result, err := h.loginUseCase.Login(usecases.LoginRequest{
+		APIKey:   request.APIKey,
+		Username: request.Username,
+		Password: request.Password,
+	})
+	if errors.Is(err, usecases.ErrInvalidCredentials) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to log in", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log in"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// RevokeTokenRequest represents the request body for revoking a token
+type RevokeTokenRequest struct {
+	// Token is the token to revoke; empty revokes the caller's own token
+	Token string `json:"token"`
+}
+
+// RevokeToken revokes a JWT before it expires
+// @Summary Revoke a token
+// @Description Revokes the token in the body, or the caller's own token, until it expires (requires JWT_REVOCATION)
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body RevokeTokenRequest false "Token to revoke"
+// @Success 200 {object} map[string]string "Revocation result"
+// @Failure 400 {object} map[string]string "Error message"
+// @Failure 401 {object} map[string]string "Error message"
+// @Failure 501 {object} map[string]string "Error message"
+// @Failure 500 {object} map[string]string "Error message"
+// @Router /auth/token/revoke [post]
+func (h *AuthHandler) RevokeToken(c *gin.Context) {
+	var request RevokeTokenRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+			return
+		}
+	}
+	if request.Token == "" {
+		request.Token = strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	}
+
+	err := h.loginUseCase.Revoke(c.Request.Context(), request.Token, c.GetString("user_id"))
+	switch {
+	case errors.Is(err, usecases.ErrRevocationDisabled):
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Token revocation is disabled"})
+		return
+	case errors.Is(err, usecases.ErrInvalidToken):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		h.logger.Error("Failed to revoke token", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "revoked"})
+}
+
 // GetQR returns a QR code for authentication
 // @Summary Get QR code for authentication
-// @Description Returns an SVG QR code for WhatsApp authentication
+// @Description Returns an SVG QR code for WhatsApp authentication, or with format=json the pairing status with the current code
 // @Tags auth
 // @Produce text/html
+// @Produce json
+// @Param format query string false "json returns the pairing status"
 // @Success 200 {string} string "SVG QR code"
+// @Success 200 {object} usecases.QRStatus "Pairing status with format=json"
 // @Failure 400 {object} map[string]string "Error message"
+// @Failure 408 {object} map[string]string "No QR code received in time"
 // @Failure 500 {object} map[string]string "Error message"
 // @Router /auth/qr [get]
 func (h *AuthHandler) GetQR(c *gin.Context) {
+	if c.Query("format") == "json" {
+		h.getQRStatus(c)
+		return
+	}
+
 	ctx := context.Background()
 
 	// Generate QR code
 	qrCode, err := h.authUseCase.GenerateQR(ctx)
 	if err != nil {
-		h.logger.Error("Failed to generate QR code", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate QR code"})
+		h.qrError(c, err)
 		return
 	}
 
@@ -59,6 +174,189 @@ func (h *AuthHandler) GetQR(c *gin.Context) {
 	c.String(http.StatusOK, qrCode)
 }
 
+// GetQRPNG returns a QR code for authentication as an image
+// @Summary Get QR code image for authentication
+// @Description Returns the QR code for WhatsApp authentication as a PNG of the configured size, ready to show in an img tag
+// @Tags auth
+// @Produce image/png
+// @Success 200 {file} binary "PNG QR code"
+// @Failure 408 {object} map[string]string "No QR code received in time"
+// @Failure 500 {object} map[string]string "Error message"
+// @Router /auth/qr.png [get]
+func (h *AuthHandler) GetQRPNG(c *gin.Context) {
+	image, err := h.authUseCase.GenerateQRPNG(c.Request.Context())
+	if err != nil {
+		h.qrError(c, err)
+		return
+	}
+
+	// Each request may return a new code
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "image/png", image)
+}
+
+// qrError responds to a failed QR generation. Not receiving a code in time
+// is reported as 408 so the UI can request a new one instead of treating it
+// as a server failure.
+func (h *AuthHandler) qrError(c *gin.Context, err error) {
+	if errors.Is(err, usecases.ErrQRTimeout) || errors.Is(err, whatsapp.ErrConnectTimeout) {
+		h.logger.Warn("Timed out generating QR code", zap.Error(err))
+		c.JSON(http.StatusRequestTimeout, gin.H{
+			"status": "timeout",
+			"error":  "No QR code was received in time, request a new one",
+		})
+		return
+	}
+
+	h.logger.Error("Failed to generate QR code", zap.Error(err))
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate QR code"})
+}
+
+// GetQRState returns the state of the QR code without generating one
+// @Summary Get QR code state
+// @Description Reports whether a QR code is pending, cached with the seconds it stays valid, or expired, so a UI can poll before requesting /auth/qr
+// @Tags auth
+// @Produce json
+// @Success 200 {object} usecases.QRState "QR code state"
+// @Router /auth/qr/status [get]
+func (h *AuthHandler) GetQRState(c *gin.Context) {
+	c.JSON(http.StatusOK, h.authUseCase.QRState())
+}
+
+// getQRStatus returns the pairing status, with the QR code while the device
+// is not paired
+func (h *AuthHandler) getQRStatus(c *gin.Context) {
+	status, err := h.authUseCase.QRStatus(c.Request.Context())
+	if err != nil {
+		h.qrError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// StreamQR streams the QR codes as server-sent events until the device is paired
+// @Summary Stream QR codes for authentication
+// @Description Sends a "qr" event with each fresh QR code, "reconnecting" while the connection recovers mid-pairing, "paired" once the device is linked and "timeout" when the QR timeout elapses
+// @Tags auth
+// @Produce text/event-stream
+// @Success 200 {object} usecases.QRStreamEvent "QR stream events"
+// @Router /auth/qr/stream [get]
+func (h *AuthHandler) StreamQR(c *gin.Context) {
+	// The stream stays open up to the QR timeout, past the server's write
+	// timeout
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		h.logger.Warn("Failed to clear the QR stream write deadline", zap.Error(err))
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Status(http.StatusOK)
+
+	err := h.authUseCase.StreamQR(c.Request.Context(), func(event usecases.QRStreamEvent) {
+		c.SSEvent(event.Type, event)
+		c.Writer.Flush()
+	})
+	if errors.Is(err, usecases.ErrQRTimeout) {
+		c.SSEvent(usecases.QRStreamTimeout, gin.H{"error": err.Error()})
+		c.Writer.Flush()
+	} else if err != nil && !errors.Is(err, context.Canceled) {
+		h.logger.Error("QR stream ended with an error", zap.Error(err))
+	}
+}
+
+// GetPairCode returns a code to link the device without scanning the QR
+// @Summary Get a pairing code for authentication
+// @Description Returns an 8-character code to enter on the phone under Linked devices, as an alternative to the QR code
+// @Tags auth
+// @Produce json
+// @Param phone query string true "Phone number in international format"
+// @Success 200 {object} map[string]string "Pairing code"
+// @Failure 400 {object} map[string]string "Error message"
+// @Failure 408 {object} map[string]string "Timed out waiting for the connection"
+// @Failure 409 {object} map[string]string "Error message"
+// @Failure 500 {object} map[string]string "Error message"
+// @Router /auth/pair [get]
+func (h *AuthHandler) GetPairCode(c *gin.Context) {
+	phone := c.Query("phone")
+	if phone == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "phone is required"})
+		return
+	}
+
+	code, err := h.authUseCase.GeneratePairCode(c.Request.Context(), phone)
+	if err != nil {
+		h.pairCodeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": code})
+}
+
+// pairCodeError responds to a failed pairing code request. A connection
+// that was not ready in time is a timeout, as for the QR code.
+func (h *AuthHandler) pairCodeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, whatsapp.ErrInvalidPairPhone):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, whatsapp.ErrAlreadyLoggedIn):
+		c.JSON(http.StatusConflict, gin.H{"error": "Already logged in"})
+	case errors.Is(err, whatsapp.ErrConnectTimeout) || errors.Is(err, context.DeadlineExceeded):
+		h.logger.Warn("Timed out generating pairing code", zap.Error(err))
+		c.JSON(http.StatusRequestTimeout, gin.H{
+			"status": "timeout",
+			"error":  "The connection was not ready in time, request a new pairing code",
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate pairing code"})
+	}
+}
+
+// IssueQRToken issues a one-time token to retrieve the QR code
+// @Summary Issue a QR access token
+// @Description Returns a single-use, short-lived token that grants access to /auth/qr?token=...
+// @Tags auth
+// @Produce json
+// @Success 200 {object} map[string]string "QR token"
+// @Failure 401 {object} map[string]string "Error message"
+// @Failure 500 {object} map[string]string "Error message"
+// @Router /auth/qr/token [post]
+func (h *AuthHandler) IssueQRToken(c *gin.Context) {
+	token, expiresAt, err := h.authUseCase.IssueQRToken(c.Request.Context())
+	if err != nil {
+		h.logger.Error("Failed to issue QR token", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue QR token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"token":      token,
+		"expires_at": expiresAt,
+	})
+}
+
+// QRAccessMiddleware allows access to the QR code with a one-time token in
+// the query string, or with a valid JWT otherwise
+func (h *AuthHandler) QRAccessMiddleware() gin.HandlerFunc {
+	jwtMiddleware := JWTMiddleware()
+	return func(c *gin.Context) {
+		token := c.Query("token")
+		if token == "" {
+			jwtMiddleware(c)
+			return
+		}
+
+		if err := h.authUseCase.ConsumeQRToken(c.Request.Context(), token); err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
 // GetStatus returns the current authentication status
 // @Summary Get authentication status
 // @Description Returns the current WhatsApp authentication status
@@ -71,12 +369,31 @@ func (h *AuthHandler) GetStatus(c *gin.Context) {
 	c.JSON(http.StatusOK, status)
 }
 
+// Connect connects to WhatsApp
+// @Summary Connect to WhatsApp
+// @Description Resets the reconnect counter and connects to WhatsApp, resuming a session that gave up reconnecting
+// @Tags auth
+// @Produce json
+// @Success 200 {object} map[string]string "Success message"
+// @Failure 401 {object} map[string]string "Error message"
+// @Failure 500 {object} map[string]string "Error message"
+// @Router /auth/connect [post]
+func (h *AuthHandler) Connect(c *gin.Context) {
+	if err := h.authUseCase.Connect(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to connect"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Connected successfully"})
+}
+
 // Logout logs out from WhatsApp
 // @Summary Logout from WhatsApp
 // @Description Logs out from WhatsApp and invalidates the session
 // @Tags auth
 // @Produce json
 // @Success 200 {object} map[string]string "Success message"
+// @Failure 401 {object} map[string]string "Error message"
 // @Failure 500 {object} map[string]string "Error message"
 // @Router /auth/logout [post]
 func (h *AuthHandler) Logout(c *gin.Context) {
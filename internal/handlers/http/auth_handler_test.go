@@ -0,0 +1,217 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/internal/usecases"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/auth"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/store"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/whatsapp"
+)
+
+func TestSessionRoutesRequireToken(t *testing.T) {
+	token := newTestToken(t)
+	router := newTestRouter()
+	newTestAuthHandler(newTestClient(t)).RegisterRoutes(router)
+
+	for _, path := range []string{"/auth/connect", "/auth/logout"} {
+		t.Run(path, func(t *testing.T) {
+			if rec := serve(router, http.MethodPost, path, "", ""); rec.Code != http.StatusUnauthorized {
+				t.Errorf("without token: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+			}
+			if rec := serve(router, http.MethodPost, path, "", "not-a-token"); rec.Code != http.StatusUnauthorized {
+				t.Errorf("with invalid token: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+			}
+			if rec := serve(router, http.MethodPost, path, "", token); rec.Code != http.StatusOK {
+				t.Errorf("with token: status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+			}
+		})
+	}
+}
+
+func TestJWTMiddlewareRequiresRevocationMiddleware(t *testing.T) {
+	token := newTestToken(t)
+	router := gin.New()
+	router.GET("/protected", JWTMiddleware(), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	// Without TokenRevocationMiddleware a valid token is not enough
+	if rec := serve(router, http.MethodGet, "/protected", "", token); rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+}
+
+func TestQRAccessMiddleware(t *testing.T) {
+	token := newTestToken(t)
+	authUseCase := usecases.NewWhatsAppAuthUseCase(newTestClient(t), logger.NewNop(), usecases.WithTokenStore(store.NewMemoryStore()))
+	handler := NewAuthHandler(authUseCase, nil, logger.NewNop())
+	router := newTestRouter()
+	router.POST("/auth/qr/token", JWTMiddleware(), handler.IssueQRToken)
+	router.GET("/qr", handler.QRAccessMiddleware(), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	if rec := serve(router, http.MethodPost, "/auth/qr/token", "", ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("issuing without JWT: status = %d, want 401", rec.Code)
+	}
+	rec := serve(router, http.MethodPost, "/auth/qr/token", "", token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("issuing: status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+	var issued struct {
+		Token string `json:"token"`
+	}
+	decode(t, rec, &issued)
+
+	if rec := serve(router, http.MethodGet, "/qr?token="+issued.Token, "", ""); rec.Code != http.StatusOK {
+		t.Errorf("with the QR token: status = %d, want 200", rec.Code)
+	}
+	if rec := serve(router, http.MethodGet, "/qr?token="+issued.Token, "", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("reusing the QR token: status = %d, want 401", rec.Code)
+	}
+	if rec := serve(router, http.MethodGet, "/qr", "", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("without any token: status = %d, want 401", rec.Code)
+	}
+	if rec := serve(router, http.MethodGet, "/qr", "", token); rec.Code != http.StatusOK {
+		t.Errorf("with a JWT: status = %d, want 200", rec.Code)
+	}
+}
+
+func TestQRStatusWhenConnected(t *testing.T) {
+	token := newTestToken(t)
+	router := newTestRouter()
+	newTestAuthHandler(newLoggedInClient(t)).RegisterRoutes(router)
+
+	rec := serve(router, http.MethodGet, "/auth/qr?format=json", "", token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	var status usecases.QRStatus
+	decode(t, rec, &status)
+	if status.Status != usecases.QRStatusConnected || status.Phone != "56961234567" || status.Code != "" {
+		t.Errorf("QR status = %+v, want connected without a code", status)
+	}
+
+	if rec := serve(router, http.MethodGet, "/auth/qr?format=json", "", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("without token: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestRevokeTokenEndToEnd(t *testing.T) {
+	token := newTestToken(t)
+	other, err := auth.GenerateToken("tester")
+	if err != nil {
+		t.Fatalf("GenerateToken() error = %v", err)
+	}
+	redisClient, server := newTestRedis(t)
+	revocations := auth.NewRevocationList(redisClient)
+	loginUseCase := usecases.NewLoginUseCase(logger.NewNop(), usecases.WithRevocationList(revocations))
+	authUseCase := usecases.NewWhatsAppAuthUseCase(newTestClient(t), logger.NewNop())
+
+	router := gin.New()
+	router.Use(TokenRevocationMiddleware(revocations))
+	NewAuthHandler(authUseCase, loginUseCase, logger.NewNop()).RegisterRoutes(router)
+	router.GET("/protected", JWTMiddleware(), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	if rec := serve(router, http.MethodGet, "/protected", "", token); rec.Code != http.StatusOK {
+		t.Fatalf("before revoking: status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+
+	// Revoking another token in the body leaves the caller's token valid
+	if rec := serve(router, http.MethodPost, "/auth/token/revoke", `{"token":"`+other+`"}`, token); rec.Code != http.StatusOK {
+		t.Fatalf("revoking another token: status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+	if rec := serve(router, http.MethodGet, "/protected", "", other); rec.Code != http.StatusUnauthorized {
+		t.Errorf("revoked token: status = %d, want 401", rec.Code)
+	}
+	if rec := serve(router, http.MethodGet, "/protected", "", token); rec.Code != http.StatusOK {
+		t.Errorf("caller's token: status = %d, want 200", rec.Code)
+	}
+
+	// Without a body the caller's own token is revoked
+	if rec := serve(router, http.MethodPost, "/auth/token/revoke", "", token); rec.Code != http.StatusOK {
+		t.Fatalf("revoking own token: status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+	if rec := serve(router, http.MethodGet, "/protected", "", token); rec.Code != http.StatusUnauthorized {
+		t.Errorf("own revoked token: status = %d, want 401", rec.Code)
+	}
+	if rec := serve(router, http.MethodPost, "/auth/token/revoke", "", token); rec.Code != http.StatusUnauthorized {
+		t.Errorf("revoking with a revoked token: status = %d, want 401", rec.Code)
+	}
+
+	// When the revocation list cannot be read, tokens are refused with 503
+	fresh, _ := auth.GenerateToken("tester")
+	server.Close()
+	if rec := serve(router, http.MethodGet, "/protected", "", fresh); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("revocation list down: status = %d, want 503", rec.Code)
+	}
+}
+
+func TestGetPairCode(t *testing.T) {
+	token := newTestToken(t)
+	unpaired := newTestRouter()
+	newTestAuthHandler(newTestClient(t)).RegisterRoutes(unpaired)
+	paired := newTestRouter()
+	newTestAuthHandler(newLoggedInClient(t)).RegisterRoutes(paired)
+
+	tests := []struct {
+		name   string
+		router http.Handler
+		path   string
+		status int
+	}{
+		{name: "missing phone", router: unpaired, path: "/auth/pair", status: http.StatusBadRequest},
+		{name: "local number", router: unpaired, path: "/auth/pair?phone=0961234567", status: http.StatusBadRequest},
+		{name: "already paired", router: paired, path: "/auth/pair?phone=56961234567", status: http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := serve(tt.router, http.MethodGet, tt.path, "", token); rec.Code != tt.status {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+		})
+	}
+
+	if rec := serve(unpaired, http.MethodGet, "/auth/pair?phone=56961234567", "", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("without token: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestPairCodeError(t *testing.T) {
+	h := newTestAuthHandler(nil)
+	tests := []struct {
+		name       string
+		err        error
+		status     int
+		wantStatus string
+	}{
+		{name: "connect timeout", err: fmt.Errorf("%w: %v", whatsapp.ErrConnectTimeout, context.DeadlineExceeded), status: http.StatusRequestTimeout, wantStatus: "timeout"},
+		{name: "deadline exceeded", err: fmt.Errorf("failed to request pairing code: %w", context.DeadlineExceeded), status: http.StatusRequestTimeout, wantStatus: "timeout"},
+		{name: "invalid phone", err: whatsapp.ErrInvalidPairPhone, status: http.StatusBadRequest},
+		{name: "already logged in", err: whatsapp.ErrAlreadyLoggedIn, status: http.StatusConflict},
+		{name: "other error", err: errors.New("websocket closed"), status: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(rec)
+			h.pairCodeError(c, tt.err)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			var body struct {
+				Status string `json:"status"`
+				Error  string `json:"error"`
+			}
+			decode(t, rec, &body)
+			if body.Status != tt.wantStatus || body.Error == "" {
+				t.Errorf("body = %+v, want status %q with an error", body, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -1,7 +1,9 @@
 package http
 
 import (
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/pabbloacevedog/whatspp-service-glidpa/internal/usecases"
@@ -25,12 +27,22 @@ func NewBookingHandler(bookingUseCase *usecases.BookingUseCase, logger logger.Lo
 
 // RegisterRoutes registers the booking routes
 func (h *BookingHandler) RegisterRoutes(router *gin.Engine, authHandler *AuthHandler) {
-	booking := router.Group("/booking")
+	booking := router.Group("/booking", JWTMiddleware())
 	{
 		booking.POST("/confirm", authHandler.AuthMiddleware(), h.ConfirmBooking)
+		booking.POST("/cancel", authHandler.AuthMiddleware(), h.CancelBooking)
+		booking.POST("/reminder", authHandler.AuthMiddleware(), h.ScheduleReminder)
+		booking.PATCH("/:id", authHandler.AuthMiddleware(), h.UpdateBooking)
+		booking.GET("/:id/trace", authHandler.AuthMiddleware(), h.GetTrace)
 	}
 }
 
+// IdempotencyKeyHeader carries the caller's idempotency key on confirmations
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// maxIdempotencyKeyLength is the longest idempotency key accepted
+const maxIdempotencyKeyLength = 255
+
 // BookingRequest represents the request body for confirming a booking
 type BookingRequest struct {
 	BookingID    string `json:"booking_id" binding:"required"`
@@ -41,6 +53,19 @@ type BookingRequest struct {
 	Date         string `json:"date" binding:"required"`
 	EmployeeName string `json:"employee_name" binding:"required"`
 	PhoneNumber  string `json:"phone_number" binding:"required"`
+	Emoji        *bool  `json:"emoji"`
+	// Metadata is returned verbatim in the response callback
+	Metadata map[string]string `json:"metadata"`
+	// AccountID selects the sending business account (the tenant of the
+	// bearer token, or the default, when empty)
+	AccountID string `json:"account_id"`
+	// ConfirmWithin overrides the response deadline, e.g. "2h"
+	ConfirmWithin string `json:"confirm_within"`
+	// DisappearAfter sets the disappearing message timer in seconds,
+	// replacing the account default; 0 sends without a timer
+	DisappearAfter *int `json:"disappear_after"`
+	// Language of the messages (es, en, pt); unsupported ones use Spanish
+	Language string `json:"language"`
 }
 
 // ConfirmBooking sends a confirmation message with booking details
@@ -50,34 +75,270 @@ type BookingRequest struct {
 // @Accept json
 // @Produce json
 // @Param request body BookingRequest true "Booking confirmation request"
+// @Param Idempotency-Key header string false "Repeats of the key return the first response without sending again"
 // @Success 200 {object} usecases.BookingResponse "Success response"
 // @Failure 400 {object} map[string]string "Error message"
+// @Failure 401 {object} map[string]string "Error message"
+// @Failure 403 {object} map[string]string "Error message"
+// @Failure 409 {object} map[string]string "Error message"
+// @Failure 429 {object} map[string]string "Error message"
 // @Failure 500 {object} map[string]string "Error message"
+// @Failure 503 {object} map[string]string "Error message"
 // @Router /booking/confirm [post]
 func (h *BookingHandler) ConfirmBooking(c *gin.Context) {
 	var request BookingRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		invalidSendRequest(c, "Invalid request body: "+err.Error())
+		return
+	}
+
+	idempotencyKey := c.GetHeader(IdempotencyKeyHeader)
+	if len(idempotencyKey) > maxIdempotencyKeyLength {
+		invalidSendRequest(c, "Idempotency-Key must be at most 255 characters")
+		return
+	}
+
+	var deadline time.Duration
+	if request.ConfirmWithin != "" {
+		parsed, err := time.ParseDuration(request.ConfirmWithin)
+		if err != nil || parsed <= 0 {
+			invalidSendRequest(c, "confirm_within must be a positive duration")
+			return
+		}
+		deadline = parsed
+	}
+
+	disappear, ok := disappearAfter(c, request.DisappearAfter)
+	if !ok {
+		return
+	}
+
+	accountID, ok := tenantAccount(c, request.AccountID)
+	if !ok {
+		return
+	}
+
+	// Send confirmation message with booking details, once per idempotency key
+	response, replayed, err := h.bookingUseCase.ConfirmIdempotent(c.Request.Context(), idempotencyKey, usecases.BookingRequest{
+		BookingID:            request.BookingID,
+		ServiceName:          request.ServiceName,
+		UserName:             request.UserName,
+		LocationName:         request.LocationName,
+		StartTime:            request.StartTime,
+		Date:                 request.Date, // Use 'Date' instead of 'date'
+		EmployeeName:         request.EmployeeName,
+		PhoneNumber:          request.PhoneNumber,
+		Emoji:                request.Emoji,
+		Metadata:             request.Metadata,
+		AccountID:            accountID,
+		ConfirmationDeadline: deadline,
+		DisappearAfter:       disappear,
+		Language:             request.Language,
+	})
+
+	if err != nil {
+		sendError(c, h.logger, err, "Failed to send confirmation message")
+		return
+	}
+
+	if replayed {
+		c.Header("Idempotent-Replayed", "true")
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// CancelBookingRequest represents the request body for cancelling a booking
+type CancelBookingRequest struct {
+	BookingID   string `json:"booking_id" binding:"required"`
+	PhoneNumber string `json:"phone_number" binding:"required"`
+	// Reason is included in the notice sent to the customer
+	Reason string `json:"reason"`
+	// AccountID selects the sending business account, as in confirmations
+	AccountID string `json:"account_id"`
+	// Language of the notice when the booking is no longer pending
+	Language string `json:"language"`
+}
+
+// CancelBooking tells the customer a booking was cancelled
+// @Summary Cancel booking
+// @Description Sends a cancellation notice with the reason and clears the booking if it awaits the customer's response
+// @Tags booking
+// @Accept json
+// @Produce json
+// @Param request body CancelBookingRequest true "Booking cancellation request"
+// @Success 200 {object} usecases.BookingResponse "Success response"
+// @Failure 400 {object} map[string]string "Error message"
+// @Failure 401 {object} map[string]string "Error message"
+// @Failure 403 {object} map[string]string "Error message"
+// @Failure 429 {object} map[string]string "Error message"
+// @Failure 500 {object} map[string]string "Error message"
+// @Failure 503 {object} map[string]string "Error message"
+// @Router /booking/cancel [post]
+func (h *BookingHandler) CancelBooking(c *gin.Context) {
+	var request CancelBookingRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		invalidSendRequest(c, "Invalid request body: "+err.Error())
+		return
+	}
+
+	accountID, ok := tenantAccount(c, request.AccountID)
+	if !ok {
+		return
+	}
+
+	response, err := h.bookingUseCase.SendCancellationMessage(c.Request.Context(), usecases.BookingCancellation{
+		BookingID:   request.BookingID,
+		PhoneNumber: request.PhoneNumber,
+		Reason:      request.Reason,
+		AccountID:   accountID,
+		Language:    request.Language,
+	})
+	if err != nil {
+		sendError(c, h.logger, err, "Failed to send cancellation message")
 		return
 	}
 
-	// Send confirmation message with booking details
-	response, err := h.bookingUseCase.SendConfirmationMessage(usecases.BookingRequest{
+	c.JSON(http.StatusOK, response)
+}
+
+// ReminderRequest represents the request body for scheduling a booking reminder
+type ReminderRequest struct {
+	BookingID    string `json:"booking_id" binding:"required"`
+	ServiceName  string `json:"service_name" binding:"required"`
+	UserName     string `json:"user_name" binding:"required"`
+	LocationName string `json:"location_name" binding:"required"`
+	StartTime    string `json:"start_time" binding:"required"`
+	Date         string `json:"date" binding:"required"`
+	EmployeeName string `json:"employee_name" binding:"required"`
+	PhoneNumber  string `json:"phone_number" binding:"required"`
+	Emoji        *bool  `json:"emoji"`
+	// SendAt is when the reminder is sent, in RFC 3339
+	SendAt time.Time `json:"send_at" binding:"required"`
+	// AccountID selects the sending business account, as in confirmations
+	AccountID string `json:"account_id"`
+	// Language of the reminder, as in confirmations
+	Language string `json:"language"`
+}
+
+// ScheduleReminder schedules a reminder of a booking
+// @Summary Schedule booking reminder
+// @Description Schedules a WhatsApp reminder with the booking details, sent at send_at unless the booking is cancelled first
+// @Tags booking
+// @Accept json
+// @Produce json
+// @Param request body ReminderRequest true "Booking reminder request"
+// @Success 202 {object} usecases.BookingResponse "Scheduled reminder"
+// @Failure 400 {object} map[string]string "Error message"
+// @Failure 401 {object} map[string]string "Error message"
+// @Failure 403 {object} map[string]string "Error message"
+// @Failure 409 {object} map[string]string "Error message"
+// @Failure 500 {object} map[string]string "Error message"
+// @Router /booking/reminder [post]
+func (h *BookingHandler) ScheduleReminder(c *gin.Context) {
+	var request ReminderRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		invalidSendRequest(c, "Invalid request body: "+err.Error())
+		return
+	}
+
+	accountID, ok := tenantAccount(c, request.AccountID)
+	if !ok {
+		return
+	}
+
+	response, err := h.bookingUseCase.ScheduleReminder(c.Request.Context(), usecases.BookingRequest{
 		BookingID:    request.BookingID,
 		ServiceName:  request.ServiceName,
 		UserName:     request.UserName,
 		LocationName: request.LocationName,
 		StartTime:    request.StartTime,
-		Date:         request.Date, // Use 'Date' instead of 'date'
+		Date:         request.Date,
 		EmployeeName: request.EmployeeName,
 		PhoneNumber:  request.PhoneNumber,
-	})
+		Emoji:        request.Emoji,
+		AccountID:    accountID,
+		Language:     request.Language,
+	}, request.SendAt)
+	if err != nil {
+		sendError(c, h.logger, err, "Failed to schedule reminder")
+		return
+	}
 
+	c.JSON(http.StatusAccepted, response)
+}
+
+// UpdateBookingRequest represents the request body for updating a pending
+// booking; omitted fields are kept
+type UpdateBookingRequest struct {
+	StartTime    *string `json:"start_time"`
+	Date         *string `json:"date"`
+	LocationName *string `json:"location_name"`
+	EmployeeName *string `json:"employee_name"`
+}
+
+// UpdateBooking changes the details of a booking awaiting confirmation
+// @Summary Update pending booking
+// @Description Updates a booking the customer has not answered yet and edits the confirmation message already sent
+// @Tags booking
+// @Accept json
+// @Produce json
+// @Param id path string true "Booking ID"
+// @Param request body UpdateBookingRequest true "Fields to update"
+// @Success 200 {object} usecases.BookingResponse "Success response"
+// @Failure 400 {object} map[string]string "Error message"
+// @Failure 401 {object} map[string]string "Error message"
+// @Failure 404 {object} map[string]string "Error message"
+// @Failure 409 {object} map[string]string "Error message"
+// @Failure 500 {object} map[string]string "Error message"
+// @Failure 503 {object} map[string]string "Error message"
+// @Router /booking/{id} [patch]
+func (h *BookingHandler) UpdateBooking(c *gin.Context) {
+	var request UpdateBookingRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		invalidSendRequest(c, "Invalid request body: "+err.Error())
+		return
+	}
+	if request.StartTime == nil && request.Date == nil && request.LocationName == nil && request.EmployeeName == nil {
+		invalidSendRequest(c, "At least one of start_time, date, location_name or employee_name is required")
+		return
+	}
+
+	response, err := h.bookingUseCase.UpdateBooking(c.Request.Context(), c.Param("id"), usecases.BookingUpdate{
+		StartTime:    request.StartTime,
+		Date:         request.Date,
+		LocationName: request.LocationName,
+		EmployeeName: request.EmployeeName,
+	})
 	if err != nil {
-		h.logger.Error("Failed to send confirmation message", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send confirmation message"})
+		sendError(c, h.logger, err, "Failed to update booking")
 		return
 	}
 
 	c.JSON(http.StatusOK, response)
 }
+
+// GetTrace returns the lifecycle events recorded for a booking
+// @Summary Get booking trace
+// @Description Returns the ordered lifecycle events of a booking, from the confirmation to the callback
+// @Tags booking
+// @Produce json
+// @Param id path string true "Booking ID"
+// @Success 200 {object} usecases.BookingTrace "Booking trace"
+// @Failure 401 {object} map[string]string "Error message"
+// @Failure 404 {object} map[string]string "Error message"
+// @Failure 500 {object} map[string]string "Error message"
+// @Router /booking/{id}/trace [get]
+func (h *BookingHandler) GetTrace(c *gin.Context) {
+	trace, err := h.bookingUseCase.Trace(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, usecases.ErrBookingNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No trace recorded for this booking"})
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to load booking trace", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load booking trace"})
+		return
+	}
+
+	c.JSON(http.StatusOK, trace)
+}
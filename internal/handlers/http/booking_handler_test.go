@@ -0,0 +1,100 @@
+package http
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/internal/usecases"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/store"
+)
+
+func TestBookingRoutesRequireToken(t *testing.T) {
+	token := newTestToken(t)
+	client := newLoggedInClient(t)
+	redisClient, _ := newTestRedis(t)
+	stateStore := store.NewMemoryStore()
+	bookingUseCase := usecases.NewBookingUseCase(client, logger.NewNop(),
+		usecases.WithBookingStore(stateStore),
+		usecases.WithTrace(usecases.NewTraceUseCase(stateStore, logger.NewNop(), time.Hour)),
+		usecases.WithReminders(redisClient))
+	router := newTestRouter()
+	NewBookingHandler(bookingUseCase, logger.NewNop()).RegisterRoutes(router, newTestAuthHandler(client))
+
+	details := `"booking_id":"booking-1","service_name":"Corte","user_name":"Ana","location_name":"Centro","start_time":"10:00","date":"01/06/2025","employee_name":"Luis","phone_number":"56961234567"`
+	sendAt := time.Now().Add(time.Hour).Format(time.RFC3339)
+	// In order, so the booking confirmed first can be updated, traced and
+	// cancelled
+	routes := []struct {
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{method: http.MethodPost, path: "/booking/confirm", body: `{` + details + `}`, status: http.StatusOK},
+		{method: http.MethodPatch, path: "/booking/booking-1", body: `{"start_time":"11:00"}`, status: http.StatusOK},
+		{method: http.MethodGet, path: "/booking/booking-1/trace", status: http.StatusOK},
+		{method: http.MethodPost, path: "/booking/reminder", body: `{` + details + `,"send_at":"` + sendAt + `"}`, status: http.StatusAccepted},
+		{method: http.MethodPost, path: "/booking/cancel", body: `{"booking_id":"booking-1","phone_number":"56961234567"}`, status: http.StatusOK},
+	}
+	for _, route := range routes {
+		t.Run(route.method+" "+route.path, func(t *testing.T) {
+			if rec := serve(router, route.method, route.path, route.body, ""); rec.Code != http.StatusUnauthorized {
+				t.Errorf("without token: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+			}
+			if rec := serve(router, route.method, route.path, route.body, "not-a-token"); rec.Code != http.StatusUnauthorized {
+				t.Errorf("with invalid token: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+			}
+			if rec := serve(router, route.method, route.path, route.body, token); rec.Code != route.status {
+				t.Errorf("with token: status = %d, want %d: %s", rec.Code, route.status, rec.Body)
+			}
+		})
+	}
+
+	// Requests turned away without a token never reach the booking
+	rec := serve(router, http.MethodGet, "/booking/booking-1/trace", "", token)
+	var lifecycle usecases.BookingTrace
+	decode(t, rec, &lifecycle)
+	created := 0
+	for _, event := range lifecycle.Events {
+		if event.Stage == usecases.TraceStageCreated {
+			created++
+		}
+	}
+	if created != 1 {
+		t.Errorf("trace has %d created events, want only the authenticated confirmation", created)
+	}
+}
+
+func TestLoginTokenOpensBookingRoutes(t *testing.T) {
+	newTestToken(t)
+	client := newLoggedInClient(t)
+	stateStore := store.NewMemoryStore()
+	bookingUseCase := usecases.NewBookingUseCase(client, logger.NewNop(),
+		usecases.WithBookingStore(stateStore),
+		usecases.WithTrace(usecases.NewTraceUseCase(stateStore, logger.NewNop(), time.Hour)))
+	loginUseCase := usecases.NewLoginUseCase(logger.NewNop(), usecases.WithLoginAPIKey("test-key"))
+	authHandler := NewAuthHandler(usecases.NewWhatsAppAuthUseCase(client, logger.NewNop()), loginUseCase, logger.NewNop())
+	router := newTestRouter()
+	authHandler.RegisterRoutes(router)
+	NewBookingHandler(bookingUseCase, logger.NewNop()).RegisterRoutes(router, authHandler)
+
+	if rec := serve(router, http.MethodPost, "/auth/login", `{"api_key":"wrong"}`, ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("login with a wrong key status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	rec := serve(router, http.MethodPost, "/auth/login", `{"api_key":"test-key"}`, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("login status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	var login usecases.LoginResult
+	decode(t, rec, &login)
+
+	body := `{"booking_id":"booking-1","service_name":"Corte","user_name":"Ana","location_name":"Centro","start_time":"10:00","date":"01/06/2025","employee_name":"Luis","phone_number":"56961234567"}`
+	if rec := serve(router, http.MethodPost, "/booking/confirm", body, login.Token); rec.Code != http.StatusOK {
+		t.Errorf("confirm with the login token status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	if rec := serve(router, http.MethodGet, "/booking/booking-1/trace", "", login.Token); rec.Code != http.StatusOK {
+		t.Errorf("trace with the login token status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+}
@@ -0,0 +1,156 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/internal/usecases"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+)
+
+// ContactHandler handles contact lookup endpoints
+type ContactHandler struct {
+	contactUseCase *usecases.ContactUseCase
+	consentUseCase *usecases.ConsentUseCase
+	deliverability *usecases.DeliverabilityUseCase
+	logger         logger.Logger
+}
+
+// NewContactHandler creates a new ContactHandler
+func NewContactHandler(contactUseCase *usecases.ContactUseCase, consentUseCase *usecases.ConsentUseCase, deliverability *usecases.DeliverabilityUseCase, logger logger.Logger) *ContactHandler {
+	return &ContactHandler{
+		contactUseCase: contactUseCase,
+		consentUseCase: consentUseCase,
+		deliverability: deliverability,
+		logger:         logger,
+	}
+}
+
+// RegisterRoutes registers the contact routes
+func (h *ContactHandler) RegisterRoutes(router *gin.Engine) {
+	contacts := router.Group("/contacts", JWTMiddleware())
+	{
+		contacts.GET("/check", h.CheckContacts)
+		contacts.GET("/:number", h.CheckContact)
+		contacts.GET("/:number/consent", h.GetConsent)
+		contacts.GET("/:number/deliverability", h.GetDeliverability)
+		contacts.DELETE("/:number/deliverability", h.ClearDeliverability)
+	}
+}
+
+// CheckContact returns whether a number is registered on WhatsApp
+// @Summary Check a number on WhatsApp
+// @Description Returns whether the number is registered on WhatsApp and its JID; results are cached
+// @Tags contacts
+// @Produce json
+// @Param number path string true "Phone number"
+// @Success 200 {object} whatsapp.Contact "Contact"
+// @Failure 400 {object} map[string]string "Error message"
+// @Failure 401 {object} map[string]string "Error message"
+// @Failure 500 {object} map[string]string "Error message"
+// @Failure 503 {object} map[string]string "Error message"
+// @Router /contacts/{number} [get]
+func (h *ContactHandler) CheckContact(c *gin.Context) {
+	contact, err := h.contactUseCase.Check(c.Request.Context(), c.Param("number"))
+	if err != nil {
+		sendError(c, h.logger, err, "Failed to look up contact")
+		return
+	}
+
+	c.JSON(http.StatusOK, contact)
+}
+
+// CheckContacts returns whether each number is registered on WhatsApp
+// @Summary Check several numbers on WhatsApp
+// @Description Looks up up to 50 comma-separated numbers in one query, bypassing the cache, and returns for each its JID, whether it is registered and its verified business name
+// @Tags contacts
+// @Produce json
+// @Param phones query string true "Comma-separated phone numbers"
+// @Success 200 {array} whatsapp.Contact "Contacts"
+// @Failure 400 {object} map[string]string "Error message"
+// @Failure 401 {object} map[string]string "Error message"
+// @Failure 500 {object} map[string]string "Error message"
+// @Failure 503 {object} map[string]string "Error message"
+// @Router /contacts/check [get]
+func (h *ContactHandler) CheckContacts(c *gin.Context) {
+	var numbers []string
+	for _, value := range c.QueryArray("phones") {
+		for _, number := range strings.Split(value, ",") {
+			if number = strings.TrimSpace(number); number != "" {
+				numbers = append(numbers, number)
+			}
+		}
+	}
+
+	contacts, err := h.contactUseCase.CheckMany(c.Request.Context(), numbers)
+	if err != nil {
+		sendError(c, h.logger, err, "Failed to look up contacts")
+		return
+	}
+
+	c.JSON(http.StatusOK, contacts)
+}
+
+// GetConsent returns the opt-out status of a number
+// @Summary Get the messaging consent of a number
+// @Description Returns whether the number opted out, its cooldown and which messages may be sent to it
+// @Tags contacts
+// @Produce json
+// @Param number path string true "Phone number"
+// @Success 200 {object} usecases.Consent "Consent"
+// @Failure 400 {object} map[string]string "Error message"
+// @Failure 401 {object} map[string]string "Error message"
+// @Failure 500 {object} map[string]string "Error message"
+// @Router /contacts/{number}/consent [get]
+func (h *ContactHandler) GetConsent(c *gin.Context) {
+	consent, err := h.consentUseCase.Status(c.Request.Context(), c.Param("number"))
+	if err != nil {
+		sendError(c, h.logger, err, "Failed to get consent")
+		return
+	}
+
+	c.JSON(http.StatusOK, consent)
+}
+
+// GetDeliverability returns whether messages are sent to a number
+// @Summary Get the deliverability of a number
+// @Description Returns whether the number was marked as undeliverable after WhatsApp rejected a send to it, and why
+// @Tags contacts
+// @Produce json
+// @Param number path string true "Phone number"
+// @Success 200 {object} usecases.Deliverability "Deliverability"
+// @Failure 400 {object} map[string]string "Error message"
+// @Failure 401 {object} map[string]string "Error message"
+// @Failure 500 {object} map[string]string "Error message"
+// @Router /contacts/{number}/deliverability [get]
+func (h *ContactHandler) GetDeliverability(c *gin.Context) {
+	deliverability, err := h.deliverability.Status(c.Request.Context(), c.Param("number"))
+	if err != nil {
+		sendError(c, h.logger, err, "Failed to get deliverability")
+		return
+	}
+
+	c.JSON(http.StatusOK, deliverability)
+}
+
+// ClearDeliverability clears the undeliverable mark of a number
+// @Summary Clear the undeliverable mark of a number
+// @Description Allows sending to a number marked as undeliverable again
+// @Tags contacts
+// @Produce json
+// @Param number path string true "Phone number"
+// @Success 200 {object} usecases.Deliverability "Deliverability"
+// @Failure 400 {object} map[string]string "Error message"
+// @Failure 401 {object} map[string]string "Error message"
+// @Failure 500 {object} map[string]string "Error message"
+// @Router /contacts/{number}/deliverability [delete]
+func (h *ContactHandler) ClearDeliverability(c *gin.Context) {
+	deliverability, err := h.deliverability.Clear(c.Request.Context(), c.Param("number"))
+	if err != nil {
+		sendError(c, h.logger, err, "Failed to clear deliverability")
+		return
+	}
+
+	c.JSON(http.StatusOK, deliverability)
+}
@@ -0,0 +1,89 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/internal/usecases"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// ConversationHandler handles conversation history endpoints
+type ConversationHandler struct {
+	historyUseCase *usecases.HistoryUseCase
+	logger         logger.Logger
+	phoneRegion    string
+}
+
+// NewConversationHandler creates a new ConversationHandler
+func NewConversationHandler(historyUseCase *usecases.HistoryUseCase, logger logger.Logger, phoneRegion string) *ConversationHandler {
+	return &ConversationHandler{
+		historyUseCase: historyUseCase,
+		logger:         logger,
+		phoneRegion:    phoneRegion,
+	}
+}
+
+// RegisterRoutes registers the conversation routes
+func (h *ConversationHandler) RegisterRoutes(router *gin.Engine) {
+	conversations := router.Group("/conversations", JWTMiddleware())
+	{
+		conversations.GET("/:number/messages", h.ListMessages)
+	}
+}
+
+// ListMessages returns the recent messages exchanged with a number
+// @Summary List recent messages for a number
+// @Description Returns messages exchanged with the number, newest first. Use the next_before cursor to scroll back.
+// @Tags conversations
+// @Produce json
+// @Param number path string true "Phone number"
+// @Param limit query int false "Maximum messages to return (default 20, max 100)"
+// @Param before query string false "Only messages before this RFC 3339 timestamp"
+// @Success 200 {object} map[string]interface{} "Messages and next cursor"
+// @Failure 400 {object} map[string]string "Error message"
+// @Failure 401 {object} map[string]string "Error message"
+// @Failure 500 {object} map[string]string "Error message"
+// @Router /conversations/{number}/messages [get]
+func (h *ConversationHandler) ListMessages(c *gin.Context) {
+	number, err := utils.NormalizePhone(c.Param("number"), h.phoneRegion)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid phone number: " + err.Error()})
+		return
+	}
+
+	limit := 0
+	if raw := c.Query("limit"); raw != "" {
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return
+		}
+	}
+
+	var before time.Time
+	if raw := c.Query("before"); raw != "" {
+		before, err = time.Parse(time.RFC3339Nano, raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "before must be an RFC 3339 timestamp"})
+			return
+		}
+	}
+
+	messages, err := h.historyUseCase.List(c.Request.Context(), number, limit, before)
+	if err != nil {
+		h.logger.Error("Failed to list conversation messages", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list messages"})
+		return
+	}
+
+	response := gin.H{"messages": messages}
+	if len(messages) > 0 {
+		response["next_before"] = messages[len(messages)-1].Timestamp.Format(time.RFC3339Nano)
+	}
+	c.JSON(http.StatusOK, response)
+}
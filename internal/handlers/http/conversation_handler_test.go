@@ -0,0 +1,159 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/internal/usecases"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/store"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/whatsapp"
+)
+
+// messagesPage is the body of GET /conversations/:number/messages
+type messagesPage struct {
+	Messages   []usecases.HistoryMessage `json:"messages"`
+	NextBefore string                    `json:"next_before"`
+}
+
+// newConversationRouter serves the conversation routes on a history holding
+// five messages exchanged with the test phone, one minute apart
+func newConversationRouter(t *testing.T, storeBodies bool) *gin.Engine {
+	t.Helper()
+	history := usecases.NewHistoryUseCase(store.NewMemoryStore(), logger.NewNop(), time.Hour, storeBodies)
+	start := time.Now().Add(-10 * time.Minute)
+	for i := range 5 {
+		at := start.Add(time.Duration(i) * time.Minute)
+		id := fmt.Sprintf("msg-%d", i)
+		var err error
+		if i%2 == 0 {
+			err = history.RecordInbound(context.Background(), &whatsapp.WhatsAppMessage{ID: id, From: "56961234567", Body: "hola " + id}, at)
+		} else {
+			err = history.RecordSend(context.Background(), whatsapp.SendRecord{
+				MessageID: id,
+				JID:       "56961234567@s.whatsapp.net",
+				SentAt:    at,
+				Status:    whatsapp.SendStatusSent,
+				Text:      "respuesta " + id,
+			})
+		}
+		if err != nil {
+			t.Fatalf("record %s: %v", id, err)
+		}
+	}
+
+	router := newTestRouter()
+	NewConversationHandler(history, logger.NewNop(), "CL").RegisterRoutes(router)
+	return router
+}
+
+// ids returns the IDs of the messages of a page
+func (p messagesPage) ids() []string {
+	var ids []string
+	for _, message := range p.Messages {
+		ids = append(ids, message.ID)
+	}
+	return ids
+}
+
+func TestListMessagesPaging(t *testing.T) {
+	token := newTestToken(t)
+	router := newConversationRouter(t, true)
+
+	var pages [][]string
+	path := "/conversations/+56961234567/messages?limit=2"
+	for range 4 {
+		rec := serve(router, http.MethodGet, path, "", token)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s: status = %d, want 200: %s", path, rec.Code, rec.Body)
+		}
+		var page messagesPage
+		decode(t, rec, &page)
+		pages = append(pages, page.ids())
+		if page.NextBefore == "" {
+			break
+		}
+		path = "/conversations/56961234567/messages?limit=2&before=" + url.QueryEscape(page.NextBefore)
+	}
+
+	want := [][]string{{"msg-4", "msg-3"}, {"msg-2", "msg-1"}, {"msg-0"}, nil}
+	if !slices.EqualFunc(pages, want, slices.Equal[[]string]) {
+		t.Errorf("pages = %v, want %v", pages, want)
+	}
+}
+
+func TestListMessages(t *testing.T) {
+	token := newTestToken(t)
+	tests := []struct {
+		name        string
+		path        string
+		storeBodies bool
+		wantStatus  int
+		wantIDs     []string
+		wantBody    string
+	}{
+		{
+			name:        "default limit",
+			path:        "/conversations/56961234567/messages",
+			storeBodies: true,
+			wantStatus:  http.StatusOK,
+			wantIDs:     []string{"msg-4", "msg-3", "msg-2", "msg-1", "msg-0"},
+			wantBody:    "hola msg-4",
+		},
+		{
+			name:       "redacted bodies",
+			path:       "/conversations/56961234567/messages?limit=1",
+			wantStatus: http.StatusOK,
+			wantIDs:    []string{"msg-4"},
+		},
+		{
+			name:       "unknown number",
+			path:       "/conversations/14155552671/messages",
+			wantStatus: http.StatusOK,
+			wantIDs:    nil,
+		},
+		{name: "invalid limit", path: "/conversations/56961234567/messages?limit=0", wantStatus: http.StatusBadRequest},
+		{name: "invalid cursor", path: "/conversations/56961234567/messages?before=yesterday", wantStatus: http.StatusBadRequest},
+		{name: "invalid number", path: "/conversations/123/messages", wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newConversationRouter(t, tt.storeBodies)
+			rec := serve(router, http.MethodGet, tt.path, "", token)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var page messagesPage
+			decode(t, rec, &page)
+			if page.Messages == nil {
+				t.Error("messages = null, want an array")
+			}
+			if !slices.Equal(page.ids(), tt.wantIDs) {
+				t.Fatalf("messages = %v, want %v", page.ids(), tt.wantIDs)
+			}
+			if len(page.Messages) > 0 {
+				newest := page.Messages[0]
+				if newest.Body != tt.wantBody || newest.Redacted == tt.storeBodies || newest.Direction != usecases.DirectionInbound {
+					t.Errorf("newest = %+v, want inbound body %q", newest, tt.wantBody)
+				}
+			}
+		})
+	}
+
+	t.Run("without token", func(t *testing.T) {
+		rec := serve(newConversationRouter(t, true), http.MethodGet, "/conversations/56961234567/messages", "", "")
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("status = %d, want 401", rec.Code)
+		}
+	})
+}
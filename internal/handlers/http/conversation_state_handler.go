@@ -0,0 +1,84 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/internal/usecases"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+)
+
+// ConversationStateHandler handles inspection and clearing of the state
+// stored for a conversation
+type ConversationStateHandler struct {
+	bookingUseCase *usecases.BookingUseCase
+	logger         logger.Logger
+}
+
+// NewConversationStateHandler creates a new ConversationStateHandler
+func NewConversationStateHandler(bookingUseCase *usecases.BookingUseCase, logger logger.Logger) *ConversationStateHandler {
+	return &ConversationStateHandler{
+		bookingUseCase: bookingUseCase,
+		logger:         logger,
+	}
+}
+
+// RegisterRoutes registers the conversation state routes
+func (h *ConversationStateHandler) RegisterRoutes(router *gin.Engine) {
+	admin := router.Group("/admin", JWTMiddleware())
+	{
+		admin.GET("/conversations/:number/state", h.GetState)
+		admin.DELETE("/conversations/:number/state", h.ClearState)
+	}
+}
+
+// GetState returns the state stored for the conversation with a number
+// @Summary Inspect conversation state
+// @Description Returns the pending and expired bookings, locale and opt-out stored for the number
+// @Tags admin
+// @Produce json
+// @Param number path string true "Phone number"
+// @Success 200 {object} usecases.ConversationState "Conversation state"
+// @Failure 400 {object} map[string]string "Error message"
+// @Failure 401 {object} map[string]string "Error message"
+// @Failure 500 {object} map[string]string "Error message"
+// @Router /admin/conversations/{number}/state [get]
+func (h *ConversationStateHandler) GetState(c *gin.Context) {
+	state, err := h.bookingUseCase.ConversationState(c.Request.Context(), c.Param("number"))
+	if err != nil {
+		sendError(c, h.logger, err, "Failed to get conversation state")
+		return
+	}
+
+	c.JSON(http.StatusOK, state)
+}
+
+// ClearState clears the state stored for the conversation with a number
+// @Summary Clear conversation state
+// @Description Clears the pending and expired bookings and locale of the number, and its opt-out with opt_out=true. The clear is logged with the user of the token.
+// @Tags admin
+// @Produce json
+// @Param number path string true "Phone number"
+// @Param opt_out query bool false "Also clear the opt-out"
+// @Success 200 {object} map[string]interface{} "Cleared state and the state before clearing"
+// @Failure 400 {object} map[string]string "Error message"
+// @Failure 401 {object} map[string]string "Error message"
+// @Failure 500 {object} map[string]string "Error message"
+// @Router /admin/conversations/{number}/state [delete]
+func (h *ConversationStateHandler) ClearState(c *gin.Context) {
+	actor := c.GetString("user_id")
+	if actor == "" {
+		actor = "unknown"
+	}
+
+	previous, cleared, err := h.bookingUseCase.ClearConversationState(c.Request.Context(), c.Param("number"), actor, c.Query("opt_out") == "true")
+	if err != nil {
+		sendError(c, h.logger, err, "Failed to clear conversation state")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"cleared":  cleared,
+		"previous": previous,
+	})
+}
@@ -0,0 +1,105 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/internal/usecases"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/store"
+)
+
+func TestConversationStateRoutes(t *testing.T) {
+	token := newTestToken(t)
+	stateStore := store.NewMemoryStore()
+	trace := usecases.NewTraceUseCase(stateStore, logger.NewNop(), time.Hour)
+	bookingUseCase := usecases.NewBookingUseCase(newTestClient(t), logger.NewNop(),
+		usecases.WithBookingStore(stateStore),
+		usecases.WithTrace(trace))
+	router := newTestRouter()
+	NewConversationStateHandler(bookingUseCase, logger.NewNop()).RegisterRoutes(router)
+	path := "/admin/conversations/56961234567/state"
+
+	for _, method := range []string{http.MethodGet, http.MethodDelete} {
+		if rec := serve(router, method, path, "", ""); rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s without a token status = %d, want %d", method, rec.Code, http.StatusUnauthorized)
+		}
+	}
+
+	// A customer stuck with a pending booking
+	_, err := bookingUseCase.SendConfirmationMessage(context.Background(), usecases.BookingRequest{
+		BookingID:   "booking-1",
+		ServiceName: "Corte",
+		UserName:    "Ana",
+		Date:        "01/06/2025",
+		StartTime:   "10:00",
+		PhoneNumber: "56961234567",
+	})
+	if err != nil {
+		t.Fatalf("SendConfirmationMessage() error = %v", err)
+	}
+
+	rec := serve(router, http.MethodGet, path, "", token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	var state usecases.ConversationState
+	decode(t, rec, &state)
+	if state.PhoneNumber != "56961234567" || state.PendingBooking == nil || state.PendingBooking.BookingID != "booking-1" {
+		t.Errorf("state = %+v, want booking-1 pending", state)
+	}
+
+	// Clearing returns what was found and removes it
+	rec = serve(router, http.MethodDelete, path, "", token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("DELETE status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	var body struct {
+		Cleared  []string                   `json:"cleared"`
+		Previous usecases.ConversationState `json:"previous"`
+	}
+	decode(t, rec, &body)
+	if len(body.Cleared) != 1 || body.Cleared[0] != usecases.StatePendingBooking {
+		t.Errorf("cleared = %v, want the pending booking", body.Cleared)
+	}
+	if body.Previous.PendingBooking == nil || body.Previous.PendingBooking.BookingID != "booking-1" {
+		t.Errorf("previous = %+v, want booking-1 pending", body.Previous)
+	}
+
+	rec = serve(router, http.MethodGet, path, "", token)
+	state = usecases.ConversationState{}
+	decode(t, rec, &state)
+	if state.PendingBooking != nil {
+		t.Errorf("pending booking after clearing = %+v, want none", state.PendingBooking)
+	}
+
+	// The clear is audited with the user of the token
+	deadline := time.Now().Add(time.Second)
+	for {
+		lifecycle, err := bookingUseCase.Trace(context.Background(), "booking-1")
+		if err == nil && hasTraceEvent(lifecycle, usecases.TraceStageCleared, "cleared by tester") {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("trace = %+v, %v, want the clear by tester", lifecycle, err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if rec := serve(router, http.MethodGet, "/admin/conversations/123/state", "", token); rec.Code != http.StatusBadRequest {
+		t.Errorf("GET an invalid number status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+// hasTraceEvent reports whether the trace holds an event of the stage with
+// the detail
+func hasTraceEvent(lifecycle *usecases.BookingTrace, stage, detail string) bool {
+	for _, event := range lifecycle.Events {
+		if event.Stage == stage && event.Detail == detail {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package http
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/config"
+)
+
+// corsRoute binds a route prefix to the CORS handler of its policy
+type corsRoute struct {
+	prefix  string
+	handler gin.HandlerFunc
+}
+
+// NewCORSMiddleware builds the CORS middleware. Requests under a route prefix
+// of a named policy use that policy; everything else uses the default one.
+// It must be installed globally so preflight requests are handled too.
+func NewCORSMiddleware(cfg *config.Config) gin.HandlerFunc {
+	defaultHandler := newCORSHandler(strings.Split(cfg.CorsAllowedOrigins, ","), cfg.CorsAllowCredentials, cfg.CorsMaxAge)
+
+	var routes []corsRoute
+	for _, policy := range cfg.CorsPolicies {
+		handler := newCORSHandler(policy.AllowedOrigins, policy.AllowCredentials, cfg.CorsMaxAge)
+		for _, prefix := range policy.Routes {
+			routes = append(routes, corsRoute{prefix: prefix, handler: handler})
+		}
+	}
+
+	return func(c *gin.Context) {
+		path := c.Request.URL.Path
+		for _, route := range routes {
+			if strings.HasPrefix(path, route.prefix) {
+				route.handler(c)
+				return
+			}
+		}
+		defaultHandler(c)
+	}
+}
+
+// newCORSHandler creates the gin-contrib CORS handler for a policy
+func newCORSHandler(origins []string, allowCredentials bool, maxAge time.Duration) gin.HandlerFunc {
+	return cors.New(cors.Config{
+		AllowOrigins:     origins,
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "Idempotency-Key"},
+		ExposeHeaders:    []string{"Content-Length"},
+		AllowCredentials: allowCredentials,
+		MaxAge:           maxAge,
+	})
+}
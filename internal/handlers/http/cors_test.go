@@ -0,0 +1,83 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/config"
+)
+
+func TestCORSPolicySelection(t *testing.T) {
+	cfg := &config.Config{
+		CorsAllowedOrigins:   "http://localhost:3000",
+		CorsAllowCredentials: true,
+		CorsMaxAge:           time.Hour,
+		CorsPolicies: []config.CorsPolicy{
+			{Name: "admin", AllowedOrigins: []string{"https://admin.example.com"}, AllowCredentials: true, Routes: []string{"/admin"}},
+			{Name: "widget", AllowedOrigins: []string{"*"}, Routes: []string{"/booking"}},
+		},
+	}
+	router := gin.New()
+	router.Use(NewCORSMiddleware(cfg))
+	for _, path := range []string{"/admin/sessions", "/booking/confirm", "/auth/status"} {
+		router.GET(path, func(c *gin.Context) { c.Status(http.StatusOK) })
+	}
+
+	tests := []struct {
+		name            string
+		path            string
+		origin          string
+		wantStatus      int
+		wantOrigin      string
+		wantCredentials string
+	}{
+		{"admin origin on admin routes", "/admin/sessions", "https://admin.example.com", http.StatusOK, "https://admin.example.com", "true"},
+		{"widget origin on admin routes", "/admin/sessions", "https://shop.example.com", http.StatusForbidden, "", ""},
+		{"any origin on booking routes", "/booking/confirm", "https://shop.example.com", http.StatusOK, "*", ""},
+		{"default origin on other routes", "/auth/status", "http://localhost:3000", http.StatusOK, "http://localhost:3000", "true"},
+		{"admin origin on other routes", "/auth/status", "https://admin.example.com", http.StatusForbidden, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("Origin", tt.origin)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantOrigin)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Credentials"); got != tt.wantCredentials {
+				t.Errorf("Access-Control-Allow-Credentials = %q, want %q", got, tt.wantCredentials)
+			}
+		})
+	}
+}
+
+func TestCORSPreflightMaxAge(t *testing.T) {
+	router := gin.New()
+	router.Use(NewCORSMiddleware(&config.Config{
+		CorsAllowedOrigins: "http://localhost:3000",
+		CorsMaxAge:         2 * time.Hour,
+	}))
+
+	req := httptest.NewRequest(http.MethodOptions, "/booking/confirm", nil)
+	req.Header.Set("Origin", "http://localhost:3000")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+	if got := rec.Header().Get("Access-Control-Max-Age"); got != "7200" {
+		t.Errorf("Access-Control-Max-Age = %q, want 7200", got)
+	}
+}
@@ -0,0 +1,44 @@
+package http
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/internal/usecases"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+)
+
+// DeliveryHealthHandler handles the delivery health endpoint
+type DeliveryHealthHandler struct {
+	deliveryHealthUseCase *usecases.DeliveryHealthUseCase
+	logger                logger.Logger
+}
+
+// NewDeliveryHealthHandler creates a new DeliveryHealthHandler
+func NewDeliveryHealthHandler(deliveryHealthUseCase *usecases.DeliveryHealthUseCase, logger logger.Logger) *DeliveryHealthHandler {
+	return &DeliveryHealthHandler{
+		deliveryHealthUseCase: deliveryHealthUseCase,
+		logger:                logger,
+	}
+}
+
+// RegisterRoutes registers the delivery health routes
+func (h *DeliveryHealthHandler) RegisterRoutes(router *gin.Engine) {
+	admin := router.Group("/admin", JWTMiddleware())
+	{
+		admin.GET("/delivery-health", h.GetHealth)
+	}
+}
+
+// GetHealth returns the delivery success rates over the rolling window
+// @Summary Delivery health
+// @Description Returns the global and per-number delivery success rates over the rolling window, the worst numbers first
+// @Tags admin
+// @Produce json
+// @Success 200 {object} usecases.DeliveryHealth "Delivery health"
+// @Failure 401 {object} map[string]string "Error message"
+// @Router /admin/delivery-health [get]
+func (h *DeliveryHealthHandler) GetHealth(c *gin.Context) {
+	c.JSON(http.StatusOK, h.deliveryHealthUseCase.Health(time.Now()))
+}
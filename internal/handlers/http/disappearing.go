@@ -0,0 +1,23 @@
+package http
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/whatsapp"
+)
+
+// disappearAfter converts the disappear_after seconds of a send request to a
+// timer, writing a 400 when WhatsApp does not support it. A nil timer keeps
+// the account default.
+func disappearAfter(c *gin.Context, seconds *int) (*time.Duration, bool) {
+	if seconds == nil {
+		return nil, true
+	}
+	timer := time.Duration(*seconds) * time.Second
+	if *seconds < 0 || !whatsapp.ValidDisappearTimer(timer) {
+		invalidSendRequest(c, "disappear_after must be 0, 86400, 604800 or 7776000 seconds")
+		return nil, false
+	}
+	return &timer, true
+}
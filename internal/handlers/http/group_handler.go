@@ -0,0 +1,89 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/internal/usecases"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+)
+
+// GroupHandler handles group endpoints
+type GroupHandler struct {
+	groupUseCase *usecases.GroupUseCase
+	logger       logger.Logger
+}
+
+// NewGroupHandler creates a new GroupHandler
+func NewGroupHandler(groupUseCase *usecases.GroupUseCase, logger logger.Logger) *GroupHandler {
+	return &GroupHandler{
+		groupUseCase: groupUseCase,
+		logger:       logger,
+	}
+}
+
+// RegisterRoutes registers the group routes
+func (h *GroupHandler) RegisterRoutes(router *gin.Engine, authHandler *AuthHandler) {
+	groups := router.Group("/groups", JWTMiddleware(), authHandler.AuthMiddleware())
+	{
+		groups.GET("", h.ListGroups)
+		groups.POST("/:jid/messages", h.SendMessage)
+	}
+}
+
+// ListGroups returns the joined groups
+// @Summary List joined groups
+// @Description Returns the groups the connected number currently belongs to
+// @Tags groups
+// @Produce json
+// @Success 200 {array} whatsapp.Group "Joined groups"
+// @Failure 401 {object} map[string]string "Error message"
+// @Failure 500 {object} map[string]string "Error message"
+// @Router /groups [get]
+func (h *GroupHandler) ListGroups(c *gin.Context) {
+	groups, err := h.groupUseCase.ListGroups(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list groups"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"groups": groups})
+}
+
+// GroupMessageRequest represents the request body for sending a group message
+type GroupMessageRequest struct {
+	Text string `json:"text"`
+	// Mentions lists the phone numbers to @-mention; each must be a participant
+	Mentions []string `json:"mentions"`
+}
+
+// SendMessage sends a text to a group, optionally mentioning participants
+// @Summary Send a group message
+// @Description Sends a text to a group; mentioned numbers get an @number token in the text and a mention notification
+// @Tags groups
+// @Accept json
+// @Produce json
+// @Param jid path string true "Group JID (with or without @g.us)"
+// @Param request body GroupMessageRequest true "Group message request"
+// @Success 200 {object} usecases.SendResult "Sent message"
+// @Failure 400 {object} map[string]string "Error message"
+// @Failure 401 {object} map[string]string "Error message"
+// @Failure 429 {object} map[string]string "Error message"
+// @Failure 500 {object} map[string]string "Error message"
+// @Failure 503 {object} map[string]string "Error message"
+// @Router /groups/{jid}/messages [post]
+func (h *GroupHandler) SendMessage(c *gin.Context) {
+	var request GroupMessageRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		invalidSendRequest(c, "Invalid request body: "+err.Error())
+		return
+	}
+
+	result, err := h.groupUseCase.SendMessage(c.Request.Context(), c.Param("jid"), request.Text, request.Mentions)
+	if err != nil {
+		sendError(c, h.logger, err, "Failed to send group message")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,78 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/internal/usecases"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/version"
+)
+
+// HealthHandler handles health check endpoints
+type HealthHandler struct {
+	healthUseCase *usecases.HealthUseCase
+	logger        logger.Logger
+}
+
+// NewHealthHandler creates a new HealthHandler
+func NewHealthHandler(healthUseCase *usecases.HealthUseCase, logger logger.Logger) *HealthHandler {
+	return &HealthHandler{
+		healthUseCase: healthUseCase,
+		logger:        logger,
+	}
+}
+
+// RegisterRoutes registers the health routes
+func (h *HealthHandler) RegisterRoutes(router *gin.Engine) {
+	router.GET("/health", h.Health)
+	router.GET("/readyz", h.Readyz)
+	router.GET("/version", h.Version)
+}
+
+// Health returns the health of the service and each dependency
+// @Summary Health check
+// @Description Returns 200 only when WhatsApp is connected, the session is logged in and Redis responds; otherwise 503 with the state of each dependency
+// @Tags health
+// @Produce json
+// @Success 200 {object} usecases.Health "Service healthy"
+// @Failure 503 {object} usecases.Health "Service unhealthy"
+// @Router /health [get]
+func (h *HealthHandler) Health(c *gin.Context) {
+	health := h.healthUseCase.Health(c.Request.Context())
+	if !health.Healthy() {
+		c.JSON(http.StatusServiceUnavailable, health)
+		return
+	}
+
+	c.JSON(http.StatusOK, health)
+}
+
+// Readyz returns the readiness of the service
+// @Summary Readiness check
+// @Description Returns 200 when WhatsApp is connected; Redis health is reported but not fatal
+// @Tags health
+// @Produce json
+// @Success 200 {object} usecases.Readiness "Service ready"
+// @Failure 503 {object} usecases.Readiness "Service not ready"
+// @Router /readyz [get]
+func (h *HealthHandler) Readyz(c *gin.Context) {
+	readiness := h.healthUseCase.Readiness(c.Request.Context())
+	if !readiness.Ready {
+		c.JSON(http.StatusServiceUnavailable, readiness)
+		return
+	}
+
+	c.JSON(http.StatusOK, readiness)
+}
+
+// Version returns the build metadata of the running service
+// @Summary Build version
+// @Description Returns the build version, git commit, build time and whatsmeow version
+// @Tags health
+// @Produce json
+// @Success 200 {object} version.Info "Build metadata"
+// @Router /version [get]
+func (h *HealthHandler) Version(c *gin.Context) {
+	c.JSON(http.StatusOK, version.Get())
+}
@@ -0,0 +1,106 @@
+package http
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/internal/usecases"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/version"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/whatsapp"
+)
+
+func TestReadyzReportsRedisWithoutFailing(t *testing.T) {
+	client := newLoggedInClient(t)
+	redisClient, server := newTestRedis(t)
+	authUseCase := usecases.NewWhatsAppAuthUseCase(client, logger.NewNop())
+	router := gin.New()
+	NewHealthHandler(usecases.NewHealthUseCase(client, redisClient, authUseCase, logger.NewNop()), logger.NewNop()).RegisterRoutes(router)
+
+	var readiness usecases.Readiness
+	rec := serve(router, http.MethodGet, "/readyz", "", "")
+	decode(t, rec, &readiness)
+	if rec.Code != http.StatusOK || readiness.Redis != "ok" {
+		t.Errorf("with Redis up: status = %d, redis = %q; want 200, ok", rec.Code, readiness.Redis)
+	}
+	if rec := serve(router, http.MethodGet, "/health", "", ""); rec.Code != http.StatusOK {
+		t.Errorf("GET /health with Redis up: status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+
+	server.Close()
+
+	// Sending does not need Redis, so the service stays ready
+	rec = serve(router, http.MethodGet, "/readyz", "", "")
+	decode(t, rec, &readiness)
+	if rec.Code != http.StatusOK || readiness.Redis != "degraded" || !readiness.Ready {
+		t.Errorf("with Redis down: status = %d, readiness = %+v; want 200, degraded", rec.Code, readiness)
+	}
+
+	// The health check does report the outage
+	var health usecases.Health
+	rec = serve(router, http.MethodGet, "/health", "", "")
+	decode(t, rec, &health)
+	if rec.Code != http.StatusServiceUnavailable || health.Redis.Healthy || health.Redis.Status != "down" {
+		t.Errorf("GET /health with Redis down: status = %d, redis = %+v; want 503, down", rec.Code, health.Redis)
+	}
+}
+
+func TestReadyzNotReadyWhileDisconnected(t *testing.T) {
+	client := newTestClient(t)
+	client.Disconnect()
+	redisClient, _ := newTestRedis(t)
+	router := gin.New()
+	NewHealthHandler(usecases.NewHealthUseCase(client, redisClient, usecases.NewWhatsAppAuthUseCase(client, logger.NewNop()), logger.NewNop()), logger.NewNop()).RegisterRoutes(router)
+
+	var readiness usecases.Readiness
+	rec := serve(router, http.MethodGet, "/readyz", "", "")
+	decode(t, rec, &readiness)
+	if rec.Code != http.StatusServiceUnavailable || readiness.WhatsApp != "disconnected" {
+		t.Errorf("status = %d, readiness = %+v; want 503, disconnected", rec.Code, readiness)
+	}
+}
+
+func TestVersion(t *testing.T) {
+	router := gin.New()
+	NewHealthHandler(nil, logger.NewNop()).RegisterRoutes(router)
+	get := func() version.Info {
+		t.Helper()
+		rec := serve(router, http.MethodGet, "/version", "", "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+		}
+		var info version.Info
+		decode(t, rec, &info)
+		return info
+	}
+
+	if info := get(); info.Version != "dev" || info.Commit != "unknown" || info.BuildTime != "unknown" || info.Whatsmeow == "" {
+		t.Errorf("version without injected values = %+v, want dev and unknown", info)
+	}
+
+	// The values -ldflags -X sets at build time
+	defaults := version.Get()
+	t.Cleanup(func() {
+		version.Version, version.Commit, version.BuildTime, version.Whatsmeow = defaults.Version, defaults.Commit, defaults.BuildTime, ""
+	})
+	version.Version, version.Commit, version.BuildTime, version.Whatsmeow = "1.4.0", "3192905", "2026-10-16T17:45:23Z", "v0.0.0-20250101000000-abcdef123456"
+	want := version.Info{Version: "1.4.0", Commit: "3192905", BuildTime: "2026-10-16T17:45:23Z", Whatsmeow: "v0.0.0-20250101000000-abcdef123456"}
+	if info := get(); info != want {
+		t.Errorf("version = %+v, want %+v", info, want)
+	}
+}
+
+func TestReadyzReportsDeviceStoreCheck(t *testing.T) {
+	client := newTestClient(t, whatsapp.WithStoreIntegrityCheck(false))
+	redisClient, _ := newTestRedis(t)
+	router := gin.New()
+	NewHealthHandler(usecases.NewHealthUseCase(client, redisClient, usecases.NewWhatsAppAuthUseCase(client, logger.NewNop()), logger.NewNop()), logger.NewNop()).RegisterRoutes(router)
+
+	var readiness usecases.Readiness
+	rec := serve(router, http.MethodGet, "/readyz", "", "")
+	decode(t, rec, &readiness)
+	if readiness.DeviceStore.Status != whatsapp.StoreOK {
+		t.Errorf("device store = %+v, want %s", readiness.DeviceStore, whatsapp.StoreOK)
+	}
+}
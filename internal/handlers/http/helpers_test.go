@@ -0,0 +1,163 @@
+package http
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/internal/usecases"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/auth"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/redis"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/whatsapp"
+	"go.mau.fi/whatsmeow/proto/waAdv"
+	"go.mau.fi/whatsmeow/store/sqlstore"
+	"go.mau.fi/whatsmeow/types"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// newTestToken configures the JWT secret and returns a valid token
+func newTestToken(t *testing.T) string {
+	t.Helper()
+	t.Setenv("JWT_SECRET", "test-secret")
+	token, err := auth.GenerateToken("tester")
+	if err != nil {
+		t.Fatalf("GenerateToken() error = %v", err)
+	}
+	return token
+}
+
+// newTestClient creates a dry-run WhatsApp client on a temporary device
+// store, so sends succeed without contacting WhatsApp
+func newTestClient(t *testing.T, options ...whatsapp.ClientOption) *whatsapp.Client {
+	t.Helper()
+	options = append([]whatsapp.ClientOption{
+		whatsapp.WithLogger(logger.NewNop()),
+		whatsapp.WithDryRun(0, 0),
+	}, options...)
+	client, err := whatsapp.NewClient("file:"+t.TempDir()+"/whatsapp.db?_foreign_keys=on", options...)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	t.Cleanup(func() { client.Disconnect() })
+	return client
+}
+
+// newLoggedInClient creates a dry-run client whose device store already
+// holds a paired device, so it reports a connected session
+func newLoggedInClient(t *testing.T, options ...whatsapp.ClientOption) *whatsapp.Client {
+	t.Helper()
+	dbPath := "file:" + t.TempDir() + "/whatsapp.db?_foreign_keys=on"
+	container, err := sqlstore.New("sqlite3", dbPath, nil)
+	if err != nil {
+		t.Fatalf("sqlstore.New() error = %v", err)
+	}
+	device := container.NewDevice()
+	jid := types.NewADJID("56961234567", 0, 1)
+	device.ID = &jid
+	device.Account = &waAdv.ADVSignedDeviceIdentity{
+		Details:             []byte{1},
+		AccountSignatureKey: make([]byte, 32),
+		AccountSignature:    make([]byte, 64),
+		DeviceSignature:     make([]byte, 64),
+	}
+	if err := device.Save(); err != nil {
+		t.Fatalf("Save() device error = %v", err)
+	}
+	container.Close()
+
+	options = append([]whatsapp.ClientOption{
+		whatsapp.WithLogger(logger.NewNop()),
+		whatsapp.WithDryRun(0, 0),
+	}, options...)
+	client, err := whatsapp.NewClient(dbPath, options...)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	t.Cleanup(func() { client.Disconnect() })
+	return client
+}
+
+// newTestAuthHandler creates an AuthHandler for the client, used by the
+// routes that require a connected session
+func newTestAuthHandler(client *whatsapp.Client) *AuthHandler {
+	return NewAuthHandler(usecases.NewWhatsAppAuthUseCase(client, logger.NewNop()), nil, logger.NewNop())
+}
+
+// newTestRouter creates a router with token revocation disabled, which the
+// JWT routes require to be registered
+func newTestRouter() *gin.Engine {
+	router := gin.New()
+	router.Use(TokenRevocationMiddleware(nil))
+	return router
+}
+
+// newTestRedis starts an in-process Redis server and returns a client
+// connected to it
+func newTestRedis(t *testing.T) (*redis.Client, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(server.Addr())
+	t.Cleanup(func() { client.Close() })
+	return client, server
+}
+
+// serve sends a request to the router, with the bearer token when given
+func serve(router http.Handler, method, path, body, token string) *httptest.ResponseRecorder {
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+// decode decodes the JSON response body
+func decode(t *testing.T, rec *httptest.ResponseRecorder, v any) {
+	t.Helper()
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("invalid JSON response %q: %v", rec.Body, err)
+	}
+}
+
+// sentMessages records the messages sent through a test client
+type sentMessages struct {
+	mu       sync.Mutex
+	messages []*whatsapp.OutboundMessage
+}
+
+// captureSends records every message sent through the client
+func captureSends(client *whatsapp.Client) *sentMessages {
+	sent := &sentMessages{}
+	client.OnBeforeSend(func(msg *whatsapp.OutboundMessage) error {
+		sent.mu.Lock()
+		defer sent.mu.Unlock()
+		sent.messages = append(sent.messages, msg)
+		return nil
+	})
+	return sent
+}
+
+// all returns the messages sent so far
+func (s *sentMessages) all() []*whatsapp.OutboundMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*whatsapp.OutboundMessage(nil), s.messages...)
+}
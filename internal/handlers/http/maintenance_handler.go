@@ -0,0 +1,105 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/internal/usecases"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// maintenanceGatedRoutes are the send and webhook routes rejected during maintenance
+var maintenanceGatedRoutes = map[string]bool{
+	"/booking/confirm":      true,
+	"/booking/cancel":       true,
+	"/messages/raw":         true,
+	"/messages/bulk":        true,
+	"/templates/:name/send": true,
+	"/webhook":              true,
+}
+
+// MaintenanceHandler handles the maintenance mode toggle
+type MaintenanceHandler struct {
+	maintenanceUseCase *usecases.MaintenanceUseCase
+	logger             logger.Logger
+}
+
+// NewMaintenanceHandler creates a new MaintenanceHandler
+func NewMaintenanceHandler(maintenanceUseCase *usecases.MaintenanceUseCase, logger logger.Logger) *MaintenanceHandler {
+	return &MaintenanceHandler{
+		maintenanceUseCase: maintenanceUseCase,
+		logger:             logger,
+	}
+}
+
+// RegisterRoutes registers the maintenance routes
+func (h *MaintenanceHandler) RegisterRoutes(router *gin.Engine) {
+	admin := router.Group("/admin", JWTMiddleware())
+	{
+		admin.GET("/maintenance", h.GetMaintenance)
+		admin.POST("/maintenance", h.SetMaintenance)
+	}
+}
+
+// Middleware rejects send and webhook requests with 503 while maintenance
+// mode is on; health and status routes stay reachable
+func (h *MaintenanceHandler) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if maintenanceGatedRoutes[c.FullPath()] && h.maintenanceUseCase.Enabled(c.Request.Context()) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"error":     "Service is in maintenance mode",
+				"code":      "MAINTENANCE",
+				"retryable": true,
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// MaintenanceRequest represents the request body for toggling maintenance mode
+type MaintenanceRequest struct {
+	Enabled *bool `json:"enabled" binding:"required"`
+}
+
+// GetMaintenance returns whether maintenance mode is on
+// @Summary Get maintenance mode
+// @Description Returns whether maintenance mode is enabled
+// @Tags admin
+// @Produce json
+// @Success 200 {object} map[string]bool "Maintenance state"
+// @Failure 401 {object} map[string]string "Error message"
+// @Router /admin/maintenance [get]
+func (h *MaintenanceHandler) GetMaintenance(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"enabled": h.maintenanceUseCase.Enabled(c.Request.Context())})
+}
+
+// SetMaintenance turns maintenance mode on or off
+// @Summary Toggle maintenance mode
+// @Description Rejects send and webhook requests while enabled; inbound messages are deferred and processed when disabled
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param request body MaintenanceRequest true "Maintenance state"
+// @Success 200 {object} map[string]bool "Maintenance state"
+// @Failure 400 {object} map[string]string "Error message"
+// @Failure 401 {object} map[string]string "Error message"
+// @Failure 500 {object} map[string]string "Error message"
+// @Router /admin/maintenance [post]
+func (h *MaintenanceHandler) SetMaintenance(c *gin.Context) {
+	var request MaintenanceRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	if err := h.maintenanceUseCase.SetEnabled(c.Request.Context(), *request.Enabled); err != nil {
+		h.logger.Error("Failed to toggle maintenance mode", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to toggle maintenance mode"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"enabled": *request.Enabled})
+}
@@ -0,0 +1,111 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/internal/usecases"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/store"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/whatsapp"
+)
+
+// newMaintenanceRouter serves the maintenance toggle and, behind the
+// maintenance middleware, stub send, webhook and status routes
+func newMaintenanceRouter() *gin.Engine {
+	maintenance := usecases.NewMaintenanceUseCase(store.NewMemoryStore(), logger.NewNop(),
+		func(context.Context, *whatsapp.WhatsAppMessage) {})
+	handler := NewMaintenanceHandler(maintenance, logger.NewNop())
+
+	router := newTestRouter()
+	router.Use(handler.Middleware())
+	handler.RegisterRoutes(router)
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.POST("/messages/raw", ok)
+	router.POST("/booking/confirm", ok)
+	router.POST("/webhook", ok)
+	router.GET("/health", ok)
+	router.GET("/auth/status", ok)
+	return router
+}
+
+func TestMaintenanceToggle(t *testing.T) {
+	token := newTestToken(t)
+	router := newMaintenanceRouter()
+
+	if rec := serve(router, http.MethodPost, "/admin/maintenance", `{"enabled":true}`, ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("toggle without token: status = %d, want 401", rec.Code)
+	}
+	if rec := serve(router, http.MethodPost, "/admin/maintenance", `{}`, token); rec.Code != http.StatusBadRequest {
+		t.Errorf("toggle without enabled: status = %d, want 400", rec.Code)
+	}
+
+	for _, enabled := range []bool{true, false} {
+		body := `{"enabled":false}`
+		if enabled {
+			body = `{"enabled":true}`
+		}
+		if rec := serve(router, http.MethodPost, "/admin/maintenance", body, token); rec.Code != http.StatusOK {
+			t.Fatalf("toggle %s: status = %d, want 200: %s", body, rec.Code, rec.Body)
+		}
+
+		rec := serve(router, http.MethodGet, "/admin/maintenance", "", token)
+		var state struct {
+			Enabled bool `json:"enabled"`
+		}
+		decode(t, rec, &state)
+		if state.Enabled != enabled {
+			t.Errorf("after %s: enabled = %v", body, state.Enabled)
+		}
+	}
+}
+
+func TestMaintenanceGatesRoutes(t *testing.T) {
+	token := newTestToken(t)
+	router := newMaintenanceRouter()
+	if rec := serve(router, http.MethodPost, "/admin/maintenance", `{"enabled":true}`, token); rec.Code != http.StatusOK {
+		t.Fatalf("enable maintenance: status = %d: %s", rec.Code, rec.Body)
+	}
+
+	tests := []struct {
+		method string
+		path   string
+		gated  bool
+	}{
+		{http.MethodPost, "/messages/raw", true},
+		{http.MethodPost, "/booking/confirm", true},
+		{http.MethodPost, "/webhook", true},
+		{http.MethodGet, "/health", false},
+		{http.MethodGet, "/auth/status", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := serve(router, tt.method, tt.path, "", token)
+			if !tt.gated {
+				if rec.Code != http.StatusOK {
+					t.Errorf("status = %d, want 200", rec.Code)
+				}
+				return
+			}
+
+			if rec.Code != http.StatusServiceUnavailable {
+				t.Fatalf("status = %d, want 503", rec.Code)
+			}
+			var body struct {
+				Code      string `json:"code"`
+				Retryable bool   `json:"retryable"`
+			}
+			decode(t, rec, &body)
+			if body.Code != "MAINTENANCE" || !body.Retryable {
+				t.Errorf("body = %+v, want retryable MAINTENANCE", body)
+			}
+		})
+	}
+
+	serve(router, http.MethodPost, "/admin/maintenance", `{"enabled":false}`, token)
+	if rec := serve(router, http.MethodPost, "/messages/raw", "", token); rec.Code != http.StatusOK {
+		t.Errorf("send after maintenance: status = %d, want 200", rec.Code)
+	}
+}
@@ -0,0 +1,144 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/internal/usecases"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+)
+
+// MessageHandler handles generic message endpoints
+type MessageHandler struct {
+	messageUseCase   *usecases.MessageUseCase
+	broadcastUseCase *usecases.BroadcastUseCase
+	logger           logger.Logger
+}
+
+// NewMessageHandler creates a new MessageHandler
+func NewMessageHandler(messageUseCase *usecases.MessageUseCase, broadcastUseCase *usecases.BroadcastUseCase, logger logger.Logger) *MessageHandler {
+	return &MessageHandler{
+		messageUseCase:   messageUseCase,
+		broadcastUseCase: broadcastUseCase,
+		logger:           logger,
+	}
+}
+
+// RegisterRoutes registers the message routes
+func (h *MessageHandler) RegisterRoutes(router *gin.Engine, authHandler *AuthHandler) {
+	messages := router.Group("/messages", JWTMiddleware(), authHandler.AuthMiddleware())
+	{
+		messages.POST("/raw", h.SendRaw)
+		messages.POST("/bulk", h.SendBulk)
+		messages.POST("/broadcast/test", h.SendTestBroadcast)
+	}
+}
+
+// RawMessageRequest represents the request body for sending a raw message
+type RawMessageRequest struct {
+	To      string          `json:"to" binding:"required"`
+	Message json.RawMessage `json:"message" binding:"required"`
+}
+
+// SendRaw sends a pre-built waE2E.Message
+// @Summary Send a raw WhatsApp message
+// @Description Sends a waE2E.Message given in its protojson representation
+// @Tags messages
+// @Accept json
+// @Produce json
+// @Param request body RawMessageRequest true "Raw message request"
+// @Success 200 {object} usecases.SendResult "Sent message"
+// @Failure 400 {object} map[string]string "Error message"
+// @Failure 401 {object} map[string]string "Error message"
+// @Failure 429 {object} map[string]string "Error message"
+// @Failure 500 {object} map[string]string "Error message"
+// @Failure 503 {object} map[string]string "Error message"
+// @Router /messages/raw [post]
+func (h *MessageHandler) SendRaw(c *gin.Context) {
+	var request RawMessageRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		invalidSendRequest(c, "Invalid request body: "+err.Error())
+		return
+	}
+
+	result, err := h.messageUseCase.SendRaw(c.Request.Context(), request.To, request.Message)
+	if err != nil {
+		sendError(c, h.logger, err, "Failed to send message")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// BulkMessageRequest represents the request body for sending a text to many recipients
+type BulkMessageRequest struct {
+	Recipients []string `json:"recipients" binding:"required,min=1"`
+	Text       string   `json:"text" binding:"required"`
+}
+
+// SendBulk sends a text to each recipient
+// @Summary Send a text to many recipients
+// @Description Sends the text to each recipient in turn, pausing BULK_SEND_DELAY between sends, and returns the result of each send. A failed recipient does not stop the batch.
+// @Tags messages
+// @Accept json
+// @Produce json
+// @Param request body BulkMessageRequest true "Bulk message request"
+// @Success 200 {object} usecases.BulkResult "Result per recipient"
+// @Failure 400 {object} map[string]string "Error message"
+// @Failure 401 {object} map[string]string "Error message"
+// @Failure 429 {object} map[string]string "Error message"
+// @Failure 500 {object} map[string]string "Error message"
+// @Router /messages/bulk [post]
+func (h *MessageHandler) SendBulk(c *gin.Context) {
+	var request BulkMessageRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		invalidSendRequest(c, "Invalid request body: "+err.Error())
+		return
+	}
+
+	result, err := h.messageUseCase.SendBulk(c.Request.Context(), request.Recipients, request.Text)
+	if err != nil {
+		sendError(c, h.logger, err, "Failed to send bulk message")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// BroadcastRequest represents the request body for a template broadcast
+type BroadcastRequest struct {
+	Template   string            `json:"template" binding:"required"`
+	Recipients []string          `json:"recipients"`
+	Variables  map[string]string `json:"variables"`
+}
+
+// SendTestBroadcast sends a template broadcast to the sandbox numbers only
+// @Summary Send a test broadcast
+// @Description Sends the template to the numbers in SANDBOX_NUMBERS instead of the given recipients, returning the result of each send
+// @Tags messages
+// @Accept json
+// @Produce json
+// @Param request body BroadcastRequest true "Broadcast request"
+// @Success 200 {object} usecases.BroadcastResult "Broadcast result"
+// @Failure 400 {object} map[string]string "Error message"
+// @Failure 401 {object} map[string]string "Error message"
+// @Failure 404 {object} map[string]string "Error message"
+// @Failure 409 {object} map[string]string "Error message"
+// @Failure 500 {object} map[string]string "Error message"
+// @Router /messages/broadcast/test [post]
+func (h *MessageHandler) SendTestBroadcast(c *gin.Context) {
+	var request BroadcastRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		invalidSendRequest(c, "Invalid request body: "+err.Error())
+		return
+	}
+
+	result, err := h.broadcastUseCase.SendTest(c.Request.Context(), request.Template, request.Recipients, request.Variables)
+	if err != nil {
+		sendError(c, h.logger, err, "Failed to send test broadcast")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
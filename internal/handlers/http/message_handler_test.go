@@ -0,0 +1,178 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/internal/usecases"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/store"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/templates"
+)
+
+func TestSendRaw(t *testing.T) {
+	token := newTestToken(t)
+	client := newLoggedInClient(t)
+	sent := captureSends(client)
+	router := newTestRouter()
+	messageUseCase := usecases.NewMessageUseCase(client, logger.NewNop(), "CL")
+	NewMessageHandler(messageUseCase, nil, logger.NewNop()).RegisterRoutes(router, newTestAuthHandler(client))
+
+	t.Run("text", func(t *testing.T) {
+		rec := serve(router, http.MethodPost, "/messages/raw", `{"to":"+56 9 6123 4567","message":{"conversation":"Hola"}}`, token)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+		}
+		var result usecases.SendResult
+		decode(t, rec, &result)
+		if result.MessageID == "" {
+			t.Error("response has no message ID")
+		}
+		msg := sent.all()[len(sent.all())-1]
+		if msg.To.User != "56961234567" || msg.Message.GetConversation() != "Hola" {
+			t.Errorf("sent %s to %s, want Hola to 56961234567", msg.Message, msg.To)
+		}
+	})
+
+	t.Run("buttons", func(t *testing.T) {
+		body := `{"to":"56961234567","message":{"buttonsMessage":{
+			"contentText":"¿Confirmas tu cita?",
+			"headerType":"EMPTY",
+			"buttons":[
+				{"buttonID":"booking_confirm","buttonText":{"displayText":"Confirmar"},"type":"RESPONSE"},
+				{"buttonID":"booking_cancel","buttonText":{"displayText":"Cancelar"},"type":"RESPONSE"}
+			]}}}`
+		rec := serve(router, http.MethodPost, "/messages/raw", body, token)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+		}
+		buttons := sent.all()[len(sent.all())-1].Message.GetButtonsMessage()
+		if buttons.GetContentText() != "¿Confirmas tu cita?" || len(buttons.GetButtons()) != 2 ||
+			buttons.GetButtons()[0].GetButtonID() != "booking_confirm" {
+			t.Errorf("sent buttons message %v", buttons)
+		}
+	})
+
+	rejected := []struct {
+		name string
+		body string
+		code string
+	}{
+		{"malformed message", `{"to":"56961234567","message":{"conversation":42}}`, CodeInvalidRequest},
+		{"unknown field", `{"to":"56961234567","message":{"notAField":"x"}}`, CodeInvalidRequest},
+		{"disallowed type", `{"to":"56961234567","message":{"protocolMessage":{"type":"REVOKE"}}}`, CodeInvalidRequest},
+		{"no content", `{"to":"56961234567","message":{}}`, CodeInvalidRequest},
+		{"invalid phone", `{"to":"123","message":{"conversation":"Hola"}}`, CodeInvalidPhone},
+		{"missing recipient", `{"message":{"conversation":"Hola"}}`, CodeInvalidRequest},
+	}
+	for _, tt := range rejected {
+		t.Run(tt.name, func(t *testing.T) {
+			before := len(sent.all())
+			rec := serve(router, http.MethodPost, "/messages/raw", tt.body, token)
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusBadRequest, rec.Body)
+			}
+			var body struct {
+				Code string `json:"code"`
+			}
+			decode(t, rec, &body)
+			if body.Code != tt.code {
+				t.Errorf("code = %q, want %q", body.Code, tt.code)
+			}
+			if len(sent.all()) != before {
+				t.Error("rejected message was sent")
+			}
+		})
+	}
+
+	if rec := serve(router, http.MethodPost, "/messages/raw", `{"to":"56961234567","message":{"conversation":"Hola"}}`, ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("without token: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestSendTestBroadcast(t *testing.T) {
+	token := newTestToken(t)
+	client := newLoggedInClient(t)
+	sent := captureSends(client)
+	templateUseCase := usecases.NewTemplateUseCase(templates.NewStoreRepository(store.NewMemoryStore()), client, logger.NewNop(), "CL")
+	if _, err := templateUseCase.Save(context.Background(), "promo", "Hola {{name}}"); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	newRouter := func(sandbox []string) *gin.Engine {
+		router := newTestRouter()
+		broadcastUseCase := usecases.NewBroadcastUseCase(templateUseCase, sandbox, logger.NewNop())
+		NewMessageHandler(nil, broadcastUseCase, logger.NewNop()).RegisterRoutes(router, newTestAuthHandler(client))
+		return router
+	}
+	body := `{"template":"promo","recipients":["+56 9 6123 4567","56961234568"],"variables":{"name":"Ana"}}`
+
+	// Only the sandbox number is contacted
+	rec := serve(newRouter([]string{"56961234501"}), http.MethodPost, "/messages/broadcast/test", body, token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	var result usecases.BroadcastResult
+	decode(t, rec, &result)
+	if !result.Sandbox || result.Sent != 1 || result.IgnoredRecipients != 2 ||
+		len(result.Results) != 1 || result.Results[0].To != "56961234501" {
+		t.Errorf("result = %+v, want one send to the sandbox number", result)
+	}
+	messages := sent.all()
+	if len(messages) != 1 || messages[0].To.User != "56961234501" {
+		t.Fatalf("sent %d messages, want one to the sandbox number", len(messages))
+	}
+
+	// Without a sandbox the broadcast is refused
+	rec = serve(newRouter(nil), http.MethodPost, "/messages/broadcast/test", body, token)
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("status without a sandbox = %d, want %d: %s", rec.Code, http.StatusConflict, rec.Body)
+	}
+	var refused struct {
+		Code string `json:"code"`
+	}
+	decode(t, rec, &refused)
+	if refused.Code != CodeNoSandbox {
+		t.Errorf("code = %q, want %q", refused.Code, CodeNoSandbox)
+	}
+	if got := len(sent.all()); got != 1 {
+		t.Errorf("sent %d messages in total, want nothing sent without a sandbox", got)
+	}
+}
+
+func TestSendBulk(t *testing.T) {
+	token := newTestToken(t)
+	client := newLoggedInClient(t)
+	sent := captureSends(client)
+	router := newTestRouter()
+	messageUseCase := usecases.NewMessageUseCase(client, logger.NewNop(), "CL", usecases.WithBulkSend(2, 0))
+	NewMessageHandler(messageUseCase, nil, logger.NewNop()).RegisterRoutes(router, newTestAuthHandler(client))
+
+	rec := serve(router, http.MethodPost, "/messages/bulk", `{"recipients":["56961234501","56961234501"],"text":"Hola"}`, token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	var result usecases.BulkResult
+	decode(t, rec, &result)
+	if result.Sent != 1 || result.Skipped != 1 || len(result.Results) != 2 || result.Results[1].Status != usecases.BulkSkipped {
+		t.Errorf("result = %+v, want one sent and the duplicate skipped", result)
+	}
+
+	// A batch over BULK_MAX_RECIPIENTS is rejected before sending anything
+	before := len(sent.all())
+	rec = serve(router, http.MethodPost, "/messages/bulk", `{"recipients":["56961234501","56961234502","56961234503"],"text":"Hola"}`, token)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusBadRequest, rec.Body)
+	}
+	var body struct {
+		Code string `json:"code"`
+	}
+	decode(t, rec, &body)
+	if body.Code != CodeInvalidRequest {
+		t.Errorf("code = %q, want %q", body.Code, CodeInvalidRequest)
+	}
+	if len(sent.all()) != before {
+		t.Error("an oversized batch was partly sent")
+	}
+}
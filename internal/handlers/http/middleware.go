@@ -0,0 +1,149 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/auth"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/requestid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestIDMiddleware reuses the inbound request ID header or generates a
+// new ID, and stores it in the request context so it reaches outbound
+// callbacks and send records
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestid.Header())
+		if id == "" {
+			id = requestid.New()
+		}
+
+		c.Set("request_id", id)
+		c.Request = c.Request.WithContext(requestid.NewContext(c.Request.Context(), id))
+		c.Header(requestid.Header(), id)
+		c.Next()
+	}
+}
+
+// TracingMiddleware starts a server span per request, continuing the trace
+// of an inbound traceparent header, so spans created while handling the
+// request (e.g. Redis commands) join the caller's trace
+func TracingMiddleware() gin.HandlerFunc {
+	tracer := otel.Tracer("github.com/pabbloacevedog/whatspp-service-glidpa/internal/handlers/http")
+	return func(c *gin.Context) {
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+
+		route := c.FullPath()
+		if route == "" {
+			route = c.Request.URL.Path
+		}
+		ctx, span := tracer.Start(ctx, c.Request.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", c.Request.Method),
+				attribute.String("http.route", route),
+			))
+		defer span.End()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+}
+
+// revocationsKey is the context key holding the token revocation list
+const revocationsKey = "token_revocations"
+
+// TokenRevocationMiddleware makes the JWT middlewares reject revoked tokens.
+// It must be registered before the routes that require a token, with a nil
+// list when revocation is disabled; without it those routes fail closed.
+func TokenRevocationMiddleware(revocations *auth.RevocationList) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(revocationsKey, revocations)
+		c.Next()
+	}
+}
+
+// JWTMiddleware is a middleware that requires a valid bearer token
+func JWTMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		tokenString, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok || tokenString == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing bearer token"})
+			c.Abort()
+			return
+		}
+
+		if !setClaims(c, tokenString) {
+			return
+		}
+		c.Next()
+	}
+}
+
+// setClaims validates a token and stores its claims in the request context,
+// or aborts the request
+func setClaims(c *gin.Context, tokenString string) bool {
+	// A route registered ahead of TokenRevocationMiddleware would otherwise
+	// accept revoked tokens
+	value, ok := c.Get(revocationsKey)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Token revocation not configured"})
+		c.Abort()
+		return false
+	}
+	revocations, _ := value.(*auth.RevocationList)
+	claims, err := auth.ValidateTokenWithRevocation(c.Request.Context(), tokenString, revocations)
+	if errors.Is(err, auth.ErrTokenRevoked) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Token revoked"})
+		c.Abort()
+		return false
+	}
+	if errors.Is(err, auth.ErrRevocationUnavailable) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Token revocation list unavailable"})
+		c.Abort()
+		return false
+	}
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		c.Abort()
+		return false
+	}
+
+	c.Set("user_id", claims.UserID)
+	c.Set("tenant_id", claims.TenantID)
+	c.Set("claims", claims)
+	return true
+}
+
+// tenantAccount returns the business account of a request: the account
+// requested or, when empty, the tenant of the token. A token bound to a
+// tenant cannot send from another account.
+func tenantAccount(c *gin.Context, accountID string) (string, bool) {
+	tenantID := c.GetString("tenant_id")
+	if tenantID == "" {
+		return accountID, true
+	}
+	if accountID != "" && accountID != tenantID {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":     "account_id does not match the tenant of the token",
+			"code":      CodeInvalidRequest,
+			"retryable": false,
+		})
+		return "", false
+	}
+	return tenantID, true
+}
@@ -0,0 +1,76 @@
+package http
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/auth"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/ratelimit"
+	"go.uber.org/zap"
+)
+
+// rateLimitedRoutes are the routes that send messages to WhatsApp
+var rateLimitedRoutes = map[string]bool{
+	"/booking/confirm":         true,
+	"/booking/cancel":          true,
+	"/messages/raw":            true,
+	"/messages/bulk":           true,
+	"/messages/broadcast/test": true,
+	"/templates/:name/send":    true,
+	"/groups/:jid/messages":    true,
+}
+
+// RateLimitMiddleware limits the send routes per caller: the user_id of a
+// valid bearer token or, without one, the client IP. Requests over the
+// limit get 429 with Retry-After. If Redis fails the request is let
+// through, so an outage does not stop sends.
+func RateLimitMiddleware(limiter *ratelimit.Limiter, logger logger.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !rateLimitedRoutes[c.FullPath()] {
+			c.Next()
+			return
+		}
+
+		key := rateLimitKey(c)
+		allowed, retryAfter, err := limiter.Allow(c.Request.Context(), key)
+		if err != nil {
+			logger.Warn("Rate limit unavailable, allowing request", zap.String("key", key), zap.Error(err))
+			c.Next()
+			return
+		}
+		if !allowed {
+			seconds := int(math.Ceil(retryAfter.Seconds()))
+			if seconds < 1 {
+				seconds = 1
+			}
+			logger.Warn("Rate limit exceeded",
+				zap.String("key", key),
+				zap.String("route", c.FullPath()),
+				zap.Int("retry_after_seconds", seconds))
+			c.Header("Retry-After", strconv.Itoa(seconds))
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error":     "Too many requests",
+				"code":      CodeRateLimited,
+				"retryable": true,
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// rateLimitKey identifies the caller of a request. The token is validated
+// here because the route's JWT middleware runs later.
+func rateLimitKey(c *gin.Context) string {
+	if tokenString, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok && tokenString != "" {
+		if claims, err := auth.ValidateToken(tokenString); err == nil && claims.UserID != "" {
+			return "user:" + claims.UserID
+		}
+	}
+	return "ip:" + c.ClientIP()
+}
@@ -0,0 +1,36 @@
+package http
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/ratelimit"
+)
+
+func TestRateLimitFailsOpenWhenRedisIsDown(t *testing.T) {
+	redisClient, server := newTestRedis(t)
+	router := gin.New()
+	router.Use(RateLimitMiddleware(ratelimit.NewLimiter(redisClient, 1, 1), logger.NewNop()))
+	router.POST("/messages/raw", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/auth/status", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	if rec := serve(router, http.MethodPost, "/messages/raw", "", ""); rec.Code != http.StatusOK {
+		t.Fatalf("first request: status = %d, want 200", rec.Code)
+	}
+	rec := serve(router, http.MethodPost, "/messages/raw", "", "")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("second request: status = %d, Retry-After = %q; want 429 with Retry-After", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	// Routes that do not send are not limited
+	if rec := serve(router, http.MethodGet, "/auth/status", "", ""); rec.Code != http.StatusOK {
+		t.Errorf("unlimited route: status = %d, want 200", rec.Code)
+	}
+
+	server.Close()
+
+	if rec := serve(router, http.MethodPost, "/messages/raw", "", ""); rec.Code != http.StatusOK {
+		t.Errorf("with Redis down: status = %d, want the request let through", rec.Code)
+	}
+}
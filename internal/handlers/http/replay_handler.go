@@ -0,0 +1,72 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/internal/usecases"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// ReplayHandler handles replay of journaled events
+type ReplayHandler struct {
+	replayUseCase *usecases.ReplayUseCase
+	logger        logger.Logger
+}
+
+// NewReplayHandler creates a new ReplayHandler
+func NewReplayHandler(replayUseCase *usecases.ReplayUseCase, logger logger.Logger) *ReplayHandler {
+	return &ReplayHandler{
+		replayUseCase: replayUseCase,
+		logger:        logger,
+	}
+}
+
+// RegisterRoutes registers the replay routes
+func (h *ReplayHandler) RegisterRoutes(router *gin.Engine) {
+	admin := router.Group("/admin", JWTMiddleware())
+	{
+		admin.POST("/replay", h.Replay)
+	}
+}
+
+// ReplayRequest represents the request body for replaying an event
+type ReplayRequest struct {
+	MessageID   string `json:"message_id" binding:"required"`
+	PhoneNumber string `json:"phone_number" binding:"required"`
+}
+
+// Replay replays a journaled inbound message event
+// @Summary Replay a recorded message event
+// @Description Feeds a journaled inbound message through the handler chain, replying to the given phone number
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param request body ReplayRequest true "Replay request"
+// @Success 200 {object} map[string]string "Success message"
+// @Failure 400 {object} map[string]string "Error message"
+// @Failure 401 {object} map[string]string "Error message"
+// @Failure 404 {object} map[string]string "Error message"
+// @Failure 500 {object} map[string]string "Error message"
+// @Router /admin/replay [post]
+func (h *ReplayHandler) Replay(c *gin.Context) {
+	var request ReplayRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	if err := h.replayUseCase.Replay(request.MessageID, request.PhoneNumber); err != nil {
+		if errors.Is(err, usecases.ErrJournalEntryNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Journal entry not found"})
+			return
+		}
+		h.logger.Error("Failed to replay event", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to replay event"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Event replayed"})
+}
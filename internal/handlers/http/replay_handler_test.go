@@ -0,0 +1,74 @@
+package http
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/internal/usecases"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/whatsapp"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestReplayRecordedEvent(t *testing.T) {
+	token := newTestToken(t)
+	journal, err := whatsapp.OpenJournal(filepath.Join(t.TempDir(), "events.jsonl"))
+	if err != nil {
+		t.Fatalf("OpenJournal() error = %v", err)
+	}
+	defer journal.Close()
+
+	sender := types.NewJID("56961234567", types.DefaultUserServer)
+	if err := journal.Record(&events.Message{
+		Info: types.MessageInfo{
+			MessageSource: types.MessageSource{Chat: sender, Sender: sender},
+			ID:            "msg-1",
+			Timestamp:     time.Now(),
+		},
+		Message: &waE2E.Message{Conversation: proto.String("Sí")},
+	}); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+
+	client := newTestClient(t)
+	replayed := make(chan *whatsapp.WhatsAppMessage, 1)
+	client.AddEventHandler(func(evt interface{}) {
+		if msg, ok := evt.(*whatsapp.WhatsAppMessage); ok {
+			replayed <- msg
+		}
+	})
+	router := newTestRouter()
+	NewReplayHandler(usecases.NewReplayUseCase(client, journal, logger.NewNop()), logger.NewNop()).RegisterRoutes(router)
+
+	body := `{"message_id": "msg-1", "phone_number": "56961234567"}`
+	if rec := serve(router, http.MethodPost, "/admin/replay", body, ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("without token: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	select {
+	case msg := <-replayed:
+		t.Fatalf("event replayed without a token: %+v", msg)
+	default:
+	}
+
+	if rec := serve(router, http.MethodPost, "/admin/replay", `{"message_id": "missing", "phone_number": "56961234567"}`, token); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown message: status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+
+	if rec := serve(router, http.MethodPost, "/admin/replay", body, token); rec.Code != http.StatusOK {
+		t.Fatalf("with token: status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	select {
+	case msg := <-replayed:
+		// The recorded sender is redacted; replies go to the given number
+		if !msg.Replayed || msg.ID != "msg-1" || msg.Body != "Sí" || msg.From != "56961234567" {
+			t.Errorf("replayed message = %+v", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("event not replayed")
+	}
+}
@@ -0,0 +1,96 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/internal/usecases"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// ScheduleHandler handles inspection of scheduled jobs
+type ScheduleHandler struct {
+	bookingUseCase *usecases.BookingUseCase
+	logger         logger.Logger
+	phoneRegion    string
+}
+
+// NewScheduleHandler creates a new ScheduleHandler
+func NewScheduleHandler(bookingUseCase *usecases.BookingUseCase, logger logger.Logger, phoneRegion string) *ScheduleHandler {
+	return &ScheduleHandler{
+		bookingUseCase: bookingUseCase,
+		logger:         logger,
+		phoneRegion:    phoneRegion,
+	}
+}
+
+// RegisterRoutes registers the schedule routes
+func (h *ScheduleHandler) RegisterRoutes(router *gin.Engine) {
+	admin := router.Group("/admin", JWTMiddleware())
+	{
+		admin.GET("/schedule", h.ListJobs)
+		admin.DELETE("/schedule/:id", h.CancelJob)
+	}
+}
+
+// ListJobs returns the pending scheduled jobs
+// @Summary List scheduled jobs
+// @Description Returns the pending scheduled jobs, soonest first, optionally filtered by type and number
+// @Tags admin
+// @Produce json
+// @Param type query string false "Job type (booking_expiry)"
+// @Param number query string false "Phone number"
+// @Success 200 {object} map[string]interface{} "Scheduled jobs"
+// @Failure 400 {object} map[string]string "Error message"
+// @Failure 401 {object} map[string]string "Error message"
+// @Failure 500 {object} map[string]string "Error message"
+// @Router /admin/schedule [get]
+func (h *ScheduleHandler) ListJobs(c *gin.Context) {
+	var number string
+	if raw := c.Query("number"); raw != "" {
+		normalized, err := utils.NormalizePhone(raw, h.phoneRegion)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid phone number: " + err.Error()})
+			return
+		}
+		number = normalized
+	}
+
+	jobs, err := h.bookingUseCase.ScheduledJobs(c.Request.Context(), c.Query("type"), number)
+	if err != nil {
+		h.logger.Error("Failed to list scheduled jobs", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list scheduled jobs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"jobs": jobs})
+}
+
+// CancelJob cancels a scheduled job
+// @Summary Cancel a scheduled job
+// @Description Cancels the job; a cancelled booking expiry keeps the booking pending without a deadline
+// @Tags admin
+// @Produce json
+// @Param id path string true "Job ID"
+// @Success 200 {object} map[string]string "Success message"
+// @Failure 401 {object} map[string]string "Error message"
+// @Failure 404 {object} map[string]string "Error message"
+// @Failure 500 {object} map[string]string "Error message"
+// @Router /admin/schedule/{id} [delete]
+func (h *ScheduleHandler) CancelJob(c *gin.Context) {
+	err := h.bookingUseCase.CancelScheduledJob(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, usecases.ErrJobNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Scheduled job not found"})
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to cancel scheduled job", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel scheduled job"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Scheduled job cancelled"})
+}
@@ -0,0 +1,122 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/internal/usecases"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/store"
+)
+
+// schedulePage is the body of GET /admin/schedule
+type schedulePage struct {
+	Jobs []usecases.ScheduledJob `json:"jobs"`
+}
+
+// newScheduleRouter serves the schedule routes on a booking use case with an
+// unconfirmed booking for each phone, the first expiring soonest
+func newScheduleRouter(t *testing.T, phones ...string) (*gin.Engine, *usecases.BookingUseCase) {
+	t.Helper()
+	client := newTestClient(t)
+	stateStore := store.NewMemoryStore()
+	for i, phone := range phones {
+		bookingUseCase := usecases.NewBookingUseCase(client, logger.NewNop(),
+			usecases.WithBookingStore(stateStore),
+			usecases.WithConfirmationDeadline(time.Duration(i+1)*time.Hour))
+		_, err := bookingUseCase.SendConfirmationMessage(context.Background(), usecases.BookingRequest{
+			BookingID:    "booking-" + phone,
+			ServiceName:  "Corte",
+			UserName:     "Ana",
+			LocationName: "Centro",
+			StartTime:    "10:00",
+			Date:         "01/06/2025",
+			EmployeeName: "Luis",
+			PhoneNumber:  phone,
+		})
+		if err != nil {
+			t.Fatalf("SendConfirmationMessage() error = %v", err)
+		}
+	}
+
+	bookingUseCase := usecases.NewBookingUseCase(client, logger.NewNop(),
+		usecases.WithBookingStore(stateStore),
+		usecases.WithConfirmationDeadline(time.Hour))
+	router := newTestRouter()
+	NewScheduleHandler(bookingUseCase, logger.NewNop(), "CL").RegisterRoutes(router)
+	return router, bookingUseCase
+}
+
+func TestListScheduledJobs(t *testing.T) {
+	token := newTestToken(t)
+	router, _ := newScheduleRouter(t, "56961234567", "56961234568")
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{name: "all jobs soonest first", want: []string{"56961234567", "56961234568"}},
+		{name: "by type", query: "?type=booking_expiry", want: []string{"56961234567", "56961234568"}},
+		{name: "unknown type", query: "?type=reminder"},
+		{name: "by number", query: "?number=%2B56961234568", want: []string{"56961234568"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(router, http.MethodGet, "/admin/schedule"+tt.query, "", token)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+			}
+			var page schedulePage
+			decode(t, rec, &page)
+			if len(page.Jobs) != len(tt.want) {
+				t.Fatalf("listed %d jobs, want %d: %+v", len(page.Jobs), len(tt.want), page.Jobs)
+			}
+			for i, job := range page.Jobs {
+				if job.PhoneNumber != tt.want[i] || job.BookingID != "booking-"+tt.want[i] || job.Type != usecases.JobTypeBookingExpiry {
+					t.Errorf("job %d = %+v, want the expiry of booking-%s", i, job, tt.want[i])
+				}
+				if job.ID != usecases.JobTypeBookingExpiry+":"+tt.want[i] || job.FiresAt.IsZero() {
+					t.Errorf("job %d has ID %q and fire time %v", i, job.ID, job.FiresAt)
+				}
+			}
+		})
+	}
+
+	if rec := serve(router, http.MethodGet, "/admin/schedule?number=123", "", token); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid number: status = %d, want 400", rec.Code)
+	}
+	if rec := serve(router, http.MethodGet, "/admin/schedule", "", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("without a token: status = %d, want 401", rec.Code)
+	}
+}
+
+func TestCancelScheduledJob(t *testing.T) {
+	token := newTestToken(t)
+	router, bookingUseCase := newScheduleRouter(t, "56961234567", "56961234568")
+
+	rec := serve(router, http.MethodDelete, "/admin/schedule/booking_expiry:56961234567", "", token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+
+	var page schedulePage
+	decode(t, serve(router, http.MethodGet, "/admin/schedule", "", token), &page)
+	if len(page.Jobs) != 1 || page.Jobs[0].PhoneNumber != "56961234568" {
+		t.Errorf("jobs after cancelling = %+v, want only 56961234568", page.Jobs)
+	}
+	// The booking stays pending but no longer expires
+	expired, err := bookingUseCase.ExpireOverdue(context.Background(), time.Now().Add(24*time.Hour))
+	if err != nil || expired != 1 {
+		t.Errorf("ExpireOverdue() = %d, %v, want only the other booking", expired, err)
+	}
+
+	for _, id := range []string{"booking_expiry:56961234567", "booking_expiry:56961234568", "reminder:56961234567", "unknown"} {
+		if rec := serve(router, http.MethodDelete, "/admin/schedule/"+id, "", token); rec.Code != http.StatusNotFound {
+			t.Errorf("DELETE %s: status = %d, want 404", id, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/internal/usecases"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/templates"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/whatsapp"
+	"go.uber.org/zap"
+)
+
+// Error codes returned by the send endpoints. Callers decide whether to retry
+// from the code and the retryable flag sent with it. OUTSIDE_WINDOW and
+// CIRCUIT_OPEN complete the taxonomy; no send path returns them yet.
+const (
+	CodeNotConnected   = "NOT_CONNECTED"
+	CodeSessionInvalid = "SESSION_INVALID"
+	CodeInvalidPhone   = "INVALID_PHONE"
+	CodeRateLimited    = "RATE_LIMITED"
+	CodeOutsideWindow  = "OUTSIDE_WINDOW"
+	CodeCircuitOpen    = "CIRCUIT_OPEN"
+	CodeInvalidRequest = "INVALID_REQUEST"
+	CodeNotFound       = "NOT_FOUND"
+	CodeNotPending     = "NOT_PENDING"
+	CodeOptedOut       = "OPTED_OUT"
+	CodeUnreachable    = "RECIPIENT_UNREACHABLE"
+	CodeUndeliverable  = "UNDELIVERABLE"
+	CodeNoSandbox      = "NO_SANDBOX"
+	CodeInFlight       = "IN_FLIGHT"
+	CodeNoReminders    = "REMINDERS_DISABLED"
+	CodeSendFailed     = "SEND_FAILED"
+)
+
+// rateLimitRetryAfter is the wait suggested to callers after a rate limit
+const rateLimitRetryAfter = 30 * time.Second
+
+// sendErrorMapping maps a send-path error to its HTTP response
+type sendErrorMapping struct {
+	err        error
+	status     int
+	code       string
+	retryable  bool
+	retryAfter time.Duration
+}
+
+// sendErrorMappings lists the known send-path errors, checked in order
+var sendErrorMappings = []sendErrorMapping{
+	{err: whatsapp.ErrSessionInvalid, status: http.StatusServiceUnavailable, code: CodeSessionInvalid},
+	{err: whatsapp.ErrNotConnected, status: http.StatusServiceUnavailable, code: CodeNotConnected, retryable: true},
+	{err: whatsapp.ErrAccountNotConnected, status: http.StatusServiceUnavailable, code: CodeNotConnected, retryable: true},
+	{err: whatsapp.ErrRateLimited, status: http.StatusTooManyRequests, code: CodeRateLimited, retryable: true, retryAfter: rateLimitRetryAfter},
+	{err: usecases.ErrInvalidPhoneNumber, status: http.StatusBadRequest, code: CodeInvalidPhone},
+	{err: whatsapp.ErrNotOnWhatsApp, status: http.StatusBadRequest, code: CodeInvalidPhone},
+	{err: whatsapp.ErrNotGroupParticipant, status: http.StatusBadRequest, code: CodeInvalidPhone},
+	{err: whatsapp.ErrNotGroup, status: http.StatusBadRequest, code: CodeInvalidRequest},
+	{err: whatsapp.ErrInvalidDisappearTimer, status: http.StatusBadRequest, code: CodeInvalidRequest},
+	{err: usecases.ErrInvalidMessage, status: http.StatusBadRequest, code: CodeInvalidRequest},
+	{err: usecases.ErrInvalidMetadata, status: http.StatusBadRequest, code: CodeInvalidRequest},
+	{err: usecases.ErrTooManyRecipients, status: http.StatusBadRequest, code: CodeInvalidRequest},
+	{err: whatsapp.ErrUnknownAccount, status: http.StatusBadRequest, code: CodeInvalidRequest},
+	{err: templates.ErrMissingVariable, status: http.StatusBadRequest, code: CodeInvalidRequest},
+	{err: templates.ErrNotFound, status: http.StatusNotFound, code: CodeNotFound},
+	{err: usecases.ErrBookingNotFound, status: http.StatusNotFound, code: CodeNotFound},
+	{err: usecases.ErrBookingNotPending, status: http.StatusConflict, code: CodeNotPending},
+	{err: usecases.ErrNoSandbox, status: http.StatusConflict, code: CodeNoSandbox},
+	{err: usecases.ErrRemindersDisabled, status: http.StatusConflict, code: CodeNoReminders},
+	{err: usecases.ErrIdempotencyInFlight, status: http.StatusConflict, code: CodeInFlight, retryable: true},
+	{err: usecases.ErrOptedOut, status: http.StatusForbidden, code: CodeOptedOut},
+	{err: usecases.ErrUndeliverable, status: http.StatusUnprocessableEntity, code: CodeUndeliverable},
+	{err: whatsapp.ErrRecipientRejected, status: http.StatusUnprocessableEntity, code: CodeUndeliverable},
+	{err: whatsapp.ErrRecipientUnreachable, status: http.StatusServiceUnavailable, code: CodeUnreachable, retryable: true},
+}
+
+// sendError writes the JSON error for a failed send. Errors without a
+// mapping are logged and reported as a retryable SEND_FAILED with the given
+// message.
+func sendError(c *gin.Context, logger logger.Logger, err error, message string) {
+	for _, mapping := range sendErrorMappings {
+		if !errors.Is(err, mapping.err) {
+			continue
+		}
+		if mapping.retryAfter > 0 {
+			c.Header("Retry-After", strconv.Itoa(int(mapping.retryAfter.Seconds())))
+		}
+		c.JSON(mapping.status, gin.H{
+			"error":     err.Error(),
+			"code":      mapping.code,
+			"retryable": mapping.retryable,
+		})
+		return
+	}
+
+	logger.Error(message, zap.Error(err))
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error":     message,
+		"code":      CodeSendFailed,
+		"retryable": true,
+	})
+}
+
+// invalidSendRequest writes the JSON error for a malformed send request
+func invalidSendRequest(c *gin.Context, message string) {
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error":     message,
+		"code":      CodeInvalidRequest,
+		"retryable": false,
+	})
+}
@@ -0,0 +1,120 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/internal/usecases"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/templates"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/whatsapp"
+)
+
+// sendErrorResponse is the JSON body of a failed send
+type sendErrorResponse struct {
+	Error     string `json:"error"`
+	Code      string `json:"code"`
+	Retryable bool   `json:"retryable"`
+}
+
+func TestSendErrorMapping(t *testing.T) {
+	tests := []struct {
+		err        error
+		status     int
+		code       string
+		retryable  bool
+		retryAfter string
+	}{
+		{err: whatsapp.ErrSessionInvalid, status: http.StatusServiceUnavailable, code: CodeSessionInvalid},
+		{err: whatsapp.ErrNotConnected, status: http.StatusServiceUnavailable, code: CodeNotConnected, retryable: true},
+		{err: whatsapp.ErrAccountNotConnected, status: http.StatusServiceUnavailable, code: CodeNotConnected, retryable: true},
+		{err: whatsapp.ErrRateLimited, status: http.StatusTooManyRequests, code: CodeRateLimited, retryable: true, retryAfter: "30"},
+		{err: usecases.ErrInvalidPhoneNumber, status: http.StatusBadRequest, code: CodeInvalidPhone},
+		{err: whatsapp.ErrNotOnWhatsApp, status: http.StatusBadRequest, code: CodeInvalidPhone},
+		{err: whatsapp.ErrNotGroupParticipant, status: http.StatusBadRequest, code: CodeInvalidPhone},
+		{err: whatsapp.ErrNotGroup, status: http.StatusBadRequest, code: CodeInvalidRequest},
+		{err: whatsapp.ErrInvalidDisappearTimer, status: http.StatusBadRequest, code: CodeInvalidRequest},
+		{err: usecases.ErrInvalidMessage, status: http.StatusBadRequest, code: CodeInvalidRequest},
+		{err: usecases.ErrInvalidMetadata, status: http.StatusBadRequest, code: CodeInvalidRequest},
+		{err: usecases.ErrTooManyRecipients, status: http.StatusBadRequest, code: CodeInvalidRequest},
+		{err: whatsapp.ErrUnknownAccount, status: http.StatusBadRequest, code: CodeInvalidRequest},
+		{err: templates.ErrMissingVariable, status: http.StatusBadRequest, code: CodeInvalidRequest},
+		{err: templates.ErrNotFound, status: http.StatusNotFound, code: CodeNotFound},
+		{err: usecases.ErrBookingNotFound, status: http.StatusNotFound, code: CodeNotFound},
+		{err: usecases.ErrBookingNotPending, status: http.StatusConflict, code: CodeNotPending},
+		{err: usecases.ErrNoSandbox, status: http.StatusConflict, code: CodeNoSandbox},
+		{err: usecases.ErrRemindersDisabled, status: http.StatusConflict, code: CodeNoReminders},
+		{err: usecases.ErrIdempotencyInFlight, status: http.StatusConflict, code: CodeInFlight, retryable: true},
+		{err: usecases.ErrOptedOut, status: http.StatusForbidden, code: CodeOptedOut},
+		{err: usecases.ErrUndeliverable, status: http.StatusUnprocessableEntity, code: CodeUndeliverable},
+		{err: whatsapp.ErrRecipientRejected, status: http.StatusUnprocessableEntity, code: CodeUndeliverable},
+		{err: whatsapp.ErrRecipientUnreachable, status: http.StatusServiceUnavailable, code: CodeUnreachable, retryable: true},
+		{err: errors.New("disk full"), status: http.StatusInternalServerError, code: CodeSendFailed, retryable: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			// Errors reach the handlers wrapped by the layers below
+			err := fmt.Errorf("send failed: %w", tt.err)
+			router := newTestRouter()
+			router.POST("/send", func(c *gin.Context) {
+				sendError(c, logger.NewNop(), err, "Failed to send message")
+			})
+
+			rec := serve(router, http.MethodPost, "/send", "", "")
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+			var body sendErrorResponse
+			decode(t, rec, &body)
+			if body.Code != tt.code || body.Retryable != tt.retryable {
+				t.Errorf("code = %s, retryable = %t, want %s, %t", body.Code, body.Retryable, tt.code, tt.retryable)
+			}
+			if body.Error == "" {
+				t.Error("response has no error message")
+			}
+			if got := rec.Header().Get("Retry-After"); got != tt.retryAfter {
+				t.Errorf("Retry-After = %q, want %q", got, tt.retryAfter)
+			}
+		})
+	}
+}
+
+func TestSendEndpointErrors(t *testing.T) {
+	token := newTestToken(t)
+	client := newLoggedInClient(t)
+	var sendErr error
+	client.OnBeforeSend(func(*whatsapp.OutboundMessage) error { return sendErr })
+	router := newTestRouter()
+	messageUseCase := usecases.NewMessageUseCase(client, logger.NewNop(), "CL")
+	NewMessageHandler(messageUseCase, nil, logger.NewNop()).RegisterRoutes(router, newTestAuthHandler(client))
+
+	tests := []struct {
+		name      string
+		to        string
+		sendErr   error
+		status    int
+		code      string
+		retryable bool
+	}{
+		{name: "invalid phone", to: "123", status: http.StatusBadRequest, code: CodeInvalidPhone},
+		{name: "rate limited", to: "56961234567", sendErr: whatsapp.ErrRateLimited, status: http.StatusTooManyRequests, code: CodeRateLimited, retryable: true},
+		{name: "unreachable", to: "56961234567", sendErr: whatsapp.ErrRecipientUnreachable, status: http.StatusServiceUnavailable, code: CodeUnreachable, retryable: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sendErr = tt.sendErr
+			rec := serve(router, http.MethodPost, "/messages/raw", `{"to":"`+tt.to+`","message":{"conversation":"Hola"}}`, token)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			var body sendErrorResponse
+			decode(t, rec, &body)
+			if body.Code != tt.code || body.Retryable != tt.retryable {
+				t.Errorf("code = %s, retryable = %t, want %s, %t", body.Code, body.Retryable, tt.code, tt.retryable)
+			}
+		})
+	}
+}
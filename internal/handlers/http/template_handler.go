@@ -0,0 +1,153 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/internal/usecases"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/whatsapp"
+	"go.uber.org/zap"
+)
+
+// TemplateHandler handles message template endpoints
+type TemplateHandler struct {
+	templateUseCase *usecases.TemplateUseCase
+	logger          logger.Logger
+}
+
+// NewTemplateHandler creates a new TemplateHandler
+func NewTemplateHandler(templateUseCase *usecases.TemplateUseCase, logger logger.Logger) *TemplateHandler {
+	return &TemplateHandler{
+		templateUseCase: templateUseCase,
+		logger:          logger,
+	}
+}
+
+// RegisterRoutes registers the template routes
+func (h *TemplateHandler) RegisterRoutes(router *gin.Engine, authHandler *AuthHandler) {
+	templateRoutes := router.Group("/templates", JWTMiddleware())
+	{
+		templateRoutes.GET("", h.ListTemplates)
+		templateRoutes.PUT("/:name", h.SaveTemplate)
+		templateRoutes.POST("/:name/send", authHandler.AuthMiddleware(), h.SendTemplate)
+	}
+}
+
+// SaveTemplateRequest represents the request body for saving a template
+type SaveTemplateRequest struct {
+	Body string `json:"body" binding:"required"`
+}
+
+// SendTemplateRequest represents the request body for sending a template
+type SendTemplateRequest struct {
+	To        string            `json:"to" binding:"required"`
+	Variables map[string]string `json:"variables"`
+	// Marketing sends the template as a non-transactional message, at low
+	// priority and never to numbers that opted out
+	Marketing bool `json:"marketing"`
+	// DisappearAfter sets the disappearing message timer in seconds,
+	// replacing the account default; 0 sends without a timer
+	DisappearAfter *int `json:"disappear_after"`
+}
+
+// ListTemplates returns the latest version of every template
+// @Summary List message templates
+// @Description Returns the latest version of every template with its variables and last-modified time
+// @Tags templates
+// @Produce json
+// @Success 200 {array} templates.Template "Templates"
+// @Failure 401 {object} map[string]string "Error message"
+// @Failure 500 {object} map[string]string "Error message"
+// @Router /templates [get]
+func (h *TemplateHandler) ListTemplates(c *gin.Context) {
+	list, err := h.templateUseCase.List(c.Request.Context())
+	if err != nil {
+		h.logger.Error("Failed to list templates", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list templates"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"templates": list})
+}
+
+// SaveTemplate creates a template or a new version of it
+// @Summary Create or update a message template
+// @Description Stores the body as a new version of the template; sends always use the latest version
+// @Tags templates
+// @Accept json
+// @Produce json
+// @Param name path string true "Template name"
+// @Param request body SaveTemplateRequest true "Template body"
+// @Success 200 {object} templates.Template "Saved template"
+// @Failure 400 {object} map[string]string "Error message"
+// @Failure 401 {object} map[string]string "Error message"
+// @Failure 500 {object} map[string]string "Error message"
+// @Router /templates/{name} [put]
+func (h *TemplateHandler) SaveTemplate(c *gin.Context) {
+	var request SaveTemplateRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	template, err := h.templateUseCase.Save(c.Request.Context(), c.Param("name"), request.Body)
+	if errors.Is(err, usecases.ErrInvalidTemplate) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to save template", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save template"})
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+// SendTemplate renders the latest version of a template and sends it
+// @Summary Send a message template
+// @Description Renders the latest version of the template with the given variables and sends it
+// @Tags templates
+// @Accept json
+// @Produce json
+// @Param name path string true "Template name"
+// @Param request body SendTemplateRequest true "Send request"
+// @Success 200 {object} usecases.SendResult "Sent message"
+// @Failure 400 {object} map[string]string "Error message"
+// @Failure 401 {object} map[string]string "Error message"
+// @Failure 404 {object} map[string]string "Error message"
+// @Failure 429 {object} map[string]string "Error message"
+// @Failure 500 {object} map[string]string "Error message"
+// @Failure 503 {object} map[string]string "Error message"
+// @Router /templates/{name}/send [post]
+func (h *TemplateHandler) SendTemplate(c *gin.Context) {
+	var request SendTemplateRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		invalidSendRequest(c, "Invalid request body: "+err.Error())
+		return
+	}
+
+	disappear, ok := disappearAfter(c, request.DisappearAfter)
+	if !ok {
+		return
+	}
+	var options []whatsapp.SendOption
+	if disappear != nil {
+		options = append(options, whatsapp.WithDisappearAfter(*disappear))
+	}
+
+	ctx := c.Request.Context()
+	if request.Marketing {
+		ctx = whatsapp.ContextWithPriority(ctx, whatsapp.PriorityLow)
+	}
+
+	result, err := h.templateUseCase.Send(ctx, c.Param("name"), request.To, request.Variables, options...)
+	if err != nil {
+		sendError(c, h.logger, err, "Failed to send template")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
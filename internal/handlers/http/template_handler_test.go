@@ -0,0 +1,66 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/internal/usecases"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/store"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/templates"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/whatsapp"
+)
+
+func TestSendTemplateDisappearAfter(t *testing.T) {
+	token := newTestToken(t)
+	client := newLoggedInClient(t, whatsapp.WithDefaultDisappearTimer(24*time.Hour))
+	sent := captureSends(client)
+	repository := templates.NewStoreRepository(store.NewMemoryStore())
+	if _, err := repository.Save(context.Background(), "recordatorio", "Hola {{name}}"); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	router := newTestRouter()
+	templateUseCase := usecases.NewTemplateUseCase(repository, client, logger.NewNop(), "CL")
+	NewTemplateHandler(templateUseCase, logger.NewNop()).RegisterRoutes(router, newTestAuthHandler(client))
+
+	tests := []struct {
+		name      string
+		disappear string
+		status    int
+		want      uint32
+	}{
+		{name: "account default", status: http.StatusOK, want: 86400},
+		{name: "per-request timer", disappear: `,"disappear_after":604800`, status: http.StatusOK, want: 604800},
+		{name: "per-request zero", disappear: `,"disappear_after":0`, status: http.StatusOK},
+		{name: "unsupported timer", disappear: `,"disappear_after":3600`, status: http.StatusBadRequest},
+		{name: "negative timer", disappear: `,"disappear_after":-86400`, status: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := len(sent.all())
+			body := `{"to":"56961234567","variables":{"name":"Ana"}` + tt.disappear + `}`
+			rec := serve(router, http.MethodPost, "/templates/recordatorio/send", body, token)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+
+			messages := sent.all()[before:]
+			if tt.status != http.StatusOK {
+				var response sendErrorResponse
+				decode(t, rec, &response)
+				if response.Code != CodeInvalidRequest || len(messages) != 0 {
+					t.Errorf("code = %s after %d sends, want %s and no send", response.Code, len(messages), CodeInvalidRequest)
+				}
+				return
+			}
+			if len(messages) != 1 {
+				t.Fatalf("sent %d messages, want 1", len(messages))
+			}
+			if got := messages[0].Message.GetExtendedTextMessage().GetContextInfo().GetExpiration(); got != tt.want {
+				t.Errorf("Expiration = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
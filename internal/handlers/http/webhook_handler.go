@@ -1,58 +1,209 @@
 package http
 
 import (
+	"bytes"
+	"io"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/pabbloacevedog/whatspp-service-glidpa/internal/usecases"
 	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/requestid"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/utils"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/webhook"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/whatsapp"
 	"go.uber.org/zap"
 )
 
 // WebhookHandler handles incoming webhook requests from WhatsApp
 type WebhookHandler struct {
 	bookingUseCase *usecases.BookingUseCase
+	dispatcher     *usecases.WebhookDispatcher
+	dedup          *usecases.InboundDedup
+	history        *usecases.HistoryUseCase
+	mapping        webhook.FieldMapping
+	secret         string
+	phoneRegion    string
 	logger         logger.Logger
 }
 
-// NewWebhookHandler creates a new WebhookHandler
-func NewWebhookHandler(bookingUseCase *usecases.BookingUseCase, logger logger.Logger) *WebhookHandler {
+// NewWebhookHandler creates a new WebhookHandler. With a dispatcher, messages
+// are acknowledged with 202 and processed in the background; without one
+// they are processed before responding. With dedup, messages whose ID was
+// already received are dropped; a message that could not be queued or
+// processed gives its ID back, so the sender's retry is taken. With history,
+// the messages taken are recorded as inbound history. The mapping locates the message fields in
+// the payload, so providers with their own format can post directly. With a
+// secret, payloads without a valid signature are rejected. The sender is
+// normalized with the default phone region.
+func NewWebhookHandler(bookingUseCase *usecases.BookingUseCase, dispatcher *usecases.WebhookDispatcher, dedup *usecases.InboundDedup, history *usecases.HistoryUseCase, mapping webhook.FieldMapping, secret, phoneRegion string, logger logger.Logger) *WebhookHandler {
 	return &WebhookHandler{
 		bookingUseCase: bookingUseCase,
+		dispatcher:     dispatcher,
+		dedup:          dedup,
+		history:        history,
+		mapping:        mapping,
+		secret:         secret,
+		phoneRegion:    phoneRegion,
 		logger:         logger,
 	}
 }
 
 // RegisterRoutes registers the webhook routes
 func (h *WebhookHandler) RegisterRoutes(router *gin.Engine) {
-	router.POST("/webhook", h.HandleIncomingMessage)
+	if h.secret == "" {
+		router.POST("/webhook", h.HandleIncomingMessage)
+		return
+	}
+	router.POST("/webhook", h.SignatureMiddleware(), h.HandleIncomingMessage)
 }
 
-// WhatsAppMessage represents the structure of an incoming WhatsApp message
+// SignatureMiddleware rejects payloads whose X-Webhook-Signature header is
+// not the HMAC-SHA256 of the raw body with the shared secret
+func (h *WebhookHandler) SignatureMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		payload, err := c.GetRawData()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+			c.Abort()
+			return
+		}
+
+		if !webhook.VerifySignature(h.secret, payload, c.GetHeader(webhook.SignatureHeader)) {
+			h.logger.Warn("Rejected webhook with an invalid signature", zap.String("client_ip", c.ClientIP()))
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid webhook signature"})
+			c.Abort()
+			return
+		}
+
+		// The handler reads the body again
+		c.Request.Body = io.NopCloser(bytes.NewReader(payload))
+		c.Next()
+	}
+}
+
+// WhatsAppMessage represents the structure of an incoming WhatsApp message,
+// the payload of the default mapping
 type WhatsAppMessage struct {
+	// ID is the WhatsApp message ID, used to drop duplicates
+	ID string `json:"message_id"`
+	// From is the sender's phone number or user JID
 	From string `json:"from"`
 	Body string `json:"body"`
+	// ResponseID is the ID of the selected button or list row, if any
+	ResponseID string `json:"response_id"`
 }
 
 // HandleIncomingMessage processes incoming messages from WhatsApp
 func (h *WebhookHandler) HandleIncomingMessage(c *gin.Context) {
-	var message WhatsAppMessage
-	if err := c.ShouldBindJSON(&message); err != nil {
+	message, err := h.bind(c)
+	if err != nil {
 		h.logger.Error("Invalid message format", zap.Error(err))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message format"})
 		return
 	}
 
+	// Provider payloads without a message, such as delivery statuses, are
+	// acknowledged so they are not redelivered
+	if message == (WhatsAppMessage{}) {
+		c.JSON(http.StatusOK, gin.H{"status": "ignored"})
+		return
+	}
+
+	// Replies go to the sender, so it must be a plausible number
+	from, err := utils.NormalizeSender(message.From, h.phoneRegion)
+	if err != nil {
+		h.logger.Warn("Invalid webhook sender", zap.String("from", message.From), zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sender: " + err.Error()})
+		return
+	}
+	message.From = from
+
+	if h.dedup != nil && !h.dedup.Claim(c.Request.Context(), message.ID, "webhook") {
+		c.JSON(http.StatusOK, gin.H{"status": "duplicate"})
+		return
+	}
+
+	receivedAt := time.Now()
+
+	if h.dispatcher != nil {
+		h.enqueue(c, message, receivedAt)
+		return
+	}
+
 	// Process the message
-	response, err := h.bookingUseCase.ProcessIncomingMessage(message.From, message.Body)
+	response, err := h.bookingUseCase.ProcessIncomingResponse(c.Request.Context(), message.From, message.Body, message.ResponseID)
 	if err != nil {
 		h.logger.Error("Failed to process message", zap.Error(err))
+		h.release(c, message)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process message"})
 		return
 	}
+	h.record(c, message, receivedAt)
 
 	c.JSON(http.StatusOK, gin.H{
 		"status":  "received",
 		"message": response,
 	})
 }
+
+// bind extracts the message from the request payload using the mapping
+func (h *WebhookHandler) bind(c *gin.Context) (WhatsAppMessage, error) {
+	payload, err := c.GetRawData()
+	if err != nil {
+		return WhatsAppMessage{}, err
+	}
+
+	fields, err := h.mapping.Extract(payload)
+	if err != nil {
+		return WhatsAppMessage{}, err
+	}
+	return WhatsAppMessage{
+		ID:         fields.MessageID,
+		From:       fields.From,
+		Body:       fields.Body,
+		ResponseID: fields.ResponseID,
+	}, nil
+}
+
+// enqueue queues a message for background processing and acknowledges it
+func (h *WebhookHandler) enqueue(c *gin.Context, message WhatsAppMessage, receivedAt time.Time) {
+	err := h.dispatcher.Enqueue(usecases.InboundWebhook{
+		From:       message.From,
+		Body:       message.Body,
+		ResponseID: message.ResponseID,
+		RequestID:  requestid.FromContext(c.Request.Context()),
+	})
+	if err != nil {
+		// The queue is full or shutting down; the sender should retry
+		h.logger.Warn("Webhook message rejected", zap.Error(err))
+		h.release(c, message)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Failed to queue message, retry later"})
+		return
+	}
+	h.record(c, message, receivedAt)
+
+	c.JSON(http.StatusAccepted, gin.H{"status": "queued"})
+}
+
+// release forgets the dedup claim of a message that was not processed, so
+// the sender's retry is not dropped as a duplicate
+func (h *WebhookHandler) release(c *gin.Context, message WhatsAppMessage) {
+	if h.dedup != nil {
+		h.dedup.Release(c.Request.Context(), message.ID)
+	}
+}
+
+// record stores a message taken for processing in the inbound history. It is
+// recorded once taken, so a retry after a failure is not stored twice, with
+// the time it arrived so it sorts before the reply.
+func (h *WebhookHandler) record(c *gin.Context, message WhatsAppMessage, receivedAt time.Time) {
+	if h.history == nil {
+		return
+	}
+	inbound := &whatsapp.WhatsAppMessage{ID: message.ID, From: message.From, Body: message.Body}
+	if err := h.history.RecordInbound(c.Request.Context(), inbound, receivedAt); err != nil {
+		h.logger.Warn("Failed to record webhook message in history", zap.String("message_id", message.ID), zap.Error(err))
+	}
+}
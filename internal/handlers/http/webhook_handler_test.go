@@ -0,0 +1,393 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/internal/usecases"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/requestid"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/store"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/webhook"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/whatsapp"
+)
+
+func TestWebhookQueueFullAsksToRetry(t *testing.T) {
+	client := newTestClient(t)
+	// Hold the only worker in its first reply
+	started, release := make(chan struct{}, 4), make(chan struct{})
+	client.OnBeforeSend(func(*whatsapp.OutboundMessage) error {
+		started <- struct{}{}
+		<-release
+		return nil
+	})
+	bookingUseCase := usecases.NewBookingUseCase(client, logger.NewNop())
+	dispatcher := usecases.NewWebhookDispatcher(bookingUseCase, logger.NewNop(), 1, 1)
+	t.Cleanup(func() {
+		close(release)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		dispatcher.Stop(ctx)
+	})
+	mapping, _ := webhook.Preset("default")
+	router := gin.New()
+	NewWebhookHandler(bookingUseCase, dispatcher, nil, nil, mapping, "", "CL", logger.NewNop()).RegisterRoutes(router)
+
+	body := `{"from":"56961234567","body":"Sí"}`
+	if rec := serve(router, http.MethodPost, "/webhook", body, ""); rec.Code != http.StatusAccepted {
+		t.Fatalf("first message: status = %d, want 202: %s", rec.Code, rec.Body)
+	}
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("first message not processed")
+	}
+	if rec := serve(router, http.MethodPost, "/webhook", body, ""); rec.Code != http.StatusAccepted {
+		t.Fatalf("second message: status = %d, want 202: %s", rec.Code, rec.Body)
+	}
+
+	rec := serve(router, http.MethodPost, "/webhook", body, "")
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("message over the queue size: status = %d, want 503: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestWebhookRetryAfterQueueFullIsProcessed(t *testing.T) {
+	client := newTestClient(t)
+	// Hold the only worker in its first reply
+	started, release := make(chan struct{}, 8), make(chan struct{})
+	client.OnBeforeSend(func(*whatsapp.OutboundMessage) error {
+		started <- struct{}{}
+		<-release
+		return nil
+	})
+	bookingUseCase := usecases.NewBookingUseCase(client, logger.NewNop())
+	dispatcher := usecases.NewWebhookDispatcher(bookingUseCase, logger.NewNop(), 1, 1)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		dispatcher.Stop(ctx)
+	})
+	dedup := usecases.NewInboundDedup(store.NewMemoryStore(), logger.NewNop())
+	mapping, _ := webhook.Preset("default")
+	router := gin.New()
+	NewWebhookHandler(bookingUseCase, dispatcher, dedup, nil, mapping, "", "CL", logger.NewNop()).RegisterRoutes(router)
+	body := func(id string) string {
+		return `{"message_id":"` + id + `","from":"56961234567","body":"Sí"}`
+	}
+
+	if rec := serve(router, http.MethodPost, "/webhook", body("msg-1"), ""); rec.Code != http.StatusAccepted {
+		t.Fatalf("first message: status = %d, want 202: %s", rec.Code, rec.Body)
+	}
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("first message not processed")
+	}
+	if rec := serve(router, http.MethodPost, "/webhook", body("msg-2"), ""); rec.Code != http.StatusAccepted {
+		t.Fatalf("second message: status = %d, want 202: %s", rec.Code, rec.Body)
+	}
+	if rec := serve(router, http.MethodPost, "/webhook", body("msg-3"), ""); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("message over the queue size: status = %d, want 503: %s", rec.Code, rec.Body)
+	}
+
+	// Once the queue drains, the provider's retry is taken, not dropped as a
+	// duplicate
+	close(release)
+	deadline := time.Now().Add(time.Second)
+	for {
+		rec := serve(router, http.MethodPost, "/webhook", body("msg-3"), "")
+		if rec.Code == http.StatusAccepted {
+			break
+		}
+		if rec.Code != http.StatusServiceUnavailable || time.Now().After(deadline) {
+			t.Fatalf("retry: status = %d, want 202: %s", rec.Code, rec.Body)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case <-started:
+		case <-time.After(time.Second):
+			t.Fatalf("replied to %d of the remaining messages, want 2", i)
+		}
+	}
+
+	// A message that was taken is still deduplicated
+	rec := serve(router, http.MethodPost, "/webhook", body("msg-3"), "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "duplicate") {
+		t.Errorf("second retry: status = %d, want 200 duplicate: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestWebhookPropagatesRequestID(t *testing.T) {
+	callbacks := make(chan string, 4)
+	callback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		callbacks <- r.Header.Get(requestid.DefaultHeader)
+	}))
+	t.Cleanup(callback.Close)
+
+	client := newTestClient(t)
+	sent := captureSends(client)
+	bookingUseCase := usecases.NewBookingUseCase(client, logger.NewNop(),
+		usecases.WithResponseCallback(webhook.NewNotifier(callback.URL)))
+	dispatcher := usecases.NewWebhookDispatcher(bookingUseCase, logger.NewNop(), 1, 4)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		dispatcher.Stop(ctx)
+	})
+	mapping, _ := webhook.Preset("default")
+	router := gin.New()
+	router.Use(RequestIDMiddleware())
+	NewWebhookHandler(bookingUseCase, dispatcher, nil, nil, mapping, "", "CL", logger.NewNop()).RegisterRoutes(router)
+
+	tests := []struct {
+		name    string
+		inbound string
+	}{
+		{name: "inbound ID reused", inbound: "req-from-integrator"},
+		{name: "ID generated"},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(`{"from":"56961234567","body":"Sí"}`))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.inbound != "" {
+				req.Header.Set(requestid.DefaultHeader, tt.inbound)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != http.StatusAccepted {
+				t.Fatalf("status = %d, want 202: %s", rec.Code, rec.Body)
+			}
+
+			id := rec.Header().Get(requestid.DefaultHeader)
+			if id == "" || (tt.inbound != "" && id != tt.inbound) {
+				t.Fatalf("response request ID = %q, want %q", id, tt.inbound)
+			}
+
+			select {
+			case got := <-callbacks:
+				if got != id {
+					t.Errorf("callback request ID = %q, want %q", got, id)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatal("callback not posted")
+			}
+
+			messages := sent.all()
+			if len(messages) != i+1 {
+				t.Fatalf("sent %d messages, want %d", len(messages), i+1)
+			}
+			if got := messages[i].Metadata["request_id"]; got != id {
+				t.Errorf("send record request ID = %q, want %q", got, id)
+			}
+		})
+	}
+}
+
+func TestWebhookSyncModeRepliesBeforeResponding(t *testing.T) {
+	client := newTestClient(t)
+	sent := captureSends(client)
+	bookingUseCase := usecases.NewBookingUseCase(client, logger.NewNop())
+	mapping, _ := webhook.Preset("default")
+	router := gin.New()
+	NewWebhookHandler(bookingUseCase, nil, nil, nil, mapping, "", "CL", logger.NewNop()).RegisterRoutes(router)
+
+	for i, body := range []string{"Sí", "No"} {
+		rec := serve(router, http.MethodPost, "/webhook", `{"from":"56961234567","body":"`+body+`"}`, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, want 200: %s", body, rec.Code, rec.Body)
+		}
+		// The reply was sent before the response, in arrival order
+		messages := sent.all()
+		if len(messages) != i+1 {
+			t.Fatalf("%s: sent %d messages before responding, want %d", body, len(messages), i+1)
+		}
+		if !strings.Contains(rec.Body.String(), `"message"`) {
+			t.Errorf("%s: response %s has no processing result", body, rec.Body)
+		}
+	}
+}
+
+func TestWebhookRecordsHistory(t *testing.T) {
+	client := newTestClient(t)
+	stateStore := store.NewMemoryStore()
+	bookingUseCase := usecases.NewBookingUseCase(client, logger.NewNop())
+	history := usecases.NewHistoryUseCase(stateStore, logger.NewNop(), time.Hour, true)
+	dedup := usecases.NewInboundDedup(stateStore, logger.NewNop())
+	dispatcher := usecases.NewWebhookDispatcher(bookingUseCase, logger.NewNop(), 1, 4)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		dispatcher.Stop(ctx)
+	})
+	mapping, _ := webhook.Preset("default")
+	syncRouter, queueRouter := gin.New(), gin.New()
+	NewWebhookHandler(bookingUseCase, nil, dedup, history, mapping, "", "CL", logger.NewNop()).RegisterRoutes(syncRouter)
+	NewWebhookHandler(bookingUseCase, dispatcher, dedup, history, mapping, "", "CL", logger.NewNop()).RegisterRoutes(queueRouter)
+
+	// Processed or queued, each message is recorded once, and duplicates are
+	// not recorded again
+	posts := []struct {
+		router http.Handler
+		body   string
+		status int
+	}{
+		{router: syncRouter, body: `{"message_id":"msg-1","from":"+56 9 6123 4567","body":"Sí"}`, status: http.StatusOK},
+		{router: queueRouter, body: `{"message_id":"msg-2","from":"56961234567","body":"No"}`, status: http.StatusAccepted},
+		{router: queueRouter, body: `{"message_id":"msg-1","from":"56961234567","body":"Sí"}`, status: http.StatusOK},
+	}
+	for _, post := range posts {
+		if rec := serve(post.router, http.MethodPost, "/webhook", post.body, ""); rec.Code != post.status {
+			t.Fatalf("%s: status = %d, want %d: %s", post.body, rec.Code, post.status, rec.Body)
+		}
+	}
+
+	messages, err := history.List(context.Background(), "56961234567", 0, time.Time{})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	var got []string
+	for _, message := range messages {
+		if message.Direction != usecases.DirectionInbound {
+			t.Errorf("history message %s direction = %s, want inbound", message.ID, message.Direction)
+		}
+		got = append(got, message.ID+" "+message.Body)
+	}
+	if len(got) != 2 || !slices.Contains(got, "msg-1 Sí") || !slices.Contains(got, "msg-2 No") {
+		t.Errorf("history = %v, want msg-1 and msg-2 once each", got)
+	}
+}
+
+func TestWebhookMapsProviderPayloads(t *testing.T) {
+	client := newTestClient(t)
+	sent := captureSends(client)
+	bookingUseCase := usecases.NewBookingUseCase(client, logger.NewNop())
+	mapping, _ := webhook.Preset("meta")
+	router := gin.New()
+	NewWebhookHandler(bookingUseCase, nil, nil, nil, mapping, "", "CL", logger.NewNop()).RegisterRoutes(router)
+
+	message := `{"entry":[{"changes":[{"value":{"messages":[{"id":"wamid.1","from":"56961234567","type":"text","text":{"body":"Sí"}}]}}]}]}`
+	rec := serve(router, http.MethodPost, "/webhook", message, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+	messages := sent.all()
+	if len(messages) != 1 || messages[0].To.User != "56961234567" {
+		t.Fatalf("sent %d messages, want the reply to the mapped sender", len(messages))
+	}
+
+	// Notifications without a message, such as statuses, are acknowledged
+	status := `{"entry":[{"changes":[{"value":{"statuses":[{"id":"wamid.1","status":"read"}]}}]}]}`
+	rec = serve(router, http.MethodPost, "/webhook", status, "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "ignored") {
+		t.Errorf("status notification = %d %s, want 200 ignored", rec.Code, rec.Body)
+	}
+	if got := len(sent.all()); got != 1 {
+		t.Errorf("sent %d messages, want the status notification ignored", got)
+	}
+}
+
+func TestWebhookNormalizesSender(t *testing.T) {
+	client := newTestClient(t)
+	sent := captureSends(client)
+	bookingUseCase := usecases.NewBookingUseCase(client, logger.NewNop())
+	mapping, _ := webhook.Preset("default")
+	router := gin.New()
+	NewWebhookHandler(bookingUseCase, nil, nil, nil, mapping, "", "CL", logger.NewNop()).RegisterRoutes(router)
+
+	tests := []struct {
+		name string
+		from string
+		want int
+	}{
+		{name: "bare number", from: "56961234567", want: http.StatusOK},
+		{name: "formatted number", from: "+56 9 6123 4567", want: http.StatusOK},
+		{name: "user JID", from: "56961234567@s.whatsapp.net", want: http.StatusOK},
+		{name: "user JID with device", from: "56961234567:12@s.whatsapp.net", want: http.StatusOK},
+		{name: "empty", from: "", want: http.StatusBadRequest},
+		{name: "group JID", from: "120363025246125486@g.us", want: http.StatusBadRequest},
+		{name: "not a number", from: "abc", want: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := len(sent.all())
+			rec := serve(router, http.MethodPost, "/webhook", `{"from":"`+tt.from+`","body":"Sí"}`, "")
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			messages := sent.all()[before:]
+			if tt.want != http.StatusOK {
+				if len(messages) != 0 {
+					t.Errorf("sent %d messages for an invalid sender, want none", len(messages))
+				}
+				return
+			}
+			// The reply goes to the bare number
+			if len(messages) != 1 || messages[0].To.User != "56961234567" || messages[0].To.Device != 0 {
+				t.Errorf("sent %d messages, want the reply to 56961234567", len(messages))
+			}
+		})
+	}
+}
+
+func TestWebhookSignature(t *testing.T) {
+	const secret = "webhook-secret"
+	body := `{"message_id":"msg-1","from":"56961234567","body":"Hola"}`
+	valid := webhook.Sign(secret, []byte(body))
+
+	tests := []struct {
+		name      string
+		signature string
+		wantCode  int
+	}{
+		{name: "valid", signature: valid, wantCode: http.StatusOK},
+		{name: "valid with prefix", signature: "sha256=" + valid, wantCode: http.StatusOK},
+		{name: "wrong secret", signature: webhook.Sign("other-secret", []byte(body)), wantCode: http.StatusUnauthorized},
+		{name: "not hex", signature: "sha256=not-a-signature", wantCode: http.StatusUnauthorized},
+		{name: "missing", wantCode: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bookingUseCase := usecases.NewBookingUseCase(newTestClient(t), logger.NewNop())
+			mapping, _ := webhook.Preset("default")
+			router := gin.New()
+			NewWebhookHandler(bookingUseCase, nil, nil, nil, mapping, secret, "CL", logger.NewNop()).RegisterRoutes(router)
+
+			req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.signature != "" {
+				req.Header.Set(webhook.SignatureHeader, tt.signature)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantCode, rec.Body)
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+			// The middleware reads the body, so the handler must get it back
+			var response struct {
+				Status  string `json:"status"`
+				Message struct {
+					PhoneNumber string `json:"PhoneNumber"`
+				} `json:"message"`
+			}
+			decode(t, rec, &response)
+			if response.Status != "received" || response.Message.PhoneNumber != "56961234567" {
+				t.Errorf("response = %s, want the message processed", rec.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/whatsapp"
+)
+
+func TestConfirmationRoutedToAccount(t *testing.T) {
+	acme, globex, offline := newTestClient(t), newTestClient(t), newTestClient(t)
+	offline.Disconnect()
+	sent := map[string]*sentMessages{
+		"acme":    captureSends(acme),
+		"globex":  captureSends(globex),
+		"offline": captureSends(offline),
+	}
+	manager := whatsapp.NewClientManager("acme")
+	manager.Register("acme", acme)
+	manager.Register("globex", globex)
+	manager.Register("offline", offline)
+
+	tests := []struct {
+		name      string
+		accountID string
+		wantFrom  string
+		wantErr   error
+	}{
+		{name: "named account", accountID: "globex", wantFrom: "globex"},
+		{name: "default account", wantFrom: "acme"},
+		{name: "unknown account", accountID: "initech", wantErr: whatsapp.ErrUnknownAccount},
+		{name: "disconnected account", accountID: "offline", wantErr: whatsapp.ErrAccountNotConnected},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := make(map[string]int)
+			for account, messages := range sent {
+				before[account] = len(messages.all())
+			}
+
+			u := NewBookingUseCase(nil, logger.NewNop(), WithClientManager(manager))
+			request := testBooking("booking-1")
+			request.AccountID = tt.accountID
+			_, err := u.SendConfirmationMessage(context.Background(), request)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SendConfirmationMessage() error = %v, want %v", err, tt.wantErr)
+			}
+
+			// Only the account's own number sends the confirmation
+			for account, messages := range sent {
+				want := before[account]
+				if account == tt.wantFrom {
+					want++
+				}
+				if got := len(messages.all()); got != want {
+					t.Errorf("%s sent %d messages, want %d", account, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestConfirmationAccountWithoutManager(t *testing.T) {
+	client := newTestClient(t)
+	sent := captureSends(client)
+	u := NewBookingUseCase(client, logger.NewNop())
+
+	request := testBooking("booking-1")
+	request.AccountID = "globex"
+	if _, err := u.SendConfirmationMessage(context.Background(), request); !errors.Is(err, whatsapp.ErrUnknownAccount) {
+		t.Fatalf("SendConfirmationMessage() error = %v, want ErrUnknownAccount", err)
+	}
+	if len(sent.all()) != 0 {
+		t.Error("confirmation sent from the single configured number")
+	}
+}
+
+func TestReplyRoutedToReceivingAccount(t *testing.T) {
+	acme := newTestClient(t, whatsapp.WithAccountID("acme"))
+	globex := newTestClient(t, whatsapp.WithAccountID("globex"))
+	sent := map[string]*sentMessages{
+		"acme":   captureSends(acme),
+		"globex": captureSends(globex),
+	}
+	manager := whatsapp.NewClientManager("acme")
+	manager.Register("acme", acme)
+	manager.Register("globex", globex)
+	u := NewBookingUseCase(acme, logger.NewNop(), WithClientManager(manager))
+
+	tests := []struct {
+		name      string
+		accountID string
+		wantFrom  string
+	}{
+		{name: "other account", accountID: "globex", wantFrom: "globex"},
+		{name: "default account", accountID: "acme", wantFrom: "acme"},
+		{name: "without an account", wantFrom: "acme"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := make(map[string]int)
+			for account, messages := range sent {
+				before[account] = len(messages.all())
+			}
+
+			msg := &whatsapp.WhatsAppMessage{ID: "msg-1", From: testPhone, Body: "Sí", AccountID: tt.accountID}
+			if _, err := u.ProcessIncomingMessage(context.Background(), msg); err != nil {
+				t.Fatalf("ProcessIncomingMessage() error = %v", err)
+			}
+
+			// The reply goes out from the number the customer wrote to
+			for account, messages := range sent {
+				want := before[account]
+				if account == tt.wantFrom {
+					want++
+				}
+				if got := len(messages.all()); got != want {
+					t.Errorf("%s sent %d messages, want %d", account, got, want)
+				}
+			}
+		})
+	}
+
+	// A message received on a disconnected account is not answered from
+	// another number
+	globex.Disconnect()
+	msg := &whatsapp.WhatsAppMessage{ID: "msg-2", From: testPhone, Body: "Sí", AccountID: "globex"}
+	if _, err := u.ProcessIncomingMessage(context.Background(), msg); !errors.Is(err, whatsapp.ErrAccountNotConnected) {
+		t.Errorf("ProcessIncomingMessage() on a disconnected account error = %v, want %v", err, whatsapp.ErrAccountNotConnected)
+	}
+}
+
+func TestConfirmationUsesAccountPhoneRegion(t *testing.T) {
+	acme, globex := newTestClient(t), newTestClient(t)
+	sent := captureSends(globex)
+	manager := whatsapp.NewClientManager("acme")
+	manager.Register("acme", acme)
+	manager.Register("globex", globex)
+	manager.SetPhoneRegion("globex", "BR")
+	u := NewBookingUseCase(nil, logger.NewNop(), WithClientManager(manager), WithPhoneRegion("CL"))
+
+	// A local Brazilian number is completed with the region of the account
+	// sending to it
+	request := testBooking("booking-1")
+	request.AccountID = "globex"
+	request.PhoneNumber = "(11) 91234-5678"
+	if _, err := u.SendConfirmationMessage(context.Background(), request); err != nil {
+		t.Fatalf("SendConfirmationMessage() error = %v", err)
+	}
+	if messages := sent.all(); len(messages) != 1 || messages[0].To.User != "5511912345678" {
+		t.Errorf("globex sent %v, want one message to 5511912345678", messages)
+	}
+
+	// Accounts without their own region keep the service default
+	request.AccountID = "acme"
+	if _, err := u.SendConfirmationMessage(context.Background(), request); !errors.Is(err, ErrInvalidPhoneNumber) {
+		t.Errorf("SendConfirmationMessage() from acme error = %v, want ErrInvalidPhoneNumber", err)
+	}
+}
@@ -0,0 +1,212 @@
+package usecases
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/store"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/whatsapp"
+	"go.mau.fi/whatsmeow/types/events"
+	"go.uber.org/zap"
+)
+
+// authAttemptKeyPrefix is the state store key prefix for QR and pairing attempts
+const authAttemptKeyPrefix = "whatsapp:auth_attempt:"
+
+// Outcomes of a QR and pairing attempt
+const (
+	AuthOutcomePending   = "pending"
+	AuthOutcomeGenerated = "generated"
+	AuthOutcomeScanned   = "scanned"
+	AuthOutcomeTimeout   = "timeout"
+	AuthOutcomeFailed    = "failed"
+)
+
+// AuthAttempt is the audit record of a QR generation and the pairing that
+// may follow it. The QR content itself is never stored.
+type AuthAttempt struct {
+	ID        string    `json:"session_attempt_id"`
+	StartedAt time.Time `json:"started_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Outcome   string    `json:"outcome"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// AuthAttemptUseCase records QR and pairing attempts in the state store
+type AuthAttemptUseCase struct {
+	store     store.Store
+	logger    logger.Logger
+	retention time.Duration
+	// current is the attempt a pairing result is attributed to
+	current   string
+	currentMu sync.Mutex
+}
+
+// NewAuthAttemptUseCase creates a new AuthAttemptUseCase
+func NewAuthAttemptUseCase(stateStore store.Store, logger logger.Logger, retention time.Duration) *AuthAttemptUseCase {
+	if retention <= 0 {
+		retention = 7 * 24 * time.Hour
+	}
+
+	return &AuthAttemptUseCase{
+		store:     stateStore,
+		logger:    logger,
+		retention: retention,
+	}
+}
+
+// eventSource delivers the client events Watch records
+type eventSource interface {
+	AddNamedEventHandler(name string, handler whatsapp.EventHandler) bool
+}
+
+// Watch records the pairing results reported by the client
+func (u *AuthAttemptUseCase) Watch(client eventSource) {
+	client.AddNamedEventHandler("auth_attempts", func(evt interface{}) {
+		ctx := context.Background()
+		switch v := evt.(type) {
+		case *events.PairSuccess:
+			u.finishCurrent(ctx, AuthOutcomeScanned, nil)
+		case *events.PairError:
+			u.finishCurrent(ctx, AuthOutcomeFailed, v.Error)
+		}
+	})
+}
+
+// Begin records a new attempt and returns its ID. An earlier attempt whose
+// code was never scanned is closed as timed out.
+func (u *AuthAttemptUseCase) Begin(ctx context.Context) string {
+	id := newAttemptID()
+	now := time.Now()
+
+	u.currentMu.Lock()
+	previous := u.current
+	u.current = id
+	u.currentMu.Unlock()
+
+	if previous != "" {
+		if attempt, err := u.get(ctx, previous); err == nil && attempt.Outcome == AuthOutcomeGenerated {
+			u.update(ctx, previous, AuthOutcomeTimeout, nil)
+		}
+	}
+
+	u.save(ctx, AuthAttempt{ID: id, StartedAt: now, UpdatedAt: now, Outcome: AuthOutcomePending})
+	return id
+}
+
+// Finish records the outcome of generating the QR code for an attempt
+func (u *AuthAttemptUseCase) Finish(ctx context.Context, id string, err error) {
+	switch {
+	case err == nil:
+		u.update(ctx, id, AuthOutcomeGenerated, nil)
+	case errors.Is(err, ErrQRTimeout) || errors.Is(err, whatsapp.ErrConnectTimeout):
+		u.update(ctx, id, AuthOutcomeTimeout, err)
+	default:
+		u.update(ctx, id, AuthOutcomeFailed, err)
+	}
+}
+
+// List returns up to limit attempts, newest first
+func (u *AuthAttemptUseCase) List(ctx context.Context, limit int) ([]AuthAttempt, error) {
+	keys, err := u.store.Keys(ctx, authAttemptKeyPrefix+"*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list auth attempts: %w", err)
+	}
+
+	attempts := []AuthAttempt{}
+	for _, key := range keys {
+		attempt, err := u.get(ctx, strings.TrimPrefix(key, authAttemptKeyPrefix))
+		if err != nil {
+			continue
+		}
+		attempts = append(attempts, *attempt)
+	}
+
+	sort.Slice(attempts, func(i, j int) bool {
+		return attempts[i].StartedAt.After(attempts[j].StartedAt)
+	})
+	if limit > 0 && len(attempts) > limit {
+		attempts = attempts[:limit]
+	}
+	return attempts, nil
+}
+
+// newAttemptID returns a random attempt ID
+func newAttemptID() string {
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// finishCurrent records a pairing result on the current attempt
+func (u *AuthAttemptUseCase) finishCurrent(ctx context.Context, outcome string, err error) {
+	u.currentMu.Lock()
+	id := u.current
+	u.current = ""
+	u.currentMu.Unlock()
+
+	if id == "" {
+		// The code was generated before a restart; record the pairing alone
+		now := time.Now()
+		attempt := AuthAttempt{ID: newAttemptID(), StartedAt: now, UpdatedAt: now, Outcome: outcome}
+		if err != nil {
+			attempt.Error = err.Error()
+		}
+		u.save(ctx, attempt)
+		return
+	}
+	u.update(ctx, id, outcome, err)
+}
+
+// update sets the outcome of an attempt
+func (u *AuthAttemptUseCase) update(ctx context.Context, id, outcome string, cause error) {
+	if id == "" {
+		return
+	}
+
+	attempt, err := u.get(ctx, id)
+	if err != nil {
+		u.logger.Warn("Auth attempt not found", zap.String("session_attempt_id", id), zap.Error(err))
+		return
+	}
+	attempt.Outcome = outcome
+	attempt.UpdatedAt = time.Now()
+	if cause != nil {
+		attempt.Error = cause.Error()
+	}
+	u.save(ctx, *attempt)
+}
+
+// get returns a recorded attempt
+func (u *AuthAttemptUseCase) get(ctx context.Context, id string) (*AuthAttempt, error) {
+	value, err := u.store.Get(ctx, authAttemptKeyPrefix+id)
+	if err != nil {
+		return nil, err
+	}
+
+	var attempt AuthAttempt
+	if err := json.Unmarshal([]byte(value), &attempt); err != nil {
+		return nil, fmt.Errorf("failed to decode auth attempt: %w", err)
+	}
+	return &attempt, nil
+}
+
+// save writes an attempt, keeping it for the retention period
+func (u *AuthAttemptUseCase) save(ctx context.Context, attempt AuthAttempt) {
+	data, err := json.Marshal(attempt)
+	if err != nil {
+		return
+	}
+	if err := u.store.Set(ctx, authAttemptKeyPrefix+attempt.ID, string(data), u.retention); err != nil {
+		u.logger.Warn("Failed to record auth attempt", zap.String("session_attempt_id", attempt.ID), zap.Error(err))
+	}
+}
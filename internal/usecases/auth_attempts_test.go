@@ -0,0 +1,154 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/whatsapp"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// fakeEvents is an event source whose events are emitted by the test
+type fakeEvents struct {
+	mu       sync.Mutex
+	handlers map[string]whatsapp.EventHandler
+}
+
+func (e *fakeEvents) AddNamedEventHandler(name string, handler whatsapp.EventHandler) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.handlers == nil {
+		e.handlers = make(map[string]whatsapp.EventHandler)
+	}
+	e.handlers[name] = handler
+	return true
+}
+
+// emit delivers an event to every handler
+func (e *fakeEvents) emit(evt interface{}) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, handler := range e.handlers {
+		handler(evt)
+	}
+}
+
+// newAuditedAuth creates an auth use case recording its attempts, serving
+// the QR codes of the fake client through the refresh loop
+func newAuditedAuth(t *testing.T, qrClient *fakeQRClient) (*WhatsAppAuthUseCase, *AuthAttemptUseCase, *fakeEvents, *fakeStore) {
+	t.Helper()
+	stateStore := newFakeStore()
+	attempts := NewAuthAttemptUseCase(stateStore, logger.NewNop(), time.Hour)
+	source := &fakeEvents{}
+	attempts.Watch(source)
+
+	u := NewWhatsAppAuthUseCase(newTestClient(t), logger.NewNop(), WithAuthAttempts(attempts))
+	u.refreshClient = qrClient
+	u.refreshTick = 5 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	u.StartQRRefresh(ctx)
+	return u, attempts, source, stateStore
+}
+
+// generateQR requests a QR code, giving up after the timeout
+func generateQR(u *WhatsAppAuthUseCase, timeout time.Duration) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return u.GenerateQR(ctx)
+}
+
+// listAttempts returns the recorded attempts, newest first
+func listAttempts(t *testing.T, attempts *AuthAttemptUseCase) []AuthAttempt {
+	t.Helper()
+	list, err := attempts.List(context.Background(), 0)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	return list
+}
+
+func TestAuthAttemptTimeout(t *testing.T) {
+	qrClient := newFakeQRClient()
+	// Every connection waits, so WhatsApp never sends a code
+	qrClient.connects.Store(1)
+	u, attempts, _, _ := newAuditedAuth(t, qrClient)
+
+	if _, err := generateQR(u, 50*time.Millisecond); !errors.Is(err, ErrQRTimeout) {
+		t.Fatalf("GenerateQR() error = %v, want %v", err, ErrQRTimeout)
+	}
+
+	list := listAttempts(t, attempts)
+	if len(list) != 1 {
+		t.Fatalf("recorded %d attempts, want 1", len(list))
+	}
+	if list[0].Outcome != AuthOutcomeTimeout || list[0].Error != ErrQRTimeout.Error() || list[0].ID == "" {
+		t.Errorf("attempt = %+v, want a timeout", list[0])
+	}
+}
+
+func TestAuthAttemptPairing(t *testing.T) {
+	u, attempts, source, stateStore := newAuditedAuth(t, newFakeQRClient())
+
+	// A generated code that is never scanned times out when a new one is requested
+	code, err := generateQR(u, time.Second)
+	if err != nil {
+		t.Fatalf("GenerateQR() error = %v", err)
+	}
+	if _, err := generateQR(u, time.Second); err != nil {
+		t.Fatalf("second GenerateQR() error = %v", err)
+	}
+	list := listAttempts(t, attempts)
+	if len(list) != 2 {
+		t.Fatalf("recorded %d attempts, want 2", len(list))
+	}
+	if list[1].Outcome != AuthOutcomeTimeout || list[0].Outcome != AuthOutcomeGenerated {
+		t.Errorf("outcomes = %s, %s, want the earlier code timed out and the new one generated", list[1].Outcome, list[0].Outcome)
+	}
+
+	// The scan is attributed to the latest code
+	source.emit(&events.PairSuccess{})
+	list = listAttempts(t, attempts)
+	if list[0].Outcome != AuthOutcomeScanned || list[1].Outcome != AuthOutcomeTimeout {
+		t.Errorf("outcomes after pairing = %s, %s, want scanned and timeout", list[0].Outcome, list[1].Outcome)
+	}
+
+	// A failed pairing is recorded with its cause
+	if _, err := generateQR(u, time.Second); err != nil {
+		t.Fatalf("third GenerateQR() error = %v", err)
+	}
+	source.emit(&events.PairError{Error: errors.New("key mismatch")})
+	list = listAttempts(t, attempts)
+	if list[0].Outcome != AuthOutcomeFailed || list[0].Error != "key mismatch" {
+		t.Errorf("attempt after a failed pairing = %+v", list[0])
+	}
+
+	// The QR content itself is never stored
+	keys, _ := stateStore.Keys(context.Background(), authAttemptKeyPrefix+"*")
+	for _, key := range keys {
+		if value, _ := stateStore.Get(context.Background(), key); strings.Contains(value, code) {
+			t.Errorf("%s stores the QR code: %s", key, value)
+		}
+	}
+}
+
+func TestAuthAttemptInvalidPairPhone(t *testing.T) {
+	u, attempts, _, _ := newAuditedAuth(t, newFakeQRClient())
+
+	if _, err := u.GeneratePairCode(context.Background(), "0961234567"); !errors.Is(err, whatsapp.ErrInvalidPairPhone) {
+		t.Fatalf("GeneratePairCode() error = %v, want ErrInvalidPairPhone", err)
+	}
+
+	list := listAttempts(t, attempts)
+	if len(list) != 1 {
+		t.Fatalf("recorded %d attempts, want 1", len(list))
+	}
+	if list[0].Outcome != AuthOutcomeFailed || !strings.Contains(list[0].Error, whatsapp.ErrInvalidPairPhone.Error()) {
+		t.Errorf("attempt = %+v, want failed with the invalid phone", list[0])
+	}
+}
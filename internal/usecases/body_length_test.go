@@ -0,0 +1,101 @@
+package usecases
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/webhook"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/whatsapp"
+)
+
+func TestLongBodyTruncatedForProcessing(t *testing.T) {
+	url, posted := newEventServer(t, http.StatusOK)
+	u := NewBookingUseCase(newTestClient(t), logger.NewNop(),
+		WithExternalReply(webhook.NewNotifier(url)),
+		WithMaxBodyLength(20, 0))
+
+	// The answer is at the start; the rest is pasted spam
+	body := "Sí, confirmo " + strings.Repeat("spam ", 200)
+	response, err := u.ProcessIncomingResponse(context.Background(), testPhone, body, "")
+	if err != nil {
+		t.Fatalf("ProcessIncomingResponse() error = %v", err)
+	}
+	if response.Status != "confirmed" {
+		t.Errorf("status = %q, want confirmed from the kept prefix", response.Status)
+	}
+
+	_, intent := nextEvent(t, posted)
+	if want := []rune(body)[:20]; intent.Message != string(want) || !intent.Truncated {
+		t.Errorf("intent message = %q (truncated %v), want %q truncated", intent.Message, intent.Truncated, string(want))
+	}
+}
+
+func TestShortBodyNotTruncated(t *testing.T) {
+	url, posted := newEventServer(t, http.StatusOK)
+	u := NewBookingUseCase(newTestClient(t), logger.NewNop(),
+		WithExternalReply(webhook.NewNotifier(url)),
+		WithMaxBodyLength(20, 100))
+
+	if _, err := u.ProcessIncomingResponse(context.Background(), testPhone, "Sí", ""); err != nil {
+		t.Fatalf("ProcessIncomingResponse() error = %v", err)
+	}
+	if _, intent := nextEvent(t, posted); intent.Message != "Sí" || intent.Truncated {
+		t.Errorf("intent = %q (truncated %v), want Sí untouched", intent.Message, intent.Truncated)
+	}
+}
+
+func TestOversizedBodyAsksForShorterMessage(t *testing.T) {
+	url, posted := newEventServer(t, http.StatusOK)
+	client := newTestClient(t)
+	sent := captureSends(client)
+	u := NewBookingUseCase(client, logger.NewNop(),
+		WithExternalReply(webhook.NewNotifier(url)),
+		WithMaxBodyLength(20, 100))
+
+	response, err := u.ProcessIncomingResponse(context.Background(), testPhone, "Sí "+strings.Repeat("a", 200), "")
+	if err != nil {
+		t.Fatalf("ProcessIncomingResponse() error = %v", err)
+	}
+	if response.Status != "too_long" {
+		t.Errorf("status = %q, want too_long", response.Status)
+	}
+	// The customer is asked for a shorter message even in external mode,
+	// and the body is not classified nor posted
+	if texts := sent.texts(); len(texts) != 1 || texts[0] != response.Message {
+		t.Errorf("sent %q, want the request for a shorter message", texts)
+	}
+	select {
+	case event := <-posted:
+		t.Errorf("unexpected %s event for an oversized body", event.Type)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestFullBodyPersisted(t *testing.T) {
+	history := NewHistoryUseCase(newFakeStore(), logger.NewNop(), time.Hour, true)
+	u := NewBookingUseCase(newTestClient(t), logger.NewNop(), WithMaxBodyLength(20, 0))
+
+	body := "Sí, confirmo " + strings.Repeat("detalle ", 50)
+	msg := &whatsapp.WhatsAppMessage{ID: "msg-1", From: testPhone, Body: body}
+	if err := history.RecordInbound(context.Background(), msg, time.Now()); err != nil {
+		t.Fatalf("RecordInbound() error = %v", err)
+	}
+	if _, err := u.ProcessIncomingMessage(context.Background(), msg); err != nil {
+		t.Fatalf("ProcessIncomingMessage() error = %v", err)
+	}
+
+	if msg.Body != body {
+		t.Error("processing changed the inbound message body")
+	}
+	messages, err := history.List(context.Background(), testPhone, 0, time.Time{})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(messages) == 0 || messages[len(messages)-1].Body != body {
+		t.Errorf("history = %+v, want the full inbound body", messages)
+	}
+}
@@ -0,0 +1,135 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/i18n"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/utils"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/whatsapp"
+	"go.mau.fi/whatsmeow/types"
+	"go.uber.org/zap"
+)
+
+// TraceStageCancelled records a booking cancelled by the business
+const TraceStageCancelled = "cancelled"
+
+// BookingCancellation represents the request data for cancelling a booking
+type BookingCancellation struct {
+	BookingID   string
+	PhoneNumber string
+	// Reason is shown to the customer when set
+	Reason string
+	// AccountID selects the sending account when the booking is no longer
+	// pending; a pending booking is cancelled from the account that sent it
+	AccountID string
+	// Language selects the notice language when the booking is no longer
+	// pending; a pending booking is cancelled in its own language
+	Language string
+}
+
+// cancellationText renders the cancellation notice of a booking. Without
+// the booking details, e.g. once the customer answered, a shorter notice is
+// sent in the given language.
+func (u *BookingUseCase) cancellationText(details *bookingDetails, language, reason string) (string, error) {
+	if details == nil {
+		details = &bookingDetails{Language: language}
+	}
+	return u.bookingText(i18n.Cancellation, *details, time.Time{}, reason)
+}
+
+// SendCancellationMessage tells the customer a booking was cancelled and
+// clears the booking if it is still awaiting the customer's response, so a
+// later reply does not confirm it
+func (u *BookingUseCase) SendCancellationMessage(ctx context.Context, cancellation BookingCancellation) (*BookingResponse, error) {
+	phoneNumber, err := utils.NormalizePhone(cancellation.PhoneNumber, u.regionFor(cancellation.AccountID))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPhoneNumber, err)
+	}
+	reason := strings.TrimSpace(cancellation.Reason)
+
+	// Only the pending booking with this ID is cleared; a newer booking of
+	// the number keeps waiting
+	log := u.logger.With(zap.String("booking_id", cancellation.BookingID))
+	booking, err := u.pendingBookingFor(ctx, phoneNumber)
+	if err != nil {
+		log.Warn("Failed to load pending booking", zap.Error(err))
+	}
+	if booking != nil && booking.BookingID != cancellation.BookingID {
+		booking = nil
+	}
+
+	accountID := cancellation.AccountID
+	traceID := u.latestTraceID(ctx, cancellation.BookingID)
+	var details *bookingDetails
+	if booking != nil {
+		accountID = booking.AccountID
+		traceID = booking.TraceID
+		details = &booking.Details
+	}
+	if traceID != "" {
+		log = log.With(zap.String("trace_id", traceID))
+	}
+
+	client, err := u.clientFor(accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	messageText, err := u.cancellationText(details, u.bookingLanguage(cancellation.Language), reason)
+	if err != nil {
+		return nil, err
+	}
+	jid := types.NewJID(phoneNumber, types.DefaultUserServer)
+	sendOptions := []whatsapp.SendOption{whatsapp.WithPriority(whatsapp.PriorityNormal)}
+	if traceID != "" {
+		sendOptions = append(sendOptions,
+			whatsapp.WithMetadata("booking_id", cancellation.BookingID),
+			whatsapp.WithMetadata("trace_id", traceID))
+	}
+	sent, err := client.SendText(ctx, jid, messageText, sendOptions...)
+	if err != nil {
+		log.Error("Failed to send cancellation message", zap.Error(err))
+		return nil, fmt.Errorf("failed to send cancellation message: %w", err)
+	}
+
+	if booking != nil {
+		if err := u.resolvePendingBooking(ctx, phoneNumber); err != nil {
+			log.Warn("Failed to clear pending booking", zap.Error(err))
+		}
+	}
+	u.markCancelled(ctx, cancellation.BookingID)
+	u.recordTrace(ctx, TraceEvent{
+		TraceID:   traceID,
+		BookingID: cancellation.BookingID,
+		Stage:     TraceStageCancelled,
+		MessageID: sent.ID,
+		Detail:    reason,
+	})
+
+	log.Info("Cancellation message sent successfully",
+		zap.String("phone_number", phoneNumber),
+		zap.Bool("was_pending", booking != nil))
+
+	return &BookingResponse{
+		BookingID: cancellation.BookingID,
+		Message:   messageText,
+		Status:    "cancelled",
+		TraceID:   traceID,
+	}, nil
+}
+
+// latestTraceID returns the trace ID of the latest send of a booking, or an
+// empty string when it was not traced
+func (u *BookingUseCase) latestTraceID(ctx context.Context, bookingID string) string {
+	if u.trace == nil {
+		return ""
+	}
+	events, err := u.trace.Events(ctx, bookingID)
+	if err != nil || len(events) == 0 {
+		return ""
+	}
+	return events[len(events)-1].TraceID
+}
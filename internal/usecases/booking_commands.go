@@ -0,0 +1,129 @@
+package usecases
+
+import (
+	"context"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/whatsapp"
+	"go.mau.fi/whatsmeow/types"
+	"go.uber.org/zap"
+)
+
+// Names of the built-in conversational commands
+const (
+	CommandConfirm = "confirm"
+	CommandCancel  = "cancel"
+	CommandHelp    = "help"
+)
+
+// helpKeywords are the messages that invoke the help command
+var helpKeywords = []string{"help", "ayuda", "ajuda", "menu", "menú", "?"}
+
+// inboundMessage is the processing state of an inbound message shared with
+// the command handlers
+type inboundMessage struct {
+	log       logger.Logger
+	jid       types.JID
+	booking   *pendingBooking
+	locale    string
+	truncated bool
+	media     *inboundMedia
+	// original is the message being answered, quoted in the reply
+	original *whatsapp.WhatsAppMessage
+	// client is the client of the account that received the message
+	client *whatsapp.Client
+}
+
+// inboundKey is the context key of the inbound message being routed
+type inboundKey struct{}
+
+// withInbound returns a context carrying the inbound message being routed
+func withInbound(ctx context.Context, inbound *inboundMessage) context.Context {
+	return context.WithValue(ctx, inboundKey{}, inbound)
+}
+
+// inboundFor returns the inbound message being routed, or loads its state
+// when a command handler is invoked outside the router
+func (u *BookingUseCase) inboundFor(ctx context.Context, phoneNumber, messageBody string) *inboundMessage {
+	if inbound, ok := ctx.Value(inboundKey{}).(*inboundMessage); ok {
+		return inbound
+	}
+
+	booking, err := u.pendingBookingFor(ctx, phoneNumber)
+	if err != nil {
+		u.logger.Warn("Failed to load pending booking", zap.Error(err))
+	}
+	return &inboundMessage{
+		log:     u.logger,
+		jid:     types.NewJID(phoneNumber, types.DefaultUserServer),
+		booking: booking,
+		locale:  u.resolveLocale(ctx, phoneNumber, messageBody),
+		client:  u.client,
+	}
+}
+
+// WithCommand registers a conversational command. It is checked before the
+// built-in commands and the commands registered before it; a command named
+// like a built-in one replaces it.
+func WithCommand(command Command) BookingUseCaseOption {
+	return func(u *BookingUseCase) {
+		u.commands.Register(command)
+	}
+}
+
+// bookingCommands returns the router with the built-in commands: confirm and
+// cancel, matched by the intent rules, and help. Text that invokes none of
+// them is classified with sentiment analysis.
+func (u *BookingUseCase) bookingCommands() *CommandRouter {
+	router := NewCommandRouter(u.handleUnmatched)
+	router.Register(Command{
+		Name:    CommandConfirm,
+		Match:   u.intentMatcher("confirmed"),
+		Handler: u.bookingAnswer("confirmed", "Usuario confirmó la reserva"),
+	})
+	router.Register(Command{
+		Name:    CommandCancel,
+		Match:   u.intentMatcher("cancelled"),
+		Handler: u.bookingAnswer("cancelled", "Usuario canceló la reserva"),
+	})
+	router.Register(Command{
+		Name:    CommandHelp,
+		Match:   Keywords(helpKeywords...),
+		Handler: u.handleHelp,
+	})
+	return router
+}
+
+// intentMatcher matches messages the intent rules classify as the intent.
+// The rules are read on every match, so WithIntentRules applies in any order.
+func (u *BookingUseCase) intentMatcher(intent string) CommandMatcher {
+	return func(locale, message string) bool {
+		matched, ok := u.intentRules.Match(locale, message)
+		return ok && matched == intent
+	}
+}
+
+// bookingAnswer returns the handler of a keyword confirmation or cancellation
+func (u *BookingUseCase) bookingAnswer(status, message string) CommandHandler {
+	return func(ctx context.Context, phoneNumber, messageBody string) (*MessageResponse, error) {
+		inbound := u.inboundFor(ctx, phoneNumber, messageBody)
+		inbound.log.Info(message,
+			zap.String("phone_number", phoneNumber),
+			zap.String("status", status))
+		return u.respond(ctx, inbound, phoneNumber, messageBody, status)
+	}
+}
+
+// handleHelp answers with the options the customer can reply with
+func (u *BookingUseCase) handleHelp(ctx context.Context, phoneNumber, messageBody string) (*MessageResponse, error) {
+	inbound := u.inboundFor(ctx, phoneNumber, messageBody)
+	inbound.log.Info("Usuario pidió ayuda", zap.String("phone_number", phoneNumber))
+	return u.respond(ctx, inbound, phoneNumber, messageBody, "help")
+}
+
+// handleUnmatched classifies text that invoked no command with sentiment
+// analysis
+func (u *BookingUseCase) handleUnmatched(ctx context.Context, phoneNumber, messageBody string) (*MessageResponse, error) {
+	inbound := u.inboundFor(ctx, phoneNumber, messageBody)
+	return u.respond(ctx, inbound, phoneNumber, messageBody, u.classify(inbound.log, phoneNumber, messageBody))
+}
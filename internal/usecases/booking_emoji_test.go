@@ -0,0 +1,95 @@
+package usecases
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/utils"
+)
+
+func TestConfirmationEmojiVariants(t *testing.T) {
+	enabled, disabled := true, false
+	tests := []struct {
+		name         string
+		defaultEmoji bool
+		override     *bool
+		wantEmoji    bool
+	}{
+		{"default with emoji", true, nil, true},
+		{"request without emoji", true, &disabled, false},
+		{"default without emoji", false, nil, false},
+		{"request with emoji overrides default", false, &enabled, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t)
+			sent := captureSends(client)
+			useCase := NewBookingUseCase(client, logger.NewNop(), WithEmoji(tt.defaultEmoji))
+
+			response, err := useCase.SendConfirmationMessage(context.Background(), BookingRequest{
+				BookingID:    "b-1",
+				ServiceName:  "Corte",
+				UserName:     "Ana",
+				LocationName: "Centro",
+				StartTime:    "10:00",
+				Date:         "01/06/2025",
+				EmployeeName: "Luis",
+				PhoneNumber:  testPhone,
+				Emoji:        tt.override,
+			})
+			if err != nil {
+				t.Fatalf("SendConfirmationMessage() error = %v", err)
+			}
+			texts := sent.texts()
+			if len(texts) != 1 || texts[0] != response.Message {
+				t.Fatalf("sent %q, want the response message %q", texts, response.Message)
+			}
+
+			text := response.Message
+			if got := strings.ContainsFunc(text, utils.IsEmoji); got != tt.wantEmoji {
+				t.Errorf("message contains emoji = %v, want %v:\n%s", got, tt.wantEmoji, text)
+			}
+			if tt.wantEmoji {
+				return
+			}
+			for _, line := range strings.Split(text, "\n") {
+				if strings.Contains(line, "  ") || line != strings.TrimSpace(line) {
+					t.Errorf("line %q has awkward spacing", line)
+				}
+			}
+			if !strings.Contains(text, "Ubicación: Centro\n") || !strings.HasPrefix(text, "¡Hola Ana!\n") {
+				t.Errorf("plain message lost its content:\n%s", text)
+			}
+		})
+	}
+}
+
+func TestPlainVariantMatchesStrippedEmojiVariant(t *testing.T) {
+	disabled := false
+	request := BookingRequest{
+		BookingID:   "b-1",
+		ServiceName: "Corte",
+		UserName:    "Ana",
+		PhoneNumber: testPhone,
+	}
+	useCase := NewBookingUseCase(newTestClient(t), logger.NewNop())
+
+	withEmoji, err := useCase.SendConfirmationMessage(context.Background(), request)
+	if err != nil {
+		t.Fatalf("SendConfirmationMessage() error = %v", err)
+	}
+	request.Emoji = &disabled
+	plain, err := useCase.SendConfirmationMessage(context.Background(), request)
+	if err != nil {
+		t.Fatalf("SendConfirmationMessage() error = %v", err)
+	}
+
+	if withEmoji.Message == plain.Message {
+		t.Fatal("emoji and plain variants are identical")
+	}
+	if want := utils.StripEmoji(withEmoji.Message); plain.Message != want {
+		t.Errorf("plain variant = %q, want %q", plain.Message, want)
+	}
+}
@@ -0,0 +1,246 @@
+package usecases
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/store"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/whatsapp"
+	"go.mau.fi/whatsmeow/types"
+	"go.uber.org/zap"
+)
+
+// BookingExpiredEvent is posted when a booking was not confirmed in time
+const BookingExpiredEvent = "booking.expired"
+
+// expiredBookingKeyPrefix is the state store key prefix for the last booking
+// of a number that expired, kept to reject late button responses and to
+// render their reply
+const expiredBookingKeyPrefix = "whatsapp:booking_expired:"
+
+// WithConfirmationDeadline sets how long customers have to respond before an
+// unconfirmed booking expires; zero disables expiry unless a request sets its
+// own deadline
+func WithConfirmationDeadline(deadline time.Duration) BookingUseCaseOption {
+	return func(u *BookingUseCase) {
+		u.confirmationDeadline = deadline
+	}
+}
+
+// WithExpiryMessage sets whether customers are told when their booking expired
+func WithExpiryMessage(enabled bool) BookingUseCaseOption {
+	return func(u *BookingUseCase) {
+		u.expiryMessage = enabled
+	}
+}
+
+// WithTimezone sets the timezone of the response deadline shown in
+// confirmation messages; nil uses the server's local time
+func WithTimezone(location *time.Location) BookingUseCaseOption {
+	return func(u *BookingUseCase) {
+		if location != nil {
+			u.timezone = location
+		}
+	}
+}
+
+// StartExpiry expires overdue bookings on the given interval until the
+// context is cancelled or DrainScheduled runs. Deadlines live in the state
+// store, so bookings pending before a restart still expire.
+func (u *BookingUseCase) StartExpiry(ctx context.Context, interval time.Duration) {
+	if u.store == nil {
+		return
+	}
+
+	loopCtx, stop := context.WithCancel(ctx)
+	done := make(chan struct{})
+	u.stopExpiry = stop
+	u.expiryDone = done
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-loopCtx.Done():
+				return
+			case <-ticker.C:
+				// A sweep in progress finishes even if the loop is stopped
+				// meanwhile, so no claimed booking is left half expired
+				sweepCtx, cancel := context.WithTimeout(context.WithoutCancel(loopCtx), interval)
+				expired, err := u.ExpireOverdue(sweepCtx, time.Now())
+				cancel()
+				if err != nil {
+					u.logger.Warn("Failed to expire overdue bookings", zap.Error(err))
+					continue
+				}
+				if expired > 0 {
+					u.logger.Info("Expired unconfirmed bookings", zap.Int("count", expired))
+				}
+			}
+		}
+	}()
+}
+
+// ExpireOverdue expires the pending bookings whose deadline passed before now
+// and returns how many were expired
+func (u *BookingUseCase) ExpireOverdue(ctx context.Context, now time.Time) (int, error) {
+	keys, err := u.store.Keys(ctx, pendingBookingKeyPrefix+"*")
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, key := range keys {
+		phoneNumber := strings.TrimPrefix(key, pendingBookingKeyPrefix)
+		booking, err := u.pendingBookingFor(ctx, phoneNumber)
+		if err != nil || booking == nil || !booking.overdue(now) {
+			continue
+		}
+
+		// Take claims the booking, so a response arriving meanwhile or
+		// another replica cannot resolve it twice
+		value, err := u.store.Take(ctx, key)
+		if errors.Is(err, store.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return expired, err
+		}
+		var claimed pendingBooking
+		if err := json.Unmarshal([]byte(value), &claimed); err != nil {
+			continue
+		}
+		if !claimed.overdue(now) {
+			// A new confirmation replaced the booking; keep it pending
+			if err := u.savePendingBooking(ctx, phoneNumber, claimed); err != nil {
+				u.logger.Warn("Failed to restore pending booking", zap.Error(err))
+			}
+			continue
+		}
+
+		u.expire(ctx, phoneNumber, claimed, true)
+		expired++
+	}
+	return expired, nil
+}
+
+// expire fires the expiry callback and, when tell is set and expiry
+// messages are enabled, tells the customer. The booking is remembered so
+// buttons tapped later are rejected.
+func (u *BookingUseCase) expire(ctx context.Context, phoneNumber string, booking pendingBooking, tell bool) {
+	log := u.logger.With(zap.String("booking_id", booking.BookingID))
+	if booking.TraceID != "" {
+		log = log.With(zap.String("trace_id", booking.TraceID))
+	}
+	log.Info("Reserva expirada sin respuesta",
+		zap.String("phone_number", phoneNumber))
+	u.recordTrace(ctx, TraceEvent{
+		TraceID:   booking.TraceID,
+		BookingID: booking.BookingID,
+		Stage:     TraceStageExpired,
+	})
+	if data, err := json.Marshal(booking); err == nil {
+		if err := u.store.Set(ctx, expiredBookingKeyPrefix+phoneNumber, string(data), pendingBookingTTL); err != nil {
+			log.Warn("Failed to remember expired booking", zap.Error(err))
+		}
+	}
+
+	locale := u.defaultLocale
+	if stored, err := u.store.Get(ctx, localeKeyPrefix+phoneNumber); err == nil {
+		locale = stored
+	}
+
+	notifier := u.callbackNotifier
+	if notifier == nil {
+		notifier = u.intentNotifier
+	}
+	if notifier != nil {
+		if err := u.notify(ctx, log, notifier, BookingExpiredEvent, Intent{
+			PhoneNumber: phoneNumber,
+			Status:      "expired",
+			BookingID:   booking.BookingID,
+			Metadata:    booking.Metadata,
+			Locale:      locale,
+			TraceID:     booking.TraceID,
+		}); err != nil {
+			log.Error("Failed to post booking expiry callback", zap.Error(err))
+		}
+	}
+
+	if !u.expiryMessage || !tell {
+		return
+	}
+	client, err := u.clientFor(booking.AccountID)
+	if err != nil {
+		log.Warn("Cannot send booking expiry message", zap.Error(err))
+		return
+	}
+	jid := types.NewJID(phoneNumber, types.DefaultUserServer)
+	if _, err := client.SendText(ctx, jid, u.render(u.replyText(ctx, locale, "expired", &booking), nil),
+		whatsapp.WithMetadata("booking_id", booking.BookingID),
+		whatsapp.WithMetadata("trace_id", booking.TraceID)); err != nil {
+		log.Error("Failed to send booking expiry message", zap.Error(err))
+	}
+}
+
+// lateResponse returns the expired booking a button response answers, or nil
+// when the response arrived in time. An overdue booking the expiry loop has
+// not reached yet is expired on the spot.
+func (u *BookingUseCase) lateResponse(ctx context.Context, log logger.Logger, phoneNumber string, booking *pendingBooking, now time.Time) *pendingBooking {
+	if u.store == nil {
+		return nil
+	}
+
+	if booking == nil {
+		value, err := u.store.Get(ctx, expiredBookingKeyPrefix+phoneNumber)
+		if err != nil {
+			if !errors.Is(err, store.ErrNotFound) {
+				log.Warn("Failed to check expired booking", zap.Error(err))
+			}
+			return nil
+		}
+		var expired pendingBooking
+		if err := json.Unmarshal([]byte(value), &expired); err != nil {
+			log.Warn("Failed to decode expired booking", zap.Error(err))
+		}
+		return &expired
+	}
+	if !booking.overdue(now) {
+		return nil
+	}
+
+	// Claim the booking like the expiry loop does; if the loop won, it
+	// already expired it
+	value, err := u.store.Take(ctx, pendingBookingKeyPrefix+phoneNumber)
+	if err != nil {
+		if !errors.Is(err, store.ErrNotFound) {
+			log.Warn("Failed to claim overdue booking", zap.Error(err))
+		}
+		return booking
+	}
+	var claimed pendingBooking
+	if err := json.Unmarshal([]byte(value), &claimed); err != nil {
+		return booking
+	}
+	if !claimed.overdue(now) {
+		// A new confirmation replaced the booking meanwhile
+		if err := u.savePendingBooking(ctx, phoneNumber, claimed); err != nil {
+			log.Warn("Failed to restore pending booking", zap.Error(err))
+		}
+		return nil
+	}
+	u.expire(ctx, phoneNumber, claimed, false)
+	return booking
+}
+
+// overdue reports whether the booking has a deadline that passed before now
+func (b pendingBooking) overdue(now time.Time) bool {
+	return !b.ExpiresAt.IsZero() && now.After(b.ExpiresAt)
+}
@@ -0,0 +1,132 @@
+package usecases
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/webhook"
+)
+
+// nextEvent waits for the next event posted to the test server
+func nextEvent(t *testing.T, posted <-chan postedEvent) (string, Intent) {
+	t.Helper()
+	select {
+	case event := <-posted:
+		var intent Intent
+		if err := json.Unmarshal(event.Data, &intent); err != nil {
+			t.Fatalf("decode %s event: %v", event.Type, err)
+		}
+		return event.Type, intent
+	case <-time.After(2 * time.Second):
+		t.Fatal("no event posted")
+		return "", Intent{}
+	}
+}
+
+func TestUnconfirmedBookingExpires(t *testing.T) {
+	url, posted := newEventServer(t, http.StatusOK)
+	stateStore := newFakeStore()
+	client := newTestClient(t)
+	sent := captureSends(client)
+	options := []BookingUseCaseOption{
+		WithBookingStore(stateStore),
+		WithResponseCallback(webhook.NewNotifier(url)),
+		WithConfirmationDeadline(time.Hour),
+		WithExpiryMessage(true),
+	}
+	u := NewBookingUseCase(client, logger.NewNop(), options...)
+	ctx := context.Background()
+
+	request := testBooking("booking-1")
+	request.Metadata = map[string]string{"crm_id": "A-1042"}
+	if _, err := u.SendConfirmationMessage(ctx, request); err != nil {
+		t.Fatalf("SendConfirmationMessage() error = %v", err)
+	}
+
+	if expired, err := u.ExpireOverdue(ctx, time.Now().Add(30*time.Minute)); expired != 0 || err != nil {
+		t.Fatalf("ExpireOverdue() before the deadline = %d, %v, want 0", expired, err)
+	}
+
+	// Pending deadlines survive a restart
+	restarted := NewBookingUseCase(client, logger.NewNop(), options...)
+	if expired, err := restarted.ExpireOverdue(ctx, time.Now().Add(2*time.Hour)); expired != 1 || err != nil {
+		t.Fatalf("ExpireOverdue() after the deadline = %d, %v, want 1", expired, err)
+	}
+
+	eventType, intent := nextEvent(t, posted)
+	if eventType != BookingExpiredEvent || intent.BookingID != "booking-1" || intent.Status != "expired" || intent.Metadata["crm_id"] != "A-1042" {
+		t.Errorf("posted %s %+v, want an expired booking-1 with its metadata", eventType, intent)
+	}
+	if got := len(sent.all()); got != 2 {
+		t.Errorf("sent %d messages, want the confirmation and the expiry message", got)
+	}
+
+	// An expired booking expires once, and late answers do not confirm it
+	if expired, _ := restarted.ExpireOverdue(ctx, time.Now().Add(3*time.Hour)); expired != 0 {
+		t.Errorf("second ExpireOverdue() = %d, want 0", expired)
+	}
+	response, err := restarted.ProcessIncomingResponse(ctx, testPhone, "Confirmar", ResponseConfirm)
+	if err != nil {
+		t.Fatalf("ProcessIncomingResponse() error = %v", err)
+	}
+	if response.Status != "late" || response.BookingID != "booking-1" {
+		t.Errorf("late response = %s %s, want late booking-1", response.Status, response.BookingID)
+	}
+}
+
+func TestTimelyResponseCancelsExpiry(t *testing.T) {
+	for _, answer := range []string{"Sí", "No puedo, cancelo"} {
+		t.Run(answer, func(t *testing.T) {
+			url, posted := newEventServer(t, http.StatusOK)
+			u := NewBookingUseCase(newTestClient(t), logger.NewNop(),
+				WithBookingStore(newFakeStore()),
+				WithResponseCallback(webhook.NewNotifier(url)))
+			ctx := context.Background()
+
+			// The request deadline applies without a global one
+			request := testBooking("booking-1")
+			request.ConfirmationDeadline = time.Hour
+			if _, err := u.SendConfirmationMessage(ctx, request); err != nil {
+				t.Fatalf("SendConfirmationMessage() error = %v", err)
+			}
+			if _, err := u.ProcessIncomingResponse(ctx, testPhone, answer, ""); err != nil {
+				t.Fatalf("ProcessIncomingResponse() error = %v", err)
+			}
+			if eventType, _ := nextEvent(t, posted); eventType != BookingResponseEvent {
+				t.Fatalf("posted %s, want %s", eventType, BookingResponseEvent)
+			}
+
+			if expired, err := u.ExpireOverdue(ctx, time.Now().Add(2*time.Hour)); expired != 0 || err != nil {
+				t.Errorf("ExpireOverdue() after a response = %d, %v, want 0", expired, err)
+			}
+			select {
+			case event := <-posted:
+				t.Errorf("unexpected %s event", event.Type)
+			case <-time.After(50 * time.Millisecond):
+			}
+		})
+	}
+}
+
+func TestExpiryLoop(t *testing.T) {
+	url, posted := newEventServer(t, http.StatusOK)
+	u := NewBookingUseCase(newTestClient(t), logger.NewNop(),
+		WithBookingStore(newFakeStore()),
+		WithResponseCallback(webhook.NewNotifier(url)),
+		WithConfirmationDeadline(10*time.Millisecond))
+
+	if _, err := u.SendConfirmationMessage(context.Background(), testBooking("booking-1")); err != nil {
+		t.Fatalf("SendConfirmationMessage() error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	u.StartExpiry(ctx, 10*time.Millisecond)
+
+	if eventType, intent := nextEvent(t, posted); eventType != BookingExpiredEvent || intent.BookingID != "booking-1" {
+		t.Errorf("posted %s %s, want booking-1 expired", eventType, intent.BookingID)
+	}
+}
@@ -0,0 +1,112 @@
+package usecases
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/store"
+	"go.uber.org/zap"
+)
+
+// ErrIdempotencyInFlight is returned when a confirmation with the same
+// idempotency key is still being sent
+var ErrIdempotencyInFlight = errors.New("a request with this idempotency key is in progress")
+
+// idempotencyKeyPrefix is the state store key prefix for confirmation
+// idempotency keys
+const idempotencyKeyPrefix = "booking:idempotency:"
+
+// idempotencyPending marks a key whose confirmation is still being sent
+const idempotencyPending = "pending"
+
+// idempotencyPendingTTL bounds how long a key stays in flight, so a replica
+// that dies mid-send does not block retries for the whole window
+const idempotencyPendingTTL = 5 * time.Minute
+
+// WithIdempotencyWindow sets how long the response of a confirmation is
+// replayed for repeats of its idempotency key; zero disables idempotency
+func WithIdempotencyWindow(window time.Duration) BookingUseCaseOption {
+	return func(u *BookingUseCase) {
+		u.idempotencyWindow = window
+	}
+}
+
+// ConfirmIdempotent sends a confirmation at most once per idempotency key.
+// A repeat of a key that already succeeded returns the stored response and
+// true without sending again; a repeat while the first send is still in
+// progress returns ErrIdempotencyInFlight. Keys are scoped to the account
+// and booking. Failed sends release the key so the caller can retry. Without
+// a key, a booking store or a window the confirmation is simply sent.
+func (u *BookingUseCase) ConfirmIdempotent(ctx context.Context, idempotencyKey string, request BookingRequest) (*BookingResponse, bool, error) {
+	if idempotencyKey == "" || u.store == nil || u.idempotencyWindow <= 0 {
+		response, err := u.SendConfirmationMessage(ctx, request)
+		return response, false, err
+	}
+
+	key := idempotencyStoreKey(request.AccountID, request.BookingID, idempotencyKey)
+	pendingTTL := min(idempotencyPendingTTL, u.idempotencyWindow)
+
+	claimed, err := u.store.SetNX(ctx, key, idempotencyPending, pendingTTL)
+	if err != nil {
+		return nil, false, err
+	}
+	if !claimed {
+		response, err := u.storedResponse(ctx, key)
+		if err != nil {
+			return nil, false, err
+		}
+		u.logger.Info("Replaying booking confirmation for repeated idempotency key",
+			zap.String("booking_id", request.BookingID))
+		return response, true, nil
+	}
+
+	response, err := u.SendConfirmationMessage(ctx, request)
+	if err != nil {
+		if deleteErr := u.store.Delete(ctx, key); deleteErr != nil {
+			u.logger.Warn("Failed to release idempotency key", zap.String("booking_id", request.BookingID), zap.Error(deleteErr))
+		}
+		return nil, false, err
+	}
+
+	data, err := json.Marshal(response)
+	if err == nil {
+		err = u.store.Set(ctx, key, string(data), u.idempotencyWindow)
+	}
+	if err != nil {
+		// The send went out; repeats are rejected as in flight until the
+		// pending marker expires
+		u.logger.Warn("Failed to store idempotent booking response", zap.String("booking_id", request.BookingID), zap.Error(err))
+	}
+	return response, false, nil
+}
+
+// storedResponse returns the response stored for a claimed idempotency key,
+// or ErrIdempotencyInFlight while its confirmation is being sent
+func (u *BookingUseCase) storedResponse(ctx context.Context, key string) (*BookingResponse, error) {
+	value, err := u.store.Get(ctx, key)
+	if errors.Is(err, store.ErrNotFound) || value == idempotencyPending {
+		// A key that expired since the claim failed is treated as in
+		// flight too; the caller retries and claims it again
+		return nil, ErrIdempotencyInFlight
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var response BookingResponse
+	if err := json.Unmarshal([]byte(value), &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// idempotencyStoreKey builds the state store key of an idempotency key. The
+// caller's key is hashed so it cannot contain store pattern characters.
+func idempotencyStoreKey(accountID, bookingID, idempotencyKey string) string {
+	sum := sha256.Sum256([]byte(idempotencyKey))
+	return idempotencyKeyPrefix + accountID + ":" + bookingID + ":" + hex.EncodeToString(sum[:])
+}
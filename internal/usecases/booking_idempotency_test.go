@@ -0,0 +1,115 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/whatsapp"
+)
+
+func TestConfirmIdempotentReplaysResponse(t *testing.T) {
+	client := newTestClient(t)
+	sent := captureSends(client)
+	useCase := NewBookingUseCase(client, logger.NewNop(), WithBookingStore(newFakeStore()), WithIdempotencyWindow(time.Hour))
+	ctx := context.Background()
+
+	first, replayed, err := useCase.ConfirmIdempotent(ctx, "key-1", testBooking("b-1"))
+	if err != nil || replayed {
+		t.Fatalf("first ConfirmIdempotent() = %+v, %t, %v, want a fresh send", first, replayed, err)
+	}
+	second, replayed, err := useCase.ConfirmIdempotent(ctx, "key-1", testBooking("b-1"))
+	if err != nil || !replayed {
+		t.Fatalf("repeated ConfirmIdempotent() = %+v, %t, %v, want a replay", second, replayed, err)
+	}
+	if !reflect.DeepEqual(second, first) {
+		t.Errorf("replayed response = %+v, want %+v", second, first)
+	}
+	if got := len(sent.all()); got != 1 {
+		t.Errorf("sent %d confirmations, want 1", got)
+	}
+}
+
+func TestConfirmIdempotentInFlight(t *testing.T) {
+	client := newTestClient(t)
+	sent := captureSends(client)
+	stateStore := newFakeStore()
+	useCase := NewBookingUseCase(client, logger.NewNop(), WithBookingStore(stateStore), WithIdempotencyWindow(time.Hour))
+	ctx := context.Background()
+
+	// Another request claimed the key and is still sending
+	key := idempotencyStoreKey("", "b-1", "key-1")
+	if err := stateStore.Set(ctx, key, idempotencyPending, time.Minute); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if _, _, err := useCase.ConfirmIdempotent(ctx, "key-1", testBooking("b-1")); !errors.Is(err, ErrIdempotencyInFlight) {
+		t.Errorf("ConfirmIdempotent() error = %v, want %v", err, ErrIdempotencyInFlight)
+	}
+	if got := len(sent.all()); got != 0 {
+		t.Errorf("sent %d confirmations, want none", got)
+	}
+}
+
+func TestConfirmIdempotentReleasesFailedSend(t *testing.T) {
+	client := newTestClient(t)
+	errRefused := errors.New("send refused")
+	fail := true
+	client.OnBeforeSend(func(*whatsapp.OutboundMessage) error {
+		if fail {
+			return errRefused
+		}
+		return nil
+	})
+	sent := captureSends(client)
+	useCase := NewBookingUseCase(client, logger.NewNop(), WithBookingStore(newFakeStore()), WithIdempotencyWindow(time.Hour))
+	ctx := context.Background()
+
+	if _, _, err := useCase.ConfirmIdempotent(ctx, "key-1", testBooking("b-1")); !errors.Is(err, errRefused) {
+		t.Fatalf("failed ConfirmIdempotent() error = %v, want %v", err, errRefused)
+	}
+
+	// The retry with the same key sends instead of being held as in flight
+	fail = false
+	if _, replayed, err := useCase.ConfirmIdempotent(ctx, "key-1", testBooking("b-1")); err != nil || replayed {
+		t.Fatalf("retried ConfirmIdempotent() = %t, %v, want a fresh send", replayed, err)
+	}
+	if got := len(sent.all()); got != 1 {
+		t.Errorf("sent %d confirmations, want 1", got)
+	}
+}
+
+func TestConfirmIdempotentKeyScope(t *testing.T) {
+	acme, globex := newTestClient(t), newTestClient(t)
+	sent := map[string]*sentMessages{"acme": captureSends(acme), "globex": captureSends(globex)}
+	manager := whatsapp.NewClientManager("acme")
+	manager.Register("acme", acme)
+	manager.Register("globex", globex)
+	useCase := NewBookingUseCase(nil, logger.NewNop(), WithClientManager(manager), WithBookingStore(newFakeStore()), WithIdempotencyWindow(time.Hour))
+	ctx := context.Background()
+
+	// The same caller key for another booking or account is a new request
+	requests := []struct {
+		bookingID string
+		accountID string
+	}{
+		{bookingID: "b-1", accountID: "acme"},
+		{bookingID: "b-2", accountID: "acme"},
+		{bookingID: "b-1", accountID: "globex"},
+	}
+	for _, r := range requests {
+		request := testBooking(r.bookingID)
+		request.AccountID = r.accountID
+		if _, replayed, err := useCase.ConfirmIdempotent(ctx, "key-1", request); err != nil || replayed {
+			t.Errorf("ConfirmIdempotent(%s, %s) = %t, %v, want a fresh send", r.bookingID, r.accountID, replayed, err)
+		}
+	}
+	if got := len(sent["acme"].all()); got != 2 {
+		t.Errorf("acme sent %d confirmations, want 2", got)
+	}
+	if got := len(sent["globex"].all()); got != 1 {
+		t.Errorf("globex sent %d confirmations, want 1", got)
+	}
+}
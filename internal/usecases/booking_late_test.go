@@ -0,0 +1,115 @@
+package usecases
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+)
+
+func TestConfirmationShowsDeadline(t *testing.T) {
+	client := newTestClient(t)
+	sent := captureSends(client)
+	u := NewBookingUseCase(client, logger.NewNop(),
+		WithBookingStore(newFakeStore()),
+		WithConfirmationDeadline(2*time.Hour),
+		WithTimezone(time.UTC))
+
+	before := time.Now().UTC()
+	if _, err := u.SendConfirmationMessage(context.Background(), testBooking("booking-1")); err != nil {
+		t.Fatalf("SendConfirmationMessage() error = %v", err)
+	}
+	after := time.Now().UTC()
+	texts := sent.texts()
+	if len(texts) != 1 {
+		t.Fatalf("sent %d messages, want the confirmation", len(texts))
+	}
+	// The minute may turn while sending
+	var found bool
+	for _, sentAt := range []time.Time{before, after} {
+		deadline := sentAt.Add(2 * time.Hour)
+		found = found || strings.Contains(texts[0], "Confirma antes del "+deadline.Format("02/01/2006")+" a las "+deadline.Format("15:04"))
+	}
+	if !found {
+		t.Errorf("confirmation = %q, want the deadline two hours from now", texts[0])
+	}
+
+	// Without a deadline nothing is shown
+	plain := NewBookingUseCase(client, logger.NewNop(), WithBookingStore(newFakeStore()))
+	if _, err := plain.SendConfirmationMessage(context.Background(), testBooking("booking-2")); err != nil {
+		t.Fatalf("SendConfirmationMessage() error = %v", err)
+	}
+	if texts = sent.texts(); strings.Contains(texts[len(texts)-1], "Confirma antes") {
+		t.Errorf("confirmation without a deadline = %q, want no deadline", texts[len(texts)-1])
+	}
+}
+
+func TestOnTimeButtonTapIsProcessed(t *testing.T) {
+	client := newTestClient(t)
+	sent := captureSends(client)
+	u := NewBookingUseCase(client, logger.NewNop(), WithBookingStore(newFakeStore()), WithConfirmationDeadline(time.Hour))
+	ctx := context.Background()
+
+	if _, err := u.SendConfirmationMessage(ctx, testBooking("booking-1")); err != nil {
+		t.Fatalf("SendConfirmationMessage() error = %v", err)
+	}
+	response, err := u.ProcessIncomingResponse(ctx, testPhone, "Confirmar", ResponseConfirm)
+	if err != nil {
+		t.Fatalf("ProcessIncomingResponse() error = %v", err)
+	}
+	if response.Status != "confirmed" || response.BookingID != "booking-1" {
+		t.Errorf("response = %s %s, want booking-1 confirmed", response.Status, response.BookingID)
+	}
+	if texts := sent.texts(); strings.Contains(texts[len(texts)-1], "expirado") {
+		t.Errorf("reply = %q, want the confirmation acknowledged", texts[len(texts)-1])
+	}
+}
+
+func TestLateButtonTapIsRejected(t *testing.T) {
+	client := newTestClient(t)
+	sent := captureSends(client)
+	u := NewBookingUseCase(client, logger.NewNop(), WithBookingStore(newFakeStore()), WithExpiryMessage(true))
+	ctx := context.Background()
+
+	request := testBooking("booking-1")
+	request.ConfirmationDeadline = 10 * time.Millisecond
+	if _, err := u.SendConfirmationMessage(ctx, request); err != nil {
+		t.Fatalf("SendConfirmationMessage() error = %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	// The tap arrives before the expiry loop ran: the booking expires on the
+	// spot, without a separate expiry message
+	response, err := u.ProcessIncomingResponse(ctx, testPhone, "Confirmar", ResponseConfirm)
+	if err != nil {
+		t.Fatalf("ProcessIncomingResponse() error = %v", err)
+	}
+	if response.Status != "late" || response.BookingID != "booking-1" {
+		t.Fatalf("response = %s %s, want late booking-1", response.Status, response.BookingID)
+	}
+	texts := sent.texts()
+	if len(texts) != 2 || !strings.Contains(texts[1], "Esta confirmación ha expirado") {
+		t.Errorf("sent %q, want the confirmation and the expiry reply", texts)
+	}
+	if booking, _ := u.pendingBookingFor(ctx, testPhone); booking != nil {
+		t.Errorf("pending booking = %+v, want it expired", booking)
+	}
+	if expired, _ := u.ExpireOverdue(ctx, time.Now()); expired != 0 {
+		t.Errorf("ExpireOverdue() = %d, want the booking expired once", expired)
+	}
+
+	// Tapping again is still rejected
+	if response, err := u.ProcessIncomingResponse(ctx, testPhone, "Cancelar", ResponseCancel); err != nil || response.Status != "late" {
+		t.Errorf("second tap = %+v, %v, want late", response, err)
+	}
+
+	// The buttons of a new confirmation work again
+	if _, err := u.SendConfirmationMessage(ctx, testBooking("booking-2")); err != nil {
+		t.Fatalf("SendConfirmationMessage() error = %v", err)
+	}
+	if response, err := u.ProcessIncomingResponse(ctx, testPhone, "Confirmar", ResponseConfirm); err != nil || response.Status != "confirmed" || response.BookingID != "booking-2" {
+		t.Errorf("tap on the new confirmation = %+v, %v, want booking-2 confirmed", response, err)
+	}
+}
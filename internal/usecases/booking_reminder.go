@@ -0,0 +1,283 @@
+package usecases
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/i18n"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/redis"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/utils"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/whatsapp"
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types"
+	"go.uber.org/zap"
+)
+
+// ErrRemindersDisabled is returned when scheduling a reminder without the
+// Redis queue configured
+var ErrRemindersDisabled = errors.New("booking reminders are not configured")
+
+// TraceStageReminder records a reminder sent for a booking
+const TraceStageReminder = "reminder"
+
+// reminderQueueKey is the Redis sorted set of scheduled reminders, scored by
+// their send time in Unix seconds
+const reminderQueueKey = "whatsapp:reminders"
+
+// bookingCancelledKeyPrefix is the state store key prefix marking cancelled
+// bookings, whose reminders are skipped
+const bookingCancelledKeyPrefix = "whatsapp:booking_cancelled:"
+
+// cancelledBookingTTL is how long a cancellation is remembered for reminders
+const cancelledBookingTTL = 30 * 24 * time.Hour
+
+// Reminder delivery limits
+const (
+	// reminderBatchSize is the most reminders sent per poll
+	reminderBatchSize = 100
+	// maxReminderAttempts is how often a reminder is tried before it is dropped
+	maxReminderAttempts = 3
+	// reminderRetryDelay is the wait before retrying a failed reminder
+	reminderRetryDelay = time.Minute
+)
+
+// scheduledReminder is a reminder waiting in the queue. The encoded
+// reminder is the sorted set member, so removing it claims it.
+type scheduledReminder struct {
+	BookingID   string         `json:"booking_id"`
+	PhoneNumber string         `json:"phone_number"`
+	AccountID   string         `json:"account_id,omitempty"`
+	Details     bookingDetails `json:"details"`
+	SendAt      time.Time      `json:"send_at"`
+	Attempts    int            `json:"attempts,omitempty"`
+}
+
+// WithReminders keeps scheduled booking reminders in a Redis sorted set, so
+// they survive restarts and are sent once across replicas
+func WithReminders(client *redis.Client) BookingUseCaseOption {
+	return func(u *BookingUseCase) {
+		u.reminders = client
+	}
+}
+
+// reminderText renders the reminder message of a booking
+func (u *BookingUseCase) reminderText(details bookingDetails) (string, error) {
+	return u.bookingText(i18n.Reminder, details, time.Time{}, "")
+}
+
+// ScheduleReminder schedules a reminder of the booking to be sent at sendAt.
+// Reminders of bookings cancelled meanwhile are skipped.
+func (u *BookingUseCase) ScheduleReminder(ctx context.Context, request BookingRequest, sendAt time.Time) (*BookingResponse, error) {
+	if u.reminders == nil {
+		return nil, ErrRemindersDisabled
+	}
+	if !sendAt.After(time.Now()) {
+		return nil, fmt.Errorf("%w: the reminder must be scheduled in the future", ErrInvalidMessage)
+	}
+
+	phoneNumber, err := utils.NormalizePhone(request.PhoneNumber, u.regionFor(request.AccountID))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPhoneNumber, err)
+	}
+	// An unknown account fails now rather than when the reminder is due; a
+	// disconnected one may be back by then
+	if _, err := u.clientFor(request.AccountID); errors.Is(err, whatsapp.ErrUnknownAccount) {
+		return nil, err
+	}
+
+	reminder := scheduledReminder{
+		BookingID:   request.BookingID,
+		PhoneNumber: phoneNumber,
+		AccountID:   request.AccountID,
+		Details: bookingDetails{
+			ServiceName:  request.ServiceName,
+			UserName:     request.UserName,
+			LocationName: request.LocationName,
+			StartTime:    request.StartTime,
+			Date:         request.Date,
+			EmployeeName: request.EmployeeName,
+			Emoji:        request.Emoji,
+			Language:     u.bookingLanguage(request.Language),
+		},
+		SendAt: sendAt,
+	}
+	// A reminder that cannot be rendered fails now rather than when it is due
+	messageText, err := u.reminderText(reminder.Details)
+	if err != nil {
+		return nil, err
+	}
+	if err := u.queueReminder(ctx, reminder); err != nil {
+		return nil, err
+	}
+
+	u.logger.Info("Booking reminder scheduled",
+		zap.String("booking_id", request.BookingID),
+		zap.String("phone_number", phoneNumber),
+		zap.Time("send_at", sendAt))
+
+	return &BookingResponse{
+		BookingID: request.BookingID,
+		Message:   messageText,
+		Status:    "scheduled",
+		TraceID:   u.latestTraceID(ctx, request.BookingID),
+	}, nil
+}
+
+// queueReminder adds the reminder to the queue, due at its send time
+func (u *BookingUseCase) queueReminder(ctx context.Context, reminder scheduledReminder) error {
+	data, err := json.Marshal(reminder)
+	if err != nil {
+		return fmt.Errorf("failed to encode reminder: %w", err)
+	}
+	if err := u.reminders.ZAdd(ctx, reminderQueueKey, float64(reminder.SendAt.Unix()), string(data)); err != nil {
+		return fmt.Errorf("failed to schedule reminder: %w", err)
+	}
+	return nil
+}
+
+// StartReminders sends the due reminders on the given interval until the
+// context is cancelled. The queue lives in Redis, so reminders scheduled
+// before a restart are still sent.
+func (u *BookingUseCase) StartReminders(ctx context.Context, interval time.Duration) {
+	if u.reminders == nil {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				// Claimed reminders are sent even if the loop is stopped
+				// meanwhile, so none is lost on shutdown
+				pollCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), interval)
+				sent, err := u.SendDueReminders(pollCtx, time.Now())
+				cancel()
+				if err != nil {
+					u.logger.Warn("Failed to send due reminders", zap.Error(err))
+					continue
+				}
+				if sent > 0 {
+					u.logger.Info("Sent booking reminders", zap.Int("count", sent))
+				}
+			}
+		}
+	}()
+}
+
+// SendDueReminders sends the reminders due before now and returns how many
+// were sent. Each reminder is claimed by removing it from the queue, so
+// another replica polling at the same time cannot send it twice.
+func (u *BookingUseCase) SendDueReminders(ctx context.Context, now time.Time) (int, error) {
+	members, err := u.reminders.ZRangeByScore(ctx, reminderQueueKey, float64(now.Unix()), reminderBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, member := range members {
+		claimed, err := u.reminders.ZRem(ctx, reminderQueueKey, member)
+		if err != nil {
+			return sent, err
+		}
+		if !claimed {
+			continue
+		}
+
+		var reminder scheduledReminder
+		if err := json.Unmarshal([]byte(member), &reminder); err != nil {
+			u.logger.Warn("Dropping undecodable reminder", zap.Error(err))
+			continue
+		}
+		if u.sendReminder(ctx, reminder) {
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+// sendReminder sends a claimed reminder unless its booking was cancelled. A
+// failed send is queued again until the attempts run out.
+func (u *BookingUseCase) sendReminder(ctx context.Context, reminder scheduledReminder) bool {
+	log := u.logger.With(zap.String("booking_id", reminder.BookingID))
+
+	if u.bookingCancelled(ctx, reminder.BookingID) {
+		log.Info("Skipping reminder of cancelled booking",
+			zap.String("phone_number", reminder.PhoneNumber))
+		return false
+	}
+
+	traceID := u.latestTraceID(ctx, reminder.BookingID)
+	sendOptions := []whatsapp.SendOption{whatsapp.WithPriority(whatsapp.PriorityNormal)}
+	if traceID != "" {
+		log = log.With(zap.String("trace_id", traceID))
+		sendOptions = append(sendOptions,
+			whatsapp.WithMetadata("booking_id", reminder.BookingID),
+			whatsapp.WithMetadata("trace_id", traceID))
+	}
+
+	messageText, err := u.reminderText(reminder.Details)
+	if err != nil {
+		log.Error("Dropping reminder that cannot be rendered", zap.Error(err))
+		return false
+	}
+
+	client, err := u.clientFor(reminder.AccountID)
+	var sent whatsmeow.SendResponse
+	if err == nil {
+		jid := types.NewJID(reminder.PhoneNumber, types.DefaultUserServer)
+		sent, err = client.SendText(ctx, jid, messageText, sendOptions...)
+	}
+	if err != nil {
+		reminder.Attempts++
+		if reminder.Attempts >= maxReminderAttempts {
+			log.Error("Dropping reminder after failed attempts",
+				zap.Int("attempts", reminder.Attempts),
+				zap.Error(err))
+			return false
+		}
+		log.Warn("Failed to send reminder, retrying", zap.Int("attempts", reminder.Attempts), zap.Error(err))
+		reminder.SendAt = time.Now().Add(reminderRetryDelay)
+		if err := u.queueReminder(ctx, reminder); err != nil {
+			log.Error("Failed to requeue reminder", zap.Error(err))
+		}
+		return false
+	}
+
+	u.recordTrace(ctx, TraceEvent{
+		TraceID:   traceID,
+		BookingID: reminder.BookingID,
+		Stage:     TraceStageReminder,
+		MessageID: sent.ID,
+	})
+	log.Info("Booking reminder sent", zap.String("phone_number", reminder.PhoneNumber))
+	return true
+}
+
+// markCancelled remembers that a booking was cancelled, so its reminders
+// are skipped
+func (u *BookingUseCase) markCancelled(ctx context.Context, bookingID string) {
+	if u.store == nil || bookingID == "" {
+		return
+	}
+	if err := u.store.Set(ctx, bookingCancelledKeyPrefix+bookingID, "1", cancelledBookingTTL); err != nil {
+		u.logger.Warn("Failed to mark booking cancelled", zap.String("booking_id", bookingID), zap.Error(err))
+	}
+}
+
+// bookingCancelled reports whether the booking was cancelled by the customer
+// or through /booking/cancel
+func (u *BookingUseCase) bookingCancelled(ctx context.Context, bookingID string) bool {
+	if u.store == nil || bookingID == "" {
+		return false
+	}
+	_, err := u.store.Get(ctx, bookingCancelledKeyPrefix+bookingID)
+	return err == nil
+}
@@ -0,0 +1,144 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/redis"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/whatsapp"
+)
+
+// newReminderUseCase creates a booking use case sending reminders from a
+// dry-run client through the given Redis queue
+func newReminderUseCase(t *testing.T, redisClient *redis.Client) (*BookingUseCase, *whatsapp.Client) {
+	t.Helper()
+	client := newTestClient(t)
+	return NewBookingUseCase(client, logger.NewNop(),
+		WithBookingStore(newFakeStore()),
+		WithReminders(redisClient)), client
+}
+
+// scheduleTestReminder schedules the reminder of a test booking a minute ahead
+func scheduleTestReminder(t *testing.T, u *BookingUseCase, bookingID string) {
+	t.Helper()
+	if _, err := u.ScheduleReminder(context.Background(), testBooking(bookingID), time.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("ScheduleReminder() error = %v", err)
+	}
+}
+
+func TestDueReminderSentOnce(t *testing.T) {
+	redisClient, server := newTestRedis(t)
+	first, firstClient := newReminderUseCase(t, redisClient)
+	second, secondClient := newReminderUseCase(t, redisClient)
+	sent := []*sentMessages{captureSends(firstClient), captureSends(secondClient)}
+	scheduleTestReminder(t, first, "booking-1")
+
+	// Nothing is due before the send time
+	if count, err := first.SendDueReminders(context.Background(), time.Now()); err != nil || count != 0 {
+		t.Fatalf("SendDueReminders() before the send time = %d, %v, want 0", count, err)
+	}
+
+	// Two replicas polling at once send the reminder once between them
+	due := time.Now().Add(2 * time.Minute)
+	var total atomic.Int32
+	var wg sync.WaitGroup
+	for _, u := range []*BookingUseCase{first, second} {
+		wg.Add(1)
+		go func(u *BookingUseCase) {
+			defer wg.Done()
+			count, err := u.SendDueReminders(context.Background(), due)
+			if err != nil {
+				t.Errorf("SendDueReminders() error = %v", err)
+			}
+			total.Add(int32(count))
+		}(u)
+	}
+	wg.Wait()
+	if total.Load() != 1 {
+		t.Errorf("replicas sent %d reminders, want 1", total.Load())
+	}
+	if got := len(sent[0].all()) + len(sent[1].all()); got != 1 {
+		t.Errorf("sent %d messages, want 1", got)
+	}
+	if server.Exists(reminderQueueKey) {
+		t.Error("sent reminder still queued")
+	}
+}
+
+func TestReminderOfCancelledBookingSkipped(t *testing.T) {
+	redisClient, _ := newTestRedis(t)
+	u, client := newReminderUseCase(t, redisClient)
+	sent := captureSends(client)
+	scheduleTestReminder(t, u, "booking-1")
+	scheduleTestReminder(t, u, "booking-2")
+
+	u.markCancelled(context.Background(), "booking-1")
+
+	count, err := u.SendDueReminders(context.Background(), time.Now().Add(2*time.Minute))
+	if err != nil || count != 1 {
+		t.Fatalf("SendDueReminders() = %d, %v, want only the booking still active", count, err)
+	}
+	if len(sent.all()) != 1 {
+		t.Errorf("sent %d messages, want 1", len(sent.all()))
+	}
+	// The skipped reminder is not retried
+	if count, _ := u.SendDueReminders(context.Background(), time.Now().Add(time.Hour)); count != 0 {
+		t.Errorf("SendDueReminders() later sent %d reminders, want 0", count)
+	}
+}
+
+func TestFailedReminderRetriedThenDropped(t *testing.T) {
+	redisClient, server := newTestRedis(t)
+	u, client := newReminderUseCase(t, redisClient)
+	var attempts atomic.Int32
+	client.OnBeforeSend(func(*whatsapp.OutboundMessage) error {
+		attempts.Add(1)
+		return errors.New("send failed")
+	})
+	scheduleTestReminder(t, u, "booking-1")
+
+	// Each failed attempt requeues the reminder after the retry delay
+	for attempt := 1; attempt < maxReminderAttempts; attempt++ {
+		now := time.Now().Add(time.Duration(attempt) * time.Hour)
+		if count, err := u.SendDueReminders(context.Background(), now); err != nil || count != 0 {
+			t.Fatalf("SendDueReminders() attempt %d = %d, %v, want 0", attempt, count, err)
+		}
+		members, err := server.ZMembers(reminderQueueKey)
+		if err != nil || len(members) != 1 {
+			t.Fatalf("queue after attempt %d = %v, %v, want the reminder requeued", attempt, members, err)
+		}
+	}
+
+	// The last attempt drops it
+	if _, err := u.SendDueReminders(context.Background(), time.Now().Add(24*time.Hour)); err != nil {
+		t.Fatalf("SendDueReminders() error = %v", err)
+	}
+	if server.Exists(reminderQueueKey) {
+		t.Error("reminder still queued after the last attempt")
+	}
+	if attempts.Load() != maxReminderAttempts {
+		t.Errorf("tried %d sends, want %d", attempts.Load(), maxReminderAttempts)
+	}
+}
+
+func TestRemindersSurviveRestart(t *testing.T) {
+	redisClient, _ := newTestRedis(t)
+	u, _ := newReminderUseCase(t, redisClient)
+	scheduleTestReminder(t, u, "booking-1")
+
+	// A new instance on the same queue sends the reminder scheduled before
+	restarted, client := newReminderUseCase(t, redisClient)
+	sent := captureSends(client)
+	count, err := restarted.SendDueReminders(context.Background(), time.Now().Add(2*time.Minute))
+	if err != nil || count != 1 {
+		t.Fatalf("SendDueReminders() after restart = %d, %v, want 1", count, err)
+	}
+	if messages := sent.all(); len(messages) != 1 || messages[0].To.User != testPhone {
+		t.Errorf("sent %v, want the reminder to %s", messages, testPhone)
+	}
+}
@@ -0,0 +1,139 @@
+package usecases
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/store"
+)
+
+// pendingBookingKeyPrefix is the state store key prefix for bookings awaiting a response
+const pendingBookingKeyPrefix = "whatsapp:booking:"
+
+// bookingIndexKeyPrefix is the state store key prefix mapping a booking ID to
+// the phone number it was sent to
+const bookingIndexKeyPrefix = "whatsapp:booking_id:"
+
+// pendingBookingTTL is how long a booking waits for the customer's response
+const pendingBookingTTL = 72 * time.Hour
+
+// Booking metadata size limits
+const (
+	maxMetadataEntries  = 20
+	maxMetadataKeyLen   = 64
+	maxMetadataValueLen = 512
+)
+
+// ErrInvalidMetadata is returned when booking metadata exceeds the size limits
+var ErrInvalidMetadata = errors.New("invalid booking metadata")
+
+// pendingBooking is the booking state kept while waiting for the customer's response
+type pendingBooking struct {
+	BookingID string            `json:"booking_id"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	SentAt    time.Time         `json:"sent_at"`
+	AccountID string            `json:"account_id,omitempty"`
+	// ExpiresAt is the response deadline; zero means the booking never expires
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+	// MessageID is the ID of the confirmation message, edited on updates
+	MessageID string         `json:"message_id,omitempty"`
+	Details   bookingDetails `json:"details"`
+	// TraceID follows the booking through logs, callbacks and its trace
+	TraceID string `json:"trace_id,omitempty"`
+}
+
+// bookingDetails are the booking fields rendered in the confirmation message
+type bookingDetails struct {
+	ServiceName  string `json:"service_name"`
+	UserName     string `json:"user_name"`
+	LocationName string `json:"location_name"`
+	StartTime    string `json:"start_time"`
+	Date         string `json:"date"`
+	EmployeeName string `json:"employee_name"`
+	Emoji        *bool  `json:"emoji,omitempty"`
+	// Language is the language code the booking messages are rendered in
+	Language string `json:"language,omitempty"`
+}
+
+// validateMetadata checks booking metadata against the size limits
+func validateMetadata(metadata map[string]string) error {
+	if len(metadata) > maxMetadataEntries {
+		return fmt.Errorf("%w: at most %d entries allowed", ErrInvalidMetadata, maxMetadataEntries)
+	}
+	for key, value := range metadata {
+		if key == "" || len(key) > maxMetadataKeyLen {
+			return fmt.Errorf("%w: keys must be 1-%d bytes", ErrInvalidMetadata, maxMetadataKeyLen)
+		}
+		if len(value) > maxMetadataValueLen {
+			return fmt.Errorf("%w: value of %q exceeds %d bytes", ErrInvalidMetadata, key, maxMetadataValueLen)
+		}
+	}
+	return nil
+}
+
+// savePendingBooking stores the booking awaiting a response from phoneNumber
+func (u *BookingUseCase) savePendingBooking(ctx context.Context, phoneNumber string, booking pendingBooking) error {
+	if u.store == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(booking)
+	if err != nil {
+		return fmt.Errorf("failed to encode pending booking: %w", err)
+	}
+	// Keep bookings with a long deadline until they can expire
+	ttl := pendingBookingTTL
+	if remaining := time.Until(booking.ExpiresAt) + pendingBookingTTL; !booking.ExpiresAt.IsZero() && remaining > ttl {
+		ttl = remaining
+	}
+	if err := u.store.Set(ctx, pendingBookingKeyPrefix+phoneNumber, string(data), ttl); err != nil {
+		return fmt.Errorf("failed to save pending booking: %w", err)
+	}
+	if booking.BookingID != "" {
+		if err := u.store.Set(ctx, bookingIndexKeyPrefix+booking.BookingID, phoneNumber, ttl); err != nil {
+			return fmt.Errorf("failed to index pending booking: %w", err)
+		}
+		// A booking sent again after a cancellation gets its reminders back
+		if err := u.store.Delete(ctx, bookingCancelledKeyPrefix+booking.BookingID); err != nil {
+			return fmt.Errorf("failed to clear booking cancellation: %w", err)
+		}
+	}
+	// Buttons of the new confirmation are answerable again
+	if err := u.store.Delete(ctx, expiredBookingKeyPrefix+phoneNumber); err != nil {
+		return fmt.Errorf("failed to clear expired booking: %w", err)
+	}
+	return nil
+}
+
+// pendingBookingFor returns the booking awaiting a response from phoneNumber,
+// or nil if there is none
+func (u *BookingUseCase) pendingBookingFor(ctx context.Context, phoneNumber string) (*pendingBooking, error) {
+	if u.store == nil {
+		return nil, nil
+	}
+
+	value, err := u.store.Get(ctx, pendingBookingKeyPrefix+phoneNumber)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var booking pendingBooking
+	if err := json.Unmarshal([]byte(value), &booking); err != nil {
+		return nil, fmt.Errorf("failed to decode pending booking: %w", err)
+	}
+	return &booking, nil
+}
+
+// resolvePendingBooking removes the pending booking once the customer responded
+func (u *BookingUseCase) resolvePendingBooking(ctx context.Context, phoneNumber string) error {
+	if u.store == nil {
+		return nil
+	}
+	return u.store.Delete(ctx, pendingBookingKeyPrefix+phoneNumber)
+}
@@ -0,0 +1,92 @@
+package usecases
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/webhook"
+)
+
+func TestBookingMetadataReachesCallback(t *testing.T) {
+	stateStore := newFakeStore()
+	metadata := map[string]string{"crm_id": "A-1042", "source": "web"}
+
+	client := newTestClient(t)
+	request := testBooking("booking-1")
+	request.Metadata = metadata
+	sender := NewBookingUseCase(client, logger.NewNop(), WithBookingStore(stateStore))
+	if _, err := sender.SendConfirmationMessage(context.Background(), request); err != nil {
+		t.Fatalf("SendConfirmationMessage() error = %v", err)
+	}
+
+	// The response is handled after a restart, from the persisted state
+	url, posted := newEventServer(t, http.StatusOK)
+	receiver := NewBookingUseCase(client, logger.NewNop(),
+		WithBookingStore(stateStore),
+		WithResponseCallback(webhook.NewNotifier(url)))
+	response, err := receiver.ProcessIncomingResponse(context.Background(), testPhone, "Sí", "")
+	if err != nil {
+		t.Fatalf("ProcessIncomingResponse() error = %v", err)
+	}
+	if response.BookingID != "booking-1" || !maps.Equal(response.Metadata, metadata) {
+		t.Errorf("response = %s %v, want booking-1 %v", response.BookingID, response.Metadata, metadata)
+	}
+
+	select {
+	case event := <-posted:
+		var intent Intent
+		if err := json.Unmarshal(event.Data, &intent); err != nil {
+			t.Fatalf("decode callback: %v", err)
+		}
+		if event.Type != BookingResponseEvent || intent.BookingID != "booking-1" || !maps.Equal(intent.Metadata, metadata) {
+			t.Errorf("callback = %s %s %v, want %s booking-1 %v", event.Type, intent.BookingID, intent.Metadata, BookingResponseEvent, metadata)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("callback not posted")
+	}
+}
+
+func TestBookingMetadataLimits(t *testing.T) {
+	tooMany := make(map[string]string)
+	for i := range maxMetadataEntries + 1 {
+		tooMany[fmt.Sprintf("key%d", i)] = "value"
+	}
+	tests := []struct {
+		name     string
+		metadata map[string]string
+		wantErr  bool
+	}{
+		{name: "none"},
+		{name: "at the limits", metadata: map[string]string{strings.Repeat("k", maxMetadataKeyLen): strings.Repeat("v", maxMetadataValueLen)}},
+		{name: "too many entries", metadata: tooMany, wantErr: true},
+		{name: "empty key", metadata: map[string]string{"": "value"}, wantErr: true},
+		{name: "long key", metadata: map[string]string{strings.Repeat("k", maxMetadataKeyLen+1): "value"}, wantErr: true},
+		{name: "long value", metadata: map[string]string{"key": strings.Repeat("v", maxMetadataValueLen+1)}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t)
+			sent := captureSends(client)
+			u := NewBookingUseCase(client, logger.NewNop())
+
+			request := testBooking("booking-1")
+			request.Metadata = tt.metadata
+			_, err := u.SendConfirmationMessage(context.Background(), request)
+			if got := errors.Is(err, ErrInvalidMetadata); got != tt.wantErr {
+				t.Fatalf("SendConfirmationMessage() error = %v, want ErrInvalidMetadata %v", err, tt.wantErr)
+			}
+			// Invalid metadata is rejected before anything is sent
+			if tt.wantErr && len(sent.all()) != 0 {
+				t.Error("confirmation sent with invalid metadata")
+			}
+		})
+	}
+}
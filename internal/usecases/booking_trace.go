@@ -0,0 +1,75 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/webhook"
+	"go.uber.org/zap"
+)
+
+// BookingTrace is the ordered lifecycle of a booking
+type BookingTrace struct {
+	BookingID string       `json:"booking_id"`
+	TraceID   string       `json:"trace_id"`
+	Events    []TraceEvent `json:"events"`
+}
+
+// WithTrace records the lifecycle events of each booking in the trace use case
+func WithTrace(trace *TraceUseCase) BookingUseCaseOption {
+	return func(u *BookingUseCase) {
+		u.trace = trace
+	}
+}
+
+// Trace returns the recorded lifecycle of a booking. A booking sent more
+// than once lists the events of every send; TraceID is the latest one.
+func (u *BookingUseCase) Trace(ctx context.Context, bookingID string) (*BookingTrace, error) {
+	if u.trace == nil {
+		return nil, fmt.Errorf("%w: %s", ErrBookingNotFound, bookingID)
+	}
+
+	events, err := u.trace.Events(ctx, bookingID)
+	if err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrBookingNotFound, bookingID)
+	}
+
+	return &BookingTrace{
+		BookingID: bookingID,
+		TraceID:   events[len(events)-1].TraceID,
+		Events:    events,
+	}, nil
+}
+
+// recordTrace records a lifecycle event when tracing is enabled
+func (u *BookingUseCase) recordTrace(ctx context.Context, event TraceEvent) {
+	if u.trace != nil {
+		u.trace.Record(ctx, event)
+	}
+}
+
+// notify posts a booking event to the integrator and records the callback
+// in the booking trace
+func (u *BookingUseCase) notify(ctx context.Context, log logger.Logger, notifier *webhook.Notifier, eventType string, intent Intent) error {
+	err := notifier.Notify(ctx, eventType, intent)
+
+	status := "delivered"
+	if err != nil {
+		status = "failed"
+	}
+	u.recordTrace(ctx, TraceEvent{
+		TraceID:   intent.TraceID,
+		BookingID: intent.BookingID,
+		Stage:     TraceStageCallback,
+		Status:    status,
+		Detail:    eventType,
+	})
+	log.Debug("Booking callback posted",
+		zap.String("event", eventType),
+		zap.String("status", status))
+	return err
+}
@@ -0,0 +1,108 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/i18n"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/store"
+	"go.mau.fi/whatsmeow/types"
+	"go.uber.org/zap"
+)
+
+// ErrBookingNotFound is returned when no booking with the given ID was sent
+var ErrBookingNotFound = errors.New("booking not found")
+
+// ErrBookingNotPending is returned when updating a booking the customer
+// already answered
+var ErrBookingNotPending = errors.New("booking is no longer pending")
+
+// BookingUpdate holds the booking fields to change; nil fields are kept
+type BookingUpdate struct {
+	StartTime    *string
+	Date         *string
+	LocationName *string
+	EmployeeName *string
+}
+
+// confirmationText renders the confirmation message of a booking, with the
+// response deadline when it has one
+func (u *BookingUseCase) confirmationText(details bookingDetails, expiresAt time.Time) (string, error) {
+	return u.bookingText(i18n.Confirmation, details, expiresAt, "")
+}
+
+// UpdateBooking changes the details of a booking still awaiting the
+// customer's response and edits the confirmation message already sent
+func (u *BookingUseCase) UpdateBooking(ctx context.Context, bookingID string, update BookingUpdate) (*BookingResponse, error) {
+	if u.store == nil {
+		return nil, fmt.Errorf("%w: %s", ErrBookingNotFound, bookingID)
+	}
+
+	phoneNumber, err := u.store.Get(ctx, bookingIndexKeyPrefix+bookingID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, fmt.Errorf("%w: %s", ErrBookingNotFound, bookingID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// The customer answered, or a newer booking replaced this one
+	booking, err := u.pendingBookingFor(ctx, phoneNumber)
+	if err != nil {
+		return nil, err
+	}
+	if booking == nil || booking.BookingID != bookingID {
+		return nil, fmt.Errorf("%w: %s", ErrBookingNotPending, bookingID)
+	}
+	log := u.logger.With(zap.String("booking_id", bookingID), zap.String("trace_id", booking.TraceID))
+
+	if update.StartTime != nil {
+		booking.Details.StartTime = *update.StartTime
+	}
+	if update.Date != nil {
+		booking.Details.Date = *update.Date
+	}
+	if update.LocationName != nil {
+		booking.Details.LocationName = *update.LocationName
+	}
+	if update.EmployeeName != nil {
+		booking.Details.EmployeeName = *update.EmployeeName
+	}
+	messageText, err := u.confirmationText(booking.Details, booking.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if booking.MessageID != "" {
+		client, err := u.clientFor(booking.AccountID)
+		if err != nil {
+			return nil, err
+		}
+		jid := types.NewJID(phoneNumber, types.DefaultUserServer)
+		if _, err := client.EditText(ctx, jid, booking.MessageID, messageText); err != nil {
+			log.Error("Failed to edit confirmation message", zap.Error(err))
+			return nil, fmt.Errorf("failed to edit confirmation message: %w", err)
+		}
+	}
+
+	if err := u.savePendingBooking(ctx, phoneNumber, *booking); err != nil {
+		return nil, err
+	}
+
+	u.recordTrace(ctx, TraceEvent{
+		TraceID:   booking.TraceID,
+		BookingID: bookingID,
+		Stage:     TraceStageUpdated,
+		MessageID: booking.MessageID,
+	})
+	log.Info("Booking updated", zap.String("phone_number", phoneNumber))
+
+	return &BookingResponse{
+		BookingID: bookingID,
+		Message:   messageText,
+		Status:    "updated",
+		TraceID:   booking.TraceID,
+	}, nil
+}
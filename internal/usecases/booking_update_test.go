@@ -0,0 +1,103 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/whatsapp"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+)
+
+func TestUpdatePendingBooking(t *testing.T) {
+	client := newTestClient(t)
+	sent := captureSends(client)
+	u := NewBookingUseCase(client, logger.NewNop(), WithBookingStore(newFakeStore()))
+	ctx := context.Background()
+
+	if _, err := u.SendConfirmationMessage(ctx, testBooking("booking-1")); err != nil {
+		t.Fatalf("SendConfirmationMessage() error = %v", err)
+	}
+	booking, err := u.pendingBookingFor(ctx, testPhone)
+	if err != nil || booking == nil || booking.MessageID == "" {
+		t.Fatalf("pending booking = %+v, %v, want one with its message ID", booking, err)
+	}
+	confirmationID := booking.MessageID
+
+	startTime, location := "11:30", "Norte"
+	response, err := u.UpdateBooking(ctx, "booking-1", BookingUpdate{StartTime: &startTime, LocationName: &location})
+	if err != nil {
+		t.Fatalf("UpdateBooking() error = %v", err)
+	}
+	if response.Status != "updated" || response.BookingID != "booking-1" {
+		t.Errorf("response = %+v, want booking-1 updated", response)
+	}
+	// Updated fields change and the others are kept
+	for _, want := range []string{"11:30", "Norte", "Luis", "Corte"} {
+		if !strings.Contains(response.Message, want) {
+			t.Errorf("updated message %q does not contain %q", response.Message, want)
+		}
+	}
+	if strings.Contains(response.Message, "10:00") || strings.Contains(response.Message, "Centro") {
+		t.Errorf("updated message %q still shows the old time or location", response.Message)
+	}
+
+	// The confirmation already sent is edited in place
+	messages := sent.all()
+	if len(messages) != 2 {
+		t.Fatalf("sent %d messages, want the confirmation and its edit", len(messages))
+	}
+	protocol := messages[1].Message.GetEditedMessage().GetMessage().GetProtocolMessage()
+	if protocol.GetType() != waE2E.ProtocolMessage_MESSAGE_EDIT || protocol.GetKey().GetID() != confirmationID {
+		t.Fatalf("second message = %v, want an edit of %s", messages[1].Message, confirmationID)
+	}
+	if got := whatsapp.MessageText(protocol.GetEditedMessage()); got != response.Message {
+		t.Errorf("edited text = %q, want %q", got, response.Message)
+	}
+
+	// Later updates build on the stored details
+	employee := "Marta"
+	response, err = u.UpdateBooking(ctx, "booking-1", BookingUpdate{EmployeeName: &employee})
+	if err != nil {
+		t.Fatalf("second UpdateBooking() error = %v", err)
+	}
+	if !strings.Contains(response.Message, "Marta") || !strings.Contains(response.Message, "11:30") {
+		t.Errorf("second update message %q, want Marta at 11:30", response.Message)
+	}
+}
+
+func TestUpdateResolvedBooking(t *testing.T) {
+	client := newTestClient(t)
+	sent := captureSends(client)
+	u := NewBookingUseCase(client, logger.NewNop(), WithBookingStore(newFakeStore()))
+	ctx := context.Background()
+
+	if _, err := u.SendConfirmationMessage(ctx, testBooking("booking-1")); err != nil {
+		t.Fatalf("SendConfirmationMessage() error = %v", err)
+	}
+	if _, err := u.ProcessIncomingResponse(ctx, testPhone, "Confirmar", ResponseConfirm); err != nil {
+		t.Fatalf("ProcessIncomingResponse() error = %v", err)
+	}
+	sentBefore := len(sent.all())
+
+	startTime := "11:30"
+	if _, err := u.UpdateBooking(ctx, "booking-1", BookingUpdate{StartTime: &startTime}); !errors.Is(err, ErrBookingNotPending) {
+		t.Errorf("UpdateBooking() of a confirmed booking error = %v, want %v", err, ErrBookingNotPending)
+	}
+	if _, err := u.UpdateBooking(ctx, "unknown", BookingUpdate{StartTime: &startTime}); !errors.Is(err, ErrBookingNotFound) {
+		t.Errorf("UpdateBooking() of an unknown booking error = %v, want %v", err, ErrBookingNotFound)
+	}
+	if got := len(sent.all()); got != sentBefore {
+		t.Errorf("rejected updates sent %d messages, want none", got-sentBefore)
+	}
+
+	// A newer booking for the same number replaces the old one
+	if _, err := u.SendConfirmationMessage(ctx, testBooking("booking-2")); err != nil {
+		t.Fatalf("SendConfirmationMessage() error = %v", err)
+	}
+	if _, err := u.UpdateBooking(ctx, "booking-1", BookingUpdate{StartTime: &startTime}); !errors.Is(err, ErrBookingNotPending) {
+		t.Errorf("UpdateBooking() of a replaced booking error = %v, want %v", err, ErrBookingNotPending)
+	}
+}
@@ -2,30 +2,255 @@ package usecases
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/cdipaolo/sentiment"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/i18n"
 	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/redis"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/requestid"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/store"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/templates"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/utils"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/webhook"
 	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/whatsapp"
-	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow"
 	"go.mau.fi/whatsmeow/types"
 	"go.uber.org/zap"
-	"google.golang.org/protobuf/proto"
 )
 
+// ErrInvalidPhoneNumber is returned when a booking phone number cannot be validated
+var ErrInvalidPhoneNumber = errors.New("invalid phone number")
+
 // BookingUseCase handles booking-related operations
 type BookingUseCase struct {
-	client *whatsapp.Client
-	logger logger.Logger
+	client      *whatsapp.Client
+	logger      logger.Logger
+	emoji       bool
+	phoneRegion string
+	// intentNotifier receives classified intents in external reply mode
+	intentNotifier *webhook.Notifier
+	// callbackNotifier receives booking responses in auto reply mode
+	callbackNotifier *webhook.Notifier
+	// store keeps bookings awaiting a response and conversation locales
+	store store.Store
+	// defaultLocale is used when the message language cannot be detected
+	defaultLocale string
+	// languageThreshold is the minimum detection confidence to switch locale
+	languageThreshold float64
+	// clients routes confirmations to the requested account's client
+	clients *whatsapp.ClientManager
+	// confirmationDeadline expires unconfirmed bookings; zero disables it
+	confirmationDeadline time.Duration
+	// expiryMessage sends a final message when a booking expires
+	expiryMessage bool
+	// timezone formats the response deadline shown in the confirmation
+	timezone *time.Location
+	// maxBodyLen truncates inbound bodies before they are processed
+	maxBodyLen int
+	// maxBodyHardLen rejects inbound bodies with a request for a shorter message
+	maxBodyHardLen int
+	// trace records the lifecycle events of each booking
+	trace *TraceUseCase
+	// noBookingReply answers unrecognized messages without a pending booking
+	noBookingReply FallbackReply
+	// ambiguousReply answers unrecognized responses to a pending booking
+	ambiguousReply FallbackReply
+	// consent handles the opt-out and opt-in keywords before any booking
+	consent *ConsentUseCase
+	// intentRules detect confirmations and cancellations by keyword
+	intentRules IntentRules
+	// replyTemplates override the built-in automatic replies
+	replyTemplates templates.Repository
+	// commands route inbound text to the conversational commands
+	commands *CommandRouter
+	// reminders queues scheduled booking reminders; nil disables them
+	reminders *redis.Client
+	// mediaReply acknowledges inbound media that matches no booking response
+	mediaReply FallbackReply
+	// idempotencyWindow replays confirmation responses for repeated
+	// idempotency keys; zero disables it
+	idempotencyWindow time.Duration
+	// stopExpiry stops the expiry loop and expiryDone is closed once it
+	// returned; both are nil until StartExpiry runs
+	stopExpiry context.CancelFunc
+	expiryDone chan struct{}
+}
+
+// Webhook event types posted for inbound responses
+const (
+	// IntentEvent is posted in external reply mode
+	IntentEvent = "message.intent"
+	// BookingResponseEvent is posted in auto reply mode once the customer confirmed or cancelled
+	BookingResponseEvent = "booking.response"
+)
+
+// Intent is the classified intent of an inbound message. Metadata attached to
+// the pending booking is echoed back verbatim.
+type Intent struct {
+	PhoneNumber string            `json:"phone_number"`
+	Message     string            `json:"message"`
+	Status      string            `json:"status"`
+	BookingID   string            `json:"booking_id,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	Locale      string            `json:"locale"`
+	// TraceID follows the booking across logs, callbacks and its trace
+	TraceID string `json:"trace_id,omitempty"`
+	// Truncated is true when Message was cut to the maximum body length
+	Truncated bool `json:"truncated,omitempty"`
+	// Media is the attachment of the message, whose caption is Message
+	Media *whatsapp.InboundMedia `json:"media,omitempty"`
+}
+
+// BookingUseCaseOption is a function that configures a BookingUseCase
+type BookingUseCaseOption func(*BookingUseCase)
+
+// WithEmoji sets whether outgoing messages include emoji by default
+func WithEmoji(enabled bool) BookingUseCaseOption {
+	return func(u *BookingUseCase) {
+		u.emoji = enabled
+	}
+}
+
+// WithPhoneRegion sets the default region used to validate phone numbers
+func WithPhoneRegion(region string) BookingUseCaseOption {
+	return func(u *BookingUseCase) {
+		u.phoneRegion = region
+	}
+}
+
+// WithExternalReply switches to external reply mode: classified intents are
+// posted to the notifier and no automatic reply is sent
+func WithExternalReply(notifier *webhook.Notifier) BookingUseCaseOption {
+	return func(u *BookingUseCase) {
+		u.intentNotifier = notifier
+	}
+}
+
+// WithResponseCallback posts booking responses to the notifier in auto reply mode
+func WithResponseCallback(notifier *webhook.Notifier) BookingUseCaseOption {
+	return func(u *BookingUseCase) {
+		u.callbackNotifier = notifier
+	}
+}
+
+// WithBookingStore sets the store used to keep bookings awaiting a response
+func WithBookingStore(stateStore store.Store) BookingUseCaseOption {
+	return func(u *BookingUseCase) {
+		u.store = stateStore
+	}
+}
+
+// WithDefaultLocale sets the reply locale used when the language of a
+// message cannot be detected (es, en or pt)
+func WithDefaultLocale(locale string) BookingUseCaseOption {
+	return func(u *BookingUseCase) {
+		u.defaultLocale = locale
+	}
+}
+
+// WithLanguageThreshold sets the minimum confidence (0-1) for a detected
+// language to be used
+func WithLanguageThreshold(threshold float64) BookingUseCaseOption {
+	return func(u *BookingUseCase) {
+		u.languageThreshold = threshold
+	}
+}
+
+// WithClientManager routes confirmations through the client of the account
+// named in the request
+func WithClientManager(manager *whatsapp.ClientManager) BookingUseCaseOption {
+	return func(u *BookingUseCase) {
+		u.clients = manager
+	}
+}
+
+// WithMaxBodyLength truncates inbound bodies longer than maxLen runes before
+// logging and classification, and asks customers for a shorter message when
+// a body exceeds hardLen runes. Zero disables either limit.
+func WithMaxBodyLength(maxLen, hardLen int) BookingUseCaseOption {
+	return func(u *BookingUseCase) {
+		u.maxBodyLen = maxLen
+		u.maxBodyHardLen = hardLen
+	}
+}
+
+// WithIntentRules replaces the built-in keyword rules used to classify
+// responses; see LoadIntentRules
+func WithIntentRules(rules IntentRules) BookingUseCaseOption {
+	return func(u *BookingUseCase) {
+		u.intentRules = rules
+	}
 }
 
 // NewBookingUseCase creates a new BookingUseCase
-func NewBookingUseCase(client *whatsapp.Client, logger logger.Logger) *BookingUseCase {
-	return &BookingUseCase{
-		client: client,
-		logger: logger,
+func NewBookingUseCase(client *whatsapp.Client, logger logger.Logger, options ...BookingUseCaseOption) *BookingUseCase {
+	useCase := &BookingUseCase{
+		client:            client,
+		logger:            logger,
+		emoji:             true,
+		phoneRegion:       "CL",
+		defaultLocale:     "es",
+		languageThreshold: 0.5,
+		intentRules:       DefaultIntentRules(),
+		timezone:          time.Local,
+	}
+	useCase.commands = useCase.bookingCommands()
+
+	// Apply options
+	for _, option := range options {
+		option(useCase)
+	}
+
+	return useCase
+}
+
+// render applies the emoji preference to an outgoing message. A non-nil
+// override takes precedence over the use case default.
+func (u *BookingUseCase) render(text string, emoji *bool) string {
+	enabled := u.emoji
+	if emoji != nil {
+		enabled = *emoji
+	}
+	if enabled {
+		return text
+	}
+	return utils.StripEmoji(text)
+}
+
+// bookingLanguage returns the language of a booking's messages: the
+// requested one, or the default locale when none was requested
+func (u *BookingUseCase) bookingLanguage(language string) string {
+	if language = strings.ToLower(strings.TrimSpace(language)); language != "" {
+		return language
+	}
+	return u.defaultLocale
+}
+
+// bookingText renders a booking message in the booking's language. The
+// deadline is shown in the configured timezone.
+func (u *BookingUseCase) bookingText(key string, details bookingDetails, deadline time.Time, reason string) (string, error) {
+	if !deadline.IsZero() {
+		deadline = deadline.In(u.timezone)
+	}
+	text, err := i18n.Render(details.Language, key, i18n.Booking{
+		UserName:     details.UserName,
+		ServiceName:  details.ServiceName,
+		LocationName: details.LocationName,
+		StartTime:    details.StartTime,
+		Date:         details.Date,
+		EmployeeName: details.EmployeeName,
+		Deadline:     deadline,
+		Reason:       reason,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to render %s message: %w", key, err)
 	}
+	return u.render(text, details.Emoji), nil
 }
 
 // BookingRequest represents the request data for a booking confirmation
@@ -38,6 +263,21 @@ type BookingRequest struct {
 	Date         string
 	EmployeeName string
 	PhoneNumber  string
+	// Emoji overrides the default emoji preference when set
+	Emoji *bool
+	// Metadata is opaque integrator data returned in the response callback
+	Metadata map[string]string
+	// AccountID selects the business account sending the confirmation;
+	// empty uses the default account
+	AccountID string
+	// ConfirmationDeadline overrides the default response deadline when set
+	ConfirmationDeadline time.Duration
+	// DisappearAfter overrides the account's disappearing message timer
+	// when set; zero sends the confirmation without one
+	DisappearAfter *time.Duration
+	// Language is the language code of the booking messages (es, en, pt);
+	// empty uses the default locale and unsupported ones fall back to Spanish
+	Language string
 }
 
 // BookingResponse represents the response data for a booking confirmation
@@ -45,6 +285,7 @@ type BookingResponse struct {
 	BookingID string
 	Message   string
 	Status    string
+	TraceID   string
 }
 
 // MessageResponse represents the response to an incoming message
@@ -52,196 +293,533 @@ type MessageResponse struct {
 	PhoneNumber string
 	Message     string
 	Status      string
+	BookingID   string
+	Metadata    map[string]string
+	Locale      string
+	TraceID     string
+	// Deferred is true when the reply was left to the integrator
+	Deferred bool
 }
 
 // SendConfirmationMessage sends a confirmation message with interactive buttons
-func (u *BookingUseCase) SendConfirmationMessage(request BookingRequest) (*BookingResponse, error) {
-	// Check if the client is connected
-	if !u.client.IsConnected() {
-		return nil, fmt.Errorf("WhatsApp client is not connected")
+func (u *BookingUseCase) SendConfirmationMessage(ctx context.Context, request BookingRequest) (*BookingResponse, error) {
+	// Resolve the client of the sending account
+	client, err := u.clientFor(request.AccountID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate and normalize the phone number to E.164
+	phoneNumber, err := utils.NormalizePhone(request.PhoneNumber, u.regionFor(request.AccountID))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPhoneNumber, err)
+	}
+	request.PhoneNumber = phoneNumber
+
+	if err := validateMetadata(request.Metadata); err != nil {
+		return nil, err
 	}
 
 	// Parse the phone number to JID format
-	jid := types.NewJID(request.PhoneNumber, types.DefaultUserServer)
+	jid := types.NewJID(phoneNumber, types.DefaultUserServer)
+
+	// The trace ID follows the booking until the customer's response
+	traceID := requestid.New()
+	log := u.logger.With(zap.String("trace_id", traceID), zap.String("booking_id", request.BookingID))
+	u.recordTrace(ctx, TraceEvent{TraceID: traceID, BookingID: request.BookingID, Stage: TraceStageCreated})
 
 	// Create a detailed confirmation message
-	messageText := fmt.Sprintf(
-		"¡Hola %s! 😊\n\n"+
-			"Tu cita para el servicio de %s está casi lista.\n"+
-			"📍 Ubicación: %s\n"+
-			"⏰ Hora: %s\n"+
-			"📅 Fecha: %s\n"+
-			"👤 Atendido por: %s\n\n"+
-			"¿Te gustaría confirmar esta cita?\n"+
-			"Por favor, responde 'Sí' para confirmar o 'No' para cancelar.\n"+
-			"¡Gracias por elegirnos! 🌟",
-		request.UserName,
-		request.ServiceName,
-		request.LocationName,
-		request.StartTime,
-		request.Date,
-		request.EmployeeName,
-	)
-
-	message := &waE2E.Message{
-		Conversation: proto.String(messageText),
+	details := bookingDetails{
+		ServiceName:  request.ServiceName,
+		UserName:     request.UserName,
+		LocationName: request.LocationName,
+		StartTime:    request.StartTime,
+		Date:         request.Date,
+		EmployeeName: request.EmployeeName,
+		Emoji:        request.Emoji,
+		Language:     u.bookingLanguage(request.Language),
+	}
+
+	// The deadline is shown in the message, so it is set before sending
+	var expiresAt time.Time
+	deadline := u.confirmationDeadline
+	if request.ConfirmationDeadline > 0 {
+		deadline = request.ConfirmationDeadline
+	}
+	if deadline > 0 {
+		expiresAt = time.Now().Add(deadline)
+	}
+	messageText, err := u.confirmationText(details, expiresAt)
+	if err != nil {
+		return nil, err
 	}
 
 	// Send the message with context
-	ctx := context.Background()
-	_, err := u.client.Send(ctx, jid, message)
+	sendOptions := []whatsapp.SendOption{
+		whatsapp.WithMetadata("booking_id", request.BookingID),
+		whatsapp.WithMetadata("trace_id", traceID),
+		whatsapp.WithPriority(whatsapp.PriorityNormal),
+	}
+	if request.DisappearAfter != nil {
+		sendOptions = append(sendOptions, whatsapp.WithDisappearAfter(*request.DisappearAfter))
+	}
+	sent, err := client.SendText(ctx, jid, messageText, sendOptions...)
 	if err != nil {
-		u.logger.Error("Failed to send confirmation message", zap.Error(err))
+		log.Error("Failed to send confirmation message", zap.Error(err))
 		return nil, fmt.Errorf("failed to send confirmation message: %w", err)
 	}
 
-	u.logger.Info("Confirmation message sent successfully",
-		zap.String("booking_id", request.BookingID),
+	// Keep the booking until the customer responds
+	booking := pendingBooking{
+		BookingID: request.BookingID,
+		Metadata:  request.Metadata,
+		SentAt:    time.Now(),
+		AccountID: request.AccountID,
+		ExpiresAt: expiresAt,
+		MessageID: sent.ID,
+		Details:   details,
+		TraceID:   traceID,
+	}
+	if err := u.savePendingBooking(ctx, phoneNumber, booking); err != nil {
+		log.Warn("Failed to save pending booking", zap.Error(err))
+	}
+	// The customer is answered in the language of the booking until they
+	// write in another one
+	if request.Language != "" && i18n.Supported(details.Language) && u.store != nil {
+		if err := u.store.Set(ctx, localeKeyPrefix+phoneNumber, details.Language, localeTTL); err != nil {
+			log.Warn("Failed to save conversation locale", zap.Error(err))
+		}
+	}
+
+	log.Info("Confirmation message sent successfully",
 		zap.String("phone_number", request.PhoneNumber))
 
 	return &BookingResponse{
 		BookingID: request.BookingID,
 		Message:   messageText,
 		Status:    "sent",
+		TraceID:   traceID,
 	}, nil
 }
 
-// ProcessIncomingMessage processes incoming messages from WhatsApp
-func (u *BookingUseCase) ProcessIncomingMessage(phoneNumber, messageBody string) (*MessageResponse, error) {
-	// Check if the client is connected
+// clientFor returns the connected client of the account. Without a client
+// manager only the default account is available.
+func (u *BookingUseCase) clientFor(accountID string) (*whatsapp.Client, error) {
+	if u.clients != nil {
+		return u.clients.Get(accountID)
+	}
+	if accountID != "" {
+		return nil, fmt.Errorf("%w: %s", whatsapp.ErrUnknownAccount, accountID)
+	}
 	if !u.client.IsConnected() {
-		return nil, fmt.Errorf("WhatsApp client is not connected")
+		return nil, whatsapp.ErrNotConnected
 	}
+	return u.client, nil
+}
 
-	// Log the incoming message
-	u.logger.Info("Received message from WhatsApp",
-		zap.String("phone_number", phoneNumber),
-		zap.String("message", messageBody))
+// regionFor returns the phone region of the account, falling back to the
+// service default when the account has none
+func (u *BookingUseCase) regionFor(accountID string) string {
+	if u.clients != nil {
+		if region := u.clients.PhoneRegion(accountID); region != "" {
+			return region
+		}
+	}
+	return u.phoneRegion
+}
 
-	// Parse the phone number to JID format
-	jid := types.NewJID(phoneNumber, types.DefaultUserServer)
+// replyClient returns the client of the account that received an inbound
+// message. Messages without one, e.g. from the webhook, and all messages
+// without a client manager are answered by the default account.
+func (u *BookingUseCase) replyClient(original *whatsapp.WhatsAppMessage) (*whatsapp.Client, error) {
+	if u.clients == nil || original == nil {
+		return u.clientFor("")
+	}
+	return u.clientFor(original.AccountID)
+}
 
-	// Check if the message is a response to a booking confirmation
-	var responseMessage string
-	var status string
+// ProcessIncomingMessage processes a message received from WhatsApp, text or
+// attachment. Replies quote the original message, so they read as answers
+// to what the customer said.
+func (u *BookingUseCase) ProcessIncomingMessage(ctx context.Context, msg *whatsapp.WhatsAppMessage) (*MessageResponse, error) {
+	// A message superseded during an offline sync is not answered, but a
+	// STOP in it must still be honoured
+	if msg.Superseded {
+		if u.consent == nil {
+			return nil, nil
+		}
+		_, err := u.consent.ApplyKeyword(ctx, msg.From, msg.Body)
+		return nil, err
+	}
 
-	// Normalize the message body for case-insensitive comparison
-	normalizedMessage := strings.ToLower(messageBody)
+	var media *inboundMedia
+	if msg.Media != nil {
+		media = &inboundMedia{messageID: msg.ID, media: *msg.Media}
+	}
+	return u.processIncoming(ctx, msg.From, msg.Body, msg.ResponseID, media, msg)
+}
 
-	// Variables para el análisis de sentimiento
-	var sentimentScore int
-	var sentimentAnalysisAvailable bool
+// ProcessIncomingResponse processes an incoming message that may carry the ID
+// of a selected button or list row. A recognized ID takes precedence over the
+// text of the message.
+func (u *BookingUseCase) ProcessIncomingResponse(ctx context.Context, phoneNumber, messageBody, responseID string) (*MessageResponse, error) {
+	return u.processIncoming(ctx, phoneNumber, messageBody, responseID, nil, nil)
+}
 
-	// Inicializar el modelo de análisis de sentimiento
-	model, err := sentiment.Restore()
+// processIncoming processes an incoming message, with its attachment if any.
+// Replies quote the original message when it is known.
+func (u *BookingUseCase) processIncoming(ctx context.Context, phoneNumber, messageBody, responseID string, media *inboundMedia, original *whatsapp.WhatsAppMessage) (*MessageResponse, error) {
+	// Replies go out from the account that received the message, which must
+	// be connected
+	client, err := u.replyClient(original)
 	if err != nil {
-		u.logger.Error("Error al cargar el modelo de sentimiento", zap.Error(err))
-		// Continuar con el método tradicional si hay error con el modelo
-	} else {
-		// Realizar análisis de sentimiento (usando inglés como base)
-		analysis := model.SentimentAnalysis(normalizedMessage, sentiment.English)
-		sentimentScore = int(analysis.Score)
-		sentimentAnalysisAvailable = true
-
-		u.logger.Info("Análisis de sentimiento realizado",
-			zap.String("mensaje", normalizedMessage),
-			zap.Int("score", sentimentScore))
-
-		// Clasificar basado en el sentimiento
-		if sentimentScore > 0 {
-			u.logger.Info("Sentimiento positivo: Probable confirmación")
-		} else if sentimentScore < 0 {
-			u.logger.Info("Sentimiento negativo: Probable cancelación")
-		} else {
-			u.logger.Info("Sentimiento neutro")
+		return nil, err
+	}
+
+	// Opt-out and opt-in keywords are acknowledged, not booking responses
+	if u.consent != nil {
+		if response, err := u.consent.HandleInbound(ctx, phoneNumber, messageBody); response != nil || err != nil {
+			return response, err
 		}
 	}
 
-	// Mantener el método tradicional de detección por palabras clave
-	switch {
-	case strings.Contains(normalizedMessage, "sí") || strings.Contains(normalizedMessage, "si"):
-		// User confirmed the booking
-		responseMessage = "¡Gracias por confirmar tu cita! Te esperamos en la fecha y hora acordada. 😊"
-		status = "confirmed"
-		u.logger.Info("Usuario confirmó la reserva",
+	// Replies go to the chat the message came from, so a group message is
+	// answered in the group; consent and bookings stay with the sender
+	jid := types.NewJID(phoneNumber, types.DefaultUserServer)
+	if original != nil && original.IsGroup && !original.Chat.IsEmpty() {
+		jid = original.Chat
+	}
+
+	// Look up the booking this message responds to, so its trace ID is
+	// logged with the rest of the processing
+	log := u.logger
+	booking, err := u.pendingBookingFor(ctx, phoneNumber)
+	if err != nil {
+		log.Warn("Failed to load pending booking", zap.Error(err))
+	}
+	if booking != nil && booking.TraceID != "" {
+		log = log.With(zap.String("trace_id", booking.TraceID), zap.String("booking_id", booking.BookingID))
+	}
+
+	// Ask for a shorter message instead of processing oversized bodies
+	if _, tooLong := utils.Truncate(messageBody, u.maxBodyHardLen); tooLong {
+		return u.rejectTooLong(ctx, log, client, jid, phoneNumber, messageBody, booking, original)
+	}
+
+	// Only a bounded prefix of long bodies is logged and classified
+	messageBody, truncated := utils.Truncate(messageBody, u.maxBodyLen)
+	if truncated {
+		log.Info("Inbound message truncated for processing",
+			zap.String("phone_number", phoneNumber),
+			zap.Int("max_length", u.maxBodyLen))
+	}
+
+	// Log the incoming message
+	log.Info("Received message from WhatsApp",
+		zap.String("phone_number", phoneNumber),
+		zap.String("message", messageBody))
+
+	// Reply in the customer's language
+	locale := u.resolveLocale(ctx, phoneNumber, messageBody)
+
+	inbound := &inboundMessage{
+		log:       log,
+		jid:       jid,
+		booking:   booking,
+		locale:    locale,
+		truncated: truncated,
+		media:     media,
+		original:  original,
+		client:    client,
+	}
+
+	// Prefer the structured button/list response over the text
+	if status := responseStatus(responseID); status != "" {
+		log.Info("Respuesta estructurada recibida",
 			zap.String("phone_number", phoneNumber),
+			zap.String("response_id", responseID),
 			zap.String("status", status))
 
-	case strings.Contains(normalizedMessage, "no"):
-		// User rejected the booking
-		responseMessage = "Hemos cancelado tu cita. Si deseas reagendarla, por favor contáctanos. ¡Gracias!"
-		status = "cancelled"
-		u.logger.Info("Usuario canceló la reserva",
+		// Buttons do not expire on their own, so late taps are rejected here
+		if expired := u.lateResponse(ctx, log, phoneNumber, booking, time.Now()); expired != nil {
+			return u.rejectLate(ctx, log, client, jid, phoneNumber, locale, *expired, original)
+		}
+		return u.respond(ctx, inbound, phoneNumber, messageBody, status)
+	}
+
+	// An attachment without a caption says nothing about the booking
+	if messageBody == "" && media != nil {
+		return u.respond(ctx, inbound, phoneNumber, messageBody, "unknown")
+	}
+
+	// Text goes to the command it invokes, or to the sentiment fallback
+	return u.commands.Route(withInbound(ctx, inbound), locale, phoneNumber, messageBody)
+}
+
+// respond replies to an inbound message with the status it was classified
+// as, or defers the reply to the integrator in external reply mode
+func (u *BookingUseCase) respond(ctx context.Context, inbound *inboundMessage, phoneNumber, messageBody, status string) (*MessageResponse, error) {
+	log, jid, booking, locale, media := inbound.log, inbound.jid, inbound.booking, inbound.locale, inbound.media
+	responseMessage := u.replyText(ctx, locale, status, booking)
+
+	intent := Intent{
+		PhoneNumber: phoneNumber,
+		Message:     messageBody,
+		Status:      status,
+		Locale:      locale,
+		Truncated:   inbound.truncated,
+	}
+	if booking != nil {
+		intent.BookingID = booking.BookingID
+		intent.Metadata = booking.Metadata
+		intent.TraceID = booking.TraceID
+	}
+	if media != nil {
+		intent.Media = &media.media
+		u.recordMedia(ctx, intent, media)
+	}
+	u.recordTrace(ctx, TraceEvent{
+		TraceID:   intent.TraceID,
+		BookingID: intent.BookingID,
+		Stage:     TraceStageReply,
+		Status:    status,
+	})
+
+	// In external reply mode the integrator sends the reply via /messages
+	if u.intentNotifier != nil {
+		if err := u.notify(ctx, log, u.intentNotifier, IntentEvent, intent); err != nil {
+			log.Error("Failed to post message intent", zap.Error(err))
+			return nil, fmt.Errorf("failed to post message intent: %w", err)
+		}
+
+		log.Info("Intención enviada al integrador, respuesta diferida",
 			zap.String("phone_number", phoneNumber),
 			zap.String("status", status))
 
-	default:
-		// Unrecognized response - intentar usar el análisis de sentimiento si está disponible
-		if sentimentAnalysisAvailable {
-			if sentimentScore > 0 {
-				// Sentimiento positivo: tratar como confirmación
-				responseMessage = "¡Gracias por confirmar tu cita! Te esperamos en la fecha y hora acordada. 😊"
-				status = "confirmed"
-				u.logger.Info("Usuario confirmó la reserva (por análisis de sentimiento)",
-					zap.String("phone_number", phoneNumber),
-					zap.String("status", status),
-					zap.Int("sentiment_score", sentimentScore))
-			} else if sentimentScore < 0 {
-				// Sentimiento negativo: tratar como cancelación
-				responseMessage = "Hemos cancelado tu cita. Si deseas reagendarla, por favor contáctanos. ¡Gracias!"
-				status = "cancelled"
-				u.logger.Info("Usuario canceló la reserva (por análisis de sentimiento)",
-					zap.String("phone_number", phoneNumber),
-					zap.String("status", status),
-					zap.Int("sentiment_score", sentimentScore))
-			} else {
-				// Sentimiento neutro: respuesta no reconocida
-				responseMessage = "No entendimos tu respuesta. Por favor, responde 'Sí' para confirmar o 'No' para cancelar tu cita."
-				status = "unknown"
-				u.logger.Warn("Usuario envió respuesta no reconocida para la reserva",
-					zap.String("phone_number", phoneNumber),
-					zap.String("message", messageBody),
-					zap.String("status", status))
-			}
-		} else {
-			// Si no se pudo cargar el modelo, usar respuesta por defecto
-			responseMessage = "No entendimos tu respuesta. Por favor, responde 'Sí' para confirmar o 'No' para cancelar tu cita."
-			status = "unknown"
-			u.logger.Warn("Usuario envió respuesta no reconocida para la reserva",
+		u.resolveResponse(ctx, log, phoneNumber, intent.BookingID, status)
+
+		return &MessageResponse{
+			PhoneNumber: phoneNumber,
+			Status:      status,
+			BookingID:   intent.BookingID,
+			Metadata:    intent.Metadata,
+			Locale:      locale,
+			TraceID:     intent.TraceID,
+			Deferred:    true,
+		}, nil
+	}
+
+	// Unrecognized messages get the configured fallback, or no reply at all
+	if status == "unknown" {
+		fallback, ok := u.fallbackReply(ctx, locale, booking)
+		// Attachments get their own acknowledgment instead of the text fallback
+		if media != nil && !u.mediaReply.Disabled {
+			fallback, ok = u.mediaAck(ctx, locale, booking), true
+		} else if media != nil && messageBody == "" {
+			ok = false
+		}
+		if !ok {
+			log.Info("Respuesta no reconocida, respuesta de respaldo deshabilitada",
 				zap.String("phone_number", phoneNumber),
-				zap.String("message", messageBody),
-				zap.String("status", status))
+				zap.Bool("pending_booking", booking != nil))
+
+			return &MessageResponse{
+				PhoneNumber: phoneNumber,
+				Status:      status,
+				BookingID:   intent.BookingID,
+				Metadata:    intent.Metadata,
+				Locale:      locale,
+				TraceID:     intent.TraceID,
+			}, nil
 		}
+		responseMessage = fallback
 	}
 
 	// Send response message back to the user
-	message := &waE2E.Message{
-		Conversation: proto.String(responseMessage),
-	}
+	responseMessage = u.render(responseMessage, nil)
 
 	// Log before sending message
-	u.logger.Info("Intentando enviar respuesta al usuario",
+	log.Info("Intentando enviar respuesta al usuario",
 		zap.String("phone_number", phoneNumber),
 		zap.String("message", responseMessage),
 		zap.String("status", status))
 
 	// Send the message with context
-	ctx := context.Background()
-	resp, err := u.client.Send(ctx, jid, message)
+	sendOptions := []whatsapp.SendOption{whatsapp.WithPriority(whatsapp.PriorityHigh), whatsapp.WithTyping()}
+	if intent.TraceID != "" {
+		sendOptions = append(sendOptions,
+			whatsapp.WithMetadata("booking_id", intent.BookingID),
+			whatsapp.WithMetadata("trace_id", intent.TraceID))
+	}
+	resp, err := u.sendReply(ctx, inbound.client, jid, responseMessage, inbound.original, sendOptions...)
 	if err != nil {
-		u.logger.Error("Failed to send response message", zap.Error(err))
+		log.Error("Failed to send response message", zap.Error(err))
 		return nil, fmt.Errorf("failed to send response message: %w", err)
 	}
 
 	// Log successful message sending
-	u.logger.Info("Respuesta enviada exitosamente",
+	log.Info("Respuesta enviada exitosamente",
 		zap.String("phone_number", phoneNumber),
 		zap.String("message_id", resp.ID),
 		zap.String("status", status))
 
+	// Notify the integrator once the booking was confirmed or cancelled
+	if u.callbackNotifier != nil && isBookingAnswer(status) {
+		if err := u.notify(ctx, log, u.callbackNotifier, BookingResponseEvent, intent); err != nil {
+			log.Error("Failed to post booking response callback", zap.Error(err))
+		}
+	}
+	u.resolveResponse(ctx, log, phoneNumber, intent.BookingID, status)
+
 	return &MessageResponse{
 		PhoneNumber: phoneNumber,
 		Message:     responseMessage,
 		Status:      status,
+		BookingID:   intent.BookingID,
+		Metadata:    intent.Metadata,
+		Locale:      locale,
+		TraceID:     intent.TraceID,
+	}, nil
+}
+
+// rejectTooLong asks the customer for a shorter message without processing it
+func (u *BookingUseCase) rejectTooLong(ctx context.Context, log logger.Logger, client *whatsapp.Client, jid types.JID, phoneNumber, messageBody string, booking *pendingBooking, original *whatsapp.WhatsAppMessage) (*MessageResponse, error) {
+	preview, _ := utils.Truncate(messageBody, u.maxBodyLen)
+	log.Warn("Inbound message exceeds the hard length limit",
+		zap.String("phone_number", phoneNumber),
+		zap.Int("length", utf8.RuneCountInString(messageBody)),
+		zap.String("preview", preview))
+
+	locale := u.resolveLocale(ctx, phoneNumber, preview)
+	responseMessage := u.render(u.replyText(ctx, locale, "too_long", booking), nil)
+	if _, err := u.sendReply(ctx, client, jid, responseMessage, original, whatsapp.WithPriority(whatsapp.PriorityHigh), whatsapp.WithTyping()); err != nil {
+		log.Error("Failed to send response message", zap.Error(err))
+		return nil, fmt.Errorf("failed to send response message: %w", err)
+	}
+
+	return &MessageResponse{
+		PhoneNumber: phoneNumber,
+		Message:     responseMessage,
+		Status:      "too_long",
+		Locale:      locale,
 	}, nil
 }
+
+// rejectLate tells the customer the confirmation they answered has expired,
+// without confirming or cancelling anything
+func (u *BookingUseCase) rejectLate(ctx context.Context, log logger.Logger, client *whatsapp.Client, jid types.JID, phoneNumber, locale string, booking pendingBooking, original *whatsapp.WhatsAppMessage) (*MessageResponse, error) {
+	log.Info("Respuesta a una confirmación expirada",
+		zap.String("phone_number", phoneNumber),
+		zap.String("expired_booking_id", booking.BookingID))
+
+	response := &MessageResponse{
+		PhoneNumber: phoneNumber,
+		Status:      "late",
+		BookingID:   booking.BookingID,
+		Metadata:    booking.Metadata,
+		Locale:      locale,
+		TraceID:     booking.TraceID,
+	}
+	u.recordTrace(ctx, TraceEvent{
+		TraceID:   response.TraceID,
+		BookingID: response.BookingID,
+		Stage:     TraceStageReply,
+		Status:    response.Status,
+	})
+
+	response.Message = u.render(u.replyText(ctx, locale, "late", &booking), nil)
+	if _, err := u.sendReply(ctx, client, jid, response.Message, original, whatsapp.WithPriority(whatsapp.PriorityHigh), whatsapp.WithTyping()); err != nil {
+		log.Error("Failed to send response message", zap.Error(err))
+		return nil, fmt.Errorf("failed to send response message: %w", err)
+	}
+	return response, nil
+}
+
+// sendReply sends a reply to an inbound message from the client of the
+// account that received it, quoting the original when it is known. A
+// message without its event, e.g. one deferred during maintenance, is
+// quoted by ID while it is still cached.
+func (u *BookingUseCase) sendReply(ctx context.Context, client *whatsapp.Client, jid types.JID, text string, original *whatsapp.WhatsAppMessage, options ...whatsapp.SendOption) (whatsmeow.SendResponse, error) {
+	switch {
+	case original == nil:
+		return client.SendText(ctx, jid, text, options...)
+	case original.Event != nil:
+		return client.SendReply(ctx, jid, text, original.Event, options...)
+	default:
+		return client.SendText(ctx, jid, text, append(options, whatsapp.WithQuote(original.ID))...)
+	}
+}
+
+// classify detects with sentiment analysis whether a text message that
+// invoked no command confirms or cancels the booking
+func (u *BookingUseCase) classify(log logger.Logger, phoneNumber, messageBody string) string {
+	// Normalize the message body for case-insensitive comparison
+	normalizedMessage := strings.ToLower(messageBody)
+
+	// Inicializar el modelo de análisis de sentimiento
+	model, err := sentiment.Restore()
+	if err != nil {
+		log.Error("Error al cargar el modelo de sentimiento", zap.Error(err))
+
+		// Si no se pudo cargar el modelo, usar respuesta por defecto
+		log.Warn("Usuario envió respuesta no reconocida para la reserva",
+			zap.String("phone_number", phoneNumber),
+			zap.String("message", messageBody),
+			zap.String("status", "unknown"))
+		return "unknown"
+	}
+
+	// Realizar análisis de sentimiento (usando inglés como base)
+	analysis := model.SentimentAnalysis(normalizedMessage, sentiment.English)
+	sentimentScore := int(analysis.Score)
+
+	log.Info("Análisis de sentimiento realizado",
+		zap.String("mensaje", normalizedMessage),
+		zap.Int("score", sentimentScore))
+
+	var status string
+	if sentimentScore > 0 {
+		// Sentimiento positivo: tratar como confirmación
+		status = "confirmed"
+		log.Info("Usuario confirmó la reserva (por análisis de sentimiento)",
+			zap.String("phone_number", phoneNumber),
+			zap.String("status", status),
+			zap.Int("sentiment_score", sentimentScore))
+	} else if sentimentScore < 0 {
+		// Sentimiento negativo: tratar como cancelación
+		status = "cancelled"
+		log.Info("Usuario canceló la reserva (por análisis de sentimiento)",
+			zap.String("phone_number", phoneNumber),
+			zap.String("status", status),
+			zap.Int("sentiment_score", sentimentScore))
+	} else {
+		// Sentimiento neutro: respuesta no reconocida
+		status = "unknown"
+		log.Warn("Usuario envió respuesta no reconocida para la reserva",
+			zap.String("phone_number", phoneNumber),
+			zap.String("message", messageBody),
+			zap.String("status", status))
+	}
+
+	return status
+}
+
+// isBookingAnswer reports whether the status confirms or cancels the booking
+func isBookingAnswer(status string) bool {
+	return status == "confirmed" || status == "cancelled"
+}
+
+// resolveResponse clears the pending booking once the customer confirmed or
+// cancelled, remembering cancellations so reminders are skipped;
+// unrecognized replies and other commands keep it waiting
+func (u *BookingUseCase) resolveResponse(ctx context.Context, log logger.Logger, phoneNumber, bookingID, status string) {
+	if !isBookingAnswer(status) {
+		return
+	}
+	if status == "cancelled" {
+		u.markCancelled(ctx, bookingID)
+	}
+	if err := u.resolvePendingBooking(ctx, phoneNumber); err != nil {
+		log.Warn("Failed to resolve pending booking", zap.Error(err))
+	}
+}
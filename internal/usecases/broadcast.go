@@ -0,0 +1,119 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/whatsapp"
+	"go.uber.org/zap"
+)
+
+// ErrNoSandbox is returned when a test broadcast is requested but no
+// sandbox numbers are configured
+var ErrNoSandbox = errors.New("no sandbox numbers configured")
+
+// Broadcast recipient statuses
+const (
+	BroadcastSent   = "sent"
+	BroadcastFailed = "failed"
+)
+
+// BroadcastRecipientResult is the outcome of a broadcast for one recipient
+type BroadcastRecipientResult struct {
+	To        string     `json:"to"`
+	Status    string     `json:"status"`
+	MessageID string     `json:"message_id,omitempty"`
+	Timestamp *time.Time `json:"timestamp,omitempty"`
+	Error     string     `json:"error,omitempty"`
+}
+
+// BroadcastResult is the outcome of a broadcast
+type BroadcastResult struct {
+	Template string `json:"template"`
+	// Sandbox reports that the broadcast only went to the sandbox numbers
+	Sandbox bool `json:"sandbox"`
+	// IgnoredRecipients counts the requested recipients a sandbox broadcast
+	// did not contact
+	IgnoredRecipients int                        `json:"ignored_recipients"`
+	Sent              int                        `json:"sent"`
+	Failed            int                        `json:"failed"`
+	Results           []BroadcastRecipientResult `json:"results"`
+}
+
+// BroadcastUseCase sends a template to many recipients as a bulk,
+// non-transactional send
+type BroadcastUseCase struct {
+	templates *TemplateUseCase
+	sandbox   []string
+	logger    logger.Logger
+}
+
+// NewBroadcastUseCase creates a new BroadcastUseCase. sandbox are the
+// normalized numbers that receive test broadcasts.
+func NewBroadcastUseCase(templates *TemplateUseCase, sandbox []string, logger logger.Logger) *BroadcastUseCase {
+	return &BroadcastUseCase{
+		templates: templates,
+		sandbox:   sandbox,
+		logger:    logger,
+	}
+}
+
+// SendTest sends the template only to the sandbox numbers, whatever the
+// requested recipients, so templates and pacing can be checked before a
+// real broadcast
+func (u *BroadcastUseCase) SendTest(ctx context.Context, name string, recipients []string, values map[string]string) (*BroadcastResult, error) {
+	if len(u.sandbox) == 0 {
+		return nil, ErrNoSandbox
+	}
+
+	u.logger.Info("Sending test broadcast to the sandbox numbers",
+		zap.String("template", name),
+		zap.Int("sandbox_numbers", len(u.sandbox)),
+		zap.Int("ignored_recipients", len(recipients)))
+
+	result, err := u.send(ctx, name, u.sandbox, values)
+	if err != nil {
+		return nil, err
+	}
+	result.Sandbox = true
+	result.IgnoredRecipients = len(recipients)
+	return result, nil
+}
+
+// send sends the template to each recipient in turn at low priority. A
+// template that cannot be rendered fails the whole broadcast; other errors
+// are reported per recipient.
+func (u *BroadcastUseCase) send(ctx context.Context, name string, recipients []string, values map[string]string) (*BroadcastResult, error) {
+	template, err := u.templates.repository.Latest(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := template.Render(values); err != nil {
+		return nil, err
+	}
+
+	ctx = whatsapp.ContextWithPriority(ctx, whatsapp.PriorityLow)
+	result := &BroadcastResult{Template: template.Name}
+	for _, to := range recipients {
+		sent, err := u.templates.Send(ctx, name, to, values)
+		if err != nil {
+			result.Failed++
+			result.Results = append(result.Results, BroadcastRecipientResult{
+				To:     to,
+				Status: BroadcastFailed,
+				Error:  err.Error(),
+			})
+			continue
+		}
+		result.Sent++
+		result.Results = append(result.Results, BroadcastRecipientResult{
+			To:        to,
+			Status:    BroadcastSent,
+			MessageID: sent.MessageID,
+			Timestamp: &sent.Timestamp,
+		})
+	}
+	return result, nil
+}
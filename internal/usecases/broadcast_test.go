@@ -0,0 +1,77 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/templates"
+)
+
+// newTestBroadcast creates a broadcast use case with a saved "promo"
+// template, sending to the given sandbox numbers
+func newTestBroadcast(t *testing.T, sandbox []string) (*BroadcastUseCase, *sentMessages) {
+	t.Helper()
+	client := newTestClient(t)
+	sent := captureSends(client)
+	templateUseCase := NewTemplateUseCase(templates.NewStoreRepository(newFakeStore()), client, logger.NewNop(), "CL")
+	if _, err := templateUseCase.Save(context.Background(), "promo", "Hola {{name}}, tenemos 20% de descuento"); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	return NewBroadcastUseCase(templateUseCase, sandbox, logger.NewNop()), sent
+}
+
+func TestTestBroadcastOnlyContactsSandbox(t *testing.T) {
+	sandbox := []string{"56961234501", "56961234502"}
+	u, sent := newTestBroadcast(t, sandbox)
+
+	recipients := []string{testPhone, "56961234568", "56961234569"}
+	result, err := u.SendTest(context.Background(), "promo", recipients, map[string]string{"name": "Ana"})
+	if err != nil {
+		t.Fatalf("SendTest() error = %v", err)
+	}
+	if !result.Sandbox || result.IgnoredRecipients != 3 || result.Sent != 2 || result.Failed != 0 {
+		t.Errorf("result = %+v, want 2 sandbox sends and 3 ignored recipients", result)
+	}
+
+	messages := sent.all()
+	if len(messages) != 2 {
+		t.Fatalf("sent %d messages, want one per sandbox number", len(messages))
+	}
+	for i, msg := range messages {
+		if msg.To.User != sandbox[i] {
+			t.Errorf("message %d sent to %s, want %s", i, msg.To.User, sandbox[i])
+		}
+		if result.Results[i].To != sandbox[i] || result.Results[i].Status != BroadcastSent || result.Results[i].MessageID == "" {
+			t.Errorf("result %d = %+v, want %s sent", i, result.Results[i], sandbox[i])
+		}
+	}
+	if got := sent.texts()[0]; got != "Hola Ana, tenemos 20% de descuento" {
+		t.Errorf("text = %q, want the template rendered", got)
+	}
+}
+
+func TestTestBroadcastErrors(t *testing.T) {
+	u, sent := newTestBroadcast(t, nil)
+	ctx := context.Background()
+
+	// Without a sandbox nothing is sent, not even to the given recipients
+	if _, err := u.SendTest(ctx, "promo", []string{testPhone}, map[string]string{"name": "Ana"}); !errors.Is(err, ErrNoSandbox) {
+		t.Errorf("SendTest() without a sandbox error = %v, want %v", err, ErrNoSandbox)
+	}
+	if got := len(sent.all()); got != 0 {
+		t.Errorf("sent %d messages without a sandbox, want none", got)
+	}
+
+	u, sent = newTestBroadcast(t, []string{"56961234501"})
+	if _, err := u.SendTest(ctx, "unknown", nil, nil); !errors.Is(err, templates.ErrNotFound) {
+		t.Errorf("SendTest() of an unknown template error = %v, want %v", err, templates.ErrNotFound)
+	}
+	if _, err := u.SendTest(ctx, "promo", nil, nil); !errors.Is(err, templates.ErrMissingVariable) {
+		t.Errorf("SendTest() without the variables error = %v, want %v", err, templates.ErrMissingVariable)
+	}
+	if got := len(sent.all()); got != 0 {
+		t.Errorf("failed broadcasts sent %d messages, want none", got)
+	}
+}
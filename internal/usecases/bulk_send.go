@@ -0,0 +1,137 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/utils"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/whatsapp"
+	"go.mau.fi/whatsmeow/types"
+	"go.uber.org/zap"
+)
+
+// ErrTooManyRecipients is returned when a bulk send exceeds the batch size
+var ErrTooManyRecipients = errors.New("too many recipients")
+
+// BulkSkipped is the status of a recipient repeated in a bulk send
+const BulkSkipped = "skipped"
+
+// Default bulk send limits, used unless WithBulkSend is given
+const (
+	defaultBulkMaxRecipients = 50
+	defaultBulkSendDelay     = 200 * time.Millisecond
+)
+
+// BulkRecipientResult is the outcome of a bulk send for one recipient
+type BulkRecipientResult struct {
+	Phone     string `json:"phone"`
+	Status    string `json:"status"`
+	MessageID string `json:"message_id,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// BulkResult is the outcome of a bulk send, with a result per recipient in
+// the requested order
+type BulkResult struct {
+	Sent    int                   `json:"sent"`
+	Failed  int                   `json:"failed"`
+	Skipped int                   `json:"skipped"`
+	Results []BulkRecipientResult `json:"results"`
+}
+
+// MessageUseCaseOption configures a MessageUseCase
+type MessageUseCaseOption func(*MessageUseCase)
+
+// WithBulkSend sets the most recipients of a bulk send and the pause between
+// its sends, which keeps large batches from tripping WhatsApp's rate limits
+func WithBulkSend(maxRecipients int, delay time.Duration) MessageUseCaseOption {
+	return func(u *MessageUseCase) {
+		u.bulkMaxRecipients = maxRecipients
+		u.bulkSendDelay = delay
+	}
+}
+
+// SendBulk sends the text to each recipient in turn at low priority, so
+// opted-out numbers are skipped like in broadcasts. A failed recipient does
+// not stop the batch; it is reported in its result. Numbers repeated in the
+// request are sent once.
+func (u *MessageUseCase) SendBulk(ctx context.Context, recipients []string, text string) (*BulkResult, error) {
+	if len(recipients) > u.bulkMaxRecipients {
+		return nil, fmt.Errorf("%w: at most %d allowed", ErrTooManyRecipients, u.bulkMaxRecipients)
+	}
+	if text == "" {
+		return nil, fmt.Errorf("%w: text is empty", ErrInvalidMessage)
+	}
+
+	u.logger.Info("Sending bulk message", zap.Int("recipients", len(recipients)))
+
+	ctx = whatsapp.ContextWithPriority(ctx, whatsapp.PriorityLow)
+	result := &BulkResult{Results: make([]BulkRecipientResult, 0, len(recipients))}
+	seen := make(map[string]bool, len(recipients))
+	sending := false
+	for _, recipient := range recipients {
+		phoneNumber, err := utils.NormalizePhone(recipient, u.phoneRegion)
+		if err != nil {
+			result.add(BulkRecipientResult{Phone: recipient, Status: BroadcastFailed, Error: fmt.Sprintf("%v: %v", ErrInvalidPhoneNumber, err)})
+			continue
+		}
+		if seen[phoneNumber] {
+			result.add(BulkRecipientResult{Phone: phoneNumber, Status: BulkSkipped, Error: "duplicate recipient"})
+			continue
+		}
+		seen[phoneNumber] = true
+
+		// Pause between sends, not before the first one
+		if sending && !u.pause(ctx) {
+			result.add(BulkRecipientResult{Phone: phoneNumber, Status: BroadcastFailed, Error: ctx.Err().Error()})
+			continue
+		}
+		sending = true
+
+		jid := types.NewJID(phoneNumber, types.DefaultUserServer)
+		sent, err := u.client.SendText(ctx, jid, text)
+		if err != nil {
+			u.logger.Warn("Bulk send failed for recipient", zap.String("phone_number", phoneNumber), zap.Error(err))
+			result.add(BulkRecipientResult{Phone: phoneNumber, Status: BroadcastFailed, Error: err.Error()})
+			continue
+		}
+		result.add(BulkRecipientResult{Phone: phoneNumber, Status: BroadcastSent, MessageID: sent.ID})
+	}
+
+	u.logger.Info("Bulk message sent",
+		zap.Int("sent", result.Sent),
+		zap.Int("failed", result.Failed),
+		zap.Int("skipped", result.Skipped))
+	return result, nil
+}
+
+// pause waits the delay between bulk sends, or returns false when the
+// context is done first
+func (u *MessageUseCase) pause(ctx context.Context) bool {
+	if u.bulkSendDelay <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(u.bulkSendDelay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// add appends a recipient result and counts it by status
+func (r *BulkResult) add(result BulkRecipientResult) {
+	switch result.Status {
+	case BroadcastSent:
+		r.Sent++
+	case BulkSkipped:
+		r.Skipped++
+	default:
+		r.Failed++
+	}
+	r.Results = append(r.Results, result)
+}
@@ -0,0 +1,154 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/whatsapp"
+)
+
+func TestSendBulkContinuesPastFailures(t *testing.T) {
+	client := newTestClient(t)
+	client.OnBeforeSend(func(msg *whatsapp.OutboundMessage) error {
+		if msg.To.User == "56961234502" {
+			return errors.New("recipient rejected")
+		}
+		return nil
+	})
+	sent := captureSends(client)
+	u := NewMessageUseCase(client, logger.NewNop(), "CL", WithBulkSend(10, 0))
+
+	recipients := []string{"56961234501", "56961234502", "123", "+56 9 6123 4501", "56961234503"}
+	result, err := u.SendBulk(context.Background(), recipients, "Hola")
+	if err != nil {
+		t.Fatalf("SendBulk() error = %v", err)
+	}
+
+	want := []BulkRecipientResult{
+		{Phone: "56961234501", Status: BroadcastSent},
+		{Phone: "56961234502", Status: BroadcastFailed},
+		{Phone: "123", Status: BroadcastFailed},
+		// The same number written another way is sent once
+		{Phone: "56961234501", Status: BulkSkipped},
+		{Phone: "56961234503", Status: BroadcastSent},
+	}
+	if len(result.Results) != len(want) {
+		t.Fatalf("Results = %+v, want one per recipient", result.Results)
+	}
+	for i, got := range result.Results {
+		if got.Phone != want[i].Phone || got.Status != want[i].Status {
+			t.Errorf("Results[%d] = %+v, want %s %s", i, got, want[i].Phone, want[i].Status)
+		}
+		if (got.Status == BroadcastSent) != (got.MessageID != "") {
+			t.Errorf("Results[%d] = %+v, want a message ID only when sent", i, got)
+		}
+		if (got.Status == BroadcastSent) == (got.Error != "") {
+			t.Errorf("Results[%d] = %+v, want an error only when not sent", i, got)
+		}
+	}
+	if result.Results[3].Error != "duplicate recipient" {
+		t.Errorf("duplicate result error = %q, want duplicate recipient", result.Results[3].Error)
+	}
+	if result.Sent != 2 || result.Failed != 2 || result.Skipped != 1 {
+		t.Errorf("Sent, Failed, Skipped = %d, %d, %d, want 2, 2, 1", result.Sent, result.Failed, result.Skipped)
+	}
+
+	// The failed recipient did not stop the batch, and sends are low priority
+	messages := sent.all()
+	if len(messages) != 2 || messages[1].To.User != "56961234503" {
+		t.Fatalf("sent %d messages, want the two that succeeded", len(messages))
+	}
+	for _, msg := range messages {
+		if msg.Priority != whatsapp.PriorityLow {
+			t.Errorf("sent to %s at priority %v, want low", msg.To.User, msg.Priority)
+		}
+	}
+}
+
+func TestSendBulkTooManyRecipients(t *testing.T) {
+	client := newTestClient(t)
+	sent := captureSends(client)
+	u := NewMessageUseCase(client, logger.NewNop(), "CL", WithBulkSend(2, 0))
+
+	_, err := u.SendBulk(context.Background(), []string{"56961234501", "56961234502", "56961234503"}, "Hola")
+	if !errors.Is(err, ErrTooManyRecipients) {
+		t.Fatalf("SendBulk() error = %v, want ErrTooManyRecipients", err)
+	}
+	if len(sent.all()) != 0 {
+		t.Error("an oversized batch was partly sent")
+	}
+}
+
+func TestSendBulkPausesBetweenSends(t *testing.T) {
+	client := newTestClient(t)
+	var mu sync.Mutex
+	var sentAt []time.Time
+	client.OnBeforeSend(func(*whatsapp.OutboundMessage) error {
+		mu.Lock()
+		defer mu.Unlock()
+		sentAt = append(sentAt, time.Now())
+		return nil
+	})
+	const delay = 50 * time.Millisecond
+	u := NewMessageUseCase(client, logger.NewNop(), "CL", WithBulkSend(10, delay))
+
+	// Skipped and invalid recipients do not wait
+	start := time.Now()
+	result, err := u.SendBulk(context.Background(), []string{"56961234501", "123", "56961234501", "56961234502", "56961234503"}, "Hola")
+	if err != nil || result.Sent != 3 {
+		t.Fatalf("SendBulk() = %+v, %v, want 3 sent", result, err)
+	}
+	if elapsed := time.Since(start); elapsed >= 4*delay {
+		t.Errorf("SendBulk() took %s, want only the two pauses between sends", elapsed)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if sentAt[0].Sub(start) >= delay {
+		t.Errorf("first send waited %s, want no pause before it", sentAt[0].Sub(start))
+	}
+	for i := 1; i < len(sentAt); i++ {
+		if gap := sentAt[i].Sub(sentAt[i-1]); gap < delay {
+			t.Errorf("send %d followed the previous one after %s, want at least %s", i, gap, delay)
+		}
+	}
+}
+
+func TestSendBulkCancelled(t *testing.T) {
+	client := newTestClient(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// Cancel while the batch waits after its first send
+	first := make(chan struct{})
+	var once sync.Once
+	client.OnBeforeSend(func(*whatsapp.OutboundMessage) error {
+		once.Do(func() { close(first) })
+		return nil
+	})
+	go func() {
+		<-first
+		cancel()
+	}()
+	sent := captureSends(client)
+	u := NewMessageUseCase(client, logger.NewNop(), "CL", WithBulkSend(10, time.Hour))
+
+	result, err := u.SendBulk(ctx, []string{"56961234501", "56961234502", "56961234503"}, "Hola")
+	if err != nil {
+		t.Fatalf("SendBulk() error = %v", err)
+	}
+	if result.Sent != 1 || result.Failed != 2 {
+		t.Fatalf("Sent, Failed = %d, %d, want the first sent and the rest failed", result.Sent, result.Failed)
+	}
+	for _, got := range result.Results[1:] {
+		if got.Status != BroadcastFailed || got.Error != context.Canceled.Error() {
+			t.Errorf("result %+v, want failed with %v", got, context.Canceled)
+		}
+	}
+	if len(sent.all()) != 1 {
+		t.Errorf("sent %d messages after the cancellation, want 1", len(sent.all()))
+	}
+}
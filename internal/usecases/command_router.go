@@ -0,0 +1,77 @@
+package usecases
+
+import (
+	"context"
+	"strings"
+)
+
+// CommandHandler handles an inbound text message that invoked a command
+type CommandHandler func(ctx context.Context, phoneNumber, messageBody string) (*MessageResponse, error)
+
+// CommandMatcher reports whether a message, in the conversation locale,
+// invokes a command
+type CommandMatcher func(locale, message string) bool
+
+// Command is a conversational command: the handler of the inbound messages
+// its matcher accepts
+type Command struct {
+	Name    string
+	Match   CommandMatcher
+	Handler CommandHandler
+}
+
+// CommandRouter dispatches inbound text messages to the first command that
+// matches them, or to the fallback when none does. Commands registered later
+// are checked first, so a new command is not shadowed by the broad keywords
+// of the built-in booking responses.
+type CommandRouter struct {
+	commands []Command
+	fallback CommandHandler
+}
+
+// NewCommandRouter creates a router that hands unmatched messages to fallback
+func NewCommandRouter(fallback CommandHandler) *CommandRouter {
+	return &CommandRouter{fallback: fallback}
+}
+
+// Register adds a command, checked before the commands already registered.
+// A command with the name of a registered one replaces it.
+func (r *CommandRouter) Register(command Command) {
+	commands := []Command{command}
+	for _, registered := range r.commands {
+		if registered.Name != command.Name {
+			commands = append(commands, registered)
+		}
+	}
+	r.commands = commands
+}
+
+// Match returns the command the message invokes
+func (r *CommandRouter) Match(locale, message string) (Command, bool) {
+	for _, command := range r.commands {
+		if command.Match(locale, message) {
+			return command, true
+		}
+	}
+	return Command{}, false
+}
+
+// Route handles the message with the command it invokes, or the fallback
+func (r *CommandRouter) Route(ctx context.Context, locale, phoneNumber, messageBody string) (*MessageResponse, error) {
+	if command, ok := r.Match(locale, messageBody); ok {
+		return command.Handler(ctx, phoneNumber, messageBody)
+	}
+	return r.fallback(ctx, phoneNumber, messageBody)
+}
+
+// Keywords matches messages that are exactly one of the words, ignoring case
+// and surrounding whitespace, in any locale
+func Keywords(words ...string) CommandMatcher {
+	keywords := make(map[string]bool, len(words))
+	for _, word := range words {
+		keywords[strings.ToLower(strings.TrimSpace(word))] = true
+	}
+	return func(_, message string) bool {
+		return keywords[strings.ToLower(strings.TrimSpace(message))]
+	}
+}
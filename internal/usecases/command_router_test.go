@@ -0,0 +1,105 @@
+package usecases
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+)
+
+// routedTo returns a command handler answering with its name, so tests can
+// tell which handler a message reached
+func routedTo(name string) CommandHandler {
+	return func(context.Context, string, string) (*MessageResponse, error) {
+		return &MessageResponse{Status: name}, nil
+	}
+}
+
+// route routes the message and returns the name of the handler it reached
+func route(t *testing.T, router *CommandRouter, message string) string {
+	t.Helper()
+	response, err := router.Route(context.Background(), "es", testPhone, message)
+	if err != nil {
+		t.Fatalf("Route(%q) error = %v", message, err)
+	}
+	return response.Status
+}
+
+func TestCommandRouterPrecedence(t *testing.T) {
+	router := NewCommandRouter(routedTo("fallback"))
+	router.Register(Command{Name: "booking", Match: Keywords("si", "estado"), Handler: routedTo("booking")})
+	router.Register(Command{Name: "status", Match: Keywords("estado"), Handler: routedTo("status")})
+
+	tests := []struct {
+		message string
+		want    string
+	}{
+		// The later registration wins where both match
+		{message: "estado", want: "status"},
+		// The earlier one still handles what only it matches
+		{message: "si", want: "booking"},
+		{message: "hola", want: "fallback"},
+	}
+	for _, tt := range tests {
+		if got := route(t, router, tt.message); got != tt.want {
+			t.Errorf("Route(%q) reached %s, want %s", tt.message, got, tt.want)
+		}
+	}
+}
+
+func TestCommandRouterReplacesSameName(t *testing.T) {
+	router := NewCommandRouter(routedTo("fallback"))
+	router.Register(Command{Name: "help", Match: Keywords("ayuda"), Handler: routedTo("old")})
+	router.Register(Command{Name: "other", Match: Keywords("otro"), Handler: routedTo("other")})
+	router.Register(Command{Name: "help", Match: Keywords("socorro"), Handler: routedTo("new")})
+
+	if len(router.commands) != 2 {
+		t.Fatalf("registered %d commands, want the replaced one dropped", len(router.commands))
+	}
+	if got := route(t, router, "socorro"); got != "new" {
+		t.Errorf("Route(socorro) reached %s, want new", got)
+	}
+	// The replaced matcher no longer applies
+	if got := route(t, router, "ayuda"); got != "fallback" {
+		t.Errorf("Route(ayuda) reached %s, want fallback", got)
+	}
+	if got := route(t, router, "otro"); got != "other" {
+		t.Errorf("Route(otro) reached %s, want other", got)
+	}
+}
+
+func TestHelpCommandKeywords(t *testing.T) {
+	router := NewBookingUseCase(nil, logger.NewNop()).commands
+
+	for _, message := range []string{"help", "AYUDA", " ajuda ", "menu", "Menú", "?"} {
+		command, ok := router.Match("es", message)
+		if !ok || command.Name != CommandHelp {
+			t.Errorf("Match(%q) = %q, %v, want help", message, command.Name, ok)
+		}
+	}
+	// Only the whole message invokes it
+	for _, message := range []string{"ayúdame", "necesito ayuda", "¿?"} {
+		if command, ok := router.Match("es", message); ok && command.Name == CommandHelp {
+			t.Errorf("Match(%q) = help, want no help command", message)
+		}
+	}
+}
+
+func TestCommandRouterFallback(t *testing.T) {
+	var got string
+	router := NewCommandRouter(func(_ context.Context, phoneNumber, messageBody string) (*MessageResponse, error) {
+		got = phoneNumber + ":" + messageBody
+		return &MessageResponse{Status: "fallback"}, nil
+	})
+	router.Register(Command{Name: "help", Match: Keywords("ayuda"), Handler: routedTo("help")})
+
+	if status := route(t, router, "quiero cambiar la hora"); status != "fallback" {
+		t.Errorf("Route() reached %s, want fallback", status)
+	}
+	if want := testPhone + ":quiero cambiar la hora"; got != want {
+		t.Errorf("fallback got %q, want %q", got, want)
+	}
+	if _, ok := router.Match("es", "quiero cambiar la hora"); ok {
+		t.Error("Match() matched text no command accepts")
+	}
+}
@@ -0,0 +1,314 @@
+package usecases
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/store"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/utils"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/whatsapp"
+	"go.mau.fi/whatsmeow/types"
+	"go.uber.org/zap"
+)
+
+// consentKeyPrefix is the state store key prefix for the opt-out record of a number
+const consentKeyPrefix = "whatsapp:consent:"
+
+// ErrOptedOut is returned when a message is blocked because the recipient
+// opted out or is still in the cooldown after opting out
+var ErrOptedOut = errors.New("recipient opted out")
+
+// Consent changes detected in an inbound message
+const (
+	ConsentOptedOut = "opted_out"
+	ConsentOptedIn  = "opted_in"
+)
+
+// consentRecord is the opt-out state of a number kept in the state store
+type consentRecord struct {
+	OptedOut      bool       `json:"opted_out"`
+	OptedOutAt    time.Time  `json:"opted_out_at"`
+	OptedInAt     *time.Time `json:"opted_in_at,omitempty"`
+	CooldownUntil time.Time  `json:"cooldown_until"`
+}
+
+// Consent is the messaging consent of a number
+type Consent struct {
+	PhoneNumber   string     `json:"phone_number"`
+	OptedOut      bool       `json:"opted_out"`
+	OptedOutAt    *time.Time `json:"opted_out_at,omitempty"`
+	OptedInAt     *time.Time `json:"opted_in_at,omitempty"`
+	CooldownUntil *time.Time `json:"cooldown_until,omitempty"`
+	InCooldown    bool       `json:"in_cooldown"`
+	// MarketingAllowed reports whether non-transactional messages may be sent
+	MarketingAllowed bool `json:"marketing_allowed"`
+	// TransactionalAllowed reports whether transactional messages such as
+	// booking confirmations may be sent; direct replies always are
+	TransactionalAllowed bool `json:"transactional_allowed"`
+}
+
+// ConsentUseCase records opt-outs (STOP) and opt-ins (START) and blocks
+// non-transactional messages to numbers that opted out, and during a
+// cooldown after the opt-out even if they opted back in. Messages sent at
+// whatsapp.PriorityLow (bulk and marketing) are non-transactional; replies
+// to the customer (whatsapp.PriorityHigh) are always allowed.
+type ConsentUseCase struct {
+	client             *whatsapp.Client
+	store              store.Store
+	logger             logger.Logger
+	cooldown           time.Duration
+	allowTransactional bool
+	optOutKeywords     map[string]bool
+	optInKeywords      map[string]bool
+	phoneRegion        string
+	defaultLocale      string
+}
+
+// NewConsentUseCase creates a new ConsentUseCase. Keywords match the whole
+// message, case-insensitively. allowTransactional keeps transactional
+// messages such as confirmations flowing to numbers that opted out.
+func NewConsentUseCase(client *whatsapp.Client, stateStore store.Store, logger logger.Logger, cooldown time.Duration, allowTransactional bool, optOutKeywords, optInKeywords []string, phoneRegion, defaultLocale string) *ConsentUseCase {
+	u := &ConsentUseCase{
+		client:             client,
+		store:              stateStore,
+		logger:             logger,
+		cooldown:           cooldown,
+		allowTransactional: allowTransactional,
+		optOutKeywords:     make(map[string]bool),
+		optInKeywords:      make(map[string]bool),
+		phoneRegion:        phoneRegion,
+		defaultLocale:      defaultLocale,
+	}
+	for _, keyword := range optOutKeywords {
+		u.optOutKeywords[normalizeKeyword(keyword)] = true
+	}
+	for _, keyword := range optInKeywords {
+		u.optInKeywords[normalizeKeyword(keyword)] = true
+	}
+	return u
+}
+
+// Guard enforces the consent of the recipient on every message the client
+// sends to a number
+func (u *ConsentUseCase) Guard(client *whatsapp.Client) {
+	client.OnBeforeSend(func(msg *whatsapp.OutboundMessage) error {
+		if msg.To.Server != types.DefaultUserServer {
+			return nil
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		return u.Allow(ctx, msg.To.User, msg.Priority)
+	})
+}
+
+// HandleInbound records an opt-out or opt-in keyword sent by the number and
+// acknowledges it. It returns nil when the message is not a keyword and must
+// be processed normally.
+func (u *ConsentUseCase) HandleInbound(ctx context.Context, phoneNumber, body string) (*MessageResponse, error) {
+	change, err := u.ApplyKeyword(ctx, phoneNumber, body)
+	if change == "" || err != nil {
+		return nil, err
+	}
+
+	locale := u.locale(ctx, phoneNumber)
+	text := reply(locale, change)
+	jid := types.NewJID(phoneNumber, types.DefaultUserServer)
+	if _, err := u.client.SendText(ctx, jid, text, whatsapp.WithPriority(whatsapp.PriorityHigh), whatsapp.WithTyping()); err != nil {
+		u.logger.Warn("Failed to acknowledge consent change",
+			zap.String("phone_number", phoneNumber),
+			zap.String("change", change),
+			zap.Error(err))
+	}
+
+	return &MessageResponse{
+		PhoneNumber: phoneNumber,
+		Message:     text,
+		Status:      change,
+		Locale:      locale,
+	}, nil
+}
+
+// ApplyKeyword records an opt-out or opt-in keyword sent by the number
+// without acknowledging it, and returns the consent change. It returns ""
+// when the message is not a keyword.
+func (u *ConsentUseCase) ApplyKeyword(ctx context.Context, phoneNumber, body string) (string, error) {
+	keyword := normalizeKeyword(body)
+	switch {
+	case u.optOutKeywords[keyword]:
+		return ConsentOptedOut, u.OptOut(ctx, phoneNumber, time.Now())
+	case u.optInKeywords[keyword]:
+		return ConsentOptedIn, u.OptIn(ctx, phoneNumber, time.Now())
+	default:
+		return "", nil
+	}
+}
+
+// OptOut records that the number opted out and starts its cooldown
+func (u *ConsentUseCase) OptOut(ctx context.Context, phoneNumber string, at time.Time) error {
+	record := consentRecord{
+		OptedOut:      true,
+		OptedOutAt:    at,
+		CooldownUntil: at.Add(u.cooldown),
+	}
+	if err := u.save(ctx, phoneNumber, record); err != nil {
+		return err
+	}
+	u.logger.Info("Number opted out",
+		zap.String("phone_number", phoneNumber),
+		zap.Time("cooldown_until", record.CooldownUntil))
+	return nil
+}
+
+// OptIn records that the number opted back in. Marketing stays blocked
+// until the cooldown of the last opt-out ends.
+func (u *ConsentUseCase) OptIn(ctx context.Context, phoneNumber string, at time.Time) error {
+	record, err := u.get(ctx, phoneNumber)
+	if err != nil {
+		return err
+	}
+	if record == nil {
+		return nil
+	}
+
+	record.OptedOut = false
+	record.OptedInAt = &at
+	if err := u.save(ctx, phoneNumber, *record); err != nil {
+		return err
+	}
+	u.logger.Info("Number opted back in",
+		zap.String("phone_number", phoneNumber),
+		zap.Time("cooldown_until", record.CooldownUntil))
+	return nil
+}
+
+// Status returns the consent of a number
+func (u *ConsentUseCase) Status(ctx context.Context, number string) (*Consent, error) {
+	phoneNumber, err := utils.NormalizePhone(number, u.phoneRegion)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPhoneNumber, err)
+	}
+
+	record, err := u.get(ctx, phoneNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	consent := &Consent{
+		PhoneNumber:          phoneNumber,
+		MarketingAllowed:     true,
+		TransactionalAllowed: true,
+	}
+	if record == nil {
+		return consent, nil
+	}
+
+	restricted, inCooldown := record.restricted(time.Now())
+	consent.OptedOut = record.OptedOut
+	consent.OptedOutAt = &record.OptedOutAt
+	consent.OptedInAt = record.OptedInAt
+	consent.CooldownUntil = &record.CooldownUntil
+	consent.InCooldown = inCooldown
+	consent.MarketingAllowed = !restricted
+	consent.TransactionalAllowed = !restricted || u.allowTransactional
+	return consent, nil
+}
+
+// Allow returns ErrOptedOut when a message of the given priority must not be
+// sent to the number. Marketing fails closed when the state store is down.
+func (u *ConsentUseCase) Allow(ctx context.Context, phoneNumber string, priority whatsapp.Priority) error {
+	if priority == whatsapp.PriorityHigh {
+		return nil
+	}
+
+	record, err := u.get(ctx, phoneNumber)
+	if err != nil {
+		if priority == whatsapp.PriorityLow {
+			return fmt.Errorf("%w: consent unavailable: %v", ErrOptedOut, err)
+		}
+		u.logger.Warn("Failed to check consent, sending anyway",
+			zap.String("phone_number", phoneNumber),
+			zap.Error(err))
+		return nil
+	}
+	if record == nil {
+		return nil
+	}
+
+	restricted, _ := record.restricted(time.Now())
+	if !restricted || (priority == whatsapp.PriorityNormal && u.allowTransactional) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrOptedOut, phoneNumber)
+}
+
+// restricted reports whether non-transactional messages are blocked, and
+// whether the cooldown is still running
+func (r consentRecord) restricted(now time.Time) (bool, bool) {
+	inCooldown := now.Before(r.CooldownUntil)
+	return r.OptedOut || inCooldown, inCooldown
+}
+
+// Clear forgets the opt-out of a number, cooldown included
+func (u *ConsentUseCase) Clear(ctx context.Context, phoneNumber string) error {
+	if err := u.store.Delete(ctx, consentKeyPrefix+phoneNumber); err != nil {
+		return fmt.Errorf("failed to clear consent: %w", err)
+	}
+	u.logger.Info("Consent cleared", zap.String("phone_number", phoneNumber))
+	return nil
+}
+
+// get returns the consent record of a number, or nil when it never opted out
+func (u *ConsentUseCase) get(ctx context.Context, phoneNumber string) (*consentRecord, error) {
+	value, err := u.store.Get(ctx, consentKeyPrefix+phoneNumber)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var record consentRecord
+	if err := json.Unmarshal([]byte(value), &record); err != nil {
+		return nil, fmt.Errorf("failed to decode consent: %w", err)
+	}
+	return &record, nil
+}
+
+// save stores the consent record of a number without expiration
+func (u *ConsentUseCase) save(ctx context.Context, phoneNumber string, record consentRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode consent: %w", err)
+	}
+	if err := u.store.Set(ctx, consentKeyPrefix+phoneNumber, string(data), 0); err != nil {
+		return fmt.Errorf("failed to save consent: %w", err)
+	}
+	return nil
+}
+
+// WithConsent records the opt-out and opt-in keywords received instead of
+// processing them as booking responses
+func WithConsent(consent *ConsentUseCase) BookingUseCaseOption {
+	return func(u *BookingUseCase) {
+		u.consent = consent
+	}
+}
+
+// locale returns the remembered locale of the conversation or the default
+func (u *ConsentUseCase) locale(ctx context.Context, phoneNumber string) string {
+	if locale, err := u.store.Get(ctx, localeKeyPrefix+phoneNumber); err == nil {
+		return locale
+	}
+	return u.defaultLocale
+}
+
+// normalizeKeyword lowercases a keyword and strips surrounding spaces and
+// punctuation
+func normalizeKeyword(text string) string {
+	return strings.Trim(strings.ToLower(text), " \t\n.!¡")
+}
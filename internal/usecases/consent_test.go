@@ -0,0 +1,159 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/whatsapp"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// newGuardedConsent creates a consent use case with a day of cooldown
+// guarding the sends of a dry-run client
+func newGuardedConsent(t *testing.T, allowTransactional bool) (*ConsentUseCase, *whatsapp.Client, *fakeStore) {
+	t.Helper()
+	client := newTestClient(t)
+	stateStore := newFakeStore()
+	u := NewConsentUseCase(client, stateStore, logger.NewNop(), 24*time.Hour, allowTransactional,
+		[]string{"STOP", "baja"}, []string{"START"}, "CL", "es")
+	u.Guard(client)
+	return u, client, stateStore
+}
+
+// sendWithPriority sends a text to the test phone at the given priority
+func sendWithPriority(client *whatsapp.Client, priority whatsapp.Priority) error {
+	_, err := client.SendText(context.Background(), types.NewJID(testPhone, types.DefaultUserServer), "Hola", whatsapp.WithPriority(priority))
+	return err
+}
+
+func TestConsentCooldown(t *testing.T) {
+	u, client, _ := newGuardedConsent(t, true)
+	sent := captureSends(client)
+	ctx := context.Background()
+
+	// The opt-out keyword is acknowledged, whatever its case and punctuation
+	response, err := u.HandleInbound(ctx, testPhone, " Stop! ")
+	if err != nil || response == nil || response.Status != ConsentOptedOut {
+		t.Fatalf("HandleInbound(STOP) = %+v, %v, want opted out", response, err)
+	}
+	if texts := sent.texts(); len(texts) != 1 || texts[0] != response.Message {
+		t.Errorf("sent %q, want the acknowledgement", texts)
+	}
+
+	// Marketing is blocked; transactional messages and replies still flow
+	if err := sendWithPriority(client, whatsapp.PriorityLow); !errors.Is(err, ErrOptedOut) {
+		t.Errorf("marketing send error = %v, want %v", err, ErrOptedOut)
+	}
+	if err := sendWithPriority(client, whatsapp.PriorityNormal); err != nil {
+		t.Errorf("transactional send error = %v", err)
+	}
+	if err := sendWithPriority(client, whatsapp.PriorityHigh); err != nil {
+		t.Errorf("reply send error = %v", err)
+	}
+
+	// Opting back in does not end the cooldown
+	if response, err := u.HandleInbound(ctx, testPhone, "start"); err != nil || response.Status != ConsentOptedIn {
+		t.Fatalf("HandleInbound(START) = %+v, %v, want opted in", response, err)
+	}
+	if err := sendWithPriority(client, whatsapp.PriorityLow); !errors.Is(err, ErrOptedOut) {
+		t.Errorf("marketing send during the cooldown error = %v, want %v", err, ErrOptedOut)
+	}
+	consent, err := u.Status(ctx, "+56 9 6123 4567")
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	if consent.PhoneNumber != testPhone || consent.OptedOut || !consent.InCooldown || consent.MarketingAllowed || !consent.TransactionalAllowed {
+		t.Errorf("consent = %+v, want opted in but in cooldown", consent)
+	}
+
+	// Once the cooldown is over, marketing is allowed again after opting in
+	if err := u.OptOut(ctx, testPhone, time.Now().Add(-25*time.Hour)); err != nil {
+		t.Fatalf("OptOut() error = %v", err)
+	}
+	if err := sendWithPriority(client, whatsapp.PriorityLow); !errors.Is(err, ErrOptedOut) {
+		t.Errorf("marketing send while opted out error = %v, want %v", err, ErrOptedOut)
+	}
+	if err := u.OptIn(ctx, testPhone, time.Now()); err != nil {
+		t.Fatalf("OptIn() error = %v", err)
+	}
+	if err := sendWithPriority(client, whatsapp.PriorityLow); err != nil {
+		t.Errorf("marketing send after the cooldown error = %v", err)
+	}
+
+	// Numbers that never opted out are not restricted
+	if consent, err := u.Status(ctx, "56961234568"); err != nil || !consent.MarketingAllowed || consent.OptedOutAt != nil {
+		t.Errorf("Status() of a new number = %+v, %v, want marketing allowed", consent, err)
+	}
+	if response, err := u.HandleInbound(ctx, testPhone, "stop please"); response != nil || err != nil {
+		t.Errorf("HandleInbound() of a regular message = %+v, %v, want nil", response, err)
+	}
+}
+
+func TestConsentBlocksTransactional(t *testing.T) {
+	u, client, stateStore := newGuardedConsent(t, false)
+	ctx := context.Background()
+
+	if err := u.OptOut(ctx, testPhone, time.Now()); err != nil {
+		t.Fatalf("OptOut() error = %v", err)
+	}
+	if err := sendWithPriority(client, whatsapp.PriorityNormal); !errors.Is(err, ErrOptedOut) {
+		t.Errorf("transactional send error = %v, want %v", err, ErrOptedOut)
+	}
+	if err := sendWithPriority(client, whatsapp.PriorityHigh); err != nil {
+		t.Errorf("reply send error = %v", err)
+	}
+
+	// Without the state store marketing fails closed and the rest is sent
+	stateStore.fail(errors.New("redis down"))
+	if err := sendWithPriority(client, whatsapp.PriorityLow); !errors.Is(err, ErrOptedOut) {
+		t.Errorf("marketing send without consent error = %v, want %v", err, ErrOptedOut)
+	}
+	if err := sendWithPriority(client, whatsapp.PriorityNormal); err != nil {
+		t.Errorf("transactional send without consent error = %v", err)
+	}
+}
+
+func TestBookingHandlesOptOut(t *testing.T) {
+	client := newTestClient(t)
+	stateStore := newFakeStore()
+	consent := NewConsentUseCase(client, stateStore, logger.NewNop(), time.Hour, true, []string{"STOP"}, []string{"START"}, "CL", "es")
+	u := NewBookingUseCase(client, logger.NewNop(), WithBookingStore(stateStore), WithConsent(consent))
+	ctx := context.Background()
+
+	if _, err := u.SendConfirmationMessage(ctx, testBooking("booking-1")); err != nil {
+		t.Fatalf("SendConfirmationMessage() error = %v", err)
+	}
+	response, err := u.ProcessIncomingResponse(ctx, testPhone, "STOP", "")
+	if err != nil || response.Status != ConsentOptedOut {
+		t.Fatalf("ProcessIncomingResponse(STOP) = %+v, %v, want opted out", response, err)
+	}
+	// The keyword is not taken as an answer to the pending booking
+	if booking, _ := u.pendingBookingFor(ctx, testPhone); booking == nil {
+		t.Error("opting out resolved the pending booking")
+	}
+}
+
+func TestBookingAppliesSupersededOptOut(t *testing.T) {
+	client := newTestClient(t)
+	sent := captureSends(client)
+	stateStore := newFakeStore()
+	consent := NewConsentUseCase(client, stateStore, logger.NewNop(), time.Hour, true, []string{"STOP"}, []string{"START"}, "CL", "es")
+	u := NewBookingUseCase(client, logger.NewNop(), WithBookingStore(stateStore), WithConsent(consent))
+	ctx := context.Background()
+
+	// A STOP followed by another message during an offline sync is applied
+	// without being acknowledged
+	msg := &whatsapp.WhatsAppMessage{ID: "a1", From: testPhone, Body: "STOP", Superseded: true}
+	if response, err := u.ProcessIncomingMessage(ctx, msg); response != nil || err != nil {
+		t.Fatalf("ProcessIncomingMessage(superseded STOP) = %+v, %v, want nil", response, err)
+	}
+	if texts := sent.texts(); len(texts) != 0 {
+		t.Errorf("sent %q, want no reply to a superseded message", texts)
+	}
+	if status, err := consent.Status(ctx, testPhone); err != nil || !status.OptedOut {
+		t.Errorf("Status() = %+v, %v, want opted out", status, err)
+	}
+}
@@ -0,0 +1,60 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/utils"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/whatsapp"
+)
+
+// maxContactChecks is the most numbers checked in one request
+const maxContactChecks = 50
+
+// ContactUseCase handles contact lookups
+type ContactUseCase struct {
+	client      *whatsapp.Client
+	logger      logger.Logger
+	phoneRegion string
+}
+
+// NewContactUseCase creates a new ContactUseCase
+func NewContactUseCase(client *whatsapp.Client, logger logger.Logger, phoneRegion string) *ContactUseCase {
+	return &ContactUseCase{
+		client:      client,
+		logger:      logger,
+		phoneRegion: phoneRegion,
+	}
+}
+
+// Check returns whether a number is registered on WhatsApp and its JID
+func (u *ContactUseCase) Check(ctx context.Context, number string) (*whatsapp.Contact, error) {
+	phoneNumber, err := utils.NormalizePhone(number, u.phoneRegion)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPhoneNumber, err)
+	}
+	return u.client.LookupContact(ctx, phoneNumber)
+}
+
+// CheckMany checks several numbers on WhatsApp in one query, without the
+// cache, so numbers that are not reachable can be skipped or flagged before
+// sending. Contacts are returned in the order of the numbers.
+func (u *ContactUseCase) CheckMany(ctx context.Context, numbers []string) ([]whatsapp.Contact, error) {
+	if len(numbers) == 0 {
+		return nil, fmt.Errorf("%w: no numbers given", ErrInvalidPhoneNumber)
+	}
+	if len(numbers) > maxContactChecks {
+		return nil, fmt.Errorf("%w: at most %d numbers per check", ErrTooManyRecipients, maxContactChecks)
+	}
+
+	phones := make([]string, len(numbers))
+	for i, number := range numbers {
+		phoneNumber, err := utils.NormalizePhone(number, u.phoneRegion)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPhoneNumber, err)
+		}
+		phones[i] = phoneNumber
+	}
+	return u.client.CheckOnWhatsApp(ctx, phones)
+}
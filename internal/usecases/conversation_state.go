@@ -0,0 +1,187 @@
+package usecases
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/store"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// TraceStageCleared is recorded when support clears a pending booking
+const TraceStageCleared = "cleared"
+
+// Conversation state cleared by ClearConversationState
+const (
+	StatePendingBooking = "pending_booking"
+	StateExpiredBooking = "expired_booking"
+	StateLocale         = "locale"
+	StateOptOut         = "opt_out"
+)
+
+// ConversationBooking is a booking kept in the state of a conversation
+type ConversationBooking struct {
+	BookingID string            `json:"booking_id"`
+	TraceID   string            `json:"trace_id,omitempty"`
+	AccountID string            `json:"account_id,omitempty"`
+	MessageID string            `json:"message_id,omitempty"`
+	SentAt    time.Time         `json:"sent_at"`
+	ExpiresAt *time.Time        `json:"expires_at,omitempty"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+}
+
+// ConversationState is the state stored for the conversation with a number
+type ConversationState struct {
+	PhoneNumber string `json:"phone_number"`
+	// PendingBooking awaits the customer's response and receives every
+	// confirmation or cancellation they send
+	PendingBooking *ConversationBooking `json:"pending_booking,omitempty"`
+	// ExpiredBooking is the last booking that expired, kept to reject late
+	// button responses
+	ExpiredBooking *ConversationBooking `json:"expired_booking,omitempty"`
+	// Locale is the remembered language of the replies
+	Locale string `json:"locale,omitempty"`
+	// Consent is the opt-out state, when consent is handled
+	Consent *Consent `json:"consent,omitempty"`
+}
+
+// ConversationState returns the state stored for the conversation with a
+// number
+func (u *BookingUseCase) ConversationState(ctx context.Context, number string) (*ConversationState, error) {
+	phoneNumber, err := utils.NormalizePhone(number, u.phoneRegion)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPhoneNumber, err)
+	}
+
+	state := &ConversationState{PhoneNumber: phoneNumber}
+	if u.store == nil {
+		return state, nil
+	}
+
+	pending, err := u.pendingBookingFor(ctx, phoneNumber)
+	if err != nil {
+		return nil, err
+	}
+	state.PendingBooking = conversationBooking(pending)
+
+	expired, err := u.storedBooking(ctx, expiredBookingKeyPrefix+phoneNumber)
+	if err != nil {
+		return nil, err
+	}
+	state.ExpiredBooking = conversationBooking(expired)
+
+	locale, err := u.store.Get(ctx, localeKeyPrefix+phoneNumber)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		return nil, err
+	}
+	state.Locale = locale
+
+	if u.consent != nil {
+		if state.Consent, err = u.consent.Status(ctx, phoneNumber); err != nil {
+			return nil, err
+		}
+	}
+	return state, nil
+}
+
+// ClearConversationState clears the bookings and locale stored for the
+// conversation with a number, and its opt-out when optOut is set, so a stuck
+// customer can book again. It returns the state found before clearing and
+// what was cleared; the clear is logged with the actor and recorded in the
+// booking trace.
+func (u *BookingUseCase) ClearConversationState(ctx context.Context, number, actor string, optOut bool) (*ConversationState, []string, error) {
+	state, err := u.ConversationState(ctx, number)
+	if err != nil {
+		return nil, nil, err
+	}
+	phoneNumber := state.PhoneNumber
+	cleared := []string{}
+	if u.store == nil {
+		return state, cleared, nil
+	}
+
+	if booking := state.PendingBooking; booking != nil {
+		if err := u.store.Delete(ctx, pendingBookingKeyPrefix+phoneNumber); err != nil {
+			return nil, nil, fmt.Errorf("failed to clear pending booking: %w", err)
+		}
+		// The index may already point to a newer send of the same booking ID
+		if indexed, err := u.store.Get(ctx, bookingIndexKeyPrefix+booking.BookingID); err == nil && indexed == phoneNumber {
+			if err := u.store.Delete(ctx, bookingIndexKeyPrefix+booking.BookingID); err != nil {
+				return nil, nil, fmt.Errorf("failed to clear booking index: %w", err)
+			}
+		}
+		u.recordTrace(ctx, TraceEvent{
+			TraceID:   booking.TraceID,
+			BookingID: booking.BookingID,
+			Stage:     TraceStageCleared,
+			Detail:    "cleared by " + actor,
+		})
+		cleared = append(cleared, StatePendingBooking)
+	}
+	if state.ExpiredBooking != nil {
+		if err := u.store.Delete(ctx, expiredBookingKeyPrefix+phoneNumber); err != nil {
+			return nil, nil, fmt.Errorf("failed to clear expired booking: %w", err)
+		}
+		cleared = append(cleared, StateExpiredBooking)
+	}
+	if state.Locale != "" {
+		if err := u.store.Delete(ctx, localeKeyPrefix+phoneNumber); err != nil {
+			return nil, nil, fmt.Errorf("failed to clear locale: %w", err)
+		}
+		cleared = append(cleared, StateLocale)
+	}
+	if optOut && state.Consent != nil && state.Consent.OptedOutAt != nil {
+		if err := u.consent.Clear(ctx, phoneNumber); err != nil {
+			return nil, nil, err
+		}
+		cleared = append(cleared, StateOptOut)
+	}
+
+	u.logger.Warn("Conversation state cleared",
+		zap.String("phone_number", phoneNumber),
+		zap.String("actor", actor),
+		zap.Strings("cleared", cleared))
+	return state, cleared, nil
+}
+
+// storedBooking decodes the booking kept under a key, or returns nil if
+// there is none
+func (u *BookingUseCase) storedBooking(ctx context.Context, key string) (*pendingBooking, error) {
+	value, err := u.store.Get(ctx, key)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var booking pendingBooking
+	if err := json.Unmarshal([]byte(value), &booking); err != nil {
+		return nil, fmt.Errorf("failed to decode booking: %w", err)
+	}
+	return &booking, nil
+}
+
+// conversationBooking exposes the stored fields of a booking
+func conversationBooking(booking *pendingBooking) *ConversationBooking {
+	if booking == nil {
+		return nil
+	}
+	exposed := &ConversationBooking{
+		BookingID: booking.BookingID,
+		TraceID:   booking.TraceID,
+		AccountID: booking.AccountID,
+		MessageID: booking.MessageID,
+		SentAt:    booking.SentAt,
+		Metadata:  booking.Metadata,
+	}
+	if !booking.ExpiresAt.IsZero() {
+		expiresAt := booking.ExpiresAt
+		exposed.ExpiresAt = &expiresAt
+	}
+	return exposed
+}
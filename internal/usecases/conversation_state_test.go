@@ -0,0 +1,164 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/store"
+)
+
+func TestConversationState(t *testing.T) {
+	client := newTestClient(t)
+	stateStore := newFakeStore()
+	consent := NewConsentUseCase(client, stateStore, logger.NewNop(), time.Hour, true, []string{"STOP"}, []string{"START"}, "CL", "es")
+	trace := NewTraceUseCase(stateStore, logger.NewNop(), time.Hour)
+	u := NewBookingUseCase(client, logger.NewNop(),
+		WithBookingStore(stateStore),
+		WithConsent(consent),
+		WithTrace(trace))
+	ctx := context.Background()
+
+	// Nothing is stored for a new customer
+	state, err := u.ConversationState(ctx, testPhone)
+	if err != nil {
+		t.Fatalf("ConversationState() error = %v", err)
+	}
+	if state.PendingBooking != nil || state.ExpiredBooking != nil || state.Locale != "" || state.Consent.OptedOut {
+		t.Errorf("state of a new customer = %+v, want empty", state)
+	}
+
+	// A booking that expired
+	expired := testBooking("booking-1")
+	expired.ConfirmationDeadline = time.Minute
+	if _, err := u.SendConfirmationMessage(ctx, expired); err != nil {
+		t.Fatalf("SendConfirmationMessage(booking-1) error = %v", err)
+	}
+	if n, err := u.ExpireOverdue(ctx, time.Now().Add(time.Hour)); err != nil || n != 1 {
+		t.Fatalf("ExpireOverdue() = %d, %v, want 1", n, err)
+	}
+	state, err = u.ConversationState(ctx, testPhone)
+	if err != nil {
+		t.Fatalf("ConversationState() error = %v", err)
+	}
+	if state.ExpiredBooking == nil || state.ExpiredBooking.BookingID != "booking-1" || state.ExpiredBooking.ExpiresAt == nil {
+		t.Errorf("ExpiredBooking = %+v, want booking-1 with its deadline", state.ExpiredBooking)
+	}
+
+	// A new confirmation replaces it
+	if _, err := u.SendConfirmationMessage(ctx, testBooking("booking-2")); err != nil {
+		t.Fatalf("SendConfirmationMessage(booking-2) error = %v", err)
+	}
+	// With a remembered locale and an opt-out
+	if err := stateStore.Set(ctx, localeKeyPrefix+testPhone, "pt", 0); err != nil {
+		t.Fatalf("Set() locale error = %v", err)
+	}
+	if err := consent.OptOut(ctx, testPhone, time.Now()); err != nil {
+		t.Fatalf("OptOut() error = %v", err)
+	}
+
+	state, err = u.ConversationState(ctx, "+56 9 6123 4567")
+	if err != nil {
+		t.Fatalf("ConversationState() error = %v", err)
+	}
+	if state.PhoneNumber != testPhone {
+		t.Errorf("PhoneNumber = %q, want %q", state.PhoneNumber, testPhone)
+	}
+	if state.PendingBooking == nil || state.PendingBooking.BookingID != "booking-2" || state.PendingBooking.MessageID == "" {
+		t.Errorf("PendingBooking = %+v, want booking-2 with its message", state.PendingBooking)
+	}
+	if state.ExpiredBooking != nil {
+		t.Errorf("ExpiredBooking = %+v, want it replaced by the pending booking", state.ExpiredBooking)
+	}
+	if state.Locale != "pt" || state.Consent == nil || !state.Consent.OptedOut {
+		t.Errorf("locale = %q, consent = %+v, want pt and opted out", state.Locale, state.Consent)
+	}
+
+	if _, err := u.ConversationState(ctx, "123"); !errors.Is(err, ErrInvalidPhoneNumber) {
+		t.Errorf("ConversationState() of an invalid number error = %v, want %v", err, ErrInvalidPhoneNumber)
+	}
+}
+
+func TestClearConversationState(t *testing.T) {
+	client := newTestClient(t)
+	stateStore := newFakeStore()
+	consent := NewConsentUseCase(client, stateStore, logger.NewNop(), time.Hour, true, []string{"STOP"}, []string{"START"}, "CL", "es")
+	trace := NewTraceUseCase(stateStore, logger.NewNop(), time.Hour)
+	u := NewBookingUseCase(client, logger.NewNop(),
+		WithBookingStore(stateStore),
+		WithConsent(consent),
+		WithTrace(trace))
+	ctx := context.Background()
+
+	if _, err := u.SendConfirmationMessage(ctx, testBooking("booking-1")); err != nil {
+		t.Fatalf("SendConfirmationMessage() error = %v", err)
+	}
+	if err := stateStore.Set(ctx, localeKeyPrefix+testPhone, "pt", 0); err != nil {
+		t.Fatalf("Set() locale error = %v", err)
+	}
+	if err := consent.OptOut(ctx, testPhone, time.Now()); err != nil {
+		t.Fatalf("OptOut() error = %v", err)
+	}
+
+	// The opt-out is kept unless asked for
+	previous, cleared, err := u.ClearConversationState(ctx, testPhone, "support", false)
+	if err != nil {
+		t.Fatalf("ClearConversationState() error = %v", err)
+	}
+	if fmt.Sprint(cleared) != fmt.Sprint([]string{StatePendingBooking, StateLocale}) {
+		t.Errorf("cleared %v, want the pending booking and locale", cleared)
+	}
+	if previous.PendingBooking == nil || previous.PendingBooking.BookingID != "booking-1" {
+		t.Errorf("previous state = %+v, want booking-1 pending", previous)
+	}
+	state, err := u.ConversationState(ctx, testPhone)
+	if err != nil {
+		t.Fatalf("ConversationState() error = %v", err)
+	}
+	if state.PendingBooking != nil || state.Locale != "" || !state.Consent.OptedOut {
+		t.Errorf("state after clearing = %+v, want only the opt-out left", state)
+	}
+	if _, err := stateStore.Get(ctx, bookingIndexKeyPrefix+"booking-1"); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("booking index after clearing error = %v, want %v", err, store.ErrNotFound)
+	}
+
+	// The clear is recorded in the booking trace with the actor
+	eventually(t, func() bool {
+		lifecycle, err := u.Trace(ctx, "booking-1")
+		if err != nil {
+			return false
+		}
+		i := stageIndex(lifecycle.Events, TraceStageCleared)
+		return i >= 0 && lifecycle.Events[i].Detail == "cleared by support"
+	})
+
+	// Clearing the opt-out as well
+	_, cleared, err = u.ClearConversationState(ctx, testPhone, "support", true)
+	if err != nil {
+		t.Fatalf("ClearConversationState(opt_out) error = %v", err)
+	}
+	if fmt.Sprint(cleared) != fmt.Sprint([]string{StateOptOut}) {
+		t.Errorf("cleared %v, want the opt-out", cleared)
+	}
+	if status, err := consent.Status(ctx, testPhone); err != nil || status.OptedOut {
+		t.Errorf("Status() after clearing = %+v, %v, want no opt-out", status, err)
+	}
+
+	// The customer's answer is no longer taken for the stuck booking
+	response, err := u.ProcessIncomingResponse(ctx, testPhone, "Sí", "")
+	if err != nil {
+		t.Fatalf("ProcessIncomingResponse() error = %v", err)
+	}
+	if response.BookingID != "" {
+		t.Errorf("answer after clearing resolved %s, want no booking", response.BookingID)
+	}
+
+	// A store failure is reported, not taken as an empty state
+	stateStore.fail(errors.New("redis down"))
+	if _, _, err := u.ClearConversationState(ctx, testPhone, "support", false); err == nil {
+		t.Error("ClearConversationState() with the store down succeeded")
+	}
+}
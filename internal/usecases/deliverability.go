@@ -0,0 +1,177 @@
+package usecases
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/store"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/utils"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/whatsapp"
+	"go.mau.fi/whatsmeow/types"
+	"go.uber.org/zap"
+)
+
+// undeliverableKeyPrefix is the state store key prefix for the record of a
+// number WhatsApp refused to deliver to
+const undeliverableKeyPrefix = "whatsapp:undeliverable:"
+
+// ErrUndeliverable is returned when a message is blocked because an earlier
+// send to the number was rejected for good
+var ErrUndeliverable = errors.New("recipient is marked as undeliverable")
+
+// undeliverableRecord is why a number was marked as undeliverable
+type undeliverableRecord struct {
+	Error    string    `json:"error"`
+	MarkedAt time.Time `json:"marked_at"`
+}
+
+// Deliverability is whether messages are sent to a number
+type Deliverability struct {
+	PhoneNumber string `json:"phone_number"`
+	Deliverable bool   `json:"deliverable"`
+	// Error is the send failure that marked the number as undeliverable
+	Error    string     `json:"error,omitempty"`
+	MarkedAt *time.Time `json:"marked_at,omitempty"`
+}
+
+// DeliverabilityUseCase marks numbers as undeliverable when WhatsApp rejects
+// a send because of the recipient (whatsapp.ErrRecipientRejected) and
+// blocks further sends to them until the mark is cleared
+type DeliverabilityUseCase struct {
+	store       store.Store
+	logger      logger.Logger
+	phoneRegion string
+}
+
+// NewDeliverabilityUseCase creates a new DeliverabilityUseCase
+func NewDeliverabilityUseCase(stateStore store.Store, logger logger.Logger, phoneRegion string) *DeliverabilityUseCase {
+	return &DeliverabilityUseCase{
+		store:       stateStore,
+		logger:      logger,
+		phoneRegion: phoneRegion,
+	}
+}
+
+// sendHooks registers the send hooks Guard relies on
+type sendHooks interface {
+	OnSendFailure(hook whatsapp.SendFailureHook)
+	OnBeforeSend(hook whatsapp.BeforeSendHook)
+}
+
+// Guard marks the numbers the client fails to deliver to for good and
+// short-circuits every later send to them
+func (u *DeliverabilityUseCase) Guard(client sendHooks) {
+	client.OnSendFailure(func(jid types.JID, err error) {
+		if jid.Server != types.DefaultUserServer || !errors.Is(err, whatsapp.ErrRecipientRejected) {
+			return
+		}
+		// Failure hooks must not block the send path
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+			if err := u.Mark(ctx, jid.User, err, time.Now()); err != nil {
+				u.logger.Warn("Failed to mark number as undeliverable",
+					zap.String("phone_number", jid.User),
+					zap.Error(err))
+			}
+		}()
+	})
+
+	client.OnBeforeSend(func(msg *whatsapp.OutboundMessage) error {
+		if msg.To.Server != types.DefaultUserServer {
+			return nil
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		return u.Allow(ctx, msg.To.User)
+	})
+}
+
+// Mark records that sends to the number are rejected for good
+func (u *DeliverabilityUseCase) Mark(ctx context.Context, phoneNumber string, cause error, at time.Time) error {
+	data, err := json.Marshal(undeliverableRecord{Error: cause.Error(), MarkedAt: at})
+	if err != nil {
+		return fmt.Errorf("failed to encode deliverability: %w", err)
+	}
+	if err := u.store.Set(ctx, undeliverableKeyPrefix+phoneNumber, string(data), 0); err != nil {
+		return fmt.Errorf("failed to save deliverability: %w", err)
+	}
+	u.logger.Warn("Number marked as undeliverable",
+		zap.String("phone_number", phoneNumber),
+		zap.Error(cause))
+	return nil
+}
+
+// Allow returns ErrUndeliverable when the number is marked as undeliverable.
+// Sends go ahead when the state store is down.
+func (u *DeliverabilityUseCase) Allow(ctx context.Context, phoneNumber string) error {
+	record, err := u.get(ctx, phoneNumber)
+	if err != nil {
+		u.logger.Warn("Failed to check deliverability, sending anyway",
+			zap.String("phone_number", phoneNumber),
+			zap.Error(err))
+		return nil
+	}
+	if record == nil {
+		return nil
+	}
+	return fmt.Errorf("%w: %s: %s", ErrUndeliverable, phoneNumber, record.Error)
+}
+
+// Status returns the deliverability of a number
+func (u *DeliverabilityUseCase) Status(ctx context.Context, number string) (*Deliverability, error) {
+	phoneNumber, err := utils.NormalizePhone(number, u.phoneRegion)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPhoneNumber, err)
+	}
+
+	record, err := u.get(ctx, phoneNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	deliverability := &Deliverability{PhoneNumber: phoneNumber, Deliverable: true}
+	if record != nil {
+		deliverability.Deliverable = false
+		deliverability.Error = record.Error
+		deliverability.MarkedAt = &record.MarkedAt
+	}
+	return deliverability, nil
+}
+
+// Clear removes the undeliverable mark of a number so it can be sent to
+// again
+func (u *DeliverabilityUseCase) Clear(ctx context.Context, number string) (*Deliverability, error) {
+	phoneNumber, err := utils.NormalizePhone(number, u.phoneRegion)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPhoneNumber, err)
+	}
+
+	if err := u.store.Delete(ctx, undeliverableKeyPrefix+phoneNumber); err != nil {
+		return nil, fmt.Errorf("failed to clear deliverability: %w", err)
+	}
+	u.logger.Info("Undeliverable mark cleared", zap.String("phone_number", phoneNumber))
+	return &Deliverability{PhoneNumber: phoneNumber, Deliverable: true}, nil
+}
+
+// get returns the undeliverable record of a number, or nil when it is
+// deliverable
+func (u *DeliverabilityUseCase) get(ctx context.Context, phoneNumber string) (*undeliverableRecord, error) {
+	value, err := u.store.Get(ctx, undeliverableKeyPrefix+phoneNumber)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var record undeliverableRecord
+	if err := json.Unmarshal([]byte(value), &record); err != nil {
+		return nil, fmt.Errorf("failed to decode deliverability: %w", err)
+	}
+	return &record, nil
+}
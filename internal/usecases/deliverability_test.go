@@ -0,0 +1,131 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/whatsapp"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// fakeSendHooks collects the send hooks registered by a guard so the test
+// can run them
+type fakeSendHooks struct {
+	mu      sync.Mutex
+	failure []whatsapp.SendFailureHook
+	before  []whatsapp.BeforeSendHook
+}
+
+func (h *fakeSendHooks) OnSendFailure(hook whatsapp.SendFailureHook) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.failure = append(h.failure, hook)
+}
+
+func (h *fakeSendHooks) OnBeforeSend(hook whatsapp.BeforeSendHook) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.before = append(h.before, hook)
+}
+
+// fail reports a failed send to the number
+func (h *fakeSendHooks) fail(jid types.JID, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, hook := range h.failure {
+		hook(jid, err)
+	}
+}
+
+// send runs the before-send hooks for a message to the number
+func (h *fakeSendHooks) send(jid types.JID) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, hook := range h.before {
+		if err := hook(&whatsapp.OutboundMessage{To: jid}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestDeliverabilityMarksRejectedNumbers(t *testing.T) {
+	u := NewDeliverabilityUseCase(newFakeStore(), logger.NewNop(), "CL")
+	hooks := &fakeSendHooks{}
+	u.Guard(hooks)
+	ctx := context.Background()
+	jid := types.NewJID(testPhone, types.DefaultUserServer)
+
+	// Transient failures and other failures do not mark the number
+	hooks.fail(jid, fmt.Errorf("%w: %w", whatsapp.ErrRecipientUnreachable, errors.New("message timed out")))
+	hooks.fail(jid, whatsapp.ErrDryRunFailure)
+	hooks.fail(types.NewJID("120363025246125486", types.GroupServer), fmt.Errorf("%w: forbidden", whatsapp.ErrRecipientRejected))
+	if status, err := u.Status(ctx, testPhone); err != nil || !status.Deliverable {
+		t.Fatalf("Status() after transient failures = %+v, %v, want deliverable", status, err)
+	}
+	if err := hooks.send(jid); err != nil {
+		t.Fatalf("send after transient failures error = %v", err)
+	}
+
+	// A rejection marks it and later sends short-circuit
+	hooks.fail(jid, fmt.Errorf("%w: server returned error 403", whatsapp.ErrRecipientRejected))
+	var status *Deliverability
+	eventually(t, func() bool {
+		var err error
+		status, err = u.Status(ctx, "+56 9 6123 4567")
+		return err == nil && !status.Deliverable
+	})
+	if status.PhoneNumber != testPhone || status.MarkedAt == nil || status.Error == "" {
+		t.Errorf("status = %+v, want %s marked with the rejection", status, testPhone)
+	}
+	if err := hooks.send(jid); !errors.Is(err, ErrUndeliverable) {
+		t.Errorf("send to a rejected number error = %v, want %v", err, ErrUndeliverable)
+	}
+	if err := hooks.send(types.NewJID("56961234568", types.DefaultUserServer)); err != nil {
+		t.Errorf("send to another number error = %v", err)
+	}
+
+	// Until the mark is cleared
+	if _, err := u.Clear(ctx, testPhone); err != nil {
+		t.Fatalf("Clear() error = %v", err)
+	}
+	if err := hooks.send(jid); err != nil {
+		t.Errorf("send after clearing error = %v", err)
+	}
+}
+
+func TestDeliverabilityGuardsClientSends(t *testing.T) {
+	stateStore := newFakeStore()
+	u := NewDeliverabilityUseCase(stateStore, logger.NewNop(), "CL")
+	client := newTestClient(t)
+	u.Guard(client)
+	// Registered after the guard, so only messages it lets through are seen
+	sent := captureSends(client)
+	ctx := context.Background()
+	jid := types.NewJID(testPhone, types.DefaultUserServer)
+
+	if err := u.Mark(ctx, testPhone, fmt.Errorf("%w: blocked", whatsapp.ErrRecipientRejected), time.Now()); err != nil {
+		t.Fatalf("Mark() error = %v", err)
+	}
+	if _, err := client.SendText(ctx, jid, "Hola"); !errors.Is(err, ErrUndeliverable) {
+		t.Errorf("SendText() error = %v, want %v", err, ErrUndeliverable)
+	}
+	if got := len(sent.all()); got != 0 {
+		t.Errorf("sent %d messages to an undeliverable number, want none", got)
+	}
+
+	// Sends go ahead while the state store is down
+	stateStore.fail(errors.New("redis down"))
+	if _, err := client.SendText(ctx, jid, "Hola"); err != nil {
+		t.Errorf("SendText() with the store down error = %v", err)
+	}
+
+	if _, err := u.Status(ctx, "not a number"); !errors.Is(err, ErrInvalidPhoneNumber) {
+		t.Errorf("Status() of an invalid number error = %v, want %v", err, ErrInvalidPhoneNumber)
+	}
+}
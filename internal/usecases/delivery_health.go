@@ -0,0 +1,303 @@
+package usecases
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/whatsapp"
+	"go.mau.fi/whatsmeow/types"
+	"go.uber.org/zap"
+)
+
+// GlobalDeliveryScope is the alert scope of the rate across all numbers
+const GlobalDeliveryScope = "global"
+
+// DeliveryAlertFunc is called when the delivery rate of a scope (a phone
+// number or GlobalDeliveryScope) drops below the threshold
+type DeliveryAlertFunc func(scope string, rate DeliveryRate)
+
+// DeliveryRate is the delivery success rate over the rolling window
+type DeliveryRate struct {
+	Delivered int     `json:"delivered"`
+	Failed    int     `json:"failed"`
+	Rate      float64 `json:"rate"`
+}
+
+// NumberDeliveryRate is the delivery rate of one phone number
+type NumberDeliveryRate struct {
+	PhoneNumber string `json:"phone_number"`
+	DeliveryRate
+	Alerting bool `json:"alerting"`
+}
+
+// DeliveryHealth is the current delivery health
+type DeliveryHealth struct {
+	Window    string               `json:"window"`
+	Threshold float64              `json:"threshold"`
+	Global    DeliveryRate         `json:"global"`
+	Alerting  bool                 `json:"alerting"`
+	Numbers   []NumberDeliveryRate `json:"numbers"`
+	Pending   int                  `json:"pending"`
+}
+
+// deliveryOutcome is the final outcome of one sent message
+type deliveryOutcome struct {
+	phone     string
+	delivered bool
+	at        time.Time
+}
+
+// pendingDelivery is a sent message waiting for its delivery receipt
+type pendingDelivery struct {
+	phone  string
+	sentAt time.Time
+}
+
+// DeliveryHealthUseCase tracks delivery success rates per number and
+// globally over a rolling window. A message counts as delivered when its
+// delivery receipt arrives, and as failed when the send is rejected or no
+// receipt arrives within the timeout. It implements whatsapp.SendRecorder.
+type DeliveryHealthUseCase struct {
+	logger     logger.Logger
+	window     time.Duration
+	timeout    time.Duration
+	threshold  float64
+	minSamples int
+	alert      DeliveryAlertFunc
+
+	pending  map[string]pendingDelivery
+	outcomes []deliveryOutcome
+	alerting map[string]bool
+	mu       sync.Mutex
+}
+
+// NewDeliveryHealthUseCase creates a DeliveryHealthUseCase that alerts when
+// a scope with at least minSamples outcomes in the window delivers less than
+// threshold (0-1) of its messages
+func NewDeliveryHealthUseCase(logger logger.Logger, window, timeout time.Duration, threshold float64, minSamples int, alert DeliveryAlertFunc) *DeliveryHealthUseCase {
+	if window <= 0 {
+		window = time.Hour
+	}
+	if timeout <= 0 {
+		timeout = 10 * time.Minute
+	}
+	if minSamples <= 0 {
+		minSamples = 1
+	}
+
+	return &DeliveryHealthUseCase{
+		logger:     logger,
+		window:     window,
+		timeout:    timeout,
+		threshold:  threshold,
+		minSamples: minSamples,
+		alert:      alert,
+		pending:    make(map[string]pendingDelivery),
+		alerting:   make(map[string]bool),
+	}
+}
+
+// Watch records the sends the client fails to deliver. Group messages are
+// not tracked.
+func (u *DeliveryHealthUseCase) Watch(client *whatsapp.Client) {
+	client.OnSendFailure(func(jid types.JID, err error) {
+		if jid.Server == types.DefaultUserServer {
+			u.RecordFailure(jid.User, time.Now())
+		}
+	})
+}
+
+// RecordSend starts waiting for the delivery receipt of a sent message.
+// Group messages are not tracked.
+func (u *DeliveryHealthUseCase) RecordSend(ctx context.Context, record whatsapp.SendRecord) error {
+	jid, err := types.ParseJID(record.JID)
+	if err != nil || jid.Server != types.DefaultUserServer {
+		return nil
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.pending[record.MessageID] = pendingDelivery{phone: jid.User, sentAt: record.SentAt}
+	return nil
+}
+
+// UpdateStatus records a message as delivered once any delivery receipt arrives
+func (u *DeliveryHealthUseCase) UpdateStatus(ctx context.Context, messageID, status string, at time.Time) error {
+	if whatsapp.StatusRank(status) < whatsapp.StatusRank(whatsapp.SendStatusDelivered) {
+		return nil
+	}
+
+	u.mu.Lock()
+	pending, ok := u.pending[messageID]
+	if ok {
+		delete(u.pending, messageID)
+	}
+	u.mu.Unlock()
+
+	if ok {
+		u.record(deliveryOutcome{phone: pending.phone, delivered: true, at: at})
+	}
+	return nil
+}
+
+// RecordFailure records a message to the number that failed to send
+func (u *DeliveryHealthUseCase) RecordFailure(phone string, at time.Time) {
+	u.record(deliveryOutcome{phone: phone, delivered: false, at: at})
+}
+
+// Sweep fails the messages without a receipt after the timeout and drops the
+// outcomes that left the window
+func (u *DeliveryHealthUseCase) Sweep(now time.Time) {
+	u.mu.Lock()
+	var timedOut []deliveryOutcome
+	for messageID, pending := range u.pending {
+		if now.Sub(pending.sentAt) > u.timeout {
+			delete(u.pending, messageID)
+			timedOut = append(timedOut, deliveryOutcome{phone: pending.phone, at: now})
+		}
+	}
+	u.prune(now)
+	u.mu.Unlock()
+
+	for _, outcome := range timedOut {
+		u.record(outcome)
+	}
+}
+
+// Start sweeps on the given interval until the context is cancelled
+func (u *DeliveryHealthUseCase) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				u.Sweep(now)
+			}
+		}
+	}()
+}
+
+// Health returns the delivery rates over the window, the worst numbers first
+func (u *DeliveryHealthUseCase) Health(now time.Time) DeliveryHealth {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.prune(now)
+
+	global, byNumber := u.rates()
+	health := DeliveryHealth{
+		Window:    u.window.String(),
+		Threshold: u.threshold,
+		Global:    global,
+		Alerting:  u.alerting[GlobalDeliveryScope],
+		Numbers:   make([]NumberDeliveryRate, 0, len(byNumber)),
+		Pending:   len(u.pending),
+	}
+	for phone, rate := range byNumber {
+		health.Numbers = append(health.Numbers, NumberDeliveryRate{
+			PhoneNumber:  phone,
+			DeliveryRate: rate,
+			Alerting:     u.alerting[phone],
+		})
+	}
+	sort.Slice(health.Numbers, func(i, j int) bool {
+		if health.Numbers[i].Rate != health.Numbers[j].Rate {
+			return health.Numbers[i].Rate < health.Numbers[j].Rate
+		}
+		return health.Numbers[i].PhoneNumber < health.Numbers[j].PhoneNumber
+	})
+	return health
+}
+
+// record adds an outcome and alerts the scopes that dropped below the threshold
+func (u *DeliveryHealthUseCase) record(outcome deliveryOutcome) {
+	u.mu.Lock()
+	u.outcomes = append(u.outcomes, outcome)
+	u.prune(outcome.at)
+
+	global, byNumber := u.rates()
+	var alerts []string
+	for _, scope := range []string{GlobalDeliveryScope, outcome.phone} {
+		rate := global
+		if scope != GlobalDeliveryScope {
+			rate = byNumber[scope]
+		}
+		below := rate.Delivered+rate.Failed >= u.minSamples && rate.Rate < u.threshold
+		if below && !u.alerting[scope] {
+			alerts = append(alerts, scope)
+		}
+		if below {
+			u.alerting[scope] = true
+		} else {
+			delete(u.alerting, scope)
+		}
+	}
+	u.mu.Unlock()
+
+	for _, scope := range alerts {
+		rate := global
+		if scope != GlobalDeliveryScope {
+			rate = byNumber[scope]
+		}
+		u.logger.Warn("Delivery rate below threshold",
+			zap.String("scope", scope),
+			zap.Float64("rate", rate.Rate),
+			zap.Float64("threshold", u.threshold),
+			zap.Int("failed", rate.Failed))
+		// Outcomes are recorded on the send path; never wait for the alert
+		if u.alert != nil {
+			go u.alert(scope, rate)
+		}
+	}
+}
+
+// prune drops the outcomes older than the window; callers hold the lock
+func (u *DeliveryHealthUseCase) prune(now time.Time) {
+	cutoff := now.Add(-u.window)
+	kept := u.outcomes[:0]
+	for _, outcome := range u.outcomes {
+		if outcome.at.After(cutoff) {
+			kept = append(kept, outcome)
+		}
+	}
+	u.outcomes = kept
+}
+
+// rates computes the global and per-number rates; callers hold the lock
+func (u *DeliveryHealthUseCase) rates() (DeliveryRate, map[string]DeliveryRate) {
+	var global DeliveryRate
+	byNumber := make(map[string]DeliveryRate)
+	for _, outcome := range u.outcomes {
+		rate := byNumber[outcome.phone]
+		if outcome.delivered {
+			global.Delivered++
+			rate.Delivered++
+		} else {
+			global.Failed++
+			rate.Failed++
+		}
+		byNumber[outcome.phone] = rate
+	}
+
+	global.Rate = successRate(global)
+	for phone, rate := range byNumber {
+		rate.Rate = successRate(rate)
+		byNumber[phone] = rate
+	}
+	return global, byNumber
+}
+
+// successRate returns the delivered fraction, or 1 without outcomes
+func successRate(rate DeliveryRate) float64 {
+	total := rate.Delivered + rate.Failed
+	if total == 0 {
+		return 1
+	}
+	return float64(rate.Delivered) / float64(total)
+}
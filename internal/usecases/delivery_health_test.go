@@ -0,0 +1,157 @@
+package usecases
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/whatsapp"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// deliveryAlert is one alert fired by the delivery health use case
+type deliveryAlert struct {
+	scope string
+	rate  DeliveryRate
+}
+
+// newDeliveryHealth creates a use case alerting below 60% with at least
+// four outcomes, returning the alerts it fires
+func newDeliveryHealth() (*DeliveryHealthUseCase, <-chan deliveryAlert) {
+	alerts := make(chan deliveryAlert, 8)
+	u := NewDeliveryHealthUseCase(logger.NewNop(), time.Hour, time.Minute, 0.6, 4, func(scope string, rate DeliveryRate) {
+		alerts <- deliveryAlert{scope, rate}
+	})
+	return u, alerts
+}
+
+// seedDelivered records a sent message to the phone and its delivery receipt
+func seedDelivered(t *testing.T, u *DeliveryHealthUseCase, messageID, phone string, at time.Time) {
+	t.Helper()
+	ctx := context.Background()
+	record := whatsapp.SendRecord{MessageID: messageID, JID: types.NewJID(phone, types.DefaultUserServer).String(), SentAt: at}
+	if err := u.RecordSend(ctx, record); err != nil {
+		t.Fatalf("RecordSend() error = %v", err)
+	}
+	if err := u.UpdateStatus(ctx, messageID, whatsapp.SendStatusDelivered, at); err != nil {
+		t.Fatalf("UpdateStatus() error = %v", err)
+	}
+}
+
+// collectAlerts returns the scopes alerted within a short wait
+func collectAlerts(alerts <-chan deliveryAlert) map[string]DeliveryRate {
+	got := make(map[string]DeliveryRate)
+	timeout := time.After(100 * time.Millisecond)
+	for {
+		select {
+		case alert := <-alerts:
+			got[alert.scope] = alert.rate
+		case <-timeout:
+			return got
+		}
+	}
+}
+
+func TestDeliveryHealthAlertsBelowThreshold(t *testing.T) {
+	u, alerts := newDeliveryHealth()
+	now := time.Now()
+	const healthy, failing = testPhone, "56961234568"
+
+	// Healthy traffic does not alert
+	for i, id := range []string{"msg-1", "msg-2", "msg-3", "msg-4"} {
+		seedDelivered(t, u, id, healthy, now.Add(time.Duration(i)*time.Second))
+	}
+	// Fewer outcomes than the minimum do not alert, even all failing
+	for i := 0; i < 2; i++ {
+		u.RecordFailure(failing, now)
+	}
+	if got := collectAlerts(alerts); len(got) != 0 {
+		t.Fatalf("alerts = %v, want none", got)
+	}
+
+	// Reaching the minimum takes the number below the threshold, and the
+	// global rate drops below it on the way (4 of 7)
+	u.RecordFailure(failing, now)
+	u.RecordFailure(failing, now)
+	got := collectAlerts(alerts)
+	if rate, ok := got[failing]; !ok || rate.Failed != 4 || rate.Rate != 0 {
+		t.Errorf("alert of %s = %+v, %t, want 4 failed at rate 0", failing, rate, ok)
+	}
+	if rate, ok := got[GlobalDeliveryScope]; !ok || rate.Delivered != 4 || rate.Failed != 3 {
+		t.Errorf("global alert = %+v, %t, want 4 delivered and 3 failed", rate, ok)
+	}
+	if _, ok := got[healthy]; ok {
+		t.Errorf("alerted the healthy number %s", healthy)
+	}
+
+	// Scopes already alerting do not alert again
+	u.RecordFailure(failing, now)
+	if got := collectAlerts(alerts); len(got) != 0 {
+		t.Errorf("repeated alerts = %v, want none while still alerting", got)
+	}
+
+	health := u.Health(now)
+	if !health.Alerting || health.Global.Failed != 5 || health.Global.Delivered != 4 {
+		t.Errorf("health = %+v, want 4 delivered, 5 failed and alerting", health)
+	}
+	if len(health.Numbers) != 2 || health.Numbers[0].PhoneNumber != failing || !health.Numbers[0].Alerting || health.Numbers[1].Alerting {
+		t.Errorf("numbers = %+v, want the failing number first and alerting", health.Numbers)
+	}
+
+	// Outcomes leaving the window clear the alert
+	if health := u.Health(now.Add(2 * time.Hour)); health.Global.Delivered+health.Global.Failed != 0 {
+		t.Errorf("health after the window = %+v, want no outcomes", health.Global)
+	}
+}
+
+func TestDeliveryHealthTimesOutMissingReceipts(t *testing.T) {
+	u, _ := newDeliveryHealth()
+	ctx := context.Background()
+	now := time.Now()
+
+	record := whatsapp.SendRecord{MessageID: "msg-1", JID: types.NewJID(testPhone, types.DefaultUserServer).String(), SentAt: now}
+	if err := u.RecordSend(ctx, record); err != nil {
+		t.Fatalf("RecordSend() error = %v", err)
+	}
+	// Group messages are not tracked
+	group := whatsapp.SendRecord{MessageID: "msg-2", JID: types.NewJID("120363000000000001", types.GroupServer).String(), SentAt: now}
+	if err := u.RecordSend(ctx, group); err != nil {
+		t.Fatalf("RecordSend() of a group message error = %v", err)
+	}
+	if health := u.Health(now); health.Pending != 1 {
+		t.Fatalf("pending = %d, want 1", health.Pending)
+	}
+
+	u.Sweep(now.Add(30 * time.Second))
+	if health := u.Health(now); health.Pending != 1 {
+		t.Errorf("pending before the timeout = %d, want 1", health.Pending)
+	}
+	u.Sweep(now.Add(2 * time.Minute))
+	health := u.Health(now.Add(2 * time.Minute))
+	if health.Pending != 0 || health.Global.Failed != 1 {
+		t.Errorf("health after the timeout = %+v, want the message failed", health)
+	}
+
+	// A late receipt does not count the message twice
+	if err := u.UpdateStatus(ctx, "msg-1", whatsapp.SendStatusDelivered, now.Add(3*time.Minute)); err != nil {
+		t.Fatalf("UpdateStatus() error = %v", err)
+	}
+	if health := u.Health(now.Add(3 * time.Minute)); health.Global.Delivered != 0 {
+		t.Errorf("delivered = %d after a late receipt, want 0", health.Global.Delivered)
+	}
+}
+
+func TestDeliveryHealthWatchesSendFailures(t *testing.T) {
+	u, _ := newDeliveryHealth()
+	client := newTestClient(t, whatsapp.WithDryRun(0, 1))
+	u.Watch(client)
+
+	if _, err := client.SendText(context.Background(), types.NewJID(testPhone, types.DefaultUserServer), "Hola"); err == nil {
+		t.Fatal("SendText() succeeded, want the injected failure")
+	}
+	health := u.Health(time.Now())
+	if health.Global.Failed == 0 || len(health.Numbers) != 1 || health.Numbers[0].PhoneNumber != testPhone {
+		t.Errorf("health = %+v, want the failed send to %s", health, testPhone)
+	}
+}
@@ -0,0 +1,50 @@
+package usecases
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/whatsapp"
+	"go.mau.fi/whatsmeow/types"
+)
+
+func TestGroupMessageAnsweredInGroup(t *testing.T) {
+	client := newTestClient(t)
+	sent := captureSends(client)
+	u := NewBookingUseCase(client, logger.NewNop(), WithBookingStore(newFakeStore()))
+	ctx := context.Background()
+	if _, err := u.SendConfirmationMessage(ctx, testBooking("booking-1")); err != nil {
+		t.Fatalf("SendConfirmationMessage() error = %v", err)
+	}
+
+	// The sender's booking is answered in the group it wrote from
+	group := types.NewJID("120363000000000001", types.GroupServer)
+	response, err := u.ProcessIncomingMessage(ctx, &whatsapp.WhatsAppMessage{
+		ID:      "msg-1",
+		From:    testPhone,
+		Body:    "Sí",
+		IsGroup: true,
+		Chat:    group,
+	})
+	if err != nil {
+		t.Fatalf("ProcessIncomingMessage() error = %v", err)
+	}
+	if response.BookingID != "booking-1" || response.Status != "confirmed" {
+		t.Errorf("response = %+v, want booking-1 confirmed", response)
+	}
+	messages := sent.all()
+	if got := messages[len(messages)-1].To; got != group {
+		t.Errorf("reply sent to %s, want the group %s", got, group)
+	}
+
+	// A private message is answered in the sender's chat
+	sender := types.NewJID(testPhone, types.DefaultUserServer)
+	if _, err := u.ProcessIncomingMessage(ctx, &whatsapp.WhatsAppMessage{ID: "msg-2", From: testPhone, Body: "Hola", Chat: sender}); err != nil {
+		t.Fatalf("ProcessIncomingMessage() error = %v", err)
+	}
+	messages = sent.all()
+	if got := messages[len(messages)-1].To; got != sender {
+		t.Errorf("reply sent to %s, want the sender %s", got, sender)
+	}
+}
@@ -0,0 +1,73 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/utils"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/whatsapp"
+	"go.uber.org/zap"
+)
+
+// GroupUseCase handles group-related operations
+type GroupUseCase struct {
+	client      *whatsapp.Client
+	logger      logger.Logger
+	phoneRegion string
+}
+
+// NewGroupUseCase creates a new GroupUseCase
+func NewGroupUseCase(client *whatsapp.Client, logger logger.Logger, phoneRegion string) *GroupUseCase {
+	return &GroupUseCase{
+		client:      client,
+		logger:      logger,
+		phoneRegion: phoneRegion,
+	}
+}
+
+// ListGroups returns the groups the connected number belongs to
+func (u *GroupUseCase) ListGroups(ctx context.Context) ([]whatsapp.Group, error) {
+	groups, err := u.client.ListGroups(ctx)
+	if err != nil {
+		u.logger.Error("Failed to list groups", zap.Error(err))
+		return nil, fmt.Errorf("failed to list groups: %w", err)
+	}
+	return groups, nil
+}
+
+// SendMessage sends a text to a group, @-mentioning the given phone numbers
+func (u *GroupUseCase) SendMessage(ctx context.Context, groupJID, text string, mentions []string) (*SendResult, error) {
+	group, err := whatsapp.ParseGroupJID(groupJID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid group JID %q", ErrInvalidMessage, groupJID)
+	}
+	if strings.TrimSpace(text) == "" && len(mentions) == 0 {
+		return nil, fmt.Errorf("%w: text or mentions are required", ErrInvalidMessage)
+	}
+
+	phones := make([]string, len(mentions))
+	for i, mention := range mentions {
+		phone, err := utils.NormalizePhone(mention, u.phoneRegion)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPhoneNumber, err)
+		}
+		phones[i] = phone
+	}
+
+	resp, err := u.client.SendGroupText(ctx, group, text, phones)
+	if err != nil {
+		return nil, err
+	}
+
+	u.logger.Info("Group message sent",
+		zap.String("group", group.String()),
+		zap.String("message_id", resp.ID),
+		zap.Int("mentions", len(phones)))
+
+	return &SendResult{
+		MessageID: resp.ID,
+		Timestamp: resp.Timestamp,
+	}, nil
+}
@@ -0,0 +1,117 @@
+package usecases
+
+import (
+	"context"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/redis"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/whatsapp"
+)
+
+// Readiness represents the readiness of the service and its dependencies
+type Readiness struct {
+	Ready    bool   `json:"ready"`
+	WhatsApp string `json:"whatsapp"`
+	Redis    string `json:"redis"`
+	// DeviceStore is the result of the startup integrity check
+	DeviceStore whatsapp.StoreCheck `json:"device_store"`
+}
+
+// DependencyHealth is the state of one dependency in the health check
+type DependencyHealth struct {
+	Healthy bool   `json:"healthy"`
+	Status  string `json:"status"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Health represents the health of the service: healthy only when WhatsApp is
+// connected, the session is logged in and Redis responds
+type Health struct {
+	Status   string           `json:"status"`
+	WhatsApp DependencyHealth `json:"whatsapp"`
+	Session  DependencyHealth `json:"session"`
+	Redis    DependencyHealth `json:"redis"`
+	// Phone is the logged-in phone number, set while connected
+	Phone string `json:"phone,omitempty"`
+}
+
+// healthCheckTimeout bounds the Redis ping of the health checks
+const healthCheckTimeout = 2 * time.Second
+
+// HealthUseCase reports the health of the service dependencies
+type HealthUseCase struct {
+	client *whatsapp.Client
+	redis  *redis.Client
+	auth   *WhatsAppAuthUseCase
+	logger logger.Logger
+}
+
+// NewHealthUseCase creates a new HealthUseCase
+func NewHealthUseCase(client *whatsapp.Client, redisClient *redis.Client, auth *WhatsAppAuthUseCase, logger logger.Logger) *HealthUseCase {
+	return &HealthUseCase{
+		client: client,
+		redis:  redisClient,
+		auth:   auth,
+		logger: logger,
+	}
+}
+
+// Readiness checks the dependencies. Only WhatsApp gates readiness; Redis
+// being down degrades features but does not stop sending.
+func (u *HealthUseCase) Readiness(ctx context.Context) Readiness {
+	readiness := Readiness{
+		Ready:    u.client.IsConnected(),
+		WhatsApp: "connected",
+		Redis:    "ok",
+		// A recovered store only means the device has to be paired again
+		DeviceStore: u.client.StoreCheck(),
+	}
+	if !readiness.Ready {
+		readiness.WhatsApp = "disconnected"
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+	if err := u.redis.Ping(ctx); err != nil {
+		readiness.Redis = "degraded"
+	}
+
+	return readiness
+}
+
+// Health checks every dependency. Unlike Readiness, Redis being down makes
+// the service unhealthy.
+func (u *HealthUseCase) Health(ctx context.Context) Health {
+	health := Health{
+		WhatsApp: DependencyHealth{Healthy: u.client.IsConnected(), Status: "connected"},
+		Redis:    DependencyHealth{Healthy: true, Status: "ok"},
+	}
+	if !health.WhatsApp.Healthy {
+		health.WhatsApp.Status = "disconnected"
+	}
+
+	// The session is connected, needs_qr, failed or disconnected
+	status := u.auth.GetStatus()
+	health.Session = DependencyHealth{Healthy: status.Status == "connected", Status: status.Status}
+	if health.Session.Healthy {
+		health.Phone = status.Phone
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+	if err := u.redis.Ping(ctx); err != nil {
+		health.Redis = DependencyHealth{Status: "down", Error: err.Error()}
+	}
+
+	health.Status = "ok"
+	if !health.Healthy() {
+		health.Status = "unhealthy"
+	}
+	return health
+}
+
+// Healthy reports whether every dependency is healthy
+func (h Health) Healthy() bool {
+	return h.WhatsApp.Healthy && h.Session.Healthy && h.Redis.Healthy
+}
@@ -0,0 +1,273 @@
+package usecases
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/redis"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/store"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/whatsapp"
+)
+
+// newTestClient creates a dry-run WhatsApp client on a temporary device
+// store, so sends succeed without contacting WhatsApp
+func newTestClient(t *testing.T, options ...whatsapp.ClientOption) *whatsapp.Client {
+	t.Helper()
+	options = append([]whatsapp.ClientOption{
+		whatsapp.WithLogger(logger.NewNop()),
+		whatsapp.WithDryRun(0, 0),
+	}, options...)
+	client, err := whatsapp.NewClient("file:"+t.TempDir()+"/whatsapp.db?_foreign_keys=on", options...)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	t.Cleanup(func() { client.Disconnect() })
+	return client
+}
+
+// newTestRedis starts an in-process Redis server and returns a client
+// connected to it
+func newTestRedis(t *testing.T) (*redis.Client, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(server.Addr())
+	t.Cleanup(func() { client.Close() })
+	return client, server
+}
+
+// eventually fails the test unless the condition holds within a second
+func eventually(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// sentMessages records the messages sent through a test client
+type sentMessages struct {
+	mu       sync.Mutex
+	messages []*whatsapp.OutboundMessage
+}
+
+// captureSends records every message sent through the client
+func captureSends(client *whatsapp.Client) *sentMessages {
+	sent := &sentMessages{}
+	client.OnBeforeSend(func(msg *whatsapp.OutboundMessage) error {
+		sent.mu.Lock()
+		defer sent.mu.Unlock()
+		sent.messages = append(sent.messages, msg)
+		return nil
+	})
+	return sent
+}
+
+// all returns the messages sent so far
+func (s *sentMessages) all() []*whatsapp.OutboundMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*whatsapp.OutboundMessage(nil), s.messages...)
+}
+
+// texts returns the text of the messages sent so far
+func (s *sentMessages) texts() []string {
+	var texts []string
+	for _, msg := range s.all() {
+		texts = append(texts, whatsapp.MessageText(msg.Message))
+	}
+	return texts
+}
+
+// postedEvent is a webhook event received by the test server
+type postedEvent struct {
+	Type   string          `json:"type"`
+	Data   json.RawMessage `json:"data"`
+	Header http.Header     `json:"-"`
+}
+
+// newEventServer starts a webhook receiver answering with the given status
+// and returns its URL and the events posted to it
+func newEventServer(t *testing.T, status int) (string, <-chan postedEvent) {
+	t.Helper()
+	posted := make(chan postedEvent, 16)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event postedEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("decode webhook event: %v", err)
+		}
+		event.Header = r.Header
+		posted <- event
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server.URL, posted
+}
+
+// testPhone is a valid Chilean mobile number in E.164 without the plus sign
+const testPhone = "56961234567"
+
+// fakeStore is an in-memory store.Store on a fake clock that records the
+// expiration of each key and can be made to fail every operation
+type fakeStore struct {
+	mu       sync.Mutex
+	values   map[string]string
+	expires  map[string]time.Duration
+	deadline map[string]time.Time
+	now      time.Time
+	takes    int
+	err      error
+}
+
+// newFakeStore creates an empty fakeStore with its clock at the current time
+func newFakeStore() *fakeStore {
+	return &fakeStore{
+		values:   make(map[string]string),
+		expires:  make(map[string]time.Duration),
+		deadline: make(map[string]time.Time),
+		now:      time.Now(),
+	}
+}
+
+// fail makes every later operation return err; nil restores the store
+func (s *fakeStore) fail(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
+
+// advance moves the clock forward, expiring the keys whose time has come
+func (s *fakeStore) advance(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.now = s.now.Add(d)
+	for key, deadline := range s.deadline {
+		if !s.now.Before(deadline) {
+			s.remove(key)
+		}
+	}
+}
+
+// expiration returns the expiration the key was last written with
+func (s *fakeStore) expiration(key string) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.expires[key]
+}
+
+// set writes a key; the caller holds the lock
+func (s *fakeStore) set(key, value string, expiration time.Duration) {
+	s.values[key] = value
+	s.expires[key] = expiration
+	delete(s.deadline, key)
+	if expiration > 0 {
+		s.deadline[key] = s.now.Add(expiration)
+	}
+}
+
+// remove deletes a key; the caller holds the lock
+func (s *fakeStore) remove(key string) {
+	delete(s.values, key)
+	delete(s.expires, key)
+	delete(s.deadline, key)
+}
+
+func (s *fakeStore) Set(ctx context.Context, key, value string, expiration time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	s.set(key, value, expiration)
+	return nil
+}
+
+func (s *fakeStore) SetNX(ctx context.Context, key, value string, expiration time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return false, s.err
+	}
+	if _, ok := s.values[key]; ok {
+		return false, nil
+	}
+	s.set(key, value, expiration)
+	return true, nil
+}
+
+func (s *fakeStore) Get(ctx context.Context, key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return "", s.err
+	}
+	value, ok := s.values[key]
+	if !ok {
+		return "", store.ErrNotFound
+	}
+	return value, nil
+}
+
+func (s *fakeStore) Take(ctx context.Context, key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return "", s.err
+	}
+	s.takes++
+	value, ok := s.values[key]
+	if !ok {
+		return "", store.ErrNotFound
+	}
+	s.remove(key)
+	return value, nil
+}
+
+func (s *fakeStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	s.remove(key)
+	return nil
+}
+
+func (s *fakeStore) Keys(ctx context.Context, pattern string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return nil, s.err
+	}
+	var keys []string
+	for key := range s.values {
+		if ok, _ := path.Match(pattern, key); ok {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// testBooking returns a complete booking request for the test phone
+func testBooking(bookingID string) BookingRequest {
+	return BookingRequest{
+		BookingID:    bookingID,
+		ServiceName:  "Corte",
+		UserName:     "Ana",
+		LocationName: "Centro",
+		StartTime:    "10:00",
+		Date:         "01/06/2025",
+		EmployeeName: "Luis",
+		PhoneNumber:  testPhone,
+	}
+}
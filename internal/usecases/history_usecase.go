@@ -0,0 +1,228 @@
+package usecases
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/store"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/whatsapp"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// History key prefixes. Messages are keyed by number and zero-padded send
+// time so keys sort chronologically; the ID index maps a message ID to its key.
+const (
+	historyKeyPrefix   = "whatsapp:history:"
+	historyIDKeyPrefix = "whatsapp:history_id:"
+)
+
+// Message directions
+const (
+	DirectionInbound  = "inbound"
+	DirectionOutbound = "outbound"
+)
+
+// Page size limits when listing a conversation
+const (
+	defaultHistoryLimit = 20
+	maxHistoryLimit     = 100
+)
+
+// HistoryMessage is a message exchanged with a customer
+type HistoryMessage struct {
+	ID        string                 `json:"id"`
+	Direction string                 `json:"direction"`
+	Body      string                 `json:"body,omitempty"`
+	Redacted  bool                   `json:"redacted,omitempty"`
+	Media     *whatsapp.InboundMedia `json:"media,omitempty"`
+	Status    string                 `json:"status,omitempty"`
+	Timestamp time.Time              `json:"timestamp"`
+}
+
+// HistoryUseCase keeps the recent messages exchanged with each number. It
+// implements whatsapp.SendRecorder to capture outbound messages.
+type HistoryUseCase struct {
+	store       store.Store
+	logger      logger.Logger
+	retention   time.Duration
+	storeBodies bool
+}
+
+// NewHistoryUseCase creates a new HistoryUseCase. When storeBodies is false
+// message bodies are never persisted and are returned as redacted.
+func NewHistoryUseCase(stateStore store.Store, logger logger.Logger, retention time.Duration, storeBodies bool) *HistoryUseCase {
+	if retention <= 0 {
+		retention = 7 * 24 * time.Hour
+	}
+
+	return &HistoryUseCase{
+		store:       stateStore,
+		logger:      logger,
+		retention:   retention,
+		storeBodies: storeBodies,
+	}
+}
+
+// RecordInbound stores a message received from a customer
+func (u *HistoryUseCase) RecordInbound(ctx context.Context, msg *whatsapp.WhatsAppMessage, at time.Time) error {
+	return u.save(ctx, msg.From, HistoryMessage{
+		ID:        msg.ID,
+		Direction: DirectionInbound,
+		Body:      msg.Body,
+		Media:     msg.Media,
+		Timestamp: at,
+	})
+}
+
+// RecordSend stores a message sent to a customer
+func (u *HistoryUseCase) RecordSend(ctx context.Context, record whatsapp.SendRecord) error {
+	jid, err := types.ParseJID(record.JID)
+	if err != nil {
+		return fmt.Errorf("failed to parse send record JID: %w", err)
+	}
+	if jid.Server != types.DefaultUserServer {
+		return nil
+	}
+
+	return u.save(ctx, jid.User, HistoryMessage{
+		ID:        record.MessageID,
+		Direction: DirectionOutbound,
+		Body:      record.Text,
+		Status:    record.Status,
+		Timestamp: record.SentAt,
+	})
+}
+
+// UpdateStatus applies a delivery receipt to an outbound history message.
+// Receipts for messages that are not in the history are ignored.
+func (u *HistoryUseCase) UpdateStatus(ctx context.Context, messageID, status string, at time.Time) error {
+	key, err := u.store.Get(ctx, historyIDKeyPrefix+messageID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	message, err := u.get(ctx, key)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if whatsapp.StatusRank(status) <= whatsapp.StatusRank(message.Status) {
+		return nil
+	}
+
+	message.Status = status
+	return u.put(ctx, key, *message)
+}
+
+// List returns up to limit messages exchanged with number, newest first,
+// sent strictly before the given time (zero means now). Unknown numbers
+// return an empty list.
+func (u *HistoryUseCase) List(ctx context.Context, number string, limit int, before time.Time) ([]HistoryMessage, error) {
+	if limit <= 0 {
+		limit = defaultHistoryLimit
+	}
+	if limit > maxHistoryLimit {
+		limit = maxHistoryLimit
+	}
+
+	keys, err := u.store.Keys(ctx, historyKeyPrefix+number+":*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list history: %w", err)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(keys)))
+
+	messages := make([]HistoryMessage, 0, limit)
+	for _, key := range keys {
+		if len(messages) == limit {
+			break
+		}
+		if !before.IsZero() && !keyBefore(key, number, before) {
+			continue
+		}
+
+		message, err := u.get(ctx, key)
+		if err != nil {
+			continue
+		}
+		messages = append(messages, *message)
+	}
+
+	return messages, nil
+}
+
+// save stores a history message and indexes it by message ID
+func (u *HistoryUseCase) save(ctx context.Context, number string, message HistoryMessage) error {
+	if !u.storeBodies {
+		message.Body = ""
+		message.Redacted = true
+	}
+
+	key := historyKey(number, message.Timestamp, message.ID)
+	if err := u.put(ctx, key, message); err != nil {
+		return err
+	}
+
+	if message.ID != "" {
+		if err := u.store.Set(ctx, historyIDKeyPrefix+message.ID, key, u.retention); err != nil {
+			return fmt.Errorf("failed to index history message: %w", err)
+		}
+	}
+	return nil
+}
+
+// put writes a history message under key, keeping it for the retention period
+func (u *HistoryUseCase) put(ctx context.Context, key string, message HistoryMessage) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to encode history message: %w", err)
+	}
+
+	expiration := u.retention - time.Since(message.Timestamp)
+	if expiration <= 0 {
+		return nil
+	}
+
+	if err := u.store.Set(ctx, key, string(data), expiration); err != nil {
+		return fmt.Errorf("failed to save history message: %w", err)
+	}
+	return nil
+}
+
+// get reads the history message stored under key
+func (u *HistoryUseCase) get(ctx context.Context, key string) (*HistoryMessage, error) {
+	value, err := u.store.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	var message HistoryMessage
+	if err := json.Unmarshal([]byte(value), &message); err != nil {
+		return nil, fmt.Errorf("failed to decode history message: %w", err)
+	}
+	return &message, nil
+}
+
+// historyKey builds the sortable key of a history message
+func historyKey(number string, at time.Time, messageID string) string {
+	return fmt.Sprintf("%s%s:%020d:%s", historyKeyPrefix, number, at.UnixNano(), messageID)
+}
+
+// keyBefore reports whether the history key was stored before the given time
+func keyBefore(key, number string, before time.Time) bool {
+	stamp := strings.TrimPrefix(key, historyKeyPrefix+number+":")
+	if i := strings.Index(stamp, ":"); i >= 0 {
+		stamp = stamp[:i]
+	}
+	return stamp < fmt.Sprintf("%020d", before.UnixNano())
+}
@@ -0,0 +1,64 @@
+package usecases
+
+import (
+	"context"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/store"
+	"go.uber.org/zap"
+)
+
+// inboundKeyPrefix is the state store key prefix for received inbound message IDs
+const inboundKeyPrefix = "whatsapp:inbound:"
+
+// inboundDedupTTL is how long a message ID is remembered
+const inboundDedupTTL = 24 * time.Hour
+
+// InboundDedup drops inbound messages that already arrived through another
+// ingestion path (the direct whatsmeow events or the webhook). Message IDs
+// are claimed in the state store, so whichever path arrives first wins.
+type InboundDedup struct {
+	store  store.Store
+	logger logger.Logger
+}
+
+// NewInboundDedup creates a new InboundDedup
+func NewInboundDedup(stateStore store.Store, logger logger.Logger) *InboundDedup {
+	return &InboundDedup{
+		store:  stateStore,
+		logger: logger,
+	}
+}
+
+// Claim reports whether the message should be processed. It returns false
+// when the message ID was already claimed; messages without an ID are always
+// processed.
+func (d *InboundDedup) Claim(ctx context.Context, messageID, source string) bool {
+	if messageID == "" {
+		return true
+	}
+
+	claimed, err := d.store.SetNX(ctx, inboundKeyPrefix+messageID, source, inboundDedupTTL)
+	if err != nil {
+		d.logger.Warn("Failed to claim inbound message", zap.String("message_id", messageID), zap.Error(err))
+		return true
+	}
+	if !claimed {
+		d.logger.Info("Dropping duplicate inbound message",
+			zap.String("message_id", messageID),
+			zap.String("source", source))
+	}
+	return claimed
+}
+
+// Release forgets a claimed message ID, so a message that failed to be
+// processed is taken again when the sender retries it
+func (d *InboundDedup) Release(ctx context.Context, messageID string) {
+	if messageID == "" {
+		return
+	}
+	if err := d.store.Delete(ctx, inboundKeyPrefix+messageID); err != nil {
+		d.logger.Warn("Failed to release inbound message", zap.String("message_id", messageID), zap.Error(err))
+	}
+}
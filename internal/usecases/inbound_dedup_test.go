@@ -0,0 +1,71 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+)
+
+func TestInboundDedup(t *testing.T) {
+	stateStore := newFakeStore()
+	dedup := NewInboundDedup(stateStore, logger.NewNop())
+	ctx := context.Background()
+
+	// Whichever path claims a message first wins
+	if !dedup.Claim(ctx, "msg-1", "direct") {
+		t.Fatal("first claim = false, want true")
+	}
+	if dedup.Claim(ctx, "msg-1", "webhook") {
+		t.Error("claim from the other path = true, want the duplicate dropped")
+	}
+	if dedup.Claim(ctx, "msg-1", "direct") {
+		t.Error("repeated claim = true, want the duplicate dropped")
+	}
+	if !dedup.Claim(ctx, "msg-2", "webhook") {
+		t.Error("claim of another message = false, want true")
+	}
+	if got, _ := stateStore.Get(ctx, inboundKeyPrefix+"msg-1"); got != "direct" {
+		t.Errorf("msg-1 claimed by %q, want direct", got)
+	}
+
+	// Messages without an ID cannot be matched and are always processed
+	if !dedup.Claim(ctx, "", "webhook") || !dedup.Claim(ctx, "", "direct") {
+		t.Error("claim without a message ID = false, want true")
+	}
+
+	// IDs are forgotten after the TTL
+	if got := stateStore.expiration(inboundKeyPrefix + "msg-1"); got != inboundDedupTTL {
+		t.Errorf("claim expiration = %v, want %v", got, inboundDedupTTL)
+	}
+	stateStore.advance(inboundDedupTTL + time.Second)
+	if !dedup.Claim(ctx, "msg-1", "webhook") {
+		t.Error("claim after the TTL = false, want true")
+	}
+
+	// Without the store a message is processed rather than lost
+	stateStore.fail(errors.New("connection refused"))
+	if !dedup.Claim(ctx, "msg-1", "direct") {
+		t.Error("claim while the store fails = false, want true")
+	}
+}
+
+func TestInboundDedupRelease(t *testing.T) {
+	stateStore := newFakeStore()
+	dedup := NewInboundDedup(stateStore, logger.NewNop())
+	ctx := context.Background()
+
+	if !dedup.Claim(ctx, "msg-1", "webhook") {
+		t.Fatal("first claim = false, want true")
+	}
+	// A message that failed is taken again on the retry
+	dedup.Release(ctx, "msg-1")
+	if !dedup.Claim(ctx, "msg-1", "webhook") {
+		t.Error("claim after the release = false, want the retry processed")
+	}
+	if dedup.Claim(ctx, "msg-1", "direct") {
+		t.Error("claim after the retry = true, want the duplicate dropped")
+	}
+}
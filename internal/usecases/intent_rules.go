@@ -0,0 +1,152 @@
+package usecases
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// ErrInvalidIntentRules is returned when an intent rules file cannot be used
+var ErrInvalidIntentRules = errors.New("invalid intent rules")
+
+// Pattern match modes of an intent rule
+const (
+	MatchExact    = "exact"
+	MatchContains = "contains"
+	MatchRegex    = "regex"
+)
+
+// AnyLocale holds the rules checked for every conversation locale
+const AnyLocale = "*"
+
+// IntentPattern is a keyword pattern matched against the lowercased message
+type IntentPattern struct {
+	Mode    string `json:"mode"`
+	Pattern string `json:"pattern"`
+	regex   *regexp.Regexp
+}
+
+// IntentRule maps a booking intent to the patterns that detect it
+type IntentRule struct {
+	Intent   string          `json:"intent"`
+	Patterns []IntentPattern `json:"patterns"`
+}
+
+// IntentRules are the rules by locale, checked in order. Rules of the
+// conversation locale are checked before the AnyLocale rules.
+type IntentRules map[string][]IntentRule
+
+// DefaultIntentRules returns the built-in rules: any message containing "sí"
+// or "si" confirms and any message containing "no" cancels. English
+// conversations also confirm with "yes" and Portuguese ones cancel with
+// "não" or "nao", the answers their confirmation messages ask for.
+func DefaultIntentRules() IntentRules {
+	return IntentRules{
+		"en": {
+			{Intent: "confirmed", Patterns: []IntentPattern{
+				{Mode: MatchContains, Pattern: "yes"},
+			}},
+		},
+		"pt": {
+			{Intent: "cancelled", Patterns: []IntentPattern{
+				{Mode: MatchContains, Pattern: "não"},
+				{Mode: MatchContains, Pattern: "nao"},
+			}},
+		},
+		AnyLocale: {
+			{Intent: "confirmed", Patterns: []IntentPattern{
+				{Mode: MatchContains, Pattern: "sí"},
+				{Mode: MatchContains, Pattern: "si"},
+			}},
+			{Intent: "cancelled", Patterns: []IntentPattern{
+				{Mode: MatchContains, Pattern: "no"},
+			}},
+		},
+	}
+}
+
+// LoadIntentRules reads intent rules from a JSON file keyed by locale and
+// validates them. An empty path returns the built-in rules.
+func LoadIntentRules(path string) (IntentRules, error) {
+	if path == "" {
+		return DefaultIntentRules(), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read intent rules: %w", err)
+	}
+
+	var rules IntentRules
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIntentRules, err)
+	}
+	if err := rules.compile(); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// compile validates the rules, normalizes keyword patterns and compiles the
+// regular expressions
+func (r IntentRules) compile() error {
+	for locale, rules := range r {
+		for i := range rules {
+			rule := &rules[i]
+			if rule.Intent != "confirmed" && rule.Intent != "cancelled" {
+				return fmt.Errorf("%w: %s: intent %q must be confirmed or cancelled", ErrInvalidIntentRules, locale, rule.Intent)
+			}
+			for j := range rule.Patterns {
+				pattern := &rule.Patterns[j]
+				if pattern.Pattern == "" {
+					return fmt.Errorf("%w: %s: empty pattern for %s", ErrInvalidIntentRules, locale, rule.Intent)
+				}
+				switch pattern.Mode {
+				case MatchExact, MatchContains:
+					pattern.Pattern = strings.ToLower(strings.TrimSpace(pattern.Pattern))
+				case MatchRegex:
+					regex, err := regexp.Compile(pattern.Pattern)
+					if err != nil {
+						return fmt.Errorf("%w: %s: %v", ErrInvalidIntentRules, locale, err)
+					}
+					pattern.regex = regex
+				default:
+					return fmt.Errorf("%w: %s: unknown match mode %q", ErrInvalidIntentRules, locale, pattern.Mode)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Match returns the intent of the first rule matching the message, checking
+// the rules of the locale before the AnyLocale rules
+func (r IntentRules) Match(locale, message string) (string, bool) {
+	normalized := strings.ToLower(strings.TrimSpace(message))
+	for _, key := range []string{locale, AnyLocale} {
+		for _, rule := range r[key] {
+			for _, pattern := range rule.Patterns {
+				if pattern.matches(normalized) {
+					return rule.Intent, true
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+// matches reports whether the pattern matches the normalized message
+func (p IntentPattern) matches(message string) bool {
+	switch p.Mode {
+	case MatchExact:
+		return message == p.Pattern
+	case MatchContains:
+		return strings.Contains(message, p.Pattern)
+	case MatchRegex:
+		return p.regex != nil && p.regex.MatchString(message)
+	}
+	return false
+}
@@ -0,0 +1,150 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+)
+
+// writeIntentRules writes an intent rules file and returns its path
+func writeIntentRules(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "intent_rules.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	return path
+}
+
+// customIntentRules confirms with "dale", "confirmo" or a bare "ok" and
+// cancels with "no gracias"; English conversations cancel with "cancel"
+const customIntentRules = `{
+	"es": [
+		{"intent": "cancelled", "patterns": [{"mode": "contains", "pattern": "No Gracias"}]},
+		{"intent": "confirmed", "patterns": [
+			{"mode": "exact", "pattern": "dale"},
+			{"mode": "contains", "pattern": "confirmo"}
+		]}
+	],
+	"en": [
+		{"intent": "cancelled", "patterns": [{"mode": "regex", "pattern": "^cancel(led)?\\b"}]}
+	],
+	"*": [
+		{"intent": "confirmed", "patterns": [{"mode": "regex", "pattern": "^(ok|okay)[.!]*$"}]}
+	]
+}`
+
+func TestIntentRulesMatch(t *testing.T) {
+	rules, err := LoadIntentRules(writeIntentRules(t, customIntentRules))
+	if err != nil {
+		t.Fatalf("LoadIntentRules() error = %v", err)
+	}
+
+	tests := []struct {
+		locale  string
+		message string
+		want    string
+	}{
+		{locale: "es", message: "Dale", want: "confirmed"},
+		{locale: "es", message: "  dale  ", want: "confirmed"},
+		{locale: "es", message: "dale que sí", want: ""},
+		{locale: "es", message: "Sí, confirmo la hora", want: "confirmed"},
+		{locale: "es", message: "no gracias, confirmo otro día", want: "cancelled"},
+		{locale: "en", message: "Cancelled, sorry", want: "cancelled"},
+		{locale: "en", message: "please don't cancel", want: ""},
+		{locale: "es", message: "cancel", want: ""},
+		{locale: "pt", message: "OK!", want: "confirmed"},
+		{locale: "pt", message: "ok, mas mais tarde", want: ""},
+		// The built-in keywords are replaced, not extended
+		{locale: "es", message: "Sí", want: ""},
+	}
+	for _, tt := range tests {
+		got, ok := rules.Match(tt.locale, tt.message)
+		if got != tt.want || ok != (tt.want != "") {
+			t.Errorf("Match(%s, %q) = %q, %t, want %q", tt.locale, tt.message, got, ok, tt.want)
+		}
+	}
+}
+
+func TestDefaultIntentRules(t *testing.T) {
+	rules, err := LoadIntentRules("")
+	if err != nil {
+		t.Fatalf("LoadIntentRules() error = %v", err)
+	}
+
+	tests := []struct {
+		locale  string
+		message string
+		want    string
+	}{
+		{locale: "es", message: "Sí", want: "confirmed"},
+		{locale: "es", message: "si claro", want: "confirmed"},
+		{locale: "es", message: "No", want: "cancelled"},
+		{locale: "en", message: "Yes", want: "confirmed"},
+		{locale: "es", message: "yes", want: ""},
+		{locale: "pt", message: "Não", want: "cancelled"},
+		{locale: "es", message: "mañana", want: ""},
+	}
+	for _, tt := range tests {
+		if got, _ := rules.Match(tt.locale, tt.message); got != tt.want {
+			t.Errorf("Match(%s, %q) = %q, want %q", tt.locale, tt.message, got, tt.want)
+		}
+	}
+}
+
+func TestLoadInvalidIntentRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "invalid regex", content: `{"es": [{"intent": "confirmed", "patterns": [{"mode": "regex", "pattern": "(ok"}]}]}`},
+		{name: "unknown mode", content: `{"es": [{"intent": "confirmed", "patterns": [{"mode": "prefix", "pattern": "ok"}]}]}`},
+		{name: "unknown intent", content: `{"es": [{"intent": "maybe", "patterns": [{"mode": "exact", "pattern": "ok"}]}]}`},
+		{name: "empty pattern", content: `{"es": [{"intent": "confirmed", "patterns": [{"mode": "exact", "pattern": ""}]}]}`},
+		{name: "malformed JSON", content: `{"es": [`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadIntentRules(writeIntentRules(t, tt.content)); !errors.Is(err, ErrInvalidIntentRules) {
+				t.Errorf("LoadIntentRules() error = %v, want %v", err, ErrInvalidIntentRules)
+			}
+		})
+	}
+
+	if _, err := LoadIntentRules(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("LoadIntentRules() of a missing file succeeded")
+	}
+}
+
+func TestBookingUsesIntentRules(t *testing.T) {
+	rules, err := LoadIntentRules(writeIntentRules(t, customIntentRules))
+	if err != nil {
+		t.Fatalf("LoadIntentRules() error = %v", err)
+	}
+	u := NewBookingUseCase(newTestClient(t), logger.NewNop(),
+		WithBookingStore(newFakeStore()),
+		WithDefaultLocale("es"),
+		WithIntentRules(rules))
+	ctx := context.Background()
+
+	for _, tt := range []struct{ body, want string }{
+		{body: "Dale", want: "confirmed"},
+		{body: "No gracias", want: "cancelled"},
+		{body: "Sí", want: "unknown"},
+	} {
+		if _, err := u.SendConfirmationMessage(ctx, testBooking("booking-1")); err != nil {
+			t.Fatalf("SendConfirmationMessage() error = %v", err)
+		}
+		response, err := u.ProcessIncomingResponse(ctx, testPhone, tt.body, "")
+		if err != nil {
+			t.Fatalf("ProcessIncomingResponse(%q) error = %v", tt.body, err)
+		}
+		if response.Status != tt.want {
+			t.Errorf("%q: status = %q, want %q", tt.body, response.Status, tt.want)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package usecases
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/auth"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// apiKeyUserID is the user_id of the tokens issued for the API key
+const apiKeyUserID = "api_key"
+
+// ErrInvalidCredentials is returned when a login does not match the
+// configured credentials
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// ErrRevocationDisabled is returned when revoking a token without a
+// revocation list
+var ErrRevocationDisabled = errors.New("token revocation is disabled")
+
+// ErrInvalidToken is returned when revoking a token that is malformed,
+// wrongly signed or already expired
+var ErrInvalidToken = errors.New("invalid token")
+
+// LoginRequest holds the credentials of a login: an API key, or a username
+// and password
+type LoginRequest struct {
+	APIKey   string
+	Username string
+	Password string
+}
+
+// LoginResult is the JWT issued for a login
+type LoginResult struct {
+	Token     string    `json:"token"`
+	TokenType string    `json:"token_type"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// LoginUseCase exchanges the configured credentials for JWTs
+type LoginUseCase struct {
+	apiKey   string
+	username string
+	password string
+	// revocations holds the tokens revoked before they expire
+	revocations *auth.RevocationList
+	logger      logger.Logger
+}
+
+// LoginUseCaseOption is a function that configures a LoginUseCase
+type LoginUseCaseOption func(*LoginUseCase)
+
+// WithLoginAPIKey accepts the API key as a login
+func WithLoginAPIKey(apiKey string) LoginUseCaseOption {
+	return func(u *LoginUseCase) {
+		u.apiKey = apiKey
+	}
+}
+
+// WithLoginPassword accepts the username and password as a login
+func WithLoginPassword(username, password string) LoginUseCaseOption {
+	return func(u *LoginUseCase) {
+		u.username = username
+		u.password = password
+	}
+}
+
+// WithRevocationList allows revoking tokens before they expire
+func WithRevocationList(revocations *auth.RevocationList) LoginUseCaseOption {
+	return func(u *LoginUseCase) {
+		u.revocations = revocations
+	}
+}
+
+// NewLoginUseCase creates a new LoginUseCase. Without credentials every
+// login fails.
+func NewLoginUseCase(logger logger.Logger, options ...LoginUseCaseOption) *LoginUseCase {
+	useCase := &LoginUseCase{logger: logger}
+	for _, option := range options {
+		option(useCase)
+	}
+	return useCase
+}
+
+// Login validates the credentials and issues a JWT whose user_id is the
+// username, or "api_key" for the API key
+func (u *LoginUseCase) Login(request LoginRequest) (*LoginResult, error) {
+	var userID string
+	switch {
+	case request.APIKey != "":
+		if u.apiKey == "" || !secureEqual(request.APIKey, u.apiKey) {
+			u.logger.Warn("Rejected login with an invalid API key")
+			return nil, ErrInvalidCredentials
+		}
+		userID = apiKeyUserID
+	case request.Username != "":
+		// Compare both fields even when the username is wrong, so the time
+		// taken does not reveal it
+		userOK := secureEqual(request.Username, u.username)
+		passwordOK := secureEqual(request.Password, u.password)
+		if u.username == "" || !userOK || !passwordOK {
+			u.logger.Warn("Rejected login with invalid credentials", zap.String("username", request.Username))
+			return nil, ErrInvalidCredentials
+		}
+		userID = request.Username
+	default:
+		return nil, ErrInvalidCredentials
+	}
+
+	token, err := auth.GenerateToken(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+	claims, err := auth.ValidateToken(token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read generated token: %w", err)
+	}
+
+	u.logger.Info("Issued login token", zap.String("user_id", userID))
+	return &LoginResult{
+		Token:     token,
+		TokenType: "Bearer",
+		ExpiresAt: claims.ExpiresAt.Time,
+	}, nil
+}
+
+// Revoke revokes a token until it expires, so it is rejected even though
+// its signature is still valid. Revoking a token twice is harmless.
+func (u *LoginUseCase) Revoke(ctx context.Context, token, actor string) error {
+	if u.revocations == nil {
+		return ErrRevocationDisabled
+	}
+	claims, err := auth.ValidateToken(token)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if claims.ID == "" {
+		// Tokens issued before they carried a jti cannot be tracked
+		return fmt.Errorf("%w: token has no jti and cannot be revoked", ErrInvalidToken)
+	}
+	if err := u.revocations.RevokeClaims(ctx, claims); err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+
+	u.logger.Info("Revoked token",
+		zap.String("token_id", claims.ID),
+		zap.String("user_id", claims.UserID),
+		zap.String("actor", actor))
+	return nil
+}
+
+// secureEqual compares two secrets in constant time
+func secureEqual(given, expected string) bool {
+	return subtle.ConstantTimeCompare([]byte(given), []byte(expected)) == 1
+}
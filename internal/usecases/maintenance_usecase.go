@@ -0,0 +1,124 @@
+package usecases
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/store"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/whatsapp"
+	"go.uber.org/zap"
+)
+
+// Maintenance state store keys. The flag is shared by all replicas; inbound
+// messages received during maintenance are keyed by arrival time so they are
+// processed in order on exit.
+const (
+	maintenanceKey              = "whatsapp:maintenance"
+	maintenanceInboundKeyPrefix = "whatsapp:maintenance_inbound:"
+)
+
+// InboundHandler processes an inbound message
+type InboundHandler func(ctx context.Context, msg *whatsapp.WhatsAppMessage)
+
+// MaintenanceUseCase toggles maintenance mode, during which sends are
+// rejected and inbound messages are deferred until maintenance ends
+type MaintenanceUseCase struct {
+	store   store.Store
+	logger  logger.Logger
+	handler InboundHandler
+}
+
+// NewMaintenanceUseCase creates a new MaintenanceUseCase. Deferred inbound
+// messages are passed to handler when maintenance is disabled.
+func NewMaintenanceUseCase(stateStore store.Store, logger logger.Logger, handler InboundHandler) *MaintenanceUseCase {
+	return &MaintenanceUseCase{
+		store:   stateStore,
+		logger:  logger,
+		handler: handler,
+	}
+}
+
+// Enabled reports whether maintenance mode is on
+func (u *MaintenanceUseCase) Enabled(ctx context.Context) bool {
+	_, err := u.store.Get(ctx, maintenanceKey)
+	return err == nil
+}
+
+// SetEnabled turns maintenance mode on or off. Turning it off processes the
+// inbound messages deferred while it was on.
+func (u *MaintenanceUseCase) SetEnabled(ctx context.Context, enabled bool) error {
+	if enabled {
+		if err := u.store.Set(ctx, maintenanceKey, time.Now().Format(time.RFC3339), 0); err != nil {
+			return fmt.Errorf("failed to enable maintenance mode: %w", err)
+		}
+		u.logger.Warn("Maintenance mode enabled")
+		return nil
+	}
+
+	if err := u.store.Delete(ctx, maintenanceKey); err != nil {
+		return fmt.Errorf("failed to disable maintenance mode: %w", err)
+	}
+	u.logger.Info("Maintenance mode disabled")
+
+	go func() {
+		processed, err := u.Drain(context.Background())
+		if err != nil {
+			u.logger.Error("Failed to process deferred inbound messages", zap.Error(err))
+		}
+		if processed > 0 {
+			u.logger.Info("Processed deferred inbound messages", zap.Int("count", processed))
+		}
+	}()
+	return nil
+}
+
+// Defer stores an inbound message to be processed when maintenance ends
+func (u *MaintenanceUseCase) Defer(ctx context.Context, msg *whatsapp.WhatsAppMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to encode inbound message: %w", err)
+	}
+
+	key := fmt.Sprintf("%s%020d:%s", maintenanceInboundKeyPrefix, time.Now().UnixNano(), msg.ID)
+	if err := u.store.Set(ctx, key, string(data), 0); err != nil {
+		return fmt.Errorf("failed to defer inbound message: %w", err)
+	}
+	return nil
+}
+
+// Drain processes the deferred inbound messages in arrival order and
+// returns how many were processed
+func (u *MaintenanceUseCase) Drain(ctx context.Context) (int, error) {
+	keys, err := u.store.Keys(ctx, maintenanceInboundKeyPrefix+"*")
+	if err != nil {
+		return 0, fmt.Errorf("failed to list deferred inbound messages: %w", err)
+	}
+	sort.Strings(keys)
+
+	processed := 0
+	for _, key := range keys {
+		// Take is atomic, so each message is processed by a single replica
+		value, err := u.store.Take(ctx, key)
+		if errors.Is(err, store.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return processed, fmt.Errorf("failed to take deferred inbound message: %w", err)
+		}
+
+		var msg whatsapp.WhatsAppMessage
+		if err := json.Unmarshal([]byte(value), &msg); err != nil {
+			u.logger.Warn("Dropping undecodable deferred inbound message", zap.Error(err))
+			continue
+		}
+
+		u.handler(ctx, &msg)
+		processed++
+	}
+	return processed, nil
+}
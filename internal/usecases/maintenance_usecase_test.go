@@ -0,0 +1,116 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/whatsapp"
+)
+
+// handledMessages records the IDs of the inbound messages handled
+type handledMessages struct {
+	mu  sync.Mutex
+	ids []string
+}
+
+func (h *handledMessages) handle(ctx context.Context, msg *whatsapp.WhatsAppMessage) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.ids = append(h.ids, msg.ID)
+}
+
+func (h *handledMessages) all() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return slices.Clone(h.ids)
+}
+
+func TestMaintenanceSharedAcrossReplicas(t *testing.T) {
+	stateStore := newFakeStore()
+	first := NewMaintenanceUseCase(stateStore, logger.NewNop(), func(context.Context, *whatsapp.WhatsAppMessage) {})
+	second := NewMaintenanceUseCase(stateStore, logger.NewNop(), func(context.Context, *whatsapp.WhatsAppMessage) {})
+	ctx := context.Background()
+
+	if first.Enabled(ctx) {
+		t.Fatal("maintenance enabled by default")
+	}
+	if err := first.SetEnabled(ctx, true); err != nil {
+		t.Fatalf("SetEnabled(true) error = %v", err)
+	}
+	if !second.Enabled(ctx) {
+		t.Error("other replica does not see maintenance enabled")
+	}
+	if err := second.SetEnabled(ctx, false); err != nil {
+		t.Fatalf("SetEnabled(false) error = %v", err)
+	}
+	if first.Enabled(ctx) {
+		t.Error("maintenance still enabled after another replica disabled it")
+	}
+}
+
+func TestMaintenanceProcessesDeferredOnExit(t *testing.T) {
+	handled := &handledMessages{}
+	u := NewMaintenanceUseCase(newFakeStore(), logger.NewNop(), handled.handle)
+	ctx := context.Background()
+
+	if err := u.SetEnabled(ctx, true); err != nil {
+		t.Fatalf("SetEnabled(true) error = %v", err)
+	}
+	for i := range 3 {
+		msg := &whatsapp.WhatsAppMessage{ID: fmt.Sprintf("msg-%d", i), From: testPhone, Body: "Sí"}
+		if err := u.Defer(ctx, msg); err != nil {
+			t.Fatalf("Defer() error = %v", err)
+		}
+	}
+	if got := handled.all(); len(got) != 0 {
+		t.Fatalf("handled %v during maintenance, want none", got)
+	}
+
+	if err := u.SetEnabled(ctx, false); err != nil {
+		t.Fatalf("SetEnabled(false) error = %v", err)
+	}
+	want := []string{"msg-0", "msg-1", "msg-2"}
+	eventually(t, func() bool { return slices.Equal(handled.all(), want) })
+
+	// Processed messages are gone
+	if processed, err := u.Drain(ctx); processed != 0 || err != nil {
+		t.Errorf("second Drain() = %d, %v, want 0", processed, err)
+	}
+}
+
+func TestMaintenanceDrainOncePerMessage(t *testing.T) {
+	stateStore := newFakeStore()
+	handled := &handledMessages{}
+	ctx := context.Background()
+	for i := range 20 {
+		u := NewMaintenanceUseCase(stateStore, logger.NewNop(), handled.handle)
+		if err := u.Defer(ctx, &whatsapp.WhatsAppMessage{ID: fmt.Sprintf("msg-%02d", i)}); err != nil {
+			t.Fatalf("Defer() error = %v", err)
+		}
+	}
+
+	// Every replica drains when maintenance ends
+	var wg sync.WaitGroup
+	for range 3 {
+		u := NewMaintenanceUseCase(stateStore, logger.NewNop(), handled.handle)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := u.Drain(ctx); err != nil {
+				t.Errorf("Drain() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	got := handled.all()
+	slices.Sort(got)
+	total := len(got)
+	if distinct := len(slices.Compact(got)); total != 20 || distinct != 20 {
+		t.Errorf("handled %d messages (%d distinct), want each of 20 once", total, distinct)
+	}
+}
@@ -0,0 +1,65 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/whatsapp"
+)
+
+// TraceStageMedia records an attachment received for a booking
+const TraceStageMedia = "media"
+
+// inboundMedia is the attachment of an inbound message
+type inboundMedia struct {
+	messageID string
+	media     whatsapp.InboundMedia
+}
+
+// WithMediaReply configures the acknowledgment of inbound attachments that
+// match no booking response, sent instead of the text fallback. Text
+// replaces the catalog reply, Locale forces its language, and Disabled
+// leaves attachments to the text fallback, or unanswered without a caption.
+func WithMediaReply(reply FallbackReply) BookingUseCaseOption {
+	return func(u *BookingUseCase) {
+		u.mediaReply = reply
+	}
+}
+
+// ProcessIncomingMedia processes an inbound attachment. Its caption is
+// processed as the message body; an attachment without a recognized booking
+// response is acknowledged with the media reply.
+func (u *BookingUseCase) ProcessIncomingMedia(ctx context.Context, phoneNumber, caption, messageID string, media whatsapp.InboundMedia) (*MessageResponse, error) {
+	return u.processIncoming(ctx, phoneNumber, caption, "", &inboundMedia{messageID: messageID, media: media}, nil)
+}
+
+// mediaAck returns the acknowledgment of an inbound attachment in the
+// conversation locale
+func (u *BookingUseCase) mediaAck(ctx context.Context, locale string, booking *pendingBooking) string {
+	if u.mediaReply.Text != "" {
+		return u.mediaReply.Text
+	}
+	if u.mediaReply.Locale != "" {
+		locale = u.mediaReply.Locale
+	}
+	return u.replyText(ctx, locale, "media_received", booking)
+}
+
+// recordMedia keeps a reference to an attachment received for a pending
+// booking in its trace, so support can find the file
+func (u *BookingUseCase) recordMedia(ctx context.Context, intent Intent, media *inboundMedia) {
+	detail := media.media.Type
+	switch {
+	case media.media.Path != "":
+		detail = fmt.Sprintf("%s %s", media.media.Type, media.media.Path)
+	case media.media.Skipped:
+		detail = fmt.Sprintf("%s skipped: %s", media.media.Type, media.media.SkipReason)
+	}
+	u.recordTrace(ctx, TraceEvent{
+		TraceID:   intent.TraceID,
+		BookingID: intent.BookingID,
+		Stage:     TraceStageMedia,
+		MessageID: media.messageID,
+		Detail:    detail,
+	})
+}
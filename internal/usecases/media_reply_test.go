@@ -0,0 +1,109 @@
+package usecases
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/webhook"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/whatsapp"
+)
+
+// testImage is a downloaded inbound image
+var testImage = whatsapp.InboundMedia{Type: whatsapp.MediaImage, MimeType: "image/jpeg", Size: 2048, Path: "/var/media/msg-1.jpg"}
+
+func TestMediaReply(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []BookingUseCaseOption
+		pending bool
+		caption string
+		media   bool
+		want    string
+	}{
+		{name: "media without a booking", media: true, want: reply("es", "media_received")},
+		{name: "media for a pending booking", pending: true, media: true, want: reply("es", "media_received")},
+		{name: "unrecognized caption", pending: true, caption: "mira esto", media: true, want: reply("es", "media_received")},
+		{name: "text uses the text fallback", pending: true, caption: "mmm", want: reply("es", "unknown")},
+		{name: "text without a booking uses the text fallback", caption: "mmm", want: reply("es", "no_booking")},
+		{name: "recognized caption answers the booking", pending: true, caption: "Sí", media: true, want: reply("es", "confirmed")},
+		{name: "conversation locale", options: []BookingUseCaseOption{WithDefaultLocale("pt")}, media: true, want: reply("pt", "media_received")},
+		{name: "forced locale", options: []BookingUseCaseOption{WithMediaReply(FallbackReply{Locale: "en"})}, media: true, want: reply("en", "media_received")},
+		{name: "custom text", options: []BookingUseCaseOption{WithMediaReply(FallbackReply{Text: "Archivo recibido"})}, media: true, want: "Archivo recibido"},
+		{name: "disabled without a caption", options: []BookingUseCaseOption{WithMediaReply(FallbackReply{Disabled: true})}, media: true},
+		{name: "disabled with a caption uses the text fallback", options: []BookingUseCaseOption{WithMediaReply(FallbackReply{Disabled: true})}, pending: true, caption: "mira esto", media: true, want: reply("es", "unknown")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t)
+			sent := captureSends(client)
+			u := NewBookingUseCase(client, logger.NewNop(), append([]BookingUseCaseOption{WithBookingStore(newFakeStore())}, tt.options...)...)
+			ctx := context.Background()
+			if tt.pending {
+				if _, err := u.SendConfirmationMessage(ctx, testBooking("booking-1")); err != nil {
+					t.Fatalf("SendConfirmationMessage() error = %v", err)
+				}
+			}
+			before := len(sent.all())
+
+			var err error
+			if tt.media {
+				_, err = u.ProcessIncomingMedia(ctx, testPhone, tt.caption, "msg-1", testImage)
+			} else {
+				_, err = u.ProcessIncomingResponse(ctx, testPhone, tt.caption, "")
+			}
+			if err != nil {
+				t.Fatalf("processing error = %v", err)
+			}
+
+			texts := sent.texts()[before:]
+			if tt.want == "" {
+				if len(texts) != 0 {
+					t.Errorf("sent %q, want no reply", texts)
+				}
+				return
+			}
+			if len(texts) != 1 || texts[0] != tt.want {
+				t.Errorf("sent %q, want %q", texts, tt.want)
+			}
+		})
+	}
+}
+
+func TestMediaReferenceIsKept(t *testing.T) {
+	url, posted := newEventServer(t, http.StatusOK)
+	stateStore := newFakeStore()
+	trace := NewTraceUseCase(stateStore, logger.NewNop(), time.Hour)
+	u := NewBookingUseCase(newTestClient(t), logger.NewNop(),
+		WithBookingStore(stateStore),
+		WithTrace(trace),
+		WithExternalReply(webhook.NewNotifier(url)))
+	ctx := context.Background()
+
+	if _, err := u.SendConfirmationMessage(ctx, testBooking("booking-1")); err != nil {
+		t.Fatalf("SendConfirmationMessage() error = %v", err)
+	}
+	if _, err := u.ProcessIncomingMedia(ctx, testPhone, "", "msg-1", testImage); err != nil {
+		t.Fatalf("ProcessIncomingMedia() error = %v", err)
+	}
+
+	// The integrator receives the attachment with the intent
+	_, intent := nextEvent(t, posted)
+	if intent.Media == nil || intent.Media.Path != testImage.Path || intent.BookingID != "booking-1" {
+		t.Errorf("posted %+v, want the media of booking-1", intent)
+	}
+
+	// And support finds it in the booking trace
+	var lifecycle *BookingTrace
+	eventually(t, func() bool {
+		var err error
+		lifecycle, err = u.Trace(ctx, "booking-1")
+		return err == nil && stageIndex(lifecycle.Events, TraceStageMedia) >= 0
+	})
+	event := lifecycle.Events[stageIndex(lifecycle.Events, TraceStageMedia)]
+	if event.MessageID != "msg-1" || event.Detail != "image /var/media/msg-1.jpg" {
+		t.Errorf("media event = %+v, want msg-1 with its path", event)
+	}
+}
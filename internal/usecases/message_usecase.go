@@ -0,0 +1,124 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/utils"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/whatsapp"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// ErrInvalidMessage is returned when a raw message is malformed or of a disallowed type
+var ErrInvalidMessage = errors.New("invalid message")
+
+// allowedRawMessageFields lists the waE2E.Message fields integrators may send.
+// Protocol-level messages (revokes, key distribution, settings) are excluded.
+var allowedRawMessageFields = map[protoreflect.Name]bool{
+	"conversation":        true,
+	"extendedTextMessage": true,
+	"imageMessage":        true,
+	"videoMessage":        true,
+	"audioMessage":        true,
+	"documentMessage":     true,
+	"stickerMessage":      true,
+	"locationMessage":     true,
+	"contactMessage":      true,
+	"buttonsMessage":      true,
+	"listMessage":         true,
+	"templateMessage":     true,
+	"interactiveMessage":  true,
+	"reactionMessage":     true,
+	"messageContextInfo":  true,
+}
+
+// SendResult represents the result of a sent message
+type SendResult struct {
+	MessageID string    `json:"message_id"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// MessageUseCase handles generic message sending
+type MessageUseCase struct {
+	client      *whatsapp.Client
+	logger      logger.Logger
+	phoneRegion string
+
+	bulkMaxRecipients int
+	bulkSendDelay     time.Duration
+}
+
+// NewMessageUseCase creates a new MessageUseCase
+func NewMessageUseCase(client *whatsapp.Client, logger logger.Logger, phoneRegion string, options ...MessageUseCaseOption) *MessageUseCase {
+	useCase := &MessageUseCase{
+		client:            client,
+		logger:            logger,
+		phoneRegion:       phoneRegion,
+		bulkMaxRecipients: defaultBulkMaxRecipients,
+		bulkSendDelay:     defaultBulkSendDelay,
+	}
+	for _, option := range options {
+		option(useCase)
+	}
+	return useCase
+}
+
+// SendRaw sends a message given as the protojson representation of a waE2E.Message
+func (u *MessageUseCase) SendRaw(ctx context.Context, to string, raw []byte) (*SendResult, error) {
+	phoneNumber, err := utils.NormalizePhone(to, u.phoneRegion)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPhoneNumber, err)
+	}
+
+	message := &waE2E.Message{}
+	if err := protojson.Unmarshal(raw, message); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+	}
+	if err := validateRawMessage(message); err != nil {
+		return nil, err
+	}
+
+	jid := types.NewJID(phoneNumber, types.DefaultUserServer)
+	resp, err := u.client.Send(ctx, jid, message)
+	if err != nil {
+		u.logger.Error("Failed to send raw message", zap.Error(err))
+		return nil, fmt.Errorf("failed to send raw message: %w", err)
+	}
+
+	return &SendResult{
+		MessageID: resp.ID,
+		Timestamp: resp.Timestamp,
+	}, nil
+}
+
+// validateRawMessage checks that the message only sets allowed fields and
+// carries some content
+func validateRawMessage(message *waE2E.Message) error {
+	var content bool
+	var disallowed protoreflect.Name
+	message.ProtoReflect().Range(func(fd protoreflect.FieldDescriptor, _ protoreflect.Value) bool {
+		if !allowedRawMessageFields[fd.Name()] {
+			disallowed = fd.Name()
+			return false
+		}
+		if fd.Name() != "messageContextInfo" {
+			content = true
+		}
+		return true
+	})
+
+	if disallowed != "" {
+		return fmt.Errorf("%w: message type %q is not allowed", ErrInvalidMessage, disallowed)
+	}
+	if !content {
+		return fmt.Errorf("%w: message has no content", ErrInvalidMessage)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package usecases
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// qrCodeLifetime is how long WhatsApp keeps the first QR code of a connection valid
+const qrCodeLifetime = 60 * time.Second
+
+// qrRefreshClient is the part of the WhatsApp client driven by the refresh loop
+type qrRefreshClient interface {
+	IsLoggedIn() bool
+	IsConnected() bool
+	ConnectAndWait(ctx context.Context) error
+	GetQRChannel(ctx context.Context) <-chan string
+	Disconnect() error
+}
+
+// StartQRRefresh keeps a current QR code cached while logged out, so
+// GenerateQR returns instantly. It holds an open WhatsApp connection and
+// stops once the device is paired or the context is cancelled.
+func (u *WhatsAppAuthUseCase) StartQRRefresh(ctx context.Context) {
+	u.refreshMu.Lock()
+	u.autoRefresh = true
+	u.refreshMu.Unlock()
+
+	go u.qrRefreshLoop(ctx)
+}
+
+// qrRefreshLoop caches every QR code received and reconnects to obtain a
+// new one when the cached code expires
+func (u *WhatsAppAuthUseCase) qrRefreshLoop(ctx context.Context) {
+	defer func() {
+		u.refreshMu.Lock()
+		u.autoRefresh = false
+		u.refreshedQR = ""
+		u.refreshMu.Unlock()
+	}()
+
+	client := u.refreshClient
+	ticker := time.NewTicker(u.refreshTick)
+	defer ticker.Stop()
+
+	var lastCode time.Time
+	for {
+		if client.IsLoggedIn() {
+			u.logger.Info("Device paired, stopping QR refresh loop")
+			return
+		}
+
+		if !client.IsConnected() {
+			connectCtx, cancel := context.WithTimeout(ctx, u.qrTimeout)
+			err := client.ConnectAndWait(connectCtx)
+			cancel()
+			if err != nil {
+				u.logger.Warn("QR refresh loop failed to connect", zap.Error(err))
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(5 * time.Second):
+				}
+				continue
+			}
+			lastCode = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+
+		case code := <-client.GetQRChannel(ctx):
+			if code == "" {
+				continue
+			}
+			lastCode = time.Now()
+			u.refreshMu.Lock()
+			u.refreshedQR = code
+			u.refreshedAt = lastCode
+			u.refreshMu.Unlock()
+			u.logger.Info("QR code refreshed")
+
+		case <-ticker.C:
+			// Once the code expired, reconnect so WhatsApp issues a fresh one
+			if time.Since(lastCode) > u.qrLifetime && !client.IsLoggedIn() {
+				u.logger.Info("QR code expired, reconnecting for a new one")
+				if err := client.Disconnect(); err != nil {
+					u.logger.Warn("QR refresh loop failed to disconnect", zap.Error(err))
+				}
+			}
+		}
+	}
+}
+
+// refreshedQRCode returns the cached QR code while the refresh loop runs.
+// The second value reports whether the loop is running.
+func (u *WhatsAppAuthUseCase) refreshedQRCode() (string, bool) {
+	u.refreshMu.RLock()
+	defer u.refreshMu.RUnlock()
+
+	if !u.autoRefresh {
+		return "", false
+	}
+	if time.Since(u.refreshedAt) > u.qrLifetime {
+		return "", true
+	}
+	return u.refreshedQR, true
+}
+
+// waitRefreshedQR waits for the refresh loop to cache a valid QR code
+func (u *WhatsAppAuthUseCase) waitRefreshedQR(ctx context.Context) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.qrTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		if code, _ := u.refreshedQRCode(); code != "" {
+			return code, nil
+		}
+		select {
+		case <-ctx.Done():
+			return "", ErrQRTimeout
+		case <-ticker.C:
+		}
+	}
+}
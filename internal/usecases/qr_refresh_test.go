@@ -0,0 +1,123 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+)
+
+// fakeQRClient issues a new QR code on every connection
+type fakeQRClient struct {
+	loggedIn    atomic.Bool
+	connected   atomic.Bool
+	connects    atomic.Int32
+	disconnects atomic.Int32
+	codes       chan string
+	// hold blocks the connections after the first one until it is closed
+	hold chan struct{}
+}
+
+func newFakeQRClient() *fakeQRClient {
+	return &fakeQRClient{codes: make(chan string, 1), hold: make(chan struct{})}
+}
+
+func (c *fakeQRClient) IsLoggedIn() bool  { return c.loggedIn.Load() }
+func (c *fakeQRClient) IsConnected() bool { return c.connected.Load() }
+
+func (c *fakeQRClient) ConnectAndWait(ctx context.Context) error {
+	n := c.connects.Add(1)
+	if n > 1 {
+		select {
+		case <-c.hold:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	c.connected.Store(true)
+	c.codes <- fmt.Sprintf("code-%d", n)
+	return nil
+}
+
+func (c *fakeQRClient) GetQRChannel(ctx context.Context) <-chan string { return c.codes }
+
+func (c *fakeQRClient) Disconnect() error {
+	c.disconnects.Add(1)
+	c.connected.Store(false)
+	return nil
+}
+
+// startQRRefresh runs the refresh loop on the fake client with a short QR
+// code lifetime
+func startQRRefresh(t *testing.T, client *fakeQRClient) *WhatsAppAuthUseCase {
+	t.Helper()
+	u := NewWhatsAppAuthUseCase(nil, logger.NewNop(), WithQRTimeout(time.Second))
+	u.refreshClient = client
+	u.qrLifetime = 100 * time.Millisecond
+	u.refreshTick = 5 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	u.StartQRRefresh(ctx)
+	return u
+}
+
+func TestQRRefreshReplacesExpiredCode(t *testing.T) {
+	client := newFakeQRClient()
+	u := startQRRefresh(t, client)
+
+	eventually(t, func() bool {
+		code, _ := u.refreshedQRCode()
+		return code == "code-1"
+	})
+
+	// Once expired the code is no longer served, and the loop reconnects
+	eventually(t, func() bool {
+		code, running := u.refreshedQRCode()
+		return code == "" && running && client.connects.Load() == 2
+	})
+	if got := client.disconnects.Load(); got != 1 {
+		t.Errorf("disconnects = %d, want 1", got)
+	}
+
+	close(client.hold)
+	eventually(t, func() bool {
+		code, _ := u.refreshedQRCode()
+		return code == "code-2"
+	})
+
+	code, err := u.waitRefreshedQR(context.Background())
+	if err != nil || code != "code-2" {
+		t.Errorf("waitRefreshedQR() = %q, %v, want code-2", code, err)
+	}
+}
+
+func TestQRRefreshStopsAfterPairing(t *testing.T) {
+	client := newFakeQRClient()
+	u := startQRRefresh(t, client)
+
+	eventually(t, func() bool {
+		code, _ := u.refreshedQRCode()
+		return code == "code-1"
+	})
+
+	client.loggedIn.Store(true)
+	eventually(t, func() bool {
+		_, running := u.refreshedQRCode()
+		return !running
+	})
+
+	// A stopped loop neither reconnects nor keeps the last code
+	time.Sleep(200 * time.Millisecond)
+	if got := client.connects.Load(); got != 1 {
+		t.Errorf("connects = %d, want 1", got)
+	}
+	u.refreshMu.RLock()
+	defer u.refreshMu.RUnlock()
+	if u.refreshedQR != "" {
+		t.Errorf("refreshedQR = %q, want empty", u.refreshedQR)
+	}
+}
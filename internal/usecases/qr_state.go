@@ -0,0 +1,54 @@
+package usecases
+
+import (
+	"time"
+)
+
+// QR code states reported by QRState
+const (
+	QRStateNone      = "none"
+	QRStatePending   = "pending"
+	QRStateCached    = "cached"
+	QRStateExpired   = "expired"
+	QRStateConnected = "connected"
+)
+
+// QRState is the state of the QR code, reported without generating one so a
+// UI can poll it
+type QRState struct {
+	State string `json:"state"`
+	// ExpiresInSeconds is how long the cached code stays valid
+	ExpiresInSeconds int `json:"expires_in_seconds,omitempty"`
+}
+
+// QRState reports whether a QR code is cached and still valid, being
+// generated, or expired and has to be requested again. A paired device
+// reports connected and a service that never generated a code reports none.
+func (u *WhatsAppAuthUseCase) QRState() QRState {
+	if u.client.IsLoggedIn() {
+		return QRState{State: QRStateConnected}
+	}
+
+	u.refreshMu.RLock()
+	issuedAt := u.qrIssuedAt
+	if u.autoRefresh && u.refreshedAt.After(issuedAt) {
+		issuedAt = u.refreshedAt
+	}
+	u.refreshMu.RUnlock()
+
+	if !issuedAt.IsZero() {
+		if remaining := u.qrLifetime - time.Since(issuedAt); remaining > 0 {
+			return QRState{
+				State:            QRStateCached,
+				ExpiresInSeconds: max(int(remaining.Round(time.Second)/time.Second), 1),
+			}
+		}
+	}
+	if u.qrPending.Load() > 0 {
+		return QRState{State: QRStatePending}
+	}
+	if !issuedAt.IsZero() {
+		return QRState{State: QRStateExpired}
+	}
+	return QRState{State: QRStateNone}
+}
@@ -0,0 +1,55 @@
+package usecases
+
+import (
+	"context"
+	"time"
+)
+
+// QR status values
+const (
+	QRStatusNeedsQR      = "needs_qr"
+	QRStatusConnected    = "connected"
+	QRStatusReconnecting = "reconnecting"
+)
+
+// QRStatus is the pairing state together with the QR code to show while the
+// device is not paired, so a single poll drives a pairing UI
+type QRStatus struct {
+	Status string `json:"status"`
+	Code   string `json:"code,omitempty"`
+	// ExpiresInSeconds is how long the code stays valid
+	ExpiresInSeconds int    `json:"expires_in_seconds,omitempty"`
+	Phone            string `json:"phone,omitempty"`
+}
+
+// QRStatus returns the pairing state. A paired device reports connected, or
+// reconnecting while its connection is down; no code is generated for it.
+// An unpaired device reports reconnecting while the connection recovers
+// mid-pairing, and needs_qr with the current code otherwise.
+func (u *WhatsAppAuthUseCase) QRStatus(ctx context.Context) (QRStatus, error) {
+	client := u.streamClient
+	if client.IsLoggedIn() {
+		status := QRStatusConnected
+		if !client.IsConnected() {
+			status = QRStatusReconnecting
+		}
+		return QRStatus{Status: status, Phone: client.GetPhoneNumber()}, nil
+	}
+	if client.IsReconnecting() {
+		return QRStatus{Status: QRStatusReconnecting}, nil
+	}
+
+	code, err := u.GenerateQR(ctx)
+	if err != nil {
+		return QRStatus{}, err
+	}
+
+	u.refreshMu.RLock()
+	remaining := u.qrLifetime - time.Since(u.qrIssuedAt)
+	u.refreshMu.RUnlock()
+	return QRStatus{
+		Status:           QRStatusNeedsQR,
+		Code:             code,
+		ExpiresInSeconds: max(int(remaining.Round(time.Second)/time.Second), 1),
+	}, nil
+}
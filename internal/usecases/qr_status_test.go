@@ -0,0 +1,76 @@
+package usecases
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+)
+
+// newQRStatusAuth creates an auth use case whose pairing state comes from
+// the fake client, with its refresh loop serving the QR codes
+func newQRStatusAuth(t *testing.T) (*WhatsAppAuthUseCase, *fakeStreamClient) {
+	t.Helper()
+	client := &fakeStreamClient{fakeQRClient: newFakeQRClient()}
+	u := NewWhatsAppAuthUseCase(newTestClient(t), logger.NewNop(), WithQRTimeout(time.Second))
+	u.refreshClient = client
+	u.streamClient = client
+	u.refreshTick = 5 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	u.StartQRRefresh(ctx)
+	return u, client
+}
+
+func TestQRStatusLoggedOut(t *testing.T) {
+	u, _ := newQRStatusAuth(t)
+
+	status, err := u.QRStatus(context.Background())
+	if err != nil {
+		t.Fatalf("QRStatus() error = %v", err)
+	}
+	if status.Status != QRStatusNeedsQR || status.Code != "code-1" || status.Phone != "" {
+		t.Errorf("status = %+v, want needs_qr with code-1", status)
+	}
+	if status.ExpiresInSeconds < 55 || status.ExpiresInSeconds > 60 {
+		t.Errorf("expires in %ds, want about a minute", status.ExpiresInSeconds)
+	}
+}
+
+func TestQRStatusMidPairing(t *testing.T) {
+	u, client := newQRStatusAuth(t)
+	eventually(t, func() bool { return client.connects.Load() == 1 })
+
+	// The connection dropped while the code was shown
+	blip(client, true)
+	status, err := u.QRStatus(context.Background())
+	if err != nil {
+		t.Fatalf("QRStatus() error = %v", err)
+	}
+	if status.Status != QRStatusReconnecting || status.Code != "" {
+		t.Errorf("status = %+v, want reconnecting without a code", status)
+	}
+}
+
+func TestQRStatusConnected(t *testing.T) {
+	u, client := newQRStatusAuth(t)
+	eventually(t, func() bool { return client.connects.Load() == 1 })
+	client.loggedIn.Store(true)
+	client.connected.Store(true)
+
+	// A paired device gets no code and no error
+	status, err := u.QRStatus(context.Background())
+	if err != nil {
+		t.Fatalf("QRStatus() error = %v", err)
+	}
+	if status.Status != QRStatusConnected || status.Phone != testPhone || status.Code != "" || status.ExpiresInSeconds != 0 {
+		t.Errorf("status = %+v, want connected as %s without a code", status, testPhone)
+	}
+
+	// Its connection recovering reports reconnecting, still without a code
+	client.connected.Store(false)
+	if status, _ = u.QRStatus(context.Background()); status.Status != QRStatusReconnecting || status.Phone != testPhone {
+		t.Errorf("status with the connection down = %+v, want reconnecting as %s", status, testPhone)
+	}
+}
@@ -0,0 +1,115 @@
+package usecases
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// QR stream event types
+const (
+	QRStreamCode         = "qr"
+	QRStreamReconnecting = "reconnecting"
+	QRStreamPaired       = "paired"
+	QRStreamTimeout      = "timeout"
+)
+
+// QRStreamEvent is an update sent to a client following the pairing
+type QRStreamEvent struct {
+	Type  string `json:"type"`
+	Code  string `json:"code,omitempty"`
+	Phone string `json:"phone,omitempty"`
+}
+
+// qrStreamClient is the part of the WhatsApp client followed by the QR stream
+type qrStreamClient interface {
+	qrRefreshClient
+	IsReconnecting() bool
+	GetPhoneNumber() string
+}
+
+// StreamQR emits the current QR code and every fresh one until the device is
+// paired, the QR timeout elapses or the context is cancelled. When the
+// connection drops mid-pairing it emits a reconnecting event and waits for
+// the reconnect loop, or dials again once the loop gave up, then emits the
+// QR code of the new connection instead of ending the stream.
+func (u *WhatsAppAuthUseCase) StreamQR(ctx context.Context, emit func(QRStreamEvent)) error {
+	ctx, cancel := context.WithTimeout(ctx, u.qrTimeout)
+	defer cancel()
+
+	client := u.streamClient
+	ticker := time.NewTicker(u.refreshTick)
+	defer ticker.Stop()
+
+	var shown string
+	var shownAt time.Time
+	var reconnecting bool
+	for {
+		if client.IsLoggedIn() {
+			emit(QRStreamEvent{Type: QRStreamPaired, Phone: client.GetPhoneNumber()})
+			return nil
+		}
+
+		if !client.IsConnected() {
+			// A code was already shown, so the connection blipped mid-pairing
+			if shown != "" && !reconnecting {
+				u.logger.Info("Connection lost during QR stream, waiting to reconnect")
+				emit(QRStreamEvent{Type: QRStreamReconnecting})
+				reconnecting = true
+			}
+			if !client.IsReconnecting() {
+				connectCtx, cancelConnect := context.WithTimeout(ctx, u.qrTimeout)
+				err := client.ConnectAndWait(connectCtx)
+				cancelConnect()
+				if err != nil {
+					u.logger.Warn("QR stream failed to connect", zap.Error(err))
+				}
+			}
+		}
+
+		// The refresh loop consumes the QR channel when it runs
+		_, refreshing := u.refreshedQRCode()
+		qrChan := client.GetQRChannel(ctx)
+		if refreshing {
+			qrChan = nil
+		}
+
+		select {
+		case <-ctx.Done():
+			if ctx.Err() == context.DeadlineExceeded {
+				return ErrQRTimeout
+			}
+			return ctx.Err()
+
+		case code := <-qrChan:
+			if code != "" && code != shown {
+				shown, shownAt, reconnecting = code, time.Now(), false
+				emit(QRStreamEvent{Type: QRStreamCode, Code: code})
+			}
+
+		case <-ticker.C:
+			if refreshing {
+				if code, _ := u.refreshedQRCode(); code != "" && code != shown {
+					shown, shownAt, reconnecting = code, time.Now(), false
+					emit(QRStreamEvent{Type: QRStreamCode, Code: code})
+				}
+				continue
+			}
+
+			// Once the code expired, reconnect so WhatsApp issues a fresh one
+			if shown != "" && !reconnecting && time.Since(shownAt) > u.qrLifetime && client.IsConnected() {
+				u.logger.Info("Streamed QR code expired, reconnecting for a new one")
+				if err := client.Disconnect(); err != nil {
+					u.logger.Warn("QR stream failed to disconnect", zap.Error(err))
+				}
+				connectCtx, cancelConnect := context.WithTimeout(ctx, u.qrTimeout)
+				err := client.ConnectAndWait(connectCtx)
+				cancelConnect()
+				if err != nil {
+					u.logger.Warn("QR stream failed to reconnect", zap.Error(err))
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package usecases
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+)
+
+// fakeStreamClient is a fake QR client whose reconnect loop is driven by
+// the test
+type fakeStreamClient struct {
+	*fakeQRClient
+	reconnecting atomic.Bool
+}
+
+func (c *fakeStreamClient) IsReconnecting() bool   { return c.reconnecting.Load() }
+func (c *fakeStreamClient) GetPhoneNumber() string { return testPhone }
+
+// startQRStream streams the QR codes of the fake client, returning the
+// events emitted and the result of the stream once it ends
+func startQRStream(t *testing.T, client *fakeStreamClient) (<-chan QRStreamEvent, <-chan error) {
+	t.Helper()
+	u := NewWhatsAppAuthUseCase(nil, logger.NewNop(), WithQRTimeout(5*time.Second))
+	u.streamClient = client
+	u.refreshTick = 5 * time.Millisecond
+	u.qrLifetime = time.Hour
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	emitted := make(chan QRStreamEvent, 8)
+	done := make(chan error, 1)
+	go func() {
+		done <- u.StreamQR(ctx, func(event QRStreamEvent) { emitted <- event })
+	}()
+	return emitted, done
+}
+
+// nextStreamEvent waits for the next event of the stream
+func nextStreamEvent(t *testing.T, emitted <-chan QRStreamEvent) QRStreamEvent {
+	t.Helper()
+	select {
+	case event := <-emitted:
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("no QR stream event emitted")
+		return QRStreamEvent{}
+	}
+}
+
+// blip drops the connection of the fake client, with the reconnect loop
+// retrying or not
+func blip(client *fakeStreamClient, reconnecting bool) {
+	client.reconnecting.Store(reconnecting)
+	client.connected.Store(false)
+}
+
+func TestQRStreamSurvivesBlip(t *testing.T) {
+	client := &fakeStreamClient{fakeQRClient: newFakeQRClient()}
+	emitted, done := startQRStream(t, client)
+
+	if event := nextStreamEvent(t, emitted); event.Type != QRStreamCode || event.Code != "code-1" {
+		t.Fatalf("first event = %+v, want code-1", event)
+	}
+
+	// The stream reports the blip and stays open while the loop reconnects
+	blip(client, true)
+	if event := nextStreamEvent(t, emitted); event.Type != QRStreamReconnecting {
+		t.Fatalf("event after the blip = %+v, want reconnecting", event)
+	}
+	select {
+	case event := <-emitted:
+		t.Fatalf("emitted %+v while reconnecting, want nothing", event)
+	case err := <-done:
+		t.Fatalf("stream ended while reconnecting: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if got := client.connects.Load(); got != 1 {
+		t.Errorf("stream connected %d times, want the reconnect loop left to it", got)
+	}
+
+	// The reconnect loop recovers and WhatsApp sends the fresh code
+	client.connected.Store(true)
+	client.reconnecting.Store(false)
+	client.codes <- "code-2"
+	if event := nextStreamEvent(t, emitted); event.Type != QRStreamCode || event.Code != "code-2" {
+		t.Fatalf("event after reconnecting = %+v, want code-2", event)
+	}
+
+	client.loggedIn.Store(true)
+	if event := nextStreamEvent(t, emitted); event.Type != QRStreamPaired || event.Phone != testPhone {
+		t.Errorf("event after pairing = %+v, want paired with %s", event, testPhone)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("StreamQR() error = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("stream still open after pairing")
+	}
+}
+
+func TestQRStreamReconnectsAfterLoopGaveUp(t *testing.T) {
+	client := &fakeStreamClient{fakeQRClient: newFakeQRClient()}
+	emitted, done := startQRStream(t, client)
+
+	if event := nextStreamEvent(t, emitted); event.Type != QRStreamCode || event.Code != "code-1" {
+		t.Fatalf("first event = %+v, want code-1", event)
+	}
+
+	// Without a reconnect loop retrying, the stream dials again itself
+	blip(client, false)
+	if event := nextStreamEvent(t, emitted); event.Type != QRStreamReconnecting {
+		t.Fatalf("event after the blip = %+v, want reconnecting", event)
+	}
+	close(client.hold)
+	if event := nextStreamEvent(t, emitted); event.Type != QRStreamCode || event.Code != "code-2" {
+		t.Fatalf("event after dialing = %+v, want code-2", event)
+	}
+	if got := client.connects.Load(); got != 2 {
+		t.Errorf("stream connected %d times, want 2", got)
+	}
+	select {
+	case err := <-done:
+		t.Errorf("stream ended: %v, want it open until pairing", err)
+	default:
+	}
+}
@@ -0,0 +1,64 @@
+package usecases
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/whatsapp"
+	"go.mau.fi/whatsmeow/types"
+	"go.uber.org/zap"
+)
+
+// ErrJournalEntryNotFound is returned when no recorded event matches the message ID
+var ErrJournalEntryNotFound = errors.New("journal entry not found")
+
+// ReplayUseCase replays recorded inbound events through the handler chain
+type ReplayUseCase struct {
+	client  *whatsapp.Client
+	journal *whatsapp.Journal
+	logger  logger.Logger
+}
+
+// NewReplayUseCase creates a new ReplayUseCase
+func NewReplayUseCase(client *whatsapp.Client, journal *whatsapp.Journal, logger logger.Logger) *ReplayUseCase {
+	return &ReplayUseCase{
+		client:  client,
+		journal: journal,
+		logger:  logger,
+	}
+}
+
+// Replay replays the recorded event with the given message ID. Recorded
+// senders are redacted, so replies are routed to phoneNumber instead.
+func (u *ReplayUseCase) Replay(messageID, phoneNumber string) error {
+	entries, err := u.journal.Entries()
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if entry.Info.ID != messageID {
+			continue
+		}
+
+		evt, err := entry.Event()
+		if err != nil {
+			return err
+		}
+
+		jid := types.NewJID(phoneNumber, types.DefaultUserServer)
+		evt.Info.Sender = jid
+		if !evt.Info.IsGroup {
+			evt.Info.Chat = jid
+		}
+
+		u.logger.Info("Replaying journal entry",
+			zap.String("message_id", messageID),
+			zap.String("phone_number", phoneNumber))
+		u.client.Replay(evt)
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s", ErrJournalEntryNotFound, messageID)
+}
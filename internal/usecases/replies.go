@@ -0,0 +1,174 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/i18n"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/templates"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// localeKeyPrefix is the state store key prefix for the locale of a conversation
+const localeKeyPrefix = "whatsapp:locale:"
+
+// localeTTL is how long a detected conversation locale is remembered
+const localeTTL = 30 * 24 * time.Hour
+
+// Button and list row IDs recognized as booking responses
+const (
+	ResponseConfirm = "booking_confirm"
+	ResponseCancel  = "booking_cancel"
+)
+
+// responseStatus maps a selected button or list row ID to a booking status,
+// or returns an empty string for unrecognized IDs
+func responseStatus(responseID string) string {
+	switch responseID {
+	case ResponseConfirm:
+		return "confirmed"
+	case ResponseCancel:
+		return "cancelled"
+	}
+	return ""
+}
+
+// FallbackReply configures the reply to a message that matches no booking
+// response. Text replaces the built-in reply, Locale forces the reply
+// language, and Disabled keeps the bot silent.
+type FallbackReply struct {
+	Disabled bool
+	Text     string
+	Locale   string
+}
+
+// WithFallbackReplies configures the reply sent when there is no pending
+// booking for the number and the reply sent when the response to a pending
+// booking is ambiguous
+func WithFallbackReplies(noBooking, ambiguous FallbackReply) BookingUseCaseOption {
+	return func(u *BookingUseCase) {
+		u.noBookingReply = noBooking
+		u.ambiguousReply = ambiguous
+	}
+}
+
+// replyTemplatePrefix prefixes the templates that override automatic replies:
+// reply_<status>_<locale> for one locale or reply_<status> for all of them
+const replyTemplatePrefix = "reply_"
+
+// WithReplyTemplates lets templates named reply_<status>_<locale> or
+// reply_<status> (e.g. reply_confirmed_en) replace the built-in automatic
+// replies. Templates can use the variables of the booking being answered.
+func WithReplyTemplates(repository templates.Repository) BookingUseCaseOption {
+	return func(u *BookingUseCase) {
+		u.replyTemplates = repository
+	}
+}
+
+// replyText returns the automatic reply for the status in the given locale,
+// rendered from its template when one is saved and from the catalog otherwise
+func (u *BookingUseCase) replyText(ctx context.Context, locale, status string, booking *pendingBooking) string {
+	if u.replyTemplates == nil {
+		return reply(locale, status)
+	}
+
+	for _, name := range []string{replyTemplatePrefix + status + "_" + locale, replyTemplatePrefix + status} {
+		template, err := u.replyTemplates.Latest(ctx, name)
+		if errors.Is(err, templates.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			u.logger.Warn("Failed to load reply template", zap.String("template", name), zap.Error(err))
+			break
+		}
+
+		text, err := template.Render(replyVariables(booking))
+		if err != nil {
+			// e.g. a booking variable in a reply sent without a booking
+			u.logger.Warn("Failed to render reply template, using the built-in reply",
+				zap.String("template", name),
+				zap.Error(err))
+			break
+		}
+		return text
+	}
+	return reply(locale, status)
+}
+
+// replyVariables returns the values reply templates can use: the booking ID,
+// its details and its metadata as metadata_<key>. Replies sent without a
+// booking have none.
+func replyVariables(booking *pendingBooking) map[string]string {
+	values := make(map[string]string)
+	if booking == nil {
+		return values
+	}
+
+	for key, value := range booking.Metadata {
+		values["metadata_"+key] = value
+	}
+	values["booking_id"] = booking.BookingID
+	values["user_name"] = booking.Details.UserName
+	values["service_name"] = booking.Details.ServiceName
+	values["location_name"] = booking.Details.LocationName
+	values["start_time"] = booking.Details.StartTime
+	values["date"] = booking.Details.Date
+	values["employee_name"] = booking.Details.EmployeeName
+	return values
+}
+
+// fallbackReply returns the reply to an unrecognized message in the
+// conversation locale, or false when the fallback is disabled. Without a
+// booking store every message is treated as a reply to a pending booking.
+func (u *BookingUseCase) fallbackReply(ctx context.Context, locale string, booking *pendingBooking) (string, bool) {
+	fallback, status := u.ambiguousReply, "unknown"
+	if booking == nil && u.store != nil {
+		fallback, status = u.noBookingReply, "no_booking"
+	}
+
+	if fallback.Disabled {
+		return "", false
+	}
+	if fallback.Text != "" {
+		return fallback.Text, true
+	}
+	if fallback.Locale != "" {
+		locale = fallback.Locale
+	}
+	return u.replyText(ctx, locale, status, booking), true
+}
+
+// reply returns the automatic reply for the status in the given locale,
+// falling back to Spanish for unsupported locales. Replies have no
+// placeholders, so only statuses without a reply fail to render.
+func reply(locale, status string) string {
+	text, err := i18n.Render(locale, status, nil)
+	if err != nil {
+		return ""
+	}
+	return text
+}
+
+// resolveLocale picks the reply locale for a message. A confident detection
+// is remembered for the conversation; otherwise the remembered locale or the
+// default locale is used.
+func (u *BookingUseCase) resolveLocale(ctx context.Context, phoneNumber, messageBody string) string {
+	language, confidence := utils.DetectLanguage(messageBody)
+	if language != "" && confidence >= u.languageThreshold {
+		if u.store != nil {
+			if err := u.store.Set(ctx, localeKeyPrefix+phoneNumber, language, localeTTL); err != nil {
+				u.logger.Warn("Failed to save conversation locale", zap.Error(err))
+			}
+		}
+		return language
+	}
+
+	if u.store != nil {
+		if locale, err := u.store.Get(ctx, localeKeyPrefix+phoneNumber); err == nil {
+			return locale
+		}
+	}
+	return u.defaultLocale
+}
@@ -0,0 +1,249 @@
+package usecases
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/templates"
+)
+
+func TestReplyLocaleDetection(t *testing.T) {
+	const otherPhone = "56961234568"
+	stateStore := newFakeStore()
+	u := NewBookingUseCase(newTestClient(t), logger.NewNop(),
+		WithBookingStore(stateStore),
+		WithDefaultLocale("pt"))
+
+	steps := []struct {
+		name  string
+		phone string
+		body  string
+		want  string
+	}{
+		{name: "clearly English", phone: testPhone, body: "Yes, I will be there, thanks", want: "en"},
+		{name: "ambiguous keeps the conversation locale", phone: testPhone, body: "ok", want: "en"},
+		{name: "clearly Spanish switches", phone: testPhone, body: "Sí, muchas gracias, ahí estaré", want: "es"},
+		{name: "ambiguous after switching", phone: testPhone, body: "👍", want: "es"},
+		{name: "ambiguous new conversation uses the default", phone: otherPhone, body: "ok", want: "pt"},
+	}
+	for _, step := range steps {
+		response, err := u.ProcessIncomingResponse(context.Background(), step.phone, step.body, "")
+		if err != nil {
+			t.Fatalf("%s: ProcessIncomingResponse() error = %v", step.name, err)
+		}
+		if response.Locale != step.want {
+			t.Errorf("%s: locale = %q, want %q", step.name, response.Locale, step.want)
+		}
+	}
+
+	if got, _ := stateStore.Get(context.Background(), localeKeyPrefix+testPhone); got != "es" {
+		t.Errorf("persisted locale = %q, want es", got)
+	}
+	if _, err := stateStore.Get(context.Background(), localeKeyPrefix+otherPhone); err == nil {
+		t.Error("locale persisted for an undetected language")
+	}
+}
+
+func TestResponseIDTakesPrecedence(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		responseID string
+		want       string
+	}{
+		{name: "confirm button over text", body: "No", responseID: ResponseConfirm, want: "confirmed"},
+		{name: "cancel row over text", body: "Sí", responseID: ResponseCancel, want: "cancelled"},
+		{name: "unknown ID falls back to text", body: "Sí", responseID: "other_row", want: "confirmed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := NewBookingUseCase(newTestClient(t), logger.NewNop())
+			response, err := u.ProcessIncomingResponse(context.Background(), testPhone, tt.body, tt.responseID)
+			if err != nil {
+				t.Fatalf("ProcessIncomingResponse() error = %v", err)
+			}
+			if response.Status != tt.want {
+				t.Errorf("status = %q, want %q", response.Status, tt.want)
+			}
+		})
+	}
+}
+
+func TestFallbackReplies(t *testing.T) {
+	tests := []struct {
+		name      string
+		pending   bool
+		noBooking FallbackReply
+		ambiguous FallbackReply
+		want      string
+	}{
+		{name: "no booking", want: reply("es", "no_booking")},
+		{name: "ambiguous reply", pending: true, want: reply("es", "unknown")},
+		{name: "no booking disabled", noBooking: FallbackReply{Disabled: true}},
+		{name: "no booking disabled keeps the ambiguous reply", pending: true, noBooking: FallbackReply{Disabled: true}, want: reply("es", "unknown")},
+		{name: "ambiguous disabled", pending: true, ambiguous: FallbackReply{Disabled: true}},
+		{name: "ambiguous disabled keeps the no booking reply", ambiguous: FallbackReply{Disabled: true}, want: reply("es", "no_booking")},
+		{name: "custom no booking text", noBooking: FallbackReply{Text: "Escríbenos a reservas@example.com"}, want: "Escríbenos a reservas@example.com"},
+		{name: "custom ambiguous text", pending: true, ambiguous: FallbackReply{Text: "Responde 1 o 2"}, want: "Responde 1 o 2"},
+		{name: "forced locale", noBooking: FallbackReply{Locale: "en"}, want: reply("en", "no_booking")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t)
+			sent := captureSends(client)
+			u := NewBookingUseCase(client, logger.NewNop(),
+				WithBookingStore(newFakeStore()),
+				WithFallbackReplies(tt.noBooking, tt.ambiguous))
+			ctx := context.Background()
+			if tt.pending {
+				if _, err := u.SendConfirmationMessage(ctx, testBooking("booking-1")); err != nil {
+					t.Fatalf("SendConfirmationMessage() error = %v", err)
+				}
+			}
+			before := len(sent.all())
+
+			response, err := u.ProcessIncomingResponse(ctx, testPhone, "mmm", "")
+			if err != nil {
+				t.Fatalf("ProcessIncomingResponse() error = %v", err)
+			}
+			if response.Status != "unknown" {
+				t.Errorf("status = %q, want unknown", response.Status)
+			}
+			texts := sent.texts()[before:]
+			if tt.want == "" {
+				if len(texts) != 0 {
+					t.Errorf("sent %q, want no reply", texts)
+				}
+				return
+			}
+			if len(texts) != 1 || texts[0] != tt.want {
+				t.Errorf("sent %q, want %q", texts, tt.want)
+			}
+		})
+	}
+}
+
+func TestFallbackWithoutBookingStore(t *testing.T) {
+	// Without a store pending bookings are unknown, so every unrecognized
+	// message is answered as ambiguous
+	client := newTestClient(t)
+	sent := captureSends(client)
+	u := NewBookingUseCase(client, logger.NewNop(),
+		WithFallbackReplies(FallbackReply{Disabled: true}, FallbackReply{Text: "Responde 1 o 2"}))
+
+	if _, err := u.ProcessIncomingResponse(context.Background(), testPhone, "mmm", ""); err != nil {
+		t.Fatalf("ProcessIncomingResponse() error = %v", err)
+	}
+	if texts := sent.texts(); len(texts) != 1 || texts[0] != "Responde 1 o 2" {
+		t.Errorf("sent %q, want the ambiguous reply", texts)
+	}
+}
+
+// newReplyTemplates saves the given reply templates in a new repository
+func newReplyTemplates(t *testing.T, bodies map[string]string) templates.Repository {
+	t.Helper()
+	repository := templates.NewStoreRepository(newFakeStore())
+	for name, body := range bodies {
+		if _, err := repository.Save(context.Background(), name, body); err != nil {
+			t.Fatalf("Save(%s) error = %v", name, err)
+		}
+	}
+	return repository
+}
+
+func TestReplyTemplates(t *testing.T) {
+	repository := newReplyTemplates(t, map[string]string{
+		"reply_confirmed_es": "Gracias {{user_name}}, te esperamos a las {{start_time}} en {{location_name}}",
+		"reply_confirmed":    "Thanks {{user_name}}, see you at {{start_time}}",
+		"reply_cancelled":    "{{user_name}}, cancelamos tu {{service_name}} del {{date}}",
+		"reply_unknown":      "{{user_name}}, no entendimos tu respuesta sobre {{booking_id}}",
+		"reply_no_booking":   "No encontramos la reserva {{booking_id}}",
+	})
+
+	tests := []struct {
+		name       string
+		body       string
+		responseID string
+		want       string
+	}{
+		{name: "confirmed in the conversation locale", body: "Sí", want: "Gracias Ana, te esperamos a las 10:00 en Centro"},
+		{name: "confirmed in any other locale", body: "Yes, I will be there, thanks", responseID: ResponseConfirm, want: "Thanks Ana, see you at 10:00"},
+		{name: "cancelled", body: "No", want: "Ana, cancelamos tu Corte del 01/06/2025"},
+		{name: "unknown", body: "mmm", want: "Ana, no entendimos tu respuesta sobre booking-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t)
+			sent := captureSends(client)
+			u := NewBookingUseCase(client, logger.NewNop(),
+				WithBookingStore(newFakeStore()),
+				WithReplyTemplates(repository))
+			ctx := context.Background()
+			if _, err := u.SendConfirmationMessage(ctx, testBooking("booking-1")); err != nil {
+				t.Fatalf("SendConfirmationMessage() error = %v", err)
+			}
+
+			response, err := u.ProcessIncomingResponse(ctx, testPhone, tt.body, tt.responseID)
+			if err != nil {
+				t.Fatalf("ProcessIncomingResponse() error = %v", err)
+			}
+			if response.Message != tt.want {
+				t.Errorf("response message = %q, want %q", response.Message, tt.want)
+			}
+			if texts := sent.texts(); texts[len(texts)-1] != tt.want {
+				t.Errorf("reply = %q, want %q", texts[len(texts)-1], tt.want)
+			}
+		})
+	}
+
+	// A template using booking variables falls back to the built-in reply
+	// when there is no booking to render it with
+	client := newTestClient(t)
+	sent := captureSends(client)
+	u := NewBookingUseCase(client, logger.NewNop(),
+		WithBookingStore(newFakeStore()),
+		WithReplyTemplates(repository))
+	if _, err := u.ProcessIncomingResponse(context.Background(), testPhone, "mmm", ""); err != nil {
+		t.Fatalf("ProcessIncomingResponse() error = %v", err)
+	}
+	if texts := sent.texts(); len(texts) != 1 || texts[0] != reply("es", "no_booking") {
+		t.Errorf("sent %q, want the built-in no booking reply", texts)
+	}
+}
+
+func TestExpiryReplyTemplates(t *testing.T) {
+	client := newTestClient(t)
+	sent := captureSends(client)
+	u := NewBookingUseCase(client, logger.NewNop(),
+		WithBookingStore(newFakeStore()),
+		WithExpiryMessage(true),
+		WithReplyTemplates(newReplyTemplates(t, map[string]string{
+			"reply_expired": "{{user_name}}, tu {{service_name}} de las {{start_time}} expiró",
+			"reply_late":    "{{user_name}}, la reserva {{booking_id}} ya expiró",
+		})))
+	ctx := context.Background()
+
+	request := testBooking("booking-1")
+	request.ConfirmationDeadline = time.Hour
+	if _, err := u.SendConfirmationMessage(ctx, request); err != nil {
+		t.Fatalf("SendConfirmationMessage() error = %v", err)
+	}
+	if expired, err := u.ExpireOverdue(ctx, time.Now().Add(2*time.Hour)); expired != 1 || err != nil {
+		t.Fatalf("ExpireOverdue() = %d, %v, want 1", expired, err)
+	}
+	eventually(t, func() bool { return len(sent.texts()) == 2 })
+	if got := sent.texts()[1]; got != "Ana, tu Corte de las 10:00 expiró" {
+		t.Errorf("expiry message = %q, want the expired template rendered", got)
+	}
+
+	// The expired booking is remembered to render the late reply
+	response, err := u.ProcessIncomingResponse(ctx, testPhone, "Confirmar", ResponseConfirm)
+	if err != nil {
+		t.Fatalf("ProcessIncomingResponse() error = %v", err)
+	}
+	if response.Message != "Ana, la reserva booking-1 ya expiró" {
+		t.Errorf("late reply = %q, want the late template rendered", response.Message)
+	}
+}
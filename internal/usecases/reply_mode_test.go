@@ -0,0 +1,76 @@
+package usecases
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/webhook"
+)
+
+func TestReplyModes(t *testing.T) {
+	tests := []struct {
+		name     string
+		external bool
+		status   int
+		wantErr  bool
+		// wantIntent is whether the intent is posted to the integrator
+		wantIntent bool
+		wantReply  bool
+	}{
+		{name: "auto replies", wantReply: true},
+		{name: "external defers the reply", external: true, status: http.StatusOK, wantIntent: true},
+		{name: "external with the webhook down", external: true, status: http.StatusInternalServerError, wantIntent: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			url, posted := newEventServer(t, tt.status)
+			client := newTestClient(t)
+			sent := captureSends(client)
+
+			var options []BookingUseCaseOption
+			if tt.external {
+				options = append(options, WithExternalReply(webhook.NewNotifier(url)))
+			}
+			u := NewBookingUseCase(client, logger.NewNop(), options...)
+
+			response, err := u.ProcessIncomingResponse(context.Background(), testPhone, "Sí", "")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ProcessIncomingResponse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil {
+				if response.Status != "confirmed" {
+					t.Errorf("status = %q, want confirmed", response.Status)
+				}
+				if response.Deferred != tt.external {
+					t.Errorf("deferred = %v, want %v", response.Deferred, tt.external)
+				}
+			}
+
+			if got := len(sent.all()) > 0; got != tt.wantReply {
+				t.Errorf("replied = %v, want %v", got, tt.wantReply)
+			}
+
+			select {
+			case event := <-posted:
+				if !tt.wantIntent {
+					t.Fatalf("unexpected %s event", event.Type)
+				}
+				var intent Intent
+				if err := json.Unmarshal(event.Data, &intent); err != nil {
+					t.Fatalf("decode intent: %v", err)
+				}
+				if event.Type != IntentEvent || intent.Status != "confirmed" || intent.PhoneNumber != testPhone || intent.Message != "Sí" {
+					t.Errorf("posted %s %+v, want a confirmed intent from %s", event.Type, intent, testPhone)
+				}
+			case <-time.After(100 * time.Millisecond):
+				if tt.wantIntent {
+					t.Fatal("intent not posted")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Scheduled job types
+const (
+	// JobTypeBookingExpiry expires an unconfirmed booking at its deadline
+	JobTypeBookingExpiry = "booking_expiry"
+)
+
+// ErrJobNotFound is returned when no scheduled job matches the ID
+var ErrJobNotFound = errors.New("scheduled job not found")
+
+// ScheduledJob is a timed action waiting to fire
+type ScheduledJob struct {
+	ID          string    `json:"id"`
+	Type        string    `json:"type"`
+	PhoneNumber string    `json:"phone_number"`
+	BookingID   string    `json:"booking_id,omitempty"`
+	FiresAt     time.Time `json:"fires_at"`
+}
+
+// ScheduledJobs lists the pending scheduled jobs, soonest first. Empty
+// jobType or phoneNumber match every job.
+func (u *BookingUseCase) ScheduledJobs(ctx context.Context, jobType, phoneNumber string) ([]ScheduledJob, error) {
+	jobs := []ScheduledJob{}
+	if u.store == nil || (jobType != "" && jobType != JobTypeBookingExpiry) {
+		return jobs, nil
+	}
+
+	pattern := pendingBookingKeyPrefix + "*"
+	if phoneNumber != "" {
+		pattern = pendingBookingKeyPrefix + phoneNumber
+	}
+	keys, err := u.store.Keys(ctx, pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pending bookings: %w", err)
+	}
+
+	for _, key := range keys {
+		number := strings.TrimPrefix(key, pendingBookingKeyPrefix)
+		booking, err := u.pendingBookingFor(ctx, number)
+		if err != nil || booking == nil || booking.ExpiresAt.IsZero() {
+			continue
+		}
+		jobs = append(jobs, ScheduledJob{
+			ID:          JobTypeBookingExpiry + ":" + number,
+			Type:        JobTypeBookingExpiry,
+			PhoneNumber: number,
+			BookingID:   booking.BookingID,
+			FiresAt:     booking.ExpiresAt,
+		})
+	}
+
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].FiresAt.Before(jobs[j].FiresAt)
+	})
+	return jobs, nil
+}
+
+// CancelScheduledJob cancels a scheduled job. Cancelling a booking expiry
+// keeps the booking pending without a deadline.
+func (u *BookingUseCase) CancelScheduledJob(ctx context.Context, id string) error {
+	jobType, number, ok := strings.Cut(id, ":")
+	if !ok || jobType != JobTypeBookingExpiry || number == "" || u.store == nil {
+		return fmt.Errorf("%w: %s", ErrJobNotFound, id)
+	}
+
+	booking, err := u.pendingBookingFor(ctx, number)
+	if err != nil {
+		return err
+	}
+	if booking == nil || booking.ExpiresAt.IsZero() {
+		return fmt.Errorf("%w: %s", ErrJobNotFound, id)
+	}
+
+	booking.ExpiresAt = time.Time{}
+	if err := u.savePendingBooking(ctx, number, *booking); err != nil {
+		return err
+	}
+
+	u.logger.Info("Cancelled scheduled job", zap.String("job_id", id))
+	return nil
+}
+
+// ScheduleDrain is the outcome of draining the scheduled jobs on shutdown
+type ScheduleDrain struct {
+	// Completed is how many imminent jobs were fired before stopping
+	Completed int
+	// Deferred are the jobs left in the state store for the next boot
+	Deferred []ScheduledJob
+}
+
+// DrainScheduled stops the expiry loop, waits for a sweep in progress to
+// finish and fires the jobs due within window so they are not delayed by a
+// restart. Later jobs are already persisted in the state store and are
+// logged as deferred; the loop of the next boot picks them up. Every job is
+// claimed before it fires, so a job is never fired twice across restarts.
+func (u *BookingUseCase) DrainScheduled(ctx context.Context, window time.Duration) (ScheduleDrain, error) {
+	var drain ScheduleDrain
+	if u.store == nil {
+		return drain, nil
+	}
+
+	if u.stopExpiry != nil {
+		u.stopExpiry()
+		select {
+		case <-u.expiryDone:
+		case <-ctx.Done():
+			return drain, fmt.Errorf("expiry loop did not stop: %w", ctx.Err())
+		}
+	}
+
+	completed, err := u.ExpireOverdue(ctx, time.Now().Add(window))
+	drain.Completed = completed
+	if err != nil {
+		return drain, fmt.Errorf("failed to fire imminent jobs: %w", err)
+	}
+
+	deferred, err := u.ScheduledJobs(ctx, "", "")
+	if err != nil {
+		return drain, err
+	}
+	drain.Deferred = deferred
+	for _, job := range deferred {
+		u.logger.Info("Deferred scheduled job to the next boot",
+			zap.String("job_id", job.ID),
+			zap.String("booking_id", job.BookingID),
+			zap.Time("fires_at", job.FiresAt))
+	}
+	return drain, nil
+}
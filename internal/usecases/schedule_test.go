@@ -0,0 +1,90 @@
+package usecases
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/store"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/webhook"
+)
+
+func TestDrainScheduledPersistsPendingJobs(t *testing.T) {
+	redisClient, _ := newTestRedis(t)
+	stateStore := store.NewRedisStore(redisClient)
+	url, posted := newEventServer(t, http.StatusOK)
+	u := NewBookingUseCase(newTestClient(t), logger.NewNop(),
+		WithBookingStore(stateStore),
+		WithResponseCallback(webhook.NewNotifier(url)))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// The loop never ticks, so only the drain fires jobs
+	u.StartExpiry(ctx, time.Hour)
+
+	imminent := testBooking("booking-1")
+	imminent.ConfirmationDeadline = time.Second
+	later := testBooking("booking-2")
+	later.PhoneNumber = "56961234568"
+	later.ConfirmationDeadline = time.Hour
+	for _, request := range []BookingRequest{imminent, later} {
+		if _, err := u.SendConfirmationMessage(ctx, request); err != nil {
+			t.Fatalf("SendConfirmationMessage(%s) error = %v", request.BookingID, err)
+		}
+	}
+
+	// Shutting down fires the imminent job and leaves the other one
+	drainCtx, drainCancel := context.WithTimeout(context.Background(), time.Second)
+	defer drainCancel()
+	drain, err := u.DrainScheduled(drainCtx, 5*time.Second)
+	if err != nil {
+		t.Fatalf("DrainScheduled() error = %v", err)
+	}
+	if drain.Completed != 1 {
+		t.Errorf("completed %d jobs, want the imminent one", drain.Completed)
+	}
+	if len(drain.Deferred) != 1 || drain.Deferred[0].BookingID != "booking-2" {
+		t.Fatalf("deferred %+v, want booking-2", drain.Deferred)
+	}
+	if eventType, intent := nextEvent(t, posted); eventType != BookingExpiredEvent || intent.BookingID != "booking-1" {
+		t.Errorf("posted %s for %s, want booking-1 expired", eventType, intent.BookingID)
+	}
+	select {
+	case <-u.expiryDone:
+	default:
+		t.Error("expiry loop still running after the drain")
+	}
+
+	// The next boot finds the deferred job persisted and fires each job once
+	restarted := NewBookingUseCase(newTestClient(t), logger.NewNop(),
+		WithBookingStore(stateStore),
+		WithResponseCallback(webhook.NewNotifier(url)))
+	jobs, err := restarted.ScheduledJobs(ctx, "", "")
+	if err != nil {
+		t.Fatalf("ScheduledJobs() error = %v", err)
+	}
+	if len(jobs) != 1 || jobs[0].BookingID != "booking-2" || !jobs[0].FiresAt.Equal(drain.Deferred[0].FiresAt) {
+		t.Fatalf("jobs after restart = %+v, want the deferred booking-2", jobs)
+	}
+	expired, err := restarted.ExpireOverdue(ctx, time.Now().Add(2*time.Hour))
+	if err != nil || expired != 1 {
+		t.Fatalf("ExpireOverdue() after restart = %d, %v, want 1", expired, err)
+	}
+	if eventType, intent := nextEvent(t, posted); eventType != BookingExpiredEvent || intent.BookingID != "booking-2" {
+		t.Errorf("posted %s for %s, want booking-2 expired", eventType, intent.BookingID)
+	}
+	select {
+	case event := <-posted:
+		t.Errorf("posted %s again, want each job fired once", event.Type)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestDrainScheduledWithoutStore(t *testing.T) {
+	u := NewBookingUseCase(newTestClient(t), logger.NewNop())
+	drain, err := u.DrainScheduled(context.Background(), time.Minute)
+	if err != nil || drain.Completed != 0 || len(drain.Deferred) != 0 {
+		t.Errorf("DrainScheduled() = %+v, %v, want nothing to drain", drain, err)
+	}
+}
@@ -0,0 +1,149 @@
+package usecases
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/store"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/whatsapp"
+	"go.uber.org/zap"
+)
+
+// sendRecordKeyPrefix is the state store key prefix for send records
+const sendRecordKeyPrefix = "whatsapp:send:"
+
+// SendRecordUseCase persists send records in the state store. It implements
+// whatsapp.SendRecorder.
+type SendRecordUseCase struct {
+	store     store.Store
+	logger    logger.Logger
+	retention time.Duration
+}
+
+// NewSendRecordUseCase creates a new SendRecordUseCase
+func NewSendRecordUseCase(stateStore store.Store, logger logger.Logger, retention time.Duration) *SendRecordUseCase {
+	if retention <= 0 {
+		retention = 7 * 24 * time.Hour
+	}
+
+	return &SendRecordUseCase{
+		store:     stateStore,
+		logger:    logger,
+		retention: retention,
+	}
+}
+
+// RecordSend persists a new send record
+func (u *SendRecordUseCase) RecordSend(ctx context.Context, record whatsapp.SendRecord) error {
+	return u.save(ctx, record)
+}
+
+// UpdateStatus applies a delivery receipt to the matching send record.
+// Receipts for messages that were not recorded are ignored.
+func (u *SendRecordUseCase) UpdateStatus(ctx context.Context, messageID, status string, at time.Time) error {
+	record, err := u.Get(ctx, messageID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if whatsapp.StatusRank(status) <= whatsapp.StatusRank(record.Status) {
+		return nil
+	}
+
+	record.Status = status
+	record.UpdatedAt = at
+	return u.save(ctx, *record)
+}
+
+// Get returns the send record for a message ID
+func (u *SendRecordUseCase) Get(ctx context.Context, messageID string) (*whatsapp.SendRecord, error) {
+	value, err := u.store.Get(ctx, sendRecordKeyPrefix+messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	var record whatsapp.SendRecord
+	if err := json.Unmarshal([]byte(value), &record); err != nil {
+		return nil, fmt.Errorf("failed to decode send record: %w", err)
+	}
+	return &record, nil
+}
+
+// Prune deletes send records older than the retention period and returns
+// how many were removed
+func (u *SendRecordUseCase) Prune(ctx context.Context) (int, error) {
+	keys, err := u.store.Keys(ctx, sendRecordKeyPrefix+"*")
+	if err != nil {
+		return 0, fmt.Errorf("failed to list send records: %w", err)
+	}
+
+	cutoff := time.Now().Add(-u.retention)
+	pruned := 0
+	for _, key := range keys {
+		value, err := u.store.Get(ctx, key)
+		if err != nil {
+			continue
+		}
+
+		var record whatsapp.SendRecord
+		if err := json.Unmarshal([]byte(value), &record); err == nil && record.SentAt.After(cutoff) {
+			continue
+		}
+
+		if err := u.store.Delete(ctx, key); err != nil {
+			return pruned, fmt.Errorf("failed to delete send record: %w", err)
+		}
+		pruned++
+	}
+
+	return pruned, nil
+}
+
+// Start prunes old send records on the given interval until the context is cancelled
+func (u *SendRecordUseCase) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				pruned, err := u.Prune(ctx)
+				if err != nil {
+					u.logger.Warn("Failed to prune send records", zap.Error(err))
+					continue
+				}
+				if pruned > 0 {
+					u.logger.Info("Pruned send records", zap.Int("count", pruned))
+				}
+			}
+		}
+	}()
+}
+
+// save writes a send record, keeping it for the retention period
+func (u *SendRecordUseCase) save(ctx context.Context, record whatsapp.SendRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode send record: %w", err)
+	}
+
+	expiration := u.retention - time.Since(record.SentAt)
+	if expiration <= 0 {
+		return nil
+	}
+
+	if err := u.store.Set(ctx, sendRecordKeyPrefix+record.MessageID, string(data), expiration); err != nil {
+		return fmt.Errorf("failed to save send record: %w", err)
+	}
+	return nil
+}
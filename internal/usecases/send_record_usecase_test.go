@@ -0,0 +1,121 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/store"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/whatsapp"
+	"go.mau.fi/whatsmeow/types"
+)
+
+func TestSendRecordSurvivesRestart(t *testing.T) {
+	ctx := context.Background()
+	stateStore := newFakeStore()
+	recorder := NewSendRecordUseCase(stateStore, logger.NewNop(), time.Hour)
+	client := newTestClient(t, whatsapp.WithSendRecorder(recorder))
+
+	jid := types.NewJID(testPhone, types.DefaultUserServer)
+	resp, err := client.SendText(ctx, jid, "Hola", whatsapp.WithMetadata("booking_id", "b-1"))
+	if err != nil {
+		t.Fatalf("SendText() error = %v", err)
+	}
+
+	key := sendRecordKeyPrefix + resp.ID
+	if got := stateStore.expiration(key); got <= 0 || got > time.Hour {
+		t.Errorf("record expiration = %v, want within the 1h retention", got)
+	}
+
+	// A new process only shares the state store with the old one
+	restarted := NewSendRecordUseCase(stateStore, logger.NewNop(), time.Hour)
+	deliveredAt := resp.Timestamp.Add(time.Second)
+	if err := restarted.UpdateStatus(ctx, resp.ID, whatsapp.SendStatusDelivered, deliveredAt); err != nil {
+		t.Fatalf("UpdateStatus() error = %v", err)
+	}
+
+	record, err := restarted.Get(ctx, resp.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if record.Status != whatsapp.SendStatusDelivered || !record.UpdatedAt.Equal(deliveredAt) {
+		t.Errorf("record = %+v, want delivered at %v", record, deliveredAt)
+	}
+	if record.JID != jid.String() || record.Metadata["booking_id"] != "b-1" {
+		t.Errorf("record = %+v, want JID %s and booking b-1", record, jid)
+	}
+}
+
+func TestSendRecordUpdateStatus(t *testing.T) {
+	ctx := context.Background()
+	recorder := NewSendRecordUseCase(newFakeStore(), logger.NewNop(), time.Hour)
+	sentAt := time.Now()
+	if err := recorder.RecordSend(ctx, whatsapp.SendRecord{
+		MessageID: "msg-1",
+		SentAt:    sentAt,
+		Status:    whatsapp.SendStatusSent,
+		UpdatedAt: sentAt,
+	}); err != nil {
+		t.Fatalf("RecordSend() error = %v", err)
+	}
+
+	steps := []struct {
+		status string
+		want   string
+	}{
+		{whatsapp.SendStatusRead, whatsapp.SendStatusRead},
+		// A delivery receipt arriving after the read receipt is ignored
+		{whatsapp.SendStatusDelivered, whatsapp.SendStatusRead},
+		{whatsapp.SendStatusPlayed, whatsapp.SendStatusPlayed},
+	}
+	for _, step := range steps {
+		if err := recorder.UpdateStatus(ctx, "msg-1", step.status, time.Now()); err != nil {
+			t.Fatalf("UpdateStatus(%s) error = %v", step.status, err)
+		}
+		record, err := recorder.Get(ctx, "msg-1")
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		if record.Status != step.want {
+			t.Errorf("after %s: status = %s, want %s", step.status, record.Status, step.want)
+		}
+	}
+
+	if err := recorder.UpdateStatus(ctx, "unknown", whatsapp.SendStatusRead, time.Now()); err != nil {
+		t.Errorf("UpdateStatus() for an unrecorded message error = %v, want nil", err)
+	}
+}
+
+func TestSendRecordPrune(t *testing.T) {
+	ctx := context.Background()
+	stateStore := newFakeStore()
+	recorder := NewSendRecordUseCase(stateStore, logger.NewNop(), time.Hour)
+
+	now := time.Now()
+	if err := recorder.RecordSend(ctx, whatsapp.SendRecord{MessageID: "recent", SentAt: now}); err != nil {
+		t.Fatalf("RecordSend() error = %v", err)
+	}
+	// Stores without native expiration may still hold old records
+	if err := stateStore.Set(ctx, sendRecordKeyPrefix+"old", `{"message_id":"old","sent_at":"`+now.Add(-2*time.Hour).Format(time.RFC3339)+`"}`, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := stateStore.Set(ctx, sendRecordKeyPrefix+"corrupt", "{", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	pruned, err := recorder.Prune(ctx)
+	if err != nil {
+		t.Fatalf("Prune() error = %v", err)
+	}
+	if pruned != 2 {
+		t.Errorf("Prune() = %d, want 2", pruned)
+	}
+	if _, err := recorder.Get(ctx, "recent"); err != nil {
+		t.Errorf("Get(recent) error = %v", err)
+	}
+	if _, err := recorder.Get(ctx, "old"); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("Get(old) error = %v, want ErrNotFound", err)
+	}
+}
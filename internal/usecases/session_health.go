@@ -0,0 +1,160 @@
+package usecases
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/redis"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/whatsapp"
+	"go.mau.fi/whatsmeow/types/events"
+	"go.uber.org/zap"
+)
+
+// sessionHealthKeyPrefix is the Redis key prefix for session health entries
+const sessionHealthKeyPrefix = "whatsapp:session:"
+
+// SessionHealth represents the health of a replica's WhatsApp session
+type SessionHealth struct {
+	ReplicaID string `json:"replica_id"`
+	Connected bool   `json:"connected"`
+	// State is the connection lifecycle state, e.g. reconnecting
+	State    whatsapp.ConnectionState `json:"state"`
+	LoggedIn bool                     `json:"logged_in"`
+	Phone    string                   `json:"phone,omitempty"`
+	// LastActivity is the last message sent or received, nil before the
+	// first one
+	LastActivity *time.Time `json:"last_activity,omitempty"`
+	ReportedAt   time.Time  `json:"reported_at"`
+}
+
+// SessionHealthUseCase publishes the session health of this replica to Redis
+type SessionHealthUseCase struct {
+	client    *whatsapp.Client
+	redis     *redis.Client
+	logger    logger.Logger
+	replicaID string
+	interval  time.Duration
+}
+
+// NewSessionHealthUseCase creates a new SessionHealthUseCase
+func NewSessionHealthUseCase(client *whatsapp.Client, redisClient *redis.Client, logger logger.Logger, replicaID string, interval time.Duration) *SessionHealthUseCase {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+
+	return &SessionHealthUseCase{
+		client:    client,
+		redis:     redisClient,
+		logger:    logger,
+		replicaID: replicaID,
+		interval:  interval,
+	}
+}
+
+// TTL returns the expiration of a health entry; a replica that misses
+// several reports is considered dead and its entry disappears
+func (u *SessionHealthUseCase) TTL() time.Duration {
+	return 3 * u.interval
+}
+
+// Start publishes the session health on every interval, on connection
+// state changes and on pairing until the context is cancelled
+func (u *SessionHealthUseCase) Start(ctx context.Context) {
+	u.client.OnConnectionStateChange(u.StateHook(ctx))
+	u.client.AddNamedEventHandler("session_health", func(evt interface{}) {
+		if _, ok := evt.(*events.PairSuccess); ok {
+			u.publish(ctx)
+		}
+	})
+
+	go func() {
+		ticker := time.NewTicker(u.interval)
+		defer ticker.Stop()
+
+		u.publish(ctx)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				u.publish(ctx)
+			}
+		}
+	}()
+}
+
+// StateHook returns a connection state hook that publishes the session
+// health, so the fleet view sees transitions right away
+func (u *SessionHealthUseCase) StateHook(ctx context.Context) whatsapp.ConnectionStateHook {
+	return func(old, new whatsapp.ConnectionState) {
+		// Hooks must not block the connection state transition
+		go u.publish(ctx)
+	}
+}
+
+// Publish writes the current session health to Redis
+func (u *SessionHealthUseCase) Publish(ctx context.Context) error {
+	health := SessionHealth{
+		ReplicaID:  u.replicaID,
+		Connected:  u.client.IsConnected(),
+		State:      u.client.ConnectionState(),
+		LoggedIn:   u.client.IsLoggedIn(),
+		Phone:      u.client.GetPhoneNumber(),
+		ReportedAt: time.Now(),
+	}
+	if lastActivity := u.client.LastActivity(); !lastActivity.IsZero() {
+		health.LastActivity = &lastActivity
+	}
+
+	data, err := json.Marshal(health)
+	if err != nil {
+		return fmt.Errorf("failed to marshal session health: %w", err)
+	}
+
+	if err := u.redis.Set(ctx, sessionHealthKeyPrefix+u.replicaID, data, u.TTL()); err != nil {
+		return fmt.Errorf("failed to publish session health: %w", err)
+	}
+
+	return nil
+}
+
+// publish publishes the session health and logs any failure
+func (u *SessionHealthUseCase) publish(ctx context.Context) {
+	if err := u.Publish(ctx); err != nil {
+		u.logger.Warn("Failed to publish session health", zap.Error(err))
+	}
+}
+
+// ListSessions returns the session health of all live replicas
+func (u *SessionHealthUseCase) ListSessions(ctx context.Context) ([]SessionHealth, error) {
+	keys, err := u.redis.Keys(ctx, sessionHealthKeyPrefix+"*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list session keys: %w", err)
+	}
+
+	sessions := make([]SessionHealth, 0, len(keys))
+	for _, key := range keys {
+		value, err := u.redis.Get(ctx, key)
+		if err != nil {
+			// The entry may have expired between SCAN and GET
+			continue
+		}
+
+		var health SessionHealth
+		if err := json.Unmarshal([]byte(value), &health); err != nil {
+			u.logger.Warn("Invalid session health entry", zap.String("key", key), zap.Error(err))
+			continue
+		}
+		sessions = append(sessions, health)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].ReplicaID < sessions[j].ReplicaID
+	})
+
+	return sessions, nil
+}
@@ -0,0 +1,119 @@
+package usecases
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/whatsapp"
+	"go.mau.fi/whatsmeow/types"
+)
+
+func TestSessionHealthPublishSetsTTL(t *testing.T) {
+	redisClient, server := newTestRedis(t)
+	client := newTestClient(t)
+	useCase := NewSessionHealthUseCase(client, redisClient, logger.NewNop(), "replica-1", 10*time.Second)
+
+	if err := useCase.Publish(context.Background()); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+
+	key := sessionHealthKeyPrefix + "replica-1"
+	if ttl := server.TTL(key); ttl != 30*time.Second {
+		t.Errorf("TTL = %v, want %v", ttl, 30*time.Second)
+	}
+	value, err := server.Get(key)
+	if err != nil {
+		t.Fatalf("Get(%q) error = %v", key, err)
+	}
+	var health SessionHealth
+	if err := json.Unmarshal([]byte(value), &health); err != nil {
+		t.Fatalf("entry is not valid JSON: %v", err)
+	}
+	if health.ReplicaID != "replica-1" || !health.Connected || health.State != whatsapp.StateConnected {
+		t.Errorf("health = %+v, want a connected replica-1", health)
+	}
+
+	// A replica that stops reporting disappears from the fleet view
+	server.FastForward(31 * time.Second)
+	sessions, err := useCase.ListSessions(context.Background())
+	if err != nil {
+		t.Fatalf("ListSessions() error = %v", err)
+	}
+	if len(sessions) != 0 {
+		t.Errorf("ListSessions() = %+v, want none after the TTL", sessions)
+	}
+}
+
+func TestSessionHealthUpdatedOnConnectionEvents(t *testing.T) {
+	redisClient, server := newTestRedis(t)
+	client := newTestClient(t)
+	// A long interval, so only the state change can publish again
+	useCase := NewSessionHealthUseCase(client, redisClient, logger.NewNop(), "replica-1", time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	useCase.Start(ctx)
+
+	key := sessionHealthKeyPrefix + "replica-1"
+	state := func() whatsapp.ConnectionState {
+		value, err := server.Get(key)
+		if err != nil {
+			return ""
+		}
+		var health SessionHealth
+		if err := json.Unmarshal([]byte(value), &health); err != nil {
+			return ""
+		}
+		return health.State
+	}
+	eventually(t, func() bool { return state() == whatsapp.StateConnected })
+
+	if err := client.Disconnect(); err != nil {
+		t.Fatalf("Disconnect() error = %v", err)
+	}
+	eventually(t, func() bool { return state() == whatsapp.StateDisconnected })
+	if ttl := server.TTL(key); ttl != 3*time.Hour {
+		t.Errorf("TTL = %v, want %v", ttl, 3*time.Hour)
+	}
+}
+
+func TestSessionHealthLastActivity(t *testing.T) {
+	redisClient, server := newTestRedis(t)
+	client := newTestClient(t)
+	useCase := NewSessionHealthUseCase(client, redisClient, logger.NewNop(), "replica-1", 10*time.Second)
+	key := sessionHealthKeyPrefix + "replica-1"
+
+	// Without activity the field is left out instead of a zero time
+	if err := useCase.Publish(context.Background()); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	value, err := server.Get(key)
+	if err != nil {
+		t.Fatalf("Get(%q) error = %v", key, err)
+	}
+	var entry map[string]any
+	if err := json.Unmarshal([]byte(value), &entry); err != nil {
+		t.Fatalf("entry is not valid JSON: %v", err)
+	}
+	if _, ok := entry["last_activity"]; ok {
+		t.Errorf("entry = %s, want no last_activity before any message", value)
+	}
+
+	before := time.Now()
+	if _, err := client.SendText(context.Background(), types.NewJID(testPhone, types.DefaultUserServer), "Hola"); err != nil {
+		t.Fatalf("SendText() error = %v", err)
+	}
+	if err := useCase.Publish(context.Background()); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	sessions, err := useCase.ListSessions(context.Background())
+	if err != nil || len(sessions) != 1 {
+		t.Fatalf("ListSessions() = %+v, %v, want replica-1", sessions, err)
+	}
+	if last := sessions[0].LastActivity; last == nil || last.Before(before) {
+		t.Errorf("LastActivity = %v, want the send after %v", last, before)
+	}
+}
@@ -0,0 +1,75 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/store"
+)
+
+// errStoreDown simulates Redis refusing connections
+var errStoreDown = errors.New("dial tcp 127.0.0.1:6379: connection refused")
+
+// newDegradedStore returns a resilient store over a backend that is down
+func newDegradedStore(t *testing.T) (*store.ResilientStore, *fakeStore) {
+	t.Helper()
+	backend := newFakeStore()
+	backend.fail(errStoreDown)
+	return store.NewResilientStore(backend, logger.NewNop()), backend
+}
+
+func TestIdempotencyAllowsSendsWhenStoreIsDown(t *testing.T) {
+	stateStore, _ := newDegradedStore(t)
+	client := newTestClient(t)
+	sent := captureSends(client)
+	useCase := NewBookingUseCase(client, logger.NewNop(), WithBookingStore(stateStore), WithIdempotencyWindow(time.Hour))
+
+	for i := 0; i < 2; i++ {
+		_, replayed, err := useCase.ConfirmIdempotent(context.Background(), "key-1", testBooking("b-1"))
+		if err != nil {
+			t.Fatalf("ConfirmIdempotent() #%d error = %v", i+1, err)
+		}
+		if replayed {
+			t.Errorf("ConfirmIdempotent() #%d replayed a response the store could not keep", i+1)
+		}
+	}
+	if got := len(sent.all()); got != 2 {
+		t.Errorf("sent %d confirmations, want 2", got)
+	}
+	if stateStore.Available() {
+		t.Error("Available() = true while the store is down")
+	}
+}
+
+func TestConversationFallsBackToKeywordsWhenStoreIsDown(t *testing.T) {
+	stateStore, backend := newDegradedStore(t)
+	client := newTestClient(t)
+	sent := captureSends(client)
+	useCase := NewBookingUseCase(client, logger.NewNop(), WithBookingStore(stateStore))
+
+	// The confirmation goes out although its pending booking is not saved
+	if _, err := useCase.SendConfirmationMessage(context.Background(), testBooking("b-1")); err != nil {
+		t.Fatalf("SendConfirmationMessage() error = %v", err)
+	}
+
+	response, err := useCase.ProcessIncomingResponse(context.Background(), testPhone, "Sí", "")
+	if err != nil {
+		t.Fatalf("ProcessIncomingResponse() error = %v", err)
+	}
+	if response.Status != "confirmed" {
+		t.Errorf("status = %q, want confirmed", response.Status)
+	}
+	// Without state the reply cannot name the booking
+	if response.BookingID != "" {
+		t.Errorf("booking ID = %q, want none", response.BookingID)
+	}
+	if got := len(sent.all()); got != 2 {
+		t.Errorf("sent %d messages, want the confirmation and the reply", got)
+	}
+	if len(backend.values) != 0 {
+		t.Errorf("store holds %v while down", backend.values)
+	}
+}
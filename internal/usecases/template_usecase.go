@@ -0,0 +1,98 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/templates"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/utils"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/whatsapp"
+	"go.mau.fi/whatsmeow/types"
+	"go.uber.org/zap"
+)
+
+// ErrInvalidTemplate is returned when a template name or body is invalid
+var ErrInvalidTemplate = errors.New("invalid template")
+
+// templateNamePattern restricts template names to URL-safe identifiers
+var templateNamePattern = regexp.MustCompile(`^[a-z0-9_\-]{1,64}$`)
+
+// TemplateUseCase manages message templates and sends rendered templates
+type TemplateUseCase struct {
+	repository  templates.Repository
+	client      *whatsapp.Client
+	logger      logger.Logger
+	phoneRegion string
+}
+
+// NewTemplateUseCase creates a new TemplateUseCase
+func NewTemplateUseCase(repository templates.Repository, client *whatsapp.Client, logger logger.Logger, phoneRegion string) *TemplateUseCase {
+	return &TemplateUseCase{
+		repository:  repository,
+		client:      client,
+		logger:      logger,
+		phoneRegion: phoneRegion,
+	}
+}
+
+// Save stores body as a new version of the named template
+func (u *TemplateUseCase) Save(ctx context.Context, name, body string) (*templates.Template, error) {
+	if !templateNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("%w: name must be 1-64 lowercase letters, digits, '-' or '_'", ErrInvalidTemplate)
+	}
+	if body == "" {
+		return nil, fmt.Errorf("%w: body is required", ErrInvalidTemplate)
+	}
+
+	template, err := u.repository.Save(ctx, name, body)
+	if err != nil {
+		return nil, err
+	}
+
+	u.logger.Info("Template saved",
+		zap.String("name", name),
+		zap.Int("version", template.Version))
+	return template, nil
+}
+
+// List returns the latest version of every template
+func (u *TemplateUseCase) List(ctx context.Context) ([]templates.Template, error) {
+	return u.repository.List(ctx)
+}
+
+// Send renders the latest version of the named template and sends it with
+// the given options
+func (u *TemplateUseCase) Send(ctx context.Context, name, to string, values map[string]string, options ...whatsapp.SendOption) (*SendResult, error) {
+	phoneNumber, err := utils.NormalizePhone(to, u.phoneRegion)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPhoneNumber, err)
+	}
+
+	template, err := u.repository.Latest(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	text, err := template.Render(values)
+	if err != nil {
+		return nil, err
+	}
+
+	jid := types.NewJID(phoneNumber, types.DefaultUserServer)
+	options = append([]whatsapp.SendOption{
+		whatsapp.WithMetadata("template", template.Name),
+		whatsapp.WithMetadata("template_version", fmt.Sprint(template.Version)),
+	}, options...)
+	resp, err := u.client.SendText(ctx, jid, text, options...)
+	if err != nil {
+		u.logger.Error("Failed to send template", zap.String("name", name), zap.Error(err))
+		return nil, fmt.Errorf("failed to send template: %w", err)
+	}
+
+	return &SendResult{
+		MessageID: resp.ID,
+		Timestamp: resp.Timestamp,
+	}, nil
+}
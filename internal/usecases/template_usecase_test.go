@@ -0,0 +1,108 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/templates"
+)
+
+func TestTemplateSendUsesLatestVersion(t *testing.T) {
+	client := newTestClient(t)
+	sent := captureSends(client)
+	u := NewTemplateUseCase(templates.NewStoreRepository(newFakeStore()), client, logger.NewNop(), "CL")
+	ctx := context.Background()
+
+	if _, err := u.Save(ctx, "reminder", "Hola {{name}}"); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	updated, err := u.Save(ctx, "reminder", "Hola {{name}}, te esperamos")
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if updated.Version != 2 {
+		t.Fatalf("version = %d, want 2", updated.Version)
+	}
+
+	if _, err := u.Send(ctx, "reminder", "+56 9 6123 4567", map[string]string{"name": "Ana"}); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	messages := sent.all()
+	if len(messages) != 1 {
+		t.Fatalf("sent %d messages, want 1", len(messages))
+	}
+	if got := sent.texts()[0]; got != "Hola Ana, te esperamos" {
+		t.Errorf("text = %q, want the latest version rendered", got)
+	}
+	if got := messages[0].Metadata["template_version"]; got != "2" {
+		t.Errorf("template_version = %q, want 2", got)
+	}
+}
+
+func TestTemplateErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		run     func(u *TemplateUseCase) error
+		wantErr error
+	}{
+		{
+			name: "invalid name",
+			run: func(u *TemplateUseCase) error {
+				_, err := u.Save(context.Background(), "Reminder!", "Hola")
+				return err
+			},
+			wantErr: ErrInvalidTemplate,
+		},
+		{
+			name: "empty body",
+			run: func(u *TemplateUseCase) error {
+				_, err := u.Save(context.Background(), "reminder", "")
+				return err
+			},
+			wantErr: ErrInvalidTemplate,
+		},
+		{
+			name: "unknown template",
+			run: func(u *TemplateUseCase) error {
+				_, err := u.Send(context.Background(), "missing", testPhone, nil)
+				return err
+			},
+			wantErr: templates.ErrNotFound,
+		},
+		{
+			name: "missing variable",
+			run: func(u *TemplateUseCase) error {
+				if _, err := u.Save(context.Background(), "reminder", "Hola {{name}}"); err != nil {
+					return err
+				}
+				_, err := u.Send(context.Background(), "reminder", testPhone, nil)
+				return err
+			},
+			wantErr: templates.ErrMissingVariable,
+		},
+		{
+			name: "invalid phone",
+			run: func(u *TemplateUseCase) error {
+				_, err := u.Send(context.Background(), "reminder", "123", nil)
+				return err
+			},
+			wantErr: ErrInvalidPhoneNumber,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t)
+			sent := captureSends(client)
+			u := NewTemplateUseCase(templates.NewStoreRepository(newFakeStore()), client, logger.NewNop(), "CL")
+
+			if err := tt.run(u); !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+			if len(sent.all()) != 0 {
+				t.Error("message sent despite the error")
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package usecases
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/store"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/whatsapp"
+	"go.uber.org/zap"
+)
+
+// State store key prefixes for booking traces
+const (
+	// traceKeyPrefix holds one key per lifecycle event of a booking
+	traceKeyPrefix = "whatsapp:trace:"
+	// traceMessageKeyPrefix maps a sent message ID to its booking, so delivery
+	// receipts can be attributed to the trace
+	traceMessageKeyPrefix = "whatsapp:trace_message:"
+)
+
+// Lifecycle stages recorded in a booking trace. Delivery receipts are
+// recorded with their delivery status as the stage.
+const (
+	TraceStageCreated  = "created"
+	TraceStageSent     = "sent"
+	TraceStageUpdated  = "updated"
+	TraceStageReply    = "reply"
+	TraceStageCallback = "callback"
+	TraceStageExpired  = "expired"
+)
+
+// TraceEvent is one step in the lifecycle of a booking
+type TraceEvent struct {
+	TraceID   string    `json:"trace_id"`
+	BookingID string    `json:"booking_id"`
+	Stage     string    `json:"stage"`
+	At        time.Time `json:"at"`
+	MessageID string    `json:"message_id,omitempty"`
+	Status    string    `json:"status,omitempty"`
+	Detail    string    `json:"detail,omitempty"`
+}
+
+// traceMessage is the booking a sent message belongs to
+type traceMessage struct {
+	TraceID   string `json:"trace_id"`
+	BookingID string `json:"booking_id"`
+}
+
+// TraceUseCase records the lifecycle events of bookings in the state store.
+// It implements whatsapp.SendRecorder to follow the messages sent for a
+// booking, which carry the trace_id and booking_id metadata.
+type TraceUseCase struct {
+	store     store.Store
+	logger    logger.Logger
+	retention time.Duration
+}
+
+// NewTraceUseCase creates a new TraceUseCase
+func NewTraceUseCase(stateStore store.Store, logger logger.Logger, retention time.Duration) *TraceUseCase {
+	if retention <= 0 {
+		retention = 7 * 24 * time.Hour
+	}
+
+	return &TraceUseCase{
+		store:     stateStore,
+		logger:    logger,
+		retention: retention,
+	}
+}
+
+// Record stores a lifecycle event. Events without a trace ID are ignored.
+func (u *TraceUseCase) Record(ctx context.Context, event TraceEvent) {
+	if event.TraceID == "" || event.BookingID == "" {
+		return
+	}
+	if event.At.IsZero() {
+		event.At = time.Now()
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	key := fmt.Sprintf("%s%s:%020d-%s", traceKeyPrefix, event.BookingID, event.At.UnixNano(), event.Stage)
+	if err := u.store.Set(ctx, key, string(data), u.retention); err != nil {
+		u.logger.Warn("Failed to record trace event",
+			zap.String("trace_id", event.TraceID),
+			zap.String("stage", event.Stage),
+			zap.Error(err))
+	}
+}
+
+// Events returns the recorded events of a booking, oldest first
+func (u *TraceUseCase) Events(ctx context.Context, bookingID string) ([]TraceEvent, error) {
+	prefix := traceKeyPrefix + bookingID + ":"
+	keys, err := u.store.Keys(ctx, prefix+"*")
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]TraceEvent, 0, len(keys))
+	for _, key := range keys {
+		// Skip the events of booking IDs that extend this one
+		if strings.Contains(strings.TrimPrefix(key, prefix), ":") {
+			continue
+		}
+		value, err := u.store.Get(ctx, key)
+		if err != nil {
+			continue
+		}
+		var event TraceEvent
+		if err := json.Unmarshal([]byte(value), &event); err != nil {
+			continue
+		}
+		events = append(events, event)
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].At.Before(events[j].At)
+	})
+	return events, nil
+}
+
+// RecordSend records the send of a traced message and remembers its booking
+// for the delivery receipts
+func (u *TraceUseCase) RecordSend(ctx context.Context, record whatsapp.SendRecord) error {
+	message := traceMessage{
+		TraceID:   record.Metadata["trace_id"],
+		BookingID: record.Metadata["booking_id"],
+	}
+	if message.TraceID == "" || message.BookingID == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to encode trace message: %w", err)
+	}
+	if err := u.store.Set(ctx, traceMessageKeyPrefix+record.MessageID, string(data), u.retention); err != nil {
+		return err
+	}
+
+	u.Record(ctx, TraceEvent{
+		TraceID:   message.TraceID,
+		BookingID: message.BookingID,
+		Stage:     TraceStageSent,
+		At:        record.SentAt,
+		MessageID: record.MessageID,
+		Status:    record.Status,
+	})
+	return nil
+}
+
+// UpdateStatus records a delivery receipt of a traced message. Receipts for
+// other messages are ignored.
+func (u *TraceUseCase) UpdateStatus(ctx context.Context, messageID, status string, at time.Time) error {
+	value, err := u.store.Get(ctx, traceMessageKeyPrefix+messageID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var message traceMessage
+	if err := json.Unmarshal([]byte(value), &message); err != nil {
+		return fmt.Errorf("failed to decode trace message: %w", err)
+	}
+
+	u.logger.Debug("Delivery receipt for traced message",
+		zap.String("trace_id", message.TraceID),
+		zap.String("booking_id", message.BookingID),
+		zap.String("message_id", messageID),
+		zap.String("status", status))
+
+	u.Record(ctx, TraceEvent{
+		TraceID:   message.TraceID,
+		BookingID: message.BookingID,
+		Stage:     status,
+		At:        at,
+		MessageID: messageID,
+		Status:    status,
+	})
+	return nil
+}
@@ -0,0 +1,92 @@
+package usecases
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/webhook"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/whatsapp"
+)
+
+// stageIndex returns the position of the first event of the stage, or -1
+func stageIndex(events []TraceEvent, stage string) int {
+	for i, event := range events {
+		if event.Stage == stage {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestTraceFollowsBookingLifecycle(t *testing.T) {
+	url, posted := newEventServer(t, http.StatusOK)
+	stateStore := newFakeStore()
+	trace := NewTraceUseCase(stateStore, logger.NewNop(), time.Hour)
+	client := newTestClient(t, whatsapp.WithSendRecorder(trace))
+	u := NewBookingUseCase(client, logger.NewNop(),
+		WithBookingStore(stateStore),
+		WithTrace(trace),
+		WithResponseCallback(webhook.NewNotifier(url)))
+	ctx := context.Background()
+
+	// Confirm, send and delivery receipt
+	response, err := u.SendConfirmationMessage(ctx, testBooking("booking-1"))
+	if err != nil {
+		t.Fatalf("SendConfirmationMessage() error = %v", err)
+	}
+	traceID := response.TraceID
+	if traceID == "" {
+		t.Fatal("booking created without a trace ID")
+	}
+	booking, _ := u.pendingBookingFor(ctx, testPhone)
+	if booking == nil || booking.TraceID != traceID {
+		t.Fatalf("pending booking = %+v, want trace ID %s", booking, traceID)
+	}
+	if err := trace.UpdateStatus(ctx, booking.MessageID, whatsapp.SendStatusDelivered, time.Now()); err != nil {
+		t.Fatalf("UpdateStatus() error = %v", err)
+	}
+
+	// Customer reply and the callback it triggers
+	reply, err := u.ProcessIncomingResponse(ctx, testPhone, "Confirmar", ResponseConfirm)
+	if err != nil {
+		t.Fatalf("ProcessIncomingResponse() error = %v", err)
+	}
+	if reply.TraceID != traceID {
+		t.Errorf("reply trace ID = %q, want %q", reply.TraceID, traceID)
+	}
+	if _, intent := nextEvent(t, posted); intent.TraceID != traceID {
+		t.Errorf("callback trace ID = %q, want %q", intent.TraceID, traceID)
+	}
+
+	var lifecycle *BookingTrace
+	eventually(t, func() bool {
+		lifecycle, err = u.Trace(ctx, "booking-1")
+		return err == nil && stageIndex(lifecycle.Events, TraceStageCallback) >= 0
+	})
+	if lifecycle.TraceID != traceID || lifecycle.BookingID != "booking-1" {
+		t.Errorf("trace = %s for %s, want %s for booking-1", lifecycle.TraceID, lifecycle.BookingID, traceID)
+	}
+	previous := -1
+	for _, stage := range []string{TraceStageCreated, TraceStageSent, whatsapp.SendStatusDelivered, TraceStageReply, TraceStageCallback} {
+		i := stageIndex(lifecycle.Events, stage)
+		if i <= previous {
+			t.Fatalf("stage %s at %d after %d in %+v, want the lifecycle in order", stage, i, previous, lifecycle.Events)
+		}
+		previous = i
+	}
+	for _, event := range lifecycle.Events {
+		if event.TraceID != traceID || event.BookingID != "booking-1" {
+			t.Errorf("event %+v, want trace %s of booking-1", event, traceID)
+		}
+	}
+	if sent := lifecycle.Events[stageIndex(lifecycle.Events, TraceStageSent)]; sent.MessageID != booking.MessageID {
+		t.Errorf("sent event message ID = %q, want %q", sent.MessageID, booking.MessageID)
+	}
+
+	if _, err := u.Trace(ctx, "unknown"); err == nil {
+		t.Error("Trace() of an unknown booking succeeded")
+	}
+}
@@ -0,0 +1,298 @@
+package usecases
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/requestid"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/store"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/whatsapp"
+	"go.uber.org/zap"
+)
+
+// webhookQueueKeyPrefix is the state store key prefix for queued inbound
+// webhooks not yet processed. Keys carry the replica and the arrival time, so
+// each replica replays its own messages in order.
+const webhookQueueKeyPrefix = "whatsapp:webhook_queue:"
+
+// webhookQueueTTL is how long an unprocessed webhook is kept for replay
+const webhookQueueTTL = 24 * time.Hour
+
+// ErrWebhookQueueFull is returned when an inbound webhook cannot be queued
+var ErrWebhookQueueFull = errors.New("webhook queue is full")
+
+// ErrWebhookDispatcherStopped is returned when a webhook arrives after shutdown
+var ErrWebhookDispatcherStopped = errors.New("webhook dispatcher is stopped")
+
+// InboundWebhook is an inbound message received through the webhook
+type InboundWebhook struct {
+	From       string `json:"from"`
+	Body       string `json:"body"`
+	ResponseID string `json:"response_id,omitempty"`
+	RequestID  string `json:"request_id,omitempty"`
+
+	// queueKey is the state store key of a durable message
+	queueKey string
+}
+
+// WebhookDispatcher processes inbound webhooks in the background with a
+// fixed number of workers. All messages from a number go to the same worker,
+// so they are processed in the order they were received.
+type WebhookDispatcher struct {
+	bookingUseCase *BookingUseCase
+	logger         logger.Logger
+	queues         []chan InboundWebhook
+	stopped        bool
+	mu             sync.RWMutex
+	wg             sync.WaitGroup
+	// store persists queued messages until processed when durable
+	store     store.Store
+	replicaID string
+	// inflight holds the keys of the durable messages in the queues, so a
+	// replay does not queue them twice
+	inflight sync.Map
+}
+
+// WebhookDispatcherOption is a function that configures a WebhookDispatcher
+type WebhookDispatcherOption func(*WebhookDispatcher)
+
+// WithDurableQueue persists each message in the state store before it is
+// queued and removes it once processed, so messages queued when the process
+// dies are processed by Replay on the next start of the same replica, and
+// messages that failed while disconnected on the next Replay once connected
+func WithDurableQueue(stateStore store.Store, replicaID string) WebhookDispatcherOption {
+	return func(d *WebhookDispatcher) {
+		d.store = stateStore
+		d.replicaID = replicaID
+	}
+}
+
+// NewWebhookDispatcher creates a WebhookDispatcher with the given number of
+// workers, each holding up to queueSize pending messages, and starts it
+func NewWebhookDispatcher(bookingUseCase *BookingUseCase, logger logger.Logger, workers, queueSize int, options ...WebhookDispatcherOption) *WebhookDispatcher {
+	if workers <= 0 {
+		workers = 1
+	}
+	if queueSize <= 0 {
+		queueSize = 1
+	}
+
+	d := &WebhookDispatcher{
+		bookingUseCase: bookingUseCase,
+		logger:         logger,
+		queues:         make([]chan InboundWebhook, workers),
+	}
+	for _, option := range options {
+		option(d)
+	}
+	for i := range d.queues {
+		d.queues[i] = make(chan InboundWebhook, queueSize)
+		d.wg.Add(1)
+		go d.worker(d.queues[i])
+	}
+	return d
+}
+
+// Enqueue queues a message for processing without waiting for it. With a
+// durable queue the message is persisted first, and a failure to persist it
+// is returned so the sender retries.
+func (d *WebhookDispatcher) Enqueue(message InboundWebhook) error {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.stopped {
+		return ErrWebhookDispatcherStopped
+	}
+
+	if d.store != nil {
+		if err := d.persist(&message); err != nil {
+			return err
+		}
+		d.inflight.Store(message.queueKey, struct{}{})
+	}
+
+	select {
+	case d.queues[d.workerFor(message.From)] <- message:
+		return nil
+	default:
+		d.ack(message)
+		d.inflight.Delete(message.queueKey)
+		return ErrWebhookQueueFull
+	}
+}
+
+// Replay queues the messages this replica persisted but did not process, in
+// arrival order, and returns how many were queued. Messages still in the
+// queues are skipped, so it can run while messages arrive.
+func (d *WebhookDispatcher) Replay(ctx context.Context) (int, error) {
+	if d.store == nil {
+		return 0, nil
+	}
+
+	keys, err := d.store.Keys(ctx, webhookQueueKeyPrefix+d.replicaID+":*")
+	if err != nil {
+		return 0, fmt.Errorf("failed to list queued webhooks: %w", err)
+	}
+	sort.Strings(keys)
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.stopped {
+		return 0, ErrWebhookDispatcherStopped
+	}
+
+	replayed := 0
+	for _, key := range keys {
+		// Taken before reading it, so a message a worker acknowledges
+		// meanwhile is seen as gone rather than queued again
+		if _, queued := d.inflight.LoadOrStore(key, struct{}{}); queued {
+			continue
+		}
+		value, err := d.store.Get(ctx, key)
+		if errors.Is(err, store.ErrNotFound) {
+			d.inflight.Delete(key)
+			continue
+		}
+		if err != nil {
+			d.inflight.Delete(key)
+			return replayed, fmt.Errorf("failed to read queued webhook: %w", err)
+		}
+
+		var message InboundWebhook
+		if err := json.Unmarshal([]byte(value), &message); err != nil {
+			d.logger.Warn("Dropping undecodable queued webhook", zap.String("key", key), zap.Error(err))
+			_ = d.store.Delete(ctx, key)
+			d.inflight.Delete(key)
+			continue
+		}
+		message.queueKey = key
+
+		// Wait for room; the workers keep draining the queues
+		select {
+		case d.queues[d.workerFor(message.From)] <- message:
+			replayed++
+		case <-ctx.Done():
+			d.inflight.Delete(key)
+			return replayed, ctx.Err()
+		}
+	}
+	return replayed, nil
+}
+
+// Stop stops accepting messages and waits until the queued ones are processed
+// or the context ends
+func (d *WebhookDispatcher) Stop(ctx context.Context) error {
+	d.mu.Lock()
+	if !d.stopped {
+		d.stopped = true
+		for _, queue := range d.queues {
+			close(queue)
+		}
+	}
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// workerFor returns the index of the worker handling a number
+func (d *WebhookDispatcher) workerFor(from string) int {
+	h := fnv.New32a()
+	h.Write([]byte(from))
+	return int(h.Sum32() % uint32(len(d.queues)))
+}
+
+// worker processes the messages of one queue in order
+func (d *WebhookDispatcher) worker(queue <-chan InboundWebhook) {
+	defer d.wg.Done()
+
+	for message := range queue {
+		ctx := context.Background()
+		if message.RequestID != "" {
+			ctx = requestid.NewContext(ctx, message.RequestID)
+		}
+
+		_, err := d.bookingUseCase.ProcessIncomingResponse(ctx, message.From, message.Body, message.ResponseID)
+		if err != nil {
+			d.logger.Error("Failed to process queued webhook message",
+				zap.String("request_id", message.RequestID),
+				zap.Error(err))
+		}
+		// Messages that could not be processed while disconnected stay
+		// persisted for the next replay
+		if !errors.Is(err, whatsapp.ErrNotConnected) && !errors.Is(err, whatsapp.ErrAccountNotConnected) {
+			d.ack(message)
+		}
+		d.inflight.Delete(message.queueKey)
+	}
+}
+
+// StateHook returns a connection state hook that replays the durable queue
+// once connected, so the messages that failed while disconnected are
+// processed without waiting for a restart
+func (d *WebhookDispatcher) StateHook(ctx context.Context) whatsapp.ConnectionStateHook {
+	return func(_, state whatsapp.ConnectionState) {
+		if state != whatsapp.StateConnected || d.store == nil {
+			return
+		}
+
+		// Hooks must not block the connection state transition
+		go func() {
+			replayed, err := d.Replay(ctx)
+			if err != nil && !errors.Is(err, ErrWebhookDispatcherStopped) {
+				d.logger.Error("Failed to replay queued webhooks after connecting", zap.Error(err))
+			}
+			if replayed > 0 {
+				d.logger.Info("Replayed queued webhooks after connecting", zap.Int("count", replayed))
+			}
+		}()
+	}
+}
+
+// persist stores a message until it is processed
+func (d *WebhookDispatcher) persist(message *InboundWebhook) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook: %w", err)
+	}
+
+	key := fmt.Sprintf("%s%s:%020d:%s", webhookQueueKeyPrefix, d.replicaID, time.Now().UnixNano(), requestid.New())
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := d.store.Set(ctx, key, string(data), webhookQueueTTL); err != nil {
+		return fmt.Errorf("failed to persist webhook: %w", err)
+	}
+	message.queueKey = key
+	return nil
+}
+
+// ack removes a processed message from the durable queue
+func (d *WebhookDispatcher) ack(message InboundWebhook) {
+	if message.queueKey == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := d.store.Delete(ctx, message.queueKey); err != nil {
+		d.logger.Warn("Failed to remove processed webhook from the durable queue",
+			zap.String("request_id", message.RequestID),
+			zap.Error(err))
+	}
+}
@@ -0,0 +1,267 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/store"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/whatsapp"
+)
+
+// blockSends makes every send wait until release is closed, signalling each
+// send on started
+func blockSends(client *whatsapp.Client) (started <-chan struct{}, release chan struct{}) {
+	startedCh := make(chan struct{}, 16)
+	release = make(chan struct{})
+	client.OnBeforeSend(func(*whatsapp.OutboundMessage) error {
+		startedCh <- struct{}{}
+		<-release
+		return nil
+	})
+	return startedCh, release
+}
+
+func TestWebhookDispatcherRejectsWhenQueueIsFull(t *testing.T) {
+	client := newTestClient(t)
+	sent := captureSends(client)
+	started, release := blockSends(client)
+	stateStore := newFakeStore()
+	dispatcher := NewWebhookDispatcher(NewBookingUseCase(client, logger.NewNop()), logger.NewNop(), 1, 1,
+		WithDurableQueue(stateStore, "replica-1"))
+
+	// The only worker is busy replying to the first message
+	if err := dispatcher.Enqueue(InboundWebhook{From: testPhone, Body: "Sí"}); err != nil {
+		t.Fatalf("Enqueue() #1 error = %v", err)
+	}
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("first message not processed")
+	}
+
+	// The second fills its queue and the third is rejected
+	if err := dispatcher.Enqueue(InboundWebhook{From: testPhone, Body: "Sí"}); err != nil {
+		t.Fatalf("Enqueue() #2 error = %v", err)
+	}
+	if err := dispatcher.Enqueue(InboundWebhook{From: testPhone, Body: "No"}); !errors.Is(err, ErrWebhookQueueFull) {
+		t.Fatalf("Enqueue() #3 error = %v, want %v", err, ErrWebhookQueueFull)
+	}
+	// The rejected message is not left in the durable queue to be replayed
+	if keys, _ := stateStore.Keys(context.Background(), webhookQueueKeyPrefix+"*"); len(keys) != 2 {
+		t.Errorf("durable queue holds %d messages, want 2", len(keys))
+	}
+
+	close(release)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := dispatcher.Stop(ctx); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	// Both replies answer a "Sí"; the rejected "No" got none
+	if texts := sent.texts(); len(texts) != 2 || texts[0] != texts[1] {
+		t.Errorf("sent %q, want the same reply twice", texts)
+	}
+	if keys, _ := stateStore.Keys(context.Background(), webhookQueueKeyPrefix+"*"); len(keys) != 0 {
+		t.Errorf("durable queue holds %d messages after processing, want 0", len(keys))
+	}
+
+	if err := dispatcher.Enqueue(InboundWebhook{From: testPhone, Body: "Sí"}); !errors.Is(err, ErrWebhookDispatcherStopped) {
+		t.Errorf("Enqueue() after Stop error = %v, want %v", err, ErrWebhookDispatcherStopped)
+	}
+}
+
+func TestWebhookDispatcherKeepsOrderPerNumber(t *testing.T) {
+	client := newTestClient(t)
+	sent := captureSends(client)
+	// Uneven send times would reorder the replies of a number handled by
+	// more than one worker
+	var sends atomic.Int32
+	client.OnBeforeSend(func(*whatsapp.OutboundMessage) error {
+		time.Sleep(time.Duration(sends.Add(1)%3) * time.Millisecond)
+		return nil
+	})
+	dispatcher := NewWebhookDispatcher(NewBookingUseCase(client, logger.NewNop()), logger.NewNop(), 4, 32)
+
+	phones := []string{"56961234567", "56961234568", "56961234569"}
+	const perPhone = 10
+	for i := 0; i < perPhone; i++ {
+		for _, phone := range phones {
+			body := "Sí"
+			if i%2 == 1 {
+				body = "No"
+			}
+			if err := dispatcher.Enqueue(InboundWebhook{From: phone, Body: body}); err != nil {
+				t.Fatalf("Enqueue() error = %v", err)
+			}
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := dispatcher.Stop(ctx); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+
+	replies := make(map[string][]string)
+	for _, msg := range sent.all() {
+		replies[msg.To.User] = append(replies[msg.To.User], whatsapp.MessageText(msg.Message))
+	}
+	for _, phone := range phones {
+		texts := replies[phone]
+		if len(texts) != perPhone {
+			t.Fatalf("%s got %d replies, want %d", phone, len(texts), perPhone)
+		}
+		if texts[0] == texts[1] {
+			t.Fatalf("%s: the replies to Sí and No are both %q", phone, texts[0])
+		}
+		for i, text := range texts {
+			if text != texts[i%2] {
+				t.Errorf("%s reply %d = %q, want %q", phone, i, text, texts[i%2])
+			}
+		}
+	}
+}
+
+func TestDurableWebhookQueueSurvivesRestart(t *testing.T) {
+	redisClient, _ := newTestRedis(t)
+	queueStore := store.NewRedisStore(redisClient)
+	ctx := context.Background()
+
+	// The first run dies while its only worker is stuck on the first
+	// message, with the others still queued
+	crashed := newTestClient(t)
+	started, release := blockSends(crashed)
+	t.Cleanup(func() { close(release) })
+	first := NewWebhookDispatcher(NewBookingUseCase(crashed, logger.NewNop()), logger.NewNop(), 1, 8,
+		WithDurableQueue(queueStore, "replica-1"))
+	for _, body := range []string{"Sí", "No", "Hola"} {
+		if err := first.Enqueue(InboundWebhook{From: testPhone, Body: body}); err != nil {
+			t.Fatalf("Enqueue(%s) error = %v", body, err)
+		}
+	}
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("first message not processed")
+	}
+
+	// Messages of another replica are not replayed by this one
+	other := NewWebhookDispatcher(NewBookingUseCase(newTestClient(t), logger.NewNop()), logger.NewNop(), 1, 8,
+		WithDurableQueue(queueStore, "replica-2"))
+	if err := other.Enqueue(InboundWebhook{From: "56961234568", Body: "Sí"}); err != nil {
+		t.Fatalf("Enqueue() on replica-2 error = %v", err)
+	}
+	stopCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	if err := other.Stop(stopCtx); err != nil {
+		t.Fatalf("Stop() replica-2 error = %v", err)
+	}
+
+	// The restarted replica replays every unprocessed message in order
+	restarted := newTestClient(t)
+	sent := captureSends(restarted)
+	second := NewWebhookDispatcher(NewBookingUseCase(restarted, logger.NewNop()), logger.NewNop(), 1, 8,
+		WithDurableQueue(queueStore, "replica-1"))
+	replayed, err := second.Replay(ctx)
+	if err != nil || replayed != 3 {
+		t.Fatalf("Replay() = %d, %v, want 3", replayed, err)
+	}
+	if err := second.Stop(stopCtx); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	messages := sent.all()
+	if len(messages) != 3 {
+		t.Fatalf("replayed messages sent %d replies, want 3", len(messages))
+	}
+	for _, msg := range messages {
+		if msg.To.User != testPhone {
+			t.Errorf("replied to %s, want %s", msg.To.User, testPhone)
+		}
+	}
+
+	// Processed messages are acknowledged and not replayed again
+	keys, err := queueStore.Keys(ctx, webhookQueueKeyPrefix+"replica-1:*")
+	if err != nil || len(keys) != 0 {
+		t.Errorf("durable queue holds %v, %v after the replay, want none", keys, err)
+	}
+}
+
+func TestDurableWebhookQueueRejectsUnpersisted(t *testing.T) {
+	stateStore := newFakeStore()
+	dispatcher := NewWebhookDispatcher(NewBookingUseCase(newTestClient(t), logger.NewNop()), logger.NewNop(), 1, 8,
+		WithDurableQueue(stateStore, "replica-1"))
+
+	// A message that cannot be persisted is refused so the sender retries it
+	stateStore.fail(errors.New("redis down"))
+	if err := dispatcher.Enqueue(InboundWebhook{From: testPhone, Body: "Sí"}); err == nil {
+		t.Error("Enqueue() succeeded without persisting the message")
+	}
+}
+
+func TestDurableWebhookQueueReplaysOnConnect(t *testing.T) {
+	client := newTestClient(t)
+	// Sends fail as disconnected until the connection comes back; the
+	// hook registered first stops the capture below while offline
+	var offline atomic.Bool
+	var attempts atomic.Int32
+	offline.Store(true)
+	client.OnBeforeSend(func(*whatsapp.OutboundMessage) error {
+		attempts.Add(1)
+		if offline.Load() {
+			return whatsapp.ErrNotConnected
+		}
+		return nil
+	})
+	sent := captureSends(client)
+	stateStore := newFakeStore()
+	dispatcher := NewWebhookDispatcher(NewBookingUseCase(client, logger.NewNop()), logger.NewNop(), 1, 8,
+		WithDurableQueue(stateStore, "replica-1"))
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		dispatcher.Stop(ctx)
+	})
+	hook := dispatcher.StateHook(context.Background())
+
+	for _, body := range []string{"Sí", "No"} {
+		if err := dispatcher.Enqueue(InboundWebhook{From: testPhone, Body: body}); err != nil {
+			t.Fatalf("Enqueue(%s) error = %v", body, err)
+		}
+	}
+	// Both fail and stay persisted, out of the queues
+	eventually(t, func() bool {
+		queued := 0
+		dispatcher.inflight.Range(func(any, any) bool {
+			queued++
+			return true
+		})
+		return attempts.Load() == 2 && queued == 0
+	})
+	if keys, _ := stateStore.Keys(context.Background(), webhookQueueKeyPrefix+"*"); len(keys) != 2 {
+		t.Fatalf("durable queue holds %d messages while disconnected, want 2", len(keys))
+	}
+
+	// Other transitions do not replay them
+	hook(whatsapp.StateConnected, whatsapp.StateDisconnected)
+	time.Sleep(50 * time.Millisecond)
+	if got := attempts.Load(); got != 2 {
+		t.Fatalf("%d send attempts after disconnecting, want no replay", got)
+	}
+
+	// Once connected they are processed without a restart, once each even
+	// if the connection flaps
+	offline.Store(false)
+	hook(whatsapp.StateDisconnected, whatsapp.StateConnected)
+	hook(whatsapp.StateReconnecting, whatsapp.StateConnected)
+	eventually(t, func() bool {
+		keys, _ := stateStore.Keys(context.Background(), webhookQueueKeyPrefix+"*")
+		return len(sent.all()) == 2 && len(keys) == 0
+	})
+	time.Sleep(50 * time.Millisecond)
+	if got := len(sent.all()); got != 2 {
+		t.Errorf("sent %d replies after connecting, want 2", got)
+	}
+}
@@ -2,15 +2,30 @@ package usecases
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/store"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/utils"
 	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/whatsapp"
 	"go.uber.org/zap"
 )
 
+// qrTokenKeyPrefix is the state store key prefix for QR access tokens
+const qrTokenKeyPrefix = "whatsapp:qr_token:"
+
+// ErrInvalidQRToken is returned when a QR token is unknown, expired or already used
+var ErrInvalidQRToken = errors.New("invalid or expired QR token")
+
+// ErrQRTimeout is returned when no QR code was received in time
+var ErrQRTimeout = errors.New("timeout waiting for QR code")
+
 // WhatsAppAuthUseCase handles WhatsApp authentication
 type WhatsAppAuthUseCase struct {
 	client      *whatsapp.Client
@@ -18,6 +33,24 @@ type WhatsAppAuthUseCase struct {
 	qrTimeout   time.Duration
 	qrSize      int
 	qrCodeCache string
+	// qrIssuedAt is when WhatsApp issued the last QR code returned
+	qrIssuedAt time.Time
+	// qrPending counts the QR codes being generated
+	qrPending  atomic.Int32
+	tokenStore store.Store
+	qrTokenTTL time.Duration
+	// qrLifetime is how long a QR code stays valid
+	qrLifetime time.Duration
+	// refreshClient and refreshTick drive the QR refresh loop; the QR
+	// stream follows streamClient on the same tick
+	refreshClient qrRefreshClient
+	streamClient  qrStreamClient
+	refreshTick   time.Duration
+	autoRefresh   bool
+	refreshedQR   string
+	refreshedAt   time.Time
+	refreshMu     sync.RWMutex
+	attempts      *AuthAttemptUseCase
 	// QRCodeCache is exported for testing purposes
 	QRCodeCache string
 }
@@ -39,13 +72,40 @@ func WithQRSize(size int) WhatsAppAuthUseCaseOption {
 	}
 }
 
+// WithTokenStore sets the store holding one-time QR access tokens
+func WithTokenStore(tokenStore store.Store) WhatsAppAuthUseCaseOption {
+	return func(u *WhatsAppAuthUseCase) {
+		u.tokenStore = tokenStore
+	}
+}
+
+// WithQRTokenTTL sets how long a QR access token stays valid
+func WithQRTokenTTL(ttl time.Duration) WhatsAppAuthUseCaseOption {
+	return func(u *WhatsAppAuthUseCase) {
+		u.qrTokenTTL = ttl
+	}
+}
+
+// WithAuthAttempts records every QR generation and its outcome for auditing
+func WithAuthAttempts(attempts *AuthAttemptUseCase) WhatsAppAuthUseCaseOption {
+	return func(u *WhatsAppAuthUseCase) {
+		u.attempts = attempts
+	}
+}
+
 // NewWhatsAppAuthUseCase creates a new WhatsAppAuthUseCase
 func NewWhatsAppAuthUseCase(client *whatsapp.Client, logger logger.Logger, options ...WhatsAppAuthUseCaseOption) *WhatsAppAuthUseCase {
 	useCase := &WhatsAppAuthUseCase{
-		client:    client,
-		logger:    logger,
-		qrTimeout: 5 * time.Minute,
-		qrSize:    256,
+		client:     client,
+		logger:     logger,
+		qrTimeout:  5 * time.Minute,
+		qrSize:     256,
+		qrTokenTTL: 2 * time.Minute,
+		qrLifetime: qrCodeLifetime,
+		// The refresh loop and the QR stream drive the same client
+		refreshClient: client,
+		streamClient:  client,
+		refreshTick:   time.Second,
 	}
 
 	// Apply options
@@ -64,6 +124,19 @@ type Status struct {
 
 // GetStatus returns the current authentication status
 func (u *WhatsAppAuthUseCase) GetStatus() Status {
+	if u.client.IsReconnectFailed() {
+		return Status{
+			Status: "failed",
+			Phone:  u.client.GetPhoneNumber(),
+		}
+	}
+
+	if u.client.NeedsReauth() {
+		return Status{
+			Status: "needs_qr",
+		}
+	}
+
 	if u.client.IsLoggedIn() {
 		return Status{
 			Status: "connected",
@@ -80,7 +153,46 @@ func (u *WhatsAppAuthUseCase) GetStatus() Status {
 func (u *WhatsAppAuthUseCase) GenerateQR(ctx context.Context) (string, error) {
 	// If already logged in, return an error
 	if u.client.IsLoggedIn() {
-		return "", errors.New("already logged in")
+		return "", whatsapp.ErrAlreadyLoggedIn
+	}
+
+	u.qrPending.Add(1)
+	defer u.qrPending.Add(-1)
+
+	if u.attempts == nil {
+		return u.generateQR(ctx)
+	}
+	attemptID := u.attempts.Begin(ctx)
+	qrCode, err := u.generateQR(ctx)
+	u.attempts.Finish(ctx, attemptID, err)
+	return qrCode, err
+}
+
+// GenerateQRPNG generates a QR code for authentication rendered as a PNG
+// of the configured size
+func (u *WhatsAppAuthUseCase) GenerateQRPNG(ctx context.Context) ([]byte, error) {
+	qrCode, err := u.GenerateQR(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return utils.RenderQRPNG(qrCode, u.qrSize)
+}
+
+// generateQR waits for a QR code from the refresh loop or a new connection
+func (u *WhatsAppAuthUseCase) generateQR(ctx context.Context) (string, error) {
+	// Serve the code kept current by the refresh loop when it runs
+	if _, running := u.refreshedQRCode(); running {
+		qrCode, err := u.waitRefreshedQR(ctx)
+		if err != nil {
+			u.logger.Error("Timeout waiting for refreshed QR code")
+			return "", err
+		}
+		u.refreshMu.Lock()
+		u.qrCodeCache = qrCode
+		u.QRCodeCache = qrCode
+		u.qrIssuedAt = u.refreshedAt
+		u.refreshMu.Unlock()
+		return qrCode, nil
 	}
 
 	// Clear the QR code cache to ensure we get a fresh QR code
@@ -88,19 +200,17 @@ func (u *WhatsAppAuthUseCase) GenerateQR(ctx context.Context) (string, error) {
 	u.QRCodeCache = ""
 	u.logger.Info("Generating new QR code for authentication")
 
-	// Connect to WhatsApp if not connected
-	if !u.client.IsConnected() {
-		u.logger.Info("Connecting to WhatsApp for QR code generation")
-		if err := u.client.Connect(); err != nil {
-			u.logger.Error("Failed to connect to WhatsApp", zap.Error(err))
-			return "", fmt.Errorf("failed to connect to WhatsApp: %w", err)
-		}
-	}
-
 	// Create a context with timeout
 	ctx, cancel := context.WithTimeout(ctx, u.qrTimeout)
 	defer cancel()
 
+	// Connect to WhatsApp and wait until the connection can serve a QR code
+	u.logger.Info("Connecting to WhatsApp for QR code generation")
+	if err := u.client.ConnectAndWait(ctx); err != nil {
+		u.logger.Error("Failed to connect to WhatsApp", zap.Error(err))
+		return "", fmt.Errorf("failed to connect to WhatsApp: %w", err)
+	}
+
 	// Get the QR channel
 	qrChan := u.client.GetQRChannel(ctx)
 	u.logger.Info("Waiting for QR code from WhatsApp")
@@ -115,8 +225,11 @@ func (u *WhatsAppAuthUseCase) GenerateQR(ctx context.Context) (string, error) {
 		}
 
 		// Cache the QR code text
+		u.refreshMu.Lock()
 		u.qrCodeCache = qrCode
 		u.QRCodeCache = qrCode
+		u.qrIssuedAt = time.Now()
+		u.refreshMu.Unlock()
 		u.logger.Info("Successfully received and cached QR code",
 			zap.Int("qr_code_length", len(qrCode)))
 
@@ -124,8 +237,79 @@ func (u *WhatsAppAuthUseCase) GenerateQR(ctx context.Context) (string, error) {
 
 	case <-ctx.Done():
 		u.logger.Error("Timeout waiting for QR code from WhatsApp")
-		return "", errors.New("timeout waiting for QR code")
+		return "", ErrQRTimeout
+	}
+}
+
+// GeneratePairCode requests a code to link the device by entering it on the
+// phone, for hosts where scanning a QR is impractical. A login attempt uses
+// either the QR or the pairing code, not both.
+func (u *WhatsAppAuthUseCase) GeneratePairCode(ctx context.Context, phone string) (string, error) {
+	// If already logged in, return an error
+	if u.client.IsLoggedIn() {
+		return "", whatsapp.ErrAlreadyLoggedIn
+	}
+	u.logger.Info("Generating pairing code for authentication")
+
+	ctx, cancel := context.WithTimeout(ctx, u.qrTimeout)
+	defer cancel()
+
+	var attemptID string
+	if u.attempts != nil {
+		attemptID = u.attempts.Begin(ctx)
+	}
+	code, err := u.client.PairPhone(ctx, phone)
+	if u.attempts != nil {
+		u.attempts.Finish(ctx, attemptID, err)
+	}
+	if err != nil {
+		u.logger.Error("Failed to generate pairing code", zap.Error(err))
+		return "", err
+	}
+	return code, nil
+}
+
+// IssueQRToken issues a single-use token granting access to the QR code
+func (u *WhatsAppAuthUseCase) IssueQRToken(ctx context.Context) (string, time.Time, error) {
+	if u.tokenStore == nil {
+		return "", time.Time{}, errors.New("QR tokens are not configured")
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to generate QR token: %w", err)
+	}
+	token := hex.EncodeToString(buf)
+
+	if err := u.tokenStore.Set(ctx, qrTokenKeyPrefix+token, "1", u.qrTokenTTL); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to store QR token: %w", err)
+	}
+
+	u.logger.Info("Issued QR access token", zap.Duration("ttl", u.qrTokenTTL))
+	return token, time.Now().Add(u.qrTokenTTL), nil
+}
+
+// ConsumeQRToken validates a QR token and invalidates it
+func (u *WhatsAppAuthUseCase) ConsumeQRToken(ctx context.Context, token string) error {
+	if u.tokenStore == nil || token == "" {
+		return ErrInvalidQRToken
+	}
+
+	if _, err := u.tokenStore.Take(ctx, qrTokenKeyPrefix+token); err != nil {
+		return ErrInvalidQRToken
+	}
+	return nil
+}
+
+// Connect connects to WhatsApp, resetting the reconnect counter so a
+// session that gave up reconnecting resumes
+func (u *WhatsAppAuthUseCase) Connect() error {
+	u.logger.Info("Manual connect requested")
+	if err := u.client.Reconnect(); err != nil {
+		u.logger.Error("Failed to connect to WhatsApp", zap.Error(err))
+		return fmt.Errorf("failed to connect to WhatsApp: %w", err)
 	}
+	return nil
 }
 
 // Logout logs out from WhatsApp
@@ -0,0 +1,145 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+)
+
+func TestQRTokens(t *testing.T) {
+	const ttl = 2 * time.Minute
+	tests := []struct {
+		name string
+		// use consumes the issued token and returns the error of the last
+		// consumption
+		use     func(u *WhatsAppAuthUseCase, s *fakeStore, token string) error
+		wantErr error
+	}{
+		{
+			name: "valid",
+			use: func(u *WhatsAppAuthUseCase, s *fakeStore, token string) error {
+				return u.ConsumeQRToken(context.Background(), token)
+			},
+		},
+		{
+			name: "used just before it expires",
+			use: func(u *WhatsAppAuthUseCase, s *fakeStore, token string) error {
+				s.advance(ttl - time.Second)
+				return u.ConsumeQRToken(context.Background(), token)
+			},
+		},
+		{
+			name: "expired",
+			use: func(u *WhatsAppAuthUseCase, s *fakeStore, token string) error {
+				s.advance(ttl)
+				return u.ConsumeQRToken(context.Background(), token)
+			},
+			wantErr: ErrInvalidQRToken,
+		},
+		{
+			name: "reused",
+			use: func(u *WhatsAppAuthUseCase, s *fakeStore, token string) error {
+				if err := u.ConsumeQRToken(context.Background(), token); err != nil {
+					t.Fatalf("first ConsumeQRToken() error = %v", err)
+				}
+				return u.ConsumeQRToken(context.Background(), token)
+			},
+			wantErr: ErrInvalidQRToken,
+		},
+		{
+			name: "unknown",
+			use: func(u *WhatsAppAuthUseCase, s *fakeStore, token string) error {
+				return u.ConsumeQRToken(context.Background(), token+"0")
+			},
+			wantErr: ErrInvalidQRToken,
+		},
+		{
+			name: "empty",
+			use: func(u *WhatsAppAuthUseCase, s *fakeStore, token string) error {
+				return u.ConsumeQRToken(context.Background(), "")
+			},
+			wantErr: ErrInvalidQRToken,
+		},
+		{
+			name: "store unavailable",
+			use: func(u *WhatsAppAuthUseCase, s *fakeStore, token string) error {
+				s.fail(errStoreDown)
+				return u.ConsumeQRToken(context.Background(), token)
+			},
+			wantErr: ErrInvalidQRToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokenStore := newFakeStore()
+			useCase := NewWhatsAppAuthUseCase(newTestClient(t), logger.NewNop(), WithTokenStore(tokenStore), WithQRTokenTTL(ttl))
+
+			token, expiresAt, err := useCase.IssueQRToken(context.Background())
+			if err != nil {
+				t.Fatalf("IssueQRToken() error = %v", err)
+			}
+			if len(token) != 64 {
+				t.Errorf("token %q has %d characters, want 64", token, len(token))
+			}
+			if until := time.Until(expiresAt); until <= ttl-time.Minute || until > ttl {
+				t.Errorf("expires in %v, want %v", until, ttl)
+			}
+			if got := tokenStore.expiration(qrTokenKeyPrefix + token); got != ttl {
+				t.Errorf("token stored for %v, want %v", got, ttl)
+			}
+
+			if err := tt.use(useCase, tokenStore, token); !errors.Is(err, tt.wantErr) {
+				t.Errorf("ConsumeQRToken() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestQRTokenSingleUseUnderConcurrency(t *testing.T) {
+	tokenStore := newFakeStore()
+	useCase := NewWhatsAppAuthUseCase(newTestClient(t), logger.NewNop(), WithTokenStore(tokenStore))
+	token, _, err := useCase.IssueQRToken(context.Background())
+	if err != nil {
+		t.Fatalf("IssueQRToken() error = %v", err)
+	}
+
+	// Take reads and deletes atomically, so only one request gets through
+	var accepted atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if useCase.ConsumeQRToken(context.Background(), token) == nil {
+				accepted.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := accepted.Load(); got != 1 {
+		t.Errorf("%d requests accepted the token, want 1", got)
+	}
+	if tokenStore.takes != 10 {
+		t.Errorf("store saw %d takes, want 10", tokenStore.takes)
+	}
+	if keys, _ := tokenStore.Keys(context.Background(), qrTokenKeyPrefix+"*"); len(keys) != 0 {
+		t.Errorf("store still holds %v", keys)
+	}
+}
+
+func TestQRTokensWithoutStore(t *testing.T) {
+	useCase := NewWhatsAppAuthUseCase(newTestClient(t), logger.NewNop())
+	if _, _, err := useCase.IssueQRToken(context.Background()); err == nil {
+		t.Error("IssueQRToken() without a store succeeded")
+	}
+	if err := useCase.ConsumeQRToken(context.Background(), "token"); !errors.Is(err, ErrInvalidQRToken) {
+		t.Errorf("ConsumeQRToken() error = %v, want %v", err, ErrInvalidQRToken)
+	}
+}
@@ -1,21 +1,136 @@
 package auth
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// ErrReservedClaim se devuelve cuando un claim personalizado intenta
+// reemplazar un claim reservado
+var ErrReservedClaim = errors.New("reserved claim")
+
+// reservedClaims son los claims que el servicio controla y que los claims
+// personalizados no pueden reemplazar
+var reservedClaims = map[string]bool{
+	"iss": true, "sub": true, "aud": true, "exp": true, "nbf": true, "iat": true, "jti": true,
+	"user_id": true, "tenant_id": true,
+}
+
 // Claims representa los datos que se almacenarán en el token JWT
 type Claims struct {
 	UserID string `json:"user_id"`
+	// TenantID identifica la cuenta del integrador y selecciona la cuenta de
+	// WhatsApp que envía sus mensajes
+	TenantID string `json:"tenant_id,omitempty"`
+	// Custom contiene los claims personalizados del integrador, que se
+	// serializan al mismo nivel que los demás
+	Custom map[string]interface{} `json:"-"`
 	jwt.RegisteredClaims
 }
 
-// GenerateToken genera un nuevo token JWT con el user_id proporcionado
-func GenerateToken(userID string) (string, error) {
+// claimsJSON evita la recursión al serializar Claims
+type claimsJSON Claims
+
+// MarshalJSON agrega los claims personalizados a los claims del token
+func (c Claims) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(claimsJSON(c))
+	if err != nil || len(c.Custom) == 0 {
+		return data, err
+	}
+
+	merged := make(map[string]interface{}, len(c.Custom))
+	for name, value := range c.Custom {
+		if reservedClaims[name] {
+			return nil, fmt.Errorf("%w: %s", ErrReservedClaim, name)
+		}
+		merged[name] = value
+	}
+	var standard map[string]interface{}
+	if err := json.Unmarshal(data, &standard); err != nil {
+		return nil, err
+	}
+	for name, value := range standard {
+		merged[name] = value
+	}
+	return json.Marshal(merged)
+}
+
+// UnmarshalJSON lee los claims del token y deja en Custom los que no son
+// reservados
+func (c *Claims) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, (*claimsJSON)(c)); err != nil {
+		return err
+	}
+
+	var all map[string]interface{}
+	if err := json.Unmarshal(data, &all); err != nil {
+		return err
+	}
+	c.Custom = nil
+	for name, value := range all {
+		if reservedClaims[name] {
+			continue
+		}
+		if c.Custom == nil {
+			c.Custom = make(map[string]interface{})
+		}
+		c.Custom[name] = value
+	}
+	return nil
+}
+
+// TokenOptions configura los claims adicionales de un token
+type TokenOptions struct {
+	// TenantID se guarda en el claim tenant_id
+	TenantID string
+	// Custom se agrega al token; no puede contener claims reservados
+	Custom map[string]interface{}
+}
+
+// TokenOption modifica las opciones de un token
+type TokenOption func(*TokenOptions)
+
+// WithTenantID agrega el claim tenant_id al token
+func WithTenantID(tenantID string) TokenOption {
+	return func(o *TokenOptions) {
+		o.TenantID = tenantID
+	}
+}
+
+// WithCustomClaims agrega claims personalizados al token, por ejemplo los
+// permisos del integrador
+func WithCustomClaims(custom map[string]interface{}) TokenOption {
+	return func(o *TokenOptions) {
+		if o.Custom == nil {
+			o.Custom = make(map[string]interface{}, len(custom))
+		}
+		for name, value := range custom {
+			o.Custom[name] = value
+		}
+	}
+}
+
+// GenerateToken genera un nuevo token JWT con el user_id proporcionado y los
+// claims adicionales de las opciones. Devuelve ErrReservedClaim si un claim
+// personalizado coincide con uno reservado.
+func GenerateToken(userID string, options ...TokenOption) (string, error) {
+	var tokenOptions TokenOptions
+	for _, option := range options {
+		option(&tokenOptions)
+	}
+	for name := range tokenOptions.Custom {
+		if reservedClaims[name] {
+			return "", fmt.Errorf("%w: %s", ErrReservedClaim, name)
+		}
+	}
+
 	// Obtener la clave secreta y el tiempo de expiración de las variables de entorno
 	secretKey := os.Getenv("JWT_SECRET")
 	if secretKey == "" {
@@ -33,10 +148,19 @@ func GenerateToken(userID string) (string, error) {
 		return "", err
 	}
 
+	// El jti identifica al token para poder revocarlo
+	tokenID, err := newTokenID()
+	if err != nil {
+		return "", err
+	}
+
 	// Crear los claims con el user_id y la información de expiración
 	claims := &Claims{
-		UserID: userID,
+		UserID:   userID,
+		TenantID: tokenOptions.TenantID,
+		Custom:   tokenOptions.Custom,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID,
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresDuration)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
@@ -55,7 +179,8 @@ func GenerateToken(userID string) (string, error) {
 	return tokenString, nil
 }
 
-// ValidateToken valida un token JWT y devuelve los claims si es válido
+// ValidateToken valida un token JWT y devuelve los claims si es válido,
+// incluidos los personalizados
 func ValidateToken(tokenString string) (*Claims, error) {
 	// Obtener la clave secreta de las variables de entorno
 	secretKey := os.Getenv("JWT_SECRET")
@@ -83,3 +208,12 @@ func ValidateToken(tokenString string) (*Claims, error) {
 
 	return claims, nil
 }
+
+// newTokenID genera un jti aleatorio
+func newTokenID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
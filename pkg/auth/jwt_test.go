@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestCustomClaimsRoundTrip(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+
+	token, err := GenerateToken("user-1",
+		WithTenantID("acme"),
+		WithCustomClaims(map[string]interface{}{"permissions": []string{"send", "read"}}),
+		WithCustomClaims(map[string]interface{}{"plan": "pro"}))
+	if err != nil {
+		t.Fatalf("GenerateToken() error = %v", err)
+	}
+
+	claims, err := ValidateToken(token)
+	if err != nil {
+		t.Fatalf("ValidateToken() error = %v", err)
+	}
+	if claims.UserID != "user-1" || claims.TenantID != "acme" || claims.ID == "" {
+		t.Errorf("claims = %+v, want user-1 of acme with a jti", claims)
+	}
+	// Los claims personalizados vuelven tal como se leen de JSON
+	want := map[string]interface{}{
+		"permissions": []interface{}{"send", "read"},
+		"plan":        "pro",
+	}
+	if !reflect.DeepEqual(claims.Custom, want) {
+		t.Errorf("custom claims = %v, want %v", claims.Custom, want)
+	}
+
+	// Un token sin claims personalizados no los inventa
+	token, err = GenerateToken("user-2")
+	if err != nil {
+		t.Fatalf("GenerateToken() without options error = %v", err)
+	}
+	claims, err = ValidateToken(token)
+	if err != nil {
+		t.Fatalf("ValidateToken() error = %v", err)
+	}
+	if claims.TenantID != "" || claims.Custom != nil {
+		t.Errorf("claims = %+v, want no tenant nor custom claims", claims)
+	}
+}
+
+func TestReservedClaimsCannotBeOverridden(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+
+	for _, name := range []string{"exp", "iat", "jti", "sub", "user_id", "tenant_id"} {
+		_, err := GenerateToken("user-1", WithCustomClaims(map[string]interface{}{name: "forged"}))
+		if !errors.Is(err, ErrReservedClaim) {
+			t.Errorf("GenerateToken() with custom %s error = %v, want %v", name, err, ErrReservedClaim)
+		}
+	}
+
+	// Serializar los claims directamente tampoco permite reemplazarlos
+	claims := Claims{UserID: "user-1", Custom: map[string]interface{}{"user_id": "admin"}}
+	if _, err := json.Marshal(claims); !errors.Is(err, ErrReservedClaim) {
+		t.Errorf("json.Marshal() with a reserved custom claim error = %v, want %v", err, ErrReservedClaim)
+	}
+}
+
+func TestValidateTokenRejectsOtherSecret(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+	token, err := GenerateToken("user-1", WithTenantID("acme"))
+	if err != nil {
+		t.Fatalf("GenerateToken() error = %v", err)
+	}
+
+	t.Setenv("JWT_SECRET", "other-secret")
+	if _, err := ValidateToken(token); err == nil {
+		t.Error("ValidateToken() accepted a token signed with another secret")
+	}
+}
@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/redis"
+)
+
+// ErrTokenRevoked se devuelve cuando el token fue revocado antes de expirar
+var ErrTokenRevoked = errors.New("token revocado")
+
+// ErrRevocationUnavailable se devuelve cuando no se puede consultar la lista
+// de revocación; el token se rechaza porque no se sabe si fue revocado
+var ErrRevocationUnavailable = errors.New("lista de revocación no disponible")
+
+// ErrMissingTokenID se devuelve al revocar un token sin jti, emitido antes
+// de que los tokens lo incluyeran
+var ErrMissingTokenID = errors.New("el token no tiene jti")
+
+// revokedKeyPrefix es el prefijo de las claves de los tokens revocados
+const revokedKeyPrefix = "auth:revoked:"
+
+// RevocationList guarda en Redis los jti de los tokens revocados hasta que
+// los tokens expiran
+type RevocationList struct {
+	client *redis.Client
+}
+
+// NewRevocationList crea una lista de revocación respaldada por Redis
+func NewRevocationList(client *redis.Client) *RevocationList {
+	return &RevocationList{client: client}
+}
+
+// RevokeToken revoca el token con el jti indicado durante ttl, que debe ser
+// lo que le queda de vida al token para que la entrada expire con él
+func (l *RevocationList) RevokeToken(ctx context.Context, tokenID string, ttl time.Duration) error {
+	if tokenID == "" {
+		return ErrMissingTokenID
+	}
+	if ttl <= 0 {
+		// El token ya expiró y ValidateToken lo rechaza
+		return nil
+	}
+	return l.client.Set(ctx, revokedKeyPrefix+tokenID, "1", ttl)
+}
+
+// RevokeClaims revoca el token de los claims hasta su expiración
+func (l *RevocationList) RevokeClaims(ctx context.Context, claims *Claims) error {
+	var ttl time.Duration
+	if claims.ExpiresAt != nil {
+		ttl = time.Until(claims.ExpiresAt.Time)
+	}
+	return l.RevokeToken(ctx, claims.ID, ttl)
+}
+
+// IsRevoked indica si el token con el jti indicado fue revocado
+func (l *RevocationList) IsRevoked(ctx context.Context, tokenID string) (bool, error) {
+	_, err := l.client.Get(ctx, revokedKeyPrefix+tokenID)
+	if errors.Is(err, redis.Nil) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ValidateTokenWithRevocation valida el token como ValidateToken y además lo
+// rechaza con ErrTokenRevoked si fue revocado. Si la lista no responde
+// devuelve ErrRevocationUnavailable. Sin lista equivale a ValidateToken.
+func ValidateTokenWithRevocation(ctx context.Context, tokenString string, revocations *RevocationList) (*Claims, error) {
+	claims, err := ValidateToken(tokenString)
+	// Los tokens emitidos antes de agregar el jti no pueden revocarse
+	if err != nil || revocations == nil || claims.ID == "" {
+		return claims, err
+	}
+
+	revoked, err := revocations.IsRevoked(ctx, claims.ID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrRevocationUnavailable, err)
+	}
+	if revoked {
+		return nil, ErrTokenRevoked
+	}
+	return claims, nil
+}
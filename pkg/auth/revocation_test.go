@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/redis"
+)
+
+// newTestRevocationList crea una lista de revocación sobre un Redis en memoria
+func newTestRevocationList(t *testing.T) (*RevocationList, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(server.Addr())
+	t.Cleanup(func() { client.Close() })
+	return NewRevocationList(client), server
+}
+
+func TestValidateTokenWithRevocation(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+	revocations, _ := newTestRevocationList(t)
+	ctx := context.Background()
+
+	token, err := GenerateToken("user-1")
+	if err != nil {
+		t.Fatalf("GenerateToken() error = %v", err)
+	}
+	if _, err := ValidateTokenWithRevocation(ctx, token, revocations); err != nil {
+		t.Fatalf("ValidateTokenWithRevocation() antes de revocar error = %v", err)
+	}
+
+	claims, _ := ValidateToken(token)
+	if err := revocations.RevokeClaims(ctx, claims); err != nil {
+		t.Fatalf("RevokeClaims() error = %v", err)
+	}
+	if _, err := ValidateTokenWithRevocation(ctx, token, revocations); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("ValidateTokenWithRevocation() revocado error = %v, want %v", err, ErrTokenRevoked)
+	}
+
+	// Los demás tokens siguen siendo válidos
+	other, _ := GenerateToken("user-1")
+	if _, err := ValidateTokenWithRevocation(ctx, other, revocations); err != nil {
+		t.Errorf("ValidateTokenWithRevocation() de otro token error = %v", err)
+	}
+}
+
+func TestRevocationUnavailable(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+	revocations, server := newTestRevocationList(t)
+	token, _ := GenerateToken("user-1")
+
+	// Sin Redis no se sabe si el token fue revocado, así que se rechaza
+	server.Close()
+	if _, err := ValidateTokenWithRevocation(context.Background(), token, revocations); !errors.Is(err, ErrRevocationUnavailable) {
+		t.Errorf("ValidateTokenWithRevocation() error = %v, want %v", err, ErrRevocationUnavailable)
+	}
+}
+
+func TestRevocationExpiresWithToken(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+	t.Setenv("JWT_EXPIRES", "30m")
+	revocations, server := newTestRevocationList(t)
+	ctx := context.Background()
+
+	token, _ := GenerateToken("user-1")
+	claims, _ := ValidateToken(token)
+	if err := revocations.RevokeClaims(ctx, claims); err != nil {
+		t.Fatalf("RevokeClaims() error = %v", err)
+	}
+
+	// La entrada dura lo que le queda de vida al token
+	ttl := server.TTL(revokedKeyPrefix + claims.ID)
+	if ttl > 30*time.Minute || ttl < 29*time.Minute {
+		t.Errorf("TTL = %v, want about 30m", ttl)
+	}
+
+	// Al expirar el token la entrada desaparece
+	server.FastForward(31 * time.Minute)
+	if revoked, err := revocations.IsRevoked(ctx, claims.ID); err != nil || revoked {
+		t.Errorf("IsRevoked() tras expirar = %t, %v, want false", revoked, err)
+	}
+
+	// Un token sin jti no puede revocarse
+	if err := revocations.RevokeToken(ctx, "", time.Minute); !errors.Is(err, ErrMissingTokenID) {
+		t.Errorf("RevokeToken() sin jti error = %v, want %v", err, ErrMissingTokenID)
+	}
+}
@@ -0,0 +1,372 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadCorsPolicies(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    []CorsPolicy
+		wantErr string
+	}{
+		{
+			name: "no policies",
+			env:  map[string]string{"CORS_POLICIES": ""},
+		},
+		{
+			name: "admin and widget",
+			env: map[string]string{
+				"CORS_POLICIES":                 "admin, widget",
+				"CORS_POLICY_ADMIN_ORIGINS":     "https://admin.example.com",
+				"CORS_POLICY_ADMIN_CREDENTIALS": "true",
+				"CORS_POLICY_ADMIN_ROUTES":      "/admin,/auth",
+				"CORS_POLICY_WIDGET_ORIGINS":    "*",
+				"CORS_POLICY_WIDGET_ROUTES":     "/booking",
+			},
+			want: []CorsPolicy{
+				{Name: "admin", AllowedOrigins: []string{"https://admin.example.com"}, AllowCredentials: true, Routes: []string{"/admin", "/auth"}},
+				{Name: "widget", AllowedOrigins: []string{"*"}, Routes: []string{"/booking"}},
+			},
+		},
+		{
+			name: "wildcard with credentials",
+			env: map[string]string{
+				"CORS_POLICIES":                  "widget",
+				"CORS_POLICY_WIDGET_ORIGINS":     "https://a.example.com,*",
+				"CORS_POLICY_WIDGET_CREDENTIALS": "true",
+				"CORS_POLICY_WIDGET_ROUTES":      "/booking",
+			},
+			wantErr: "wildcard origin cannot be combined with credentials",
+		},
+		{
+			name: "missing origins",
+			env: map[string]string{
+				"CORS_POLICIES":             "widget",
+				"CORS_POLICY_WIDGET_ROUTES": "/booking",
+			},
+			wantErr: "CORS_POLICY_WIDGET_ORIGINS is required",
+		},
+		{
+			name: "missing routes",
+			env: map[string]string{
+				"CORS_POLICIES":              "widget",
+				"CORS_POLICY_WIDGET_ORIGINS": "*",
+			},
+			wantErr: "CORS_POLICY_WIDGET_ROUTES is required",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			got, err := loadCorsPolicies()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("loadCorsPolicies() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadCorsPolicies() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("loadCorsPolicies() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadRejectsWildcardDefaultWithCredentials(t *testing.T) {
+	t.Setenv("CORS_ALLOWED_ORIGINS", "*")
+	t.Setenv("CORS_ALLOW_CREDENTIALS", "true")
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "wildcard origin") {
+		t.Fatalf("Load() error = %v, want wildcard origin error", err)
+	}
+
+	t.Setenv("CORS_ALLOW_CREDENTIALS", "false")
+	if _, err := Load(); err != nil {
+		t.Fatalf("Load() without credentials error = %v", err)
+	}
+}
+
+func TestLoadRetryConfig(t *testing.T) {
+	defaults := RetryConfig{BaseDelay: time.Second, MaxDelay: time.Minute, MaxAttempts: 5, Multiplier: 2, Jitter: 0.2}
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    RetryConfig
+		wantErr string
+	}{
+		{
+			name: "defaults",
+			want: defaults,
+		},
+		{
+			name: "every field",
+			env: map[string]string{
+				"TEST_RETRY_BASE_DELAY":   "500ms",
+				"TEST_RETRY_MAX_DELAY":    "30s",
+				"TEST_RETRY_MAX_ATTEMPTS": "0",
+				"TEST_RETRY_MULTIPLIER":   "1.5",
+				"TEST_RETRY_JITTER":       "0",
+			},
+			want: RetryConfig{BaseDelay: 500 * time.Millisecond, MaxDelay: 30 * time.Second, MaxAttempts: 0, Multiplier: 1.5},
+		},
+		{
+			name: "partial override",
+			env:  map[string]string{"TEST_RETRY_MAX_ATTEMPTS": "8"},
+			want: RetryConfig{BaseDelay: time.Second, MaxDelay: time.Minute, MaxAttempts: 8, Multiplier: 2, Jitter: 0.2},
+		},
+		{name: "unparsable delay", env: map[string]string{"TEST_RETRY_BASE_DELAY": "soon"}, wantErr: "invalid TEST_RETRY_BASE_DELAY"},
+		{name: "unparsable attempts", env: map[string]string{"TEST_RETRY_MAX_ATTEMPTS": "many"}, wantErr: "invalid TEST_RETRY_MAX_ATTEMPTS"},
+		{name: "zero base delay", env: map[string]string{"TEST_RETRY_BASE_DELAY": "0s"}, wantErr: "must be positive"},
+		{name: "max below base", env: map[string]string{"TEST_RETRY_MAX_DELAY": "100ms"}, wantErr: "must be at least the base delay"},
+		{name: "negative attempts", env: map[string]string{"TEST_RETRY_MAX_ATTEMPTS": "-1"}, wantErr: "must be 0 (unlimited) or more"},
+		{name: "shrinking multiplier", env: map[string]string{"TEST_RETRY_MULTIPLIER": "0.5"}, wantErr: "must be at least 1"},
+		{name: "jitter above one", env: map[string]string{"TEST_RETRY_JITTER": "1.5"}, wantErr: "must be between 0 and 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			got, err := loadRetryConfig("TEST_RETRY", defaults)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("loadRetryConfig() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadRetryConfig() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("loadRetryConfig() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadFeatureRetryOverrides(t *testing.T) {
+	t.Setenv("RETRY_BASE_DELAY", "2s")
+	t.Setenv("RETRY_MAX_ATTEMPTS", "4")
+	t.Setenv("RECONNECT_MAX_ATTEMPTS", "12")
+	t.Setenv("CALLBACK_RETRY_MAX_ATTEMPTS", "3")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Retry.BaseDelay != 2*time.Second || cfg.Retry.MaxAttempts != 4 {
+		t.Errorf("Retry = %+v, want a 2s base delay and 4 attempts", cfg.Retry)
+	}
+	// Features inherit the shared backoff and override single fields
+	if cfg.CallbackRetry.BaseDelay != 2*time.Second || cfg.CallbackRetry.MaxAttempts != 3 {
+		t.Errorf("CallbackRetry = %+v, want the shared 2s base delay and 3 attempts", cfg.CallbackRetry)
+	}
+	// The legacy reconnect ceiling still applies
+	if cfg.ReconnectRetry.BaseDelay != 2*time.Second || cfg.ReconnectRetry.MaxAttempts != 12 {
+		t.Errorf("ReconnectRetry = %+v, want the shared 2s base delay and 12 attempts", cfg.ReconnectRetry)
+	}
+	t.Setenv("RECONNECT_RETRY_MAX_ATTEMPTS", "20")
+	if cfg, err = Load(); err != nil || cfg.ReconnectRetry.MaxAttempts != 20 {
+		t.Errorf("ReconnectRetry with an explicit ceiling = %+v, %v, want 20 attempts", cfg.ReconnectRetry, err)
+	}
+
+	// An invalid override keeps the inherited backoff and is reported
+	t.Setenv("CALLBACK_RETRY_JITTER", "2")
+	if cfg, err = Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.CallbackRetry != cfg.Retry {
+		t.Errorf("CallbackRetry = %+v, want the inherited %+v", cfg.CallbackRetry, cfg.Retry)
+	}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "CALLBACK_RETRY_JITTER") {
+		t.Errorf("Validate() with an invalid callback jitter error = %v", err)
+	}
+}
+
+func TestLoadSandboxNumbers(t *testing.T) {
+	t.Setenv("SANDBOX_NUMBERS", "+56 9 6123 4501, 961234502")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(cfg.SandboxNumbers) != 2 || cfg.SandboxNumbers[0] != "56961234501" || cfg.SandboxNumbers[1] != "56961234502" {
+		t.Errorf("SandboxNumbers = %v, want both numbers normalized", cfg.SandboxNumbers)
+	}
+
+	t.Setenv("SANDBOX_NUMBERS", "56961234501,123")
+	if cfg, err = Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(cfg.SandboxNumbers) != 1 || cfg.SandboxNumbers[0] != "56961234501" {
+		t.Errorf("SandboxNumbers = %v, want the invalid number skipped", cfg.SandboxNumbers)
+	}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "SANDBOX_NUMBERS") {
+		t.Errorf("Validate() with an invalid sandbox number error = %v", err)
+	}
+}
+
+func TestLoadMessageDisappearAfter(t *testing.T) {
+	t.Setenv("MESSAGE_DISAPPEAR_AFTER", "604800")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.MessageDisappearAfter != 7*24*time.Hour {
+		t.Errorf("MessageDisappearAfter = %s, want 7 days", cfg.MessageDisappearAfter)
+	}
+
+	for _, value := range []string{"3600", "-86400", "1d"} {
+		t.Setenv("MESSAGE_DISAPPEAR_AFTER", value)
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("Load() with MESSAGE_DISAPPEAR_AFTER=%s error = %v", value, err)
+		}
+		if cfg.MessageDisappearAfter != 0 {
+			t.Errorf("MessageDisappearAfter with %s = %s, want the default 0", value, cfg.MessageDisappearAfter)
+		}
+		if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "MESSAGE_DISAPPEAR_AFTER") {
+			t.Errorf("Validate() with MESSAGE_DISAPPEAR_AFTER=%s error = %v", value, err)
+		}
+	}
+}
+
+func TestLoadDefaultPhoneRegion(t *testing.T) {
+	t.Setenv("DEFAULT_PHONE_REGION", "+56")
+	t.Setenv("SANDBOX_NUMBERS", "9 6123 4501")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.DefaultPhoneRegion != "CL" {
+		t.Errorf("DefaultPhoneRegion = %q, want CL", cfg.DefaultPhoneRegion)
+	}
+	if len(cfg.SandboxNumbers) != 1 || cfg.SandboxNumbers[0] != "56961234501" {
+		t.Errorf("SandboxNumbers = %v, want the local number completed", cfg.SandboxNumbers)
+	}
+
+	t.Setenv("DEFAULT_PHONE_REGION", "XX")
+	if cfg, err = Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.DefaultPhoneRegion != "CL" {
+		t.Errorf("DefaultPhoneRegion = %q, want the default CL", cfg.DefaultPhoneRegion)
+	}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "DEFAULT_PHONE_REGION") {
+		t.Errorf("Validate() with an unknown region error = %v", err)
+	}
+}
+
+func TestLoadAccountPhoneRegions(t *testing.T) {
+	t.Setenv("ACCOUNT_PHONE_REGIONS", "acme=ar, globex=+55")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(cfg.AccountPhoneRegions) != 2 || cfg.AccountPhoneRegions["acme"] != "AR" || cfg.AccountPhoneRegions["globex"] != "BR" {
+		t.Errorf("AccountPhoneRegions = %v, want acme=AR and globex=BR", cfg.AccountPhoneRegions)
+	}
+
+	// Invalid entries keep DEFAULT_PHONE_REGION for the account
+	t.Setenv("ACCOUNT_PHONE_REGIONS", "acme=XX,globex,=BR")
+	if cfg, err = Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(cfg.AccountPhoneRegions) != 0 {
+		t.Errorf("AccountPhoneRegions = %v, want none", cfg.AccountPhoneRegions)
+	}
+	if err := cfg.Validate(); err == nil || strings.Count(err.Error(), "ACCOUNT_PHONE_REGIONS") != 3 {
+		t.Errorf("Validate() with invalid account regions error = %v", err)
+	}
+}
+
+func TestValidateReportsEveryInvalidValue(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+	t.Setenv("WEBHOOK_SECRET", "webhook-secret")
+	invalid := map[string]string{
+		"RATE_LIMIT_PER_MINUTE":      "-1",
+		"RATE_LIMIT_BURST":           "0",
+		"BULK_MAX_RECIPIENTS":        "many",
+		"BULK_SEND_DELAY":            "-1s",
+		"OPT_OUT_COOLDOWN":           "forever",
+		"REMINDER_POLL_INTERVAL":     "soon",
+		"BOOKING_IDEMPOTENCY_WINDOW": "-1h",
+		"WEBHOOK_WORKERS":            "none",
+	}
+	for key, value := range invalid {
+		t.Setenv(key, value)
+	}
+
+	// Development keeps running on the defaults
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.RateLimitPerMinute != 60 || cfg.RateLimitBurst != 10 || cfg.BulkMaxRecipients != 50 {
+		t.Errorf("RateLimitPerMinute, RateLimitBurst, BulkMaxRecipients = %d, %d, %d, want the defaults", cfg.RateLimitPerMinute, cfg.RateLimitBurst, cfg.BulkMaxRecipients)
+	}
+	if cfg.OptOutCooldown != 30*24*time.Hour || cfg.ReminderPollInterval != 30*time.Second {
+		t.Errorf("OptOutCooldown, ReminderPollInterval = %s, %s, want the defaults", cfg.OptOutCooldown, cfg.ReminderPollInterval)
+	}
+
+	// Validate reports all of them at once
+	err = cfg.Validate()
+	if err == nil {
+		t.Fatal("Validate() error = nil, want every invalid value")
+	}
+	for key := range invalid {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("Validate() error = %v, want %s reported", err, key)
+		}
+	}
+}
+
+func TestValidateDurableWebhookReplicaID(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+	t.Setenv("INBOUND_SOURCE", "webhook")
+	t.Setenv("WEBHOOK_SECRET", "webhook-secret")
+	t.Setenv("WEBHOOK_MODE", "async")
+	t.Setenv("WEBHOOK_DURABLE", "true")
+	t.Setenv("REPLICA_ID", "")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	// The hostname may change on restart, orphaning the queued webhooks
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "REPLICA_ID") {
+		t.Errorf("Validate() without REPLICA_ID error = %v, want REPLICA_ID required", err)
+	}
+
+	t.Setenv("REPLICA_ID", "replica-1")
+	if cfg, err = Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() with REPLICA_ID error = %v", err)
+	}
+
+	// Without the durable queue the hostname is enough
+	t.Setenv("REPLICA_ID", "")
+	t.Setenv("WEBHOOK_DURABLE", "false")
+	if cfg, err = Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() without the durable queue error = %v", err)
+	}
+}
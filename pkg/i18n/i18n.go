@@ -0,0 +1,98 @@
+// Package i18n renders the built-in customer messages in the customer's
+// language. Messages are text/template templates keyed by language code and
+// message key; languages without a message fall back to Spanish.
+package i18n
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// DefaultLanguage is used for languages without a message
+const DefaultLanguage = "es"
+
+// ErrUnknownMessage is returned when no language has the message
+var ErrUnknownMessage = errors.New("unknown message")
+
+// ErrMissingField is returned when a template references a field its data
+// does not have
+var ErrMissingField = errors.New("missing template field")
+
+// Booking holds the fields the booking message templates can use
+type Booking struct {
+	UserName     string
+	ServiceName  string
+	LocationName string
+	StartTime    string
+	Date         string
+	EmployeeName string
+	// Deadline is the response deadline, zero when the booking has none
+	Deadline time.Time
+	// Reason is the cancellation reason, empty when none was given
+	Reason string
+}
+
+// catalog holds the compiled messages by language and key
+var catalog = compile(messages)
+
+// compile parses the message templates. Templates fail on missing map keys
+// as they do on missing struct fields.
+func compile(source map[string]map[string]string) map[string]map[string]*template.Template {
+	compiled := make(map[string]map[string]*template.Template, len(source))
+	for language, texts := range source {
+		compiled[language] = make(map[string]*template.Template, len(texts))
+		for key, text := range texts {
+			compiled[language][key] = template.Must(
+				template.New(language + "/" + key).Option("missingkey=error").Parse(text))
+		}
+	}
+	return compiled
+}
+
+// Languages returns the supported language codes
+func Languages() []string {
+	languages := make([]string, 0, len(catalog))
+	for language := range catalog {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+// Supported reports whether messages are available in the language
+func Supported(language string) bool {
+	_, ok := catalog[strings.ToLower(language)]
+	return ok
+}
+
+// Render renders the message in the language, or in Spanish when the
+// language or the message in that language is not available
+func Render(language, key string, data any) (string, error) {
+	tmpl, ok := catalog[strings.ToLower(language)][key]
+	if !ok {
+		tmpl, ok = catalog[DefaultLanguage][key]
+	}
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownMessage, key)
+	}
+
+	var text strings.Builder
+	if err := tmpl.Execute(&text, data); err != nil {
+		if missingField(err) {
+			return "", fmt.Errorf("%w: %v", ErrMissingField, err)
+		}
+		return "", fmt.Errorf("failed to render %s: %w", key, err)
+	}
+	return text.String(), nil
+}
+
+// missingField reports whether a template failed on a field or map key its
+// data does not have. text/template reports both only in the error text.
+func missingField(err error) bool {
+	message := err.Error()
+	return strings.Contains(message, "can't evaluate field") || strings.Contains(message, "no entry for key")
+}
@@ -0,0 +1,103 @@
+package i18n
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// testBooking is the booking rendered in the tests
+var testBooking = Booking{
+	UserName:     "Ana",
+	ServiceName:  "Corte",
+	LocationName: "Providencia",
+	StartTime:    "10:00",
+	Date:         "2025-01-15",
+	EmployeeName: "Carla",
+}
+
+// useCatalog replaces the compiled messages for the rest of the test
+func useCatalog(t *testing.T, source map[string]map[string]string) {
+	t.Helper()
+	previous := catalog
+	catalog = compile(source)
+	t.Cleanup(func() { catalog = previous })
+}
+
+func TestRenderLanguages(t *testing.T) {
+	tests := []struct {
+		language string
+		want     string
+	}{
+		{language: "es", want: "¡Hola Ana!"},
+		{language: "en", want: "Hi Ana!"},
+		{language: "pt", want: "Olá Ana!"},
+		{language: "EN", want: "Hi Ana!"},
+		// Languages without messages fall back to Spanish
+		{language: "fr", want: "¡Hola Ana!"},
+		{language: "de", want: "¡Hola Ana!"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.language, func(t *testing.T) {
+			text, err := Render(tt.language, Confirmation, testBooking)
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			if !strings.HasPrefix(text, tt.want) || !strings.Contains(text, "Corte") {
+				t.Errorf("Render() = %q, want it to start with %q and name the service", text, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderFallsBackPerMessage(t *testing.T) {
+	useCatalog(t, map[string]map[string]string{
+		"es": {"greeting": "Hola", "farewell": "Adiós"},
+		"en": {"greeting": "Hello"},
+	})
+
+	if text, err := Render("en", "greeting", nil); err != nil || text != "Hello" {
+		t.Errorf("Render(en, greeting) = %q, %v, want Hello", text, err)
+	}
+	// A message missing in the language is sent in Spanish
+	if text, err := Render("en", "farewell", nil); err != nil || text != "Adiós" {
+		t.Errorf("Render(en, farewell) = %q, %v, want Adiós", text, err)
+	}
+	if _, err := Render("en", "unknown", nil); !errors.Is(err, ErrUnknownMessage) {
+		t.Errorf("Render(en, unknown) error = %v, want ErrUnknownMessage", err)
+	}
+}
+
+func TestRenderMissingField(t *testing.T) {
+	useCatalog(t, map[string]map[string]string{
+		"es": {
+			"field":   "Hola {{.Nickname}}",
+			"key":     "Hola {{.nickname}}",
+			"nil":     "Hola {{.UserName}}",
+			"failing": "Hola {{.Deadline.Format}}",
+		},
+	})
+
+	tests := []struct {
+		name string
+		key  string
+		data any
+	}{
+		{name: "struct field", key: "field", data: testBooking},
+		{name: "map key", key: "key", data: map[string]string{"name": "Ana"}},
+		{name: "nil data", key: "nil", data: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Render("es", tt.key, tt.data); !errors.Is(err, ErrMissingField) {
+				t.Errorf("Render() error = %v, want ErrMissingField", err)
+			}
+		})
+	}
+
+	// Other execution errors are not reported as missing fields
+	_, err := Render("es", "failing", testBooking)
+	if err == nil || errors.Is(err, ErrMissingField) {
+		t.Errorf("Render() with a failing call error = %v, want an error other than ErrMissingField", err)
+	}
+}
@@ -0,0 +1,127 @@
+package i18n
+
+// Message keys of the booking messages, rendered with Booking data
+const (
+	Confirmation = "confirmation"
+	Cancellation = "cancellation"
+	Reminder     = "reminder"
+)
+
+// messages holds the message templates by language and key. The automatic
+// replies are keyed by the response status they answer.
+var messages = map[string]map[string]string{
+	"es": {
+		Confirmation: "¡Hola {{.UserName}}! 😊\n\n" +
+			"Tu cita para el servicio de {{.ServiceName}} está casi lista.\n" +
+			"📍 Ubicación: {{.LocationName}}\n" +
+			"⏰ Hora: {{.StartTime}}\n" +
+			"📅 Fecha: {{.Date}}\n" +
+			"👤 Atendido por: {{.EmployeeName}}\n\n" +
+			"¿Te gustaría confirmar esta cita?\n" +
+			"Por favor, responde 'Sí' para confirmar o 'No' para cancelar.\n" +
+			"{{if not .Deadline.IsZero}}⏳ Confirma antes del {{.Deadline.Format \"02/01/2006\"}} a las {{.Deadline.Format \"15:04\"}}.\n{{end}}" +
+			"¡Gracias por elegirnos! 🌟",
+		Cancellation: "{{if .ServiceName}}¡Hola {{.UserName}}!\n\n" +
+			"Tu cita para el servicio de {{.ServiceName}} ha sido cancelada.\n" +
+			"📍 Ubicación: {{.LocationName}}\n" +
+			"⏰ Hora: {{.StartTime}}\n" +
+			"📅 Fecha: {{.Date}}\n" +
+			"{{else}}Hola,\n\nTu cita ha sido cancelada.\n{{end}}" +
+			"{{if .Reason}}📝 Motivo: {{.Reason}}\n{{end}}" +
+			"\nSi deseas reagendarla, por favor contáctanos. ¡Gracias!",
+		Reminder: "¡Hola {{.UserName}}! 👋\n\n" +
+			"Te recordamos tu cita para el servicio de {{.ServiceName}}.\n" +
+			"📍 Ubicación: {{.LocationName}}\n" +
+			"⏰ Hora: {{.StartTime}}\n" +
+			"📅 Fecha: {{.Date}}\n" +
+			"👤 Atendido por: {{.EmployeeName}}\n\n" +
+			"¡Te esperamos! 🌟",
+		"confirmed":      "¡Gracias por confirmar tu cita! Te esperamos en la fecha y hora acordada. 😊",
+		"cancelled":      "Hemos cancelado tu cita. Si deseas reagendarla, por favor contáctanos. ¡Gracias!",
+		"unknown":        "No entendimos tu respuesta. Por favor, responde 'Sí' para confirmar o 'No' para cancelar tu cita.",
+		"expired":        "Tu cita expiró porque no recibimos confirmación a tiempo. Si aún deseas asistir, por favor contáctanos.",
+		"too_long":       "Tu mensaje es demasiado largo. Por favor, envíanos un mensaje más corto.",
+		"no_booking":     "No tenemos una cita pendiente de confirmación asociada a este número. Si necesitas ayuda, por favor contáctanos.",
+		"opted_out":      "Has dejado de recibir nuestros mensajes. Si deseas volver a recibirlos, responde START.",
+		"opted_in":       "Has vuelto a suscribirte a nuestros mensajes. ¡Gracias!",
+		"late":           "Esta confirmación ha expirado. Si aún deseas asistir, por favor contáctanos.",
+		"media_received": "Recibimos tu archivo, ¡gracias! Si necesitas algo más, escríbenos.",
+		"help":           "Puedes responder:\n• 'Sí' para confirmar tu cita\n• 'No' para cancelarla\n• 'Ayuda' para ver estas opciones",
+	},
+	"en": {
+		Confirmation: "Hi {{.UserName}}! 😊\n\n" +
+			"Your appointment for {{.ServiceName}} is almost ready.\n" +
+			"📍 Location: {{.LocationName}}\n" +
+			"⏰ Time: {{.StartTime}}\n" +
+			"📅 Date: {{.Date}}\n" +
+			"👤 With: {{.EmployeeName}}\n\n" +
+			"Would you like to confirm this appointment?\n" +
+			"Please reply 'Yes' to confirm or 'No' to cancel.\n" +
+			"{{if not .Deadline.IsZero}}⏳ Please confirm before {{.Deadline.Format \"Jan 2, 2006\"}} at {{.Deadline.Format \"15:04\"}}.\n{{end}}" +
+			"Thank you for choosing us! 🌟",
+		Cancellation: "{{if .ServiceName}}Hi {{.UserName}}!\n\n" +
+			"Your appointment for {{.ServiceName}} has been cancelled.\n" +
+			"📍 Location: {{.LocationName}}\n" +
+			"⏰ Time: {{.StartTime}}\n" +
+			"📅 Date: {{.Date}}\n" +
+			"{{else}}Hi,\n\nYour appointment has been cancelled.\n{{end}}" +
+			"{{if .Reason}}📝 Reason: {{.Reason}}\n{{end}}" +
+			"\nIf you would like to reschedule, please contact us. Thank you!",
+		Reminder: "Hi {{.UserName}}! 👋\n\n" +
+			"This is a reminder of your appointment for {{.ServiceName}}.\n" +
+			"📍 Location: {{.LocationName}}\n" +
+			"⏰ Time: {{.StartTime}}\n" +
+			"📅 Date: {{.Date}}\n" +
+			"👤 With: {{.EmployeeName}}\n\n" +
+			"See you soon! 🌟",
+		"confirmed":      "Thank you for confirming your appointment! We look forward to seeing you at the agreed date and time. 😊",
+		"cancelled":      "We have cancelled your appointment. If you would like to reschedule, please contact us. Thank you!",
+		"unknown":        "We didn't understand your reply. Please answer 'Yes' to confirm or 'No' to cancel your appointment.",
+		"expired":        "Your appointment expired because we did not receive a confirmation in time. If you still wish to attend, please contact us.",
+		"too_long":       "Your message is too long. Please send us a shorter message.",
+		"no_booking":     "We don't have an appointment awaiting confirmation for this number. If you need help, please contact us.",
+		"opted_out":      "You will no longer receive our messages. To receive them again, reply START.",
+		"opted_in":       "You have subscribed to our messages again. Thank you!",
+		"late":           "This confirmation has expired. If you still wish to attend, please contact us.",
+		"media_received": "We received your file, thank you! If you need anything else, write to us.",
+		"help":           "You can reply:\n• 'Yes' to confirm your appointment\n• 'No' to cancel it\n• 'Help' to see these options",
+	},
+	"pt": {
+		Confirmation: "Olá {{.UserName}}! 😊\n\n" +
+			"Sua consulta para o serviço de {{.ServiceName}} está quase pronta.\n" +
+			"📍 Local: {{.LocationName}}\n" +
+			"⏰ Hora: {{.StartTime}}\n" +
+			"📅 Data: {{.Date}}\n" +
+			"👤 Atendido por: {{.EmployeeName}}\n\n" +
+			"Gostaria de confirmar esta consulta?\n" +
+			"Por favor, responda 'Sim' para confirmar ou 'Não' para cancelar.\n" +
+			"{{if not .Deadline.IsZero}}⏳ Confirme antes de {{.Deadline.Format \"02/01/2006\"}} às {{.Deadline.Format \"15:04\"}}.\n{{end}}" +
+			"Obrigado por nos escolher! 🌟",
+		Cancellation: "{{if .ServiceName}}Olá {{.UserName}}!\n\n" +
+			"Sua consulta para o serviço de {{.ServiceName}} foi cancelada.\n" +
+			"📍 Local: {{.LocationName}}\n" +
+			"⏰ Hora: {{.StartTime}}\n" +
+			"📅 Data: {{.Date}}\n" +
+			"{{else}}Olá,\n\nSua consulta foi cancelada.\n{{end}}" +
+			"{{if .Reason}}📝 Motivo: {{.Reason}}\n{{end}}" +
+			"\nSe quiser reagendar, entre em contato conosco. Obrigado!",
+		Reminder: "Olá {{.UserName}}! 👋\n\n" +
+			"Lembramos da sua consulta para o serviço de {{.ServiceName}}.\n" +
+			"📍 Local: {{.LocationName}}\n" +
+			"⏰ Hora: {{.StartTime}}\n" +
+			"📅 Data: {{.Date}}\n" +
+			"👤 Atendido por: {{.EmployeeName}}\n\n" +
+			"Esperamos você! 🌟",
+		"confirmed":      "Obrigado por confirmar sua consulta! Esperamos você na data e hora combinadas. 😊",
+		"cancelled":      "Cancelamos sua consulta. Se quiser reagendar, entre em contato conosco. Obrigado!",
+		"unknown":        "Não entendemos sua resposta. Por favor, responda 'Sim' para confirmar ou 'Não' para cancelar sua consulta.",
+		"expired":        "Sua consulta expirou porque não recebemos a confirmação a tempo. Se ainda quiser comparecer, entre em contato conosco.",
+		"too_long":       "Sua mensagem é muito longa. Por favor, envie uma mensagem mais curta.",
+		"no_booking":     "Não temos uma consulta aguardando confirmação para este número. Se precisar de ajuda, entre em contato conosco.",
+		"opted_out":      "Você deixou de receber nossas mensagens. Para voltar a recebê-las, responda START.",
+		"opted_in":       "Você voltou a receber nossas mensagens. Obrigado!",
+		"late":           "Esta confirmação expirou. Se ainda quiser comparecer, entre em contato conosco.",
+		"media_received": "Recebemos seu arquivo, obrigado! Se precisar de algo mais, escreva para nós.",
+		"help":           "Você pode responder:\n• 'Sim' para confirmar sua consulta\n• 'Não' para cancelá-la\n• 'Ajuda' para ver estas opções",
+	},
+}
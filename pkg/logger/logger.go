@@ -1,6 +1,9 @@
 package logger
 
 import (
+	"errors"
+	"syscall"
+
 	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/config"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -14,6 +17,8 @@ type Logger interface {
 	Error(msg string, fields ...zapcore.Field)
 	Fatal(msg string, fields ...zapcore.Field)
 	With(fields ...zapcore.Field) Logger
+	// Sync flushes buffered log entries
+	Sync() error
 }
 
 // ZapLogger implements the Logger interface using zap
@@ -70,6 +75,11 @@ func New(cfg *config.Config) (Logger, error) {
 	return &ZapLogger{logger: logger}, nil
 }
 
+// NewNop creates a logger that discards every entry, for tests
+func NewNop() Logger {
+	return &ZapLogger{logger: zap.NewNop()}
+}
+
 // Debug logs a debug message
 func (l *ZapLogger) Debug(msg string, fields ...zapcore.Field) {
 	l.logger.Debug(msg, fields...)
@@ -99,3 +109,31 @@ func (l *ZapLogger) Fatal(msg string, fields ...zapcore.Field) {
 func (l *ZapLogger) With(fields ...zapcore.Field) Logger {
 	return &ZapLogger{logger: l.logger.With(fields...)}
 }
+
+// Sync flushes buffered log entries. Syncing stderr or stdout fails with
+// EINVAL or ENOTTY on some platforms when they are a terminal or a pipe;
+// those errors are ignored since there is nothing to flush.
+func (l *ZapLogger) Sync() error {
+	return ignoreConsoleSyncErrors(l.logger.Sync())
+}
+
+// ignoreConsoleSyncErrors drops the errors of syncing a console from the
+// errors combined by zap, keeping any other
+func ignoreConsoleSyncErrors(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	errs := []error{err}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = joined.Unwrap()
+	}
+	var remaining []error
+	for _, err := range errs {
+		if errors.Is(err, syscall.EINVAL) || errors.Is(err, syscall.ENOTTY) {
+			continue
+		}
+		remaining = append(remaining, err)
+	}
+	return errors.Join(remaining...)
+}
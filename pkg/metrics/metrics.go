@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace prefixes every metric exported by the service
+const namespace = "whatsapp_service"
+
+// StoreEntriesCleaned counts expired state store entries removed by the janitor
+var StoreEntriesCleaned = promauto.NewCounter(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "store_expired_entries_cleaned_total",
+	Help:      "Expired entries removed from the in-memory state store.",
+})
+
+// StoreFallbacks counts state store operations that failed and fell back
+var StoreFallbacks = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "store_fallbacks_total",
+	Help:      "State store operations that failed and degraded gracefully.",
+}, []string{"operation"})
+
+// SendQueueDepth reports the number of messages waiting in the send queue
+var SendQueueDepth = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: namespace,
+	Name:      "send_queue_depth",
+	Help:      "Messages waiting in the send queue by priority.",
+}, []string{"priority"})
+
+// RedisOperationDuration observes the latency of Redis commands
+var RedisOperationDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: namespace,
+	Name:      "redis_operation_duration_seconds",
+	Help:      "Latency of Redis commands by operation.",
+	Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+}, []string{"operation"})
+
+// RedisErrors counts Redis commands that failed; cache misses are not errors
+var RedisErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "redis_errors_total",
+	Help:      "Redis commands that failed by operation.",
+}, []string{"operation"})
+
+// Handler returns the HTTP handler exposing the metrics
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
@@ -0,0 +1,80 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/redis"
+)
+
+// keyPrefix is the Redis key prefix of the token buckets
+const keyPrefix = "ratelimit:"
+
+// tokenBucket refills the bucket for the time elapsed since the last request
+// and takes a token if one is available. It returns whether the request is
+// allowed and, if not, the milliseconds until a token is available. Redis'
+// clock is used so every instance shares the same time.
+var tokenBucket = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'updated_at')
+local tokens = tonumber(bucket[1]) or burst
+local updated = tonumber(bucket[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - updated) * rate / 1000)
+
+local allowed = 0
+local wait = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	wait = math.ceil((1 - tokens) * 1000 / rate)
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'updated_at', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst * 1000 / rate) + 1000)
+return {allowed, wait}
+`)
+
+// Limiter is a token bucket per key shared by every instance through Redis
+type Limiter struct {
+	client *redis.Client
+	// rate is the tokens added per second
+	rate float64
+	// burst is the size of the bucket
+	burst int
+}
+
+// NewLimiter creates a limiter allowing perMinute requests per key on
+// average, with bursts of up to burst requests
+func NewLimiter(client *redis.Client, perMinute, burst int) *Limiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &Limiter{
+		client: client,
+		rate:   float64(perMinute) / 60,
+		burst:  burst,
+	}
+}
+
+// Allow takes a token from the bucket of the key. When the bucket is empty
+// it returns false and how long until the next token.
+func (l *Limiter) Allow(ctx context.Context, key string) (bool, time.Duration, error) {
+	result, err := l.client.Run(ctx, tokenBucket, []string{keyPrefix + key}, l.rate, l.burst)
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to check rate limit: %w", err)
+	}
+
+	values, ok := result.([]interface{})
+	if !ok || len(values) != 2 {
+		return false, 0, fmt.Errorf("unexpected rate limit result %v", result)
+	}
+	allowed, _ := values[0].(int64)
+	wait, _ := values[1].(int64)
+	return allowed == 1, time.Duration(wait) * time.Millisecond, nil
+}
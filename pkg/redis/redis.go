@@ -2,48 +2,228 @@ package redis
 
 import (
 	"context"
+	"errors"
+	"strconv"
 	"time"
 
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/metrics"
 	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// Nil is returned by Get when the key does not exist
+const Nil = redis.Nil
+
+// Script is a Lua script run atomically by Redis
+type Script = redis.Script
+
+// NewScript creates a script; Run loads it into Redis the first time
+func NewScript(source string) *Script {
+	return redis.NewScript(source)
+}
+
+// tracerName identifies the spans created by the client
+const tracerName = "github.com/pabbloacevedog/whatspp-service-glidpa/pkg/redis"
+
 // Client is a wrapper around the Redis client
 type Client struct {
 	client *redis.Client
+	// metrics records the latency and errors of each command
+	metrics bool
+	// tracer creates a span per command when tracing is enabled
+	tracer trace.Tracer
+}
+
+// ClientOption configures the Redis client
+type ClientOption func(*Client)
+
+// WithMetrics exports the latency and errors of each command to Prometheus
+func WithMetrics() ClientOption {
+	return func(c *Client) {
+		c.metrics = true
+	}
+}
+
+// WithTracing creates an OpenTelemetry span per command, child of the span
+// in the command's context, using the global tracer provider
+func WithTracing() ClientOption {
+	return func(c *Client) {
+		c.tracer = otel.Tracer(tracerName)
+	}
 }
 
 // NewClient creates a new Redis client
-func NewClient(addr string) *Client {
+func NewClient(addr string, options ...ClientOption) *Client {
 	client := redis.NewClient(&redis.Options{
 		Addr: addr,
 	})
 
-	return &Client{
+	c := &Client{
 		client: client,
 	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
 }
 
 // Set sets a key-value pair in Redis with an expiration time
 func (c *Client) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
-	return c.client.Set(ctx, key, value, expiration).Err()
+	return c.instrument(ctx, "set", func(ctx context.Context) error {
+		return c.client.Set(ctx, key, value, expiration).Err()
+	})
 }
 
 // Get gets a value from Redis by key
 func (c *Client) Get(ctx context.Context, key string) (string, error) {
-	return c.client.Get(ctx, key).Result()
+	var value string
+	err := c.instrument(ctx, "get", func(ctx context.Context) error {
+		var err error
+		value, err = c.client.Get(ctx, key).Result()
+		return err
+	})
+	return value, err
+}
+
+// SetNX sets a key only if it does not exist and reports whether it was set
+func (c *Client) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error) {
+	var set bool
+	err := c.instrument(ctx, "setnx", func(ctx context.Context) error {
+		var err error
+		set, err = c.client.SetNX(ctx, key, value, expiration).Result()
+		return err
+	})
+	return set, err
+}
+
+// GetDel gets a value and deletes the key atomically
+func (c *Client) GetDel(ctx context.Context, key string) (string, error) {
+	var value string
+	err := c.instrument(ctx, "getdel", func(ctx context.Context) error {
+		var err error
+		value, err = c.client.GetDel(ctx, key).Result()
+		return err
+	})
+	return value, err
 }
 
 // Delete deletes a key from Redis
 func (c *Client) Delete(ctx context.Context, key string) error {
-	return c.client.Del(ctx, key).Err()
+	return c.instrument(ctx, "del", func(ctx context.Context) error {
+		return c.client.Del(ctx, key).Err()
+	})
+}
+
+// Keys returns all keys matching the given pattern using SCAN
+func (c *Client) Keys(ctx context.Context, pattern string) ([]string, error) {
+	var keys []string
+	err := c.instrument(ctx, "scan", func(ctx context.Context) error {
+		iter := c.client.Scan(ctx, 0, pattern, 100).Iterator()
+		for iter.Next(ctx) {
+			keys = append(keys, iter.Val())
+		}
+		return iter.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// ZAdd adds a member to a sorted set with the given score, updating the
+// score of an existing member
+func (c *Client) ZAdd(ctx context.Context, key string, score float64, member string) error {
+	return c.instrument(ctx, "zadd", func(ctx context.Context) error {
+		return c.client.ZAdd(ctx, key, redis.Z{Score: score, Member: member}).Err()
+	})
+}
+
+// ZRangeByScore returns up to count members of a sorted set with a score of
+// at most max, lowest score first
+func (c *Client) ZRangeByScore(ctx context.Context, key string, max float64, count int64) ([]string, error) {
+	var members []string
+	err := c.instrument(ctx, "zrangebyscore", func(ctx context.Context) error {
+		var err error
+		members, err = c.client.ZRangeByScore(ctx, key, &redis.ZRangeBy{
+			Min:   "-inf",
+			Max:   strconv.FormatFloat(max, 'f', -1, 64),
+			Count: count,
+		}).Result()
+		return err
+	})
+	return members, err
+}
+
+// ZRem removes a member from a sorted set and reports whether it was there,
+// so concurrent callers can claim a member
+func (c *Client) ZRem(ctx context.Context, key, member string) (bool, error) {
+	var removed int64
+	err := c.instrument(ctx, "zrem", func(ctx context.Context) error {
+		var err error
+		removed, err = c.client.ZRem(ctx, key, member).Result()
+		return err
+	})
+	return removed > 0, err
+}
+
+// Run runs a Lua script with the given keys and arguments, sending only its
+// hash once Redis has cached it
+func (c *Client) Run(ctx context.Context, script *Script, keys []string, args ...interface{}) (interface{}, error) {
+	var result interface{}
+	err := c.instrument(ctx, "evalsha", func(ctx context.Context) error {
+		var err error
+		result, err = script.Run(ctx, c.client, keys, args...).Result()
+		return err
+	})
+	return result, err
 }
 
 // Ping pings the Redis server
 func (c *Client) Ping(ctx context.Context) error {
-	return c.client.Ping(ctx).Err()
+	return c.instrument(ctx, "ping", func(ctx context.Context) error {
+		return c.client.Ping(ctx).Err()
+	})
 }
 
 // Close closes the Redis client
 func (c *Client) Close() error {
 	return c.client.Close()
 }
+
+// instrument runs a command, recording its latency, errors and span when
+// enabled. A missing key is a normal result, not an error.
+func (c *Client) instrument(ctx context.Context, operation string, command func(context.Context) error) error {
+	if !c.metrics && c.tracer == nil {
+		return command(ctx)
+	}
+
+	var span trace.Span
+	if c.tracer != nil {
+		ctx, span = c.tracer.Start(ctx, "redis."+operation,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				attribute.String("db.system", "redis"),
+				attribute.String("db.operation", operation),
+			))
+		defer span.End()
+	}
+
+	start := time.Now()
+	err := command(ctx)
+	failed := err != nil && !errors.Is(err, redis.Nil)
+
+	if c.metrics {
+		metrics.RedisOperationDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+		if failed {
+			metrics.RedisErrors.WithLabelValues(operation).Inc()
+		}
+	}
+	if span != nil && failed {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
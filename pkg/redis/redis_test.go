@@ -0,0 +1,164 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// newTestClient starts an in-process Redis server and returns a client
+// connected to it
+func newTestClient(t *testing.T, options ...ClientOption) (*Client, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := NewClient(server.Addr(), options...)
+	t.Cleanup(func() { client.Close() })
+	return client, server
+}
+
+// observations returns how many latencies were recorded for the operation
+func observations(t *testing.T, operation string) uint64 {
+	t.Helper()
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatalf("Gather() error = %v", err)
+	}
+	for _, family := range families {
+		if family.GetName() != "whatsapp_service_redis_operation_duration_seconds" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "operation" && label.GetValue() == operation {
+					return metric.GetHistogram().GetSampleCount()
+				}
+			}
+		}
+	}
+	return 0
+}
+
+// redisErrors returns the errors counted for the operation
+func redisErrors(operation string) float64 {
+	return testutil.ToFloat64(metrics.RedisErrors.WithLabelValues(operation))
+}
+
+func TestClientMetrics(t *testing.T) {
+	client, server := newTestClient(t, WithMetrics())
+	ctx := context.Background()
+
+	before := map[string]uint64{}
+	for _, operation := range []string{"set", "get", "del"} {
+		before[operation] = observations(t, operation)
+	}
+	getErrors := redisErrors("get")
+
+	if err := client.Set(ctx, "key", "value", time.Minute); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if value, err := client.Get(ctx, "key"); err != nil || value != "value" {
+		t.Fatalf("Get() = %q, %v, want value", value, err)
+	}
+	if err := client.Delete(ctx, "key"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	// A missing key is timed but is not an error
+	if _, err := client.Get(ctx, "key"); !errors.Is(err, Nil) {
+		t.Fatalf("Get() of a deleted key error = %v, want %v", err, Nil)
+	}
+
+	for operation, want := range map[string]uint64{"set": 1, "get": 2, "del": 1} {
+		if got := observations(t, operation) - before[operation]; got != want {
+			t.Errorf("%s observed %d times, want %d", operation, got, want)
+		}
+	}
+	if got := redisErrors("get") - getErrors; got != 0 {
+		t.Errorf("get counted %v errors for a missing key, want 0", got)
+	}
+
+	// Failed commands are counted by operation
+	setErrors, delErrors := redisErrors("set"), redisErrors("del")
+	server.SetError("LOADING Redis is loading the dataset in memory")
+	if err := client.Set(ctx, "key", "value", time.Minute); err == nil {
+		t.Fatal("Set() succeeded against a failing server")
+	}
+	if _, err := client.Get(ctx, "key"); err == nil {
+		t.Fatal("Get() succeeded against a failing server")
+	}
+	if err := client.Delete(ctx, "key"); err == nil {
+		t.Fatal("Delete() succeeded against a failing server")
+	}
+	for operation, got := range map[string]float64{
+		"set": redisErrors("set") - setErrors,
+		"get": redisErrors("get") - getErrors,
+		"del": redisErrors("del") - delErrors,
+	} {
+		if got != 1 {
+			t.Errorf("%s counted %v errors, want 1", operation, got)
+		}
+	}
+}
+
+func TestClientWithoutMetrics(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+
+	before := observations(t, "set")
+	if err := client.Set(ctx, "key", "value", time.Minute); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if got := observations(t, "set") - before; got != 0 {
+		t.Errorf("set observed %d times without metrics, want 0", got)
+	}
+}
+
+func TestClientTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	client, server := newTestClient(t, WithTracing())
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "request")
+
+	if err := client.Set(ctx, "key", "value", time.Minute); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	server.SetError("LOADING Redis is loading the dataset in memory")
+	if _, err := client.Get(ctx, "key"); err == nil {
+		t.Fatal("Get() succeeded against a failing server")
+	}
+	parent.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("recorded %d spans, want the two commands and the request", len(spans))
+	}
+	for i, name := range []string{"redis.set", "redis.get"} {
+		span := spans[i]
+		if span.Name() != name {
+			t.Errorf("span %d = %s, want %s", i, span.Name(), name)
+		}
+		// Command spans join the request trace
+		if span.Parent().SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("%s parent = %s, want the request span", name, span.Parent().SpanID())
+		}
+	}
+	if spans[0].Status().Code == codes.Error {
+		t.Errorf("redis.set status = %v, want no error", spans[0].Status())
+	}
+	if spans[1].Status().Code != codes.Error {
+		t.Errorf("redis.get status = %v, want an error", spans[1].Status())
+	}
+}
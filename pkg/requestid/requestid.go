@@ -0,0 +1,46 @@
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// DefaultHeader is the HTTP header carrying the request ID
+const DefaultHeader = "X-Request-ID"
+
+// contextKey is the context key holding the request ID
+type contextKey struct{}
+
+// header is the configured HTTP header carrying the request ID
+var header = DefaultHeader
+
+// SetHeader configures the HTTP header used to propagate request IDs
+func SetHeader(name string) {
+	if name != "" {
+		header = name
+	}
+}
+
+// Header returns the HTTP header used to propagate request IDs
+func Header() string {
+	return header
+}
+
+// New generates a new random request ID
+func New() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// NewContext returns a context carrying the request ID
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID carried by the context, if any
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
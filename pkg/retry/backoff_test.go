@@ -0,0 +1,86 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	b := Backoff{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second, Multiplier: 2}
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{attempt: 0, want: 100 * time.Millisecond},
+		{attempt: 1, want: 100 * time.Millisecond},
+		{attempt: 2, want: 200 * time.Millisecond},
+		{attempt: 4, want: 800 * time.Millisecond},
+		{attempt: 5, want: time.Second},
+		{attempt: 50, want: time.Second},
+	}
+	for _, tt := range tests {
+		if got := b.Delay(tt.attempt); got != tt.want {
+			t.Errorf("Delay(%d) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+
+	// Jitter keeps each delay within the fraction
+	b.Jitter = 0.2
+	for i := 0; i < 100; i++ {
+		if got := b.Delay(2); got < 160*time.Millisecond || got > 240*time.Millisecond {
+			t.Fatalf("Delay(2) with jitter = %v, want within 20%% of 200ms", got)
+		}
+	}
+}
+
+func TestBackoffDo(t *testing.T) {
+	errTemporary := errors.New("temporary")
+	b := Backoff{BaseDelay: time.Millisecond, MaxDelay: time.Millisecond, MaxAttempts: 3, Multiplier: 2}
+	ctx := context.Background()
+
+	attempts := 0
+	err := b.Do(ctx, func(context.Context) error {
+		attempts++
+		return errTemporary
+	})
+	if !errors.Is(err, errTemporary) || attempts != 3 {
+		t.Errorf("Do() = %v after %d attempts, want %v after 3", err, attempts, errTemporary)
+	}
+
+	attempts = 0
+	err = b.Do(ctx, func(context.Context) error {
+		attempts++
+		if attempts < 2 {
+			return errTemporary
+		}
+		return nil
+	})
+	if err != nil || attempts != 2 {
+		t.Errorf("Do() = %v after %d attempts, want success after 2", err, attempts)
+	}
+
+	// Permanent errors stop the retries and are returned unwrapped
+	attempts = 0
+	err = b.Do(ctx, func(context.Context) error {
+		attempts++
+		return Permanent(errTemporary)
+	})
+	if err != errTemporary || attempts != 1 {
+		t.Errorf("Do() = %v after %d attempts, want %v after 1", err, attempts, errTemporary)
+	}
+
+	// A cancelled context stops waiting between attempts
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	attempts = 0
+	b = Backoff{BaseDelay: time.Hour, MaxDelay: time.Hour}
+	err = b.Do(cancelled, func(context.Context) error {
+		attempts++
+		return errTemporary
+	})
+	if !errors.Is(err, errTemporary) || attempts != 1 {
+		t.Errorf("Do() with a cancelled context = %v after %d attempts, want 1 attempt", err, attempts)
+	}
+}
@@ -0,0 +1,150 @@
+package store
+
+import (
+	"context"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/metrics"
+)
+
+// memoryEntry is a value held by the MemoryStore
+type memoryEntry struct {
+	value     string
+	expiresAt time.Time
+}
+
+// expired reports whether the entry has expired at the given time
+func (e memoryEntry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && now.After(e.expiresAt)
+}
+
+// MemoryStore is an in-process Store used when Redis is not configured.
+// Expired entries are hidden on read but only freed by Cleanup.
+type MemoryStore struct {
+	entries map[string]memoryEntry
+	mu      sync.RWMutex
+}
+
+// NewMemoryStore creates a new MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		entries: make(map[string]memoryEntry),
+	}
+}
+
+// Set stores a value with an optional expiration
+func (s *MemoryStore) Set(ctx context.Context, key, value string, expiration time.Duration) error {
+	entry := memoryEntry{value: value}
+	if expiration > 0 {
+		entry.expiresAt = time.Now().Add(expiration)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[key] = entry
+	return nil
+}
+
+// SetNX stores a value only if the key does not exist or has expired
+func (s *MemoryStore) SetNX(ctx context.Context, key, value string, expiration time.Duration) (bool, error) {
+	entry := memoryEntry{value: value}
+	if expiration > 0 {
+		entry.expiresAt = time.Now().Add(expiration)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if current, ok := s.entries[key]; ok && !current.expired(time.Now()) {
+		return false, nil
+	}
+	s.entries[key] = entry
+	return true, nil
+}
+
+// Get returns the value of a key
+func (s *MemoryStore) Get(ctx context.Context, key string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entry, ok := s.entries[key]
+	if !ok || entry.expired(time.Now()) {
+		return "", ErrNotFound
+	}
+	return entry.value, nil
+}
+
+// Take returns the value of a key and deletes it
+func (s *MemoryStore) Take(ctx context.Context, key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[key]
+	if !ok || entry.expired(time.Now()) {
+		return "", ErrNotFound
+	}
+	delete(s.entries, key)
+	return entry.value, nil
+}
+
+// Delete removes a key
+func (s *MemoryStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
+
+// Keys returns all live keys matching the glob pattern
+func (s *MemoryStore) Keys(ctx context.Context, pattern string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	var keys []string
+	for key, entry := range s.entries {
+		if entry.expired(now) {
+			continue
+		}
+		if ok, _ := path.Match(pattern, key); ok {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// Cleanup frees entries that expired before now and returns how many were removed
+func (s *MemoryStore) Cleanup(now time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed := 0
+	for key, entry := range s.entries {
+		if entry.expired(now) {
+			delete(s.entries, key)
+			removed++
+		}
+	}
+	return removed
+}
+
+// StartJanitor frees expired entries on the given interval until the context
+// is cancelled. Redis expires keys natively and needs no janitor.
+func (s *MemoryStore) StartJanitor(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				if removed := s.Cleanup(now); removed > 0 {
+					metrics.StoreEntriesCleaned.Add(float64(removed))
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,90 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMemoryStoreCleanup(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	s.Set(ctx, "conversation:1", "menu", time.Minute)
+	s.Set(ctx, "idempotency:1", "{}", time.Hour)
+	s.Set(ctx, "dedup:1", "1", 10*time.Minute)
+	s.Set(ctx, "config", "keep", 0)
+
+	now := time.Now()
+	if removed := s.Cleanup(now); removed != 0 {
+		t.Errorf("Cleanup(now) = %d, want 0", removed)
+	}
+
+	// Advance the clock past the shortest expirations
+	if removed := s.Cleanup(now.Add(15 * time.Minute)); removed != 2 {
+		t.Errorf("Cleanup(+15m) = %d, want 2", removed)
+	}
+	if got := len(s.entries); got != 2 {
+		t.Errorf("%d entries left, want 2", got)
+	}
+	if _, err := s.Get(ctx, "idempotency:1"); err != nil {
+		t.Errorf("Get(idempotency:1) error = %v", err)
+	}
+
+	if removed := s.Cleanup(now.Add(24 * time.Hour)); removed != 1 {
+		t.Errorf("Cleanup(+24h) = %d, want 1", removed)
+	}
+	if _, err := s.Get(ctx, "config"); err != nil {
+		t.Errorf("entry without expiration was removed: %v", err)
+	}
+}
+
+func TestMemoryStoreHidesExpiredEntries(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	s.Set(ctx, "key", "value", time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	if _, err := s.Get(ctx, "key"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() error = %v, want ErrNotFound", err)
+	}
+	if keys, _ := s.Keys(ctx, "*"); len(keys) != 0 {
+		t.Errorf("Keys() = %v, want none", keys)
+	}
+	if stored, _ := s.SetNX(ctx, "key", "new", 0); !stored {
+		t.Error("SetNX() did not replace an expired entry")
+	}
+}
+
+func TestMemoryStoreJanitor(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := NewMemoryStore()
+	s.Set(ctx, "conversation:1", "menu", time.Millisecond)
+	s.Set(ctx, "conversation:2", "menu", time.Hour)
+	before := testutil.ToFloat64(metrics.StoreEntriesCleaned)
+
+	s.StartJanitor(ctx, 5*time.Millisecond)
+
+	// The metric is updated after the entries are freed
+	deadline := time.Now().Add(time.Second)
+	for testutil.ToFloat64(metrics.StoreEntriesCleaned)-before < 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("janitor did not clean the expired entry")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	s.mu.RLock()
+	_, expired := s.entries["conversation:1"]
+	_, live := s.entries["conversation:2"]
+	s.mu.RUnlock()
+	if expired || !live {
+		t.Errorf("after janitor: expired entry kept = %v, live entry kept = %v", expired, live)
+	}
+	if got := testutil.ToFloat64(metrics.StoreEntriesCleaned) - before; got != 1 {
+		t.Errorf("cleaned entries metric grew by %v, want 1", got)
+	}
+}
@@ -0,0 +1,57 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/redis"
+)
+
+// RedisStore is a Store backed by Redis; expiration is handled natively
+type RedisStore struct {
+	client *redis.Client
+}
+
+// NewRedisStore creates a new RedisStore
+func NewRedisStore(client *redis.Client) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+// Set stores a value with an optional expiration
+func (s *RedisStore) Set(ctx context.Context, key, value string, expiration time.Duration) error {
+	return s.client.Set(ctx, key, value, expiration)
+}
+
+// SetNX stores a value only if the key does not exist
+func (s *RedisStore) SetNX(ctx context.Context, key, value string, expiration time.Duration) (bool, error) {
+	return s.client.SetNX(ctx, key, value, expiration)
+}
+
+// Get returns the value of a key
+func (s *RedisStore) Get(ctx context.Context, key string) (string, error) {
+	value, err := s.client.Get(ctx, key)
+	if errors.Is(err, redis.Nil) {
+		return "", ErrNotFound
+	}
+	return value, err
+}
+
+// Take returns the value of a key and deletes it atomically
+func (s *RedisStore) Take(ctx context.Context, key string) (string, error) {
+	value, err := s.client.GetDel(ctx, key)
+	if errors.Is(err, redis.Nil) {
+		return "", ErrNotFound
+	}
+	return value, err
+}
+
+// Delete removes a key
+func (s *RedisStore) Delete(ctx context.Context, key string) error {
+	return s.client.Delete(ctx, key)
+}
+
+// Keys returns all keys matching the glob pattern
+func (s *RedisStore) Keys(ctx context.Context, pattern string) ([]string, error) {
+	return s.client.Keys(ctx, pattern)
+}
@@ -0,0 +1,125 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/metrics"
+	"go.uber.org/zap"
+)
+
+// ResilientStore wraps a Store so backend failures degrade gracefully
+// instead of failing the caller. Reads behave as if the key did not exist
+// and writes are dropped, which makes every feature fail open: idempotency
+// allows the send, rate limits let the request through and conversation
+// state falls back to stateless handling.
+type ResilientStore struct {
+	store     Store
+	logger    logger.Logger
+	available bool
+	lastError time.Time
+	mu        sync.RWMutex
+}
+
+// NewResilientStore creates a new ResilientStore around the given store
+func NewResilientStore(store Store, logger logger.Logger) *ResilientStore {
+	return &ResilientStore{
+		store:     store,
+		logger:    logger,
+		available: true,
+	}
+}
+
+// Set stores a value, dropping the write if the backend fails
+func (s *ResilientStore) Set(ctx context.Context, key, value string, expiration time.Duration) error {
+	s.observe("set", key, s.store.Set(ctx, key, value, expiration))
+	return nil
+}
+
+// SetNX stores a value if the key does not exist, reporting it as stored if
+// the backend fails
+func (s *ResilientStore) SetNX(ctx context.Context, key, value string, expiration time.Duration) (bool, error) {
+	stored, err := s.store.SetNX(ctx, key, value, expiration)
+	if s.observe("setnx", key, err) {
+		return true, nil
+	}
+	return stored, nil
+}
+
+// Get returns the value of a key, or ErrNotFound if the backend fails
+func (s *ResilientStore) Get(ctx context.Context, key string) (string, error) {
+	value, err := s.store.Get(ctx, key)
+	if errors.Is(err, ErrNotFound) {
+		s.observe("get", key, nil)
+		return "", ErrNotFound
+	}
+	if s.observe("get", key, err) {
+		return "", ErrNotFound
+	}
+	return value, nil
+}
+
+// Take returns and deletes the value of a key, or ErrNotFound if the backend fails
+func (s *ResilientStore) Take(ctx context.Context, key string) (string, error) {
+	value, err := s.store.Take(ctx, key)
+	if errors.Is(err, ErrNotFound) {
+		s.observe("take", key, nil)
+		return "", ErrNotFound
+	}
+	if s.observe("take", key, err) {
+		return "", ErrNotFound
+	}
+	return value, nil
+}
+
+// Delete removes a key, dropping the operation if the backend fails
+func (s *ResilientStore) Delete(ctx context.Context, key string) error {
+	s.observe("delete", key, s.store.Delete(ctx, key))
+	return nil
+}
+
+// Keys returns the keys matching the pattern, or none if the backend fails
+func (s *ResilientStore) Keys(ctx context.Context, pattern string) ([]string, error) {
+	keys, err := s.store.Keys(ctx, pattern)
+	if s.observe("keys", pattern, err) {
+		return nil, nil
+	}
+	return keys, nil
+}
+
+// Available reports whether the last operation on the backend succeeded
+func (s *ResilientStore) Available() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.available
+}
+
+// observe records the outcome of a backend operation and reports whether it failed
+func (s *ResilientStore) observe(op, key string, err error) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err == nil {
+		if !s.available {
+			s.logger.Info("State store recovered")
+		}
+		s.available = true
+		return false
+	}
+
+	metrics.StoreFallbacks.WithLabelValues(op).Inc()
+
+	// Avoid flooding the logs while the backend is down
+	if s.available || time.Since(s.lastError) > time.Minute {
+		s.logger.Warn("State store unavailable, degrading gracefully",
+			zap.String("operation", op),
+			zap.String("key", key),
+			zap.Error(err))
+		s.lastError = time.Now()
+	}
+	s.available = false
+	return true
+}
@@ -0,0 +1,115 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+)
+
+// flakyStore is a MemoryStore whose operations fail while down is set
+type flakyStore struct {
+	*MemoryStore
+	down bool
+}
+
+var errBackendDown = errors.New("connection refused")
+
+func (s *flakyStore) Set(ctx context.Context, key, value string, expiration time.Duration) error {
+	if s.down {
+		return errBackendDown
+	}
+	return s.MemoryStore.Set(ctx, key, value, expiration)
+}
+
+func (s *flakyStore) SetNX(ctx context.Context, key, value string, expiration time.Duration) (bool, error) {
+	if s.down {
+		return false, errBackendDown
+	}
+	return s.MemoryStore.SetNX(ctx, key, value, expiration)
+}
+
+func (s *flakyStore) Get(ctx context.Context, key string) (string, error) {
+	if s.down {
+		return "", errBackendDown
+	}
+	return s.MemoryStore.Get(ctx, key)
+}
+
+func (s *flakyStore) Take(ctx context.Context, key string) (string, error) {
+	if s.down {
+		return "", errBackendDown
+	}
+	return s.MemoryStore.Take(ctx, key)
+}
+
+func (s *flakyStore) Delete(ctx context.Context, key string) error {
+	if s.down {
+		return errBackendDown
+	}
+	return s.MemoryStore.Delete(ctx, key)
+}
+
+func (s *flakyStore) Keys(ctx context.Context, pattern string) ([]string, error) {
+	if s.down {
+		return nil, errBackendDown
+	}
+	return s.MemoryStore.Keys(ctx, pattern)
+}
+
+func TestResilientStoreDegradesWhenBackendFails(t *testing.T) {
+	ctx := context.Background()
+	backend := &flakyStore{MemoryStore: NewMemoryStore()}
+	s := NewResilientStore(backend, logger.NewNop())
+	if err := s.Set(ctx, "key", "value", 0); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	backend.down = true
+
+	if err := s.Set(ctx, "other", "value", 0); err != nil {
+		t.Errorf("Set() error = %v, want the write dropped", err)
+	}
+	if err := s.Delete(ctx, "key"); err != nil {
+		t.Errorf("Delete() error = %v, want the delete dropped", err)
+	}
+	// SetNX reports the key as stored so callers go ahead
+	if stored, err := s.SetNX(ctx, "key", "value", 0); !stored || err != nil {
+		t.Errorf("SetNX() = %v, %v; want true, nil", stored, err)
+	}
+	// Reads behave as if the key did not exist
+	if _, err := s.Get(ctx, "key"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() error = %v, want ErrNotFound", err)
+	}
+	if _, err := s.Take(ctx, "key"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Take() error = %v, want ErrNotFound", err)
+	}
+	if keys, err := s.Keys(ctx, "*"); keys != nil || err != nil {
+		t.Errorf("Keys() = %v, %v; want nil, nil", keys, err)
+	}
+	if s.Available() {
+		t.Error("Available() = true while the backend is down")
+	}
+
+	backend.down = false
+
+	// The dropped delete left the key in place
+	if value, err := s.Get(ctx, "key"); err != nil || value != "value" {
+		t.Errorf("Get() = %q, %v; want value", value, err)
+	}
+	if !s.Available() {
+		t.Error("Available() = false after the backend recovered")
+	}
+}
+
+func TestResilientStoreMissIsNotAFailure(t *testing.T) {
+	s := NewResilientStore(&flakyStore{MemoryStore: NewMemoryStore()}, logger.NewNop())
+	if _, err := s.Get(context.Background(), "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() error = %v, want ErrNotFound", err)
+	}
+	if !s.Available() {
+		t.Error("Available() = false after a cache miss")
+	}
+}
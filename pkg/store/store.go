@@ -0,0 +1,27 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrNotFound is returned when a key does not exist or has expired
+var ErrNotFound = errors.New("key not found")
+
+// Store is a key-value state store with per-key expiration
+type Store interface {
+	// Set stores a value; a zero expiration keeps the key forever
+	Set(ctx context.Context, key, value string, expiration time.Duration) error
+	// SetNX stores a value only if the key does not exist and reports
+	// whether it was stored
+	SetNX(ctx context.Context, key, value string, expiration time.Duration) (bool, error)
+	// Get returns the value of a key or ErrNotFound
+	Get(ctx context.Context, key string) (string, error)
+	// Take returns the value of a key and deletes it atomically, or ErrNotFound
+	Take(ctx context.Context, key string) (string, error)
+	// Delete removes a key
+	Delete(ctx context.Context, key string) error
+	// Keys returns all keys matching a glob pattern
+	Keys(ctx context.Context, pattern string) ([]string, error)
+}
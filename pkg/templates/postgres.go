@@ -0,0 +1,104 @@
+package templates
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	// Register the Postgres driver
+	_ "github.com/lib/pq"
+)
+
+// schema creates the template table; every edit is a new row
+const schema = `
+CREATE TABLE IF NOT EXISTS message_templates (
+	name       TEXT        NOT NULL,
+	version    INTEGER     NOT NULL,
+	body       TEXT        NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	PRIMARY KEY (name, version)
+)`
+
+// PostgresRepository stores versioned templates in Postgres
+type PostgresRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresRepository connects to Postgres and migrates the template schema
+func NewPostgresRepository(ctx context.Context, url string) (*PostgresRepository, error) {
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open postgres: %w", err)
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to postgres: %w", err)
+	}
+	if _, err := db.ExecContext(ctx, schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate template schema: %w", err)
+	}
+
+	return &PostgresRepository{db: db}, nil
+}
+
+// Save inserts body as the next version of the named template
+func (r *PostgresRepository) Save(ctx context.Context, name, body string) (*Template, error) {
+	template := &Template{Name: name, Body: body, Variables: Variables(body)}
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO message_templates (name, version, body)
+		SELECT $1, COALESCE(MAX(version), 0) + 1, $2 FROM message_templates WHERE name = $1
+		RETURNING version, created_at`,
+		name, body,
+	).Scan(&template.Version, &template.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save template: %w", err)
+	}
+	return template, nil
+}
+
+// Latest returns the latest version of the named template
+func (r *PostgresRepository) Latest(ctx context.Context, name string) (*Template, error) {
+	template := &Template{Name: name}
+	err := r.db.QueryRowContext(ctx, `
+		SELECT version, body, created_at FROM message_templates
+		WHERE name = $1 ORDER BY version DESC LIMIT 1`,
+		name,
+	).Scan(&template.Version, &template.Body, &template.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get template: %w", err)
+	}
+	template.Variables = Variables(template.Body)
+	return template, nil
+}
+
+// List returns the latest version of every template ordered by name
+func (r *PostgresRepository) List(ctx context.Context) ([]Template, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT DISTINCT ON (name) name, version, body, created_at FROM message_templates
+		ORDER BY name, version DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list templates: %w", err)
+	}
+	defer rows.Close()
+
+	templates := []Template{}
+	for rows.Next() {
+		var template Template
+		if err := rows.Scan(&template.Name, &template.Version, &template.Body, &template.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to read template: %w", err)
+		}
+		template.Variables = Variables(template.Body)
+		templates = append(templates, template)
+	}
+	return templates, rows.Err()
+}
+
+// Close closes the database connection
+func (r *PostgresRepository) Close() error {
+	return r.db.Close()
+}
@@ -0,0 +1,91 @@
+package templates
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/store"
+)
+
+func TestStoreRepository(t *testing.T) {
+	testRepository(t, NewStoreRepository(store.NewMemoryStore()))
+}
+
+// TestPostgresRepository runs against the database in TEST_POSTGRES_URL
+func TestPostgresRepository(t *testing.T) {
+	url := os.Getenv("TEST_POSTGRES_URL")
+	if url == "" {
+		t.Skip("TEST_POSTGRES_URL not set")
+	}
+	repository, err := NewPostgresRepository(context.Background(), url)
+	if err != nil {
+		t.Fatalf("NewPostgresRepository() error = %v", err)
+	}
+	t.Cleanup(func() { repository.Close() })
+	testRepository(t, repository)
+}
+
+// testRepository checks the versioning contract every Repository follows.
+// Template names are unique to the run so a shared database can be reused.
+func testRepository(t *testing.T, repository Repository) {
+	ctx := context.Background()
+	prefix := fmt.Sprintf("test_%d_", time.Now().UnixNano())
+	reminder, greeting := prefix+"reminder", prefix+"greeting"
+
+	if _, err := repository.Latest(ctx, reminder); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Latest() of a missing template error = %v, want ErrNotFound", err)
+	}
+
+	created, err := repository.Save(ctx, reminder, "Hola {{name}}")
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if created.Version != 1 || !slices.Equal(created.Variables, []string{"name"}) {
+		t.Errorf("created = v%d %v, want v1 [name]", created.Version, created.Variables)
+	}
+
+	updated, err := repository.Save(ctx, reminder, "Hola {{name}}, tu cita es a las {{time}}")
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if updated.Version != 2 {
+		t.Errorf("updated version = %d, want 2", updated.Version)
+	}
+	if _, err := repository.Save(ctx, greeting, "Bienvenido"); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	// Sends render the latest version
+	latest, err := repository.Latest(ctx, reminder)
+	if err != nil {
+		t.Fatalf("Latest() error = %v", err)
+	}
+	text, err := latest.Render(map[string]string{"name": "Ana", "time": "10:00"})
+	if err != nil || text != "Hola Ana, tu cita es a las 10:00" {
+		t.Errorf("Render() = %q, %v", text, err)
+	}
+	if latest.Version != 2 || latest.UpdatedAt.IsZero() || !slices.Equal(latest.Variables, []string{"name", "time"}) {
+		t.Errorf("latest = v%d %v updated %v, want v2 [name time]", latest.Version, latest.Variables, latest.UpdatedAt)
+	}
+
+	list, err := repository.List(ctx)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	var got []string
+	for _, template := range list {
+		if strings.HasPrefix(template.Name, prefix) {
+			got = append(got, fmt.Sprintf("%s v%d", template.Name, template.Version))
+		}
+	}
+	want := []string{greeting + " v1", reminder + " v2"}
+	if !slices.Equal(got, want) {
+		t.Errorf("List() = %v, want %v", got, want)
+	}
+}
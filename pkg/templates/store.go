@@ -0,0 +1,89 @@
+package templates
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/store"
+)
+
+// templateKeyPrefix is the state store key prefix for templates
+const templateKeyPrefix = "whatsapp:template:"
+
+// StoreRepository keeps the latest version of each template in the state
+// store. It is used when Postgres is not configured.
+type StoreRepository struct {
+	store store.Store
+}
+
+// NewStoreRepository creates a new StoreRepository
+func NewStoreRepository(stateStore store.Store) *StoreRepository {
+	return &StoreRepository{store: stateStore}
+}
+
+// Save replaces the named template with body, bumping its version
+func (r *StoreRepository) Save(ctx context.Context, name, body string) (*Template, error) {
+	version := 1
+	if current, err := r.Latest(ctx, name); err == nil {
+		version = current.Version + 1
+	} else if !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+
+	template := &Template{
+		Name:      name,
+		Version:   version,
+		Body:      body,
+		Variables: Variables(body),
+		UpdatedAt: time.Now(),
+	}
+	data, err := json.Marshal(template)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode template: %w", err)
+	}
+	if err := r.store.Set(ctx, templateKeyPrefix+name, string(data), 0); err != nil {
+		return nil, fmt.Errorf("failed to save template: %w", err)
+	}
+	return template, nil
+}
+
+// Latest returns the named template
+func (r *StoreRepository) Latest(ctx context.Context, name string) (*Template, error) {
+	value, err := r.store.Get(ctx, templateKeyPrefix+name)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get template: %w", err)
+	}
+
+	var template Template
+	if err := json.Unmarshal([]byte(value), &template); err != nil {
+		return nil, fmt.Errorf("failed to decode template: %w", err)
+	}
+	return &template, nil
+}
+
+// List returns every template ordered by name
+func (r *StoreRepository) List(ctx context.Context) ([]Template, error) {
+	keys, err := r.store.Keys(ctx, templateKeyPrefix+"*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list templates: %w", err)
+	}
+	sort.Strings(keys)
+
+	templates := []Template{}
+	for _, key := range keys {
+		template, err := r.Latest(ctx, strings.TrimPrefix(key, templateKeyPrefix))
+		if err != nil {
+			continue
+		}
+		templates = append(templates, *template)
+	}
+	return templates, nil
+}
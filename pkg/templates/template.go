@@ -0,0 +1,65 @@
+package templates
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned when no template exists with the given name
+var ErrNotFound = errors.New("template not found")
+
+// ErrMissingVariable is returned when rendering a template without one of its variables
+var ErrMissingVariable = errors.New("missing template variable")
+
+// variablePattern matches {{name}} placeholders
+var variablePattern = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_]+)\s*\}\}`)
+
+// Template is a versioned message template. Each edit creates a new version.
+type Template struct {
+	Name      string    `json:"name"`
+	Version   int       `json:"version"`
+	Body      string    `json:"body"`
+	Variables []string  `json:"variables"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Repository stores message templates
+type Repository interface {
+	// Save stores body as a new version of the named template
+	Save(ctx context.Context, name, body string) (*Template, error)
+	// Latest returns the latest version of the named template or ErrNotFound
+	Latest(ctx context.Context, name string) (*Template, error)
+	// List returns the latest version of every template
+	List(ctx context.Context) ([]Template, error)
+}
+
+// Variables returns the distinct placeholders of a template body in order of appearance
+func Variables(body string) []string {
+	variables := []string{}
+	seen := make(map[string]bool)
+	for _, match := range variablePattern.FindAllStringSubmatch(body, -1) {
+		if !seen[match[1]] {
+			seen[match[1]] = true
+			variables = append(variables, match[1])
+		}
+	}
+	return variables
+}
+
+// Render replaces the placeholders of the template with the given values
+func (t *Template) Render(values map[string]string) (string, error) {
+	for _, variable := range Variables(t.Body) {
+		if _, ok := values[variable]; !ok {
+			return "", fmt.Errorf("%w: %s", ErrMissingVariable, variable)
+		}
+	}
+
+	return variablePattern.ReplaceAllStringFunc(t.Body, func(placeholder string) string {
+		name := strings.TrimSpace(strings.Trim(placeholder, "{}"))
+		return values[name]
+	}), nil
+}
@@ -0,0 +1,53 @@
+package templates
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		values   map[string]string
+		want     string
+		wantVars []string
+		wantErr  error
+	}{
+		{
+			name:     "every placeholder",
+			body:     "Hola {{name}}, te esperamos el {{ date }}. Gracias {{name}}",
+			values:   map[string]string{"name": "Ana", "date": "01/06"},
+			want:     "Hola Ana, te esperamos el 01/06. Gracias Ana",
+			wantVars: []string{"name", "date"},
+		},
+		{
+			name:     "no placeholders",
+			body:     "Sin variables",
+			want:     "Sin variables",
+			wantVars: []string{},
+		},
+		{
+			name:     "missing value",
+			body:     "Hola {{name}}",
+			wantVars: []string{"name"},
+			wantErr:  ErrMissingVariable,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template := &Template{Body: tt.body}
+			if got := Variables(tt.body); !slices.Equal(got, tt.wantVars) {
+				t.Errorf("Variables() = %v, want %v", got, tt.wantVars)
+			}
+			got, err := template.Render(tt.values)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Render() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Render() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
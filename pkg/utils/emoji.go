@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"strings"
+)
+
+// IsEmoji reports whether the rune is an emoji or an emoji modifier
+func IsEmoji(r rune) bool {
+	switch {
+	case r >= 0x1F000 && r <= 0x1FAFF: // Pictographs, emoticons, transport, flags, skin tones
+		return true
+	case r >= 0x2600 && r <= 0x27BF: // Miscellaneous symbols and dingbats
+		return true
+	case r >= 0x2300 && r <= 0x23FF: // Miscellaneous technical (⏰, ⌛, ...)
+		return true
+	case r >= 0x2B00 && r <= 0x2BFF: // Stars, arrows and squares (⭐, ⬆, ...)
+		return true
+	case r >= 0xE0020 && r <= 0xE007F: // Tag sequences
+		return true
+	case r == 0x200D, r == 0xFE0F, r == 0x20E3: // ZWJ, variation selector, keycap
+		return true
+	case r == 0x3030, r == 0x303D, r == 0x3297, r == 0x3299:
+		return true
+	}
+	return false
+}
+
+// StripEmoji removes emoji from the text. Lines that contained emoji have
+// their spacing normalized so no double or dangling spaces are left behind.
+func StripEmoji(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if !strings.ContainsFunc(line, IsEmoji) {
+			continue
+		}
+
+		lines[i] = strings.Join(strings.Fields(stripLine(line)), " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// stripLine removes the emoji of a line, including the digit or symbol a
+// keycap emoji (1️⃣, #️⃣) is built on
+func stripLine(line string) string {
+	runes := []rune(line)
+	var stripped strings.Builder
+	for i, r := range runes {
+		if IsEmoji(r) || isKeycapBase(runes, i) {
+			continue
+		}
+		stripped.WriteRune(r)
+	}
+	return stripped.String()
+}
+
+// isKeycapBase reports whether the rune at i starts a keycap sequence: a
+// digit, '#' or '*' followed by the keycap mark, optionally after a
+// variation selector
+func isKeycapBase(runes []rune, i int) bool {
+	r := runes[i]
+	if (r < '0' || r > '9') && r != '#' && r != '*' {
+		return false
+	}
+	next := i + 1
+	if next < len(runes) && runes[next] == 0xFE0F {
+		next++
+	}
+	return next < len(runes) && runes[next] == 0x20E3
+}
@@ -0,0 +1,30 @@
+package utils
+
+import "testing"
+
+func TestStripEmoji(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"leading emoji", "📍 Ubicación: Centro", "Ubicación: Centro"},
+		{"trailing emoji", "¡Gracias por elegirnos! 🌟", "¡Gracias por elegirnos!"},
+		{"emoji between words", "Hola 😊 Ana", "Hola Ana"},
+		{"several emoji", "🎉🎉 Listo 🎉", "Listo"},
+		{"zwj sequence", "Equipo 👩‍💻 listo", "Equipo listo"},
+		{"keycap and variation selector", "Opción 1️⃣ elegida ❤️", "Opción elegida"},
+		{"skin tone modifier", "Hola 👋🏽 Ana", "Hola Ana"},
+		{"text without emoji keeps its spacing", "Hola  Ana ", "Hola  Ana "},
+		{"only emoji", "😊", ""},
+		{"accents are kept", "Atención: señor García", "Atención: señor García"},
+		{"lines stay separate", "📍 Centro\n\n⏰ 10:00\nSin emoji", "Centro\n\n10:00\nSin emoji"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := StripEmoji(tt.text); got != tt.want {
+				t.Errorf("StripEmoji(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
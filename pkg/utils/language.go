@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// languageWords are common words of each supported language. Words shared by
+// several languages count for all of them.
+var languageWords = map[string][]string{
+	"es": {
+		"sí", "si", "no", "gracias", "por", "favor", "puedo", "cita", "mañana", "hola",
+		"quiero", "confirmo", "lo", "siento", "para", "el", "la", "los", "las", "de",
+		"que", "y", "es", "está", "muy", "bien", "buenas", "buenos", "días", "tardes",
+		"cancelar", "cancelo", "hora", "tengo", "voy", "mi", "una", "un", "con", "pero",
+		"también", "dónde", "cuándo", "qué", "claro", "vale", "perfecto", "asistir", "ahí", "allí",
+	},
+	"en": {
+		"yes", "yeah", "no", "the", "and", "is", "are", "you", "i", "my",
+		"thanks", "thank", "please", "can't", "cannot", "can", "will", "appointment", "confirm", "cancel",
+		"sorry", "tomorrow", "today", "hello", "hi", "sure", "it", "to", "of", "for",
+		"with", "but", "what", "when", "where", "make", "not", "don't", "won't", "there",
+	},
+	"pt": {
+		"sim", "não", "nao", "obrigado", "obrigada", "por", "favor", "minha", "meu", "consulta",
+		"amanhã", "olá", "ola", "quero", "confirmo", "desculpe", "para", "o", "a", "os",
+		"as", "de", "que", "e", "é", "está", "muito", "bem", "bom", "dia",
+		"posso", "você", "tudo", "com", "mas", "também", "onde", "quando", "lá", "cancelo",
+	},
+}
+
+// languageLetters are letters used by a single supported language
+var languageLetters = map[rune]string{
+	'ñ': "es", '¿': "es", '¡': "es",
+	'ã': "pt", 'õ': "pt", 'ç': "pt",
+}
+
+// languageIndex maps each word to the languages using it
+var languageIndex = func() map[string][]string {
+	index := make(map[string][]string)
+	for language, words := range languageWords {
+		for _, word := range words {
+			index[word] = append(index[word], language)
+		}
+	}
+	return index
+}()
+
+// DetectLanguage detects whether the text is Spanish ("es"), English ("en")
+// or Portuguese ("pt"). The confidence ranges from 0 to 1 and is the margin of
+// the best match over the runner-up; texts without known words score 0.
+func DetectLanguage(text string) (string, float64) {
+	scores := make(map[string]int)
+
+	text = strings.ToLower(text)
+	for _, r := range text {
+		if language, ok := languageLetters[r]; ok {
+			scores[language] += 2
+		}
+	}
+
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+	for _, word := range words {
+		for _, language := range languageIndex[word] {
+			scores[language]++
+		}
+	}
+
+	best, bestScore, secondScore := "", 0, 0
+	for _, language := range []string{"es", "en", "pt"} {
+		score := scores[language]
+		switch {
+		case score > bestScore:
+			best, bestScore, secondScore = language, score, bestScore
+		case score > secondScore:
+			secondScore = score
+		}
+	}
+	if bestScore == 0 {
+		return "", 0
+	}
+
+	return best, float64(bestScore-secondScore) / float64(bestScore)
+}
@@ -0,0 +1,32 @@
+package utils
+
+import "testing"
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		name          string
+		text          string
+		want          string
+		minConfidence float64
+		maxConfidence float64
+	}{
+		{name: "clearly Spanish", text: "Sí, confirmo la cita de mañana, gracias", want: "es", minConfidence: 0.5, maxConfidence: 1},
+		{name: "Spanish letters", text: "¿Puedo cambiar?", want: "es", minConfidence: 0.5, maxConfidence: 1},
+		{name: "clearly English", text: "Yes, I can make it tomorrow, thanks", want: "en", minConfidence: 0.5, maxConfidence: 1},
+		{name: "clearly Portuguese", text: "Sim, obrigado, até amanhã", want: "pt", minConfidence: 0.5, maxConfidence: 1},
+		{name: "word shared by two languages", text: "No", want: "es", maxConfidence: 0},
+		{name: "no known words", text: "ok 👍", want: "", maxConfidence: 0},
+		{name: "empty", text: "", want: "", maxConfidence: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, confidence := DetectLanguage(tt.text)
+			if got != tt.want {
+				t.Errorf("DetectLanguage(%q) language = %q, want %q", tt.text, got, tt.want)
+			}
+			if confidence < tt.minConfidence || confidence > tt.maxConfidence {
+				t.Errorf("DetectLanguage(%q) confidence = %v, want %v-%v", tt.text, confidence, tt.minConfidence, tt.maxConfidence)
+			}
+		})
+	}
+}
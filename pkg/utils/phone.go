@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/nyaruka/phonenumbers"
+)
+
+// NormalizePhone validates a phone number for the given default region
+// (ISO 3166-1 alpha-2, e.g. "CL", or its country calling code, e.g. "56" or
+// "+56") and returns it in E.164 format without the leading "+", which is
+// the form WhatsApp uses as the JID user. Numbers written with an
+// international prefix are parsed regardless of the region.
+func NormalizePhone(raw, region string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", fmt.Errorf("phone number is empty")
+	}
+	region, err := PhoneRegion(region)
+	if err != nil {
+		return "", err
+	}
+
+	// Bare digit strings that already include the country code are common
+	// in our payloads; try them as international numbers first
+	if !strings.HasPrefix(raw, "+") && isDigits(raw) {
+		if number, err := phonenumbers.Parse("+"+raw, ""); err == nil && phonenumbers.IsValidNumber(number) {
+			return formatE164(number), nil
+		}
+	}
+
+	number, err := phonenumbers.Parse(raw, region)
+	if err != nil {
+		return "", fmt.Errorf("invalid phone number %q: %w", raw, err)
+	}
+	if !phonenumbers.IsValidNumber(number) {
+		return "", fmt.Errorf("invalid phone number %q for region %s", raw, region)
+	}
+
+	return formatE164(number), nil
+}
+
+// NormalizePhoneNumber normalizes a raw phone number, applying the default
+// country calling code (e.g. "56" or "+56") when the number is local, and
+// returns the E.164 digits suitable for types.NewJID.
+func NormalizePhoneNumber(raw, defaultCountryCode string) (string, error) {
+	region, err := PhoneRegion(defaultCountryCode)
+	if err != nil {
+		return "", err
+	}
+	return NormalizePhone(raw, region)
+}
+
+// PhoneRegion returns the ISO 3166-1 region of a default region given by
+// its code or by its country calling code. Calling codes shared by several
+// regions, such as "1", resolve to their main region. An empty region is
+// kept, so only international numbers are accepted.
+func PhoneRegion(region string) (string, error) {
+	region = strings.TrimSpace(region)
+	if region == "" {
+		return "", nil
+	}
+	digits := strings.TrimPrefix(region, "+")
+	if !isDigits(digits) {
+		region = strings.ToUpper(region)
+		if phonenumbers.GetCountryCodeForRegion(region) == 0 {
+			return "", fmt.Errorf("unknown phone region %q", region)
+		}
+		return region, nil
+	}
+	code, err := strconv.Atoi(digits)
+	if err != nil {
+		return "", fmt.Errorf("invalid country calling code %q", region)
+	}
+	resolved := phonenumbers.GetRegionCodeForCountryCode(code)
+	if resolved == "ZZ" {
+		return "", fmt.Errorf("unknown country calling code %q", region)
+	}
+	return resolved, nil
+}
+
+// userServers are the JID servers whose user part is a phone number
+var userServers = map[string]bool{
+	"s.whatsapp.net": true,
+	"c.us":           true,
+}
+
+// NormalizeSender normalizes the sender of an inbound message, given either
+// as a phone number or as a user JID such as "56912345678@s.whatsapp.net"
+// (optionally with a device, "56912345678:3@s.whatsapp.net"). JIDs of other
+// servers, such as groups, are rejected.
+func NormalizeSender(raw, region string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if user, server, ok := strings.Cut(raw, "@"); ok {
+		if !userServers[strings.ToLower(server)] {
+			return "", fmt.Errorf("sender %q is not a user JID", raw)
+		}
+		raw, _, _ = strings.Cut(user, ":")
+	}
+	return NormalizePhone(raw, region)
+}
+
+// formatE164 formats the number as E.164 without the leading "+"
+func formatE164(number *phonenumbers.PhoneNumber) string {
+	return strings.TrimPrefix(phonenumbers.Format(number, phonenumbers.E164), "+")
+}
+
+// isDigits reports whether the string contains only ASCII digits
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
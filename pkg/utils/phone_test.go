@@ -0,0 +1,169 @@
+package utils
+
+import "testing"
+
+func TestNormalizePhone(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		region  string
+		want    string
+		wantErr bool
+	}{
+		// Chile
+		{name: "CL local mobile", raw: "9 6123 4567", region: "CL", want: "56961234567"},
+		{name: "CL E.164", raw: "+56 9 6123 4567", region: "CL", want: "56961234567"},
+		{name: "CL digits with country code", raw: "56961234567", region: "CL", want: "56961234567"},
+		{name: "CL E.164 from another region", raw: "+56961234567", region: "US", want: "56961234567"},
+		{name: "CL too short", raw: "9 6123", region: "CL", wantErr: true},
+		{name: "CL unassigned range", raw: "+56 9 1111 2222", region: "CL", wantErr: true},
+
+		// Mexico
+		{name: "MX local", raw: "55 1234 5678", region: "MX", want: "525512345678"},
+		{name: "MX E.164", raw: "+52 55 1234 5678", region: "MX", want: "525512345678"},
+		{name: "MX lowercase region", raw: "(55) 1234-5678", region: "mx", want: "525512345678"},
+		{name: "MX too long", raw: "+52 55 1234 5678 9", region: "MX", wantErr: true},
+
+		// United States
+		{name: "US local", raw: "(650) 253-0000", region: "US", want: "16502530000"},
+		{name: "US E.164", raw: "+1 650-253-0000", region: "US", want: "16502530000"},
+		{name: "US invalid area code", raw: "(123) 456-7890", region: "US", wantErr: true},
+
+		// Region given by its country calling code
+		{name: "calling code", raw: "9 6123 4567", region: "56", want: "56961234567"},
+		{name: "calling code with plus", raw: "(55) 1234-5678", region: "+52", want: "525512345678"},
+		{name: "shared calling code", raw: "(650) 253-0000", region: "1", want: "16502530000"},
+		{name: "unknown calling code", raw: "9 6123 4567", region: "999", wantErr: true},
+		{name: "unknown region", raw: "9 6123 4567", region: "XX", wantErr: true},
+		{name: "no region", raw: "+56 9 6123 4567", want: "56961234567"},
+		{name: "local without a region", raw: "9 6123 4567", wantErr: true},
+
+		// Not phone numbers at all
+		{name: "empty", raw: "  ", region: "CL", wantErr: true},
+		{name: "letters", raw: "call me", region: "CL", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizePhone(tt.raw, tt.region)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("NormalizePhone(%q, %q) = %q, want error", tt.raw, tt.region, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NormalizePhone(%q, %q) error = %v", tt.raw, tt.region, err)
+			}
+			if got != tt.want {
+				t.Errorf("NormalizePhone(%q, %q) = %q, want %q", tt.raw, tt.region, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizePhoneNumber(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		code    string
+		want    string
+		wantErr bool
+	}{
+		{name: "leading plus", raw: "+56961234567", code: "56", want: "56961234567"},
+		{name: "spaces", raw: "+56 9 6123 4567", code: "56", want: "56961234567"},
+		{name: "local with default code", raw: "9 6123 4567", code: "56", want: "56961234567"},
+		{name: "local with plus default code", raw: "(55) 1234-5678", code: "+52", want: "525512345678"},
+		{name: "empty", raw: "", code: "56", wantErr: true},
+		{name: "too short", raw: "9 61", code: "56", wantErr: true},
+		{name: "unknown default code", raw: "9 6123 4567", code: "999", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizePhoneNumber(tt.raw, tt.code)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("NormalizePhoneNumber(%q, %q) = %q, want error", tt.raw, tt.code, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NormalizePhoneNumber(%q, %q) error = %v", tt.raw, tt.code, err)
+			}
+			if got != tt.want {
+				t.Errorf("NormalizePhoneNumber(%q, %q) = %q, want %q", tt.raw, tt.code, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPhoneRegion(t *testing.T) {
+	tests := []struct {
+		region  string
+		want    string
+		wantErr bool
+	}{
+		{region: "CL", want: "CL"},
+		{region: " mx ", want: "MX"},
+		{region: "56", want: "CL"},
+		{region: "+56", want: "CL"},
+		{region: "1", want: "US"},
+		{region: "", want: ""},
+		{region: "XX", wantErr: true},
+		{region: "999", wantErr: true},
+		{region: "+", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := PhoneRegion(tt.region)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("PhoneRegion(%q) = %q, want error", tt.region, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("PhoneRegion(%q) = %q, %v, want %q", tt.region, got, err, tt.want)
+		}
+	}
+}
+
+func TestNormalizeSender(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    string
+		wantErr bool
+	}{
+		{name: "bare number", raw: "56961234567", want: "56961234567"},
+		{name: "formatted number", raw: " +56 9 6123 4567 ", want: "56961234567"},
+		{name: "local number", raw: "9 6123 4567", want: "56961234567"},
+		{name: "user JID", raw: "56961234567@s.whatsapp.net", want: "56961234567"},
+		{name: "user JID with device", raw: "56961234567:3@s.whatsapp.net", want: "56961234567"},
+		{name: "legacy user JID", raw: "56961234567@C.US", want: "56961234567"},
+		{name: "empty", raw: "", wantErr: true},
+		{name: "empty user", raw: "@s.whatsapp.net", wantErr: true},
+		{name: "group JID", raw: "120363025246125486@g.us", wantErr: true},
+		{name: "invalid number in JID", raw: "123@s.whatsapp.net", wantErr: true},
+		{name: "letters", raw: "someone", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeSender(tt.raw, "CL")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("NormalizeSender(%q) = %q, want error", tt.raw, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NormalizeSender(%q) error = %v", tt.raw, err)
+			}
+			if got != tt.want {
+				t.Errorf("NormalizeSender(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+
+	"github.com/mdp/qrterminal/v3"
+	"rsc.io/qr"
+)
+
+// qrQuietZone is the blank margin around the QR modules required by scanners
+const qrQuietZone = 4
+
+// RenderQR renders a QR code as text for a terminal, packing two rows of
+// modules into each line with half blocks
+func RenderQR(code string) string {
+	var buf bytes.Buffer
+	qrterminal.GenerateWithConfig(code, qrterminal.Config{
+		Level:          qrterminal.L,
+		Writer:         &buf,
+		HalfBlocks:     true,
+		BlackChar:      qrterminal.BLACK_BLACK,
+		WhiteBlackChar: qrterminal.WHITE_BLACK,
+		WhiteChar:      qrterminal.WHITE_WHITE,
+		BlackWhiteChar: qrterminal.BLACK_WHITE,
+		QuietZone:      1,
+	})
+	return buf.String()
+}
+
+// RenderQRPNG renders a QR code as a size x size PNG. Each module is drawn
+// with the same whole number of pixels and the code is centered, so the
+// image stays sharp; a size too small for one pixel per module yields a
+// larger image.
+func RenderQRPNG(code string, size int) ([]byte, error) {
+	encoded, err := qr.Encode(code, qr.L)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode QR code: %w", err)
+	}
+
+	modules := encoded.Size + 2*qrQuietZone
+	scale := size / modules
+	if scale < 1 {
+		scale = 1
+		size = modules
+	}
+	offset := (size - modules*scale) / 2
+
+	img := image.NewGray(image.Rect(0, 0, size, size))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	for y := 0; y < encoded.Size; y++ {
+		for x := 0; x < encoded.Size; x++ {
+			if !encoded.Black(x, y) {
+				continue
+			}
+			left := offset + (x+qrQuietZone)*scale
+			top := offset + (y+qrQuietZone)*scale
+			for dy := 0; dy < scale; dy++ {
+				for dx := 0; dx < scale; dx++ {
+					img.SetGray(left+dx, top+dy, color.Gray{})
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode QR image: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+
+	"rsc.io/qr"
+)
+
+// qrCode is a pairing code shaped like the ones WhatsApp sends
+const qrCode = "2@ZrXv9c1Ma0dE2Lz3sQ7H,Wn4pO8kYq6JfTg1BvR5uXe==,9pLkQ2sW8dFh3Jm==,Xy7ZaB4cD1eF6gH=="
+
+// parseHalfBlocks decodes terminal half-block output into rows of modules,
+// true for light ones. Each character holds two rows: light modules are the
+// drawn blocks. The first row is the blank half above the quiet zone and is
+// dropped.
+func parseHalfBlocks(t *testing.T, output string) [][]bool {
+	t.Helper()
+	var rows [][]bool
+	for _, line := range strings.Split(strings.TrimRight(output, "\n"), "\n") {
+		var top, bottom []bool
+		for _, r := range line {
+			switch r {
+			case '█':
+				top, bottom = append(top, true), append(bottom, true)
+			case '▀':
+				top, bottom = append(top, true), append(bottom, false)
+			case '▄':
+				top, bottom = append(top, false), append(bottom, true)
+			case ' ':
+				top, bottom = append(top, false), append(bottom, false)
+			default:
+				t.Fatalf("unexpected character %q in the rendered QR", r)
+			}
+		}
+		rows = append(rows, top, bottom)
+	}
+	if len(rows) == 0 {
+		t.Fatal("rendered QR is empty")
+	}
+	return rows[1:]
+}
+
+func TestRenderQR(t *testing.T) {
+	encoded, err := qr.Encode(qrCode, qr.L)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	rows := parseHalfBlocks(t, RenderQR(qrCode))
+
+	// One light module of quiet zone on every side
+	width := encoded.Size + 2
+	if len(rows) < width {
+		t.Fatalf("rendered %d rows, want at least %d", len(rows), width)
+	}
+	for y := 0; y < width; y++ {
+		if len(rows[y]) != width {
+			t.Fatalf("row %d has %d modules, want %d", y, len(rows[y]), width)
+		}
+		for x := 0; x < width; x++ {
+			quiet := x == 0 || y == 0 || x == width-1 || y == width-1
+			wantLight := quiet || !encoded.Black(x-1, y-1)
+			if rows[y][x] != wantLight {
+				t.Fatalf("module (%d, %d) light = %t, want %t", x, y, rows[y][x], wantLight)
+			}
+		}
+	}
+
+	if RenderQR(qrCode) != RenderQR(qrCode) {
+		t.Error("RenderQR() is not deterministic")
+	}
+}
+
+func TestRenderQRPNG(t *testing.T) {
+	encoded, err := qr.Encode(qrCode, qr.L)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+
+	for _, size := range []int{256, 10} {
+		data, err := RenderQRPNG(qrCode, size)
+		if err != nil {
+			t.Fatalf("RenderQRPNG(%d) error = %v", size, err)
+		}
+		img, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("png.Decode() error = %v", err)
+		}
+
+		// Sizes too small for one pixel per module grow to fit the code
+		want := size
+		if modules := encoded.Size + 2*qrQuietZone; size < modules {
+			want = modules
+		}
+		if bounds := img.Bounds(); bounds.Dx() != want || bounds.Dy() != want {
+			t.Errorf("RenderQRPNG(%d) is %dx%d, want %dx%d", size, bounds.Dx(), bounds.Dy(), want, want)
+		}
+		if r, _, _, _ := img.At(0, 0).RGBA(); r == 0 {
+			t.Errorf("RenderQRPNG(%d) corner is dark, want the quiet zone", size)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+package utils
+
+import "unicode/utf8"
+
+// Truncate shortens text to at most limit runes and reports whether it was
+// cut. A limit of zero or less leaves the text unchanged.
+func Truncate(text string, limit int) (string, bool) {
+	if limit <= 0 || utf8.RuneCountInString(text) <= limit {
+		return text, false
+	}
+
+	runes := []rune(text)
+	return string(runes[:limit]), true
+}
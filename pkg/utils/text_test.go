@@ -0,0 +1,28 @@
+package utils
+
+import "testing"
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		name          string
+		text          string
+		limit         int
+		want          string
+		wantTruncated bool
+	}{
+		{name: "shorter than the limit", text: "Sí", limit: 5, want: "Sí"},
+		{name: "exactly the limit", text: "Hola", limit: 4, want: "Hola"},
+		{name: "cut at the limit", text: "Hola mundo", limit: 4, want: "Hola", wantTruncated: true},
+		{name: "counts runes not bytes", text: "añoñoño", limit: 3, want: "año", wantTruncated: true},
+		{name: "emoji kept whole", text: "👍👍👍", limit: 2, want: "👍👍", wantTruncated: true},
+		{name: "zero disables the limit", text: "Hola mundo", limit: 0, want: "Hola mundo"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, truncated := Truncate(tt.text, tt.limit)
+			if got != tt.want || truncated != tt.wantTruncated {
+				t.Errorf("Truncate(%q, %d) = %q, %v, want %q, %v", tt.text, tt.limit, got, truncated, tt.want, tt.wantTruncated)
+			}
+		})
+	}
+}
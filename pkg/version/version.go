@@ -0,0 +1,51 @@
+package version
+
+import "runtime/debug"
+
+// whatsmeowModule is the module path of the WhatsApp library
+const whatsmeowModule = "go.mau.fi/whatsmeow"
+
+// Build metadata, injected at build time with -ldflags, e.g.
+//
+//	go build -ldflags "-X github.com/pabbloacevedog/whatspp-service-glidpa/pkg/version.Version=1.2.0"
+var (
+	Version   = "dev"
+	Commit    = "unknown"
+	BuildTime = "unknown"
+	// Whatsmeow defaults to the version recorded in the binary's build info
+	Whatsmeow = ""
+)
+
+// Info is the build metadata of the running binary
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+	Whatsmeow string `json:"whatsmeow"`
+}
+
+// Get returns the build metadata of the running binary
+func Get() Info {
+	return Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildTime: BuildTime,
+		Whatsmeow: whatsmeowVersion(),
+	}
+}
+
+// whatsmeowVersion returns the injected whatsmeow version, falling back to
+// the module version recorded in the build info
+func whatsmeowVersion() string {
+	if Whatsmeow != "" {
+		return Whatsmeow
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, dep := range info.Deps {
+			if dep.Path == whatsmeowModule {
+				return dep.Version
+			}
+		}
+	}
+	return "unknown"
+}
@@ -0,0 +1,177 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FieldMapping locates the fields of an inbound message in a webhook
+// payload. Each field is a dot-separated path where numeric segments index
+// arrays, e.g. "entry.0.changes.0.value.messages.0.from". Alternatives are
+// separated by "|" and the first one present wins.
+type FieldMapping struct {
+	MessageID  string
+	From       string
+	Body       string
+	ResponseID string
+}
+
+// InboundFields are the fields of an inbound message extracted from a
+// webhook payload
+type InboundFields struct {
+	MessageID  string
+	From       string
+	Body       string
+	ResponseID string
+}
+
+// DefaultMapping is the service's own {message_id, from, body, response_id}
+// payload
+const DefaultMapping = "default"
+
+// cloudAPIMessage is the path of the message in a WhatsApp Cloud API
+// notification
+const cloudAPIMessage = "entry.0.changes.0.value.messages.0."
+
+// presets are the mappings of common BSP payloads
+var presets = map[string]FieldMapping{
+	DefaultMapping: {
+		MessageID:  "message_id",
+		From:       "from",
+		Body:       "body",
+		ResponseID: "response_id",
+	},
+	// WhatsApp Cloud API notifications, also forwarded as-is by 360dialog
+	"meta": {
+		MessageID: cloudAPIMessage + "id",
+		From:      cloudAPIMessage + "from",
+		Body: cloudAPIMessage + "text.body|" +
+			cloudAPIMessage + "interactive.button_reply.title|" +
+			cloudAPIMessage + "interactive.list_reply.title|" +
+			cloudAPIMessage + "button.text",
+		ResponseID: cloudAPIMessage + "interactive.button_reply.id|" +
+			cloudAPIMessage + "interactive.list_reply.id|" +
+			cloudAPIMessage + "button.payload",
+	},
+	// 360dialog on-premise API payloads
+	"360dialog": {
+		MessageID:  "messages.0.id",
+		From:       "messages.0.from",
+		Body:       "messages.0.text.body|messages.0.interactive.button_reply.title|messages.0.interactive.list_reply.title|messages.0.button.text",
+		ResponseID: "messages.0.interactive.button_reply.id|messages.0.interactive.list_reply.id|messages.0.button.payload",
+	},
+	// Gupshup v2 inbound message events
+	"gupshup": {
+		MessageID:  "payload.id",
+		From:       "payload.source|payload.sender.phone",
+		Body:       "payload.payload.text|payload.payload.title",
+		ResponseID: "payload.payload.id",
+	},
+}
+
+// Preset returns the mapping of a preset by name
+func Preset(name string) (FieldMapping, bool) {
+	mapping, ok := presets[name]
+	return mapping, ok
+}
+
+// PresetNames returns the names of the available presets
+func PresetNames() []string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Override returns the mapping with the non-empty paths of other replacing
+// its own
+func (m FieldMapping) Override(other FieldMapping) FieldMapping {
+	if other.MessageID != "" {
+		m.MessageID = other.MessageID
+	}
+	if other.From != "" {
+		m.From = other.From
+	}
+	if other.Body != "" {
+		m.Body = other.Body
+	}
+	if other.ResponseID != "" {
+		m.ResponseID = other.ResponseID
+	}
+	return m
+}
+
+// Extract decodes a JSON payload and returns the fields found by the
+// mapping. Missing fields are left empty.
+func (m FieldMapping) Extract(payload []byte) (InboundFields, error) {
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return InboundFields{}, fmt.Errorf("invalid webhook payload: %w", err)
+	}
+	if _, ok := document.(map[string]interface{}); !ok {
+		return InboundFields{}, fmt.Errorf("invalid webhook payload: expected a JSON object")
+	}
+
+	return InboundFields{
+		MessageID:  lookup(document, m.MessageID),
+		From:       lookup(document, m.From),
+		Body:       lookup(document, m.Body),
+		ResponseID: lookup(document, m.ResponseID),
+	}, nil
+}
+
+// lookup returns the first scalar value found at one of the alternative
+// paths, or an empty string
+func lookup(document interface{}, paths string) string {
+	for _, path := range strings.Split(paths, "|") {
+		if path = strings.TrimSpace(path); path == "" {
+			continue
+		}
+		if value, ok := scalar(resolve(document, path)); ok {
+			return value
+		}
+	}
+	return ""
+}
+
+// resolve walks a dot-separated path through objects and arrays
+func resolve(value interface{}, path string) interface{} {
+	for _, segment := range strings.Split(path, ".") {
+		switch node := value.(type) {
+		case map[string]interface{}:
+			value = node[segment]
+		case []interface{}:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil
+			}
+			value = node[index]
+		default:
+			return nil
+		}
+	}
+	return value
+}
+
+// scalar formats a string, number or boolean; objects, arrays and null are
+// not field values
+func scalar(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, v != ""
+	case json.Number:
+		return v.String(), true
+	case bool:
+		return strconv.FormatBool(v), true
+	}
+	return "", false
+}
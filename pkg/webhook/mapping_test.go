@@ -0,0 +1,115 @@
+package webhook
+
+import (
+	"strings"
+	"testing"
+)
+
+// cloudAPIPayload is a WhatsApp Cloud API notification with a button reply
+const cloudAPIPayload = `{
+	"object": "whatsapp_business_account",
+	"entry": [{
+		"id": "1029384756",
+		"changes": [{
+			"field": "messages",
+			"value": {
+				"messaging_product": "whatsapp",
+				"messages": [{
+					"from": "56961234567",
+					"id": "wamid.HBgLNTY5NjEyMzQ1Njc",
+					"timestamp": "1717200000",
+					"type": "interactive",
+					"interactive": {
+						"type": "button_reply",
+						"button_reply": {"id": "confirm", "title": "Confirmar"}
+					}
+				}]
+			}
+		}]
+	}]
+}`
+
+// gupshupPayload is a Gupshup v2 inbound text message event
+const gupshupPayload = `{
+	"app": "glidpa",
+	"timestamp": 1717200000000,
+	"type": "message",
+	"payload": {
+		"id": "ABEGVWlhI0VnAhCMKLOAmd4Iz",
+		"source": "56961234568",
+		"type": "text",
+		"payload": {"text": "Sí, confirmo"},
+		"sender": {"phone": "56961234568", "name": "Ana"}
+	}
+}`
+
+func TestPresetMappings(t *testing.T) {
+	tests := []struct {
+		preset  string
+		payload string
+		want    InboundFields
+	}{
+		{
+			preset:  DefaultMapping,
+			payload: `{"message_id": "msg-1", "from": "56961234567", "body": "Sí"}`,
+			want:    InboundFields{MessageID: "msg-1", From: "56961234567", Body: "Sí"},
+		},
+		{
+			preset:  "meta",
+			payload: cloudAPIPayload,
+			want:    InboundFields{MessageID: "wamid.HBgLNTY5NjEyMzQ1Njc", From: "56961234567", Body: "Confirmar", ResponseID: "confirm"},
+		},
+		{
+			preset:  "gupshup",
+			payload: gupshupPayload,
+			want:    InboundFields{MessageID: "ABEGVWlhI0VnAhCMKLOAmd4Iz", From: "56961234568", Body: "Sí, confirmo"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.preset, func(t *testing.T) {
+			mapping, ok := Preset(tt.preset)
+			if !ok {
+				t.Fatalf("Preset(%q) not found", tt.preset)
+			}
+			got, err := mapping.Extract([]byte(tt.payload))
+			if err != nil {
+				t.Fatalf("Extract() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Extract() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMappingPaths(t *testing.T) {
+	mapping := FieldMapping{
+		MessageID: "data.id",
+		From:      "data.sender|data.contact.wa_id",
+		Body:      "data.message",
+	}
+	payload := `{"data": {"id": 42, "contact": {"wa_id": "56961234567"}, "message": {"text": "Sí"}}}`
+
+	got, err := mapping.Extract([]byte(payload))
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	// Numbers are formatted, the first alternative present wins and objects
+	// are not field values
+	want := InboundFields{MessageID: "42", From: "56961234567"}
+	if got != want {
+		t.Errorf("Extract() = %+v, want %+v", got, want)
+	}
+
+	// Overrides replace single paths of a preset
+	custom := mapping.Override(FieldMapping{Body: "data.message.text"})
+	if got, _ := custom.Extract([]byte(payload)); got.Body != "Sí" || got.From != "56961234567" {
+		t.Errorf("overridden Extract() = %+v, want the body and the original sender path", got)
+	}
+
+	for _, payload := range []string{`{"data":`, `["not", "an", "object"]`} {
+		if _, err := mapping.Extract([]byte(payload)); err == nil || !strings.Contains(err.Error(), "invalid webhook payload") {
+			t.Errorf("Extract(%s) error = %v, want an invalid payload", payload, err)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/requestid"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/retry"
+)
+
+// Event is the payload posted to the webhook
+type Event struct {
+	Type      string      `json:"type"`
+	Timestamp time.Time   `json:"timestamp"`
+	Data      interface{} `json:"data,omitempty"`
+}
+
+// Notifier posts JSON events to an external webhook URL
+type Notifier struct {
+	url     string
+	client  *http.Client
+	backoff retry.Backoff
+	secret  string
+}
+
+// NotifierOption is a function that configures a Notifier
+type NotifierOption func(*Notifier)
+
+// WithRetry retries failed posts with the given backoff. Events rejected
+// with a 4xx status other than 429 are not retried.
+func WithRetry(backoff retry.Backoff) NotifierOption {
+	return func(n *Notifier) {
+		n.backoff = backoff
+	}
+}
+
+// WithSecret signs each post with the HMAC-SHA256 of its body in the
+// SignatureHeader, the same scheme VerifySignature checks on inbound webhooks
+func WithSecret(secret string) NotifierOption {
+	return func(n *Notifier) {
+		n.secret = secret
+	}
+}
+
+// NewNotifier creates a new Notifier for the given URL. Without WithRetry
+// each event is posted once.
+func NewNotifier(url string, options ...NotifierOption) *Notifier {
+	n := &Notifier{
+		url:     url,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		backoff: retry.Backoff{MaxAttempts: 1},
+	}
+	for _, option := range options {
+		option(n)
+	}
+	return n
+}
+
+// Notify posts an event of the given type with its data
+func (n *Notifier) Notify(ctx context.Context, eventType string, data interface{}) error {
+	return n.Post(ctx, Event{
+		Type:      eventType,
+		Timestamp: time.Now(),
+		Data:      data,
+	})
+}
+
+// Post posts the payload as is, for receivers expecting their own format
+// rather than an Event
+func (n *Notifier) Post(ctx context.Context, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook event: %w", err)
+	}
+
+	return n.backoff.Do(ctx, func(ctx context.Context) error {
+		return n.post(ctx, body)
+	})
+}
+
+// post sends the encoded event once
+func (n *Notifier) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return retry.Permanent(fmt.Errorf("failed to create webhook request: %w", err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if n.secret != "" {
+		req.Header.Set(SignatureHeader, signaturePrefix+Sign(n.secret, body))
+	}
+	if id := requestid.FromContext(ctx); id != "" {
+		req.Header.Set(requestid.Header(), id)
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post webhook event: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return retry.Permanent(fmt.Errorf("webhook returned status %d", resp.StatusCode))
+	}
+
+	return nil
+}
@@ -0,0 +1,38 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// SignatureHeader is the HTTP header carrying the signature of a webhook
+// payload
+const SignatureHeader = "X-Webhook-Signature"
+
+// signaturePrefix optionally precedes the hex digest in the header, as in
+// GitHub and Meta webhooks
+const signaturePrefix = "sha256="
+
+// Sign returns the hex HMAC-SHA256 of the payload with the shared secret
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature reports whether the signature, a hex HMAC-SHA256 of the
+// raw payload with or without the "sha256=" prefix, matches the secret. The
+// comparison takes constant time.
+func VerifySignature(secret string, payload []byte, signature string) bool {
+	signature = strings.TrimPrefix(strings.TrimSpace(signature), signaturePrefix)
+	given, err := hex.DecodeString(signature)
+	if err != nil || len(given) != sha256.Size {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hmac.Equal(given, mac.Sum(nil))
+}
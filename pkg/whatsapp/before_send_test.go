@@ -0,0 +1,80 @@
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestBeforeSendHookBlocksSend(t *testing.T) {
+	errBlocked := errors.New("blocked word")
+	recorder := newFakeRecorder()
+	client := newTestClient(t, WithDryRun(0, 0), WithSendRecorder(recorder))
+	sent := captureSends(client)
+	client.OnBeforeSend(func(msg *OutboundMessage) error {
+		if strings.Contains(MessageText(msg.Message), "spam") {
+			return errBlocked
+		}
+		return nil
+	})
+	jid := types.NewJID(testPhone, types.DefaultUserServer)
+
+	if _, err := client.SendText(context.Background(), jid, "buy spam now"); !errors.Is(err, errBlocked) {
+		t.Fatalf("SendText() error = %v, want %v", err, errBlocked)
+	}
+	if len(recorder.records) != 0 {
+		t.Errorf("blocked send was recorded: %+v", recorder.records)
+	}
+
+	resp, err := client.SendText(context.Background(), jid, "Hola")
+	if err != nil {
+		t.Fatalf("SendText() error = %v", err)
+	}
+	if _, ok := recorder.get(resp.ID); !ok {
+		t.Error("allowed send was not recorded")
+	}
+	// The capture hook runs before the filter, so it saw both messages
+	if got := len(sent.all()); got != 2 {
+		t.Errorf("hooks ran for %d sends, want 2", got)
+	}
+}
+
+func TestBeforeSendHooksMutateInOrder(t *testing.T) {
+	recorder := newFakeRecorder()
+	client := newTestClient(t, WithDryRun(0, 0), WithSendRecorder(recorder))
+	var order []string
+	client.OnBeforeSend(func(msg *OutboundMessage) error {
+		order = append(order, "first")
+		msg.Message = &waE2E.Message{Conversation: proto.String(MessageText(msg.Message) + " [A]")}
+		msg.Metadata = map[string]string{"variant": "A"}
+		return nil
+	})
+	client.OnBeforeSend(func(msg *OutboundMessage) error {
+		order = append(order, "second")
+		if msg.Metadata["variant"] != "A" {
+			t.Errorf("second hook saw metadata %v, want the first hook's change", msg.Metadata)
+		}
+		return nil
+	})
+	sent := captureSends(client)
+
+	resp, err := client.SendText(context.Background(), types.NewJID(testPhone, types.DefaultUserServer), "Hola")
+	if err != nil {
+		t.Fatalf("SendText() error = %v", err)
+	}
+	if strings.Join(order, ",") != "first,second" {
+		t.Errorf("hooks ran in order %v", order)
+	}
+	if got := MessageText(sent.last(t).Message); got != "Hola [A]" {
+		t.Errorf("sent text = %q, want %q", got, "Hola [A]")
+	}
+	record, _ := recorder.get(resp.ID)
+	if record.Text != "Hola [A]" || record.Metadata["variant"] != "A" {
+		t.Errorf("record = %+v, want the mutated message", record)
+	}
+}
@@ -0,0 +1,166 @@
+package whatsapp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/utils"
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+)
+
+// CTAButtonType is the action performed by a call-to-action button
+type CTAButtonType string
+
+// Supported call-to-action button types
+const (
+	CTAButtonURL  CTAButtonType = "cta_url"
+	CTAButtonCall CTAButtonType = "cta_call"
+)
+
+// CTAButton is a call-to-action button that opens a URL or calls a number.
+// Unlike quick-reply buttons, tapping it does not send a reply to the chat.
+type CTAButton struct {
+	Type        CTAButtonType `json:"type"`
+	DisplayText string        `json:"display_text"`
+	URL         string        `json:"url,omitempty"`
+	PhoneNumber string        `json:"phone_number,omitempty"`
+}
+
+// QuickReplyButton is a button that sends its ID back to the chat when tapped
+type QuickReplyButton struct {
+	ID          string `json:"id"`
+	DisplayText string `json:"display_text"`
+}
+
+// Validate checks the button has the fields its type requires
+func (b CTAButton) Validate() error {
+	if b.DisplayText == "" {
+		return fmt.Errorf("button display text is required")
+	}
+
+	switch b.Type {
+	case CTAButtonURL:
+		parsed, err := url.Parse(b.URL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("button %q has an invalid URL %q", b.DisplayText, b.URL)
+		}
+	case CTAButtonCall:
+		if _, err := utils.NormalizePhone(b.PhoneNumber, ""); err != nil {
+			return fmt.Errorf("button %q has an invalid phone number: %w", b.DisplayText, err)
+		}
+	default:
+		return fmt.Errorf("button %q has an unsupported type %q", b.DisplayText, b.Type)
+	}
+	return nil
+}
+
+// nativeFlowButton builds the native flow button for the CTA button
+func (b CTAButton) nativeFlowButton() (*waE2E.InteractiveMessage_NativeFlowMessage_NativeFlowButton, error) {
+	params := map[string]string{"display_text": b.DisplayText}
+	switch b.Type {
+	case CTAButtonURL:
+		params["url"] = b.URL
+		params["merchant_url"] = b.URL
+	case CTAButtonCall:
+		phone, _ := utils.NormalizePhone(b.PhoneNumber, "")
+		params["phone_number"] = "+" + phone
+	}
+	return newNativeFlowButton(string(b.Type), params)
+}
+
+// BuildCTAButtonsMessage builds an interactive message with call-to-action buttons
+func BuildCTAButtonsMessage(body string, buttons []CTAButton) (*waE2E.Message, error) {
+	if len(buttons) == 0 {
+		return nil, fmt.Errorf("at least one button is required")
+	}
+
+	flowButtons := make([]*waE2E.InteractiveMessage_NativeFlowMessage_NativeFlowButton, 0, len(buttons))
+	for _, button := range buttons {
+		if err := button.Validate(); err != nil {
+			return nil, err
+		}
+		flowButton, err := button.nativeFlowButton()
+		if err != nil {
+			return nil, err
+		}
+		flowButtons = append(flowButtons, flowButton)
+	}
+
+	return buildNativeFlowMessage(body, flowButtons), nil
+}
+
+// BuildQuickReplyButtonsMessage builds an interactive message with quick-reply buttons
+func BuildQuickReplyButtonsMessage(body string, buttons []QuickReplyButton) (*waE2E.Message, error) {
+	if len(buttons) == 0 {
+		return nil, fmt.Errorf("at least one button is required")
+	}
+
+	flowButtons := make([]*waE2E.InteractiveMessage_NativeFlowMessage_NativeFlowButton, 0, len(buttons))
+	for _, button := range buttons {
+		if button.ID == "" || button.DisplayText == "" {
+			return nil, fmt.Errorf("quick reply buttons need an ID and display text")
+		}
+		flowButton, err := newNativeFlowButton("quick_reply", map[string]string{
+			"display_text": button.DisplayText,
+			"id":           button.ID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		flowButtons = append(flowButtons, flowButton)
+	}
+
+	return buildNativeFlowMessage(body, flowButtons), nil
+}
+
+// SendCTAButtons sends a message with call-to-action buttons
+func (c *Client) SendCTAButtons(ctx context.Context, jid types.JID, body string, buttons []CTAButton) (whatsmeow.SendResponse, error) {
+	message, err := BuildCTAButtonsMessage(body, buttons)
+	if err != nil {
+		return whatsmeow.SendResponse{}, fmt.Errorf("invalid buttons: %w", err)
+	}
+	return c.Send(ctx, jid, message)
+}
+
+// SendQuickReplyButtons sends a message with quick-reply buttons
+func (c *Client) SendQuickReplyButtons(ctx context.Context, jid types.JID, body string, buttons []QuickReplyButton) (whatsmeow.SendResponse, error) {
+	message, err := BuildQuickReplyButtonsMessage(body, buttons)
+	if err != nil {
+		return whatsmeow.SendResponse{}, fmt.Errorf("invalid buttons: %w", err)
+	}
+	return c.Send(ctx, jid, message)
+}
+
+// newNativeFlowButton encodes the button parameters as native flow JSON
+func newNativeFlowButton(name string, params map[string]string) (*waE2E.InteractiveMessage_NativeFlowMessage_NativeFlowButton, error) {
+	paramsJSON, err := json.Marshal(params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode button params: %w", err)
+	}
+	return &waE2E.InteractiveMessage_NativeFlowMessage_NativeFlowButton{
+		Name:             proto.String(name),
+		ButtonParamsJSON: proto.String(string(paramsJSON)),
+	}, nil
+}
+
+// buildNativeFlowMessage wraps native flow buttons in an interactive message
+func buildNativeFlowMessage(body string, buttons []*waE2E.InteractiveMessage_NativeFlowMessage_NativeFlowButton) *waE2E.Message {
+	return &waE2E.Message{
+		InteractiveMessage: &waE2E.InteractiveMessage{
+			Body: &waE2E.InteractiveMessage_Body{
+				Text: proto.String(body),
+			},
+			InteractiveMessage: &waE2E.InteractiveMessage_NativeFlowMessage_{
+				NativeFlowMessage: &waE2E.InteractiveMessage_NativeFlowMessage{
+					Buttons:        buttons,
+					MessageVersion: proto.Int32(1),
+				},
+			},
+		},
+	}
+}
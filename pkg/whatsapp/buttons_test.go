@@ -0,0 +1,97 @@
+package whatsapp
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"go.mau.fi/whatsmeow/types"
+)
+
+func TestSendCTAButtons(t *testing.T) {
+	client := newTestClient(t)
+	sent := captureSends(client)
+
+	_, err := client.SendCTAButtons(context.Background(), types.NewJID(testPhone, types.DefaultUserServer), "Tu cita está confirmada", []CTAButton{
+		{Type: CTAButtonURL, DisplayText: "Ver ubicación", URL: "https://maps.google.com/?q=-33.45,-70.66"},
+		{Type: CTAButtonCall, DisplayText: "Llamar", PhoneNumber: "+56 9 6123 4567"},
+	})
+	if err != nil {
+		t.Fatalf("SendCTAButtons() error = %v", err)
+	}
+
+	interactive := sent.last(t).Message.GetInteractiveMessage()
+	if got := interactive.GetBody().GetText(); got != "Tu cita está confirmada" {
+		t.Errorf("body = %q", got)
+	}
+	flow := interactive.GetNativeFlowMessage()
+	if flow.GetMessageVersion() != 1 {
+		t.Errorf("message version = %d, want 1", flow.GetMessageVersion())
+	}
+
+	want := []struct {
+		name   string
+		params map[string]string
+	}{
+		{"cta_url", map[string]string{
+			"display_text": "Ver ubicación",
+			"url":          "https://maps.google.com/?q=-33.45,-70.66",
+			"merchant_url": "https://maps.google.com/?q=-33.45,-70.66",
+		}},
+		{"cta_call", map[string]string{
+			"display_text": "Llamar",
+			"phone_number": "+56961234567",
+		}},
+	}
+	buttons := flow.GetButtons()
+	if len(buttons) != len(want) {
+		t.Fatalf("got %d buttons, want %d", len(buttons), len(want))
+	}
+	for i, button := range buttons {
+		var params map[string]string
+		if err := json.Unmarshal([]byte(button.GetButtonParamsJSON()), &params); err != nil {
+			t.Fatalf("button %d params %q: %v", i, button.GetButtonParamsJSON(), err)
+		}
+		if button.GetName() != want[i].name || !reflect.DeepEqual(params, want[i].params) {
+			t.Errorf("button %d = %s %v, want %s %v", i, button.GetName(), params, want[i].name, want[i].params)
+		}
+	}
+}
+
+func TestBuildCTAButtonsMessageValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		buttons []CTAButton
+	}{
+		{"no buttons", nil},
+		{"missing text", []CTAButton{{Type: CTAButtonURL, URL: "https://example.com"}}},
+		{"relative URL", []CTAButton{{Type: CTAButtonURL, DisplayText: "Ver", URL: "/booking/1"}}},
+		{"unsupported scheme", []CTAButton{{Type: CTAButtonURL, DisplayText: "Ver", URL: "javascript:alert(1)"}}},
+		{"local phone number", []CTAButton{{Type: CTAButtonCall, DisplayText: "Llamar", PhoneNumber: "9 6123 4567"}}},
+		{"invalid phone number", []CTAButton{{Type: CTAButtonCall, DisplayText: "Llamar", PhoneNumber: "+56 9 1111 2222"}}},
+		{"unknown type", []CTAButton{{Type: "cta_copy", DisplayText: "Copiar"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := BuildCTAButtonsMessage("Hola", tt.buttons); err == nil {
+				t.Error("BuildCTAButtonsMessage() succeeded, want error")
+			}
+		})
+	}
+}
+
+func TestBuildQuickReplyButtonsMessage(t *testing.T) {
+	message, err := BuildQuickReplyButtonsMessage("¿Confirmas?", []QuickReplyButton{{ID: "booking_confirm", DisplayText: "Confirmar"}})
+	if err != nil {
+		t.Fatalf("BuildQuickReplyButtonsMessage() error = %v", err)
+	}
+	button := message.GetInteractiveMessage().GetNativeFlowMessage().GetButtons()[0]
+	if button.GetName() != "quick_reply" || button.GetButtonParamsJSON() != `{"display_text":"Confirmar","id":"booking_confirm"}` {
+		t.Errorf("button = %s %s", button.GetName(), button.GetButtonParamsJSON())
+	}
+
+	if _, err := BuildQuickReplyButtonsMessage("¿Confirmas?", []QuickReplyButton{{DisplayText: "Confirmar"}}); err == nil {
+		t.Error("quick reply button without ID accepted")
+	}
+}
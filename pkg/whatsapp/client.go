@@ -3,12 +3,15 @@ package whatsapp
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/requestid"
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/retry"
 	"go.mau.fi/whatsmeow"
 	"go.mau.fi/whatsmeow/proto/waE2E"
 	"go.mau.fi/whatsmeow/store"
@@ -20,13 +23,51 @@ import (
 
 // WhatsAppMessage represents a message received from WhatsApp
 type WhatsAppMessage struct {
+	ID   string
 	From string
 	Body string
+	// ResponseID is the ID of the selected button or list row, if any
+	ResponseID string
+	// Replayed is set when the message comes from the event journal
+	Replayed bool
+	// IsGroup is set when the message was sent to a group chat
+	IsGroup bool
+	// Chat is the chat the message was sent to: the sender's own chat, or
+	// the group
+	Chat types.JID
+	// AccountID is the business account that received the message, so it
+	// is answered from the same number
+	AccountID string
+	// Stale is set when the message is older than the maximum inbound age,
+	// e.g. from history sync. It is kept in the history but not answered.
+	Stale bool
+	// Superseded is set when a later message of the same sender arrived
+	// during the same offline sync. It is kept in the history and its
+	// opt-out or opt-in keyword applied, but only the later one is answered.
+	Superseded bool
+	// Media is the attachment of the message, if any; Body holds its caption
+	Media *InboundMedia
+	// Event is the original message event, used to quote it in replies. It
+	// is not kept when the message is stored, e.g. deferred in maintenance.
+	Event *events.Message `json:"-"`
 }
 
 // EventHandler is a function that handles WhatsApp events
 type EventHandler func(evt interface{})
 
+// ReconnectAlertFunc is called when the reconnect loop gives up
+type ReconnectAlertFunc func(attempts int, err error)
+
+// reconnectDelay is the wait between reconnect attempts unless a backoff is
+// configured
+const reconnectDelay = 5 * time.Second
+
+// Growth and jitter of the delay set by WithReconnectPolicy
+const (
+	reconnectMultiplier = 2
+	reconnectJitter     = 0.2
+)
+
 // Client is a wrapper around the whatsmeow client
 type Client struct {
 	client        *whatsmeow.Client
@@ -34,12 +75,85 @@ type Client struct {
 	db            *sql.DB
 	deviceStore   *store.Device
 	handlers      []EventHandler
+	handlerNames  map[string]bool
 	handlersMutex sync.RWMutex
 	logger        logger.Logger
 	connected     bool
 	connectedMu   sync.RWMutex
 	qrChan        chan string
 	qrMutex       sync.RWMutex
+	lastActivity  time.Time
+	activityMu    sync.RWMutex
+	journal       *Journal
+	linkPreview   bool
+	// disappearAfter is the default disappearing timer of text messages
+	disappearAfter time.Duration
+	sendRecorder   SendRecorder
+	beforeSend     []BeforeSendHook
+	beforeSendMu   sync.RWMutex
+	offline        offlineBuffer
+	offlineMu      sync.Mutex
+	groups         groupCache
+	maxInboundAge  time.Duration
+	accountID      string
+	queue          *sendQueue
+	waLogLevel     string
+	quotes         quoteCache
+	dryRun         dryRun
+	replays        sync.Map
+	contacts       *contactCache
+
+	sendFailureHooks []SendFailureHook
+	sendFailureMu    sync.RWMutex
+
+	sessionInvalid      bool
+	sessionInvalidHooks []func()
+	sessionMu           sync.RWMutex
+
+	readyCh chan struct{}
+	readyMu sync.Mutex
+
+	// connecting is the connection attempt in progress, shared by concurrent
+	// Connect callers
+	connecting *connectAttempt
+	connectMu  sync.Mutex
+
+	mediaDownload *mediaDownload
+
+	// clientMu guards client and deviceStore, which Logout replaces
+	clientMu sync.RWMutex
+
+	// dial opens the underlying connection; tests replace it to simulate
+	// WhatsApp accepting or refusing connections
+	dial func() error
+	// sendMessage delivers a message; tests replace it to simulate WhatsApp
+	// rejecting sends
+	sendMessage func(ctx context.Context, jid types.JID, message *waE2E.Message, extra ...whatsmeow.SendRequestExtra) (whatsmeow.SendResponse, error)
+	// isOnWhatsApp looks numbers up on WhatsApp; tests replace it to count
+	// lookups and choose their results
+	isOnWhatsApp func(phones []string) ([]types.IsOnWhatsAppResponse, error)
+	// groupInfo fetches the info of a group; tests replace it to choose its
+	// participants
+	groupInfo func(group types.JID) (*types.GroupInfo, error)
+	// download fetches an attachment; tests replace it to serve its content
+	download func(msg whatsmeow.DownloadableMessage) ([]byte, error)
+
+	// humanizedTyping shows the typing indicator before WithTyping sends
+	humanizedTyping bool
+
+	reconnectBackoff  retry.Backoff
+	reconnectAttempts int
+	reconnecting      bool
+	reconnectFailed   bool
+	reconnectMu       sync.Mutex
+	reconnectAlert    ReconnectAlertFunc
+	storeCheck        storeCheck
+	storeCheckResult  StoreCheck
+	recipientRetry    *retry.Backoff
+
+	state      ConnectionState
+	stateHooks []ConnectionStateHook
+	stateMu    sync.Mutex
 }
 
 // ClientOption is a function that configures a Client
@@ -52,8 +166,111 @@ func WithLogger(logger logger.Logger) ClientOption {
 	}
 }
 
+// WithJournal records inbound message events in the given journal
+func WithJournal(journal *Journal) ClientOption {
+	return func(c *Client) {
+		c.journal = journal
+	}
+}
+
+// WithDefaultLinkPreview sets whether text messages get link previews by default
+func WithDefaultLinkPreview(enabled bool) ClientOption {
+	return func(c *Client) {
+		c.linkPreview = enabled
+	}
+}
+
+// WithSendRecorder persists every sent message and its delivery receipts
+func WithSendRecorder(recorder SendRecorder) ClientOption {
+	return func(c *Client) {
+		c.sendRecorder = recorder
+	}
+}
+
+// WithAccountID sets the business account the client belongs to in a
+// ClientManager, carried by the messages it receives
+func WithAccountID(accountID string) ClientOption {
+	return func(c *Client) {
+		c.accountID = accountID
+	}
+}
+
+// WithMaxInboundAge sets the maximum age of an inbound message for it to be
+// processed; older messages (e.g. from history sync) are dispatched marked
+// stale and are not replied to
+func WithMaxInboundAge(age time.Duration) ClientOption {
+	return func(c *Client) {
+		c.maxInboundAge = age
+	}
+}
+
+// WithMaxReconnectAttempts sets how many failed reconnect attempts are made
+// before giving up (0 retries forever)
+func WithMaxReconnectAttempts(attempts int) ClientOption {
+	return func(c *Client) {
+		c.reconnectBackoff.MaxAttempts = attempts
+	}
+}
+
+// WithReconnectBackoff sets the wait between reconnect attempts and how many
+// are made before giving up
+func WithReconnectBackoff(backoff retry.Backoff) ClientOption {
+	return func(c *Client) {
+		c.reconnectBackoff = backoff
+	}
+}
+
+// WithReconnectPolicy makes the wait between reconnect attempts grow
+// exponentially from base up to maxDelay, with jitter so replicas do not
+// reconnect in lockstep, giving up after maxAttempts failures (0 retries
+// forever). WithReconnectBackoff sets the multiplier and jitter as well.
+func WithReconnectPolicy(base, maxDelay time.Duration, maxAttempts int) ClientOption {
+	return func(c *Client) {
+		c.reconnectBackoff = retry.Backoff{
+			BaseDelay:   base,
+			MaxDelay:    maxDelay,
+			MaxAttempts: maxAttempts,
+			Multiplier:  reconnectMultiplier,
+			Jitter:      reconnectJitter,
+		}
+	}
+}
+
+// WithReconnectAlert sets the hook called when the reconnect loop gives up
+func WithReconnectAlert(alert ReconnectAlertFunc) ClientOption {
+	return func(c *Client) {
+		c.reconnectAlert = alert
+	}
+}
+
 // NewClient creates a new WhatsApp client
 func NewClient(dbPath string, options ...ClientOption) (*Client, error) {
+	client := &Client{
+		qrChan:           make(chan string, 1), // Buffered channel to prevent blocking
+		linkPreview:      true,
+		offline:          offlineBuffer{interval: time.Second},
+		reconnectBackoff: retry.Backoff{BaseDelay: reconnectDelay, MaxDelay: reconnectDelay},
+		state:            StateDisconnected,
+	}
+
+	// Apply options
+	for _, option := range options {
+		option(client)
+	}
+
+	// Set default logger if none provided
+	if client.logger == nil {
+		// zapLogger, _ := zap.NewDevelopment()
+		// Use a development logger with default configuration
+		devLogger, _ := logger.New(nil)
+		client.logger = devLogger
+	}
+
+	// Check the store before whatsmeow reads it
+	if err := client.checkStore(dbPath); err != nil {
+		return nil, err
+	}
+
 	// Open the database
 	db, err := sql.Open("sqlite3", dbPath)
 	if err != nil {
@@ -71,49 +288,102 @@ func NewClient(dbPath string, options ...ClientOption) (*Client, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to get device: %w", err)
 	}
+	client.store = container
+	client.db = db
+	client.deviceStore = deviceStore
 
-	// Create the client
-	client := &Client{
-		store:       container,
-		db:          db,
-		deviceStore: deviceStore,
-		qrChan:      make(chan string, 1), // Buffered channel to prevent blocking
+	// Start dispatching queued sends
+	if client.queue != nil {
+		go client.queue.run(context.Background())
 	}
 
-	// Apply options
-	for _, option := range options {
-		option(client)
+	// Create the whatsmeow client
+	client.client = client.newWhatsmeowClient(deviceStore)
+	client.dial = func() error {
+		return client.wa().Connect()
+	}
+	client.sendMessage = func(ctx context.Context, jid types.JID, message *waE2E.Message, extra ...whatsmeow.SendRequestExtra) (whatsmeow.SendResponse, error) {
+		return client.wa().SendMessage(ctx, jid, message, extra...)
+	}
+	client.isOnWhatsApp = func(phones []string) ([]types.IsOnWhatsAppResponse, error) {
+		return client.wa().IsOnWhatsApp(phones)
+	}
+	client.groupInfo = func(group types.JID) (*types.GroupInfo, error) {
+		return client.wa().GetGroupInfo(group)
+	}
+	client.download = func(msg whatsmeow.DownloadableMessage) ([]byte, error) {
+		return client.wa().Download(msg)
 	}
 
-	// Set default logger if none provided
-	if client.logger == nil {
-		// zapLogger, _ := zap.NewDevelopment()
-		// Use a development logger with default configuration
-		devLogger, _ := logger.New(nil)
-		client.logger = devLogger
+	// A dry-run client never connects, so it is ready right away
+	if client.dryRun.enabled {
+		client.setConnected(true)
+		client.setState(StateConnected)
+		client.markReady()
+		client.logger.Warn("Dry run enabled, messages are not sent to WhatsApp")
 	}
 
-	// Create the whatsmeow client
-	client.client = whatsmeow.NewClient(deviceStore, nil)
+	return client, nil
+}
 
-	// Register event handler
-	client.client.AddEventHandler(client.handleEvent)
+// newWhatsmeowClient creates the whatsmeow client of the device, delivering
+// its events to the client
+func (c *Client) newWhatsmeowClient(device *store.Device) *whatsmeow.Client {
+	client := whatsmeow.NewClient(device, newWALogger(c.logger, c.waLogLevel).Sub("Client"))
+	client.AddEventHandler(c.handleEvent)
+	return client
+}
 
-	return client, nil
+// wa returns the current whatsmeow client
+func (c *Client) wa() *whatsmeow.Client {
+	c.clientMu.RLock()
+	defer c.clientMu.RUnlock()
+	return c.client
 }
 
-// Connect connects to WhatsApp
+// Connect connects to WhatsApp. Concurrent calls share a single connection
+// attempt and all return its result.
 func (c *Client) Connect() error {
+	c.connectMu.Lock()
 	if c.IsConnected() {
+		c.connectMu.Unlock()
 		return nil
 	}
+	// Join the attempt already in progress instead of dialing twice
+	if attempt := c.connecting; attempt != nil {
+		c.connectMu.Unlock()
+		<-attempt.done
+		return attempt.err
+	}
+	attempt := &connectAttempt{done: make(chan struct{})}
+	c.connecting = attempt
+	c.connectMu.Unlock()
+
+	attempt.err = c.connect()
 
-	err := c.client.Connect()
+	c.connectMu.Lock()
+	c.connecting = nil
+	c.connectMu.Unlock()
+	close(attempt.done)
+	return attempt.err
+}
+
+// connectAttempt is a connection attempt whose result is shared by every
+// Connect call made while it runs
+type connectAttempt struct {
+	done chan struct{}
+	err  error
+}
+
+// connect opens the underlying connection
+func (c *Client) connect() error {
+	err := c.dial()
 	if err != nil {
 		return fmt.Errorf("failed to connect: %w", err)
 	}
 
 	c.setConnected(true)
+	c.setState(StateConnected)
 	c.logger.Info("Connected to WhatsApp")
 	return nil
 }
@@ -124,46 +394,98 @@ func (c *Client) Disconnect() error {
 		return nil
 	}
 
-	c.client.Disconnect()
+	c.wa().Disconnect()
 	c.setConnected(false)
+	c.setState(StateDisconnected)
+	c.markNotReady()
 	c.logger.Info("Disconnected from WhatsApp")
 	return nil
 }
 
-// Logout logs out from WhatsApp and removes the session
+// Logout logs out from WhatsApp and removes the session. The client is
+// then rebuilt on a new device, so a later Connect shows a QR code for a new
+// session without restarting the service.
 func (c *Client) Logout() error {
+	deviceID, err := c.removeSession()
+	if err != nil || deviceID == "" {
+		return err
+	}
+
+	// Publish the change once connectMu is released, so state hooks may
+	// call back into the client
+	c.markNotReady()
+	c.setState(StateLoggedOut)
+	c.logger.Info("Successfully logged out from WhatsApp", zap.String("device_id", deviceID))
+	return nil
+}
+
+// removeSession logs out and deletes the paired device, then swaps in a new
+// one. It returns the removed device ID, or "" when there was no session.
+func (c *Client) removeSession() (string, error) {
+	// Keep new connection attempts out until the new device is in place
+	c.connectMu.Lock()
+	defer c.connectMu.Unlock()
+
 	// Check if we have a valid device ID
-	if c.deviceStore == nil || c.deviceStore.ID == nil {
+	c.clientMu.RLock()
+	device := c.deviceStore
+	c.clientMu.RUnlock()
+	if device == nil || device.ID == nil {
 		c.logger.Warn("No device session found during logout")
-		return nil
+		return "", nil
 	}
+	deviceID := device.ID.String()
 
 	// First disconnect if connected
 	if c.IsConnected() {
-		// Attempt to logout from WhatsApp
-		err := c.client.Logout()
+		// Attempt to logout from WhatsApp, which also deletes the device
+		err := c.wa().Logout()
 		if err != nil {
 			c.logger.Error("Failed to logout from WhatsApp", zap.Error(err))
-			return fmt.Errorf("failed to logout: %w", err)
+			return "", fmt.Errorf("failed to logout: %w", err)
 		}
 		c.setConnected(false)
 	}
 
 	// Remove the device from the store
-	c.logger.Info("Removing device session", zap.String("device_id", c.deviceStore.ID.String()))
-	err := c.deviceStore.Delete()
-	if err != nil {
+	c.logger.Info("Removing device session", zap.String("device_id", deviceID))
+	if err := device.Delete(); err != nil {
 		c.logger.Error("Failed to delete device", zap.Error(err))
-		return fmt.Errorf("failed to delete device: %w", err)
+		return "", fmt.Errorf("failed to delete device: %w", err)
 	}
 
-	c.logger.Info("Successfully logged out from WhatsApp")
-	return nil
+	c.resetDevice()
+	return deviceID, nil
+}
+
+// resetDevice replaces the whatsmeow client with one on a new device. The
+// old client keeps pointing at the deleted device, so it could not pair
+// again.
+func (c *Client) resetDevice() {
+	device := c.store.NewDevice()
+	client := c.newWhatsmeowClient(device)
+
+	c.clientMu.Lock()
+	old := c.client
+	c.client = client
+	c.deviceStore = device
+	c.clientMu.Unlock()
+
+	// Events of the old client no longer concern this session
+	old.RemoveEventHandlers()
+
+	// The groups and held-back messages belong to the old account; a new
+	// pairing loads its own
+	c.groups.mu.Lock()
+	c.groups.groups = nil
+	c.groups.loaded = false
+	c.groups.mu.Unlock()
+	c.discardOfflineSync()
 }
 
 // IsLoggedIn returns true if the client is logged in
 func (c *Client) IsLoggedIn() bool {
-	return c.client.Store.ID != nil
+	return c.wa().Store.ID != nil && !c.NeedsReauth()
 }
 
 // IsConnected returns true if the client is connected
@@ -180,6 +502,112 @@ func (c *Client) setConnected(connected bool) {
 	c.connected = connected
 }
 
+// LastActivity returns the time of the last message sent or received
+func (c *Client) LastActivity() time.Time {
+	c.activityMu.RLock()
+	defer c.activityMu.RUnlock()
+	return c.lastActivity
+}
+
+// touch records message activity on the session
+func (c *Client) touch() {
+	c.activityMu.Lock()
+	defer c.activityMu.Unlock()
+	c.lastActivity = time.Now()
+}
+
+// reconnectLoop retries the connection until it succeeds or the maximum
+// number of attempts is reached, in which case the alert hook is fired
+func (c *Client) reconnectLoop() {
+	c.reconnectMu.Lock()
+	if c.reconnecting || c.reconnectFailed {
+		c.reconnectMu.Unlock()
+		return
+	}
+	c.reconnecting = true
+	c.reconnectMu.Unlock()
+	c.setState(StateReconnecting)
+
+	defer func() {
+		c.reconnectMu.Lock()
+		c.reconnecting = false
+		c.reconnectMu.Unlock()
+	}()
+
+	for {
+		c.reconnectMu.Lock()
+		attempt := c.reconnectAttempts + 1
+		delay := c.reconnectBackoff.Delay(attempt)
+		c.reconnectMu.Unlock()
+
+		c.logger.Info("Reconnecting to WhatsApp",
+			zap.Int("attempt", attempt),
+			zap.Duration("delay", delay))
+		time.Sleep(delay)
+		if c.IsConnected() {
+			return
+		}
+
+		err := c.Connect()
+		if err == nil {
+			return
+		}
+
+		c.reconnectMu.Lock()
+		c.reconnectAttempts++
+		attempts := c.reconnectAttempts
+		exhausted := c.reconnectBackoff.Exhausted(attempts)
+		if exhausted {
+			c.reconnectFailed = true
+		}
+		c.reconnectMu.Unlock()
+
+		c.logger.Error("Failed to reconnect", zap.Int("attempt", attempts), zap.Error(err))
+		if exhausted {
+			c.logger.Error("Giving up reconnecting to WhatsApp", zap.Int("attempts", attempts))
+			c.setState(StateReconnectFailed)
+			if c.reconnectAlert != nil {
+				c.reconnectAlert(attempts, err)
+			}
+			return
+		}
+	}
+}
+
+// resetReconnectAttempts clears the reconnect counter and failed state
+func (c *Client) resetReconnectAttempts() {
+	c.reconnectMu.Lock()
+	defer c.reconnectMu.Unlock()
+	c.reconnectAttempts = 0
+	c.reconnectFailed = false
+}
+
+// IsReconnectFailed returns true if the reconnect loop gave up
+func (c *Client) IsReconnectFailed() bool {
+	c.reconnectMu.Lock()
+	defer c.reconnectMu.Unlock()
+	return c.reconnectFailed
+}
+
+// IsReconnecting returns true while the reconnect loop is retrying the
+// connection
+func (c *Client) IsReconnecting() bool {
+	c.reconnectMu.Lock()
+	defer c.reconnectMu.Unlock()
+	return c.reconnecting
+}
+
+// Reconnect resets the reconnect counter and connects again. It is used to
+// resume after the reconnect loop gave up.
+func (c *Client) Reconnect() error {
+	c.resetReconnectAttempts()
+	if err := c.Connect(); err != nil {
+		go c.reconnectLoop()
+		return err
+	}
+	return nil
+}
+
 // GetQRChannel returns a channel that receives QR codes for login
 func (c *Client) GetQRChannel(ctx context.Context) <-chan string {
 	return c.qrChan
@@ -190,7 +618,7 @@ func (c *Client) GetPhoneNumber() string {
 	if !c.IsLoggedIn() {
 		return ""
 	}
-	return c.client.Store.ID.User
+	return c.wa().Store.ID.User
 }
 
 // AddEventHandler adds an event handler
@@ -200,24 +628,85 @@ func (c *Client) AddEventHandler(handler EventHandler) {
 	c.handlers = append(c.handlers, handler)
 }
 
+// AddNamedEventHandler adds an event handler unless one was already
+// registered under the same name, so wiring that runs twice does not process
+// every event twice. It returns false when the handler was not added.
+func (c *Client) AddNamedEventHandler(name string, handler EventHandler) bool {
+	c.handlersMutex.Lock()
+	defer c.handlersMutex.Unlock()
+
+	if c.handlerNames[name] {
+		c.logger.Warn("Event handler already registered, ignoring duplicate", zap.String("handler", name))
+		return false
+	}
+	if c.handlerNames == nil {
+		c.handlerNames = make(map[string]bool)
+	}
+	c.handlerNames[name] = true
+	c.handlers = append(c.handlers, handler)
+	return true
+}
+
+// OnBeforeSend registers a hook that runs before every send. Hooks run in
+// registration order.
+func (c *Client) OnBeforeSend(hook BeforeSendHook) {
+	c.beforeSendMu.Lock()
+	defer c.beforeSendMu.Unlock()
+	c.beforeSend = append(c.beforeSend, hook)
+}
+
+// runBeforeSend runs the registered hooks over the outbound message
+func (c *Client) runBeforeSend(outbound *OutboundMessage) error {
+	c.beforeSendMu.RLock()
+	defer c.beforeSendMu.RUnlock()
+
+	for _, hook := range c.beforeSend {
+		if err := hook(outbound); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // handleEvent handles WhatsApp events
 func (c *Client) handleEvent(evt interface{}) {
+	// Record the raw message event for later replay
+	if msg, ok := evt.(*events.Message); ok && c.journal != nil {
+		if err := c.journal.Record(msg); err != nil {
+			c.logger.Warn("Failed to record message in journal", zap.Error(err))
+		}
+	}
+
+	c.dispatchEvent(evt)
+}
+
+// dispatchEvent updates the client state and calls the registered handlers
+func (c *Client) dispatchEvent(evt interface{}) {
 	switch v := evt.(type) {
 	case *events.Connected:
 		c.setConnected(true)
+		c.setState(StateConnected)
+		c.resetReconnectAttempts()
+		c.clearSessionInvalid()
+		c.markReady()
 		c.logger.Info("Connected to WhatsApp")
 
+		// Load the joined groups in the background
+		go func() {
+			if err := c.refreshGroups(context.Background()); err != nil {
+				c.logger.Warn("Failed to load joined groups", zap.Error(err))
+			}
+		}()
+
 	case *events.Disconnected:
 		c.setConnected(false)
+		c.setState(StateDisconnected)
+		c.markNotReady()
+		c.interruptOfflineSync()
 		c.logger.Info("Disconnected from WhatsApp")
 
-		// Try to reconnect after a delay
-		go func() {
-			time.Sleep(5 * time.Second)
-			if err := c.Connect(); err != nil {
-				c.logger.Error("Failed to reconnect", zap.Error(err))
-			}
-		}()
+		// Reconnect with backoff; a loop already running absorbs the event
+		go c.reconnectLoop()
 
 	case *events.QR:
 		c.qrMutex.Lock()
@@ -256,12 +745,34 @@ func (c *Client) handleEvent(evt interface{}) {
 			}
 		}
 		c.qrMutex.Unlock()
+		// An unpaired device is ready once it can show a QR code
+		c.markReady()
+
+	case *events.JoinedGroup:
+		c.handleJoinedGroup(v)
+
+	case *events.GroupInfo:
+		c.handleGroupInfo(v)
+
+	case *events.Receipt:
+		c.handleReceipt(v)
+
+	case *events.PairSuccess:
+		c.clearSessionInvalid()
 
 	case *events.LoggedOut:
 		c.setConnected(false)
+		c.setState(StateLoggedOut)
+		c.markNotReady()
+		c.interruptOfflineSync()
 		c.logger.Info("Logged out from WhatsApp")
+		c.markSessionInvalid(fmt.Errorf("logged out: %s", v.Reason.String()))
 
 	case *events.Message:
+		c.touch()
+		unwrapEphemeral(v)
+		c.rememberInbound(v)
+
 		// Process incoming message
 		c.logger.Info("Received message",
 			zap.String("from", v.Info.Sender.User),
@@ -274,24 +785,69 @@ func (c *Client) handleEvent(evt interface{}) {
 			messageBody = v.Message.GetConversation()
 		} else if v.Message.GetExtendedTextMessage() != nil && v.Message.GetExtendedTextMessage().GetText() != "" {
 			messageBody = v.Message.GetExtendedTextMessage().GetText()
+		} else if _, _, _, caption := attachment(v.Message); caption != "" && c.mediaDownload != nil {
+			messageBody = caption
 		}
 
-		if messageBody != "" {
-			c.logger.Info("Message content", zap.String("body", messageBody))
+		// Button and list responses carry the selected ID and its text
+		var responseID string
+		if response, ok := ParseInteractiveResponse(v.Message); ok {
+			responseID = response.ID
+			messageBody = response.Text
+			if messageBody == "" {
+				messageBody = response.ID
+			}
+		}
+
+		// Stale messages are dispatched marked as such, so they are kept in
+		// the history but not processed for replies, and their media is not
+		// downloaded
+		var media *InboundMedia
+		age := time.Since(v.Info.Timestamp)
+		stale := c.maxInboundAge > 0 && age > c.maxInboundAge
+		if stale {
+			if messageBody != "" {
+				c.logger.Info("Stale inbound message, not processing it",
+					zap.String("message_id", v.Info.ID),
+					zap.Duration("age", age))
+			}
+		} else {
+			media = c.downloadMedia(v)
+		}
+
+		if messageBody != "" || media != nil {
+			c.logger.Debug("Message content", zap.Int("length", len(messageBody)))
 
 			// Create a webhook message
 			webhookMessage := &WhatsAppMessage{
-				From: v.Info.Sender.User,
-				Body: messageBody,
+				ID:         v.Info.ID,
+				From:       v.Info.Sender.User,
+				Body:       messageBody,
+				ResponseID: responseID,
+				IsGroup:    v.Info.IsGroup,
+				Chat:       v.Info.Chat,
+				AccountID:  c.accountID,
+				Stale:      stale,
+				Media:      media,
+				Event:      v,
+			}
+			if _, replayed := c.replays.Load(v.Info.ID); replayed {
+				webhookMessage.Replayed = true
 			}
 
-			// Call all registered handlers with the webhook message
-			c.handlersMutex.RLock()
-			for _, handler := range c.handlers {
-				go handler(webhookMessage)
+			// Hold it back while missed messages are being replayed
+			if !c.bufferOfflineMessage(webhookMessage) {
+				c.dispatchMessage(webhookMessage)
 			}
-			c.handlersMutex.RUnlock()
 		}
+
+	case *events.OfflineSyncPreview:
+		if v.Messages > 0 {
+			c.startOfflineSync(v.Messages)
+		}
+
+	case *events.OfflineSyncCompleted:
+		c.completeOfflineSync()
 	}
 
 	// Call all registered handlers with the original event
@@ -302,23 +858,142 @@ func (c *Client) handleEvent(evt interface{}) {
 	c.handlersMutex.RUnlock()
 }
 
+// dispatchMessage calls all registered handlers with the webhook message
+func (c *Client) dispatchMessage(msg *WhatsAppMessage) {
+	c.handlersMutex.RLock()
+	defer c.handlersMutex.RUnlock()
+	for _, handler := range c.handlers {
+		go handler(msg)
+	}
+}
+
+// Replay feeds a previously recorded event through the handler chain as if
+// it had just been received
+func (c *Client) Replay(evt *events.Message) {
+	c.logger.Info("Replaying message event", zap.String("message_id", evt.Info.ID))
+	c.replays.Store(evt.Info.ID, true)
+	defer c.replays.Delete(evt.Info.ID)
+	c.dispatchEvent(evt)
+}
+
 // Send sends a message to the specified JID
 func (c *Client) Send(ctx context.Context, jid types.JID, message *waE2E.Message, extra ...whatsmeow.SendRequestExtra) (whatsmeow.SendResponse, error) {
+	return c.send(ctx, jid, message, nil, extra...)
+}
+
+// send sends a message and persists the send record with its metadata
+func (c *Client) send(ctx context.Context, jid types.JID, message *waE2E.Message, metadata map[string]string, extra ...whatsmeow.SendRequestExtra) (whatsmeow.SendResponse, error) {
 	if !c.IsConnected() {
-		return whatsmeow.SendResponse{}, fmt.Errorf("client is not connected")
+		return whatsmeow.SendResponse{}, ErrNotConnected
 	}
 
+	// Send to the canonical JID of the number when contacts are cached
+	jid, err := c.resolveRecipient(ctx, jid)
+	if err != nil {
+		return whatsmeow.SendResponse{}, err
+	}
+
+	// Keep the request ID with the send record for end-to-end tracing
+	if id := requestid.FromContext(ctx); id != "" {
+		withID := make(map[string]string, len(metadata)+1)
+		for key, value := range metadata {
+			withID[key] = value
+		}
+		withID["request_id"] = id
+		metadata = withID
+	}
+
+	// Run the before-send hooks, which may block or rewrite the message
+	outbound := &OutboundMessage{To: jid, Message: message, Metadata: metadata, Priority: PriorityFromContext(ctx)}
+	if err := c.runBeforeSend(outbound); err != nil {
+		c.logger.Warn("Send aborted by hook", zap.String("to", jid.String()), zap.Error(err))
+		return whatsmeow.SendResponse{}, fmt.Errorf("send aborted: %w", err)
+	}
+	jid, message, metadata = outbound.To, outbound.Message, outbound.Metadata
+
+	// Retry unreachable recipients, going back through the queue each time
+	extra = c.retryMessageID(extra)
+	return c.retryUnreachable(ctx, jid, func() (whatsmeow.SendResponse, error) {
+		// Wait for a slot on the rate-limited send path when queueing is enabled
+		if c.queue != nil {
+			return c.queue.submit(ctx, PriorityFromContext(ctx), func() (whatsmeow.SendResponse, error) {
+				return c.deliver(ctx, jid, message, metadata, extra...)
+			})
+		}
+		return c.deliver(ctx, jid, message, metadata, extra...)
+	})
+}
+
+// deliver sends a message through whatsmeow and persists its send record
+func (c *Client) deliver(ctx context.Context, jid types.JID, message *waE2E.Message, metadata map[string]string, extra ...whatsmeow.SendRequestExtra) (whatsmeow.SendResponse, error) {
 	// Send the message using the whatsmeow client
-	msgID, err := c.client.SendMessage(ctx, jid, message, extra...)
+	var msgID whatsmeow.SendResponse
+	var err error
+	if c.dryRun.enabled {
+		msgID, err = c.simulateSend(ctx)
+	} else {
+		msgID, err = c.sendMessage(ctx, jid, message, extra...)
+	}
 	if err != nil {
 		c.logger.Error("Failed to send message", zap.Error(err))
+		if isSessionError(err) {
+			c.runSendFailureHooks(jid, err)
+			c.markSessionInvalid(err)
+			return whatsmeow.SendResponse{}, fmt.Errorf("%w: %v", ErrSessionInvalid, err)
+		}
+		if errors.Is(err, whatsmeow.ErrIQRateOverLimit) {
+			c.runSendFailureHooks(jid, err)
+			return whatsmeow.SendResponse{}, fmt.Errorf("%w: %v", ErrRateLimited, err)
+		}
+		// Hooks see whether the failure is specific to the recipient
+		err = classifyRecipientError(err)
+		c.runSendFailureHooks(jid, err)
 		return whatsmeow.SendResponse{}, fmt.Errorf("failed to send message: %w", err)
 	}
 
+	c.touch()
 	c.logger.Info("Message sent successfully", zap.String("message_id", msgID.ID))
+
+	if c.sendRecorder != nil {
+		record := SendRecord{
+			MessageID: msgID.ID,
+			JID:       jid.String(),
+			SentAt:    msgID.Timestamp,
+			Status:    SendStatusSent,
+			UpdatedAt: msgID.Timestamp,
+			Metadata:  metadata,
+			Text:      MessageText(message),
+		}
+		if err := c.sendRecorder.RecordSend(ctx, record); err != nil {
+			c.logger.Warn("Failed to persist send record", zap.String("message_id", msgID.ID), zap.Error(err))
+		}
+	}
+
 	return msgID, nil
 }
 
+// handleReceipt updates the send records referenced by a delivery receipt
+func (c *Client) handleReceipt(receipt *events.Receipt) {
+	if c.sendRecorder == nil {
+		return
+	}
+
+	status, ok := receiptStatus(receipt.Type)
+	if !ok {
+		return
+	}
+
+	ctx := context.Background()
+	for _, messageID := range receipt.MessageIDs {
+		if err := c.sendRecorder.UpdateStatus(ctx, messageID, status, receipt.Timestamp); err != nil {
+			c.logger.Warn("Failed to update send record",
+				zap.String("message_id", messageID),
+				zap.String("status", status),
+				zap.Error(err))
+		}
+	}
+}
+
 // Close closes the client and database connection
 func (c *Client) Close() error {
 	if c.IsConnected() {
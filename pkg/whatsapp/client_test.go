@@ -0,0 +1,246 @@
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"go.mau.fi/whatsmeow/proto/waAdv"
+	"go.mau.fi/whatsmeow/store/sqlstore"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// newPairedClient creates a client, not in dry run, whose device store
+// already holds a paired device
+func newPairedClient(t *testing.T, options ...ClientOption) *Client {
+	t.Helper()
+	dbPath := "file:" + t.TempDir() + "/whatsapp.db?_foreign_keys=on"
+	container, err := sqlstore.New("sqlite3", dbPath, nil)
+	if err != nil {
+		t.Fatalf("sqlstore.New() error = %v", err)
+	}
+	device := container.NewDevice()
+	jid := types.NewADJID(testPhone, 0, 1)
+	device.ID = &jid
+	device.Account = &waAdv.ADVSignedDeviceIdentity{
+		Details:             []byte{1},
+		AccountSignatureKey: make([]byte, 32),
+		AccountSignature:    make([]byte, 64),
+		DeviceSignature:     make([]byte, 64),
+	}
+	if err := device.Save(); err != nil {
+		t.Fatalf("Save() device error = %v", err)
+	}
+	container.Close()
+
+	options = append([]ClientOption{WithLogger(logger.NewNop()), WithOfflineFlushInterval(0)}, options...)
+	client, err := NewClient(dbPath, options...)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	return client
+}
+
+func TestConcurrentConnectDialsOnce(t *testing.T) {
+	client := newTestClient(t, WithOfflineFlushInterval(0))
+	var dials atomic.Int32
+	release := make(chan struct{})
+	client.dial = func() error {
+		dials.Add(1)
+		<-release
+		return nil
+	}
+
+	const callers = 20
+	var started, finished sync.WaitGroup
+	errs := make(chan error, callers)
+	for i := 0; i < callers; i++ {
+		started.Add(1)
+		finished.Add(1)
+		go func() {
+			defer finished.Done()
+			started.Done()
+			errs <- client.Connect()
+		}()
+	}
+	started.Wait()
+	// Give every caller time to join the attempt before it completes
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	finished.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Connect() error = %v", err)
+		}
+	}
+	if got := dials.Load(); got != 1 {
+		t.Errorf("dialed %d times for %d concurrent calls, want 1", got, callers)
+	}
+	if !client.IsConnected() {
+		t.Error("client not connected after Connect()")
+	}
+}
+
+func TestConnectRetriesAfterFailure(t *testing.T) {
+	errRefused := errors.New("connection refused")
+	client := newTestClient(t, WithOfflineFlushInterval(0))
+	var dials atomic.Int32
+	client.dial = func() error {
+		if dials.Add(1) == 1 {
+			return errRefused
+		}
+		return nil
+	}
+
+	// A failed attempt is not cached, so the next call dials again
+	if err := client.Connect(); !errors.Is(err, errRefused) {
+		t.Fatalf("first Connect() error = %v, want %v", err, errRefused)
+	}
+	if err := client.Connect(); err != nil {
+		t.Fatalf("second Connect() error = %v", err)
+	}
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() while connected error = %v", err)
+	}
+	if got := dials.Load(); got != 2 {
+		t.Errorf("dialed %d times, want 2", got)
+	}
+}
+
+func TestLogoutThenNewQR(t *testing.T) {
+	client := newPairedClient(t)
+	if !client.IsLoggedIn() || client.GetPhoneNumber() != testPhone {
+		t.Fatalf("paired client IsLoggedIn() = %t, GetPhoneNumber() = %q", client.IsLoggedIn(), client.GetPhoneNumber())
+	}
+	messages := collectMessages(client)
+	old := client.wa()
+
+	if err := client.Logout(); err != nil {
+		t.Fatalf("Logout() error = %v", err)
+	}
+	if client.IsLoggedIn() || client.GetPhoneNumber() != "" {
+		t.Errorf("after Logout() IsLoggedIn() = %t, GetPhoneNumber() = %q, want logged out", client.IsLoggedIn(), client.GetPhoneNumber())
+	}
+	if got := client.ConnectionState(); got != StateLoggedOut {
+		t.Errorf("ConnectionState() = %q, want %q", got, StateLoggedOut)
+	}
+	current := client.wa()
+	if current == old || current.Store.ID != nil {
+		t.Fatal("Logout() kept the old device, want a new unpaired one")
+	}
+
+	// Connecting again shows a QR code for the new device, without a restart
+	client.dial = func() error {
+		go current.DangerousInternals().DispatchEvent(&events.QR{Codes: []string{"2@new-device"}})
+		return nil
+	}
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() after Logout() error = %v", err)
+	}
+	select {
+	case code := <-client.GetQRChannel(context.Background()):
+		if code != "2@new-device" {
+			t.Errorf("QR code = %q, want the new device's", code)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no QR code after Logout()")
+	}
+
+	// Events of the old client are no longer dispatched, so each message
+	// reaches the handlers once
+	old.DangerousInternals().DispatchEvent(textEvent("msg-1", "56961234568", "Hola"))
+	current.DangerousInternals().DispatchEvent(textEvent("msg-2", "56961234568", "Hola"))
+	if msg := receive(t, messages); msg.ID != "msg-2" {
+		t.Errorf("dispatched %s, want only the new client's msg-2", msg.ID)
+	}
+	expectNone(t, messages)
+
+	// Logging out again without a paired device is a no-op
+	if err := client.Logout(); err != nil || client.wa() != current {
+		t.Errorf("second Logout() error = %v, replaced client = %t", err, client.wa() != current)
+	}
+}
+
+func TestLogoutForgetsAccountState(t *testing.T) {
+	client := newPairedClient(t)
+	messages := collectMessages(client)
+	group := types.NewJID("120363000000000001", types.GroupServer)
+	client.groups.loaded = true
+	client.handleEvent(&events.JoinedGroup{GroupInfo: types.GroupInfo{JID: group, GroupName: types.GroupName{Name: "Ventas"}}})
+	client.handleEvent(&events.OfflineSyncPreview{Messages: 1})
+	client.handleEvent(textEvent("old-1", "56961234568", "Hola"))
+
+	if err := client.Logout(); err != nil {
+		t.Fatalf("Logout() error = %v", err)
+	}
+
+	// The next account loads its own groups instead of listing these
+	client.groups.mu.RLock()
+	loaded, groups := client.groups.loaded, len(client.groups.groups)
+	client.groups.mu.RUnlock()
+	if loaded || groups != 0 {
+		t.Errorf("group cache loaded = %t with %d groups, want it cleared", loaded, groups)
+	}
+
+	// Messages held back for the old account are dropped, and new ones are
+	// no longer buffered
+	client.handleEvent(textEvent("new-1", "56961234568", "Hola"))
+	if msg := receive(t, messages); msg.ID != "new-1" {
+		t.Errorf("dispatched %s, want new-1", msg.ID)
+	}
+	client.handleEvent(&events.OfflineSyncCompleted{})
+	expectNone(t, messages)
+}
+
+func TestLogoutStateHookReconnects(t *testing.T) {
+	client := newPairedClient(t)
+	client.dial = func() error { return nil }
+
+	// A hook reacting to the logout by connecting again must not deadlock
+	reconnected := make(chan error, 1)
+	client.OnConnectionStateChange(func(_, new ConnectionState) {
+		if new == StateLoggedOut {
+			reconnected <- client.Connect()
+		}
+	})
+
+	done := make(chan error, 1)
+	go func() { done <- client.Logout() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Logout() error = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Logout() deadlocked on a hook calling Connect()")
+	}
+	if err := <-reconnected; err != nil {
+		t.Errorf("Connect() from the state hook error = %v", err)
+	}
+}
+
+func TestInboundMessageCarriesChatAndAccount(t *testing.T) {
+	client := newTestClient(t, WithDryRun(0, 0), WithAccountID("globex"))
+	messages := collectMessages(client)
+
+	// A group message keeps the group apart from the sender
+	group := types.NewJID("120363000000000001", types.GroupServer)
+	evt := textEvent("msg-1", testPhone, "Sí")
+	evt.Info.Chat = group
+	evt.Info.IsGroup = true
+	client.handleEvent(evt)
+	msg := receive(t, messages)
+	if msg.From != testPhone || msg.Chat != group || !msg.IsGroup {
+		t.Errorf("dispatched from %s in %s, want %s in the group %s", msg.From, msg.Chat, testPhone, group)
+	}
+	if msg.AccountID != "globex" {
+		t.Errorf("AccountID = %q, want the receiving account globex", msg.AccountID)
+	}
+}
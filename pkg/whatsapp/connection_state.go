@@ -0,0 +1,83 @@
+package whatsapp
+
+import (
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// ConnectionState is the lifecycle state of the WhatsApp connection
+type ConnectionState string
+
+// Connection states
+const (
+	StateDisconnected ConnectionState = "disconnected"
+	StateConnected    ConnectionState = "connected"
+	// StateReconnecting is set while the reconnect loop retries after a drop
+	StateReconnecting ConnectionState = "reconnecting"
+	// StateReconnectFailed is set when the reconnect loop gave up
+	StateReconnectFailed ConnectionState = "reconnect_failed"
+	// StateLoggedOut is set when the session was logged out and the device
+	// must be paired again
+	StateLoggedOut ConnectionState = "logged_out"
+)
+
+// ConnectionStateHook is called on every connection state transition. Hooks
+// run in registration order on the goroutine making the transition and
+// must not block. No client lock is held while they run, so they may call
+// back into the client, e.g. Connect after a logout.
+type ConnectionStateHook func(old, new ConnectionState)
+
+// WithConnectionStateHook registers a connection state hook when the client
+// is created, so it sees every transition
+func WithConnectionStateHook(hook ConnectionStateHook) ClientOption {
+	return func(c *Client) {
+		c.stateHooks = append(c.stateHooks, hook)
+	}
+}
+
+// OnConnectionStateChange registers a hook called on every connection state
+// transition
+func (c *Client) OnConnectionStateChange(hook ConnectionStateHook) {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+	c.stateHooks = append(c.stateHooks, hook)
+}
+
+// ConnectionState returns the current connection state
+func (c *Client) ConnectionState() ConnectionState {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+	return c.state
+}
+
+// setState moves the connection to a new state and runs the hooks when it
+// changed
+func (c *Client) setState(state ConnectionState) {
+	c.stateMu.Lock()
+	old := c.state
+	if old == state {
+		c.stateMu.Unlock()
+		return
+	}
+	c.state = state
+	hooks := c.stateHooks
+	c.stateMu.Unlock()
+
+	for _, hook := range hooks {
+		hook(old, state)
+	}
+}
+
+// LogConnectionState returns a hook that logs every transition, as a
+// warning when the connection needs attention
+func LogConnectionState(log logger.Logger) ConnectionStateHook {
+	return func(old, new ConnectionState) {
+		fields := []zap.Field{zap.String("from", string(old)), zap.String("to", string(new))}
+		switch new {
+		case StateReconnectFailed, StateLoggedOut:
+			log.Warn("WhatsApp connection state changed", fields...)
+		default:
+			log.Info("WhatsApp connection state changed", fields...)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package whatsapp
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// transitionRecorder records the transitions a connection state hook sees
+type transitionRecorder struct {
+	mu          sync.Mutex
+	transitions []string
+}
+
+func (r *transitionRecorder) hook(old, new ConnectionState) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.transitions = append(r.transitions, fmt.Sprintf("%s->%s", old, new))
+}
+
+func (r *transitionRecorder) all() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.transitions...)
+}
+
+// waitState fails the test unless the client reaches the state within a second
+func waitState(t *testing.T, c *Client, want ConnectionState) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for c.ConnectionState() != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("ConnectionState() = %q, want %q", c.ConnectionState(), want)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestConnectionStateHooks(t *testing.T) {
+	construction := &transitionRecorder{}
+	client := newTestClient(t,
+		WithReconnectPolicy(time.Millisecond, time.Millisecond, 2),
+		WithConnectionStateHook(construction.hook))
+	registered := &transitionRecorder{}
+	client.OnConnectionStateChange(registered.hook)
+	if got := client.ConnectionState(); got != StateDisconnected {
+		t.Fatalf("initial ConnectionState() = %q, want %q", got, StateDisconnected)
+	}
+
+	// Connect
+	client.dial = func() error { return nil }
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+
+	// A drop the reconnect loop cannot recover from
+	client.dial = func() error { return errors.New("connection refused") }
+	client.dispatchEvent(&events.Disconnected{})
+	waitState(t, client, StateReconnectFailed)
+
+	// Connected again, repeated, then logged out
+	client.dispatchEvent(&events.Connected{})
+	client.dispatchEvent(&events.Connected{})
+	client.dispatchEvent(&events.LoggedOut{})
+
+	want := []string{
+		"disconnected->connected",
+		"connected->disconnected",
+		"disconnected->reconnecting",
+		"reconnecting->reconnect_failed",
+		"reconnect_failed->connected",
+		"connected->logged_out",
+	}
+	for name, recorder := range map[string]*transitionRecorder{"construction": construction, "registered": registered} {
+		got := recorder.all()
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("%s hook saw %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestDryRunConnectionState(t *testing.T) {
+	recorder := &transitionRecorder{}
+	client := newTestClient(t, WithDryRun(0, 0), WithConnectionStateHook(recorder.hook))
+
+	// Hooks registered at construction see the dry-run client connect
+	if got := client.ConnectionState(); got != StateConnected {
+		t.Errorf("ConnectionState() = %q, want %q", got, StateConnected)
+	}
+	if got := recorder.all(); len(got) != 1 || got[0] != "disconnected->connected" {
+		t.Errorf("hook saw %v, want the dry-run client connecting", got)
+	}
+}
@@ -0,0 +1,155 @@
+package whatsapp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/store"
+	"go.mau.fi/whatsmeow/types"
+	"go.uber.org/zap"
+)
+
+// contactKeyPrefix is the state store key prefix for resolved contacts
+const contactKeyPrefix = "whatsapp:contact:"
+
+// ErrNotOnWhatsApp is returned when sending to a number that is not registered on WhatsApp
+var ErrNotOnWhatsApp = errors.New("number is not registered on WhatsApp")
+
+// Contact is the resolved WhatsApp identity of a phone number
+type Contact struct {
+	Phone      string `json:"phone"`
+	JID        string `json:"jid,omitempty"`
+	OnWhatsApp bool   `json:"on_whatsapp"`
+	// VerifiedName is the verified business name, empty for other accounts
+	VerifiedName string    `json:"verified_name,omitempty"`
+	CheckedAt    time.Time `json:"checked_at"`
+}
+
+// contactCache keeps resolved contacts in the state store
+type contactCache struct {
+	store store.Store
+	ttl   time.Duration
+}
+
+// WithContactCache caches phone number lookups in the given store for ttl.
+// The send path then resolves numbers to their canonical JID and rejects
+// numbers that are not on WhatsApp.
+func WithContactCache(cacheStore store.Store, ttl time.Duration) ClientOption {
+	return func(c *Client) {
+		c.contacts = &contactCache{store: cacheStore, ttl: ttl}
+	}
+}
+
+// LookupContact resolves a phone number (digits only, with country code),
+// answering from the cache when possible
+func (c *Client) LookupContact(ctx context.Context, phone string) (*Contact, error) {
+	if c.contacts != nil {
+		if contact, ok := c.contacts.get(ctx, phone); ok {
+			return contact, nil
+		}
+	}
+
+	if !c.IsConnected() {
+		return nil, ErrNotConnected
+	}
+	contacts, err := c.CheckOnWhatsApp(ctx, []string{phone})
+	if err != nil {
+		return nil, err
+	}
+	return &contacts[0], nil
+}
+
+// CheckOnWhatsApp looks up phone numbers (digits only, with country code) on
+// WhatsApp in one query, bypassing the cache. It returns a contact per
+// number, in order, with its JID and verified business name when
+// registered. The results refresh the contact cache.
+func (c *Client) CheckOnWhatsApp(ctx context.Context, phones []string) ([]Contact, error) {
+	if !c.IsConnected() {
+		return nil, ErrNotConnected
+	}
+
+	queries := make([]string, len(phones))
+	for i, phone := range phones {
+		queries[i] = "+" + phone
+	}
+	results, err := c.isOnWhatsApp(queries)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up contact: %w", err)
+	}
+
+	byPhone := make(map[string]types.IsOnWhatsAppResponse, len(results))
+	for _, result := range results {
+		byPhone[strings.TrimPrefix(result.Query, "+")] = result
+	}
+
+	checkedAt := time.Now()
+	contacts := make([]Contact, len(phones))
+	for i, phone := range phones {
+		contact := Contact{Phone: phone, CheckedAt: checkedAt}
+		if result, ok := byPhone[phone]; ok && result.IsIn {
+			contact.OnWhatsApp = true
+			contact.JID = result.JID.String()
+			if result.VerifiedName != nil {
+				contact.VerifiedName = result.VerifiedName.Details.GetVerifiedName()
+			}
+		}
+		contacts[i] = contact
+
+		if c.contacts != nil {
+			if err := c.contacts.set(ctx, &contact); err != nil {
+				c.logger.Warn("Failed to cache contact", zap.Error(err))
+			}
+		}
+	}
+	return contacts, nil
+}
+
+// resolveRecipient maps a phone JID to its canonical JID using the contact
+// cache. Lookup failures fall back to the given JID.
+func (c *Client) resolveRecipient(ctx context.Context, jid types.JID) (types.JID, error) {
+	if c.contacts == nil || c.dryRun.enabled || jid.Server != types.DefaultUserServer {
+		return jid, nil
+	}
+
+	contact, err := c.LookupContact(ctx, jid.User)
+	if err != nil {
+		c.logger.Debug("Contact lookup failed, sending to the number as given", zap.Error(err))
+		return jid, nil
+	}
+	if !contact.OnWhatsApp {
+		return jid, fmt.Errorf("%w: %s", ErrNotOnWhatsApp, jid.User)
+	}
+
+	resolved, err := types.ParseJID(contact.JID)
+	if err != nil {
+		return jid, nil
+	}
+	return resolved, nil
+}
+
+// get returns a cached contact
+func (cc *contactCache) get(ctx context.Context, phone string) (*Contact, bool) {
+	value, err := cc.store.Get(ctx, contactKeyPrefix+phone)
+	if err != nil {
+		return nil, false
+	}
+
+	var contact Contact
+	if err := json.Unmarshal([]byte(value), &contact); err != nil {
+		return nil, false
+	}
+	return &contact, true
+}
+
+// set caches a contact until its TTL expires
+func (cc *contactCache) set(ctx context.Context, contact *Contact) error {
+	data, err := json.Marshal(contact)
+	if err != nil {
+		return fmt.Errorf("failed to encode contact: %w", err)
+	}
+	return cc.store.Set(ctx, contactKeyPrefix+contact.Phone, string(data), cc.ttl)
+}
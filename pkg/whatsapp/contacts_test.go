@@ -0,0 +1,117 @@
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/store"
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// fakeLookups answers WhatsApp number lookups, counting them. Numbers in
+// the map are registered under the JID they map to.
+func fakeLookups(c *Client, registered map[string]types.JID) *atomic.Int32 {
+	var lookups atomic.Int32
+	c.isOnWhatsApp = func(phones []string) ([]types.IsOnWhatsAppResponse, error) {
+		lookups.Add(1)
+		var results []types.IsOnWhatsAppResponse
+		for _, query := range phones {
+			jid, ok := registered[strings.TrimPrefix(query, "+")]
+			results = append(results, types.IsOnWhatsAppResponse{Query: query, JID: jid, IsIn: ok})
+		}
+		return results, nil
+	}
+	return &lookups
+}
+
+func TestLookupContactCache(t *testing.T) {
+	canonical := types.NewJID("56961234567", types.DefaultUserServer)
+	client := newTestClient(t, WithDryRun(0, 0), WithContactCache(store.NewMemoryStore(), 100*time.Millisecond))
+	lookups := fakeLookups(client, map[string]types.JID{testPhone: canonical})
+	ctx := context.Background()
+
+	// A miss queries WhatsApp and a hit answers from the cache
+	for i := 0; i < 3; i++ {
+		contact, err := client.LookupContact(ctx, testPhone)
+		if err != nil {
+			t.Fatalf("LookupContact() error = %v", err)
+		}
+		if !contact.OnWhatsApp || contact.JID != canonical.String() {
+			t.Errorf("contact = %+v, want %s on WhatsApp", contact, canonical)
+		}
+	}
+	if got := lookups.Load(); got != 1 {
+		t.Errorf("%d WhatsApp lookups for three calls, want 1", got)
+	}
+
+	// Unregistered numbers are cached too
+	for i := 0; i < 2; i++ {
+		contact, err := client.LookupContact(ctx, "56961234568")
+		if err != nil {
+			t.Fatalf("LookupContact() error = %v", err)
+		}
+		if contact.OnWhatsApp {
+			t.Errorf("contact = %+v, want not on WhatsApp", contact)
+		}
+	}
+	if got := lookups.Load(); got != 2 {
+		t.Errorf("%d WhatsApp lookups, want 2", got)
+	}
+
+	// Expired entries are looked up again
+	time.Sleep(150 * time.Millisecond)
+	if _, err := client.LookupContact(ctx, testPhone); err != nil {
+		t.Fatalf("LookupContact() after expiry error = %v", err)
+	}
+	if got := lookups.Load(); got != 3 {
+		t.Errorf("%d WhatsApp lookups after expiry, want 3", got)
+	}
+
+	// A bulk check bypasses the cache and refreshes it
+	if _, err := client.CheckOnWhatsApp(ctx, []string{testPhone}); err != nil {
+		t.Fatalf("CheckOnWhatsApp() error = %v", err)
+	}
+	if got := lookups.Load(); got != 4 {
+		t.Errorf("%d WhatsApp lookups after a bulk check, want 4", got)
+	}
+}
+
+func TestSendResolvesRecipientFromCache(t *testing.T) {
+	canonical := types.NewJID("5696123456789", types.DefaultUserServer)
+	client := newTestClient(t, WithDryRun(0, 0), WithContactCache(store.NewMemoryStore(), time.Hour))
+	lookups := fakeLookups(client, map[string]types.JID{testPhone: canonical})
+	var sentTo []types.JID
+	client.dryRun.enabled = false
+	client.sendMessage = func(_ context.Context, jid types.JID, _ *waE2E.Message, _ ...whatsmeow.SendRequestExtra) (whatsmeow.SendResponse, error) {
+		sentTo = append(sentTo, jid)
+		return whatsmeow.SendResponse{ID: "msg-1", Timestamp: time.Now()}, nil
+	}
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.SendText(ctx, types.NewJID(testPhone, types.DefaultUserServer), "Hola"); err != nil {
+			t.Fatalf("SendText() error = %v", err)
+		}
+	}
+	if len(sentTo) != 2 || sentTo[0] != canonical || sentTo[1] != canonical {
+		t.Errorf("sent to %v, want %s twice", sentTo, canonical)
+	}
+	if got := lookups.Load(); got != 1 {
+		t.Errorf("%d WhatsApp lookups for two sends, want 1", got)
+	}
+
+	// Numbers that are not on WhatsApp are rejected without sending
+	_, err := client.SendText(ctx, types.NewJID("56961234568", types.DefaultUserServer), "Hola")
+	if !errors.Is(err, ErrNotOnWhatsApp) {
+		t.Errorf("SendText() to an unregistered number error = %v, want %v", err, ErrNotOnWhatsApp)
+	}
+	if len(sentTo) != 2 {
+		t.Errorf("sent %d messages, want the unregistered number skipped", len(sentTo))
+	}
+}
@@ -0,0 +1,79 @@
+package whatsapp
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types/events"
+	"google.golang.org/protobuf/proto"
+)
+
+// ErrInvalidDisappearTimer is returned when a disappearing message timer is
+// not one of the durations WhatsApp supports
+var ErrInvalidDisappearTimer = errors.New("disappearing message timer must be 0, 24h, 7d or 90d")
+
+// ValidDisappearTimer reports whether WhatsApp supports the disappearing
+// message timer; zero turns it off
+func ValidDisappearTimer(timer time.Duration) bool {
+	switch timer {
+	case whatsmeow.DisappearingTimerOff,
+		whatsmeow.DisappearingTimer24Hours,
+		whatsmeow.DisappearingTimer7Days,
+		whatsmeow.DisappearingTimer90Days:
+		return true
+	}
+	return false
+}
+
+// WithDefaultDisappearTimer makes text messages disappear after the timer
+// unless a send sets its own. It is ignored when WhatsApp does not support
+// the timer.
+func WithDefaultDisappearTimer(timer time.Duration) ClientOption {
+	return func(c *Client) {
+		if ValidDisappearTimer(timer) {
+			c.disappearAfter = timer
+		}
+	}
+}
+
+// WithDisappearAfter makes the message disappear after the timer, replacing
+// the client default; zero sends it without a timer
+func WithDisappearAfter(timer time.Duration) SendOption {
+	return func(o *sendOptions) {
+		o.disappearAfter = timer
+	}
+}
+
+// withExpiration sets the disappearing timer of a text message, converting a
+// plain Conversation to an ExtendedTextMessage. A quote or mentions already
+// set are kept.
+func withExpiration(message *waE2E.Message, timer time.Duration) (*waE2E.Message, error) {
+	if !ValidDisappearTimer(timer) {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidDisappearTimer, timer)
+	}
+	if message.GetExtendedTextMessage() == nil {
+		message = &waE2E.Message{
+			ExtendedTextMessage: &waE2E.ExtendedTextMessage{
+				Text: proto.String(message.GetConversation()),
+			},
+		}
+	}
+	if message.ExtendedTextMessage.ContextInfo == nil {
+		message.ExtendedTextMessage.ContextInfo = &waE2E.ContextInfo{}
+	}
+	message.ExtendedTextMessage.ContextInfo.Expiration = proto.Uint32(uint32(timer / time.Second))
+	return message, nil
+}
+
+// unwrapEphemeral unwraps a disappearing message that still carries its
+// wrapper, as replayed or injected events may, so its content is processed
+// like any other message
+func unwrapEphemeral(evt *events.Message) {
+	if inner := evt.Message.GetEphemeralMessage().GetMessage(); inner != nil {
+		evt.Message = inner
+		evt.IsEphemeral = true
+	}
+}
@@ -0,0 +1,125 @@
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestValidDisappearTimer(t *testing.T) {
+	tests := []struct {
+		timer time.Duration
+		want  bool
+	}{
+		{timer: 0, want: true},
+		{timer: 24 * time.Hour, want: true},
+		{timer: 7 * 24 * time.Hour, want: true},
+		{timer: 90 * 24 * time.Hour, want: true},
+		{timer: time.Hour, want: false},
+		{timer: 48 * time.Hour, want: false},
+		{timer: -24 * time.Hour, want: false},
+	}
+	for _, tt := range tests {
+		if got := ValidDisappearTimer(tt.timer); got != tt.want {
+			t.Errorf("ValidDisappearTimer(%s) = %t, want %t", tt.timer, got, tt.want)
+		}
+	}
+}
+
+func TestSendTextDisappearTimer(t *testing.T) {
+	day := uint32(24 * time.Hour / time.Second)
+	week := uint32(7 * 24 * time.Hour / time.Second)
+	tests := []struct {
+		name    string
+		client  []ClientOption
+		options []SendOption
+		want    uint32
+	}{
+		{name: "no timer"},
+		{name: "client default", client: []ClientOption{WithDefaultDisappearTimer(24 * time.Hour)}, want: day},
+		{name: "per-send timer", options: []SendOption{WithDisappearAfter(7 * 24 * time.Hour)}, want: week},
+		{name: "per-send timer replaces the default", client: []ClientOption{WithDefaultDisappearTimer(24 * time.Hour)}, options: []SendOption{WithDisappearAfter(7 * 24 * time.Hour)}, want: week},
+		{name: "per-send zero turns the default off", client: []ClientOption{WithDefaultDisappearTimer(24 * time.Hour)}, options: []SendOption{WithDisappearAfter(0)}},
+		{name: "unsupported default is ignored", client: []ClientOption{WithDefaultDisappearTimer(time.Hour)}},
+		{name: "kept with a link preview", options: []SendOption{WithDisappearAfter(24 * time.Hour), WithLinkPreview(true)}, want: day},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t, append([]ClientOption{WithDryRun(0, 0)}, tt.client...)...)
+			sent := captureSends(client)
+
+			_, err := client.SendText(context.Background(), types.NewJID(testPhone, types.DefaultUserServer), "Hola https://example.com", tt.options...)
+			if err != nil {
+				t.Fatalf("SendText() error = %v", err)
+			}
+			message := sent.last(t).Message
+			if got := message.GetExtendedTextMessage().GetContextInfo().GetExpiration(); got != tt.want {
+				t.Errorf("Expiration = %d, want %d", got, tt.want)
+			}
+			if got := MessageText(message); got != "Hola https://example.com" {
+				t.Errorf("text = %q, want it unchanged", got)
+			}
+		})
+	}
+}
+
+func TestSendTextRejectsUnsupportedTimer(t *testing.T) {
+	client := newTestClient(t)
+	sent := captureSends(client)
+
+	_, err := client.SendText(context.Background(), types.NewJID(testPhone, types.DefaultUserServer), "Hola", WithDisappearAfter(time.Hour))
+	if !errors.Is(err, ErrInvalidDisappearTimer) {
+		t.Errorf("SendText() error = %v, want %v", err, ErrInvalidDisappearTimer)
+	}
+	if got := len(sent.all()); got != 0 {
+		t.Errorf("sent %d messages with an unsupported timer, want none", got)
+	}
+}
+
+func TestDisappearTimerReachesWhatsApp(t *testing.T) {
+	client := newTestClient(t, WithDryRun(0, 0), WithDefaultDisappearTimer(whatsmeow.DisappearingTimer90Days))
+	client.dryRun.enabled = false
+	var sent *waE2E.Message
+	client.sendMessage = func(_ context.Context, _ types.JID, message *waE2E.Message, _ ...whatsmeow.SendRequestExtra) (whatsmeow.SendResponse, error) {
+		sent = message
+		return whatsmeow.SendResponse{ID: "msg-1", Timestamp: time.Now()}, nil
+	}
+
+	if _, err := client.SendText(context.Background(), types.NewJID(testPhone, types.DefaultUserServer), "Hola"); err != nil {
+		t.Fatalf("SendText() error = %v", err)
+	}
+	if got, want := sent.GetExtendedTextMessage().GetContextInfo().GetExpiration(), uint32(whatsmeow.DisappearingTimer90Days/time.Second); got != want {
+		t.Errorf("Expiration = %d, want %d", got, want)
+	}
+}
+
+func TestIncomingDisappearingMessage(t *testing.T) {
+	client := newTestClient(t)
+	messages := collectMessages(client)
+
+	// A disappearing message still wrapped is dispatched with its content
+	evt := textEvent("msg-1", testPhone, "")
+	evt.Message = &waE2E.Message{
+		EphemeralMessage: &waE2E.FutureProofMessage{
+			Message: &waE2E.Message{
+				ExtendedTextMessage: &waE2E.ExtendedTextMessage{
+					Text:        proto.String("Sí"),
+					ContextInfo: &waE2E.ContextInfo{Expiration: proto.Uint32(86400)},
+				},
+			},
+		},
+	}
+	client.dispatchEvent(evt)
+	if msg := receive(t, messages); msg.Body != "Sí" || msg.ID != "msg-1" {
+		t.Errorf("dispatched %+v, want the unwrapped text", msg)
+	}
+	if !evt.IsEphemeral {
+		t.Error("IsEphemeral = false, want the event marked as disappearing")
+	}
+}
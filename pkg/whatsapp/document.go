@@ -0,0 +1,68 @@
+package whatsapp
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+)
+
+// ErrMissingFileName is returned when a document is sent without a file name
+var ErrMissingFileName = errors.New("document file name is required")
+
+// SendDocument uploads a file and sends it as a document, e.g. a PDF
+// invoice. An empty mimeType is detected from the content. Metadata and
+// priority options apply as for text messages.
+func (c *Client) SendDocument(ctx context.Context, jid types.JID, data []byte, fileName, mimeType string, options ...SendOption) (whatsmeow.SendResponse, error) {
+	if fileName == "" {
+		return whatsmeow.SendResponse{}, ErrMissingFileName
+	}
+	if !c.IsConnected() {
+		return whatsmeow.SendResponse{}, ErrNotConnected
+	}
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+
+	opts := sendOptions{priority: PriorityFromContext(ctx)}
+	for _, option := range options {
+		option(&opts)
+	}
+
+	uploaded, err := c.upload(ctx, data, whatsmeow.MediaDocument)
+	if err != nil {
+		return whatsmeow.SendResponse{}, fmt.Errorf("failed to upload document: %w", err)
+	}
+
+	message := &waE2E.Message{
+		DocumentMessage: &waE2E.DocumentMessage{
+			URL:           proto.String(uploaded.URL),
+			DirectPath:    proto.String(uploaded.DirectPath),
+			MediaKey:      uploaded.MediaKey,
+			FileEncSHA256: uploaded.FileEncSHA256,
+			FileSHA256:    uploaded.FileSHA256,
+			FileLength:    proto.Uint64(uploaded.FileLength),
+			Mimetype:      proto.String(mimeType),
+			FileName:      proto.String(fileName),
+			Title:         proto.String(fileName),
+		},
+	}
+
+	ctx = ContextWithPriority(ctx, opts.priority)
+	return c.send(ctx, jid, message, opts.metadata)
+}
+
+// upload encrypts and uploads media to WhatsApp. Dry runs only hash it.
+func (c *Client) upload(ctx context.Context, data []byte, mediaType whatsmeow.MediaType) (whatsmeow.UploadResponse, error) {
+	if c.dryRun.enabled {
+		sum := sha256.Sum256(data)
+		return whatsmeow.UploadResponse{FileSHA256: sum[:], FileLength: uint64(len(data))}, nil
+	}
+	return c.wa().Upload(ctx, data, mediaType)
+}
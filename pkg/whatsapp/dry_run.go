@@ -0,0 +1,56 @@
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+)
+
+// ErrDryRunFailure is the failure injected by the dry-run failure rate
+var ErrDryRunFailure = errors.New("simulated send failure")
+
+// dryRun simulates sends instead of delivering them to WhatsApp
+type dryRun struct {
+	enabled     bool
+	latency     time.Duration
+	failureRate float64
+}
+
+// WithDryRun simulates every send without contacting WhatsApp, for load
+// testing. Each send waits latency and fails with ErrDryRunFailure at the
+// given rate (0 to 1). The client reports itself as connected.
+func WithDryRun(latency time.Duration, failureRate float64) ClientOption {
+	return func(c *Client) {
+		c.dryRun = dryRun{
+			enabled:     true,
+			latency:     latency,
+			failureRate: failureRate,
+		}
+	}
+}
+
+// simulateSend waits the configured latency and returns a fake send response
+// or an injected failure
+func (c *Client) simulateSend(ctx context.Context) (whatsmeow.SendResponse, error) {
+	if c.dryRun.latency > 0 {
+		timer := time.NewTimer(c.dryRun.latency)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return whatsmeow.SendResponse{}, ctx.Err()
+		}
+	}
+
+	if c.dryRun.failureRate > 0 && rand.Float64() < c.dryRun.failureRate {
+		return whatsmeow.SendResponse{}, ErrDryRunFailure
+	}
+
+	return whatsmeow.SendResponse{
+		ID:        c.wa().GenerateMessageID(),
+		Timestamp: time.Now(),
+	}, nil
+}
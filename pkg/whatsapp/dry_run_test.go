@@ -0,0 +1,64 @@
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.mau.fi/whatsmeow/types"
+)
+
+func TestDryRunLatency(t *testing.T) {
+	jid := types.NewJID(testPhone, types.DefaultUserServer)
+	client := newTestClient(t, WithDryRun(50*time.Millisecond, 0))
+
+	start := time.Now()
+	if _, err := client.SendText(context.Background(), jid, "Hola"); err != nil {
+		t.Fatalf("SendText() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("send took %v, want at least 50ms", elapsed)
+	}
+
+	// A caller giving up before the latency elapses gets its context error
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := client.SendText(ctx, jid, "Hola"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("SendText() with a short deadline error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestDryRunFailureRate(t *testing.T) {
+	tests := []struct {
+		rate     float64
+		min, max float64
+	}{
+		{rate: 0, min: 0, max: 0},
+		{rate: 0.3, min: 0.25, max: 0.35},
+		{rate: 1, min: 1, max: 1},
+	}
+	for _, tt := range tests {
+		client := newTestClient(t, WithDryRun(0, tt.rate))
+		const sends = 2000
+		failures := 0
+		for i := 0; i < sends; i++ {
+			_, err := client.simulateSend(context.Background())
+			if errors.Is(err, ErrDryRunFailure) {
+				failures++
+			} else if err != nil {
+				t.Fatalf("simulateSend() error = %v", err)
+			}
+		}
+		if got := float64(failures) / sends; got < tt.min || got > tt.max {
+			t.Errorf("rate %v: %.3f of sends failed, want between %v and %v", tt.rate, got, tt.min, tt.max)
+		}
+	}
+
+	// The injected failure reaches callers of the send path
+	client := newTestClient(t, WithDryRun(0, 1))
+	jid := types.NewJID(testPhone, types.DefaultUserServer)
+	if _, err := client.SendText(context.Background(), jid, "Hola"); !errors.Is(err, ErrDryRunFailure) {
+		t.Errorf("SendText() error = %v, want %v", err, ErrDryRunFailure)
+	}
+}
@@ -0,0 +1,9 @@
+package whatsapp
+
+import "errors"
+
+// ErrNotConnected is returned when a request needs a connected client
+var ErrNotConnected = errors.New("client is not connected")
+
+// ErrRateLimited is returned when WhatsApp rejects a send for exceeding its rate limit
+var ErrRateLimited = errors.New("rate limited by WhatsApp")
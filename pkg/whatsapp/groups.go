@@ -0,0 +1,164 @@
+package whatsapp
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	"go.uber.org/zap"
+)
+
+// Group is a group the connected number belongs to
+type Group struct {
+	JID          string `json:"jid"`
+	Name         string `json:"name"`
+	Participants int    `json:"participants"`
+}
+
+// groupCache keeps the joined groups up to date from membership events
+type groupCache struct {
+	groups map[string]*Group
+	loaded bool
+	mu     sync.RWMutex
+}
+
+// refreshGroups reloads the joined groups from WhatsApp
+func (c *Client) refreshGroups(ctx context.Context) error {
+	// GetJoinedGroups takes no context, so honour cancellation before the query
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	infos, err := c.wa().GetJoinedGroups()
+	if err != nil {
+		return fmt.Errorf("failed to get joined groups: %w", err)
+	}
+
+	groups := make(map[string]*Group, len(infos))
+	for _, info := range infos {
+		groups[info.JID.String()] = groupFromInfo(info)
+	}
+
+	c.groups.mu.Lock()
+	c.groups.groups = groups
+	c.groups.loaded = true
+	c.groups.mu.Unlock()
+
+	c.logger.Info("Loaded joined groups", zap.Int("count", len(groups)))
+	return nil
+}
+
+// ListGroups returns the groups the connected number belongs to, loading
+// them from WhatsApp when the cache is still cold
+func (c *Client) ListGroups(ctx context.Context) ([]Group, error) {
+	c.groups.mu.RLock()
+	loaded := c.groups.loaded
+	c.groups.mu.RUnlock()
+
+	if !loaded {
+		if !c.IsConnected() {
+			return nil, ErrNotConnected
+		}
+		if err := c.refreshGroups(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	c.groups.mu.RLock()
+	defer c.groups.mu.RUnlock()
+
+	groups := make([]Group, 0, len(c.groups.groups))
+	for _, group := range c.groups.groups {
+		groups = append(groups, *group)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Name < groups[j].Name
+	})
+	return groups, nil
+}
+
+// ParseGroupJID parses a group JID, accepting the bare group ID without the
+// @g.us server. User and other non-group JIDs return ErrNotGroup.
+func ParseGroupJID(groupJID string) (types.JID, error) {
+	groupJID = strings.TrimSpace(groupJID)
+	if groupJID != "" && !strings.Contains(groupJID, "@") {
+		groupJID += "@" + types.GroupServer
+	}
+	jid, err := types.ParseJID(groupJID)
+	if err != nil || jid.User == "" || jid.Server != types.GroupServer {
+		return types.JID{}, fmt.Errorf("%w: %q", ErrNotGroup, groupJID)
+	}
+	return jid, nil
+}
+
+// SendToGroup sends a message to a group through the regular send path
+func (c *Client) SendToGroup(ctx context.Context, groupJID string, message *waE2E.Message, extra ...whatsmeow.SendRequestExtra) (whatsmeow.SendResponse, error) {
+	group, err := ParseGroupJID(groupJID)
+	if err != nil {
+		return whatsmeow.SendResponse{}, err
+	}
+	return c.send(ctx, group, message, nil, extra...)
+}
+
+// handleJoinedGroup adds a group the connected number was added to
+func (c *Client) handleJoinedGroup(evt *events.JoinedGroup) {
+	c.groups.mu.Lock()
+	defer c.groups.mu.Unlock()
+
+	if c.groups.groups == nil {
+		c.groups.groups = make(map[string]*Group)
+	}
+	c.groups.groups[evt.JID.String()] = groupFromInfo(&evt.GroupInfo)
+	c.logger.Info("Joined group", zap.String("group", evt.JID.String()), zap.String("name", evt.Name))
+}
+
+// handleGroupInfo applies group metadata and membership changes
+func (c *Client) handleGroupInfo(evt *events.GroupInfo) {
+	c.groups.mu.Lock()
+	defer c.groups.mu.Unlock()
+
+	key := evt.JID.String()
+	group, ok := c.groups.groups[key]
+	if !ok {
+		return
+	}
+
+	if evt.Delete != nil {
+		delete(c.groups.groups, key)
+		c.logger.Info("Group deleted", zap.String("group", key))
+		return
+	}
+
+	for _, jid := range evt.Leave {
+		if c.isOwnJID(jid) {
+			delete(c.groups.groups, key)
+			c.logger.Info("Left group", zap.String("group", key))
+			return
+		}
+	}
+
+	if evt.Name != nil {
+		group.Name = evt.Name.Name
+	}
+	group.Participants += len(evt.Join) - len(evt.Leave)
+}
+
+// isOwnJID reports whether the JID belongs to the connected number
+func (c *Client) isOwnJID(jid types.JID) bool {
+	id := c.wa().Store.ID
+	return id != nil && id.User == jid.User
+}
+
+// groupFromInfo builds the cached group from whatsmeow group info
+func groupFromInfo(info *types.GroupInfo) *Group {
+	return &Group{
+		JID:          info.JID.String(),
+		Name:         info.Name,
+		Participants: len(info.Participants),
+	}
+}
@@ -0,0 +1,158 @@
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestGroupCacheFollowsMembershipEvents(t *testing.T) {
+	client := newTestClient(t)
+	own := types.NewADJID(testPhone, 0, 1)
+	client.wa().Store.ID = &own
+	// Start from a loaded cache so ListGroups does not query WhatsApp
+	client.groups.loaded = true
+
+	sales := types.NewJID("120363000000000001", types.GroupServer)
+	support := types.NewJID("120363000000000002", types.GroupServer)
+	member := types.NewJID("56961234568", types.DefaultUserServer)
+	joined := func(jid types.JID, name string, participants int) *events.JoinedGroup {
+		info := types.GroupInfo{JID: jid, GroupName: types.GroupName{Name: name}}
+		info.Participants = make([]types.GroupParticipant, participants)
+		return &events.JoinedGroup{GroupInfo: info}
+	}
+
+	client.handleEvent(joined(sales, "Ventas", 3))
+	client.handleEvent(joined(support, "Soporte", 2))
+	assertGroups(t, client, []Group{
+		{JID: support.String(), Name: "Soporte", Participants: 2},
+		{JID: sales.String(), Name: "Ventas", Participants: 3},
+	})
+
+	// Renames and membership changes of other participants update the group
+	client.handleEvent(&events.GroupInfo{
+		JID:  sales,
+		Name: &types.GroupName{Name: "Ventas Chile"},
+		Join: []types.JID{member},
+	})
+	assertGroups(t, client, []Group{
+		{JID: support.String(), Name: "Soporte", Participants: 2},
+		{JID: sales.String(), Name: "Ventas Chile", Participants: 4},
+	})
+
+	// Leaving a group removes it, whichever device left
+	client.handleEvent(&events.GroupInfo{JID: support, Leave: []types.JID{types.NewJID(testPhone, types.DefaultUserServer)}})
+	assertGroups(t, client, []Group{
+		{JID: sales.String(), Name: "Ventas Chile", Participants: 4},
+	})
+
+	client.handleEvent(&events.GroupInfo{JID: sales, Delete: &types.GroupDelete{Deleted: true}})
+	assertGroups(t, client, []Group{})
+
+	// Events for groups we are not in are ignored
+	client.handleEvent(&events.GroupInfo{JID: support, Join: []types.JID{member}})
+	assertGroups(t, client, []Group{})
+}
+
+// assertGroups checks the cached groups, sorted by name
+func assertGroups(t *testing.T, client *Client, want []Group) {
+	t.Helper()
+	got, err := client.ListGroups(context.Background())
+	if err != nil {
+		t.Fatalf("ListGroups() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ListGroups() = %+v, want %+v", got, want)
+	}
+}
+
+func TestListGroupsNotConnected(t *testing.T) {
+	// Any option leaves out the dry run, so the client stays disconnected
+	client := newTestClient(t, WithOfflineFlushInterval(0))
+	if _, err := client.ListGroups(context.Background()); !errors.Is(err, ErrNotConnected) {
+		t.Errorf("ListGroups() error = %v, want %v", err, ErrNotConnected)
+	}
+}
+
+func TestListGroupsCancelledOnColdCache(t *testing.T) {
+	client := newTestClient(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := client.ListGroups(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("ListGroups() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestParseGroupJID(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    string
+		wantErr bool
+	}{
+		{name: "bare ID", raw: "120363000000000001", want: "120363000000000001@g.us"},
+		{name: "group JID", raw: "120363000000000001@g.us", want: "120363000000000001@g.us"},
+		{name: "padded group JID", raw: " 120363000000000001@g.us ", want: "120363000000000001@g.us"},
+		{name: "user JID", raw: "123@s.whatsapp.net", wantErr: true},
+		{name: "empty", raw: "", wantErr: true},
+		{name: "whitespace", raw: "   ", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseGroupJID(tt.raw)
+			if tt.wantErr {
+				if !errors.Is(err, ErrNotGroup) {
+					t.Fatalf("ParseGroupJID(%q) = %v, %v, want %v", tt.raw, got, err, ErrNotGroup)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseGroupJID(%q) error = %v", tt.raw, err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("ParseGroupJID(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSendToGroup(t *testing.T) {
+	tests := []struct {
+		name     string
+		groupJID string
+		wantErr  error
+	}{
+		{name: "group", groupJID: "120363000000000001@g.us"},
+		{name: "user JID", groupJID: testPhone + "@s.whatsapp.net", wantErr: ErrNotGroup},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t)
+			sent := captureSends(client)
+
+			message := &waE2E.Message{Conversation: proto.String("Hola")}
+			_, err := client.SendToGroup(context.Background(), tt.groupJID, message)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SendToGroup(%q) error = %v, want %v", tt.groupJID, err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if all := sent.all(); len(all) != 0 {
+					t.Errorf("sent %d messages, want none", len(all))
+				}
+				return
+			}
+			if to := sent.last(t).To.String(); to != tt.groupJID {
+				t.Errorf("sent to %q, want %q", to, tt.groupJID)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package whatsapp
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	"google.golang.org/protobuf/proto"
+)
+
+// testPhone is a valid Chilean mobile number in E.164 without the plus sign
+const testPhone = "56961234567"
+
+// newTestClient creates a client on a temporary device store. Without
+// options it is a dry-run client, so sends succeed without contacting
+// WhatsApp.
+func newTestClient(t *testing.T, options ...ClientOption) *Client {
+	t.Helper()
+	if len(options) == 0 {
+		options = []ClientOption{WithDryRun(0, 0)}
+	}
+	options = append([]ClientOption{WithLogger(logger.NewNop())}, options...)
+	client, err := NewClient("file:"+t.TempDir()+"/whatsapp.db?_foreign_keys=on", options...)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	return client
+}
+
+// textEvent builds an inbound text message event from the phone number
+func textEvent(id, from, text string) *events.Message {
+	jid := types.NewJID(from, types.DefaultUserServer)
+	return &events.Message{
+		Info: types.MessageInfo{
+			MessageSource: types.MessageSource{Chat: jid, Sender: jid},
+			ID:            id,
+			PushName:      "Ana",
+			Timestamp:     time.Now(),
+		},
+		Message: &waE2E.Message{Conversation: proto.String(text)},
+	}
+}
+
+// collectMessages returns a channel receiving the inbound messages the
+// client dispatches to its handlers
+func collectMessages(c *Client) <-chan *WhatsAppMessage {
+	messages := make(chan *WhatsAppMessage, 16)
+	c.AddEventHandler(func(evt interface{}) {
+		if msg, ok := evt.(*WhatsAppMessage); ok {
+			messages <- msg
+		}
+	})
+	return messages
+}
+
+// receive waits for the next dispatched message
+func receive(t *testing.T, messages <-chan *WhatsAppMessage) *WhatsAppMessage {
+	t.Helper()
+	select {
+	case msg := <-messages:
+		return msg
+	case <-time.After(time.Second):
+		t.Fatal("no message dispatched")
+		return nil
+	}
+}
+
+// expectNone fails the test if a message is dispatched shortly
+func expectNone(t *testing.T, messages <-chan *WhatsAppMessage) {
+	t.Helper()
+	select {
+	case msg := <-messages:
+		t.Fatalf("unexpected message dispatched: %+v", msg)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// sentMessages records the messages sent through a test client
+type sentMessages struct {
+	mu       sync.Mutex
+	messages []*OutboundMessage
+}
+
+// captureSends records every message sent through the client
+func captureSends(c *Client) *sentMessages {
+	sent := &sentMessages{}
+	c.OnBeforeSend(func(msg *OutboundMessage) error {
+		sent.mu.Lock()
+		defer sent.mu.Unlock()
+		sent.messages = append(sent.messages, msg)
+		return nil
+	})
+	return sent
+}
+
+// all returns the messages sent so far
+func (s *sentMessages) all() []*OutboundMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*OutboundMessage(nil), s.messages...)
+}
+
+// last returns the last message sent
+func (s *sentMessages) last(t *testing.T) *OutboundMessage {
+	t.Helper()
+	all := s.all()
+	if len(all) == 0 {
+		t.Fatal("no message sent")
+	}
+	return all[len(all)-1]
+}
+
+// failSends makes every send of a connected client fail with err, as if
+// WhatsApp rejected it
+func failSends(c *Client, err error) {
+	c.dryRun.enabled = false
+	c.sendMessage = func(context.Context, types.JID, *waE2E.Message, ...whatsmeow.SendRequestExtra) (whatsmeow.SendResponse, error) {
+		return whatsmeow.SendResponse{}, err
+	}
+}
@@ -0,0 +1,59 @@
+package whatsapp
+
+import (
+	"testing"
+	"time"
+
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+func TestMaxInboundAge(t *testing.T) {
+	client := newTestClient(t, WithDryRun(0, 0), WithMaxInboundAge(10*time.Minute))
+	messages := collectMessages(client)
+	raw := make(chan string, 4)
+	client.AddEventHandler(func(evt interface{}) {
+		if msg, ok := evt.(*events.Message); ok {
+			raw <- msg.Info.ID
+		}
+	})
+
+	fresh := textEvent("fresh", testPhone, "Sí")
+	fresh.Info.Timestamp = time.Now().Add(-time.Minute)
+	client.handleEvent(fresh)
+	if msg := receive(t, messages); msg.ID != "fresh" || msg.Stale {
+		t.Errorf("dispatched %s with stale %t, want fresh", msg.ID, msg.Stale)
+	}
+
+	// An old "No" from history sync is dispatched for the history, marked so
+	// it does not cancel a booking
+	stale := textEvent("stale", testPhone, "No")
+	stale.Info.Timestamp = time.Now().Add(-2 * 24 * time.Hour)
+	client.handleEvent(stale)
+	if msg := receive(t, messages); msg.ID != "stale" || !msg.Stale || msg.Body != "No" {
+		t.Errorf("dispatched %s with stale %t and body %q, want stale No", msg.ID, msg.Stale, msg.Body)
+	}
+
+	// Both also reach the raw event handlers
+	for _, want := range []string{"fresh", "stale"} {
+		select {
+		case got := <-raw:
+			if got != want {
+				t.Errorf("raw event %s, want %s", got, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("raw event %s not dispatched", want)
+		}
+	}
+}
+
+func TestMaxInboundAgeDisabled(t *testing.T) {
+	client := newTestClient(t, WithDryRun(0, 0), WithMaxInboundAge(0))
+	messages := collectMessages(client)
+
+	stale := textEvent("stale", testPhone, "No")
+	stale.Info.Timestamp = time.Now().Add(-2 * 24 * time.Hour)
+	client.handleEvent(stale)
+	if msg := receive(t, messages); msg.ID != "stale" || msg.Stale {
+		t.Errorf("dispatched %s with stale %t, want it processed", msg.ID, msg.Stale)
+	}
+}
@@ -0,0 +1,136 @@
+package whatsapp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// JournalEntry is an inbound message event recorded in the journal
+type JournalEntry struct {
+	RecordedAt time.Time         `json:"recorded_at"`
+	Info       types.MessageInfo `json:"info"`
+	Message    json.RawMessage   `json:"message"`
+}
+
+// Event rebuilds the whatsmeow message event from the entry
+func (e JournalEntry) Event() (*events.Message, error) {
+	message := &waE2E.Message{}
+	if err := protojson.Unmarshal(e.Message, message); err != nil {
+		return nil, fmt.Errorf("failed to decode journal message: %w", err)
+	}
+
+	return &events.Message{
+		Info:       e.Info,
+		Message:    message,
+		RawMessage: message,
+	}, nil
+}
+
+// Journal records inbound message events as JSON lines so they can be
+// replayed through the handler chain later
+type Journal struct {
+	path string
+	file *os.File
+	mu   sync.Mutex
+}
+
+// OpenJournal opens (or creates) the journal file at path for appending
+func OpenJournal(path string) (*Journal, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal: %w", err)
+	}
+
+	return &Journal{path: path, file: file}, nil
+}
+
+// Record appends a redacted copy of the message event to the journal
+func (j *Journal) Record(evt *events.Message) error {
+	message, err := protojson.Marshal(evt.Message)
+	if err != nil {
+		return fmt.Errorf("failed to encode journal message: %w", err)
+	}
+
+	entry := JournalEntry{
+		RecordedAt: time.Now(),
+		Info:       redactInfo(evt.Info),
+		Message:    message,
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode journal entry: %w", err)
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if _, err := j.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write journal entry: %w", err)
+	}
+	return nil
+}
+
+// Entries reads all entries recorded in the journal
+func (j *Journal) Entries() ([]JournalEntry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	file, err := os.Open(j.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal: %w", err)
+	}
+	defer file.Close()
+
+	var entries []JournalEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var entry JournalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("failed to decode journal entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read journal: %w", err)
+	}
+
+	return entries, nil
+}
+
+// Close closes the journal file
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.file.Close()
+}
+
+// redactInfo removes personal data from the message info. Phone numbers keep
+// only their last four digits so entries can still be told apart.
+func redactInfo(info types.MessageInfo) types.MessageInfo {
+	info.PushName = ""
+	info.VerifiedName = nil
+	info.Sender.User = redactUser(info.Sender.User)
+	if !info.IsGroup {
+		info.Chat.User = redactUser(info.Chat.User)
+	}
+	return info
+}
+
+// redactUser masks all but the last four characters of a JID user
+func redactUser(user string) string {
+	if len(user) <= 4 {
+		return user
+	}
+	return strings.Repeat("x", len(user)-4) + user[len(user)-4:]
+}
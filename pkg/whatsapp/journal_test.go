@@ -0,0 +1,54 @@
+package whatsapp
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestJournalRecordsAndReplaysMessages(t *testing.T) {
+	journal, err := OpenJournal(filepath.Join(t.TempDir(), "events.jsonl"))
+	if err != nil {
+		t.Fatalf("OpenJournal() error = %v", err)
+	}
+	defer journal.Close()
+	client := newTestClient(t, WithDryRun(0, 0), WithJournal(journal))
+	messages := collectMessages(client)
+
+	// A received message is recorded, redacted, and processed as usual
+	client.handleEvent(textEvent("msg-1", testPhone, "Sí, confirmo"))
+	if msg := receive(t, messages); msg.Replayed {
+		t.Error("live message marked as replayed")
+	}
+
+	entries, err := journal.Entries()
+	if err != nil {
+		t.Fatalf("Entries() error = %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("Entries() = %d entries, want 1", len(entries))
+	}
+	entry := entries[0]
+	if entry.Info.ID != "msg-1" {
+		t.Errorf("entry ID = %q, want msg-1", entry.Info.ID)
+	}
+	if entry.Info.Sender.User != "xxxxxxx4567" || entry.Info.Chat.User != "xxxxxxx4567" || entry.Info.PushName != "" {
+		t.Errorf("entry not redacted: sender %q, chat %q, push name %q",
+			entry.Info.Sender.User, entry.Info.Chat.User, entry.Info.PushName)
+	}
+
+	// The entry rebuilds the event, which goes through the same handlers
+	evt, err := entry.Event()
+	if err != nil {
+		t.Fatalf("Event() error = %v", err)
+	}
+	client.Replay(evt)
+	msg := receive(t, messages)
+	if !msg.Replayed || msg.ID != "msg-1" || msg.Body != "Sí, confirmo" {
+		t.Errorf("replayed message = %+v, want msg-1 marked as replayed", msg)
+	}
+
+	// Replays are not recorded again
+	if entries, _ := journal.Entries(); len(entries) != 1 {
+		t.Errorf("journal has %d entries after the replay, want 1", len(entries))
+	}
+}
@@ -0,0 +1,92 @@
+package whatsapp
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ErrUnknownAccount is returned when no client is registered for an account
+var ErrUnknownAccount = errors.New("unknown WhatsApp account")
+
+// ErrAccountNotConnected is returned when the account's client is not connected
+var ErrAccountNotConnected = errors.New("WhatsApp account is not connected")
+
+// ClientManager routes sends to the client of each business account, so a
+// tenant's messages always go out from its own number
+type ClientManager struct {
+	clients   map[string]*Client
+	regions   map[string]string
+	defaultID string
+	mu        sync.RWMutex
+}
+
+// NewClientManager creates a ClientManager. Requests without an account are
+// routed to defaultID.
+func NewClientManager(defaultID string) *ClientManager {
+	return &ClientManager{
+		clients:   make(map[string]*Client),
+		regions:   make(map[string]string),
+		defaultID: defaultID,
+	}
+}
+
+// Register sets the client of an account
+func (m *ClientManager) Register(accountID string, client *Client) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.clients[accountID] = client
+}
+
+// SetPhoneRegion sets the region that completes the local numbers an
+// account sends to, for tenants in another country than the default one
+func (m *ClientManager) SetPhoneRegion(accountID, region string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.regions[accountID] = region
+}
+
+// PhoneRegion returns the phone region of an account, or "" when it uses the
+// service default; an empty ID selects the default account
+func (m *ClientManager) PhoneRegion(accountID string) string {
+	if accountID == "" {
+		accountID = m.defaultID
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.regions[accountID]
+}
+
+// Get returns the connected client of an account; an empty ID selects the
+// default account
+func (m *ClientManager) Get(accountID string) (*Client, error) {
+	if accountID == "" {
+		accountID = m.defaultID
+	}
+
+	m.mu.RLock()
+	client, ok := m.clients[accountID]
+	m.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownAccount, accountID)
+	}
+	if !client.IsConnected() {
+		return nil, fmt.Errorf("%w: %s", ErrAccountNotConnected, accountID)
+	}
+	return client, nil
+}
+
+// Accounts returns the registered account IDs
+func (m *ClientManager) Accounts() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	accounts := make([]string, 0, len(m.clients))
+	for accountID := range m.clients {
+		accounts = append(accounts, accountID)
+	}
+	sort.Strings(accounts)
+	return accounts
+}
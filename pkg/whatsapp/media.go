@@ -0,0 +1,201 @@
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types/events"
+	"go.uber.org/zap"
+)
+
+// Inbound media types
+const (
+	MediaImage    = "image"
+	MediaVideo    = "video"
+	MediaAudio    = "audio"
+	MediaDocument = "document"
+	MediaSticker  = "sticker"
+)
+
+// ErrNoMedia is returned when a message has no downloadable media
+var ErrNoMedia = errors.New("message has no downloadable media")
+
+// Reasons an inbound attachment is not downloaded
+const (
+	MediaSkipTypeNotAllowed = "type_not_allowed"
+	MediaSkipTooLarge       = "too_large"
+	MediaSkipFailed         = "download_failed"
+)
+
+// InboundMedia is the attachment of an inbound message. Skipped attachments
+// are reported with the reason and never written to disk.
+type InboundMedia struct {
+	Type       string `json:"type"`
+	MimeType   string `json:"mime_type,omitempty"`
+	FileName   string `json:"file_name,omitempty"`
+	Size       int64  `json:"size"`
+	Path       string `json:"path,omitempty"`
+	Skipped    bool   `json:"skipped,omitempty"`
+	SkipReason string `json:"skip_reason,omitempty"`
+}
+
+// mediaDownload configures the download of inbound attachments
+type mediaDownload struct {
+	dir     string
+	allowed map[string]bool
+	maxSize int64
+}
+
+// WithMediaDownload downloads the inbound attachments of the allowed types
+// (MediaImage, MediaVideo...) up to maxSize bytes into dir. Other attachments
+// are skipped and reported on the message, and the caption of any attachment
+// is processed as the message body. Without it, attachments are ignored.
+func WithMediaDownload(dir string, allowed []string, maxSize int64) ClientOption {
+	return func(c *Client) {
+		download := &mediaDownload{dir: dir, allowed: make(map[string]bool), maxSize: maxSize}
+		for _, mediaType := range allowed {
+			download.allowed[mediaType] = true
+		}
+		c.mediaDownload = download
+	}
+}
+
+// attachment returns the downloadable part of a message with its media
+// type, file name and caption, or nil when the message has no attachment
+func attachment(msg *waE2E.Message) (whatsmeow.DownloadableMessage, string, string, string) {
+	switch {
+	case msg.GetImageMessage() != nil:
+		return msg.GetImageMessage(), MediaImage, "", msg.GetImageMessage().GetCaption()
+	case msg.GetVideoMessage() != nil:
+		return msg.GetVideoMessage(), MediaVideo, "", msg.GetVideoMessage().GetCaption()
+	case msg.GetAudioMessage() != nil:
+		return msg.GetAudioMessage(), MediaAudio, "", ""
+	case msg.GetDocumentMessage() != nil:
+		document := msg.GetDocumentMessage()
+		return document, MediaDocument, document.GetFileName(), document.GetCaption()
+	case msg.GetStickerMessage() != nil:
+		return msg.GetStickerMessage(), MediaSticker, "", ""
+	}
+	return nil, "", "", ""
+}
+
+// DownloadMedia downloads and decrypts the image, video, audio, document or
+// sticker of an inbound message and returns it with its MIME type, as
+// declared by the sender or detected from the content. Unlike
+// WithMediaDownload it ignores the download policy and writes nothing to
+// disk. Messages without media return ErrNoMedia.
+func (c *Client) DownloadMedia(ctx context.Context, msg *events.Message) ([]byte, string, error) {
+	message := msg.Message
+	if inner := message.GetEphemeralMessage().GetMessage(); inner != nil {
+		message = inner
+	}
+	downloadable, _, _, _ := attachment(message)
+	if downloadable == nil {
+		return nil, "", ErrNoMedia
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, "", err
+	}
+
+	data, err := c.download(downloadable)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to download media: %w", err)
+	}
+
+	mimeType := ""
+	if typed, ok := downloadable.(interface{ GetMimetype() string }); ok {
+		mimeType = typed.GetMimetype()
+	}
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+	return data, mimeType, nil
+}
+
+// skipReason returns why an attachment must not be downloaded, or an empty
+// string when it is allowed
+func (d *mediaDownload) skipReason(mediaType string, size int64) string {
+	if !d.allowed[mediaType] {
+		return MediaSkipTypeNotAllowed
+	}
+	if d.maxSize > 0 && size > d.maxSize {
+		return MediaSkipTooLarge
+	}
+	return ""
+}
+
+// downloadMedia downloads the attachment of an inbound message when the
+// policy allows it. It returns nil for messages without an attachment or
+// when media download is disabled.
+func (c *Client) downloadMedia(evt *events.Message) *InboundMedia {
+	if c.mediaDownload == nil {
+		return nil
+	}
+	downloadable, mediaType, fileName, _ := attachment(evt.Message)
+	if downloadable == nil {
+		return nil
+	}
+
+	media := &InboundMedia{Type: mediaType, FileName: fileName}
+	if sized, ok := downloadable.(interface{ GetFileLength() uint64 }); ok {
+		media.Size = int64(sized.GetFileLength())
+	}
+	if typed, ok := downloadable.(interface{ GetMimetype() string }); ok {
+		media.MimeType = typed.GetMimetype()
+	}
+	log := c.logger.With(
+		zap.String("message_id", evt.Info.ID),
+		zap.String("media_type", mediaType),
+		zap.Int64("size", media.Size))
+
+	if reason := c.mediaDownload.skipReason(mediaType, media.Size); reason != "" {
+		log.Info("Skipping inbound media", zap.String("reason", reason))
+		media.Skipped = true
+		media.SkipReason = reason
+		return media
+	}
+
+	path, err := c.saveMedia(evt.Info.ID, media.MimeType, downloadable)
+	if err != nil {
+		log.Error("Failed to download inbound media", zap.Error(err))
+		media.Skipped = true
+		media.SkipReason = MediaSkipFailed
+		return media
+	}
+	media.Path = path
+	log.Info("Inbound media downloaded", zap.String("path", path))
+	return media
+}
+
+// saveMedia downloads an attachment into the media directory, named after
+// the message ID
+func (c *Client) saveMedia(messageID, mimeType string, downloadable whatsmeow.DownloadableMessage) (string, error) {
+	data, err := c.download(downloadable)
+	if err != nil {
+		return "", err
+	}
+	// Enforce the limit on the actual content, not only the declared length
+	if c.mediaDownload.maxSize > 0 && int64(len(data)) > c.mediaDownload.maxSize {
+		return "", fmt.Errorf("media is %d bytes, above the %d byte limit", len(data), c.mediaDownload.maxSize)
+	}
+
+	extension := ".bin"
+	if extensions, _ := mime.ExtensionsByType(mimeType); len(extensions) > 0 {
+		extension = extensions[0]
+	}
+	if err := os.MkdirAll(c.mediaDownload.dir, 0o750); err != nil {
+		return "", fmt.Errorf("failed to create media directory: %w", err)
+	}
+	path := filepath.Join(c.mediaDownload.dir, filepath.Base(messageID)+extension)
+	if err := os.WriteFile(path, data, 0o640); err != nil {
+		return "", fmt.Errorf("failed to write media: %w", err)
+	}
+	return path, nil
+}
@@ -0,0 +1,133 @@
+package whatsapp
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types/events"
+	"google.golang.org/protobuf/proto"
+)
+
+// mediaEvent builds an inbound message event carrying the attachment
+func mediaEvent(id string, message *waE2E.Message) *events.Message {
+	evt := textEvent(id, testPhone, "")
+	evt.Message = message
+	return evt
+}
+
+// serveDownloads makes the client's downloads return the content, counting them
+func serveDownloads(c *Client, content []byte, err error) *atomic.Int32 {
+	var downloads atomic.Int32
+	c.download = func(whatsmeow.DownloadableMessage) ([]byte, error) {
+		downloads.Add(1)
+		return content, err
+	}
+	return &downloads
+}
+
+func TestInboundMediaDownload(t *testing.T) {
+	dir := t.TempDir()
+	client := newTestClient(t, WithDryRun(0, 0), WithMediaDownload(dir, []string{MediaImage, MediaDocument}, 1024))
+	content := []byte("jpeg content")
+	downloads := serveDownloads(client, content, nil)
+	messages := collectMessages(client)
+
+	client.handleEvent(mediaEvent("image-1", &waE2E.Message{ImageMessage: &waE2E.ImageMessage{
+		Caption:    proto.String("Mi comprobante"),
+		Mimetype:   proto.String("image/jpeg"),
+		FileLength: proto.Uint64(uint64(len(content))),
+	}}))
+
+	msg := receive(t, messages)
+	if msg.Body != "Mi comprobante" {
+		t.Errorf("body = %q, want the caption", msg.Body)
+	}
+	if msg.Media == nil || msg.Media.Skipped || msg.Media.Type != MediaImage || msg.Media.MimeType != "image/jpeg" {
+		t.Fatalf("media = %+v, want a downloaded image", msg.Media)
+	}
+	if filepath.Dir(msg.Media.Path) != dir {
+		t.Errorf("media saved to %s, want in %s", msg.Media.Path, dir)
+	}
+	if data, err := os.ReadFile(msg.Media.Path); err != nil || string(data) != string(content) {
+		t.Errorf("saved media = %q, %v, want the downloaded content", data, err)
+	}
+	if got := downloads.Load(); got != 1 {
+		t.Errorf("%d downloads, want 1", got)
+	}
+}
+
+func TestInboundMediaSkipped(t *testing.T) {
+	tests := []struct {
+		name    string
+		message *waE2E.Message
+		content []byte
+		err     error
+		reason  string
+		fetched bool
+	}{
+		{
+			name:    "type not allowed",
+			message: &waE2E.Message{VideoMessage: &waE2E.VideoMessage{Mimetype: proto.String("video/mp4"), FileLength: proto.Uint64(100)}},
+			reason:  MediaSkipTypeNotAllowed,
+		},
+		{
+			name:    "declared size above the limit",
+			message: &waE2E.Message{ImageMessage: &waE2E.ImageMessage{Mimetype: proto.String("image/jpeg"), FileLength: proto.Uint64(4096)}},
+			reason:  MediaSkipTooLarge,
+		},
+		{
+			name:    "content above the limit",
+			message: &waE2E.Message{ImageMessage: &waE2E.ImageMessage{Mimetype: proto.String("image/jpeg"), FileLength: proto.Uint64(10)}},
+			content: make([]byte, 4096),
+			reason:  MediaSkipFailed,
+			fetched: true,
+		},
+		{
+			name:    "download failure",
+			message: &waE2E.Message{ImageMessage: &waE2E.ImageMessage{Mimetype: proto.String("image/jpeg"), FileLength: proto.Uint64(10)}},
+			err:     errors.New("media expired"),
+			reason:  MediaSkipFailed,
+			fetched: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "media")
+			client := newTestClient(t, WithDryRun(0, 0), WithMediaDownload(dir, []string{MediaImage}, 1024))
+			downloads := serveDownloads(client, tt.content, tt.err)
+			messages := collectMessages(client)
+
+			client.handleEvent(mediaEvent("media-1", tt.message))
+
+			// The message is still surfaced, marked as skipped
+			msg := receive(t, messages)
+			if msg.Media == nil || !msg.Media.Skipped || msg.Media.SkipReason != tt.reason || msg.Media.Path != "" {
+				t.Errorf("media = %+v, want skipped for %s", msg.Media, tt.reason)
+			}
+			if fetched := downloads.Load() > 0; fetched != tt.fetched {
+				t.Errorf("downloaded = %t, want %t", fetched, tt.fetched)
+			}
+			if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+				t.Errorf("wrote %d files, want none", len(entries))
+			}
+		})
+	}
+}
+
+func TestInboundMediaDisabled(t *testing.T) {
+	client := newTestClient(t, WithDryRun(0, 0))
+	downloads := serveDownloads(client, []byte("jpeg content"), nil)
+	messages := collectMessages(client)
+
+	// Without media download attachments are ignored, captions included
+	client.handleEvent(mediaEvent("image-1", &waE2E.Message{ImageMessage: &waE2E.ImageMessage{Caption: proto.String("Sí")}}))
+	expectNone(t, messages)
+	if got := downloads.Load(); got != 0 {
+		t.Errorf("%d downloads, want none", got)
+	}
+}
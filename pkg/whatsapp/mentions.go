@@ -0,0 +1,119 @@
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+)
+
+// ErrNotGroup is returned when a group send targets a JID that is not a group
+var ErrNotGroup = errors.New("JID is not a group")
+
+// ErrNotGroupParticipant is returned when mentioning a number that is not a
+// participant of the group
+var ErrNotGroupParticipant = errors.New("number is not a group participant")
+
+// WithMentions mentions the given users in the message. The text should
+// contain an @number token for each of them; see MentionText.
+func WithMentions(jids ...types.JID) SendOption {
+	return func(o *sendOptions) {
+		o.mentions = append(o.mentions, jids...)
+	}
+}
+
+// MentionText appends an @number token to the text for every mentioned
+// number the text does not contain yet
+func MentionText(text string, phones []string) string {
+	var tokens []string
+	for _, phone := range phones {
+		token := "@" + phone
+		if !strings.Contains(text, token) {
+			tokens = append(tokens, token)
+		}
+	}
+	if len(tokens) == 0 {
+		return text
+	}
+	if text == "" {
+		return strings.Join(tokens, " ")
+	}
+	return text + " " + strings.Join(tokens, " ")
+}
+
+// SendGroupText sends a text to a group, mentioning the given numbers (digits
+// only, with country code). Every mentioned number must be a participant.
+func (c *Client) SendGroupText(ctx context.Context, group types.JID, text string, mentions []string, options ...SendOption) (whatsmeow.SendResponse, error) {
+	if group.Server != types.GroupServer {
+		return whatsmeow.SendResponse{}, fmt.Errorf("%w: %s", ErrNotGroup, group)
+	}
+	if !c.IsConnected() {
+		return whatsmeow.SendResponse{}, ErrNotConnected
+	}
+
+	if len(mentions) > 0 {
+		if err := c.checkParticipants(group, mentions); err != nil {
+			return whatsmeow.SendResponse{}, err
+		}
+
+		jids := make([]types.JID, len(mentions))
+		for i, phone := range mentions {
+			jids[i] = types.NewJID(phone, types.DefaultUserServer)
+		}
+		text = MentionText(text, mentions)
+		options = append(options, WithMentions(jids...))
+	}
+
+	return c.SendText(ctx, group, text, options...)
+}
+
+// checkParticipants verifies that every number is a participant of the
+// group. Dry runs have no group info and skip the check.
+func (c *Client) checkParticipants(group types.JID, phones []string) error {
+	if c.dryRun.enabled {
+		return nil
+	}
+
+	info, err := c.groupInfo(group)
+	if err != nil {
+		return fmt.Errorf("failed to get group info: %w", err)
+	}
+
+	participants := make(map[string]bool, len(info.Participants))
+	for _, participant := range info.Participants {
+		participants[participant.JID.User] = true
+	}
+	for _, phone := range phones {
+		if !participants[phone] {
+			return fmt.Errorf("%w: %s", ErrNotGroupParticipant, phone)
+		}
+	}
+	return nil
+}
+
+// withMentions sets the mentioned users of a text message, converting a
+// plain Conversation to an ExtendedTextMessage. A quote already set is kept.
+func withMentions(message *waE2E.Message, jids []types.JID) *waE2E.Message {
+	if message.GetExtendedTextMessage() == nil {
+		message = &waE2E.Message{
+			ExtendedTextMessage: &waE2E.ExtendedTextMessage{
+				Text: proto.String(message.GetConversation()),
+			},
+		}
+	}
+	if message.ExtendedTextMessage.ContextInfo == nil {
+		message.ExtendedTextMessage.ContextInfo = &waE2E.ContextInfo{}
+	}
+
+	mentioned := make([]string, len(jids))
+	for i, jid := range jids {
+		mentioned[i] = jid.String()
+	}
+	message.ExtendedTextMessage.ContextInfo.MentionedJID = mentioned
+	return message
+}
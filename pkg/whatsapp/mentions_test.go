@@ -0,0 +1,102 @@
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// newGroupClient creates a connected client sending for real to a group
+// with the given participants, returning the messages it sends
+func newGroupClient(t *testing.T, participants ...string) (*Client, *[]*waE2E.Message) {
+	t.Helper()
+	client := newTestClient(t, WithDryRun(0, 0))
+	client.dryRun.enabled = false
+	client.groupInfo = func(group types.JID) (*types.GroupInfo, error) {
+		info := &types.GroupInfo{JID: group}
+		for _, phone := range participants {
+			info.Participants = append(info.Participants, types.GroupParticipant{JID: types.NewJID(phone, types.DefaultUserServer)})
+		}
+		return info, nil
+	}
+	var sent []*waE2E.Message
+	client.sendMessage = func(_ context.Context, _ types.JID, message *waE2E.Message, _ ...whatsmeow.SendRequestExtra) (whatsmeow.SendResponse, error) {
+		sent = append(sent, message)
+		return whatsmeow.SendResponse{ID: "msg-1", Timestamp: time.Now()}, nil
+	}
+	return client, &sent
+}
+
+func TestMentionText(t *testing.T) {
+	tests := []struct {
+		text   string
+		phones []string
+		want   string
+	}{
+		{text: "Hola", phones: nil, want: "Hola"},
+		{text: "Hola", phones: []string{"56961234567"}, want: "Hola @56961234567"},
+		{text: "Hola @56961234567", phones: []string{"56961234567", "56961234568"}, want: "Hola @56961234567 @56961234568"},
+		{text: "", phones: []string{"56961234567"}, want: "@56961234567"},
+	}
+	for _, tt := range tests {
+		if got := MentionText(tt.text, tt.phones); got != tt.want {
+			t.Errorf("MentionText(%q, %v) = %q, want %q", tt.text, tt.phones, got, tt.want)
+		}
+	}
+}
+
+func TestSendGroupTextMentions(t *testing.T) {
+	group := types.NewJID("120363000000000001", types.GroupServer)
+	client, sent := newGroupClient(t, testPhone, "56961234568")
+
+	if _, err := client.SendGroupText(context.Background(), group, "Hola @56961234568, recuerden la reunión", []string{testPhone, "56961234568"}); err != nil {
+		t.Fatalf("SendGroupText() error = %v", err)
+	}
+	if len(*sent) != 1 {
+		t.Fatalf("sent %d messages, want 1", len(*sent))
+	}
+	extended := (*sent)[0].GetExtendedTextMessage()
+	if got, want := extended.GetText(), "Hola @56961234568, recuerden la reunión @"+testPhone; got != want {
+		t.Errorf("text = %q, want %q", got, want)
+	}
+	want := []string{testPhone + "@s.whatsapp.net", "56961234568@s.whatsapp.net"}
+	if got := extended.GetContextInfo().GetMentionedJID(); !reflect.DeepEqual(got, want) {
+		t.Errorf("MentionedJID = %v, want %v", got, want)
+	}
+
+	// Without mentions the text is sent as is
+	if _, err := client.SendGroupText(context.Background(), group, "Hola", nil); err != nil {
+		t.Fatalf("SendGroupText() without mentions error = %v", err)
+	}
+	if got := MessageText((*sent)[1]); got != "Hola" || len((*sent)[1].GetExtendedTextMessage().GetContextInfo().GetMentionedJID()) != 0 {
+		t.Errorf("message without mentions = %v", (*sent)[1])
+	}
+}
+
+func TestSendGroupTextRejectsMentions(t *testing.T) {
+	group := types.NewJID("120363000000000001", types.GroupServer)
+	client, sent := newGroupClient(t, testPhone)
+	ctx := context.Background()
+
+	if _, err := client.SendGroupText(ctx, group, "Hola", []string{testPhone, "56961234569"}); !errors.Is(err, ErrNotGroupParticipant) {
+		t.Errorf("SendGroupText() mentioning a non-participant error = %v, want %v", err, ErrNotGroupParticipant)
+	}
+	if _, err := client.SendGroupText(ctx, types.NewJID(testPhone, types.DefaultUserServer), "Hola", nil); !errors.Is(err, ErrNotGroup) {
+		t.Errorf("SendGroupText() to a user error = %v, want %v", err, ErrNotGroup)
+	}
+	client.groupInfo = func(types.JID) (*types.GroupInfo, error) {
+		return nil, errors.New("not a member")
+	}
+	if _, err := client.SendGroupText(ctx, group, "Hola", []string{testPhone}); err == nil {
+		t.Error("SendGroupText() without group info succeeded")
+	}
+	if len(*sent) != 0 {
+		t.Errorf("sent %d messages, want the rejected ones skipped", len(*sent))
+	}
+}
@@ -0,0 +1,144 @@
+package whatsapp
+
+import (
+	"context"
+	"regexp"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+)
+
+// urlPattern matches the first http(s) URL in a text
+var urlPattern = regexp.MustCompile(`https?://[^\s]+`)
+
+// OutboundMessage is a message about to be sent. Hooks may mutate it.
+type OutboundMessage struct {
+	To       types.JID
+	Message  *waE2E.Message
+	Metadata map[string]string
+	// Priority is the send priority of the message; hooks must not change it
+	Priority Priority
+}
+
+// BeforeSendHook runs synchronously before a message is sent; returning an
+// error aborts the send
+type BeforeSendHook func(*OutboundMessage) error
+
+// SendOption configures an outgoing text message
+type SendOption func(*sendOptions)
+
+// sendOptions holds the settings applied when building a text message
+type sendOptions struct {
+	linkPreview bool
+	metadata    map[string]string
+	priority    Priority
+	quoteID     string
+	quote       *waE2E.ContextInfo
+	mentions    []types.JID
+	// disappearAfter is the disappearing timer; zero sends without one
+	disappearAfter time.Duration
+	// typing shows the typing indicator first with humanized typing
+	typing bool
+}
+
+// WithLinkPreview sets whether a link preview is generated for URLs in the text
+func WithLinkPreview(enabled bool) SendOption {
+	return func(o *sendOptions) {
+		o.linkPreview = enabled
+	}
+}
+
+// WithMetadata attaches context (e.g. a booking ID) to the persisted send record
+func WithMetadata(key, value string) SendOption {
+	return func(o *sendOptions) {
+		if o.metadata == nil {
+			o.metadata = make(map[string]string)
+		}
+		o.metadata[key] = value
+	}
+}
+
+// WithPriority sets the send queue priority of the message
+func WithPriority(priority Priority) SendOption {
+	return func(o *sendOptions) {
+		o.priority = priority
+	}
+}
+
+// BuildTextMessage builds the message for a text. With link previews enabled
+// and a URL present, an ExtendedTextMessage carrying the URL is built so the
+// recipient renders a preview; otherwise a plain Conversation is used.
+func BuildTextMessage(text string, linkPreview bool) *waE2E.Message {
+	if linkPreview {
+		if url := urlPattern.FindString(text); url != "" {
+			return &waE2E.Message{
+				ExtendedTextMessage: &waE2E.ExtendedTextMessage{
+					Text:        proto.String(text),
+					MatchedText: proto.String(url),
+					Title:       proto.String(url),
+				},
+			}
+		}
+	}
+
+	return &waE2E.Message{
+		Conversation: proto.String(text),
+	}
+}
+
+// MessageText returns the text of a text message, or an empty string
+func MessageText(message *waE2E.Message) string {
+	if text := message.GetConversation(); text != "" {
+		return text
+	}
+	return message.GetExtendedTextMessage().GetText()
+}
+
+// SendText sends a text message to the specified JID
+func (c *Client) SendText(ctx context.Context, jid types.JID, text string, options ...SendOption) (whatsmeow.SendResponse, error) {
+	opts := sendOptions{
+		linkPreview:    c.linkPreview,
+		priority:       PriorityFromContext(ctx),
+		disappearAfter: c.disappearAfter,
+	}
+	for _, option := range options {
+		option(&opts)
+	}
+
+	message := BuildTextMessage(text, opts.linkPreview)
+	if opts.quote != nil {
+		message = withQuote(message, opts.quote)
+	} else if opts.quoteID != "" {
+		if contextInfo, ok := c.quoteContext(opts.quoteID); ok {
+			message = withQuote(message, contextInfo)
+		} else {
+			c.logger.Debug("Quoted message not cached, sending without quote", zap.String("quote_id", opts.quoteID))
+		}
+	}
+	if len(opts.mentions) > 0 {
+		message = withMentions(message, opts.mentions)
+	}
+	if opts.disappearAfter != 0 {
+		var err error
+		if message, err = withExpiration(message, opts.disappearAfter); err != nil {
+			return whatsmeow.SendResponse{}, err
+		}
+	}
+
+	if opts.typing && c.humanizedTyping {
+		c.typeBefore(ctx, jid, text)
+	}
+
+	ctx = ContextWithPriority(ctx, opts.priority)
+	return c.send(ctx, jid, message, opts.metadata)
+}
+
+// EditText replaces the text of a message sent earlier to the JID
+func (c *Client) EditText(ctx context.Context, jid types.JID, messageID, text string) (whatsmeow.SendResponse, error) {
+	edit := c.wa().BuildEdit(jid, messageID, BuildTextMessage(text, c.linkPreview))
+	return c.send(ctx, jid, edit, nil)
+}
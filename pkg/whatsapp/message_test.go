@@ -0,0 +1,74 @@
+package whatsapp
+
+import (
+	"context"
+	"testing"
+
+	"go.mau.fi/whatsmeow/types"
+)
+
+func TestBuildTextMessage(t *testing.T) {
+	const url = "https://maps.example.com/centro"
+	tests := []struct {
+		name        string
+		text        string
+		linkPreview bool
+		wantPreview bool
+	}{
+		{"preview on with URL", "Ubicación: " + url, true, true},
+		{"preview off with URL", "Ubicación: " + url, false, false},
+		{"preview on without URL", "Hola", true, false},
+		{"preview off without URL", "Hola", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message := BuildTextMessage(tt.text, tt.linkPreview)
+			if !tt.wantPreview {
+				if message.GetConversation() != tt.text || message.GetExtendedTextMessage() != nil {
+					t.Errorf("message = %v, want a plain conversation", message)
+				}
+				return
+			}
+			extended := message.GetExtendedTextMessage()
+			if extended == nil || message.Conversation != nil {
+				t.Fatalf("message = %v, want an extended text message", message)
+			}
+			if extended.GetText() != tt.text || extended.GetMatchedText() != url {
+				t.Errorf("extended text message = %v, want the text with %s as its preview URL", extended, url)
+			}
+		})
+	}
+}
+
+func TestSendTextLinkPreview(t *testing.T) {
+	const text = "Ubicación: https://maps.example.com/centro"
+	jid := types.NewJID(testPhone, types.DefaultUserServer)
+	tests := []struct {
+		name           string
+		defaultPreview bool
+		options        []SendOption
+		wantPreview    bool
+	}{
+		{"default on", true, nil, true},
+		{"default off", false, nil, false},
+		{"option disables the default", true, []SendOption{WithLinkPreview(false)}, false},
+		{"option enables over the default", false, []SendOption{WithLinkPreview(true)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t, WithDryRun(0, 0), WithDefaultLinkPreview(tt.defaultPreview))
+			sent := captureSends(client)
+
+			if _, err := client.SendText(context.Background(), jid, text, tt.options...); err != nil {
+				t.Fatalf("SendText() error = %v", err)
+			}
+			message := sent.last(t).Message
+			if got := message.GetExtendedTextMessage() != nil; got != tt.wantPreview {
+				t.Errorf("extended text message = %v, want %v", got, tt.wantPreview)
+			}
+			if MessageText(message) != text {
+				t.Errorf("MessageText() = %q, want %q", MessageText(message), text)
+			}
+		})
+	}
+}
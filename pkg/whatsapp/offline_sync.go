@@ -0,0 +1,125 @@
+package whatsapp
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// offlineBuffer holds the messages received while the server replays the
+// events missed during downtime
+type offlineBuffer struct {
+	syncing  bool
+	messages []*WhatsAppMessage
+	seenIDs  map[string]bool
+	interval time.Duration
+}
+
+// WithOfflineFlushInterval sets the delay between buffered messages
+// answered after an offline sync
+func WithOfflineFlushInterval(interval time.Duration) ClientOption {
+	return func(c *Client) {
+		c.offline.interval = interval
+	}
+}
+
+// startOfflineSync starts buffering inbound messages
+func (c *Client) startOfflineSync(pending int) {
+	c.offlineMu.Lock()
+	defer c.offlineMu.Unlock()
+
+	c.offline.syncing = true
+	if c.offline.seenIDs == nil {
+		c.offline.seenIDs = make(map[string]bool)
+	}
+	c.logger.Info("Offline sync started, buffering inbound messages", zap.Int("pending_messages", pending))
+}
+
+// bufferOfflineMessage holds the message back while an offline sync is
+// running. It returns false when no sync is running.
+func (c *Client) bufferOfflineMessage(msg *WhatsAppMessage) bool {
+	c.offlineMu.Lock()
+	defer c.offlineMu.Unlock()
+
+	if !c.offline.syncing {
+		return false
+	}
+
+	if msg.ID != "" {
+		if c.offline.seenIDs[msg.ID] {
+			return true
+		}
+		c.offline.seenIDs[msg.ID] = true
+	}
+
+	c.offline.messages = append(c.offline.messages, msg)
+	return true
+}
+
+// completeOfflineSync stops buffering and dispatches the buffered messages
+// in order. Only the latest message of each conversation is answered, one
+// every flush interval; the earlier ones are marked Superseded.
+func (c *Client) completeOfflineSync() {
+	pending, interval := c.takeOfflineMessages()
+	c.logger.Info("Offline sync completed", zap.Int("messages", len(pending)))
+	c.flushOfflineMessages(pending, interval)
+}
+
+// interruptOfflineSync stops an offline sync cut off by a disconnect, so
+// later messages are not held back until the next OfflineSyncCompleted.
+// The messages buffered so far are dispatched as if the sync had completed.
+func (c *Client) interruptOfflineSync() {
+	pending, interval := c.takeOfflineMessages()
+	if len(pending) > 0 {
+		c.logger.Info("Offline sync interrupted", zap.Int("messages", len(pending)))
+	}
+	c.flushOfflineMessages(pending, interval)
+}
+
+// discardOfflineSync stops buffering and drops the buffered messages, e.g.
+// when the device they were received on is replaced
+func (c *Client) discardOfflineSync() {
+	c.takeOfflineMessages()
+}
+
+// takeOfflineMessages stops buffering and returns the buffered messages
+func (c *Client) takeOfflineMessages() ([]*WhatsAppMessage, time.Duration) {
+	c.offlineMu.Lock()
+	defer c.offlineMu.Unlock()
+
+	pending := c.offline.messages
+	c.offline.syncing = false
+	c.offline.messages = nil
+	c.offline.seenIDs = nil
+	return pending, c.offline.interval
+}
+
+// flushOfflineMessages marks every message followed by a later one of the
+// same conversation as Superseded and dispatches them all, pacing the
+// answered ones by the interval
+func (c *Client) flushOfflineMessages(pending []*WhatsAppMessage, interval time.Duration) {
+	if len(pending) == 0 {
+		return
+	}
+
+	latest := make(map[string]*WhatsAppMessage, len(pending))
+	for _, msg := range pending {
+		latest[msg.From] = msg
+	}
+	for _, msg := range pending {
+		msg.Superseded = latest[msg.From] != msg
+	}
+
+	go func() {
+		answered := 0
+		for _, msg := range pending {
+			if !msg.Superseded {
+				if answered > 0 && interval > 0 {
+					time.Sleep(interval)
+				}
+				answered++
+			}
+			c.dispatchMessage(msg)
+		}
+	}()
+}
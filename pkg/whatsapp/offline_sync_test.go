@@ -0,0 +1,89 @@
+package whatsapp
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+func TestOfflineSyncAnswersLatestPerConversation(t *testing.T) {
+	const interval = 30 * time.Millisecond
+	client := newTestClient(t, WithDryRun(0, 0), WithOfflineFlushInterval(interval))
+	messages := collectMessages(client)
+	other := "56961234568"
+
+	client.handleEvent(&events.OfflineSyncPreview{Messages: 4})
+	client.handleEvent(textEvent("a1", testPhone, "STOP"))
+	client.handleEvent(textEvent("b1", other, "1"))
+	client.handleEvent(textEvent("a2", testPhone, "2"))
+	// The server may deliver the same message twice during the sync
+	client.handleEvent(textEvent("a1", testPhone, "STOP"))
+	expectNone(t, messages)
+
+	// Every message is dispatched, the earlier ones of a conversation
+	// marked as superseded; the answered ones are paced by the interval
+	client.handleEvent(&events.OfflineSyncCompleted{Count: 4})
+	superseded := make(map[string]bool)
+	var answeredAt []time.Time
+	for range 3 {
+		msg := receive(t, messages)
+		superseded[msg.ID] = msg.Superseded
+		if !msg.Superseded {
+			answeredAt = append(answeredAt, time.Now())
+		}
+	}
+	want := map[string]bool{"a1": true, "b1": false, "a2": false}
+	if !reflect.DeepEqual(superseded, want) {
+		t.Errorf("superseded = %v, want %v", superseded, want)
+	}
+	if len(answeredAt) == 2 {
+		if elapsed := answeredAt[1].Sub(answeredAt[0]); elapsed < interval/2 {
+			t.Errorf("second answered message flushed after %v, want about %v", elapsed, interval)
+		}
+	}
+	expectNone(t, messages)
+
+	// Messages after the sync are dispatched right away
+	client.handleEvent(textEvent("a3", testPhone, "3"))
+	if msg := receive(t, messages); msg.ID != "a3" || msg.Superseded {
+		t.Errorf("dispatched %+v, want a3 to be answered", msg)
+	}
+}
+
+func TestOfflineSyncInterruptedByDisconnect(t *testing.T) {
+	client := newTestClient(t, WithDryRun(0, 0), WithOfflineFlushInterval(0))
+	messages := collectMessages(client)
+	// The disconnect starts a reconnect loop, which has nothing to dial
+	client.dial = func() error { return nil }
+
+	client.handleEvent(&events.OfflineSyncPreview{Messages: 2})
+	client.handleEvent(textEvent("a1", testPhone, "hola"))
+	expectNone(t, messages)
+
+	// The messages buffered so far are flushed and buffering stops
+	client.handleEvent(&events.Disconnected{})
+	if msg := receive(t, messages); msg.ID != "a1" || msg.Superseded {
+		t.Errorf("flushed %+v, want a1 to be answered", msg)
+	}
+	client.handleEvent(textEvent("a2", testPhone, "2"))
+	if msg := receive(t, messages); msg.ID != "a2" {
+		t.Errorf("dispatched %s, want a2", msg.ID)
+	}
+}
+
+func TestOfflineSyncWithoutPendingMessages(t *testing.T) {
+	client := newTestClient(t)
+	messages := collectMessages(client)
+
+	// An empty preview does not start buffering
+	client.handleEvent(&events.OfflineSyncPreview{Messages: 0})
+	client.handleEvent(textEvent("a1", testPhone, "hola"))
+	if msg := receive(t, messages); msg.ID != "a1" {
+		t.Errorf("dispatched %s, want a1", msg.ID)
+	}
+
+	client.handleEvent(&events.OfflineSyncCompleted{})
+	expectNone(t, messages)
+}
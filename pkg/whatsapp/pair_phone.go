@@ -0,0 +1,57 @@
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.mau.fi/whatsmeow"
+	"go.uber.org/zap"
+)
+
+// ErrAlreadyLoggedIn is returned when pairing a device that is already
+// logged in
+var ErrAlreadyLoggedIn = errors.New("already logged in")
+
+// ErrInvalidPairPhone is returned when the phone number to pair is not an
+// international number
+var ErrInvalidPairPhone = errors.New("pairing requires an international phone number")
+
+// pairClientDisplayName is how the linked device appears on the phone; the
+// server only accepts common "Browser (OS)" names
+const pairClientDisplayName = "Chrome (Linux)"
+
+// PairPhone connects and requests a linking code for the phone number, to be
+// entered on the phone under Linked devices instead of scanning the QR. The
+// code is valid while the login connection serves QR codes, about 160
+// seconds.
+func (c *Client) PairPhone(ctx context.Context, phoneNumber string) (string, error) {
+	if c.IsLoggedIn() {
+		return "", ErrAlreadyLoggedIn
+	}
+
+	phone := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, phoneNumber)
+	if len(phone) <= 6 || strings.HasPrefix(phone, "0") {
+		return "", fmt.Errorf("%w: %q", ErrInvalidPairPhone, phoneNumber)
+	}
+
+	// The linking code can only be requested once the login connection is
+	// up, which the first QR code signals
+	if err := c.ConnectAndWait(ctx); err != nil {
+		return "", err
+	}
+
+	code, err := c.wa().PairPhone(phone, true, whatsmeow.PairClientChrome, pairClientDisplayName)
+	if err != nil {
+		return "", fmt.Errorf("failed to request pairing code: %w", err)
+	}
+
+	c.logger.Info("Requested pairing code", zap.String("phone", phone))
+	return code, nil
+}
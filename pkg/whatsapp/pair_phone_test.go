@@ -0,0 +1,66 @@
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPairPhoneRejectsBeforeConnecting(t *testing.T) {
+	tests := []struct {
+		name    string
+		paired  bool
+		phone   string
+		wantErr error
+	}{
+		{name: "local number", phone: "0961234567", wantErr: ErrInvalidPairPhone},
+		{name: "too short", phone: "+56 123", wantErr: ErrInvalidPairPhone},
+		{name: "no digits", phone: "phone", wantErr: ErrInvalidPairPhone},
+		{name: "already paired", paired: true, phone: "56961234567", wantErr: ErrAlreadyLoggedIn},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t, WithOfflineFlushInterval(0))
+			if tt.paired {
+				client = newPairedClient(t)
+			}
+			var dials atomic.Int32
+			client.dial = func() error {
+				dials.Add(1)
+				return nil
+			}
+
+			if _, err := client.PairPhone(context.Background(), tt.phone); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("PairPhone(%q) error = %v, want %v", tt.phone, err, tt.wantErr)
+			}
+			if dials.Load() != 0 {
+				t.Error("PairPhone() connected for a request it rejects")
+			}
+		})
+	}
+}
+
+func TestPairPhoneTimeout(t *testing.T) {
+	client := newTestClient(t, WithOfflineFlushInterval(0))
+	// The socket connects but no QR code arrives to signal the login
+	// connection is up
+	client.dial = func() error { return nil }
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := client.PairPhone(ctx, "+56 9 6123 4567"); !errors.Is(err, ErrConnectTimeout) {
+		t.Fatalf("PairPhone() error = %v, want ErrConnectTimeout", err)
+	}
+}
+
+func TestPairPhoneDialError(t *testing.T) {
+	errRefused := errors.New("connection refused")
+	client := newTestClient(t, WithOfflineFlushInterval(0))
+	client.dial = func() error { return errRefused }
+
+	if _, err := client.PairPhone(context.Background(), "56961234567"); !errors.Is(err, errRefused) {
+		t.Fatalf("PairPhone() error = %v, want %v", err, errRefused)
+	}
+}
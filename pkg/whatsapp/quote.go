@@ -0,0 +1,162 @@
+package whatsapp
+
+import (
+	"context"
+	"sync"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	"google.golang.org/protobuf/proto"
+)
+
+// quoteCacheSize is the number of recent inbound messages kept for quoting
+const quoteCacheSize = 500
+
+// quotedEntry is an inbound message that can be quoted in a reply
+type quotedEntry struct {
+	sender  types.JID
+	message *waE2E.Message
+}
+
+// quoteCache keeps the most recent inbound messages by ID
+type quoteCache struct {
+	entries map[string]quotedEntry
+	order   []string
+	mu      sync.Mutex
+}
+
+// WithQuote quotes the inbound message with the given ID in the reply. If the
+// original is no longer cached the message is sent without a quote.
+func WithQuote(messageID string) SendOption {
+	return func(o *sendOptions) {
+		o.quoteID = messageID
+	}
+}
+
+// SendReply sends a text replying to an inbound message, which is quoted
+// above the text. Unlike WithQuote it does not need the original to still be
+// cached; a message type that cannot be quoted is sent without a quote.
+func (c *Client) SendReply(ctx context.Context, jid types.JID, text string, quoted *events.Message, options ...SendOption) (whatsmeow.SendResponse, error) {
+	contextInfo, ok := quotedContext(quoted.Info.ID, quoted.Info.Sender, quoted.Message)
+	if ok && quoted.Info.Chat.ToNonAD() != jid.ToNonAD() {
+		// e.g. a group message answered in the sender's private chat
+		contextInfo.RemoteJID = proto.String(quoted.Info.Chat.String())
+	}
+	if ok {
+		options = append(options, func(o *sendOptions) {
+			o.quote = contextInfo
+		})
+	}
+	return c.SendText(ctx, jid, text, options...)
+}
+
+// rememberInbound caches an inbound message so replies can quote it
+func (c *Client) rememberInbound(evt *events.Message) {
+	c.quotes.mu.Lock()
+	defer c.quotes.mu.Unlock()
+
+	if c.quotes.entries == nil {
+		c.quotes.entries = make(map[string]quotedEntry)
+	}
+	if _, ok := c.quotes.entries[evt.Info.ID]; !ok {
+		c.quotes.order = append(c.quotes.order, evt.Info.ID)
+	}
+	c.quotes.entries[evt.Info.ID] = quotedEntry{sender: evt.Info.Sender, message: evt.Message}
+
+	if len(c.quotes.order) > quoteCacheSize {
+		delete(c.quotes.entries, c.quotes.order[0])
+		c.quotes.order = c.quotes.order[1:]
+	}
+}
+
+// quoteContext returns the context info quoting a cached inbound message
+func (c *Client) quoteContext(messageID string) (*waE2E.ContextInfo, bool) {
+	c.quotes.mu.Lock()
+	entry, ok := c.quotes.entries[messageID]
+	c.quotes.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+	return quotedContext(messageID, entry.sender, entry.message)
+}
+
+// quotedContext returns the context info quoting a message, or false when
+// its type cannot be quoted
+func quotedContext(messageID string, sender types.JID, message *waE2E.Message) (*waE2E.ContextInfo, bool) {
+	quoted := BuildQuotedMessage(message)
+	if quoted == nil {
+		return nil, false
+	}
+
+	return &waE2E.ContextInfo{
+		StanzaID:      proto.String(messageID),
+		Participant:   proto.String(sender.ToNonAD().String()),
+		QuotedMessage: quoted,
+	}, true
+}
+
+// BuildQuotedMessage builds the quoted copy of an original message. Media
+// keeps only the fields needed to render the quote (type, caption,
+// thumbnail), not the media itself. It returns nil for unsupported types.
+func BuildQuotedMessage(original *waE2E.Message) *waE2E.Message {
+	switch {
+	case original.GetConversation() != "":
+		return &waE2E.Message{Conversation: proto.String(original.GetConversation())}
+
+	case original.GetExtendedTextMessage() != nil:
+		return &waE2E.Message{Conversation: proto.String(original.GetExtendedTextMessage().GetText())}
+
+	case original.GetImageMessage() != nil:
+		image := original.GetImageMessage()
+		return &waE2E.Message{ImageMessage: &waE2E.ImageMessage{
+			Caption:       image.Caption,
+			Mimetype:      image.Mimetype,
+			JPEGThumbnail: image.JPEGThumbnail,
+			Width:         image.Width,
+			Height:        image.Height,
+		}}
+
+	case original.GetDocumentMessage() != nil:
+		document := original.GetDocumentMessage()
+		return &waE2E.Message{DocumentMessage: &waE2E.DocumentMessage{
+			FileName:      document.FileName,
+			Title:         document.Title,
+			Mimetype:      document.Mimetype,
+			PageCount:     document.PageCount,
+			JPEGThumbnail: document.JPEGThumbnail,
+		}}
+
+	case original.GetAudioMessage() != nil:
+		audio := original.GetAudioMessage()
+		return &waE2E.Message{AudioMessage: &waE2E.AudioMessage{
+			Mimetype: audio.Mimetype,
+			Seconds:  audio.Seconds,
+			PTT:      audio.PTT,
+		}}
+
+	case original.GetVideoMessage() != nil:
+		video := original.GetVideoMessage()
+		return &waE2E.Message{VideoMessage: &waE2E.VideoMessage{
+			Caption:       video.Caption,
+			Mimetype:      video.Mimetype,
+			Seconds:       video.Seconds,
+			JPEGThumbnail: video.JPEGThumbnail,
+		}}
+	}
+	return nil
+}
+
+// withQuote turns a text message into an extended text quoting another message
+func withQuote(message *waE2E.Message, contextInfo *waE2E.ContextInfo) *waE2E.Message {
+	if message.GetExtendedTextMessage() == nil {
+		message = &waE2E.Message{
+			ExtendedTextMessage: &waE2E.ExtendedTextMessage{
+				Text: proto.String(message.GetConversation()),
+			},
+		}
+	}
+	message.ExtendedTextMessage.ContextInfo = contextInfo
+	return message
+}
@@ -0,0 +1,132 @@
+package whatsapp
+
+import (
+	"context"
+	"testing"
+
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+)
+
+// quotableMessages are inbound originals of each quotable type. Media carry
+// a URL and key, which must not be copied into the quote.
+var quotableMessages = map[string]*waE2E.Message{
+	"text": {Conversation: proto.String("¿Tienen hora mañana?")},
+	"image": {ImageMessage: &waE2E.ImageMessage{
+		URL:           proto.String("https://mmg.whatsapp.net/image"),
+		MediaKey:      []byte("key"),
+		Caption:       proto.String("Mi comprobante"),
+		Mimetype:      proto.String("image/jpeg"),
+		JPEGThumbnail: []byte("thumbnail"),
+	}},
+	"document": {DocumentMessage: &waE2E.DocumentMessage{
+		URL:       proto.String("https://mmg.whatsapp.net/document"),
+		MediaKey:  []byte("key"),
+		FileName:  proto.String("orden.pdf"),
+		Mimetype:  proto.String("application/pdf"),
+		PageCount: proto.Uint32(2),
+	}},
+	"audio": {AudioMessage: &waE2E.AudioMessage{
+		URL:      proto.String("https://mmg.whatsapp.net/audio"),
+		MediaKey: []byte("key"),
+		Mimetype: proto.String("audio/ogg; codecs=opus"),
+		Seconds:  proto.Uint32(7),
+		PTT:      proto.Bool(true),
+	}},
+}
+
+// checkQuote fails unless the quoted copy keeps the original's type and
+// display fields and drops the media itself
+func checkQuote(t *testing.T, kind string, quoted *waE2E.Message) {
+	t.Helper()
+	switch kind {
+	case "text":
+		if quoted.GetConversation() != "¿Tienen hora mañana?" {
+			t.Errorf("quoted = %v, want the text", quoted)
+		}
+	case "image":
+		image := quoted.GetImageMessage()
+		if image.GetCaption() != "Mi comprobante" || string(image.GetJPEGThumbnail()) != "thumbnail" || image.GetURL() != "" || image.GetMediaKey() != nil {
+			t.Errorf("quoted image = %v, want caption and thumbnail only", image)
+		}
+	case "document":
+		document := quoted.GetDocumentMessage()
+		if document.GetFileName() != "orden.pdf" || document.GetPageCount() != 2 || document.GetURL() != "" || document.GetMediaKey() != nil {
+			t.Errorf("quoted document = %v, want file name and page count only", document)
+		}
+	case "audio":
+		audio := quoted.GetAudioMessage()
+		if audio.GetSeconds() != 7 || !audio.GetPTT() || audio.GetURL() != "" || audio.GetMediaKey() != nil {
+			t.Errorf("quoted audio = %v, want duration and voice note flag only", audio)
+		}
+	}
+}
+
+func TestQuoteCachedMessage(t *testing.T) {
+	jid := types.NewJID(testPhone, types.DefaultUserServer)
+	for kind, original := range quotableMessages {
+		t.Run(kind, func(t *testing.T) {
+			client := newTestClient(t)
+			sent := captureSends(client)
+
+			evt := textEvent("msg-"+kind, testPhone, "")
+			evt.Message = original
+			client.handleEvent(evt)
+
+			if _, err := client.SendText(context.Background(), jid, "Recibido", WithQuote("msg-"+kind)); err != nil {
+				t.Fatalf("SendText() error = %v", err)
+			}
+			contextInfo := sent.last(t).Message.GetExtendedTextMessage().GetContextInfo()
+			if contextInfo.GetStanzaID() != "msg-"+kind || contextInfo.GetParticipant() != jid.String() {
+				t.Fatalf("context info = %v, want a quote of msg-%s from %s", contextInfo, kind, jid)
+			}
+			checkQuote(t, kind, contextInfo.GetQuotedMessage())
+		})
+	}
+}
+
+func TestQuoteFallsBackWithoutOriginal(t *testing.T) {
+	jid := types.NewJID(testPhone, types.DefaultUserServer)
+	client := newTestClient(t)
+	sent := captureSends(client)
+
+	if _, err := client.SendText(context.Background(), jid, "Recibido", WithQuote("unknown")); err != nil {
+		t.Fatalf("SendText() error = %v", err)
+	}
+	if message := sent.last(t).Message; message.GetConversation() != "Recibido" || message.GetExtendedTextMessage() != nil {
+		t.Errorf("message = %v, want a plain text without quote", message)
+	}
+
+	// A type that cannot be quoted is answered without a quote
+	evt := textEvent("sticker", testPhone, "")
+	evt.Message = &waE2E.Message{StickerMessage: &waE2E.StickerMessage{URL: proto.String("https://mmg.whatsapp.net/sticker")}}
+	if _, err := client.SendReply(context.Background(), jid, "Recibido", evt); err != nil {
+		t.Fatalf("SendReply() error = %v", err)
+	}
+	if message := sent.last(t).Message; message.GetConversation() != "Recibido" {
+		t.Errorf("reply = %v, want a plain text without quote", message)
+	}
+}
+
+func TestSendReplyQuotesUncachedMedia(t *testing.T) {
+	jid := types.NewJID(testPhone, types.DefaultUserServer)
+	for kind, original := range quotableMessages {
+		t.Run(kind, func(t *testing.T) {
+			client := newTestClient(t)
+			sent := captureSends(client)
+
+			// The original was never cached by the client
+			evt := textEvent("msg-"+kind, testPhone, "")
+			evt.Message = original
+			if _, err := client.SendReply(context.Background(), jid, "Recibido", evt); err != nil {
+				t.Fatalf("SendReply() error = %v", err)
+			}
+			contextInfo := sent.last(t).Message.GetExtendedTextMessage().GetContextInfo()
+			if contextInfo.GetStanzaID() != "msg-"+kind {
+				t.Fatalf("context info = %v, want a quote of msg-%s", contextInfo, kind)
+			}
+			checkQuote(t, kind, contextInfo.GetQuotedMessage())
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrConnectTimeout is returned when the client does not become ready before
+// the context deadline
+var ErrConnectTimeout = errors.New("timed out waiting for WhatsApp connection to be ready")
+
+// ConnectAndWait connects and blocks until the client is ready: the Connected
+// event fired or, for a device that is not paired yet, the first QR code was
+// received. It returns ErrConnectTimeout if the context ends first.
+func (c *Client) ConnectAndWait(ctx context.Context) error {
+	ready := c.readySignal()
+
+	if err := c.Connect(); err != nil {
+		return err
+	}
+
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%w: %v", ErrConnectTimeout, ctx.Err())
+	}
+}
+
+// readySignal returns a channel closed once the client is ready
+func (c *Client) readySignal() <-chan struct{} {
+	c.readyMu.Lock()
+	defer c.readyMu.Unlock()
+	if c.readyCh == nil {
+		c.readyCh = make(chan struct{})
+	}
+	return c.readyCh
+}
+
+// markReady releases everyone waiting in ConnectAndWait
+func (c *Client) markReady() {
+	c.readyMu.Lock()
+	defer c.readyMu.Unlock()
+	if c.readyCh == nil {
+		c.readyCh = make(chan struct{})
+	}
+	select {
+	case <-c.readyCh:
+	default:
+		close(c.readyCh)
+	}
+}
+
+// markNotReady re-arms the ready signal after the connection is lost
+func (c *Client) markNotReady() {
+	c.readyMu.Lock()
+	defer c.readyMu.Unlock()
+	select {
+	case <-c.readyCh:
+		c.readyCh = make(chan struct{})
+	default:
+	}
+}
@@ -0,0 +1,89 @@
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// fireAfterDial makes the client's dial succeed and the fake event source
+// deliver evt after the delay, as WhatsApp does once the session is up
+func fireAfterDial(client *Client, delay time.Duration, evt interface{}) {
+	client.dial = func() error {
+		go func() {
+			time.Sleep(delay)
+			client.dispatchEvent(evt)
+		}()
+		return nil
+	}
+}
+
+func TestConnectAndWait(t *testing.T) {
+	tests := []struct {
+		name string
+		evt  interface{}
+	}{
+		{name: "connected event", evt: &events.Connected{}},
+		{name: "first QR code of an unpaired device", evt: &events.QR{Codes: []string{"code-1"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t, WithOfflineFlushInterval(0))
+			const delay = 50 * time.Millisecond
+			fireAfterDial(client, delay, tt.evt)
+
+			start := time.Now()
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			if err := client.ConnectAndWait(ctx); err != nil {
+				t.Fatalf("ConnectAndWait() error = %v", err)
+			}
+			if elapsed := time.Since(start); elapsed < delay {
+				t.Errorf("ConnectAndWait() returned after %v, before the event", elapsed)
+			}
+		})
+	}
+}
+
+func TestConnectAndWaitTimeout(t *testing.T) {
+	client := newTestClient(t, WithOfflineFlushInterval(0))
+	// The socket connects but the session never becomes ready
+	client.dial = func() error { return nil }
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := client.ConnectAndWait(ctx); !errors.Is(err, ErrConnectTimeout) {
+		t.Fatalf("ConnectAndWait() error = %v, want ErrConnectTimeout", err)
+	}
+}
+
+func TestConnectAndWaitDialError(t *testing.T) {
+	errRefused := errors.New("connection refused")
+	client := newTestClient(t, WithOfflineFlushInterval(0))
+	client.dial = func() error { return errRefused }
+
+	if err := client.ConnectAndWait(context.Background()); !errors.Is(err, errRefused) {
+		t.Fatalf("ConnectAndWait() error = %v, want %v", err, errRefused)
+	}
+}
+
+func TestConnectAndWaitAfterReconnect(t *testing.T) {
+	client := newTestClient(t, WithOfflineFlushInterval(0))
+	fireAfterDial(client, 0, &events.Connected{})
+	if err := client.ConnectAndWait(context.Background()); err != nil {
+		t.Fatalf("first ConnectAndWait() error = %v", err)
+	}
+
+	// A lost connection re-arms the signal, so the next wait blocks until
+	// the new session is ready
+	client.Disconnect()
+	client.dial = func() error { return nil }
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := client.ConnectAndWait(ctx); !errors.Is(err, ErrConnectTimeout) {
+		t.Fatalf("ConnectAndWait() after disconnecting error = %v, want ErrConnectTimeout", err)
+	}
+}
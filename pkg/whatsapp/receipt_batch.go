@@ -0,0 +1,108 @@
+package whatsapp
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// pendingReceipt is the latest buffered status of a sent message
+type pendingReceipt struct {
+	status string
+	at     time.Time
+}
+
+// ReceiptBatcher is a SendRecorder that buffers delivery receipts in memory
+// and forwards them to another recorder in batches. Receipts for the same
+// message are coalesced, so only the latest status is written. Send records
+// are forwarded immediately.
+type ReceiptBatcher struct {
+	recorder  SendRecorder
+	logger    logger.Logger
+	batchSize int
+	pending   map[string]pendingReceipt
+	mu        sync.Mutex
+	full      chan struct{}
+}
+
+// NewReceiptBatcher creates a ReceiptBatcher that flushes as soon as
+// batchSize messages have buffered receipts
+func NewReceiptBatcher(recorder SendRecorder, logger logger.Logger, batchSize int) *ReceiptBatcher {
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+
+	return &ReceiptBatcher{
+		recorder:  recorder,
+		logger:    logger,
+		batchSize: batchSize,
+		pending:   make(map[string]pendingReceipt),
+		full:      make(chan struct{}, 1),
+	}
+}
+
+// RecordSend forwards the send record without buffering
+func (b *ReceiptBatcher) RecordSend(ctx context.Context, record SendRecord) error {
+	return b.recorder.RecordSend(ctx, record)
+}
+
+// UpdateStatus buffers a receipt until the next flush
+func (b *ReceiptBatcher) UpdateStatus(ctx context.Context, messageID, status string, at time.Time) error {
+	b.mu.Lock()
+	if current, ok := b.pending[messageID]; !ok || StatusRank(status) > StatusRank(current.status) {
+		b.pending[messageID] = pendingReceipt{status: status, at: at}
+	}
+	full := len(b.pending) >= b.batchSize
+	b.mu.Unlock()
+
+	if full {
+		select {
+		case b.full <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Flush writes every buffered receipt and returns how many were written
+func (b *ReceiptBatcher) Flush(ctx context.Context) int {
+	b.mu.Lock()
+	pending := b.pending
+	b.pending = make(map[string]pendingReceipt)
+	b.mu.Unlock()
+
+	for messageID, receipt := range pending {
+		if err := b.recorder.UpdateStatus(ctx, messageID, receipt.status, receipt.at); err != nil {
+			b.logger.Warn("Failed to update send record",
+				zap.String("message_id", messageID),
+				zap.String("status", receipt.status),
+				zap.Error(err))
+		}
+	}
+	return len(pending)
+}
+
+// Start flushes buffered receipts on the given interval, or earlier when the
+// buffer fills, until the context is cancelled. Call Flush on shutdown to
+// write what is still buffered.
+func (b *ReceiptBatcher) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-b.full:
+			}
+			if flushed := b.Flush(ctx); flushed > 0 {
+				b.logger.Debug("Flushed delivery receipts", zap.Int("count", flushed))
+			}
+		}
+	}()
+}
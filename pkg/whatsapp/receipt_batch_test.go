@@ -0,0 +1,117 @@
+package whatsapp
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// countingRecorder is a fakeRecorder that counts the status writes
+type countingRecorder struct {
+	*fakeRecorder
+	updates atomic.Int32
+}
+
+func (r *countingRecorder) UpdateStatus(ctx context.Context, messageID, status string, at time.Time) error {
+	r.updates.Add(1)
+	return r.fakeRecorder.UpdateStatus(ctx, messageID, status, at)
+}
+
+// receipt builds a delivery receipt for the messages from the test phone
+func receipt(receiptType types.ReceiptType, ids ...types.MessageID) *events.Receipt {
+	jid := types.NewJID(testPhone, types.DefaultUserServer)
+	return &events.Receipt{
+		MessageSource: types.MessageSource{Chat: jid, Sender: jid},
+		MessageIDs:    ids,
+		Timestamp:     time.Now(),
+		Type:          receiptType,
+	}
+}
+
+// sendTexts sends n texts to the test phone and returns their IDs
+func sendTexts(t *testing.T, client *Client, n int) []types.MessageID {
+	t.Helper()
+	jid := types.NewJID(testPhone, types.DefaultUserServer)
+	var ids []types.MessageID
+	for i := 0; i < n; i++ {
+		resp, err := client.SendText(context.Background(), jid, "Hola")
+		if err != nil {
+			t.Fatalf("SendText() error = %v", err)
+		}
+		ids = append(ids, resp.ID)
+	}
+	return ids
+}
+
+func TestReceiptBatcherCoalescesReceipts(t *testing.T) {
+	recorder := &countingRecorder{fakeRecorder: newFakeRecorder()}
+	batcher := NewReceiptBatcher(recorder, logger.NewNop(), 100)
+	client := newTestClient(t, WithDryRun(0, 0), WithSendRecorder(batcher))
+	ids := sendTexts(t, client, 3)
+
+	// Each message is delivered and read, then a late delivered receipt arrives
+	client.handleEvent(receipt(types.ReceiptTypeDelivered, ids...))
+	client.handleEvent(receipt(types.ReceiptTypeRead, ids...))
+	client.handleEvent(receipt(types.ReceiptTypeDelivered, ids[0]))
+	if got := recorder.updates.Load(); got != 0 {
+		t.Fatalf("%d status writes before the flush, want 0", got)
+	}
+
+	if flushed := batcher.Flush(context.Background()); flushed != 3 {
+		t.Errorf("Flush() = %d, want 3", flushed)
+	}
+	// Seven receipts became one write per message
+	if got := recorder.updates.Load(); got != 3 {
+		t.Errorf("%d status writes, want 3", got)
+	}
+	for _, id := range ids {
+		if record, _ := recorder.get(id); record.Status != SendStatusRead {
+			t.Errorf("%s status = %s, want %s", id, record.Status, SendStatusRead)
+		}
+	}
+
+	if flushed := batcher.Flush(context.Background()); flushed != 0 {
+		t.Errorf("second Flush() = %d, want 0", flushed)
+	}
+}
+
+func TestReceiptBatcherFlushes(t *testing.T) {
+	tests := []struct {
+		name      string
+		batchSize int
+		interval  time.Duration
+	}{
+		{name: "when the buffer fills", batchSize: 2, interval: time.Hour},
+		{name: "on the interval", batchSize: 100, interval: 20 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := &countingRecorder{fakeRecorder: newFakeRecorder()}
+			batcher := NewReceiptBatcher(recorder, logger.NewNop(), tt.batchSize)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			batcher.Start(ctx, tt.interval)
+			client := newTestClient(t, WithDryRun(0, 0), WithSendRecorder(batcher))
+			ids := sendTexts(t, client, 2)
+
+			client.handleEvent(receipt(types.ReceiptTypeDelivered, ids...))
+			deadline := time.Now().Add(time.Second)
+			for recorder.updates.Load() < 2 {
+				if time.Now().After(deadline) {
+					t.Fatalf("%d status writes, want 2", recorder.updates.Load())
+				}
+				time.Sleep(5 * time.Millisecond)
+			}
+			for _, id := range ids {
+				if record, _ := recorder.get(id); record.Status != SendStatusDelivered {
+					t.Errorf("%s status = %s, want %s", id, record.Status, SendStatusDelivered)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/retry"
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types"
+	"go.uber.org/zap"
+)
+
+// ErrRecipientUnreachable is returned when a send to a recipient failed for
+// a reason expected to clear up, such as their devices not answering
+var ErrRecipientUnreachable = errors.New("recipient is temporarily unreachable")
+
+// ErrRecipientRejected is returned when WhatsApp rejected a send because of
+// the recipient, e.g. it is invalid or blocked the account. Retrying will
+// not help.
+var ErrRecipientRejected = errors.New("recipient rejected the message")
+
+// rejectedCodes are the send error codes returned by the WhatsApp server for
+// invalid or blocking recipients
+var rejectedCodes = map[int]bool{
+	400: true,
+	403: true,
+	404: true,
+	406: true,
+}
+
+// WithRecipientRetry re-queues sends that failed with
+// ErrRecipientUnreachable, waiting the backoff between attempts, until they
+// succeed or the attempts run out
+func WithRecipientRetry(backoff retry.Backoff) ClientOption {
+	return func(c *Client) {
+		c.recipientRetry = &backoff
+	}
+}
+
+// classifyRecipientError wraps a whatsmeow send error with
+// ErrRecipientUnreachable or ErrRecipientRejected when it is specific to
+// the recipient. Other errors are returned as is.
+func classifyRecipientError(err error) error {
+	switch {
+	case errors.Is(err, whatsmeow.ErrRecipientADJID), errors.Is(err, whatsmeow.ErrUnknownServer):
+		return fmt.Errorf("%w: %w", ErrRecipientRejected, err)
+	case errors.Is(err, whatsmeow.ErrNoSession),
+		errors.Is(err, whatsmeow.ErrMessageTimedOut),
+		errors.Is(err, whatsmeow.ErrIQTimedOut),
+		errors.Is(err, whatsmeow.ErrIQServiceUnavailable):
+		return fmt.Errorf("%w: %w", ErrRecipientUnreachable, err)
+	case errors.Is(err, whatsmeow.ErrServerReturnedError):
+		if rejectedCodes[serverErrorCode(err)] {
+			return fmt.Errorf("%w: %w", ErrRecipientRejected, err)
+		}
+		return fmt.Errorf("%w: %w", ErrRecipientUnreachable, err)
+	}
+	return err
+}
+
+// serverErrorCode returns the code of a whatsmeow ErrServerReturnedError,
+// formatted as "server returned error <code>", or 0
+func serverErrorCode(err error) int {
+	fields := strings.Fields(err.Error())
+	if len(fields) == 0 {
+		return 0
+	}
+	code, err := strconv.Atoi(fields[len(fields)-1])
+	if err != nil {
+		return 0
+	}
+	return code
+}
+
+// retryMessageID fixes the message ID of a send that may be retried. A
+// timed-out attempt may still have been delivered, and recipients drop a
+// message whose ID they already have, so every retry reuses the ID of the
+// first attempt instead of reaching the customer twice.
+func (c *Client) retryMessageID(extra []whatsmeow.SendRequestExtra) []whatsmeow.SendRequestExtra {
+	if c.recipientRetry == nil {
+		return extra
+	}
+	var req whatsmeow.SendRequestExtra
+	if len(extra) > 0 {
+		req = extra[0]
+	}
+	if req.ID == "" {
+		req.ID = c.wa().GenerateMessageID()
+	}
+	return append([]whatsmeow.SendRequestExtra{req}, extra[min(len(extra), 1):]...)
+}
+
+// retryUnreachable runs a send attempt, running it again with the recipient
+// retry backoff while it fails with ErrRecipientUnreachable
+func (c *Client) retryUnreachable(ctx context.Context, jid types.JID, attempt func() (whatsmeow.SendResponse, error)) (whatsmeow.SendResponse, error) {
+	if c.recipientRetry == nil {
+		return attempt()
+	}
+
+	var resp whatsmeow.SendResponse
+	attempts := 0
+	err := c.recipientRetry.Do(ctx, func(ctx context.Context) error {
+		attempts++
+		var err error
+		resp, err = attempt()
+		if err == nil || !errors.Is(err, ErrRecipientUnreachable) {
+			return retry.Permanent(err)
+		}
+		if !c.recipientRetry.Exhausted(attempts) {
+			c.logger.Warn("Recipient unreachable, re-queueing message",
+				zap.String("to", jid.String()),
+				zap.Int("attempt", attempts),
+				zap.Error(err))
+		}
+		return err
+	})
+	return resp, err
+}
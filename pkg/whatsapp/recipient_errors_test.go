@@ -0,0 +1,180 @@
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/retry"
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+)
+
+// failingSends makes the client send for real, failing with the given
+// errors in turn and succeeding once they run out. It returns the number
+// of attempts made.
+func failingSends(c *Client, errs ...error) func() int {
+	var mu sync.Mutex
+	attempts := 0
+	c.dryRun.enabled = false
+	c.sendMessage = func(_ context.Context, _ types.JID, _ *waE2E.Message, _ ...whatsmeow.SendRequestExtra) (whatsmeow.SendResponse, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+		if attempts <= len(errs) {
+			return whatsmeow.SendResponse{}, errs[attempts-1]
+		}
+		return whatsmeow.SendResponse{ID: "msg-1", Timestamp: time.Now()}, nil
+	}
+	return func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return attempts
+	}
+}
+
+func TestClassifyRecipientError(t *testing.T) {
+	other := errors.New("websocket closed")
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{name: "invalid recipient", err: whatsmeow.ErrRecipientADJID, want: ErrRecipientRejected},
+		{name: "unknown server", err: whatsmeow.ErrUnknownServer, want: ErrRecipientRejected},
+		{name: "blocked", err: fmt.Errorf("%w %d", whatsmeow.ErrServerReturnedError, 403), want: ErrRecipientRejected},
+		{name: "not found", err: fmt.Errorf("%w %d", whatsmeow.ErrServerReturnedError, 404), want: ErrRecipientRejected},
+		{name: "server error", err: fmt.Errorf("%w %d", whatsmeow.ErrServerReturnedError, 500), want: ErrRecipientUnreachable},
+		{name: "no session", err: whatsmeow.ErrNoSession, want: ErrRecipientUnreachable},
+		{name: "message timeout", err: whatsmeow.ErrMessageTimedOut, want: ErrRecipientUnreachable},
+		{name: "other", err: other, want: other},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := classifyRecipientError(tt.err)
+			if !errors.Is(got, tt.want) || !errors.Is(got, tt.err) {
+				t.Errorf("classifyRecipientError(%v) = %v, want it wrapped in %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecipientRetryRequeuesUnreachable(t *testing.T) {
+	client := newTestClient(t, WithDryRun(0, 0), WithRecipientRetry(retry.Backoff{
+		BaseDelay:   time.Millisecond,
+		MaxAttempts: 3,
+	}))
+	attempts := failingSends(client, whatsmeow.ErrNoSession, whatsmeow.ErrMessageTimedOut)
+	var failures []error
+	client.OnSendFailure(func(_ types.JID, err error) { failures = append(failures, err) })
+	jid := types.NewJID(testPhone, types.DefaultUserServer)
+
+	// Transient failures are retried until the send goes through
+	resp, err := client.SendText(context.Background(), jid, "Hola")
+	if err != nil {
+		t.Fatalf("SendText() error = %v", err)
+	}
+	if resp.ID != "msg-1" || attempts() != 3 {
+		t.Errorf("sent %s after %d attempts, want msg-1 after 3", resp.ID, attempts())
+	}
+	if len(failures) != 2 {
+		t.Errorf("failure hooks ran %d times, want once per failed attempt", len(failures))
+	}
+	for _, err := range failures {
+		if !errors.Is(err, ErrRecipientUnreachable) {
+			t.Errorf("failure hook got %v, want %v", err, ErrRecipientUnreachable)
+		}
+	}
+
+	// The retries are capped
+	attempts = failingSends(client, whatsmeow.ErrNoSession, whatsmeow.ErrNoSession, whatsmeow.ErrNoSession, whatsmeow.ErrNoSession)
+	if _, err := client.SendText(context.Background(), jid, "Hola"); !errors.Is(err, ErrRecipientUnreachable) {
+		t.Errorf("SendText() error = %v, want %v", err, ErrRecipientUnreachable)
+	}
+	if got := attempts(); got != 3 {
+		t.Errorf("%d attempts, want 3", got)
+	}
+}
+
+func TestRecipientRetryStopsOnRejected(t *testing.T) {
+	client := newTestClient(t, WithDryRun(0, 0), WithRecipientRetry(retry.Backoff{
+		BaseDelay:   time.Millisecond,
+		MaxAttempts: 3,
+	}))
+	attempts := failingSends(client, fmt.Errorf("%w %d", whatsmeow.ErrServerReturnedError, 403))
+	var failures []error
+	client.OnSendFailure(func(_ types.JID, err error) { failures = append(failures, err) })
+
+	// Terminal failures are not retried
+	_, err := client.SendText(context.Background(), types.NewJID(testPhone, types.DefaultUserServer), "Hola")
+	if !errors.Is(err, ErrRecipientRejected) {
+		t.Fatalf("SendText() error = %v, want %v", err, ErrRecipientRejected)
+	}
+	if got := attempts(); got != 1 {
+		t.Errorf("%d attempts, want 1", got)
+	}
+	if len(failures) != 1 || !errors.Is(failures[0], ErrRecipientRejected) {
+		t.Errorf("failure hooks got %v, want one rejection", failures)
+	}
+}
+
+func TestUnreachableWithoutRecipientRetry(t *testing.T) {
+	client := newTestClient(t, WithDryRun(0, 0))
+	attempts := failingSends(client, whatsmeow.ErrNoSession)
+
+	_, err := client.SendText(context.Background(), types.NewJID(testPhone, types.DefaultUserServer), "Hola")
+	if !errors.Is(err, ErrRecipientUnreachable) {
+		t.Errorf("SendText() error = %v, want %v", err, ErrRecipientUnreachable)
+	}
+	if got := attempts(); got != 1 {
+		t.Errorf("%d attempts without a recipient retry, want 1", got)
+	}
+}
+
+func TestRecipientRetryReusesMessageID(t *testing.T) {
+	client := newTestClient(t, WithDryRun(0, 0), WithRecipientRetry(retry.Backoff{
+		BaseDelay:   time.Millisecond,
+		MaxAttempts: 3,
+	}))
+	client.dryRun.enabled = false
+	var ids []types.MessageID
+	client.sendMessage = func(_ context.Context, _ types.JID, _ *waE2E.Message, extra ...whatsmeow.SendRequestExtra) (whatsmeow.SendResponse, error) {
+		var id types.MessageID
+		if len(extra) > 0 {
+			id = extra[0].ID
+		}
+		ids = append(ids, id)
+		if len(ids) < 3 {
+			return whatsmeow.SendResponse{}, whatsmeow.ErrMessageTimedOut
+		}
+		return whatsmeow.SendResponse{ID: id, Timestamp: time.Now()}, nil
+	}
+	jid := types.NewJID(testPhone, types.DefaultUserServer)
+
+	// A timed-out copy may have been delivered, so retries keep its ID and
+	// WhatsApp drops them as repeats
+	resp, err := client.SendText(context.Background(), jid, "Hola")
+	if err != nil {
+		t.Fatalf("SendText() error = %v", err)
+	}
+	if len(ids) != 3 || ids[0] == "" || ids[1] != ids[0] || ids[2] != ids[0] || resp.ID != ids[0] {
+		t.Errorf("attempt IDs = %q, response ID = %q, want one ID for every attempt", ids, resp.ID)
+	}
+
+	// An ID chosen by the caller is kept
+	ids = nil
+	if _, err := client.Send(context.Background(), jid, &waE2E.Message{Conversation: proto.String("Hola")}, whatsmeow.SendRequestExtra{ID: "caller-id"}); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	for _, id := range ids {
+		if id != "caller-id" {
+			t.Errorf("attempt IDs = %q, want caller-id", ids)
+			break
+		}
+	}
+}
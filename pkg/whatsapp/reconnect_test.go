@@ -0,0 +1,109 @@
+package whatsapp
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+func TestReconnectLoopStopsAtCeiling(t *testing.T) {
+	errRefused := errors.New("connection refused")
+	type alert struct {
+		attempts int
+		err      error
+	}
+	alerts := make(chan alert, 2)
+	client := newTestClient(t,
+		WithReconnectPolicy(time.Millisecond, time.Millisecond, 3),
+		WithReconnectAlert(func(attempts int, err error) {
+			alerts <- alert{attempts, err}
+		}))
+	var dials atomic.Int32
+	client.dial = func() error {
+		dials.Add(1)
+		return errRefused
+	}
+
+	client.dispatchEvent(&events.Disconnected{})
+
+	select {
+	case got := <-alerts:
+		if got.attempts != 3 || !errors.Is(got.err, errRefused) {
+			t.Errorf("alert(%d, %v), want 3 attempts failing with %v", got.attempts, got.err, errRefused)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("alert not fired")
+	}
+	if got := dials.Load(); got != 3 {
+		t.Errorf("dialed %d times, want 3", got)
+	}
+	if !client.IsReconnectFailed() {
+		t.Error("IsReconnectFailed() = false after reaching the ceiling")
+	}
+	if got := client.ConnectionState(); got != StateReconnectFailed {
+		t.Errorf("ConnectionState() = %q, want %q", got, StateReconnectFailed)
+	}
+
+	// A failed loop stays stopped until a manual reconnect
+	client.dispatchEvent(&events.Disconnected{})
+	time.Sleep(20 * time.Millisecond)
+	if got := dials.Load(); got != 3 {
+		t.Errorf("dialed %d times after giving up, want 3", got)
+	}
+	select {
+	case got := <-alerts:
+		t.Errorf("alert fired again: %+v", got)
+	default:
+	}
+
+	// A manual reconnect resets the counter and resumes
+	client.dial = func() error { return nil }
+	if err := client.Reconnect(); err != nil {
+		t.Fatalf("Reconnect() error = %v", err)
+	}
+	if client.IsReconnectFailed() || !client.IsConnected() {
+		t.Errorf("after Reconnect(): failed = %v, connected = %v", client.IsReconnectFailed(), client.IsConnected())
+	}
+	if got := client.ConnectionState(); got != StateConnected {
+		t.Errorf("ConnectionState() = %q, want %q", got, StateConnected)
+	}
+}
+
+func TestReconnectLoopStopsOnSuccess(t *testing.T) {
+	alerted := make(chan struct{}, 1)
+	client := newTestClient(t,
+		WithReconnectPolicy(time.Millisecond, time.Millisecond, 3),
+		WithReconnectAlert(func(int, error) { alerted <- struct{}{} }))
+	var dials atomic.Int32
+	client.dial = func() error {
+		if dials.Add(1) < 2 {
+			return errors.New("connection refused")
+		}
+		return nil
+	}
+
+	client.dispatchEvent(&events.Disconnected{})
+
+	deadline := time.Now().Add(time.Second)
+	for !client.IsConnected() {
+		if time.Now().After(deadline) {
+			t.Fatal("client did not reconnect")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if got := dials.Load(); got != 2 {
+		t.Errorf("dialed %d times, want 2", got)
+	}
+	if client.IsReconnectFailed() {
+		t.Error("IsReconnectFailed() = true after reconnecting")
+	}
+	select {
+	case <-alerted:
+		t.Error("alert fired although the client reconnected")
+	default:
+	}
+}
@@ -0,0 +1,56 @@
+package whatsapp
+
+import (
+	"encoding/json"
+
+	"go.mau.fi/whatsmeow/proto/waE2E"
+)
+
+// InteractiveResponse is the button or list row a customer selected
+type InteractiveResponse struct {
+	// ID is the ID of the selected button or list row
+	ID string
+	// Text is the display text of the selection
+	Text string
+}
+
+// ParseInteractiveResponse extracts the selection from a button, list,
+// template button or interactive (native flow) response message
+func ParseInteractiveResponse(message *waE2E.Message) (InteractiveResponse, bool) {
+	if response := message.GetButtonsResponseMessage(); response != nil {
+		return InteractiveResponse{
+			ID:   response.GetSelectedButtonID(),
+			Text: response.GetSelectedDisplayText(),
+		}, true
+	}
+
+	if response := message.GetListResponseMessage(); response != nil {
+		return InteractiveResponse{
+			ID:   response.GetSingleSelectReply().GetSelectedRowID(),
+			Text: response.GetTitle(),
+		}, true
+	}
+
+	if response := message.GetTemplateButtonReplyMessage(); response != nil {
+		return InteractiveResponse{
+			ID:   response.GetSelectedID(),
+			Text: response.GetSelectedDisplayText(),
+		}, true
+	}
+
+	if response := message.GetInteractiveResponseMessage(); response != nil {
+		// Native flow responses carry the selection as JSON parameters
+		var params struct {
+			ID string `json:"id"`
+		}
+		if paramsJSON := response.GetNativeFlowResponseMessage().GetParamsJSON(); paramsJSON != "" {
+			_ = json.Unmarshal([]byte(paramsJSON), &params)
+		}
+		return InteractiveResponse{
+			ID:   params.ID,
+			Text: response.GetBody().GetText(),
+		}, true
+	}
+
+	return InteractiveResponse{}, false
+}
@@ -0,0 +1,81 @@
+package whatsapp
+
+import (
+	"testing"
+
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"google.golang.org/protobuf/proto"
+)
+
+// responseMessages are a response of each supported type selecting booking_confirm
+var responseMessages = map[string]*waE2E.Message{
+	"buttons": {ButtonsResponseMessage: &waE2E.ButtonsResponseMessage{
+		SelectedButtonID: proto.String("booking_confirm"),
+		Response:         &waE2E.ButtonsResponseMessage_SelectedDisplayText{SelectedDisplayText: "Confirmar"},
+	}},
+	"list": {ListResponseMessage: &waE2E.ListResponseMessage{
+		Title:             proto.String("Confirmar"),
+		SingleSelectReply: &waE2E.ListResponseMessage_SingleSelectReply{SelectedRowID: proto.String("booking_confirm")},
+	}},
+	"template button": {TemplateButtonReplyMessage: &waE2E.TemplateButtonReplyMessage{
+		SelectedID:          proto.String("booking_confirm"),
+		SelectedDisplayText: proto.String("Confirmar"),
+	}},
+	"native flow": {InteractiveResponseMessage: &waE2E.InteractiveResponseMessage{
+		Body: &waE2E.InteractiveResponseMessage_Body{Text: proto.String("Confirmar")},
+		InteractiveResponseMessage: &waE2E.InteractiveResponseMessage_NativeFlowResponseMessage_{
+			NativeFlowResponseMessage: &waE2E.InteractiveResponseMessage_NativeFlowResponseMessage{
+				Name:       proto.String("quick_reply"),
+				ParamsJSON: proto.String(`{"id":"booking_confirm"}`),
+			},
+		},
+	}},
+}
+
+func TestParseInteractiveResponse(t *testing.T) {
+	for name, message := range responseMessages {
+		t.Run(name, func(t *testing.T) {
+			response, ok := ParseInteractiveResponse(message)
+			if !ok {
+				t.Fatal("ParseInteractiveResponse() ok = false")
+			}
+			if response.ID != "booking_confirm" || response.Text != "Confirmar" {
+				t.Errorf("response = %+v, want booking_confirm Confirmar", response)
+			}
+		})
+	}
+
+	t.Run("text message", func(t *testing.T) {
+		if response, ok := ParseInteractiveResponse(&waE2E.Message{Conversation: proto.String("Sí")}); ok {
+			t.Errorf("ParseInteractiveResponse() = %+v, want no response", response)
+		}
+	})
+	t.Run("malformed native flow params", func(t *testing.T) {
+		message := &waE2E.Message{InteractiveResponseMessage: &waE2E.InteractiveResponseMessage{
+			InteractiveResponseMessage: &waE2E.InteractiveResponseMessage_NativeFlowResponseMessage_{
+				NativeFlowResponseMessage: &waE2E.InteractiveResponseMessage_NativeFlowResponseMessage{ParamsJSON: proto.String("{")},
+			},
+		}}
+		if response, ok := ParseInteractiveResponse(message); !ok || response.ID != "" {
+			t.Errorf("ParseInteractiveResponse() = %+v, %v, want a response without ID", response, ok)
+		}
+	})
+}
+
+func TestResponseDispatchedWithID(t *testing.T) {
+	for name, message := range responseMessages {
+		t.Run(name, func(t *testing.T) {
+			client := newTestClient(t)
+			messages := collectMessages(client)
+
+			evt := textEvent("msg-1", testPhone, "")
+			evt.Message = message
+			client.handleEvent(evt)
+
+			msg := receive(t, messages)
+			if msg.ResponseID != "booking_confirm" || msg.Body != "Confirmar" {
+				t.Errorf("dispatched %+v, want response booking_confirm with body Confirmar", msg)
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package whatsapp
+
+import "go.mau.fi/whatsmeow/types"
+
+// SendFailureHook is called when WhatsApp rejects a message. Failures caused
+// by the recipient wrap ErrRecipientUnreachable or ErrRecipientRejected. It
+// runs on the send path and must not block.
+type SendFailureHook func(jid types.JID, err error)
+
+// OnSendFailure registers a hook called for every message that failed to send
+func (c *Client) OnSendFailure(hook SendFailureHook) {
+	c.sendFailureMu.Lock()
+	defer c.sendFailureMu.Unlock()
+	c.sendFailureHooks = append(c.sendFailureHooks, hook)
+}
+
+// runSendFailureHooks reports a failed send to the registered hooks
+func (c *Client) runSendFailureHooks(jid types.JID, err error) {
+	c.sendFailureMu.RLock()
+	hooks := c.sendFailureHooks
+	c.sendFailureMu.RUnlock()
+
+	for _, hook := range hooks {
+		hook(jid, err)
+	}
+}
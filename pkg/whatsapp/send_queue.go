@@ -0,0 +1,185 @@
+package whatsapp
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/metrics"
+	"go.mau.fi/whatsmeow"
+)
+
+// Priority orders messages competing for the rate-limited send path
+type Priority int
+
+const (
+	// PriorityLow is for bulk sends such as broadcasts
+	PriorityLow Priority = iota
+	// PriorityNormal is for transactional messages such as confirmations
+	PriorityNormal
+	// PriorityHigh is for interactive replies to a customer
+	PriorityHigh
+)
+
+// priorityCount is the number of priority levels
+const priorityCount = 3
+
+// String returns the priority name used in metrics and logs
+func (p Priority) String() string {
+	switch p {
+	case PriorityLow:
+		return "low"
+	case PriorityHigh:
+		return "high"
+	default:
+		return "normal"
+	}
+}
+
+// priorityKey is the context key holding the send priority
+type priorityKey struct{}
+
+// ContextWithPriority returns a context whose sends use the given priority
+func ContextWithPriority(ctx context.Context, priority Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, priority)
+}
+
+// PriorityFromContext returns the send priority of the context, or PriorityNormal
+func PriorityFromContext(ctx context.Context) Priority {
+	if priority, ok := ctx.Value(priorityKey{}).(Priority); ok && priority >= PriorityLow && priority <= PriorityHigh {
+		return priority
+	}
+	return PriorityNormal
+}
+
+// WithSendQueue paces sends through a priority queue, sending at most one
+// message per interval. Messages waiting longer than maxWait are sent ahead
+// of higher priorities so bulk sends are never starved.
+func WithSendQueue(interval, maxWait time.Duration) ClientOption {
+	return func(c *Client) {
+		c.queue = newSendQueue(interval, maxWait)
+	}
+}
+
+// sendResult is the outcome of a queued send
+type sendResult struct {
+	resp whatsmeow.SendResponse
+	err  error
+}
+
+// sendJob is a send waiting in the queue
+type sendJob struct {
+	ctx      context.Context
+	enqueued time.Time
+	run      func() (whatsmeow.SendResponse, error)
+	result   chan sendResult
+}
+
+// sendQueue dispatches sends one at a time, highest priority first
+type sendQueue struct {
+	mu       sync.Mutex
+	jobs     [priorityCount][]*sendJob
+	interval time.Duration
+	maxWait  time.Duration
+	wake     chan struct{}
+}
+
+// newSendQueue creates a sendQueue; call run to start dispatching
+func newSendQueue(interval, maxWait time.Duration) *sendQueue {
+	return &sendQueue{
+		interval: interval,
+		maxWait:  maxWait,
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// submit queues a send and waits for its result or for ctx to be cancelled
+func (q *sendQueue) submit(ctx context.Context, priority Priority, run func() (whatsmeow.SendResponse, error)) (whatsmeow.SendResponse, error) {
+	job := &sendJob{
+		ctx:      ctx,
+		enqueued: time.Now(),
+		run:      run,
+		result:   make(chan sendResult, 1),
+	}
+	q.push(priority, job)
+
+	select {
+	case result := <-job.result:
+		return result.resp, result.err
+	case <-ctx.Done():
+		return whatsmeow.SendResponse{}, ctx.Err()
+	}
+}
+
+// push appends a job to its priority queue and wakes the dispatcher
+func (q *sendQueue) push(priority Priority, job *sendJob) {
+	q.mu.Lock()
+	q.jobs[priority] = append(q.jobs[priority], job)
+	metrics.SendQueueDepth.WithLabelValues(priority.String()).Set(float64(len(q.jobs[priority])))
+	q.mu.Unlock()
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// next pops the job to send: the longest-waiting job past maxWait if any,
+// otherwise the oldest job of the highest non-empty priority
+func (q *sendQueue) next(now time.Time) (*sendJob, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	selected := -1
+	for priority := priorityCount - 1; priority >= 0; priority-- {
+		if len(q.jobs[priority]) == 0 {
+			continue
+		}
+		if selected < 0 {
+			selected = priority
+			continue
+		}
+		head := q.jobs[priority][0]
+		if q.maxWait > 0 && now.Sub(head.enqueued) >= q.maxWait && head.enqueued.Before(q.jobs[selected][0].enqueued) {
+			selected = priority
+		}
+	}
+	if selected < 0 {
+		return nil, false
+	}
+
+	job := q.jobs[selected][0]
+	q.jobs[selected] = q.jobs[selected][1:]
+	metrics.SendQueueDepth.WithLabelValues(Priority(selected).String()).Set(float64(len(q.jobs[selected])))
+	return job, true
+}
+
+// run dispatches queued sends until ctx is cancelled
+func (q *sendQueue) run(ctx context.Context) {
+	for {
+		job, ok := q.next(time.Now())
+		if !ok {
+			select {
+			case <-ctx.Done():
+				return
+			case <-q.wake:
+				continue
+			}
+		}
+
+		// Skip sends whose caller already gave up
+		if err := job.ctx.Err(); err != nil {
+			job.result <- sendResult{err: err}
+			continue
+		}
+
+		resp, err := job.run()
+		job.result <- sendResult{resp: resp, err: err}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(q.interval):
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package whatsapp
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.mau.fi/whatsmeow"
+)
+
+// queuedJob is a job pushed to the queue with its priority and age
+type queuedJob struct {
+	name     string
+	priority Priority
+	age      time.Duration
+}
+
+func TestSendQueueOrder(t *testing.T) {
+	tests := []struct {
+		name    string
+		maxWait time.Duration
+		jobs    []queuedJob
+		want    []string
+	}{
+		{
+			name: "higher priority first",
+			jobs: []queuedJob{
+				{"broadcast-1", PriorityLow, 3 * time.Second},
+				{"confirmation", PriorityNormal, 2 * time.Second},
+				{"broadcast-2", PriorityLow, 2 * time.Second},
+				{"reply", PriorityHigh, time.Second},
+			},
+			want: []string{"reply", "confirmation", "broadcast-1", "broadcast-2"},
+		},
+		{
+			name: "same priority in arrival order",
+			jobs: []queuedJob{
+				{"reply-1", PriorityHigh, 3 * time.Second},
+				{"reply-2", PriorityHigh, 2 * time.Second},
+				{"reply-3", PriorityHigh, time.Second},
+			},
+			want: []string{"reply-1", "reply-2", "reply-3"},
+		},
+		{
+			name:    "aged low priority is not starved",
+			maxWait: 10 * time.Second,
+			jobs: []queuedJob{
+				{"broadcast", PriorityLow, 15 * time.Second},
+				{"confirmation", PriorityNormal, 5 * time.Second},
+				{"reply", PriorityHigh, time.Second},
+			},
+			want: []string{"broadcast", "reply", "confirmation"},
+		},
+		{
+			name: "no aging without maxWait",
+			jobs: []queuedJob{
+				{"broadcast", PriorityLow, time.Hour},
+				{"reply", PriorityHigh, time.Second},
+			},
+			want: []string{"reply", "broadcast"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := newSendQueue(0, tt.maxWait)
+			now := time.Now()
+			names := make(map[*sendJob]string)
+			for _, queued := range tt.jobs {
+				job := &sendJob{ctx: context.Background(), enqueued: now.Add(-queued.age)}
+				names[job] = queued.name
+				q.push(queued.priority, job)
+			}
+
+			var got []string
+			for {
+				job, ok := q.next(now)
+				if !ok {
+					break
+				}
+				got = append(got, names[job])
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("order = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSendQueueDepthMetric(t *testing.T) {
+	q := newSendQueue(0, 0)
+	for range 3 {
+		q.push(PriorityLow, &sendJob{ctx: context.Background(), enqueued: time.Now()})
+	}
+	q.push(PriorityHigh, &sendJob{ctx: context.Background(), enqueued: time.Now()})
+
+	depth := func(priority Priority) float64 {
+		return testutil.ToFloat64(metrics.SendQueueDepth.WithLabelValues(priority.String()))
+	}
+	if got := depth(PriorityLow); got != 3 {
+		t.Errorf("low depth = %v, want 3", got)
+	}
+	if got := depth(PriorityHigh); got != 1 {
+		t.Errorf("high depth = %v, want 1", got)
+	}
+
+	q.next(time.Now())
+	if got := depth(PriorityHigh); got != 0 {
+		t.Errorf("high depth after send = %v, want 0", got)
+	}
+}
+
+func TestSendQueueRunsMixedPriorities(t *testing.T) {
+	q := newSendQueue(time.Millisecond, 0)
+
+	// Hold the dispatcher in a first send while the others queue up
+	started, release := make(chan struct{}), make(chan struct{})
+	var mu sync.Mutex
+	var order []string
+	send := func(name string) func() (whatsmeow.SendResponse, error) {
+		return func() (whatsmeow.SendResponse, error) {
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+			return whatsmeow.SendResponse{ID: name}, nil
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go q.run(ctx)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		q.submit(ctx, PriorityLow, func() (whatsmeow.SendResponse, error) {
+			close(started)
+			<-release
+			return send("broadcast-1")()
+		})
+	}()
+	<-started
+
+	for _, job := range []struct {
+		name     string
+		priority Priority
+	}{
+		{"broadcast-2", PriorityLow},
+		{"confirmation", PriorityNormal},
+		{"reply", PriorityHigh},
+	} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := q.submit(ctx, job.priority, send(job.name))
+			if err != nil || resp.ID != job.name {
+				t.Errorf("submit(%s) = %q, %v", job.name, resp.ID, err)
+			}
+		}()
+		// Wait until the job is queued so arrival order is fixed
+		eventuallyQueued(t, q, job.priority)
+	}
+
+	close(release)
+	wg.Wait()
+	want := []string{"broadcast-1", "reply", "confirmation", "broadcast-2"}
+	if !slices.Equal(order, want) {
+		t.Errorf("send order = %v, want %v", order, want)
+	}
+}
+
+// eventuallyQueued waits until a job of the priority is waiting in the queue
+func eventuallyQueued(t *testing.T, q *sendQueue, priority Priority) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		q.mu.Lock()
+		queued := len(q.jobs[priority]) > 0
+		q.mu.Unlock()
+		if queued {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("no %s job queued", priority)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,89 @@
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mau.fi/whatsmeow/types"
+)
+
+// Delivery statuses tracked for sent messages
+const (
+	SendStatusSent      = "sent"
+	SendStatusDelivered = "delivered"
+	SendStatusRead      = "read"
+	SendStatusPlayed    = "played"
+)
+
+// sendStatusRank orders delivery statuses so late receipts never downgrade a record
+var sendStatusRank = map[string]int{
+	SendStatusSent:      0,
+	SendStatusDelivered: 1,
+	SendStatusRead:      2,
+	SendStatusPlayed:    3,
+}
+
+// StatusRank returns the position of a delivery status; a later status has a
+// higher rank
+func StatusRank(status string) int {
+	return sendStatusRank[status]
+}
+
+// SendRecord is the persisted record of a sent message
+type SendRecord struct {
+	MessageID string            `json:"message_id"`
+	JID       string            `json:"jid"`
+	SentAt    time.Time         `json:"sent_at"`
+	Status    string            `json:"status"`
+	UpdatedAt time.Time         `json:"updated_at"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	// Text is the message text; it is not persisted with the record
+	Text string `json:"-"`
+}
+
+// SendRecorder persists send records and correlates delivery receipts with
+// them, so receipts arriving after a restart still update the right record
+type SendRecorder interface {
+	RecordSend(ctx context.Context, record SendRecord) error
+	UpdateStatus(ctx context.Context, messageID, status string, at time.Time) error
+}
+
+// multiRecorder fans send records out to several recorders
+type multiRecorder []SendRecorder
+
+// MultiSendRecorder returns a SendRecorder that forwards to every recorder
+func MultiSendRecorder(recorders ...SendRecorder) SendRecorder {
+	return multiRecorder(recorders)
+}
+
+// RecordSend forwards the record to every recorder
+func (m multiRecorder) RecordSend(ctx context.Context, record SendRecord) error {
+	var errs []error
+	for _, recorder := range m {
+		errs = append(errs, recorder.RecordSend(ctx, record))
+	}
+	return errors.Join(errs...)
+}
+
+// UpdateStatus forwards the receipt to every recorder
+func (m multiRecorder) UpdateStatus(ctx context.Context, messageID, status string, at time.Time) error {
+	var errs []error
+	for _, recorder := range m {
+		errs = append(errs, recorder.UpdateStatus(ctx, messageID, status, at))
+	}
+	return errors.Join(errs...)
+}
+
+// receiptStatus maps a receipt type to the tracked delivery status
+func receiptStatus(receiptType types.ReceiptType) (string, bool) {
+	switch receiptType {
+	case types.ReceiptTypeDelivered:
+		return SendStatusDelivered, true
+	case types.ReceiptTypeRead:
+		return SendStatusRead, true
+	case types.ReceiptTypePlayed:
+		return SendStatusPlayed, true
+	}
+	return "", false
+}
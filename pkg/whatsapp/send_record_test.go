@@ -0,0 +1,90 @@
+package whatsapp
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// fakeRecorder is a SendRecorder that keeps records in memory
+type fakeRecorder struct {
+	mu      sync.Mutex
+	records map[string]SendRecord
+}
+
+func newFakeRecorder() *fakeRecorder {
+	return &fakeRecorder{records: make(map[string]SendRecord)}
+}
+
+func (r *fakeRecorder) RecordSend(ctx context.Context, record SendRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records[record.MessageID] = record
+	return nil
+}
+
+func (r *fakeRecorder) UpdateStatus(ctx context.Context, messageID, status string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	record, ok := r.records[messageID]
+	if !ok || StatusRank(status) <= StatusRank(record.Status) {
+		return nil
+	}
+	record.Status = status
+	record.UpdatedAt = at
+	r.records[messageID] = record
+	return nil
+}
+
+func (r *fakeRecorder) get(messageID string) (SendRecord, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	record, ok := r.records[messageID]
+	return record, ok
+}
+
+func TestSendRecordsReceipts(t *testing.T) {
+	recorder := newFakeRecorder()
+	jid := types.NewJID(testPhone, types.DefaultUserServer)
+
+	client := newTestClient(t, WithDryRun(0, 0), WithSendRecorder(recorder))
+	resp, err := client.SendText(context.Background(), jid, "Hola", WithMetadata("booking_id", "b-1"))
+	if err != nil {
+		t.Fatalf("SendText() error = %v", err)
+	}
+	record, ok := recorder.get(resp.ID)
+	if !ok {
+		t.Fatalf("no record for %s", resp.ID)
+	}
+	if record.Status != SendStatusSent || record.JID != jid.String() || record.Metadata["booking_id"] != "b-1" {
+		t.Errorf("record = %+v", record)
+	}
+
+	// The receipt arrives on a new client sharing only the recorder
+	restarted := newTestClient(t, WithDryRun(0, 0), WithSendRecorder(recorder))
+	readAt := time.Now()
+	restarted.handleEvent(&events.Receipt{
+		MessageSource: types.MessageSource{Chat: jid, Sender: jid},
+		MessageIDs:    []types.MessageID{resp.ID, "unknown"},
+		Timestamp:     readAt,
+		Type:          types.ReceiptTypeRead,
+	})
+	record, _ = recorder.get(resp.ID)
+	if record.Status != SendStatusRead || !record.UpdatedAt.Equal(readAt) {
+		t.Errorf("record = %+v, want read at %v", record, readAt)
+	}
+
+	// Receipt types without a tracked status leave the record alone
+	restarted.handleEvent(&events.Receipt{
+		MessageIDs: []types.MessageID{resp.ID},
+		Timestamp:  time.Now(),
+		Type:       types.ReceiptTypeRetry,
+	})
+	if got, _ := recorder.get(resp.ID); got.Status != SendStatusRead {
+		t.Errorf("status = %s after a retry receipt, want %s", got.Status, SendStatusRead)
+	}
+}
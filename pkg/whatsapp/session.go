@@ -0,0 +1,56 @@
+package whatsapp
+
+import (
+	"errors"
+
+	"go.mau.fi/whatsmeow"
+	"go.uber.org/zap"
+)
+
+// ErrSessionInvalid is returned when a send fails because the device session
+// is no longer valid (e.g. it was logged out from the phone)
+var ErrSessionInvalid = errors.New("whatsapp session is invalid, re-authentication required")
+
+// OnSessionInvalid registers a hook called when the session is found to be invalid
+func (c *Client) OnSessionInvalid(hook func()) {
+	c.sessionMu.Lock()
+	defer c.sessionMu.Unlock()
+	c.sessionInvalidHooks = append(c.sessionInvalidHooks, hook)
+}
+
+// NeedsReauth returns true if the session was found invalid and a new QR
+// pairing is required
+func (c *Client) NeedsReauth() bool {
+	c.sessionMu.RLock()
+	defer c.sessionMu.RUnlock()
+	return c.sessionInvalid
+}
+
+// isSessionError reports whether a send error means the session is invalid
+func isSessionError(err error) bool {
+	return errors.Is(err, whatsmeow.ErrNotLoggedIn) || errors.Is(err, whatsmeow.ErrIQNotAuthorized)
+}
+
+// markSessionInvalid flags the session as needing re-auth and runs the hooks
+func (c *Client) markSessionInvalid(reason error) {
+	c.sessionMu.Lock()
+	if c.sessionInvalid {
+		c.sessionMu.Unlock()
+		return
+	}
+	c.sessionInvalid = true
+	hooks := append([]func(){}, c.sessionInvalidHooks...)
+	c.sessionMu.Unlock()
+
+	c.logger.Error("WhatsApp session is invalid, re-authentication required", zap.Error(reason))
+	for _, hook := range hooks {
+		go hook()
+	}
+}
+
+// clearSessionInvalid clears the re-auth flag after a successful login
+func (c *Client) clearSessionInvalid() {
+	c.sessionMu.Lock()
+	defer c.sessionMu.Unlock()
+	c.sessionInvalid = false
+}
@@ -0,0 +1,71 @@
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+func TestSendMarksDeadSession(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		invalid bool
+	}{
+		{name: "not logged in", err: whatsmeow.ErrNotLoggedIn, invalid: true},
+		{name: "unauthorized", err: whatsmeow.ErrIQNotAuthorized, invalid: true},
+		{name: "other failure", err: errors.New("timed out")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t)
+			var prompts atomic.Int32
+			client.OnSessionInvalid(func() { prompts.Add(1) })
+			failSends(client, tt.err)
+
+			jid := types.NewJID(testPhone, types.DefaultUserServer)
+			for range 2 {
+				_, err := client.SendText(context.Background(), jid, "Hola")
+				if err == nil {
+					t.Fatal("SendText() error = nil")
+				}
+				if got := errors.Is(err, ErrSessionInvalid); got != tt.invalid {
+					t.Fatalf("errors.Is(%v, ErrSessionInvalid) = %v, want %v", err, got, tt.invalid)
+				}
+			}
+
+			if got := client.NeedsReauth(); got != tt.invalid {
+				t.Errorf("NeedsReauth() = %v, want %v", got, tt.invalid)
+			}
+			// Re-auth is prompted once however many sends fail
+			want := int32(0)
+			if tt.invalid {
+				want = 1
+			}
+			time.Sleep(20 * time.Millisecond)
+			if got := prompts.Load(); got != want {
+				t.Errorf("re-auth prompts = %d, want %d", got, want)
+			}
+		})
+	}
+}
+
+func TestPairingClearsDeadSession(t *testing.T) {
+	client := newTestClient(t)
+	failSends(client, whatsmeow.ErrNotLoggedIn)
+	client.SendText(context.Background(), types.NewJID(testPhone, types.DefaultUserServer), "Hola")
+	if !client.NeedsReauth() {
+		t.Fatal("NeedsReauth() = false after a not logged in send")
+	}
+
+	client.handleEvent(&events.PairSuccess{})
+	if client.NeedsReauth() {
+		t.Error("NeedsReauth() = true after pairing")
+	}
+}
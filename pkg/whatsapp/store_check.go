@@ -0,0 +1,144 @@
+package whatsapp
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ErrStoreCorrupt is returned when the device store fails its integrity check
+var ErrStoreCorrupt = errors.New("device store is corrupt")
+
+// Device store integrity statuses
+const (
+	StoreUnchecked = "unchecked"
+	StoreOK        = "ok"
+	StoreRecovered = "recovered"
+)
+
+// StoreCheck is the result of the device store integrity check run when the
+// client is created
+type StoreCheck struct {
+	Status    string    `json:"status"`
+	CheckedAt time.Time `json:"checked_at,omitempty"`
+	// Detail is the first problem reported by SQLite
+	Detail string `json:"detail,omitempty"`
+	// BackupPath is where the corrupt store was moved before starting fresh
+	BackupPath string `json:"backup_path,omitempty"`
+}
+
+// storeCheck configures the integrity check of the device store
+type storeCheck struct {
+	enabled bool
+	recover bool
+}
+
+// WithStoreIntegrityCheck runs PRAGMA integrity_check on the device store
+// before opening it. A corrupt store makes NewClient fail with
+// ErrStoreCorrupt or, with recover, is moved aside so the client starts
+// fresh and the device has to be paired again.
+func WithStoreIntegrityCheck(recover bool) ClientOption {
+	return func(c *Client) {
+		c.storeCheck = storeCheck{enabled: true, recover: recover}
+	}
+}
+
+// StoreCheck returns the result of the device store integrity check
+func (c *Client) StoreCheck() StoreCheck {
+	return c.storeCheckResult
+}
+
+// checkStore runs the integrity check on the device store and recovers from
+// corruption when allowed
+func (c *Client) checkStore(dsn string) error {
+	c.storeCheckResult = StoreCheck{Status: StoreUnchecked}
+	if !c.storeCheck.enabled {
+		return nil
+	}
+
+	path := storePath(dsn)
+	if info, err := os.Stat(path); err != nil || info.Size() == 0 {
+		// A new store has nothing to check
+		c.storeCheckResult = StoreCheck{Status: StoreOK, CheckedAt: time.Now()}
+		return nil
+	}
+
+	detail, err := CheckStoreIntegrity(dsn)
+	if err != nil {
+		return fmt.Errorf("failed to check device store integrity: %w", err)
+	}
+	if detail == "" {
+		c.storeCheckResult = StoreCheck{Status: StoreOK, CheckedAt: time.Now()}
+		return nil
+	}
+
+	if !c.storeCheck.recover {
+		c.logger.Error("Device store is corrupt; restore it from a backup or enable recovery to start fresh and pair again",
+			zap.String("path", path),
+			zap.String("detail", detail))
+		return fmt.Errorf("%w: %s: %s", ErrStoreCorrupt, path, detail)
+	}
+
+	backupPath := fmt.Sprintf("%s.corrupt-%s", path, time.Now().UTC().Format("20060102T150405Z"))
+	if err := os.Rename(path, backupPath); err != nil {
+		return fmt.Errorf("failed to back up corrupt device store: %w", err)
+	}
+	// Stale journals would be replayed into the new store
+	for _, suffix := range []string{"-wal", "-shm", "-journal"} {
+		if err := os.Rename(path+suffix, backupPath+suffix); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to back up corrupt device store: %w", err)
+		}
+	}
+	c.logger.Error("Device store was corrupt and has been moved aside; pair the device again",
+		zap.String("path", path),
+		zap.String("backup_path", backupPath),
+		zap.String("detail", detail))
+
+	c.storeCheckResult = StoreCheck{
+		Status:     StoreRecovered,
+		CheckedAt:  time.Now(),
+		Detail:     detail,
+		BackupPath: backupPath,
+	}
+	return nil
+}
+
+// CheckStoreIntegrity runs PRAGMA integrity_check on a SQLite store. It
+// returns the first problem found, or an empty string when the store is
+// intact. A file that is not a database is reported as a problem; only
+// errors opening the file are returned as errors.
+func CheckStoreIntegrity(dsn string) (string, error) {
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return "", err
+	}
+	defer db.Close()
+
+	var result string
+	if err := db.QueryRow("PRAGMA integrity_check").Scan(&result); err != nil {
+		message := err.Error()
+		if strings.Contains(message, "not a database") || strings.Contains(message, "malformed") {
+			return message, nil
+		}
+		return "", err
+	}
+	if result == "ok" {
+		return "", nil
+	}
+	return result, nil
+}
+
+// storePath returns the file of a SQLite data source name such as
+// "file:whatsapp.db?_foreign_keys=on"
+func storePath(dsn string) string {
+	path := strings.TrimPrefix(dsn, "file:")
+	if i := strings.Index(path, "?"); i >= 0 {
+		path = path[:i]
+	}
+	return path
+}
@@ -0,0 +1,103 @@
+package whatsapp
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+)
+
+// newStoreFile creates a device store with whatsmeow's schema and returns
+// its path and data source name
+func newStoreFile(t *testing.T) (string, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "whatsapp.db")
+	dsn := "file:" + path + "?_foreign_keys=on"
+	client, err := NewClient(dsn, WithLogger(logger.NewNop()), WithDryRun(0, 0))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	client.Close()
+	return path, dsn
+}
+
+// truncateStore cuts the store file short, as a crash mid-write does
+func truncateStore(t *testing.T, path string) {
+	t.Helper()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if err := os.Truncate(path, info.Size()/2); err != nil {
+		t.Fatalf("Truncate() error = %v", err)
+	}
+}
+
+func TestStoreCheckDetectsTruncatedStore(t *testing.T) {
+	path, dsn := newStoreFile(t)
+	truncateStore(t, path)
+
+	detail, err := CheckStoreIntegrity(dsn)
+	if err != nil || detail == "" {
+		t.Fatalf("CheckStoreIntegrity() = %q, %v, want the corruption reported", detail, err)
+	}
+
+	// Without recovery startup fails with an actionable error
+	_, err = NewClient(dsn, WithLogger(logger.NewNop()), WithDryRun(0, 0), WithStoreIntegrityCheck(false))
+	if !errors.Is(err, ErrStoreCorrupt) {
+		t.Fatalf("NewClient() error = %v, want %v", err, ErrStoreCorrupt)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("corrupt store removed without recovery: %v", err)
+	}
+}
+
+func TestStoreCheckRecoversTruncatedStore(t *testing.T) {
+	path, dsn := newStoreFile(t)
+	truncateStore(t, path)
+
+	client, err := NewClient(dsn, WithLogger(logger.NewNop()), WithDryRun(0, 0), WithStoreIntegrityCheck(true))
+	if err != nil {
+		t.Fatalf("NewClient() with recovery error = %v", err)
+	}
+	defer client.Close()
+
+	check := client.StoreCheck()
+	if check.Status != StoreRecovered || check.Detail == "" || check.BackupPath == "" {
+		t.Fatalf("StoreCheck() = %+v, want the store recovered", check)
+	}
+	if _, err := os.Stat(check.BackupPath); err != nil {
+		t.Errorf("backup of the corrupt store missing: %v", err)
+	}
+	// The fresh store is healthy and the device has to be paired again
+	if detail, err := CheckStoreIntegrity(dsn); err != nil || detail != "" {
+		t.Errorf("CheckStoreIntegrity() of the fresh store = %q, %v, want ok", detail, err)
+	}
+	if client.IsLoggedIn() {
+		t.Error("client logged in on a fresh store")
+	}
+}
+
+func TestStoreCheckHealthyStore(t *testing.T) {
+	_, dsn := newStoreFile(t)
+
+	client, err := NewClient(dsn, WithLogger(logger.NewNop()), WithDryRun(0, 0), WithStoreIntegrityCheck(false))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	defer client.Close()
+	if check := client.StoreCheck(); check.Status != StoreOK || check.CheckedAt.IsZero() {
+		t.Errorf("StoreCheck() = %+v, want ok", check)
+	}
+
+	// Garbage that is not a database is reported too
+	garbage := filepath.Join(t.TempDir(), "garbage.db")
+	if err := os.WriteFile(garbage, []byte("definitely not a sqlite database, just some text"), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if detail, err := CheckStoreIntegrity("file:" + garbage); err != nil || detail == "" {
+		t.Errorf("CheckStoreIntegrity() of garbage = %q, %v, want it reported", detail, err)
+	}
+}
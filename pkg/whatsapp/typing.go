@@ -0,0 +1,87 @@
+package whatsapp
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"go.mau.fi/whatsmeow/types"
+	"go.uber.org/zap"
+)
+
+// Typing indicator shown before humanized replies: a base delay plus a
+// little per character, so longer replies take a bit longer to "type"
+const (
+	typingBaseDelay    = time.Second
+	typingDelayPerRune = 20 * time.Millisecond
+	maxTypingDelay     = 2 * time.Second
+)
+
+// WithHumanizedTyping shows the typing indicator for a second or two before
+// sending messages sent with WithTyping, so automated replies do not appear
+// instantly
+func WithHumanizedTyping(enabled bool) ClientOption {
+	return func(c *Client) {
+		c.humanizedTyping = enabled
+	}
+}
+
+// WithTyping shows the typing indicator before sending the message when the
+// client has humanized typing enabled
+func WithTyping() SendOption {
+	return func(o *sendOptions) {
+		o.typing = true
+	}
+}
+
+// typingDelay returns how long the typing indicator is shown before a text
+func typingDelay(text string) time.Duration {
+	delay := typingBaseDelay + time.Duration(utf8.RuneCountInString(text))*typingDelayPerRune
+	if delay > maxTypingDelay {
+		return maxTypingDelay
+	}
+	return delay
+}
+
+// SendTyping shows the typing indicator in the chat for the duration, or
+// until the context is done, and then clears it. In dry-run mode it only
+// waits.
+func (c *Client) SendTyping(ctx context.Context, jid types.JID, duration time.Duration) error {
+	if !c.IsConnected() {
+		return ErrNotConnected
+	}
+
+	if !c.dryRun.enabled {
+		if err := c.wa().SendChatPresence(jid, types.ChatPresenceComposing, types.ChatPresenceMediaText); err != nil {
+			return fmt.Errorf("failed to send typing indicator: %w", err)
+		}
+		defer func() {
+			if err := c.wa().SendChatPresence(jid, types.ChatPresencePaused, types.ChatPresenceMediaText); err != nil {
+				c.logger.Debug("Failed to clear typing indicator", zap.String("to", jid.String()), zap.Error(err))
+			}
+		}()
+	}
+
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// typeBefore shows the typing indicator before a humanized send. The wait is
+// bounded by the typing delay and the caller's context, and a failure only
+// skips the indicator.
+func (c *Client) typeBefore(ctx context.Context, jid types.JID, text string) {
+	delay := typingDelay(text)
+	typingCtx, cancel := context.WithTimeout(ctx, delay)
+	defer cancel()
+
+	if err := c.SendTyping(typingCtx, jid, delay); err != nil && ctx.Err() == nil {
+		c.logger.Debug("Sending without typing indicator", zap.String("to", jid.String()), zap.Error(err))
+	}
+}
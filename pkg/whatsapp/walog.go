@@ -0,0 +1,83 @@
+package whatsapp
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	waLog "go.mau.fi/whatsmeow/util/log"
+	"go.uber.org/zap"
+)
+
+// waLogLevels orders the whatsmeow log levels
+var waLogLevels = map[string]int{
+	"debug": 0,
+	"info":  1,
+	"warn":  2,
+	"error": 3,
+}
+
+// WithWhatsmeowLog forwards whatsmeow's protocol logs at or above the given
+// level (debug, info, warn or error) to the client logger. Protocol logs are
+// silenced by default.
+func WithWhatsmeowLog(level string) ClientOption {
+	return func(c *Client) {
+		c.waLogLevel = strings.ToLower(level)
+	}
+}
+
+// waLogger adapts logger.Logger to whatsmeow's waLog.Logger interface
+type waLogger struct {
+	logger logger.Logger
+	module string
+	min    int
+}
+
+// newWALogger returns a waLog.Logger forwarding to logger at or above level.
+// Unknown or empty levels return a silent logger.
+func newWALogger(logger logger.Logger, level string) waLog.Logger {
+	min, ok := waLogLevels[level]
+	if !ok {
+		return waLog.Noop
+	}
+	return &waLogger{logger: logger, min: min}
+}
+
+// Debugf logs a whatsmeow debug message
+func (l *waLogger) Debugf(msg string, args ...interface{}) {
+	if l.min <= waLogLevels["debug"] {
+		l.logger.Debug(fmt.Sprintf(msg, args...), l.fields()...)
+	}
+}
+
+// Infof logs a whatsmeow info message
+func (l *waLogger) Infof(msg string, args ...interface{}) {
+	if l.min <= waLogLevels["info"] {
+		l.logger.Info(fmt.Sprintf(msg, args...), l.fields()...)
+	}
+}
+
+// Warnf logs a whatsmeow warning
+func (l *waLogger) Warnf(msg string, args ...interface{}) {
+	if l.min <= waLogLevels["warn"] {
+		l.logger.Warn(fmt.Sprintf(msg, args...), l.fields()...)
+	}
+}
+
+// Errorf logs a whatsmeow error
+func (l *waLogger) Errorf(msg string, args ...interface{}) {
+	l.logger.Error(fmt.Sprintf(msg, args...), l.fields()...)
+}
+
+// Sub returns a logger for a whatsmeow submodule
+func (l *waLogger) Sub(module string) waLog.Logger {
+	if l.module != "" {
+		module = l.module + "/" + module
+	}
+	return &waLogger{logger: l.logger, module: module, min: l.min}
+}
+
+// fields tags the entry with the whatsmeow module
+func (l *waLogger) fields() []zap.Field {
+	return []zap.Field{zap.String("whatsmeow_module", l.module)}
+}
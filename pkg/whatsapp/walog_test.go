@@ -0,0 +1,95 @@
+package whatsapp
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/pabbloacevedog/whatspp-service-glidpa/pkg/logger"
+	waLog "go.mau.fi/whatsmeow/util/log"
+	"go.uber.org/zap/zapcore"
+)
+
+// recordingLogger is a logger.Logger keeping every entry as "level module: message"
+type recordingLogger struct {
+	mu      sync.Mutex
+	entries []string
+}
+
+func (l *recordingLogger) record(level, msg string, fields []zapcore.Field) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	module := ""
+	for _, field := range fields {
+		if field.Key == "whatsmeow_module" {
+			module = field.String
+		}
+	}
+	l.entries = append(l.entries, fmt.Sprintf("%s %s: %s", level, module, msg))
+}
+
+// all returns the entries logged so far
+func (l *recordingLogger) all() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return slices.Clone(l.entries)
+}
+
+func (l *recordingLogger) Debug(msg string, fields ...zapcore.Field) { l.record("debug", msg, fields) }
+func (l *recordingLogger) Info(msg string, fields ...zapcore.Field)  { l.record("info", msg, fields) }
+func (l *recordingLogger) Warn(msg string, fields ...zapcore.Field)  { l.record("warn", msg, fields) }
+func (l *recordingLogger) Error(msg string, fields ...zapcore.Field) { l.record("error", msg, fields) }
+func (l *recordingLogger) Fatal(msg string, fields ...zapcore.Field) { l.record("fatal", msg, fields) }
+func (l *recordingLogger) With(...zapcore.Field) logger.Logger       { return l }
+func (l *recordingLogger) Sync() error                               { return nil }
+
+// logAllLevels logs one formatted message at each whatsmeow level
+func logAllLevels(log waLog.Logger) {
+	log.Debugf("handshake %d", 1)
+	log.Infof("connected to %s", "server")
+	log.Warnf("slow %s", "pong")
+	log.Errorf("failed: %v", "timeout")
+}
+
+func TestWhatsmeowLogLevels(t *testing.T) {
+	tests := []struct {
+		level string
+		want  []string
+	}{
+		{level: "debug", want: []string{"debug : handshake 1", "info : connected to server", "warn : slow pong", "error : failed: timeout"}},
+		{level: "warn", want: []string{"warn : slow pong", "error : failed: timeout"}},
+		{level: "error", want: []string{"error : failed: timeout"}},
+		{level: "", want: nil},
+		{level: "verbose", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.level, func(t *testing.T) {
+			rec := &recordingLogger{}
+			logAllLevels(newWALogger(rec, tt.level))
+			if got := rec.all(); !slices.Equal(got, tt.want) {
+				t.Errorf("entries = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWhatsmeowLogSubmodules(t *testing.T) {
+	rec := &recordingLogger{}
+	newWALogger(rec, "info").Sub("Client").Sub("Socket").Infof("dialing")
+
+	want := []string{"info Client/Socket: dialing"}
+	if got := rec.all(); !slices.Equal(got, want) {
+		t.Errorf("entries = %q, want %q", got, want)
+	}
+}
+
+func TestClientForwardsWhatsmeowLogs(t *testing.T) {
+	rec := &recordingLogger{}
+	client := newTestClient(t, WithDryRun(0, 0), WithLogger(rec), WithWhatsmeowLog("INFO"))
+
+	client.wa().Log.Infof("protocol %s", "event")
+	if !slices.Contains(rec.all(), "info Client: protocol event") {
+		t.Errorf("entries = %q, want the protocol log forwarded", rec.all())
+	}
+}